@@ -23,6 +23,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/BlindspotSoftware/dutctl/internal/buildinfo"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/jobs"
 	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
 	"github.com/BlindspotSoftware/dutctl/internal/log"
 	"github.com/BlindspotSoftware/dutctl/internal/rpc"
@@ -175,6 +176,9 @@ func (agt *agent) startRPCService(ctx context.Context) error {
 	service := &rpcService{
 		devices: agt.config.Devices,
 		locker:  locker.New(),
+		// Jobs are bound to ctx, so a signal cancels the running ones and their
+		// attached requests end instead of holding up the drain.
+		jobs: jobs.New(ctx),
 	}
 
 	mux := http.NewServeMux()
@@ -0,0 +1,254 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
+	"github.com/BlindspotSoftware/dutctl/internal/keyword"
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/dut"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// powerOnTimeout bounds switching a device on again at the end of a cycle, which
+// is done even if the request was aborted meanwhile (see cycle).
+const powerOnTimeout = 30 * time.Second
+
+// powerLocks serializes the calls to the power controllers per device, so a
+// status query does not interleave with switching the same device. The zero value
+// is ready to use.
+type powerLocks struct {
+	mu      sync.Mutex
+	devices map[string]*sync.Mutex
+}
+
+// lock locks the power controller of device and returns the function unlocking it.
+func (p *powerLocks) lock(device string) func() {
+	p.mu.Lock()
+
+	if p.devices == nil {
+		p.devices = make(map[string]*sync.Mutex)
+	}
+
+	mu, ok := p.devices[device]
+	if !ok {
+		mu = &sync.Mutex{}
+		p.devices[device] = mu
+	}
+
+	p.mu.Unlock()
+
+	mu.Lock()
+
+	return mu.Unlock
+}
+
+// Power is the handler for the Power RPC. It switches or queries the power of a
+// device through the power controller configured for it. Switching takes the
+// command-scoped auto-lock of the device like Run, so it is rejected while
+// another user holds the device and does not overlap a command of another user;
+// querying only reads the state and is always allowed. Requests for the same
+// device are served one after the other, so a query waits for a running cycle.
+//
+// The response reports the state read from the controller after the action. A
+// controller failing to tell it after a successful action yields an unspecified
+// state rather than an error.
+//
+// A device without power controller may serve power requests with a command named
+// power instead. The request is rejected with CodeUnimplemented then, whatever its
+// action, so the client runs the command in its place.
+//
+// Errors: CodeNotFound for an unknown device (dut.ErrDeviceNotFound);
+// CodeUnimplemented for a device with a power command (dut.ErrPowerIsCommand);
+// CodeInvalidArgument for an unspecified action or a negative off-time;
+// CodeFailedPrecondition for a device without power controller (dut.ErrNoPower)
+// or when another owner holds the device (locker.ErrWrongOwner); as in
+// powerError for a failing controller; CodeInternal otherwise.
+func (a *rpcService) Power(
+	ctx context.Context,
+	req *connect.Request[pb.PowerRequest],
+) (*connect.Response[pb.PowerResponse], error) {
+	l := rpcLogger(ctx, "Power")
+	l.Info("request received")
+
+	device := req.Msg.GetDevice()
+	action := req.Msg.GetAction()
+	offTime := time.Duration(req.Msg.GetOffTimeMs()) * time.Millisecond
+
+	power, err := a.devices.FindPower(device)
+	if err != nil {
+		code := connect.CodeInternal
+
+		switch {
+		case errors.Is(err, dut.ErrDeviceNotFound):
+			code = connect.CodeNotFound
+		case errors.Is(err, dut.ErrPowerIsCommand):
+			code = connect.CodeUnimplemented
+		case errors.Is(err, dut.ErrNoPower):
+			code = connect.CodeFailedPrecondition
+		}
+
+		return nil, connect.NewError(code, fmt.Errorf("device %q: %w", device, err))
+	}
+
+	if action == pb.PowerAction_POWER_ACTION_UNSPECIFIED {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("no power action given"))
+	}
+
+	if offTime < 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("negative off-time %s", offTime))
+	}
+
+	if action != pb.PowerAction_POWER_ACTION_STATUS {
+		identity, err := caller(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		user := identity.User()
+
+		_, err = a.locker.AutoLock(device, user)
+		if err != nil {
+			if errors.Is(err, locker.ErrWrongOwner) {
+				return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+			}
+
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+
+		defer clearAutoLock(ctx, a.locker, device, user)
+	}
+
+	if offTime == 0 {
+		offTime = power.OffTime
+	}
+
+	unlock := a.power.lock(device)
+	defer unlock()
+
+	mlog := log.Scope(log.FromContext(ctx), "module").With("device", device, "command", keyword.Power, "module", power.Config.Name)
+	mctx := log.Into(ctx, mlog)
+	controller := power.Controller()
+
+	err = applyPower(mctx, controller, action, offTime)
+	if err != nil {
+		return nil, powerError(action, err)
+	}
+
+	state, err := controller.PowerState(mctx)
+	if err != nil {
+		l.Warn("power state unknown", "device", device, "err", err)
+
+		state = module.PowerUnknown
+	}
+
+	l.Info("request finished", "device", device, "action", action, "state", state)
+
+	return connect.NewResponse(&pb.PowerResponse{
+		Device:     device,
+		State:      powerState(state),
+		Controller: power.Config.Name,
+	}), nil
+}
+
+// applyPower performs action with controller. A status query has nothing to do.
+func applyPower(ctx context.Context, controller module.PowerController, action pb.PowerAction, offTime time.Duration) error {
+	switch action {
+	case pb.PowerAction_POWER_ACTION_ON:
+		return controller.PowerOn(ctx)
+	case pb.PowerAction_POWER_ACTION_OFF:
+		return controller.PowerOff(ctx)
+	case pb.PowerAction_POWER_ACTION_CYCLE:
+		return cycle(ctx, controller, offTime)
+	case pb.PowerAction_POWER_ACTION_STATUS:
+		return nil
+	default:
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unknown power action %v", action))
+	}
+}
+
+// cycle switches the device off and, after offTime, on again. Once the device is
+// off, it is switched on even if ctx is done before, e.g. because the client went
+// away, so an aborted cycle never leaves the device off. The off-time is cut
+// short then, and ctx's error is returned.
+func cycle(ctx context.Context, controller module.PowerController, offTime time.Duration) error {
+	err := controller.PowerOff(ctx)
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(offTime)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		log.FromContext(ctx).Warn("power cycle aborted, switching the device on again")
+	}
+
+	onCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), powerOnTimeout)
+	defer cancel()
+
+	err = controller.PowerOn(onCtx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// powerError maps an error of a power controller to the connect error that fails
+// the Power RPC, like moduleError does for a module of a command: a context
+// cancellation maps via cancelCode and anything else is CodeAborted. A typed
+// connect error passes through unchanged.
+func powerError(action pb.PowerAction, err error) error {
+	var connectErr *connect.Error
+
+	switch {
+	case errors.As(err, &connectErr):
+		return err
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(cancelCode(err), err)
+	default:
+		return connect.NewError(connect.CodeAborted, fmt.Errorf("power %s failed: %v", powerActionName(action), err))
+	}
+}
+
+// powerActionName renders action like the dutctl keyword that requests it.
+func powerActionName(action pb.PowerAction) string {
+	switch action {
+	case pb.PowerAction_POWER_ACTION_ON:
+		return "on"
+	case pb.PowerAction_POWER_ACTION_OFF:
+		return "off"
+	case pb.PowerAction_POWER_ACTION_CYCLE:
+		return "cycle"
+	case pb.PowerAction_POWER_ACTION_STATUS:
+		return "status"
+	default:
+		return action.String()
+	}
+}
+
+// powerState maps a power state to its wire representation.
+func powerState(state module.PowerState) pb.PowerState {
+	switch state {
+	case module.PowerOn:
+		return pb.PowerState_POWER_STATE_ON
+	case module.PowerOff:
+		return pb.PowerState_POWER_STATE_OFF
+	default:
+		return pb.PowerState_POWER_STATE_UNSPECIFIED
+	}
+}
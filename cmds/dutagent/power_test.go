@@ -0,0 +1,204 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/jobs"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
+	"github.com/BlindspotSoftware/dutctl/pkg/dut"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// fakePower is a power controller recording the calls it receives.
+type fakePower struct {
+	state module.PowerState
+	err   error // returned by PowerOn and PowerOff
+	calls []string
+}
+
+var _ module.PowerController = &fakePower{}
+
+func (f *fakePower) Help() string                                               { return "fake power controller" }
+func (f *fakePower) Init(_ context.Context) error                               { return nil }
+func (f *fakePower) Deinit(_ context.Context) error                             { return nil }
+func (f *fakePower) Run(_ context.Context, _ module.Session, _ ...string) error { return nil }
+
+func (f *fakePower) PowerOn(_ context.Context) error {
+	f.calls = append(f.calls, "on")
+
+	if f.err != nil {
+		return f.err
+	}
+
+	f.state = module.PowerOn
+
+	return nil
+}
+
+func (f *fakePower) PowerOff(_ context.Context) error {
+	f.calls = append(f.calls, "off")
+
+	if f.err != nil {
+		return f.err
+	}
+
+	f.state = module.PowerOff
+
+	return nil
+}
+
+func (f *fakePower) PowerState(_ context.Context) (module.PowerState, error) {
+	return f.state, nil
+}
+
+func newPowerTestService(ctrl *fakePower) *rpcService {
+	return &rpcService{
+		devices: dut.Devlist{
+			"devA": dut.Device{Power: &dut.Power{
+				Module:  dut.Module{Module: ctrl, Config: dut.ModuleConfig{Name: "fake-power"}},
+				OffTime: time.Millisecond,
+			}},
+			"otherDev": dut.Device{},
+			"cmdDev":   dut.Device{Cmds: map[string]dut.Command{"power": {}}},
+		},
+		locker: locker.New(),
+		jobs:   jobs.New(context.Background()),
+	}
+}
+
+func powerReq(device string, action pb.PowerAction, offTimeMs int64) *connect.Request[pb.PowerRequest] {
+	return connect.NewRequest(&pb.PowerRequest{Device: device, Action: action, OffTimeMs: offTimeMs})
+}
+
+func TestPowerRPC(t *testing.T) {
+	tests := []struct {
+		name      string
+		initial   module.PowerState
+		action    pb.PowerAction
+		wantCalls []string
+		wantState pb.PowerState
+	}{
+		{"on", module.PowerOff, pb.PowerAction_POWER_ACTION_ON, []string{"on"}, pb.PowerState_POWER_STATE_ON},
+		{"off", module.PowerOn, pb.PowerAction_POWER_ACTION_OFF, []string{"off"}, pb.PowerState_POWER_STATE_OFF},
+		{"cycle", module.PowerOn, pb.PowerAction_POWER_ACTION_CYCLE, []string{"off", "on"}, pb.PowerState_POWER_STATE_ON},
+		{"status", module.PowerOff, pb.PowerAction_POWER_ACTION_STATUS, nil, pb.PowerState_POWER_STATE_OFF},
+		{"status unknown", module.PowerUnknown, pb.PowerAction_POWER_ACTION_STATUS, nil, pb.PowerState_POWER_STATE_UNSPECIFIED},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &fakePower{state: tt.initial}
+			svc := newPowerTestService(ctrl)
+
+			res, err := svc.Power(userCtx("alice"), powerReq("devA", tt.action, 0))
+			if err != nil {
+				t.Fatalf("Power: unexpected error: %v", err)
+			}
+
+			if !slices.Equal(ctrl.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", ctrl.calls, tt.wantCalls)
+			}
+
+			if res.Msg.GetState() != tt.wantState {
+				t.Errorf("state = %v, want %v", res.Msg.GetState(), tt.wantState)
+			}
+
+			if res.Msg.GetController() != "fake-power" {
+				t.Errorf("controller = %q, want fake-power", res.Msg.GetController())
+			}
+
+			// The auto-lock is released once the request is done.
+			if svc.locker.CheckAccess("devA", "bob") != nil {
+				t.Error("device still locked after the request")
+			}
+		})
+	}
+}
+
+func TestPowerRPCErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   string
+		action   pb.PowerAction
+		offTime  int64
+		ctrlErr  error
+		wantCode connect.Code
+	}{
+		{"unknown device", "ghost", pb.PowerAction_POWER_ACTION_ON, 0, nil, connect.CodeNotFound},
+		{"no power controller", "otherDev", pb.PowerAction_POWER_ACTION_ON, 0, nil, connect.CodeFailedPrecondition},
+		{"power command", "cmdDev", pb.PowerAction_POWER_ACTION_ON, 0, nil, connect.CodeUnimplemented},
+		{"power command without action", "cmdDev", pb.PowerAction_POWER_ACTION_UNSPECIFIED, 0, nil, connect.CodeUnimplemented},
+		{"no action", "devA", pb.PowerAction_POWER_ACTION_UNSPECIFIED, 0, nil, connect.CodeInvalidArgument},
+		{"negative off-time", "devA", pb.PowerAction_POWER_ACTION_CYCLE, -1, nil, connect.CodeInvalidArgument},
+		{"controller fails", "devA", pb.PowerAction_POWER_ACTION_OFF, 0, errors.New("outlet stuck"), connect.CodeAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newPowerTestService(&fakePower{err: tt.ctrlErr})
+
+			_, err := svc.Power(userCtx("alice"), powerReq(tt.device, tt.action, tt.offTime))
+			if connect.CodeOf(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v (err: %v)", connect.CodeOf(err), tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestPowerRPCLocked(t *testing.T) {
+	ctrl := &fakePower{state: module.PowerOn}
+	svc := newPowerTestService(ctrl)
+
+	if _, err := svc.Lock(userCtx("bob"), lockReq("devA", 60)); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	_, err := svc.Power(userCtx("alice"), powerReq("devA", pb.PowerAction_POWER_ACTION_OFF, 0))
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Errorf("off: code = %v, want FailedPrecondition", connect.CodeOf(err))
+	}
+
+	if len(ctrl.calls) != 0 {
+		t.Errorf("calls = %v, want none on a locked device", ctrl.calls)
+	}
+
+	// A status query does not need the device.
+	res, err := svc.Power(userCtx("alice"), powerReq("devA", pb.PowerAction_POWER_ACTION_STATUS, 0))
+	if err != nil {
+		t.Fatalf("status: unexpected error: %v", err)
+	}
+
+	if res.Msg.GetState() != pb.PowerState_POWER_STATE_ON {
+		t.Errorf("state = %v, want ON", res.Msg.GetState())
+	}
+}
+
+func TestCycleSwitchesOnWhenAborted(t *testing.T) {
+	ctrl := &fakePower{state: module.PowerOn}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := cycle(ctx, ctrl, time.Hour)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	if !slices.Equal(ctrl.calls, []string{"off", "on"}) {
+		t.Errorf("calls = %v, want [off on]", ctrl.calls)
+	}
+
+	if ctrl.state != module.PowerOn {
+		t.Errorf("state = %v, want on", ctrl.state)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/dut"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// runReport records the outcome of every module of a command while the modules
+// run, for the RunResult message that concludes a Run. It is written by the
+// module goroutine and read by the FSM, hence the lock.
+type runReport struct {
+	mu      sync.Mutex
+	modules []*pb.ModuleResult
+}
+
+// newRunReport returns a report for modules in which every module is marked
+// skipped until it starts.
+func newRunReport(modules []dut.Module) *runReport {
+	r := &runReport{modules: make([]*pb.ModuleResult, 0, len(modules))}
+
+	for idx, mod := range modules {
+		r.modules = append(r.modules, &pb.ModuleResult{
+			Name:    mod.Config.Name,
+			Index:   int32(idx + 1), //nolint:gosec // a command never has 2^31 modules
+			Outcome: pb.ModuleOutcome_MODULE_OUTCOME_SKIPPED,
+		})
+	}
+
+	return r
+}
+
+// start marks the module at idx (0-based) as started at t.
+func (r *runReport) start(idx int, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modules[idx].StartedAt = t.UnixMilli()
+	r.modules[idx].Outcome = pb.ModuleOutcome_MODULE_OUTCOME_UNSPECIFIED
+}
+
+// finish records the outcome of the module at idx (0-based), which returned err
+// at t. An error from a cancelled context marks the module canceled rather than
+// failed: it returned because the run was aborted.
+func (r *runReport) finish(idx int, t time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := r.modules[idx]
+	res.FinishedAt = t.UnixMilli()
+	res.DurationMs = res.GetFinishedAt() - res.GetStartedAt()
+
+	switch {
+	case err == nil:
+		res.Outcome = pb.ModuleOutcome_MODULE_OUTCOME_SUCCEEDED
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		res.Outcome = pb.ModuleOutcome_MODULE_OUTCOME_CANCELED
+		res.Error = err.Error()
+	default:
+		res.Outcome = pb.ModuleOutcome_MODULE_OUTCOME_FAILED
+		res.Error = err.Error()
+	}
+}
+
+// result returns a snapshot of the report as a RunResult message.
+func (r *runReport) result() *pb.RunResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modules := make([]*pb.ModuleResult, 0, len(r.modules))
+	for _, res := range r.modules {
+		modules = append(modules, proto.CloneOf(res))
+	}
+
+	return &pb.RunResult{Modules: modules}
+}
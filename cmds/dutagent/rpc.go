@@ -13,6 +13,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/BlindspotSoftware/dutctl/internal/auth"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/jobs"
 	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
 	"github.com/BlindspotSoftware/dutctl/internal/fsm"
 	"github.com/BlindspotSoftware/dutctl/internal/keyword"
@@ -27,6 +28,8 @@ import (
 type rpcService struct {
 	devices dut.Devlist
 	locker  *locker.Locker
+	jobs    *jobs.Registry
+	power   powerLocks
 }
 
 // rpcLogger returns a logger scoped to the RPC subsystem and tagged with the
@@ -50,8 +53,10 @@ func caller(ctx context.Context) (auth.Identity, error) {
 // requireNamed rejects an anonymous (header-less) caller with CodeUnauthenticated.
 // Lock and a normal Unlock require a stable identity: an anonymous caller is
 // minted a fresh identity per request, so it could never release a lock it took.
-// Run and a forced Unlock do not call this — Run's auto-lock lives within one
-// request, and force-release is the cooperative escape hatch open to anyone.
+// The job RPCs require it for the same reason. Run and a forced Unlock do not
+// call this — Run's auto-lock lives within one request (a detached command,
+// which outlives it, is rejected in receiveCommandRPC instead), and
+// force-release is the cooperative escape hatch open to anyone.
 func requireNamed(id auth.Identity) error {
 	if id.IsAnonymous() {
 		return connect.NewError(connect.CodeUnauthenticated,
@@ -353,10 +358,10 @@ func clearAutoLock(ctx context.Context, lk *locker.Locker, device, user string)
 // Run is the handler for the Run RPC.
 //
 // It drives the finite state machine (see states.go); each state maps its failure
-// to a connect.Code. Run passes an already-typed *connect.Error through unchanged,
-// maps a raw context cancellation to CodeCanceled/CodeDeadlineExceeded (via
-// cancelCode), and wraps anything else as CodeInternal, so every failure reaches
-// the client with a code.
+// to a connect.Code, and runError types whatever is left, so every failure reaches
+// the client with a code. A detached command continues as a job after the Run
+// ends; the Run itself returns the job's outcome only if the client stays
+// attached until the job finished.
 func (a *rpcService) Run(
 	ctx context.Context,
 	stream *connect.BidiStream[pb.RunRequest, pb.RunResponse],
@@ -392,25 +397,90 @@ func (a *rpcService) Run(
 		stream:     rpc.NewRunStream(stream),
 		deviceList: a.devices,
 		locker:     a.locker,
+		jobs:       a.jobs,
 		user:       user,
+		anonymous:  identity.IsAnonymous(),
 		autoLock:   autoLock,
 	}
 
 	_, err = fsm.Run(ctx, fsmArgs, receiveCommandRPC)
+	err = runError(err)
 
-	var connectErr *connect.Error
-	if err != nil && !errors.As(err, &connectErr) {
-		// Wrap the error in a connect.Error if not done yet. A raw context error
-		// from the FSM boundary becomes the matching cancellation code (kept in
-		// sync with waitModules via cancelCode); anything else is internal.
-		switch {
-		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
-			err = connect.NewError(cancelCode(err), err)
-		default:
-			err = connect.NewError(connect.CodeInternal, err)
-		}
+	if err != nil {
+		l.Error("request finished with error", "err", err)
+	} else {
+		l.Info("request finished successfully")
 	}
 
+	return err
+}
+
+// jobError maps an error of the job registry to its connect code: CodeNotFound
+// for an unknown job (jobs.ErrNotFound), CodePermissionDenied for another user's
+// job (jobs.ErrWrongOwner), CodeFailedPrecondition for a job that already
+// finished (jobs.ErrNotRunning), and CodeInternal otherwise.
+func jobError(id string, err error) error {
+	code := connect.CodeInternal
+
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		code = connect.CodeNotFound
+	case errors.Is(err, jobs.ErrWrongOwner):
+		code = connect.CodePermissionDenied
+	case errors.Is(err, jobs.ErrNotRunning):
+		code = connect.CodeFailedPrecondition
+	}
+
+	return connect.NewError(code, fmt.Errorf("job %q: %w", id, err))
+}
+
+// Attach is the handler for the Attach RPC. It attaches the stream to a job
+// started by a detached command, replaying the job's output so far, and ends
+// with the job's outcome once the job finished. Only the user who started the
+// job may attach to it.
+//
+// Errors: CodeUnauthenticated for an anonymous caller; for a failed first
+// receive, as in receiveCommandRPC; CodeInvalidArgument if the first message does
+// not name a job; as in jobError for an unknown job or another user's job; and
+// as in attachJob.
+func (a *rpcService) Attach(
+	ctx context.Context,
+	stream *connect.BidiStream[pb.AttachRequest, pb.AttachResponse],
+) error {
+	identity, err := caller(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = requireNamed(identity)
+	if err != nil {
+		return err
+	}
+
+	user := identity.User()
+
+	ctx = log.With(log.WithScope(ctx, "rpc"), "rpc", "Attach", "user", user)
+	l := log.FromContext(ctx)
+	l.Info("request received")
+
+	req, err := stream.Receive()
+	if err != nil {
+		return receiveError(err)
+	}
+
+	id := req.GetJob()
+	if id == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("first attach request must name a job"))
+	}
+
+	job, err := a.jobs.Get(id, user)
+	if err != nil {
+		return jobError(id, err)
+	}
+
+	l.Info("attaching to job", "job", id)
+
+	_, _, err = attachJob(ctx, runCmdArgs{stream: rpc.NewAttachStream(stream), job: job})
 	if err != nil {
 		l.Error("request finished with error", "err", err)
 	} else {
@@ -419,3 +489,93 @@ func (a *rpcService) Run(
 
 	return err
 }
+
+// Jobs is the handler for the Jobs RPC. It lists the jobs of all users, like List
+// shows the locks of all users. It never returns an error.
+func (a *rpcService) Jobs(
+	ctx context.Context,
+	_ *connect.Request[pb.JobsRequest],
+) (*connect.Response[pb.JobsResponse], error) {
+	l := rpcLogger(ctx, "Jobs")
+	l.Info("request received")
+
+	list := a.jobs.List()
+	infos := make([]*pb.JobInfo, 0, len(list))
+
+	for _, job := range list {
+		info := &pb.JobInfo{
+			Id:        job.ID,
+			Owner:     job.Owner,
+			Device:    job.Device,
+			Command:   job.Command,
+			Args:      job.Args,
+			State:     jobState(job.State),
+			CreatedAt: job.CreatedAt.Unix(),
+			Attached:  job.Attached,
+		}
+
+		if job.Err != nil {
+			info.Error = job.Err.Error()
+		}
+
+		if !job.FinishedAt.IsZero() {
+			info.FinishedAt = job.FinishedAt.Unix()
+		}
+
+		infos = append(infos, info)
+	}
+
+	l.Info("request finished")
+
+	return connect.NewResponse(&pb.JobsResponse{Jobs: infos}), nil
+}
+
+// jobState maps a job's state to its wire representation.
+func jobState(state jobs.State) pb.JobState {
+	switch state {
+	case jobs.Running:
+		return pb.JobState_JOB_STATE_RUNNING
+	case jobs.Succeeded:
+		return pb.JobState_JOB_STATE_SUCCEEDED
+	case jobs.Failed:
+		return pb.JobState_JOB_STATE_FAILED
+	case jobs.Canceled:
+		return pb.JobState_JOB_STATE_CANCELED
+	default:
+		return pb.JobState_JOB_STATE_UNSPECIFIED
+	}
+}
+
+// CancelJob is the handler for the CancelJob RPC. It cancels a running job; the
+// job's modules see their context cancelled and the job finishes once they
+// returned. Only the user who started the job may cancel it.
+//
+// Errors: CodeUnauthenticated for an anonymous caller; as in jobError otherwise.
+func (a *rpcService) CancelJob(
+	ctx context.Context,
+	req *connect.Request[pb.CancelJobRequest],
+) (*connect.Response[pb.CancelJobResponse], error) {
+	l := rpcLogger(ctx, "CancelJob")
+	l.Info("request received")
+
+	identity, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = requireNamed(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	id := req.Msg.GetJob()
+
+	err = a.jobs.Cancel(id, identity.User())
+	if err != nil {
+		return nil, jobError(id, err)
+	}
+
+	l.Info("job canceled", "job", id)
+
+	return connect.NewResponse(&pb.CancelJobResponse{}), nil
+}
@@ -10,7 +10,9 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/BlindspotSoftware/dutctl/internal/auth"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/jobs"
 	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/session"
 	"github.com/BlindspotSoftware/dutctl/pkg/dut"
 
 	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
@@ -20,6 +22,7 @@ func newTestService() *rpcService {
 	return &rpcService{
 		devices: dut.Devlist{"devA": dut.Device{}, "otherDev": dut.Device{}},
 		locker:  locker.New(),
+		jobs:    jobs.New(context.Background()),
 	}
 }
 
@@ -251,3 +254,77 @@ func TestListRPCExplicitShadowsAuto(t *testing.T) {
 		t.Error("expected explicit-slot expires_at to win, got 0")
 	}
 }
+
+// startBlockingJob starts a job for alice on devA that runs until it is cancelled.
+func startBlockingJob(svc *rpcService) *jobs.Job {
+	spec := jobs.Spec{Owner: "alice", Device: "devA", Command: "flash"}
+
+	return svc.jobs.Start(context.Background(), spec, func(ctx context.Context, _ session.Stream) error {
+		<-ctx.Done()
+
+		return context.Cause(ctx)
+	})
+}
+
+func TestJobsRPC(t *testing.T) {
+	svc := newTestService()
+	job := startBlockingJob(svc)
+
+	res, err := svc.Jobs(context.Background(), connect.NewRequest(&pb.JobsRequest{}))
+	if err != nil {
+		t.Fatalf("Jobs: %v", err)
+	}
+
+	list := res.Msg.GetJobs()
+	if len(list) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(list))
+	}
+
+	got := list[0]
+	if got.GetId() != job.ID() || got.GetOwner() != "alice" || got.GetDevice() != "devA" || got.GetCommand() != "flash" {
+		t.Errorf("job = %v, want %s of alice running flash on devA", got, job.ID())
+	}
+
+	if got.GetState() != pb.JobState_JOB_STATE_RUNNING || got.GetFinishedAt() != 0 {
+		t.Errorf("state = %v, finished_at = %d, want a running job", got.GetState(), got.GetFinishedAt())
+	}
+}
+
+func TestCancelJobRPC(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		job      string // empty: the started job
+		wantCode connect.Code
+	}{
+		{name: "owner", ctx: userCtx("alice")},
+		{name: "other_user", ctx: userCtx("bob"), wantCode: connect.CodePermissionDenied},
+		{name: "anonymous", ctx: anonCtx(), wantCode: connect.CodeUnauthenticated},
+		{name: "unknown_job", ctx: userCtx("alice"), job: "deadbeef", wantCode: connect.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService()
+			job := startBlockingJob(svc)
+
+			id := tt.job
+			if id == "" {
+				id = job.ID()
+			}
+
+			_, err := svc.CancelJob(tt.ctx, connect.NewRequest(&pb.CancelJobRequest{Job: id}))
+			if tt.wantCode != 0 {
+				if connect.CodeOf(err) != tt.wantCode {
+					t.Errorf("code = %v, want %v", connect.CodeOf(err), tt.wantCode)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("CancelJob: unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/jobs"
 	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
 	"github.com/BlindspotSoftware/dutctl/internal/dutagent/session"
 	"github.com/BlindspotSoftware/dutctl/internal/fsm"
@@ -36,16 +38,20 @@ type runCmdArgs struct {
 	stream     session.Stream
 	deviceList dut.Devlist
 	locker     *locker.Locker
+	jobs       *jobs.Registry
 	user       string
+	anonymous  bool
 	autoLock   *autoLockHold
 
 	// fields for the states used during execution
 	cmdMsg      *pb.Command
+	job         *jobs.Job
 	dev         dut.Device
 	cmd         dut.Command
 	session     module.Session
 	moduleErrCh chan error
 	brokerErrCh <-chan error
+	report      *runReport
 }
 
 // receiveCommandRPC is the first state of the Run RPC.
@@ -56,7 +62,9 @@ type runCmdArgs struct {
 //
 // Errors: for a failed receive, a context cancellation maps to
 // CodeCanceled/CodeDeadlineExceeded (via cancelCode) and anything else is
-// CodeAborted; CodeInvalidArgument if the first message is not a command.
+// CodeAborted; CodeInvalidArgument if the first message is not a command;
+// CodeUnauthenticated for a detached command of an anonymous caller, who could
+// never attach to the job again.
 func receiveCommandRPC(_ context.Context, args runCmdArgs) (runCmdArgs, fsm.State[runCmdArgs], error) {
 	req, err := args.stream.Receive()
 	if err != nil {
@@ -70,6 +78,13 @@ func receiveCommandRPC(_ context.Context, args runCmdArgs) (runCmdArgs, fsm.Stat
 		return args, nil, e
 	}
 
+	if cmdMsg.GetDetach() && args.anonymous {
+		e := connect.NewError(connect.CodeUnauthenticated,
+			errors.New("a detached command requires an identified caller; set the From header"))
+
+		return args, nil, e
+	}
+
 	args.cmdMsg = cmdMsg
 
 	return args, findDUTCmd, nil
@@ -138,7 +153,8 @@ func checkDeviceAccess(_ context.Context, args runCmdArgs) (runCmdArgs, fsm.Stat
 // It acquires the command-scoped auto-lock for the device. AutoLock is
 // idempotent for the same owner, so this is safe even if the same owner
 // already holds an auto-lock from a previous race-lost step. On success it
-// records the hold on args.autoLock so Run releases it on every exit path.
+// records the hold on args.autoLock so Run releases it on every exit path. A
+// detached command continues with startJob, any other with executeModules.
 //
 // Errors: CodeFailedPrecondition when another owner holds the device
 // (locker.ErrWrongOwner); CodeInternal otherwise.
@@ -157,9 +173,67 @@ func acquireAutoLock(_ context.Context, args runCmdArgs) (runCmdArgs, fsm.State[
 	args.autoLock.device = device
 	args.autoLock.held = true
 
+	if args.cmdMsg.GetDetach() {
+		return args, startJob, nil
+	}
+
 	return args, executeModules, nil
 }
 
+// startJob is a state of the Run RPC for a detached command.
+//
+// It hands the command over to a job, which runs the remaining states from
+// executeModules on independently of the Run stream, announces the job to the
+// client and continues to attach the stream to it. The job takes over the
+// auto-lock: it is released once the job finished, not when the Run ends.
+//
+// Errors: CodeAborted if the job cannot be announced to the client; the job
+// keeps running regardless.
+func startJob(ctx context.Context, args runCmdArgs) (runCmdArgs, fsm.State[runCmdArgs], error) {
+	device := args.autoLock.device
+	spec := jobs.Spec{
+		Owner:   args.user,
+		Device:  device,
+		Command: args.cmdMsg.GetCommand(),
+		Args:    args.cmdMsg.GetArgs(),
+	}
+
+	jobArgs := args
+
+	job := args.jobs.Start(ctx, spec, func(ctx context.Context, stream session.Stream) error {
+		defer clearAutoLock(ctx, jobArgs.locker, device, jobArgs.user)
+
+		jobArgs.stream = stream
+		_, err := fsm.Run(ctx, jobArgs, executeModules)
+
+		return runError(err)
+	})
+
+	args.autoLock.held = false
+	args.job = job
+
+	err := args.stream.Send(&pb.RunResponse{Msg: &pb.RunResponse_Job{Job: &pb.Job{Id: job.ID()}}})
+	if err != nil {
+		return args, nil, connect.NewError(connect.CodeAborted, fmt.Errorf("announcing job %s: %w", job.ID(), err))
+	}
+
+	return args, attachJob, nil
+}
+
+// attachJob is a state of the Run RPC for a detached command, and the whole of
+// the Attach RPC.
+//
+// It relays the job to the client until the job finished, returning the job's
+// outcome, or until the client went away, which leaves the job running.
+//
+// Errors: the job's error, which is typed by the job's states; for a client
+// that went away, as in attachError.
+func attachJob(ctx context.Context, args runCmdArgs) (runCmdArgs, fsm.State[runCmdArgs], error) {
+	err := args.job.Attach(ctx, args.stream)
+
+	return args, nil, attachError(ctx, err)
+}
+
 // runModule runs a single module, recovering a panic into an error so a
 // misbehaving module aborts only its run instead of crashing the agent. (The
 // session's Console invariant guards also panic).
@@ -177,7 +251,9 @@ func runModule(ctx context.Context, mod dut.Module, s module.Session, args ...st
 //
 // It starts the current command's modules in a separate goroutine and does not
 // wait for them to finish. It also starts worker goroutines that serve the
-// module-to-client communication during module execution.
+// module-to-client communication during module execution. A Cancel message from
+// the client cancels the modules' context and skips the remaining modules, while
+// the communication goes on until the modules returned.
 //
 // Errors: CodeInvalidArgument if the command's arguments cannot be resolved
 // (see Command.ModuleArgs). Module and broker failures surface later, in waitModules.
@@ -210,14 +286,37 @@ func executeModules(ctx context.Context, args runCmdArgs) (runCmdArgs, fsm.State
 		return args, nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
 
+	// cmdCtx is handed to the modules. Besides the RPC ending, it is cancelled
+	// when the client cancels the command; the broker keeps running meanwhile,
+	// so the modules' teardown output and the result still reach the client.
+	cmdCtx, cancelCmd := context.WithCancel(rpcCtx)
+
+	go func() {
+		select {
+		case <-broker.Canceled():
+			l.Info("command canceled by client")
+			cancelCmd()
+		case <-modCtx.Done():
+		}
+	}()
+
+	report := newRunReport(args.cmd.Modules)
+	args.report = report
+
 	// Run the modules in a goroutine.
 	// Termination of the module execution is signaled by closing the moduleErrCh channel.
 	go func() {
+		defer cancelCmd()
+
 		cnt := len(args.cmd.Modules)
 
 		for idx, mod := range args.cmd.Modules {
-			if ctx.Err() != nil {
-				l.Warn("execution aborted", "modules-done", idx, "modules-total", cnt, "err", ctx.Err())
+			if cmdCtx.Err() != nil {
+				l.Warn("execution aborted", "modules-done", idx, "modules-total", cnt, "err", cmdCtx.Err())
+				// Report the abort, so waitModules concludes a canceled command
+				// whose RPC is still alive; the remaining modules are skipped.
+				args.moduleErrCh <- cmdCtx.Err()
+
 				modCtxCancel()
 
 				return
@@ -230,9 +329,16 @@ func executeModules(ctx context.Context, args runCmdArgs) (runCmdArgs, fsm.State
 
 			// Set the "module" scope on the context handed to the module, so
 			// only the module's own records are scoped to it.
-			runCtx := log.With(log.WithScope(rpcCtx, "module"), "module", mod.Config.Name, "module-index", idx+1)
+			runCtx := log.With(log.WithScope(cmdCtx, "module"), "module", mod.Config.Name, "module-index", idx+1)
+
+			report.start(idx, time.Now())
 
 			err := runModule(runCtx, mod, moduleSession, moduleArgs[idx]...)
+
+			// Record the outcome before signalling it on moduleErrCh, so the
+			// report is final once waitModules observes the signal.
+			report.finish(idx, time.Now(), err)
+
 			if err != nil {
 				args.moduleErrCh <- err
 
@@ -296,18 +402,87 @@ func waitModules(ctx context.Context, args runCmdArgs) (runCmdArgs, fsm.State[ru
 				// Module channel closed = success
 				moduleDone = true
 			} else {
-				// Module only sends errors (never nil).
+				// Module only sends errors (never nil). The failed module
+				// cancelled the broker on its way out; once the workers are
+				// gone, report the failure to the client before the error ends
+				// the stream.
+				if brokerDone || awaitBroker(ctx, args.brokerErrCh) {
+					sendResult(ctx, args)
+				}
+
 				return args, nil, moduleError(moduleErr)
 			}
 		}
 	}
 
+	sendResult(ctx, args)
+
 	// Success: the auto-lock is released by the deferred cleanup in Run, which
 	// covers every exit path including a panic, so no explicit release state is
 	// needed here.
 	return args, nil, nil
 }
 
+// awaitBroker waits until the broker's workers have stopped, reporting whether
+// they stopped cleanly. It gives up (false) on a broker error or when ctx is done.
+func awaitBroker(ctx context.Context, brokerErrCh <-chan error) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case _, ok := <-brokerErrCh:
+		// The broker only sends errors (never nil); closure means a clean stop.
+		return !ok
+	}
+}
+
+// sendResult concludes the Run with the RunResult message reporting every
+// module's outcome. It must only be called once the broker's workers have
+// stopped, as they are the stream's sender until then. A failed send is logged
+// rather than returned: the outcome of the run itself is unaffected by it.
+func sendResult(ctx context.Context, args runCmdArgs) {
+	if args.report == nil {
+		return
+	}
+
+	err := args.stream.Send(&pb.RunResponse{
+		Msg: &pb.RunResponse_Result{Result: args.report.result()},
+	})
+	if err != nil {
+		log.FromContext(ctx).Warn("failed to send run result", "err", err)
+	}
+}
+
+// runError types an error that ends the Run FSM: an already-typed
+// *connect.Error passes through unchanged, a raw context cancellation maps via
+// cancelCode (kept in sync with waitModules), and anything else is CodeInternal,
+// so every failure reaches the client with a code.
+func runError(err error) error {
+	var connectErr *connect.Error
+
+	switch {
+	case err == nil, errors.As(err, &connectErr):
+		return err
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(cancelCode(err), err)
+	default:
+		return connect.NewError(connect.CodeInternal, err)
+	}
+}
+
+// attachError maps the error that ends an attachment to a job: a takeover by
+// another client (jobs.ErrTakenOver) is CodeAborted and a client that went away
+// maps via cancelCode; the job's own outcome passes through unchanged.
+func attachError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrTakenOver):
+		return connect.NewError(connect.CodeAborted, err)
+	case ctx.Err() != nil:
+		return connect.NewError(cancelCode(ctx.Err()), fmt.Errorf("detached from job: %w", ctx.Err()))
+	default:
+		return err
+	}
+}
+
 // cancelCode maps a context cancellation error to its connect status code,
 // defaulting to CodeCanceled. It is used at every site that converts a cancelled
 // Run to a wire status, so cancellation maps to a single code across the RPC.
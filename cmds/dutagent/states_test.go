@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/jobs"
 	"github.com/BlindspotSoftware/dutctl/internal/dutagent/locker"
 	"github.com/BlindspotSoftware/dutctl/internal/fsm"
 	"github.com/BlindspotSoftware/dutctl/internal/test/fakes"
@@ -28,6 +29,7 @@ func TestReceiveCommandRPC(t *testing.T) {
 		name        string
 		recv        []*pb.RunRequest
 		recvErr     error
+		anonymous   bool
 		wantErrCode connect.Code
 		wantNext    fsm.State[runCmdArgs]
 		wantCmd     *pb.Command
@@ -48,6 +50,18 @@ func TestReceiveCommandRPC(t *testing.T) {
 			recv:        []*pb.RunRequest{{Msg: &pb.RunRequest_Console{Console: &pb.Console{Data: &pb.Console_Stdout{Stdout: []byte("hi")}}}}},
 			wantErrCode: connect.CodeInvalidArgument,
 		},
+		{
+			name:     "detached_command_named_caller",
+			recv:     []*pb.RunRequest{{Msg: &pb.RunRequest_Command{Command: &pb.Command{Device: "devA", Command: "cmdX", Detach: true}}}},
+			wantNext: findDUTCmd,
+			wantCmd:  &pb.Command{Device: "devA", Command: "cmdX", Detach: true},
+		},
+		{
+			name:        "detached_command_anonymous_caller",
+			recv:        []*pb.RunRequest{{Msg: &pb.RunRequest_Command{Command: &pb.Command{Device: "devA", Command: "cmdX", Detach: true}}}},
+			anonymous:   true,
+			wantErrCode: connect.CodeUnauthenticated,
+		},
 	}
 
 	for _, tt := range tests {
@@ -55,6 +69,7 @@ func TestReceiveCommandRPC(t *testing.T) {
 			fake := &fakes.FakeStream{RecvQueue: tt.recv, RecvErr: tt.recvErr}
 			args := runCmdArgs{
 				stream:      fake,
+				anonymous:   tt.anonymous,
 				moduleErrCh: make(chan error, 1),
 			}
 
@@ -283,6 +298,20 @@ func TestAcquireAutoLock(t *testing.T) {
 		}
 	})
 
+	t.Run("detached_proceeds_to_startJob", func(t *testing.T) {
+		detached := &pb.Command{Device: device, Command: "echo", Detach: true}
+		args := runCmdArgs{cmdMsg: detached, locker: locker.New(), user: "alice", autoLock: &autoLockHold{}}
+
+		_, next, err := acquireAutoLock(context.Background(), args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !stateEqual(next, startJob) {
+			t.Fatalf("next state = %p, want startJob", next)
+		}
+	})
+
 	t.Run("blocked_by_other_owner_returns_FailedPrecondition", func(t *testing.T) {
 		l := locker.New()
 		if _, err := l.AutoLock(device, "bob"); err != nil {
@@ -700,3 +729,201 @@ func TestWaitModules(t *testing.T) {
 		})
 	}
 }
+
+func TestWaitModulesSendsResult(t *testing.T) {
+	modules := make([]dut.Module, 3)
+	for i, name := range []string{"power", "serial", "flash"} {
+		modules[i].Config.Name = name
+	}
+
+	start := time.UnixMilli(1_700_000_000_000)
+
+	report := newRunReport(modules)
+	report.start(0, start)
+	report.finish(0, start.Add(1500*time.Millisecond), nil)
+	report.start(1, start.Add(1500*time.Millisecond))
+	report.finish(1, start.Add(2*time.Second), errors.New("expect timed out"))
+
+	stream := &fakes.FakeStream{}
+	moduleErrCh := make(chan error, 1)
+	brokerErrCh := make(chan error, 1)
+	moduleErrCh <- errors.New("expect timed out")
+	close(moduleErrCh)
+	close(brokerErrCh)
+
+	args := runCmdArgs{stream: stream, report: report, moduleErrCh: moduleErrCh, brokerErrCh: brokerErrCh}
+
+	_, _, err := waitModules(context.Background(), args)
+	if connect.CodeOf(err) != connect.CodeAborted {
+		t.Fatalf("expected connect code %v, got %v", connect.CodeAborted, err)
+	}
+
+	if len(stream.Sent) != 1 || stream.Sent[0].GetResult() == nil {
+		t.Fatalf("expected a single RunResult message, got %v", stream.Sent)
+	}
+
+	want := []struct {
+		outcome  pb.ModuleOutcome
+		duration int64
+		err      string
+	}{
+		{pb.ModuleOutcome_MODULE_OUTCOME_SUCCEEDED, 1500, ""},
+		{pb.ModuleOutcome_MODULE_OUTCOME_FAILED, 500, "expect timed out"},
+		{pb.ModuleOutcome_MODULE_OUTCOME_SKIPPED, 0, ""},
+	}
+
+	got := stream.Sent[0].GetResult().GetModules()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d module results, got %d", len(want), len(got))
+	}
+
+	for i, w := range want {
+		if got[i].GetIndex() != int32(i+1) || got[i].GetName() != modules[i].Config.Name {
+			t.Errorf("module %d: got index %d name %q", i, got[i].GetIndex(), got[i].GetName())
+		}
+
+		if got[i].GetOutcome() != w.outcome || got[i].GetDurationMs() != w.duration || got[i].GetError() != w.err {
+			t.Errorf("module %d: got %v/%dms/%q, want %v/%dms/%q", i,
+				got[i].GetOutcome(), got[i].GetDurationMs(), got[i].GetError(), w.outcome, w.duration, w.err)
+		}
+	}
+}
+
+func TestStartJob(t *testing.T) {
+	const device = "dev1"
+
+	mod := &dummyModule{}
+	wrap := dut.Module{}
+	wrap.Config.Name = "mod"
+	wrap.Config.Passthrough = true
+	wrap.Module = mod
+
+	l := locker.New()
+	if _, err := l.AutoLock(device, "alice"); err != nil {
+		t.Fatalf("setup AutoLock: %v", err)
+	}
+
+	hold := &autoLockHold{device: device, held: true}
+	stream := &fakes.FakeStream{}
+	args := runCmdArgs{
+		stream:   stream,
+		locker:   l,
+		jobs:     jobs.New(context.Background()),
+		user:     "alice",
+		autoLock: hold,
+		cmdMsg:   &pb.Command{Device: device, Command: "cmd", Args: []string{"a"}, Detach: true},
+		cmd:      dut.Command{Modules: []dut.Module{wrap}},
+	}
+
+	args, next, err := startJob(context.Background(), args)
+	if err != nil {
+		t.Fatalf("startJob: unexpected error: %v", err)
+	}
+
+	if !stateEqual(next, attachJob) {
+		t.Fatalf("next state = %p, want attachJob", next)
+	}
+
+	if hold.held {
+		t.Error("auto-lock hold still recorded on the request, want it handed over to the job")
+	}
+
+	// The client stays attached (its side of the stream is closed, which does not
+	// detach it), so attachJob returns once the job finished.
+	_, _, err = attachJob(context.Background(), args)
+	if err != nil {
+		t.Fatalf("attachJob: unexpected error: %v", err)
+	}
+
+	if len(stream.Sent) < 2 {
+		t.Fatalf("expected the job announcement and a result, got %v", stream.Sent)
+	}
+
+	if id := stream.Sent[0].GetJob().GetId(); id != args.job.ID() {
+		t.Errorf("first message announces job %q, want %q", id, args.job.ID())
+	}
+
+	if stream.Sent[len(stream.Sent)-1].GetResult() == nil {
+		t.Errorf("last message = %v, want the run result", stream.Sent[len(stream.Sent)-1])
+	}
+
+	if mod.runCalls != 1 || len(mod.runArgs) != 1 || mod.runArgs[0] != "a" {
+		t.Errorf("module ran %d times with %v, want once with [a]", mod.runCalls, mod.runArgs)
+	}
+
+	if info := args.job.Info(); info.State != jobs.Succeeded {
+		t.Errorf("job state = %v, want succeeded", info.State)
+	}
+
+	if _, locked := l.StatusAll()[device]; locked {
+		t.Error("auto-lock still held after the job finished")
+	}
+}
+
+// blockingModule runs until its context is cancelled. It closes started once it runs.
+type blockingModule struct {
+	dummyModule
+
+	started chan struct{}
+}
+
+func (m *blockingModule) Run(ctx context.Context, _ module.Session, _ ...string) error {
+	close(m.started)
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+// gatedStream holds back the client's messages until gate is closed.
+type gatedStream struct {
+	*fakes.FakeStream
+
+	gate <-chan struct{}
+}
+
+func (s gatedStream) Receive() (*pb.RunRequest, error) {
+	<-s.gate
+
+	return s.FakeStream.Receive()
+}
+
+func TestClientCancel(t *testing.T) {
+	blocking := &blockingModule{started: make(chan struct{})}
+
+	modules := make([]dut.Module, 2)
+	modules[0].Config.Name = "wait"
+	modules[0].Module = blocking
+	modules[1].Config.Name = "after"
+	modules[1].Module = &dummyModule{}
+
+	// The client cancels once the first module runs.
+	stream := &fakes.FakeStream{RecvQueue: []*pb.RunRequest{{Msg: &pb.RunRequest_Cancel{Cancel: &pb.Cancel{}}}}}
+	args := runCmdArgs{
+		stream: gatedStream{FakeStream: stream, gate: blocking.started},
+		cmdMsg: &pb.Command{Device: "devX", Command: "cmdY"},
+		cmd:    dut.Command{Modules: modules},
+	}
+
+	args, _, err := executeModules(context.Background(), args)
+	if err != nil {
+		t.Fatalf("executeModules: unexpected error: %v", err)
+	}
+
+	_, _, err = waitModules(context.Background(), args)
+	if connect.CodeOf(err) != connect.CodeCanceled {
+		t.Fatalf("expected connect code %v, got %v", connect.CodeCanceled, err)
+	}
+
+	if len(stream.Sent) != 1 || stream.Sent[0].GetResult() == nil {
+		t.Fatalf("expected a single RunResult message, got %v", stream.Sent)
+	}
+
+	got := stream.Sent[0].GetResult().GetModules()
+	want := []pb.ModuleOutcome{pb.ModuleOutcome_MODULE_OUTCOME_CANCELED, pb.ModuleOutcome_MODULE_OUTCOME_SKIPPED}
+
+	for i, w := range want {
+		if got[i].GetOutcome() != w {
+			t.Errorf("module %d: outcome %v, want %v", i, got[i].GetOutcome(), w)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/term"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// escapeKey is the key detaching from a console session in raw mode (Ctrl-]).
+// Every other key is passed on to the command.
+const escapeKey = 0x1d
+
+// stdinChunkSize bounds the input read from stdin and sent in a single message.
+const stdinChunkSize = 4096
+
+// escapeHint is printed when the terminal is switched to raw mode.
+const escapeHint = "console attached, press Ctrl-] to detach"
+
+// errNotTerminal is returned by makeRaw if the file is not a terminal. It is
+// matched by console.open, which leaves such input as it is.
+var errNotTerminal = errors.New("not a terminal")
+
+// console switches the local terminal to raw mode when the agent opens a console
+// session, and keeps the agent informed about the size of the terminal while it
+// is in raw mode. If stdin is not a terminal, opening the console has no effect.
+type console struct {
+	stdin  io.Reader
+	stream runRequestSender
+	// hint tells the user how to detach.
+	hint func(msg string)
+	// crlf, if set, is true while the terminal is in raw mode. It switches the
+	// crlfWriters of the output on.
+	crlf *atomic.Bool
+
+	mu    sync.Mutex
+	state *term.State // the terminal settings to restore, nil if not in raw mode.
+	fd    int
+	stop  context.CancelFunc
+}
+
+// open switches the terminal to raw mode, sends its size to the agent and keeps
+// sending it whenever the terminal is resized. It is a no-op if the console is
+// already open or stdin is not a terminal. The returned error is opaque.
+func (c *console) open() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state != nil {
+		return nil
+	}
+
+	file, ok := c.stdin.(*os.File)
+	if !ok {
+		return nil
+	}
+
+	fd := int(file.Fd())
+
+	state, err := makeRaw(fd)
+	if errors.Is(err, errNotTerminal) {
+		slog.Debug("stdin is not a terminal, keeping line mode")
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("switching terminal to raw mode: %w", err)
+	}
+
+	c.state, c.fd = state, fd
+	c.setCRLF(true)
+	c.hint(escapeHint)
+
+	ctx, stop := context.WithCancel(context.Background())
+	c.stop = stop
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+
+	go func() {
+		defer signal.Stop(resized)
+
+		for {
+			err := c.sendWindowSize(fd)
+			if err != nil {
+				slog.Debug("window size not sent", "err", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-resized:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// raw reports whether the terminal is in raw mode.
+func (c *console) raw() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state != nil
+}
+
+// restore switches the terminal back to the mode it was in before open. It is
+// safe to call if the console was never opened.
+func (c *console) restore() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == nil {
+		return
+	}
+
+	c.stop()
+
+	err := term.Restore(c.fd, c.state)
+	if err != nil {
+		slog.Warn("restoring terminal mode failed", "err", err)
+	}
+
+	c.state = nil
+	c.setCRLF(false)
+}
+
+// setCRLF switches the translation of newlines in the output on or off.
+func (c *console) setCRLF(on bool) {
+	if c.crlf != nil {
+		c.crlf.Store(on)
+	}
+}
+
+// sendWindowSize sends the size of the terminal fd to the agent, unless the
+// terminal does not know its size.
+func (c *console) sendWindowSize(fd int) error {
+	cols, rows, err := term.GetSize(fd)
+	if err != nil {
+		return err
+	}
+
+	if rows <= 0 || cols <= 0 {
+		return nil
+	}
+
+	return c.stream.Send(&pb.RunRequest{
+		Msg: &pb.RunRequest_Console{
+			Console: &pb.Console{
+				Data: &pb.Console_WindowSize{
+					WindowSize: &pb.WindowSize{Rows: uint32(rows), Cols: uint32(cols)},
+				},
+			},
+		},
+	})
+}
+
+// makeRaw switches the terminal fd to raw mode and returns its former settings.
+// Input is passed on byte by byte without echo and without generating signals, so
+// control keys reach the command. The terminal does no processing of its own:
+// rawInput and crlfWriter take care of line endings. It returns errNotTerminal if
+// fd is not a terminal.
+func makeRaw(fd int) (*term.State, error) {
+	if !term.IsTerminal(fd) {
+		return nil, errNotTerminal
+	}
+
+	return term.MakeRaw(fd)
+}
+
+// rawInput returns the part of the input read from a raw terminal that is to be
+// passed on to the command, and whether the input contained the escape key. As
+// in line mode, the carriage return of the Enter key is passed on as a newline,
+// so line-oriented modules keep working.
+func rawInput(input []byte) ([]byte, bool) {
+	input, escape := splitEscape(input)
+
+	for i, b := range input {
+		if b == '\r' {
+			input[i] = '\n'
+		}
+	}
+
+	return input, escape
+}
+
+// splitEscape returns the part of the input read from a raw terminal that is
+// to be passed on to the command, and whether the input contained the escape key.
+// Everything from the escape key on is dropped.
+func splitEscape(input []byte) ([]byte, bool) {
+	i := bytes.IndexByte(input, escapeKey)
+	if i < 0 {
+		return input, false
+	}
+
+	return input[:i], true
+}
+
+// crlfWriter translates newlines to CR LF while raw is true. A terminal in raw
+// mode does no output processing, so without it the output of the command and
+// dutctl's own messages would not return to the start of the line.
+type crlfWriter struct {
+	w   io.Writer
+	raw *atomic.Bool
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	if !c.raw.Load() || bytes.IndexByte(p, '\n') < 0 {
+		return c.w.Write(p)
+	}
+
+	_, err := c.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n")))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyResize is a no-op on platforms without SIGWINCH. The size of the
+// terminal is sent once when the console is opened.
+func notifyResize(chan<- os.Signal) {}
@@ -0,0 +1,96 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSplitEscape(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		escape bool
+	}{
+		{name: "no escape", input: "ls -l\r", want: "ls -l\r"},
+		{name: "control keys", input: "\x03\x04\x1b[A", want: "\x03\x04\x1b[A"},
+		{name: "escape only", input: "\x1d", want: "", escape: true},
+		{name: "escape after input", input: "abc\x1ddef", want: "abc", escape: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, escape := splitEscape([]byte(tt.input))
+			if string(got) != tt.want || escape != tt.escape {
+				t.Errorf("splitEscape(%q) = %q, %v, want %q, %v", tt.input, got, escape, tt.want, tt.escape)
+			}
+		})
+	}
+}
+
+func TestRawInput(t *testing.T) {
+	got, escape := rawInput([]byte("ls -l\rpwd\r\x1dexit\r"))
+	if string(got) != "ls -l\npwd\n" || !escape {
+		t.Errorf("rawInput = %q, %v, want %q, true", got, escape, "ls -l\npwd\n")
+	}
+}
+
+func TestCRLFWriter(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		raw atomic.Bool
+	)
+
+	w := &crlfWriter{w: &buf, raw: &raw}
+
+	n, err := w.Write([]byte("line mode\n"))
+	if err != nil || n != 10 {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	raw.Store(true)
+
+	n, err = w.Write([]byte("raw\nmode\n"))
+	if err != nil || n != 9 {
+		t.Fatalf("Write = %d, %v, want the length of the input", n, err)
+	}
+
+	if want := "line mode\nraw\r\nmode\r\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestConsoleWithoutTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+	defer w.Close()
+
+	_, err = makeRaw(int(r.Fd()))
+	if !errors.Is(err, errNotTerminal) {
+		t.Fatalf("makeRaw on a pipe: err = %v, want errNotTerminal", err)
+	}
+
+	cons := &console{stdin: r, hint: func(string) { t.Error("unexpected hint") }}
+
+	err = cons.open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	if cons.raw() {
+		t.Error("console in raw mode without a terminal")
+	}
+
+	cons.restore()
+}
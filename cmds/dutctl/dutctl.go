@@ -15,6 +15,7 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 
 	"connectrpc.com/connect"
@@ -37,6 +38,10 @@ SYNOPSIS:
 	dutctl [options] <device> <command> help
 	dutctl [options] <device> lock [duration]
 	dutctl [options] <device> unlock [force]
+	dutctl [options] <device> power [on|off|cycle [off-time]|status]
+	dutctl [options] jobs
+	dutctl [options] attach <job>
+	dutctl [options] cancel <job>
 	dutctl version
 
 `
@@ -56,6 +61,26 @@ The lock command reserves a device for the current user for an optional duration
 releases it; add the force keyword to release a lock held by another user.
 Locks are advisory, so reserve a device only as long as you need it.
 
+The power command switches a device on or off through the power controller
+configured for it on the agent, whatever the hardware behind. cycle switches it
+off and on again, keeping it off for the given off-time (e.g. 10s) or the one
+configured for the device. status, or no argument, reports the power state.
+Switching the power is subject to locks like running a command.
+
+With the -d option a command runs as a detached job: the agent prints a job ID
+and keeps the command running when dutctl exits or the connection drops. The
+jobs command lists the jobs on the agent, attach reattaches to a job of the
+current user, replaying its output so far, and cancel stops a job. Ctrl-C while
+attached to a job only detaches from it.
+
+Otherwise, Ctrl-C while a command runs is passed on to the command as an
+interrupt. A second Ctrl-C cancels the command, which still reports its result,
+and a third one aborts dutctl. Ctrl-\ is passed on as a quit signal, and the end
+of the input (Ctrl-D) as an end-of-file signal. If the command opens an
+interactive console and dutctl runs in a terminal, the terminal is switched to
+raw mode, which passes every key on to the console, Ctrl-C included, until you
+detach with Ctrl-].
+
 When dutctl is run without any positional arguments, it defaults to the list command.
 `
 
@@ -67,13 +92,16 @@ const (
 	noColorUsage      = `Disable colored output`
 	userUsage         = `User Identity of the user of the device, defaults to <user>@<host>`
 	logUsage          = `Client-side diagnostic logging (on stderr), debug|warn|none, default is warn`
+	detachUsage       = `Run the command as a detached job that keeps running on the agent when dutctl exits`
 )
 
 func newApp(stdin io.Reader, stdout, stderr io.Writer, exitFunc func(int), args []string) *application {
 	var app application
 
-	app.stdout = stdout
-	app.stderr = stderr
+	// While a console session holds the terminal in raw mode, everything written
+	// to it needs CR LF line endings.
+	app.stdout = &crlfWriter{w: stdout, raw: &app.rawTerminal}
+	app.stderr = &crlfWriter{w: stderr, raw: &app.rawTerminal}
 	app.stdin = stdin
 	app.exitFunc = exitFunc
 
@@ -94,6 +122,7 @@ func newApp(stdin io.Reader, stdout, stderr io.Writer, exitFunc func(int), args
 	fs.BoolVar(&app.verbose, "v", false, verboseUsage)
 	fs.BoolVar(&app.noColor, "no-color", false, noColorUsage)
 	fs.StringVar(&app.user, "u", auth.Default().User(), userUsage)
+	fs.BoolVar(&app.detach, "d", false, detachUsage)
 
 	mode := logModeWarn
 	fs.Var(&mode, "log", logUsage)
@@ -109,13 +138,13 @@ func newApp(stdin io.Reader, stdout, stderr io.Writer, exitFunc func(int), args
 	// Color is suppressed unless -no-color is unset AND the target stream is a
 	// terminal, so redirected/piped output stays free of ANSI escapes. The log
 	// handler is gated on stderr; the formatter's content on stdout.
-	app.logHandler = newCLIHandler(stderr, mode, !app.noColor && isTerminal(stderr))
+	app.logHandler = newCLIHandler(app.stderr, mode, !app.noColor && isTerminal(stderr))
 	slog.SetDefault(slog.New(app.logHandler))
 
 	// Setup output formatter
 	app.formatter = output.New(output.Config{
-		Stdout:  stdout,
-		Stderr:  stderr,
+		Stdout:  app.stdout,
+		Stderr:  app.stderr,
 		Format:  app.outputFormat,
 		Verbose: app.verbose,
 		NoColor: app.noColor || !isTerminal(stdout),
@@ -136,12 +165,19 @@ type application struct {
 	verbose           bool
 	noColor           bool
 	user              string
+	detach            bool
 	args              []string
 	printFlagDefaults func()
 
 	// runtime services
 	rpcClient dutctlv1connect.DeviceServiceClient
 	formatter output.Formatter
+	// signalHandler, if set, takes over the signals caught during a run (see
+	// handleSignals).
+	signalHandler atomic.Pointer[signalHandler]
+	// rawTerminal is true while a console session holds the terminal in raw
+	// mode (see crlfWriter).
+	rawTerminal atomic.Bool
 	// logHandler is retained only so exit can call Flush: diagnostics are emitted
 	// via package-level slog (this handler is the process default), but the
 	// buffered warning summary must be flushed explicitly and Flush is not part
@@ -186,13 +222,46 @@ func (app *application) dispatch() error {
 	// the in-flight RPC so the client tears down cleanly (flushing the warning
 	// summary) instead of being killed. Every RPC path shares it — unary calls
 	// wrap it in a per-call timeout, while Run uses it directly, since a stream
-	// has no overall deadline.
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	// has no overall deadline. A run passes Ctrl-C and Ctrl-\ on to the command
+	// instead (see relayRun), so only the signals it does not take cancel ctx.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+
+	defer signal.Stop(signals)
+
+	go app.handleSignals(ctx, cancel, signals)
 
 	return asInterrupt(ctx, app.route(ctx))
 }
 
+// signalHandler takes over a signal dutctl caught. It reports whether it handled
+// the signal; a signal it does not handle ends the invocation.
+type signalHandler func(sig os.Signal) bool
+
+// handleSignals offers each caught signal to the installed signalHandler, if any,
+// and calls cancel on the first signal that is not handled. It returns then, or
+// once ctx is done.
+func (app *application) handleSignals(ctx context.Context, cancel context.CancelFunc, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			if handle := app.signalHandler.Load(); handle != nil && (*handle)(sig) {
+				continue
+			}
+
+			slog.Debug("terminating on signal", "signal", sig)
+			cancel()
+
+			return
+		}
+	}
+}
+
 // route selects and runs the RPC for app.args. It returns errInvalidCmdline for a
 // malformed command line, or the dispatched RPC's error.
 func (app *application) route(ctx context.Context) error {
@@ -208,6 +277,26 @@ func (app *application) route(ctx context.Context) error {
 		return app.listRPC(ctx)
 	}
 
+	switch app.args[0] {
+	case keyword.Jobs:
+		if len(app.args) > 1 {
+			return errInvalidCmdline
+		}
+
+		return app.jobsRPC(ctx)
+	case keyword.Attach, keyword.Cancel:
+		// Both take exactly one job ID.
+		if len(app.args) != 2 {
+			return errInvalidCmdline
+		}
+
+		if app.args[0] == keyword.Attach {
+			return app.attachRPC(ctx, app.args[1])
+		}
+
+		return app.cancelJobRPC(ctx, app.args[1])
+	}
+
 	if len(app.args) == 1 {
 		return app.commandsRPC(ctx, app.args[0])
 	}
@@ -219,7 +308,7 @@ func (app *application) route(ctx context.Context) error {
 // ctx was cancelled by a signal (Ctrl-C / SIGTERM), so exit() reports the
 // conventional "interrupted" status (exit 130) uniformly across every RPC. A
 // cancelled unary call otherwise surfaces a connect CodeCanceled error that reads
-// as a generic failure (exit 1); only runRPC maps its own teardown. A per-call
+// as a generic failure (exit 1); only relayRun maps its own teardown. A per-call
 // timeout does NOT cancel the signal context, so a genuine deadline stays a normal
 // error.
 func asInterrupt(ctx context.Context, err error) error {
@@ -231,8 +320,10 @@ func asInterrupt(ctx context.Context, err error) error {
 }
 
 // dispatchCommand handles the "<device> <command> [args...]" forms: the built-in
-// lock/unlock keywords, the help keyword, and otherwise a module run. It returns
-// errInvalidCmdline for a malformed invocation.
+// lock/unlock/power keywords, the help keyword, and otherwise a module run. It returns
+// errInvalidCmdline for a malformed invocation. A device without power controller
+// may have a command named power, which takes the place of the power keyword: the
+// agent rejects the power request with CodeUnimplemented and the command is run.
 func (app *application) dispatchCommand(ctx context.Context, device, command string, cmdArgs []string) error {
 	switch command {
 	case keyword.Lock:
@@ -250,6 +341,11 @@ func (app *application) dispatchCommand(ctx context.Context, device, command str
 		}
 
 		return app.unlockRPC(ctx, device, force)
+	case keyword.Power:
+		err := app.powerRPC(ctx, device, cmdArgs)
+		if connect.CodeOf(err) != connect.CodeUnimplemented {
+			return err
+		}
 	}
 
 	// help is a keyword only as the sole argument: "<device> <command> help".
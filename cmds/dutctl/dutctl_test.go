@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"slices"
 	"testing"
 
 	"connectrpc.com/connect"
@@ -18,7 +19,7 @@ import (
 
 // fakeDeviceServiceClient is a hand-written test double for
 // dutctlv1connect.DeviceServiceClient. Only the unary RPCs are
-// implemented; Run returns nil because the streaming path is not
+// implemented; Run and Attach return nil because the streaming paths are not
 // exercised in these tests.
 type fakeDeviceServiceClient struct {
 	listDevices []string
@@ -31,6 +32,12 @@ type fakeDeviceServiceClient struct {
 
 	unlockCalls []unlockCall
 
+	jobsCalls   int
+	cancelCalls []string
+
+	powerCalls []powerCall
+	powerErr   error // powerErr is returned by every Power call.
+
 	// respectCtx makes the unary methods return ctx.Err() when the received
 	// context is already done, mimicking how connect aborts a cancelled or
 	// expired call.
@@ -55,6 +62,12 @@ type unlockCall struct {
 	force  bool
 }
 
+type powerCall struct {
+	device    string
+	action    pb.PowerAction
+	offTimeMs int64
+}
+
 func (f *fakeDeviceServiceClient) List(
 	ctx context.Context, _ *connect.Request[pb.ListRequest],
 ) (*connect.Response[pb.ListResponse], error) {
@@ -145,6 +158,62 @@ func (f *fakeDeviceServiceClient) Unlock(
 	return connect.NewResponse(&pb.UnlockResponse{}), nil
 }
 
+func (f *fakeDeviceServiceClient) Attach(
+	_ context.Context,
+) *connect.BidiStreamForClient[pb.AttachRequest, pb.AttachResponse] {
+	return nil
+}
+
+func (f *fakeDeviceServiceClient) Jobs(
+	ctx context.Context, _ *connect.Request[pb.JobsRequest],
+) (*connect.Response[pb.JobsResponse], error) {
+	f.recordCtx(ctx)
+
+	if f.respectCtx && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	f.jobsCalls++
+
+	return connect.NewResponse(&pb.JobsResponse{}), nil
+}
+
+func (f *fakeDeviceServiceClient) CancelJob(
+	ctx context.Context, req *connect.Request[pb.CancelJobRequest],
+) (*connect.Response[pb.CancelJobResponse], error) {
+	f.recordCtx(ctx)
+
+	if f.respectCtx && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	f.cancelCalls = append(f.cancelCalls, req.Msg.GetJob())
+
+	return connect.NewResponse(&pb.CancelJobResponse{}), nil
+}
+
+func (f *fakeDeviceServiceClient) Power(
+	ctx context.Context, req *connect.Request[pb.PowerRequest],
+) (*connect.Response[pb.PowerResponse], error) {
+	f.recordCtx(ctx)
+
+	if f.respectCtx && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	f.powerCalls = append(f.powerCalls, powerCall{
+		device:    req.Msg.GetDevice(),
+		action:    req.Msg.GetAction(),
+		offTimeMs: req.Msg.GetOffTimeMs(),
+	})
+
+	if f.powerErr != nil {
+		return nil, f.powerErr
+	}
+
+	return connect.NewResponse(&pb.PowerResponse{Device: req.Msg.GetDevice()}), nil
+}
+
 // Compile-time assertion that the fake satisfies the interface.
 var _ dutctlv1connect.DeviceServiceClient = (*fakeDeviceServiceClient)(nil)
 
@@ -165,15 +234,21 @@ func newTestApp(t *testing.T, fake *fakeDeviceServiceClient, args ...string) *ap
 }
 
 func TestDispatch(t *testing.T) {
+	errNoPower := connect.NewError(connect.CodeFailedPrecondition, errors.New("no power controller configured"))
+
 	tests := []struct {
 		name         string
 		args         []string
 		listDevices  []string
+		powerErr     error
 		wantErrIs    error
 		wantListHit  int
 		wantCmdHits  []string
 		wantDetailHi []detailsCall
 		wantUnlock   []unlockCall
+		wantJobsHit  int
+		wantCancel   []string
+		wantPower    []powerCall
 	}{
 		{
 			name:        "no args defaults to list",
@@ -197,14 +272,14 @@ func TestDispatch(t *testing.T) {
 		},
 		{
 			name: "device command help calls details",
-			args: []string{"mydevice", "power", "help"},
+			args: []string{"mydevice", "flash", "help"},
 			wantDetailHi: []detailsCall{
-				{device: "mydevice", cmd: "power", keyword: "help"},
+				{device: "mydevice", cmd: "flash", keyword: "help"},
 			},
 		},
 		{
 			name:      "help with trailing arg is invalid",
-			args:      []string{"mydevice", "power", "help", "extra"},
+			args:      []string{"mydevice", "flash", "help", "extra"},
 			wantErrIs: errInvalidCmdline,
 		},
 		{
@@ -236,11 +311,77 @@ func TestDispatch(t *testing.T) {
 			args:      []string{"mydevice", "unlock", "force", "extra"},
 			wantErrIs: errInvalidCmdline,
 		},
+		{
+			name:      "power on",
+			args:      []string{"mydevice", "power", "on"},
+			wantPower: []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_ON}},
+		},
+		{
+			name:      "power without action queries the status",
+			args:      []string{"mydevice", "power"},
+			wantPower: []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_STATUS}},
+		},
+		{
+			name:      "power cycle with an off-time",
+			args:      []string{"mydevice", "power", "cycle", "10s"},
+			wantPower: []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_CYCLE, offTimeMs: 10000}},
+		},
+		{
+			name:      "power with an unknown action is invalid",
+			args:      []string{"mydevice", "power", "reset"},
+			wantErrIs: errInvalidCmdline,
+			wantPower: []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_UNSPECIFIED}},
+		},
+		{
+			name:      "power off with an off-time is invalid",
+			args:      []string{"mydevice", "power", "off", "10s"},
+			wantErrIs: errInvalidCmdline,
+			wantPower: []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_UNSPECIFIED}},
+		},
+		{
+			name:         "power command of a device without power controller",
+			args:         []string{"mydevice", "power", "help"},
+			powerErr:     connect.NewError(connect.CodeUnimplemented, errors.New("power is a command of the device")),
+			wantPower:    []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_UNSPECIFIED}},
+			wantDetailHi: []detailsCall{{device: "mydevice", cmd: "power", keyword: "help"}},
+		},
+		{
+			name:      "power of a device without power controller fails",
+			args:      []string{"mydevice", "power", "on"},
+			powerErr:  errNoPower,
+			wantPower: []powerCall{{device: "mydevice", action: pb.PowerAction_POWER_ACTION_ON}},
+			wantErrIs: errNoPower,
+		},
+		{
+			name:        "jobs lists jobs",
+			args:        []string{"jobs"},
+			wantJobsHit: 1,
+		},
+		{
+			name:      "jobs with extra args is invalid",
+			args:      []string{"jobs", "extra"},
+			wantErrIs: errInvalidCmdline,
+		},
+		{
+			name:       "cancel cancels a job",
+			args:       []string{"cancel", "3fa2c1d0"},
+			wantCancel: []string{"3fa2c1d0"},
+		},
+		{
+			name:      "cancel without a job is invalid",
+			args:      []string{"cancel"},
+			wantErrIs: errInvalidCmdline,
+		},
+		{
+			name:      "attach with two jobs is invalid",
+			args:      []string{"attach", "3fa2c1d0", "77e01b2c"},
+			wantErrIs: errInvalidCmdline,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fake := &fakeDeviceServiceClient{listDevices: tt.listDevices}
+			fake := &fakeDeviceServiceClient{listDevices: tt.listDevices, powerErr: tt.powerErr}
 			app := newTestApp(t, fake, tt.args...)
 
 			err := app.dispatch()
@@ -268,6 +409,18 @@ func TestDispatch(t *testing.T) {
 			if !equalUnlock(fake.unlockCalls, tt.wantUnlock) {
 				t.Errorf("Unlock calls: want %v, got %v", tt.wantUnlock, fake.unlockCalls)
 			}
+
+			if fake.jobsCalls != tt.wantJobsHit {
+				t.Errorf("Jobs calls: want %d, got %d", tt.wantJobsHit, fake.jobsCalls)
+			}
+
+			if !equalStrings(fake.cancelCalls, tt.wantCancel) {
+				t.Errorf("CancelJob calls: want %v, got %v", tt.wantCancel, fake.cancelCalls)
+			}
+
+			if !slices.Equal(fake.powerCalls, tt.wantPower) {
+				t.Errorf("Power calls: want %v, got %v", tt.wantPower, fake.powerCalls)
+			}
 		})
 	}
 }
@@ -289,6 +442,9 @@ func TestUnaryRPCsSetDeadline(t *testing.T) {
 		{"details", func() error { return app.detailsRPC(ctx, "dev", "cmd", "help") }},
 		{"lock", func() error { return app.lockRPC(ctx, "dev", nil) }},
 		{"unlock", func() error { return app.unlockRPC(ctx, "dev", false) }},
+		{"jobs", func() error { return app.jobsRPC(ctx) }},
+		{"cancel", func() error { return app.cancelJobRPC(ctx, "3fa2c1d0") }},
+		{"power", func() error { return app.powerRPC(ctx, "dev", []string{"cycle"}) }},
 	}
 
 	for _, c := range calls {
@@ -0,0 +1,159 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// fileChunkSize is the largest payload dutctl puts into a single File message
+// when uploading a file, so a file of any size is sent with bounded memory.
+const fileChunkSize = 64 * 1024
+
+// runRequestSender is the send half of the Run stream, the only part an upload needs.
+type runRequestSender interface {
+	Send(msg *pb.RunRequest) error
+}
+
+// uploadFile sends the file at path to the agent as a sequence of File chunks,
+// concluded by a final chunk carrying the SHA-256 digest of the whole file. It
+// returns the number of bytes sent, and an error if the file cannot be read or a
+// stream send fails.
+func uploadFile(stream runRequestSender, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+
+	digest := sha256.New()
+
+	for {
+		// Each chunk gets its own buffer rather than relying on Send having
+		// serialized the previous message.
+		buf := make([]byte, fileChunkSize)
+
+		n, err := io.ReadFull(file, buf)
+
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return offset, err
+		}
+
+		digest.Write(buf[:n])
+
+		chunk := &pb.File{
+			Path:    path,
+			Content: buf[:n],
+			Offset:  offset,
+			Size:    info.Size(),
+			More:    !last,
+		}
+
+		if last {
+			chunk.Sha256 = digest.Sum(nil)
+		}
+
+		err = stream.Send(&pb.RunRequest{Msg: &pb.RunRequest_File{File: chunk}})
+		if err != nil {
+			return offset, err
+		}
+
+		offset += int64(n)
+
+		if last {
+			return offset, nil
+		}
+	}
+}
+
+// download is a file the agent sends in chunks. The chunks are written to a
+// temporary file next to the destination as they arrive. Only once the final
+// chunk matches the announced size and digest is the temporary file renamed onto
+// the destination, so a download that fails or never completes leaves an existing
+// file at the destination untouched.
+type download struct {
+	path   string
+	file   *os.File
+	hash   hash.Hash
+	offset int64
+}
+
+// newDownload creates the temporary file receiving a download to path.
+func newDownload(path string) (*download, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return nil, err
+	}
+
+	return &download{path: path, file: file, hash: sha256.New()}, nil
+}
+
+// write stores a chunk of the download. It reports true once the final chunk
+// was written and verified and the file moved to its destination, and an error
+// for a chunk that does not belong to this download, does not continue it, cannot
+// be written, or concludes it with a size or digest mismatch. The caller discards
+// the download after an error.
+func (d *download) write(chunk *pb.File) (bool, error) {
+	if chunk.GetPath() != d.path {
+		return false, fmt.Errorf("received chunk of %q while receiving %q", chunk.GetPath(), d.path)
+	}
+
+	if chunk.GetOffset() != d.offset {
+		return false, fmt.Errorf("received chunk at offset %d, expected %d", chunk.GetOffset(), d.offset)
+	}
+
+	content := chunk.GetContent()
+
+	_, err := d.file.Write(content)
+	if err != nil {
+		return false, err
+	}
+
+	d.hash.Write(content)
+	d.offset += int64(len(content))
+
+	if chunk.GetMore() {
+		return false, nil
+	}
+
+	if size := chunk.GetSize(); size != 0 && size != d.offset {
+		return false, fmt.Errorf("received %d bytes, announced %d", d.offset, size)
+	}
+
+	if sum := chunk.GetSha256(); len(sum) > 0 && !bytes.Equal(sum, d.hash.Sum(nil)) {
+		return false, errors.New("checksum mismatch")
+	}
+
+	err = d.file.Close()
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.Rename(d.file.Name(), d.path)
+}
+
+// discard closes and removes the temporary file of an unfinished or failed
+// download. The destination is left as it was.
+func (d *download) discard() {
+	d.file.Close()
+	os.Remove(d.file.Name())
+}
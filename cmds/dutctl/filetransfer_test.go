@@ -0,0 +1,127 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+type recordingSender struct {
+	sent []*pb.File
+}
+
+func (r *recordingSender) Send(msg *pb.RunRequest) error {
+	r.sent = append(r.sent, msg.GetFile())
+
+	return nil
+}
+
+func TestUploadFileRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 10, fileChunkSize, 2*fileChunkSize + 17} {
+		content := bytes.Repeat([]byte{0xA5}, size)
+
+		src := filepath.Join(t.TempDir(), "image.bin")
+		if err := os.WriteFile(src, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var rec recordingSender
+
+		sent, err := uploadFile(&rec, src)
+		if err != nil {
+			t.Fatalf("size %d: uploadFile: %v", size, err)
+		}
+
+		if sent != int64(size) {
+			t.Fatalf("size %d: reported %d bytes sent", size, sent)
+		}
+
+		// Feed the chunks into a download, which verifies offsets, size and digest.
+		dst := filepath.Join(t.TempDir(), "copy.bin")
+
+		dl, err := newDownload(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, chunk := range rec.sent {
+			chunk.Path = dst
+
+			done, err := dl.write(chunk)
+			if err != nil {
+				t.Fatalf("size %d: chunk %d: %v", size, i, err)
+			}
+
+			if done != (i == len(rec.sent)-1) {
+				t.Fatalf("size %d: chunk %d of %d reported done=%v", size, i, len(rec.sent), done)
+			}
+		}
+
+		got, err := os.ReadFile(dst)
+		if err != nil || !bytes.Equal(got, content) {
+			t.Fatalf("size %d: downloaded %d bytes (err %v), want %d", size, len(got), err, size)
+		}
+	}
+}
+
+func TestDownloadRejectsCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "out.bin")
+
+	err := os.WriteFile(dst, []byte("previous"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := newDownload(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("expected"))
+
+	_, err = dl.write(&pb.File{Path: dst, Content: []byte("tampered"), Size: 8, Sha256: sum[:]})
+	if err == nil {
+		t.Fatal("download accepted a file with a wrong digest")
+	}
+
+	dl.discard()
+
+	got, err := os.ReadFile(dst)
+	if err != nil || string(got) != "previous" {
+		t.Fatalf("failed download changed %q to %q (err %v), want it untouched", dst, got, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("discarded download left files behind: %v (err %v)", entries, err)
+	}
+}
+
+func TestDownloadRejectsOutOfOrderChunk(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "out.bin")
+
+	dl, err := newDownload(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.discard()
+
+	_, err = dl.write(&pb.File{Path: dst, Content: []byte("abc"), More: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dl.write(&pb.File{Path: dst, Content: []byte("def"), Offset: 5})
+	if err == nil {
+		t.Fatal("download accepted a chunk that does not continue the file")
+	}
+}
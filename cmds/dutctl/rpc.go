@@ -5,25 +5,25 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"log/slog"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/BlindspotSoftware/dutctl/internal/output"
+	"github.com/BlindspotSoftware/dutctl/internal/style"
 	"github.com/BlindspotSoftware/dutctl/pkg/headers"
 
 	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
 )
 
-// errInterrupted is returned by runRPC when the run is terminated by a signal
+// errInterrupted is returned by relayRun when the run is terminated by a signal
 // (Ctrl-C) rather than by the agent or an error. exit() reports it as an
 // "interrupted" status with exit code 130, not as a failure.
 var errInterrupted = errors.New("interrupted")
@@ -32,7 +32,7 @@ var errInterrupted = errors.New("interrupted")
 // are quick request/response round-trips, so a modest per-call deadline catches
 // an unresponsive agent without cutting legitimate work. Connect encodes it as a
 // grpc-timeout header, so the agent handler inherits the same deadline. The
-// streaming Run deliberately has no overall deadline (see runRPC).
+// streaming Run and Attach deliberately have no overall deadline (see relayRun).
 const unaryTimeout = 30 * time.Second
 
 func (app *application) listRPC(ctx context.Context) error {
@@ -164,6 +164,123 @@ func (app *application) unlockRPC(ctx context.Context, device string, force bool
 	return nil
 }
 
+// powerCycleTimeout bounds a power cycle keeping the off-time configured on the
+// agent, which the client does not know. An explicit off-time extends
+// unaryTimeout instead.
+const powerCycleTimeout = 5 * time.Minute
+
+// powerActions maps the actions of the power command to their wire values.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var powerActions = map[string]pb.PowerAction{
+	"on":     pb.PowerAction_POWER_ACTION_ON,
+	"off":    pb.PowerAction_POWER_ACTION_OFF,
+	"cycle":  pb.PowerAction_POWER_ACTION_CYCLE,
+	"status": pb.PowerAction_POWER_ACTION_STATUS,
+}
+
+// parsePowerArgs resolves the action and off-time from the power command's
+// arguments. No argument queries the status; cycle takes an optional positive
+// off-time, 0 if omitted, which tells the agent to apply the device's configured
+// one. A malformed command line returns errInvalidCmdline; an invalid off-time
+// returns an error whose message is user-facing display text.
+func parsePowerArgs(cmdArgs []string) (string, time.Duration, error) {
+	if len(cmdArgs) == 0 {
+		return "status", 0, nil
+	}
+
+	action := cmdArgs[0]
+
+	_, ok := powerActions[action]
+	if !ok {
+		return "", 0, errInvalidCmdline
+	}
+
+	switch {
+	case len(cmdArgs) == 1:
+		return action, 0, nil
+	case len(cmdArgs) > 2 || action != "cycle": //nolint:mnd // cycle plus its off-time
+		return "", 0, errInvalidCmdline
+	}
+
+	offTime, err := time.ParseDuration(cmdArgs[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid off-time %q: %w", cmdArgs[1], err)
+	}
+
+	if offTime <= 0 {
+		return "", 0, fmt.Errorf("off-time must be positive, got %q", cmdArgs[1])
+	}
+
+	return action, offTime, nil
+}
+
+// powerRPC switches or queries the power of device. A device without power
+// controller may have a command named power in its place, which the agent tells
+// with CodeUnimplemented; the error is returned as it is, for the caller to run the
+// command. So the agent decides even for arguments that are no power action,
+// which are sent with an unspecified action and fail with the parse error of
+// parsePowerArgs unless the device has such a command.
+func (app *application) powerRPC(ctx context.Context, device string, cmdArgs []string) error {
+	action, offTime, argErr := parsePowerArgs(cmdArgs)
+
+	timeout := unaryTimeout
+
+	switch {
+	case action == "cycle" && offTime > 0:
+		timeout += offTime
+	case action == "cycle":
+		timeout = powerCycleTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req := connect.NewRequest(&pb.PowerRequest{
+		Device:    device,
+		Action:    powerActions[action],
+		OffTimeMs: offTime.Milliseconds(),
+	})
+	req.Header().Set(headers.User, app.user)
+
+	res, err := app.rpcClient.Power(ctx, req)
+	if argErr != nil && connect.CodeOf(err) != connect.CodeUnimplemented {
+		return argErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	app.formatter.WriteContent(output.Content{
+		Type: output.TypePowerState,
+		Data: output.PowerState{
+			Device:     res.Msg.GetDevice(),
+			Controller: res.Msg.GetController(),
+			Action:     action,
+			State:      powerStateName(res.Msg.GetState()),
+		},
+		Metadata: map[string]string{
+			"server": app.serverAddr,
+			"msg":    "Power Response",
+		},
+	})
+
+	return nil
+}
+
+// powerStateName renders a power state for output.
+func powerStateName(state pb.PowerState) string {
+	switch state {
+	case pb.PowerState_POWER_STATE_ON:
+		return output.PowerOn
+	case pb.PowerState_POWER_STATE_OFF:
+		return output.PowerOff
+	default:
+		return output.PowerUnknown
+	}
+}
+
 func (app *application) commandsRPC(ctx context.Context, device string) error {
 	ctx, cancel := context.WithTimeout(ctx, unaryTimeout)
 	defer cancel()
@@ -218,43 +335,51 @@ func (app *application) detailsRPC(ctx context.Context, device, command, keyword
 	return nil
 }
 
-// runRPC executes command on device, streaming module output and forwarding
-// stdin and file transfers until the run ends. It returns nil on normal
-// completion, errInterrupted when a signal (Ctrl-C) ended the run, or a wrapped
-// error from a worker goroutine (stream send/receive or file I/O). A connect
-// status from the agent surfaces through the returned error; exit() renders it.
-//
-//nolint:funlen,cyclop,gocognit,maintidx // coordinates two streaming worker goroutines; inherently branchy
-func (app *application) runRPC(ctx context.Context, device, command string, cmdArgs []string) error {
-	const numWorkers = 2 // The send and receive worker goroutines
+// runStream is the client side of a stream relaying a command execution: the
+// Run stream itself, or the Attach stream of a detached job (see attachStream).
+type runStream interface {
+	Send(msg *pb.RunRequest) error
+	Receive() (*pb.RunResponse, error)
+}
 
-	// ctx is the shared signal context from dispatch, cancelled on SIGINT/SIGTERM
-	// so Ctrl-C terminates gracefully (running the normal teardown and flushing the
-	// warning summary) instead of killing the process. A stream has no overall
-	// deadline. runCtx is the child the workers cancel on completion.
-	runCtx, cancelRunCtx := context.WithCancel(ctx)
-	defer cancelRunCtx()
+// attachStream adapts the client side of the Attach stream to runStream, so a
+// job is relayed just like the Run that started it.
+type attachStream struct {
+	stream *connect.BidiStreamForClient[pb.AttachRequest, pb.AttachResponse]
+}
 
-	errChan := make(chan error, numWorkers)
+func (s attachStream) Send(msg *pb.RunRequest) error {
+	return s.stream.Send(&pb.AttachRequest{Msg: &pb.AttachRequest_Run{Run: msg}})
+}
+
+func (s attachStream) Receive() (*pb.RunResponse, error) {
+	res, err := s.stream.Receive()
+	if err != nil {
+		return nil, err
+	}
 
-	stream := app.rpcClient.Run(runCtx)
-	stream.RequestHeader().Set(headers.User, app.user)
+	if res.GetRun() == nil {
+		return &pb.RunResponse{}, nil
+	}
+
+	return res.GetRun(), nil
+}
 
+// runRPC executes command on device, streaming module output and forwarding
+// stdin and file transfers until the run ends (see relayRun). With -d the
+// command runs as a detached job, which the agent announces before its output.
+func (app *application) runRPC(ctx context.Context, device, command string, cmdArgs []string) error {
 	req := &pb.RunRequest{
 		Msg: &pb.RunRequest_Command{
 			Command: &pb.Command{
 				Device:  device,
 				Command: command,
 				Args:    cmdArgs,
+				Detach:  app.detach,
 			},
 		},
 	}
 
-	err := stream.Send(req)
-	if err != nil {
-		return err
-	}
-
 	metadata := map[string]string{
 		"server":  app.serverAddr,
 		"msg":     "Run Response",
@@ -263,10 +388,125 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 		"args":    strings.Join(cmdArgs, " "),
 	}
 
+	return app.relayRun(ctx, "", metadata, func(runCtx context.Context) (runStream, error) {
+		stream := app.rpcClient.Run(runCtx)
+		stream.RequestHeader().Set(headers.User, app.user)
+
+		return stream, stream.Send(req)
+	})
+}
+
+// attachRPC attaches to the detached job with the given ID, replaying its output
+// so far and then relaying it like runRPC until the job finished.
+func (app *application) attachRPC(ctx context.Context, job string) error {
+	metadata := map[string]string{
+		"server": app.serverAddr,
+		"msg":    "Attach Response",
+		"job":    job,
+	}
+
+	return app.relayRun(ctx, job, metadata, func(runCtx context.Context) (runStream, error) {
+		stream := app.rpcClient.Attach(runCtx)
+		stream.RequestHeader().Set(headers.User, app.user)
+
+		err := stream.Send(&pb.AttachRequest{Msg: &pb.AttachRequest_Job{Job: job}})
+
+		return attachStream{stream: stream}, err
+	})
+}
+
+// relayRun relays a command execution on the stream returned by open, which
+// starts the stream on the context passed to it and sends the first message. It
+// prints module output and forwards stdin and file transfers until the stream
+// ends. job is the ID of the detached job relayed, if known upfront; otherwise a
+// job announced by the agent is picked up from the stream.
+//
+// Ctrl-C and Ctrl-\ are passed on to the command rather than ending the run (see
+// interrupter), and the end of stdin is passed on as an EOF signal. Once the
+// agent opens a console session, a terminal is switched to raw mode, passing on
+// every key but the escape key, which ends the run (see console). It returns
+// nil on normal completion, errInterrupted when the command was canceled or a
+// signal or the escape key tore down the stream, or a wrapped error from a
+// worker goroutine (stream send/receive or file I/O). Tearing down the stream
+// only detaches from a job, which keeps running on the agent, so it returns nil
+// then. A connect status from the agent surfaces through the returned error;
+// exit() renders it.
+//
+//nolint:funlen,cyclop,gocognit,maintidx // coordinates two streaming worker goroutines; inherently branchy
+func (app *application) relayRun(
+	ctx context.Context,
+	job string,
+	metadata map[string]string,
+	open func(context.Context) (runStream, error),
+) error {
+	const numWorkers = 2 // The send and receive worker goroutines
+
+	// ctx is the shared signal context from dispatch, cancelled on a signal the
+	// interrupter below does not handle, so the run terminates gracefully (running
+	// the normal teardown and flushing the warning summary) instead of killing the
+	// process. A stream has no overall deadline. runCtx is the child the workers
+	// cancel on completion.
+	runCtx, cancelRunCtx := context.WithCancel(ctx)
+	defer cancelRunCtx()
+
+	errChan := make(chan error, numWorkers)
+
+	// jobID is set by the receive routine when the agent announces a job and read
+	// here once the run ended.
+	var jobID atomic.Pointer[string]
+	if job != "" {
+		jobID.Store(&job)
+	}
+
+	opened, err := open(runCtx)
+	if err != nil {
+		return err
+	}
+
+	stream := &syncStream{runStream: opened}
+
+	hint := func(msg string) {
+		fmt.Fprintln(app.stderr, style.MarkerContext+" "+msg)
+	}
+
+	intr := &interrupter{stream: stream, hint: hint}
+
+	// cons switches the terminal to raw mode once the agent opens a console
+	// session. escaped is set when the user detaches from it with the escape key,
+	// which ends the run like a signal that is not passed on.
+	cons := &console{stdin: app.stdin, stream: stream, hint: hint, crlf: &app.rawTerminal}
+	defer cons.restore()
+
+	var escaped atomic.Bool
+
+	handler := signalHandler(func(sig os.Signal) bool {
+		// A signal detaches from a job instead, which keeps running on the agent.
+		if jobID.Load() != nil {
+			return false
+		}
+
+		return intr.handle(sig)
+	})
+
+	app.signalHandler.Store(&handler)
+	defer app.signalHandler.Store(nil)
+
 	// Receive routine
 	go func() {
 		defer cancelRunCtx()
 
+		// inflight is the file the agent is currently sending in chunks, if any.
+		// A download the agent never completed is discarded, leaving the
+		// destination as it was.
+		var inflight *download
+
+		defer func() {
+			if inflight != nil {
+				slog.Warn("discarding incomplete file", "path", inflight.path)
+				inflight.discard()
+			}
+		}()
+
 		for {
 			select {
 			case <-runCtx.Done():
@@ -302,6 +542,14 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 					Metadata: metadata,
 				})
 			case *pb.RunResponse_Console:
+				// The first Console message opens the session.
+				err := cons.open()
+				if err != nil {
+					errChan <- err
+
+					return
+				}
+
 				switch consoleData := msg.Console.Data.(type) {
 				case *pb.Console_Stdout:
 					app.formatter.WriteContent(output.Content{
@@ -323,21 +571,7 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 				path := msg.FileRequest.GetPath()
 				slog.Debug("file requested by agent", "path", path)
 
-				content, err := os.ReadFile(path)
-				if err != nil {
-					errChan <- fmt.Errorf("reading requested file %q: %w", path, err)
-
-					return
-				}
-
-				err = stream.Send(&pb.RunRequest{
-					Msg: &pb.RunRequest_File{
-						File: &pb.File{
-							Path:    path,
-							Content: content,
-						},
-					},
-				})
+				sent, err := uploadFile(stream, path)
 				if err != nil {
 					errChan <- fmt.Errorf("sending requested file %q: %w", path, err)
 
@@ -346,39 +580,67 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 
 				app.formatter.WriteContent(output.Content{
 					Type:     output.TypeFileTransfer,
-					Data:     output.FileTransfer{Direction: "sent", Path: path, Bytes: len(content)},
+					Data:     output.FileTransfer{Direction: "sent", Path: path, Bytes: int(sent)},
 					Metadata: metadata,
 				})
 			case *pb.RunResponse_File:
 				path := msg.File.GetPath()
-				content := msg.File.GetContent()
 
-				if len(content) == 0 {
-					slog.Warn("received empty file content", "path", path)
-				}
+				if inflight == nil {
+					inflight, err = newDownload(path)
+					if err != nil {
+						errChan <- fmt.Errorf("saving received file %q: %w", path, err)
 
-				perm := 0600
+						return
+					}
+				}
 
-				err = os.WriteFile(path, content, fs.FileMode(perm))
+				done, err := inflight.write(msg.File)
 				if err != nil {
 					errChan <- fmt.Errorf("saving received file %q: %w", path, err)
 
 					return
 				}
 
+				if !done {
+					continue
+				}
+
+				if inflight.offset == 0 {
+					slog.Warn("received empty file content", "path", path)
+				}
+
 				app.formatter.WriteContent(output.Content{
 					Type:     output.TypeFileTransfer,
-					Data:     output.FileTransfer{Direction: "received", Path: path, Bytes: len(content)},
+					Data:     output.FileTransfer{Direction: "received", Path: path, Bytes: int(inflight.offset)},
+					Metadata: metadata,
+				})
+
+				inflight = nil
+
+			case *pb.RunResponse_Result:
+				app.formatter.WriteContent(output.Content{
+					Type:     output.TypeRunResult,
+					Data:     runResult(msg.Result),
 					Metadata: metadata,
 				})
+			case *pb.RunResponse_Job:
+				id := msg.Job.GetId()
+				jobID.Store(&id)
 
+				app.formatter.WriteContent(output.Content{
+					Type:     output.TypeJob,
+					Data:     output.JobEntry{ID: id, State: output.JobRunning},
+					Metadata: metadata,
+				})
 			default:
 				slog.Warn("unexpected message type", "type", fmt.Sprintf("%T", msg))
 			}
 		}
 	}()
 
-	// Send routine — reads lines from stdin and forwards them to the server.
+	// Send routine — reads stdin and forwards it to the server, line by line or,
+	// in raw mode, keystroke by keystroke.
 	//
 	// Unlike the receive routine this goroutine intentionally does NOT defer
 	// cancel(). When stdin reaches EOF (e.g. /dev/null in non-interactive
@@ -389,8 +651,6 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 	// Only the receive routine drives context cancellation so that all
 	// server output is processed before the RPC terminates.
 	go func() {
-		reader := bufio.NewReader(app.stdin)
-
 		for {
 			select {
 			case <-runCtx.Done():
@@ -400,26 +660,53 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 			default:
 			}
 
-			text, err := reader.ReadString('\n')
-			if err != nil {
-				if !errors.Is(err, io.EOF) {
-					errChan <- fmt.Errorf("reading stdin: %w", err)
+			buf := make([]byte, stdinChunkSize)
+
+			n, err := app.stdin.Read(buf)
+			if n > 0 {
+				input, escape := buf[:n], false
+				if cons.raw() {
+					input, escape = rawInput(input)
 				}
 
-				return
+				if len(input) > 0 {
+					sendErr := stream.Send(&pb.RunRequest{
+						Msg: &pb.RunRequest_Console{
+							Console: &pb.Console{
+								Data: &pb.Console_Stdin{
+									Stdin: input,
+								},
+							},
+						},
+					})
+					if sendErr != nil {
+						errChan <- fmt.Errorf("sending RPC message: %w", sendErr)
+
+						return
+					}
+				}
+
+				if escape {
+					slog.Debug("send routine terminating", "reason", "escape key")
+					escaped.Store(true)
+					cancelRunCtx()
+
+					return
+				}
 			}
 
-			err = stream.Send(&pb.RunRequest{
-				Msg: &pb.RunRequest_Console{
-					Console: &pb.Console{
-						Data: &pb.Console_Stdin{
-							Stdin: []byte(text),
-						},
-					},
-				},
-			})
 			if err != nil {
-				errChan <- fmt.Errorf("sending RPC message: %w", err)
+				if !errors.Is(err, io.EOF) {
+					errChan <- fmt.Errorf("reading stdin: %w", err)
+
+					return
+				}
+
+				// Pass the end of input (Ctrl-D) on to the command.
+				err = stream.Send(signalRequest(pb.SignalKind_SIGNAL_KIND_EOF))
+				if err != nil {
+					errChan <- fmt.Errorf("sending RPC message: %w", err)
+				}
 
 				return
 			}
@@ -429,15 +716,123 @@ func (app *application) runRPC(ctx context.Context, device, command string, cmdA
 	// Wait for completion or error
 	select {
 	case <-runCtx.Done():
-		// ctx.Err() is non-nil only if a signal fired (dispatch's deferred stop
-		// has not run yet), distinguishing Ctrl-C from a normal stream-closed
-		// teardown.
-		if ctx.Err() != nil {
-			return errInterrupted
+		// ctx.Err() is non-nil only if an unhandled signal fired (dispatch's
+		// deferred cancel has not run yet), distinguishing a torn-down stream
+		// from a normal stream-closed teardown; the escape key tears it down
+		// alike. A command canceled with Ctrl-C ends the stream normally, but
+		// still counts as interrupted.
+		if ctx.Err() == nil && !escaped.Load() {
+			if intr.canceled.Load() {
+				return errInterrupted
+			}
+
+			return nil
 		}
 
-		return nil
+		if id := jobID.Load(); id != nil {
+			app.formatter.WriteContent(output.Content{
+				Type: output.TypeGeneral,
+				Data: fmt.Sprintf("detached from job %s, it keeps running on the agent\n", *id),
+			})
+
+			return nil
+		}
+
+		return errInterrupted
+
 	case err := <-errChan:
 		return err
 	}
 }
+
+// runResult converts the RunResult concluding a command execution into its
+// output representation.
+func runResult(res *pb.RunResult) output.RunResult {
+	modules := make([]output.ModuleResult, 0, len(res.GetModules()))
+
+	for _, mod := range res.GetModules() {
+		m := output.ModuleResult{
+			Name:       mod.GetName(),
+			Index:      int(mod.GetIndex()),
+			Outcome:    strings.ToLower(strings.TrimPrefix(mod.GetOutcome().String(), "MODULE_OUTCOME_")),
+			Error:      mod.GetError(),
+			DurationMs: mod.GetDurationMs(),
+		}
+
+		if mod.GetStartedAt() != 0 {
+			m.StartedAt = time.UnixMilli(mod.GetStartedAt()).UTC().Format(time.RFC3339Nano)
+		}
+
+		if mod.GetFinishedAt() != 0 {
+			m.FinishedAt = time.UnixMilli(mod.GetFinishedAt()).UTC().Format(time.RFC3339Nano)
+		}
+
+		modules = append(modules, m)
+	}
+
+	return output.RunResult{Modules: modules}
+}
+
+// jobsRPC lists the detached jobs on the agent.
+func (app *application) jobsRPC(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, unaryTimeout)
+	defer cancel()
+
+	res, err := app.rpcClient.Jobs(ctx, connect.NewRequest(&pb.JobsRequest{}))
+	if err != nil {
+		return err
+	}
+
+	jobs := make([]output.JobEntry, 0, len(res.Msg.GetJobs()))
+
+	for _, info := range res.Msg.GetJobs() {
+		jobs = append(jobs, output.JobEntry{
+			ID:         info.GetId(),
+			Owner:      info.GetOwner(),
+			Device:     info.GetDevice(),
+			Command:    info.GetCommand(),
+			Args:       info.GetArgs(),
+			State:      strings.ToLower(strings.TrimPrefix(info.GetState().String(), "JOB_STATE_")),
+			Error:      info.GetError(),
+			CreatedAt:  info.GetCreatedAt(),
+			FinishedAt: info.GetFinishedAt(),
+			Attached:   info.GetAttached(),
+		})
+	}
+
+	app.formatter.WriteContent(output.Content{
+		Type: output.TypeJobList,
+		Data: jobs,
+		Metadata: map[string]string{
+			"server": app.serverAddr,
+			"msg":    "Jobs Response",
+		},
+	})
+
+	return nil
+}
+
+// cancelJobRPC cancels the detached job with the given ID.
+func (app *application) cancelJobRPC(ctx context.Context, job string) error {
+	ctx, cancel := context.WithTimeout(ctx, unaryTimeout)
+	defer cancel()
+
+	req := connect.NewRequest(&pb.CancelJobRequest{Job: job})
+	req.Header().Set(headers.User, app.user)
+
+	_, err := app.rpcClient.CancelJob(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	app.formatter.WriteContent(output.Content{
+		Type: output.TypeJob,
+		Data: output.JobEntry{ID: job, State: output.JobCanceled},
+		Metadata: map[string]string{
+			"server": app.serverAddr,
+			"msg":    "CancelJob Response",
+		},
+	})
+
+	return nil
+}
@@ -75,3 +75,45 @@ func TestParseLockDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePowerArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantAction  string
+		wantOffTime time.Duration
+		wantErr     bool
+	}{
+		{name: "no args queries the status", args: nil, wantAction: "status"},
+		{name: "on", args: []string{"on"}, wantAction: "on"},
+		{name: "cycle with the configured off-time", args: []string{"cycle"}, wantAction: "cycle"},
+		{name: "cycle with an off-time", args: []string{"cycle", "1m"}, wantAction: "cycle", wantOffTime: time.Minute},
+		{name: "unparseable off-time", args: []string{"cycle", "banana"}, wantErr: true},
+		{name: "zero off-time rejected", args: []string{"cycle", "0s"}, wantErr: true},
+		{name: "unknown action", args: []string{"reboot"}, wantErr: true},
+		{name: "off-time for on", args: []string{"on", "5s"}, wantErr: true},
+		{name: "extra args", args: []string{"cycle", "5s", "junk"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, offTime, err := parsePowerArgs(tt.args)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePowerArgs(%v): want error, got action %q", tt.args, action)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parsePowerArgs(%v): unexpected error: %v", tt.args, err)
+			}
+
+			if action != tt.wantAction || offTime != tt.wantOffTime {
+				t.Errorf("parsePowerArgs(%v) = %q, %v, want %q, %v", tt.args, action, offTime, tt.wantAction, tt.wantOffTime)
+			}
+		})
+	}
+}
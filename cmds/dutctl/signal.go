@@ -0,0 +1,99 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// Hints printed when Ctrl-C is passed on to a running command, telling the user
+// what the next Ctrl-C does.
+const (
+	interruptHint = "interrupt sent to the command, press Ctrl-C again to cancel it"
+	cancelHint    = "canceling the command, press Ctrl-C again to abort"
+)
+
+// syncStream serializes the sends on a runStream. Stdin, file uploads and
+// signals are sent from different goroutines, but a stream does not support
+// concurrent sends.
+type syncStream struct {
+	runStream
+
+	mu sync.Mutex
+}
+
+func (s *syncStream) Send(msg *pb.RunRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.runStream.Send(msg)
+}
+
+// interrupter passes the signals caught during a run on to the command instead
+// of tearing down the stream. Ctrl-\ is sent as a quit signal. Ctrl-C escalates:
+// the first one is sent as an interrupt, the second one cancels the command,
+// which lets the modules tear down and still report the result, and the third
+// one is not handled, so the stream is torn down. Any other signal is not
+// handled either.
+type interrupter struct {
+	stream runRequestSender
+	// hint tells the user what the next Ctrl-C does.
+	hint func(msg string)
+
+	// interrupts counts the Ctrl-Cs so far. It is only accessed by handle,
+	// which handleSignals calls sequentially.
+	interrupts int
+	// canceled is set once the command was canceled.
+	canceled atomic.Bool
+}
+
+// handle implements signalHandler.
+func (i *interrupter) handle(sig os.Signal) bool {
+	switch sig {
+	case syscall.SIGQUIT:
+		return i.send(signalRequest(pb.SignalKind_SIGNAL_KIND_QUIT))
+	case os.Interrupt:
+		i.interrupts++
+	default:
+		return false
+	}
+
+	switch i.interrupts {
+	case 1:
+		if !i.send(signalRequest(pb.SignalKind_SIGNAL_KIND_INTERRUPT)) {
+			return false
+		}
+
+		i.hint(interruptHint)
+	case 2: //nolint:mnd // the second Ctrl-C
+		if !i.send(&pb.RunRequest{Msg: &pb.RunRequest_Cancel{Cancel: &pb.Cancel{}}}) {
+			return false
+		}
+
+		i.canceled.Store(true)
+		i.hint(cancelHint)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// send sends msg, reporting whether it succeeded. A failed send leaves the
+// signal unhandled, so it tears down the stream.
+func (i *interrupter) send(msg *pb.RunRequest) bool {
+	return i.stream.Send(msg) == nil
+}
+
+// signalRequest returns the RunRequest passing a signal of the given kind on to
+// the command.
+func signalRequest(kind pb.SignalKind) *pb.RunRequest {
+	return &pb.RunRequest{Msg: &pb.RunRequest_Signal{Signal: &pb.Signal{Kind: kind}}}
+}
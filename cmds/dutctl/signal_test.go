@@ -0,0 +1,91 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+type requestRecorder struct {
+	sent []*pb.RunRequest
+	err  error
+}
+
+func (r *requestRecorder) Send(msg *pb.RunRequest) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	r.sent = append(r.sent, msg)
+
+	return nil
+}
+
+func TestInterrupterEscalation(t *testing.T) {
+	rec := &requestRecorder{}
+
+	var hints []string
+
+	intr := &interrupter{stream: rec, hint: func(msg string) { hints = append(hints, msg) }}
+
+	tests := []struct {
+		sig     os.Signal
+		handled bool
+	}{
+		{syscall.SIGQUIT, true},
+		{os.Interrupt, true},
+		{os.Interrupt, true},
+		{os.Interrupt, false},
+		{syscall.SIGTERM, false},
+	}
+
+	for i, tt := range tests {
+		if got := intr.handle(tt.sig); got != tt.handled {
+			t.Fatalf("signal #%d (%v): handled = %v, want %v", i, tt.sig, got, tt.handled)
+		}
+	}
+
+	if len(rec.sent) != 3 {
+		t.Fatalf("sent %d requests, want 3", len(rec.sent))
+	}
+
+	if got := rec.sent[0].GetSignal().GetKind(); got != pb.SignalKind_SIGNAL_KIND_QUIT {
+		t.Errorf("first request = %v, want quit signal", got)
+	}
+
+	if got := rec.sent[1].GetSignal().GetKind(); got != pb.SignalKind_SIGNAL_KIND_INTERRUPT {
+		t.Errorf("second request = %v, want interrupt signal", got)
+	}
+
+	if rec.sent[2].GetCancel() == nil {
+		t.Errorf("third request = %v, want cancel", rec.sent[2])
+	}
+
+	if !intr.canceled.Load() {
+		t.Error("interrupter not marked canceled after the second Ctrl-C")
+	}
+
+	if len(hints) != 2 || hints[0] != interruptHint || hints[1] != cancelHint {
+		t.Errorf("hints = %q, want interrupt and cancel hints", hints)
+	}
+}
+
+func TestInterrupterSendFailure(t *testing.T) {
+	rec := &requestRecorder{err: errors.New("stream closed")}
+	intr := &interrupter{stream: rec, hint: func(string) { t.Error("unexpected hint") }}
+
+	if intr.handle(os.Interrupt) {
+		t.Error("signal handled although sending it failed")
+	}
+
+	if intr.canceled.Load() {
+		t.Error("interrupter marked canceled")
+	}
+}
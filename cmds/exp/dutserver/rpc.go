@@ -58,7 +58,7 @@ func (a *agent) conn(ctx context.Context) dutctlv1connect.DeviceServiceClient {
 type rpcService struct {
 	// UnimplementedDeviceServiceHandler provides default CodeUnimplemented
 	// responses for DeviceService RPCs that dutserver does not forward,
-	// such as Lock and Unlock.
+	// such as Lock, Unlock and Power, and the job RPCs Attach, Jobs and CancelJob.
 	dutctlv1connect.UnimplementedDeviceServiceHandler
 
 	mu sync.RWMutex
@@ -453,6 +453,10 @@ func requestKind(req *pb.RunRequest) string {
 		return "console"
 	case req.GetFile() != nil:
 		return "file"
+	case req.GetSignal() != nil:
+		return "signal"
+	case req.GetCancel() != nil:
+		return "cancel"
 	default:
 		return "unknown"
 	}
@@ -470,6 +474,10 @@ func responseKind(res *pb.RunResponse) string {
 		return "file-request"
 	case res.GetFile() != nil:
 		return "file"
+	case res.GetResult() != nil:
+		return "result"
+	case res.GetJob() != nil:
+		return "job"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,384 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/session"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// outputLimit bounds the output a job retains for replay, in bytes of encoded
+// messages. Beyond it the oldest output is dropped, and a client attaching later
+// is told so.
+const outputLimit = 4 << 20
+
+// entry is a message the job's modules sent to the client.
+//
+// Output (prints, console output, the run result) is replayed to every client
+// that attaches. An interactive message (a file request or a file chunk) is
+// part of an exchange with a single client, so it is delivered exactly once,
+// to whichever client is attached first: claimed marks it as being sent,
+// delivered is closed once it was sent.
+type entry struct {
+	msg       *pb.RunResponse
+	delivered chan struct{} // nil for output
+	claimed   bool
+}
+
+func (e *entry) interactive() bool {
+	return e.delivered != nil
+}
+
+// Job is a detached command execution. It is created by Registry.Start.
+type Job struct {
+	id        string
+	spec      Spec
+	createdAt time.Time
+	cancel    context.CancelCauseFunc
+
+	// in carries the messages of the attached client to the job's session.
+	in chan *pb.RunRequest
+	// done is closed once the job finished.
+	done chan struct{}
+
+	// mu guards the fields below.
+	mu         sync.Mutex
+	state      State
+	err        error
+	finishedAt time.Time
+
+	// entries are the retained messages, first is the sequence number of
+	// entries[0] and size the encoded size of the retained output.
+	entries []*entry
+	first   int
+	size    int
+
+	// changed is closed and replaced whenever entries or the state change, to
+	// wake up the attached client's replay.
+	changed chan struct{}
+
+	// attached is the current attachment, if any.
+	attached *attachment
+}
+
+// attachment is a client attached to a job.
+type attachment struct {
+	detach context.CancelCauseFunc
+}
+
+func newJob(id string, spec Spec, cancel context.CancelCauseFunc) *Job {
+	return &Job{
+		id:        id,
+		spec:      spec,
+		createdAt: time.Now(),
+		cancel:    cancel,
+		in:        make(chan *pb.RunRequest),
+		done:      make(chan struct{}),
+		changed:   make(chan struct{}),
+	}
+}
+
+// ID returns the job's ID.
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Info returns a snapshot of the job.
+func (j *Job) Info() Info {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return Info{
+		Spec:       j.spec,
+		ID:         j.id,
+		State:      j.state,
+		Err:        j.err,
+		CreatedAt:  j.createdAt,
+		FinishedAt: j.finishedAt,
+		Attached:   j.attached != nil,
+	}
+}
+
+// notify wakes up the attached client's replay. The caller must hold j.mu.
+func (j *Job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// finish records the outcome of the job: err as returned by its RunFunc, and
+// whether its context was cancelled.
+func (j *Job) finish(err error, canceled bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.err = err
+	j.finishedAt = time.Now()
+
+	switch {
+	case err == nil:
+		j.state = Succeeded
+	case canceled:
+		j.state = Canceled
+	default:
+		j.state = Failed
+	}
+
+	close(j.done)
+	j.notify()
+}
+
+// append adds a message of the modules to the job and drops the oldest output
+// beyond outputLimit.
+func (j *Job) append(e *entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = append(j.entries, e)
+	if !e.interactive() {
+		j.size += proto.Size(e.msg)
+	}
+
+	// An undelivered interactive message is never dropped. Its sender blocks
+	// until it is delivered, so no output piles up behind it meanwhile.
+	for j.size > outputLimit {
+		oldest := j.entries[0]
+		if oldest.interactive() && oldest.msg != nil {
+			break
+		}
+
+		if !oldest.interactive() {
+			j.size -= proto.Size(oldest.msg)
+		}
+
+		j.entries[0] = nil
+		j.entries = j.entries[1:]
+		j.first++
+	}
+
+	j.notify()
+}
+
+// Attach relays the job to a client: it replays the output retained so far,
+// then sends everything the job's modules send, and passes the client's messages
+// on to them. Only one client is attached at a time; attaching ends the
+// previous attachment, whose Attach returns ErrTakenOver.
+//
+// Attach returns the job's error once the job finished and the client was sent
+// all of its output, or the cause when ctx ends, which leaves the job running.
+func (j *Job) Attach(ctx context.Context, client session.Stream) error {
+	ctx, detach := context.WithCancelCause(ctx)
+	defer detach(nil)
+
+	self := &attachment{detach: detach}
+
+	j.mu.Lock()
+	if j.attached != nil {
+		j.attached.detach(ErrTakenOver)
+	}
+
+	j.attached = self
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		// Unless a takeover already replaced this attachment.
+		if j.attached == self {
+			j.attached = nil
+		}
+	}()
+
+	go j.forward(ctx, client)
+
+	return j.replay(ctx, client)
+}
+
+// forward passes the client's messages on to the job's session until ctx ends
+// or the client stops sending. A client that closes its side of the stream stays
+// attached and keeps receiving.
+//
+// Receive is transport I/O that ctx cannot interrupt. It unblocks when the
+// request the client is attached through ends, shortly after Attach returns,
+// so this goroutine always terminates.
+func (j *Job) forward(ctx context.Context, client session.Stream) {
+	for {
+		req, err := client.Receive()
+		if err != nil {
+			return
+		}
+
+		select {
+		case j.in <- req:
+		case <-ctx.Done():
+			return
+		case <-j.done:
+			return
+		}
+	}
+}
+
+// replay sends the job's messages to the client, starting with the oldest one
+// retained, until the job finished and the client has them all, or ctx ends.
+//
+//nolint:cyclop // a single pass over the entries with a few skip conditions
+func (j *Job) replay(ctx context.Context, client session.Stream) error {
+	next := 0
+
+	for {
+		j.mu.Lock()
+
+		if next < j.first {
+			dropped := j.first - next
+			next = j.first
+			j.mu.Unlock()
+
+			err := client.Send(droppedNotice(dropped))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if next == j.first+len(j.entries) {
+			finished := j.state != Running
+			err := j.err
+			changed := j.changed
+			j.mu.Unlock()
+
+			if finished {
+				return err
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+
+			continue
+		}
+
+		e := j.entries[next-j.first]
+		msg := e.msg
+
+		if e.interactive() {
+			switch {
+			case msg == nil:
+				// Delivered to a previous client.
+				j.mu.Unlock()
+
+				next++
+
+				continue
+			case e.claimed:
+				// Being sent to a previous client that was just taken over;
+				// wait for the outcome.
+				changed := j.changed
+				j.mu.Unlock()
+
+				select {
+				case <-changed:
+				case <-ctx.Done():
+					return context.Cause(ctx)
+				}
+
+				continue
+			}
+
+			e.claimed = true
+		}
+
+		j.mu.Unlock()
+
+		err := client.Send(msg)
+
+		if e.interactive() {
+			j.settle(e, err == nil)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		next++
+	}
+}
+
+// settle concludes the delivery of a claimed interactive entry. A delivered
+// entry releases its sender and its payload; an undelivered one is released for
+// the next client to claim.
+func (j *Job) settle(e *entry, delivered bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.claimed = false
+
+	if delivered {
+		e.msg = nil
+		close(e.delivered)
+	}
+
+	j.notify()
+}
+
+// droppedNotice tells a client that the job's oldest output is no longer available.
+func droppedNotice(count int) *pb.RunResponse {
+	text := fmt.Sprintf("[%d earlier messages of this job were dropped]\n", count)
+
+	return &pb.RunResponse{Msg: &pb.RunResponse_Print{Print: &pb.Print{Text: []byte(text)}}}
+}
+
+// stream is the session.Stream a job's modules talk through. It is independent
+// of any client connection: Send buffers the message in the job, Receive takes
+// the messages of whichever client is attached.
+type stream struct {
+	job *Job
+	ctx context.Context
+}
+
+// Send adds msg to the job. Output returns right away; an interactive message
+// blocks until it was delivered to a client, as the module cannot proceed
+// without one, or until the job is cancelled.
+func (s *stream) Send(msg *pb.RunResponse) error {
+	e := &entry{msg: msg}
+	if msg.GetFileRequest() != nil || msg.GetFile() != nil {
+		e.delivered = make(chan struct{})
+	}
+
+	s.job.append(e)
+
+	if !e.interactive() {
+		return nil
+	}
+
+	select {
+	case <-e.delivered:
+		return nil
+	case <-s.ctx.Done():
+		return context.Cause(s.ctx)
+	}
+}
+
+// Receive returns the next message of the attached client, waiting for a client
+// to attach if there is none. It returns io.EOF once the job is cancelled or
+// finished.
+func (s *stream) Receive() (*pb.RunRequest, error) {
+	select {
+	case req := <-s.job.in:
+		return req, nil
+	case <-s.ctx.Done():
+		return nil, io.EOF
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jobs keeps track of detached command executions (jobs) on the agent.
+//
+// A job decouples a command execution from the Run stream that started it. The
+// job runs against its own session.Stream: output the modules send is buffered,
+// and interactive messages (file transfers) wait for a client. A client attaches
+// to the job to receive the buffered output and everything that follows, and to
+// talk to the modules. When the client goes away the job keeps running, and the
+// same user can attach to it again later.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/session"
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+)
+
+// Sentinel errors returned by Registry and Job.
+var (
+	// ErrNotFound is returned for an unknown job ID, including the ID of a job
+	// that finished longer than the retention period ago.
+	ErrNotFound = errors.New("job not found")
+	// ErrWrongOwner is returned when a user other than the one who started a job
+	// tries to attach to or cancel it.
+	ErrWrongOwner = errors.New("job belongs to another user")
+	// ErrNotRunning is returned when cancelling a job that already finished.
+	ErrNotRunning = errors.New("job is not running")
+	// ErrTakenOver is returned by Attach when another client attached to the job
+	// and thereby ended this attachment.
+	ErrTakenOver = errors.New("another client attached to the job")
+	// ErrCanceled is the cancellation cause of a job cancelled by its owner.
+	ErrCanceled = errors.New("job canceled")
+	// ErrShutdown is the cancellation cause of the jobs still running when the
+	// registry's context ends.
+	ErrShutdown = errors.New("agent shutting down")
+)
+
+// retention is how long a finished job is kept, so its owner can still attach
+// to it and read its output and result after a disconnect.
+const retention = time.Hour
+
+// idBytes is the number of random bytes in a job ID. The ID is typed by users
+// (dutctl attach <job>), so it is kept short; collisions are checked on creation.
+const idBytes = 4
+
+// State is the state of a job.
+type State int
+
+const (
+	// Running is the state of a job whose modules are still executing.
+	Running State = iota
+	// Succeeded is the state of a job whose modules all finished successfully.
+	Succeeded
+	// Failed is the state of a job that ended with an error.
+	Failed
+	// Canceled is the state of a job that was cancelled, by its owner or on
+	// agent shutdown.
+	Canceled
+)
+
+// String renders a State for logs and diagnostics.
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	case Canceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Spec describes the command execution a job runs.
+type Spec struct {
+	Owner   string
+	Device  string
+	Command string
+	Args    []string
+}
+
+// Info is a snapshot of a job for listing.
+type Info struct {
+	Spec
+
+	ID         string
+	State      State
+	Err        error // The job's error, set for a failed or canceled job.
+	CreatedAt  time.Time
+	FinishedAt time.Time // The zero time while the job is running.
+	Attached   bool
+}
+
+// RunFunc executes the command of a job. It talks to the client through stream,
+// which outlives any single client connection, and returns once the command
+// finished. ctx is cancelled when the job is cancelled.
+type RunFunc func(ctx context.Context, stream session.Stream) error
+
+// Registry tracks the jobs of an agent. Running jobs are cancelled when the
+// context passed to New ends. Finished jobs are kept for a retention period and
+// pruned lazily afterwards. Registry is safe for concurrent use. Jobs are held in
+// memory only and are lost on agent restart.
+type Registry struct {
+	ctx  context.Context
+	mu   sync.Mutex
+	jobs map[string]*Job
+	log  *slog.Logger
+}
+
+// New returns a ready-to-use Registry whose jobs are bound to the lifetime of ctx.
+func New(ctx context.Context) *Registry {
+	return &Registry{
+		ctx:  ctx,
+		jobs: make(map[string]*Job),
+		log:  log.Scope(slog.Default(), "jobs"),
+	}
+}
+
+// Start creates a job for spec and runs it in a new goroutine. The job's context
+// carries the values of ctx, but not its cancellation: a job outlives the request
+// that started it.
+func (r *Registry) Start(ctx context.Context, spec Spec, run RunFunc) *Job {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+
+	r.mu.Lock()
+	r.prune(time.Now())
+
+	id := r.newID()
+	job := newJob(id, spec, cancel)
+	r.jobs[id] = job
+	r.mu.Unlock()
+
+	ctx = log.With(ctx, "job", id)
+
+	stopShutdown := context.AfterFunc(r.ctx, func() { cancel(ErrShutdown) })
+
+	r.log.Info("job started", "job", id, "owner", spec.Owner, "device", spec.Device, "command", spec.Command)
+
+	go func() {
+		err := run(ctx, &stream{job: job, ctx: ctx})
+
+		stopShutdown()
+		job.finish(err, ctx.Err() != nil)
+		cancel(nil)
+
+		info := job.Info()
+		r.log.Info("job finished", "job", id, "state", info.State, "err", info.Err)
+	}()
+
+	return job
+}
+
+// newID returns a random job ID not used by any job in the registry. The caller
+// must hold r.mu.
+func (r *Registry) newID() string {
+	buf := make([]byte, idBytes)
+
+	for {
+		rand.Read(buf) //nolint:errcheck // crypto/rand.Read never returns an error
+
+		id := hex.EncodeToString(buf)
+		if _, taken := r.jobs[id]; !taken {
+			return id
+		}
+	}
+}
+
+// prune removes the jobs that finished longer than the retention period before
+// now. The caller must hold r.mu.
+func (r *Registry) prune(now time.Time) {
+	for id, job := range r.jobs {
+		if finished := job.Info().FinishedAt; !finished.IsZero() && now.Sub(finished) > retention {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+// Get returns the job with the given ID on behalf of owner. It returns
+// ErrNotFound for an unknown ID and ErrWrongOwner if owner did not start the job.
+func (r *Registry) Get(id, owner string) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(time.Now())
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if job.spec.Owner != owner {
+		return nil, ErrWrongOwner
+	}
+
+	return job, nil
+}
+
+// List returns a snapshot of all jobs, the running ones and those finished
+// within the retention period, oldest first.
+func (r *Registry) List() []Info {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(time.Now())
+
+	infos := make([]Info, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		infos = append(infos, job.Info())
+	}
+
+	slices.SortFunc(infos, func(a, b Info) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	return infos
+}
+
+// Cancel cancels the running job with the given ID on behalf of owner. It
+// returns the errors of Get, or ErrNotRunning if the job already finished.
+// Cancelling only starts the teardown; the job finishes once its modules returned.
+func (r *Registry) Cancel(id, owner string) error {
+	job, err := r.Get(id, owner)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-job.done:
+		return ErrNotRunning
+	default:
+	}
+
+	job.cancel(ErrCanceled)
+	r.log.Info("job canceled", "job", id, "owner", owner)
+
+	return nil
+}
@@ -0,0 +1,434 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/dutagent/session"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// client is a session.Stream standing in for an attached client. Receive
+// reports io.EOF once recv is closed.
+type client struct {
+	recv chan *pb.RunRequest
+	sent chan *pb.RunResponse
+}
+
+func newClient() *client {
+	return &client{
+		recv: make(chan *pb.RunRequest),
+		sent: make(chan *pb.RunResponse, 64),
+	}
+}
+
+func (c *client) Send(msg *pb.RunResponse) error {
+	c.sent <- msg
+
+	return nil
+}
+
+func (c *client) Receive() (*pb.RunRequest, error) {
+	req, ok := <-c.recv
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return req, nil
+}
+
+func (c *client) next(t *testing.T) *pb.RunResponse {
+	t.Helper()
+
+	select {
+	case res := <-c.sent:
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the job to send a message")
+	}
+
+	return nil
+}
+
+func printMsg(text string) *pb.RunResponse {
+	return &pb.RunResponse{Msg: &pb.RunResponse_Print{Print: &pb.Print{Text: []byte(text)}}}
+}
+
+// attach attaches c to job in a goroutine and returns the channel Attach's
+// result is reported on.
+func attach(ctx context.Context, job *Job, c *client) <-chan error {
+	errCh := make(chan error, 1)
+
+	go func() { errCh <- job.Attach(ctx, c) }()
+
+	return errCh
+}
+
+func wait(t *testing.T, errCh <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Attach did not return")
+	}
+
+	return nil
+}
+
+func waitDone(t *testing.T, job *Job) {
+	t.Helper()
+
+	select {
+	case <-job.done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not finish")
+	}
+}
+
+var spec = Spec{Owner: "alice", Device: "dev", Command: "flash"}
+
+func TestAttachReplaysOutput(t *testing.T) {
+	r := New(context.Background())
+
+	job := r.Start(context.Background(), spec, func(_ context.Context, s session.Stream) error {
+		s.Send(printMsg("one"))
+		s.Send(printMsg("two"))
+
+		return nil
+	})
+
+	waitDone(t, job)
+
+	// Every client attaching gets the whole output, also after the job finished.
+	for range 2 {
+		c := newClient()
+		errCh := attach(context.Background(), job, c)
+
+		for _, want := range []string{"one", "two"} {
+			if got := string(c.next(t).GetPrint().GetText()); got != want {
+				t.Fatalf("replayed %q, want %q", got, want)
+			}
+		}
+
+		if err := wait(t, errCh); err != nil {
+			t.Fatalf("Attach = %v, want nil", err)
+		}
+	}
+
+	if info := job.Info(); info.State != Succeeded || info.FinishedAt.IsZero() {
+		t.Fatalf("job state = %v (finished %v), want succeeded", info.State, info.FinishedAt)
+	}
+}
+
+func TestAttachReturnsJobError(t *testing.T) {
+	r := New(context.Background())
+	jobErr := errors.New("module failed")
+
+	job := r.Start(context.Background(), spec, func(context.Context, session.Stream) error { return jobErr })
+	waitDone(t, job)
+
+	if err := wait(t, attach(context.Background(), job, newClient())); !errors.Is(err, jobErr) {
+		t.Fatalf("Attach = %v, want %v", err, jobErr)
+	}
+
+	if state := job.Info().State; state != Failed {
+		t.Fatalf("job state = %v, want failed", state)
+	}
+}
+
+func TestAttachForwardsClientMessages(t *testing.T) {
+	r := New(context.Background())
+
+	job := r.Start(context.Background(), spec, func(_ context.Context, s session.Stream) error {
+		req, err := s.Receive()
+		if err != nil {
+			return err
+		}
+
+		return s.Send(printMsg(string(req.GetConsole().GetStdin())))
+	})
+
+	c := newClient()
+	errCh := attach(context.Background(), job, c)
+
+	c.recv <- &pb.RunRequest{Msg: &pb.RunRequest_Console{Console: &pb.Console{Data: &pb.Console_Stdin{Stdin: []byte("ping")}}}}
+
+	if got := string(c.next(t).GetPrint().GetText()); got != "ping" {
+		t.Fatalf("job echoed %q, want %q", got, "ping")
+	}
+
+	if err := wait(t, errCh); err != nil {
+		t.Fatalf("Attach = %v, want nil", err)
+	}
+}
+
+func TestDetachLeavesJobRunning(t *testing.T) {
+	r := New(context.Background())
+	release := make(chan struct{})
+
+	job := r.Start(context.Background(), spec, func(_ context.Context, s session.Stream) error {
+		s.Send(printMsg("before"))
+		<-release
+		s.Send(printMsg("after"))
+
+		return nil
+	})
+
+	ctx, detach := context.WithCancel(context.Background())
+	first := newClient()
+	errCh := attach(ctx, job, first)
+
+	first.next(t)
+	detach()
+
+	if err := wait(t, errCh); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Attach = %v, want context.Canceled", err)
+	}
+
+	if info := job.Info(); info.State != Running || info.Attached {
+		t.Fatalf("job state = %v (attached %v), want running and detached", info.State, info.Attached)
+	}
+
+	close(release)
+	waitDone(t, job)
+
+	second := newClient()
+	errCh = attach(context.Background(), job, second)
+
+	for _, want := range []string{"before", "after"} {
+		if got := string(second.next(t).GetPrint().GetText()); got != want {
+			t.Fatalf("replayed %q, want %q", got, want)
+		}
+	}
+
+	if err := wait(t, errCh); err != nil {
+		t.Fatalf("Attach = %v, want nil", err)
+	}
+}
+
+func TestInteractiveMessageDeliveredOnce(t *testing.T) {
+	r := New(context.Background())
+	sent := make(chan error, 1)
+
+	job := r.Start(context.Background(), spec, func(ctx context.Context, s session.Stream) error {
+		// Blocks until a client is attached to receive the request.
+		sent <- s.Send(&pb.RunResponse{Msg: &pb.RunResponse_FileRequest{FileRequest: &pb.FileRequest{Path: "fw.bin"}}})
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+
+	select {
+	case err := <-sent:
+		t.Fatalf("file request sent without an attached client (err %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctx, detach := context.WithCancel(context.Background())
+	first := newClient()
+	errCh := attach(ctx, job, first)
+
+	if first.next(t).GetFileRequest() == nil {
+		t.Fatal("attached client did not receive the file request")
+	}
+
+	if err := <-sent; err != nil {
+		t.Fatalf("Send = %v, want nil", err)
+	}
+
+	detach()
+	wait(t, errCh)
+
+	second := newClient()
+	errCh = attach(context.Background(), job, second)
+
+	select {
+	case msg := <-second.sent:
+		t.Fatalf("file request delivered twice: %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := r.Cancel(job.ID(), spec.Owner); err != nil {
+		t.Fatalf("Cancel = %v", err)
+	}
+
+	wait(t, errCh)
+}
+
+func TestAttachTakeover(t *testing.T) {
+	r := New(context.Background())
+
+	job := r.Start(context.Background(), spec, func(ctx context.Context, _ session.Stream) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+	defer r.Cancel(job.ID(), spec.Owner)
+
+	first := attach(context.Background(), job, newClient())
+
+	// Wait for the first attachment to register before taking it over.
+	for !job.Info().Attached {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := attach(context.Background(), job, newClient())
+
+	if err := wait(t, first); !errors.Is(err, ErrTakenOver) {
+		t.Fatalf("first Attach = %v, want ErrTakenOver", err)
+	}
+
+	if !job.Info().Attached {
+		t.Fatal("job lost the second attachment")
+	}
+
+	r.Cancel(job.ID(), spec.Owner)
+	wait(t, second)
+}
+
+func TestCancel(t *testing.T) {
+	r := New(context.Background())
+
+	job := r.Start(context.Background(), spec, func(ctx context.Context, _ session.Stream) error {
+		<-ctx.Done()
+
+		if !errors.Is(context.Cause(ctx), ErrCanceled) {
+			t.Errorf("job cancelled with cause %v, want ErrCanceled", context.Cause(ctx))
+		}
+
+		return ctx.Err()
+	})
+
+	if err := r.Cancel(job.ID(), "mallory"); !errors.Is(err, ErrWrongOwner) {
+		t.Fatalf("Cancel by another user = %v, want ErrWrongOwner", err)
+	}
+
+	if err := r.Cancel(job.ID(), spec.Owner); err != nil {
+		t.Fatalf("Cancel = %v", err)
+	}
+
+	waitDone(t, job)
+
+	if state := job.Info().State; state != Canceled {
+		t.Fatalf("job state = %v, want canceled", state)
+	}
+
+	if err := r.Cancel(job.ID(), spec.Owner); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("second Cancel = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestShutdownCancelsJobs(t *testing.T) {
+	ctx, shutdown := context.WithCancel(context.Background())
+	r := New(ctx)
+
+	job := r.Start(context.Background(), spec, func(ctx context.Context, _ session.Stream) error {
+		<-ctx.Done()
+
+		return context.Cause(ctx)
+	})
+
+	shutdown()
+	waitDone(t, job)
+
+	if info := job.Info(); info.State != Canceled || !errors.Is(info.Err, ErrShutdown) {
+		t.Fatalf("job state = %v (err %v), want canceled by shutdown", info.State, info.Err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	r := New(context.Background())
+	job := r.Start(context.Background(), spec, func(context.Context, session.Stream) error { return nil })
+
+	if got, err := r.Get(job.ID(), spec.Owner); err != nil || got != job {
+		t.Fatalf("Get = %v, %v; want the job", got, err)
+	}
+
+	if _, err := r.Get(job.ID(), "mallory"); !errors.Is(err, ErrWrongOwner) {
+		t.Fatalf("Get by another user = %v, want ErrWrongOwner", err)
+	}
+
+	if _, err := r.Get("nope", spec.Owner); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of an unknown job = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListPrunesExpiredJobs(t *testing.T) {
+	r := New(context.Background())
+
+	old := r.Start(context.Background(), spec, func(context.Context, session.Stream) error { return nil })
+	waitDone(t, old)
+
+	old.mu.Lock()
+	old.finishedAt = time.Now().Add(-retention - time.Minute)
+	old.mu.Unlock()
+
+	running := r.Start(context.Background(), spec, func(ctx context.Context, _ session.Stream) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+	defer r.Cancel(running.ID(), spec.Owner)
+
+	list := r.List()
+	if len(list) != 1 || list[0].ID != running.ID() {
+		t.Fatalf("List = %+v, want only the running job", list)
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	r := New(context.Background())
+	chunk := string(bytes.Repeat([]byte("x"), 64*1024))
+
+	job := r.Start(context.Background(), spec, func(_ context.Context, s session.Stream) error {
+		for range 2 * outputLimit / len(chunk) {
+			s.Send(printMsg(chunk))
+		}
+
+		return s.Send(printMsg("last"))
+	})
+
+	waitDone(t, job)
+
+	c := newClient()
+	c.sent = make(chan *pb.RunResponse, 2*outputLimit/len(chunk)+2)
+
+	if err := wait(t, attach(context.Background(), job, c)); err != nil {
+		t.Fatalf("Attach = %v, want nil", err)
+	}
+
+	close(c.sent)
+
+	var (
+		msgs []*pb.RunResponse
+		size int
+	)
+
+	for msg := range c.sent {
+		msgs = append(msgs, msg)
+		size += len(msg.GetPrint().GetText())
+	}
+
+	if !bytes.Contains(msgs[0].GetPrint().GetText(), []byte("dropped")) {
+		t.Fatalf("first replayed message %q is no notice of dropped output", msgs[0].GetPrint().GetText())
+	}
+
+	if last := string(msgs[len(msgs)-1].GetPrint().GetText()); last != "last" {
+		t.Fatalf("last replayed message = %q, want %q", last, "last")
+	}
+
+	if size > outputLimit {
+		t.Fatalf("replayed %d bytes of output, more than the limit of %d", size, outputLimit)
+	}
+}
@@ -15,6 +15,10 @@ import (
 	"github.com/BlindspotSoftware/dutctl/pkg/module"
 )
 
+// signalBuffer is the number of signals from the client held for a module that
+// has not received them yet. Beyond it, signals are dropped.
+const signalBuffer = 8
+
 // numWorkers is the number of broker workers. One worker handles module-to-client communication,
 // the other handles client-to-module communication.
 const numWorkers = 2
@@ -44,7 +48,11 @@ func (b *Broker) init() {
 	b.session.stdoutCh = make(chan []byte)
 	b.session.stderrCh = make(chan []byte)
 	b.session.fileReqCh = make(chan string)
-	b.session.fileCh = make(chan chan []byte)
+	b.session.uploadCh = make(chan *transfer)
+	b.session.downloadCh = make(chan *transfer)
+	b.session.signalCh = make(chan module.Signal, signalBuffer)
+	b.session.canceled = make(chan struct{})
+	b.session.windowSizeCh = make(chan module.WindowSize, 1)
 
 	// Buffer equals number of workers so error sends never block.
 	b.errCh = make(chan error, numWorkers)
@@ -92,6 +100,14 @@ func (b *Broker) Start(ctx context.Context, s Stream) (module.Session, <-chan er
 	return &b.session, b.errCh
 }
 
+// Canceled returns a channel that is closed when the client cancels the command
+// execution with a Cancel message. Unlike a client that goes away, a canceling
+// client stays connected: the caller is expected to cancel the modules and let
+// the broker carry their remaining output. Canceled must be called after Start.
+func (b *Broker) Canceled() <-chan struct{} {
+	return b.session.canceled
+}
+
 func (b *Broker) toClient(ctx context.Context, cancel context.CancelFunc) {
 	// Scope the downstream (agent → client) flow; the worker and its chanio
 	// reader inherit it from ctx.
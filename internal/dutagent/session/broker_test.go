@@ -14,6 +14,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+
 	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
 )
 
@@ -153,6 +155,108 @@ func TestBroker_StdinForwarding(t *testing.T) {
 	_ = collectErrors(t, errCh, 200*time.Millisecond) // expect none
 }
 
+// Signals from the client are passed on to the module in order, an unknown one
+// is skipped, and a Cancel closes the Canceled channel without ending the session.
+func TestBroker_SignalsAndCancel(t *testing.T) {
+	signal := func(kind pb.SignalKind) *pb.RunRequest {
+		return &pb.RunRequest{Msg: &pb.RunRequest_Signal{Signal: &pb.Signal{Kind: kind}}}
+	}
+
+	stream := &testStream{recvReqs: []*pb.RunRequest{
+		signal(pb.SignalKind_SIGNAL_KIND_INTERRUPT),
+		signal(pb.SignalKind_SIGNAL_KIND_UNSPECIFIED),
+		signal(pb.SignalKind_SIGNAL_KIND_EOF),
+		{Msg: &pb.RunRequest_Cancel{Cancel: &pb.Cancel{}}},
+	}}
+
+	b := &Broker{}
+	ctx, cancel := context.WithCancel(context.Background())
+	sess, errCh := b.Start(ctx, stream)
+
+	select {
+	case <-b.Canceled():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the cancel")
+	}
+
+	for _, want := range []module.Signal{module.Interrupt, module.EOF} {
+		select {
+		case got := <-sess.Signals():
+			if got != want {
+				t.Errorf("signal = %v, want %v", got, want)
+			}
+		default:
+			t.Fatalf("signal %v not delivered", want)
+		}
+	}
+
+	cancel()
+
+	if errs := collectErrors(t, errCh, 200*time.Millisecond); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestBroker_Console(t *testing.T) {
+	stream := newChanStream()
+
+	b := &Broker{}
+	ctx, cancel := context.WithCancel(context.Background())
+	sess, errCh := b.Start(ctx, stream)
+
+	_, stdout, _ := sess.Console()
+
+	res := stream.next(t)
+	if _, ok := res.GetConsole().GetData().(*pb.Console_Stdout); !ok || len(res.GetConsole().GetStdout()) != 0 {
+		t.Fatalf("first message = %v, want empty stdout opening the console", res)
+	}
+
+	// Only the first call opens the session.
+	_, stdout, _ = sess.Console()
+
+	go stdout.Write([]byte("hello")) //nolint:errcheck // checked through the stream
+
+	if got := string(stream.next(t).GetConsole().GetStdout()); got != "hello" {
+		t.Fatalf("second message = %q, want module output", got)
+	}
+
+	windowSize := func(rows, cols uint32) *pb.RunRequest {
+		return &pb.RunRequest{Msg: &pb.RunRequest_Console{Console: &pb.Console{
+			Data: &pb.Console_WindowSize{WindowSize: &pb.WindowSize{Rows: rows, Cols: cols}},
+		}}}
+	}
+
+	stream.recv <- windowSize(24, 80)
+	stream.recv <- windowSize(0, 80)
+	stream.recv <- windowSize(50, 120)
+	stream.recv <- &pb.RunRequest{Msg: &pb.RunRequest_Cancel{Cancel: &pb.Cancel{}}}
+
+	<-b.Canceled()
+
+	// Only the latest valid size is kept.
+	select {
+	case got := <-sess.WindowSizes():
+		if want := (module.WindowSize{Rows: 50, Cols: 120}); got != want {
+			t.Errorf("window size = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("window size not delivered")
+	}
+
+	select {
+	case got := <-sess.WindowSizes():
+		t.Errorf("unexpected window size %v", got)
+	default:
+	}
+
+	cancel()
+	close(stream.recv)
+
+	if errs := collectErrors(t, errCh, 200*time.Millisecond); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
 // Cancellation during a blocked receive should terminate fromClientWorker without producing errors.
 func TestBroker_CancelDuringBlockedReceive(t *testing.T) {
 	b := &Broker{}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/BlindspotSoftware/dutctl/internal/chanio"
 	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 )
 
 // errSessionClosed is returned by the module-facing methods when the session
@@ -27,7 +28,26 @@ type backend struct {
 	stdoutCh  chan []byte
 	stderrCh  chan []byte
 	fileReqCh chan string
-	fileCh    chan chan []byte // a single file is represented by a channel of bytes
+
+	// uploadCh hands a file the client sends (upon RequestFile) from
+	// fromClientWorker to the module, downloadCh hands a file the module sends
+	// (SendFile) to toClientWorker. The directions use separate channels so a
+	// worker can never pick up a transfer meant for the module and vice versa.
+	uploadCh   chan *transfer
+	downloadCh chan *transfer
+
+	// signalCh buffers the signals from the client until the module receives
+	// them (see Signals). canceled is closed, once, when the client cancels the
+	// command execution.
+	signalCh   chan module.Signal
+	canceled   chan struct{}
+	cancelOnce sync.Once
+
+	// windowSizeCh holds the latest size of the client's terminal until the
+	// module receives it (see WindowSizes). consoleOnce guards opening the
+	// console session towards the client, which happens once per command.
+	windowSizeCh chan module.WindowSize
+	consoleOnce  sync.Once
 
 	// mu guards currentFile, which is read and written from the module goroutine
 	// (SendFile) and from both broker workers, with no channel handing it between
@@ -137,13 +157,74 @@ func (s *backend) Console() (stdin io.Reader, stdout, stderr io.Writer) {
 		panic(fmt.Sprintf("session.Console: stderr writer: %v", err))
 	}
 
+	// Open the session towards the client with an empty stdout message, so it
+	// can prepare its terminal before the module's first output.
+	s.consoleOnce.Do(func() {
+		select {
+		case s.stdoutCh <- []byte{}:
+		case <-s.done:
+		}
+	})
+
 	return stdinReader, stdoutWriter, stderrWriter
 }
 
+// Signals returns the channel delivering the signals from the client (see
+// module.Session).
+func (s *backend) Signals() <-chan module.Signal {
+	return s.signalCh
+}
+
+// signal passes sig on to the module without blocking: a module that does not
+// receive signals must not stall the client's other messages. It reports whether
+// the signal was delivered to the buffer.
+func (s *backend) signal(sig module.Signal) bool {
+	select {
+	case s.signalCh <- sig:
+		return true
+	default:
+		return false
+	}
+}
+
+// WindowSizes returns the channel delivering the size of the client's terminal
+// (see module.Session).
+func (s *backend) WindowSizes() <-chan module.WindowSize {
+	return s.windowSizeCh
+}
+
+// resize passes the size of the client's terminal on to the module, replacing a
+// size the module did not receive yet. It never blocks. Only fromClientWorker
+// calls it, so the replaced size cannot be refilled concurrently.
+func (s *backend) resize(size module.WindowSize) {
+	for {
+		select {
+		case s.windowSizeCh <- size:
+			return
+		default:
+		}
+
+		select {
+		case <-s.windowSizeCh:
+		default:
+		}
+	}
+}
+
+// cancel records that the client canceled the command execution.
+func (s *backend) cancel() {
+	s.cancelOnce.Do(func() { close(s.canceled) })
+}
+
 // RequestFile asks the client for the named file and returns a reader over its
-// contents. It blocks until the client responds. The returned error is opaque
+// contents. It blocks until the client starts sending the file; the content then
+// streams in chunk by chunk as the module reads. The returned error is opaque
 // (reported to the module as-is): it means the session was not initialized or the
 // file stream could not be adapted, and is not meant to be matched.
+//
+// Reading from the returned reader fails instead of reporting io.EOF if the
+// transfer was not completed or did not pass the integrity check, so a module
+// never mistakes a truncated or corrupted file for the real one.
 func (s *backend) RequestFile(name string) (io.Reader, error) {
 	if s.fileReqCh == nil {
 		return nil, errors.New("session not initialized: file request channel is nil")
@@ -162,17 +243,17 @@ func (s *backend) RequestFile(name string) (io.Reader, error) {
 		return nil, fmt.Errorf("request file %q: %w", name, errSessionClosed)
 	}
 
-	var file chan []byte
+	var file *transfer
 
 	select {
-	case file = <-s.fileCh:
+	case file = <-s.uploadCh:
 	case <-s.done:
 		return nil, fmt.Errorf("request file %q: %w", name, errSessionClosed)
 	}
 
-	// The received channel is fed and closed by fromClientWorker right after the
-	// rendezvous, so this read always terminates: pass a nil done.
-	r, err := chanio.NewChanReader(file, nil, uplog)
+	// fromClientWorker closes the chunk channel on the final chunk, and
+	// fails and closes it if it exits mid-transfer, so reads always terminate.
+	r, err := file.reader(uplog)
 	if err != nil {
 		return nil, fmt.Errorf("request file %q: %w", name, err)
 	}
@@ -180,40 +261,87 @@ func (s *backend) RequestFile(name string) (io.Reader, error) {
 	return r, nil
 }
 
-// SendFile streams r to the client under the given name. It returns an error if a
-// file transfer is already in progress or if reading r fails. The error is opaque
-// (reported to the module as-is) and not meant to be matched.
+// SendFile streams r to the client under the given name. The content is read
+// and sent chunk by chunk, so r is never held in memory as a whole. It returns
+// once the client was sent the final chunk, or with an error if a file transfer
+// is already in progress, if reading r fails or if the transfer could not be
+// delivered. The error is opaque (reported to the module as-is) and not meant
+// to be matched.
 func (s *backend) SendFile(name string, r io.Reader) error {
 	if s.currentFileName() != "" {
 		return fmt.Errorf("send file %q: a file request is already in progress", name)
 	}
 
-	content, err := io.ReadAll(r)
-	if err != nil {
-		return fmt.Errorf("send file %q: read source: %w", name, err)
-	}
-
 	// Sending a file to the client is the downstream (agent → client) flow.
 	downlog := log.Scope(s.logger(), scopeSessionDownstream)
-	downlog.Debug("module sending file", "name", name, "bytes", len(content))
+
+	file := newTransfer(name, sizeHint(r))
+	downlog.Debug("module sending file", "name", name, "size", file.size)
 
 	s.setCurrentFile(name)
 
-	file := make(chan []byte, 1)
+	// Clear the in-flight file however the transfer ends, so a failed or
+	// cancelled download is not taken for the next transfer.
+	defer s.setCurrentFile("")
 
-	// Hand the file to toClientWorker. Guard the send with done: if the session
-	// is torn down first, return rather than wedge the module goroutine. The
-	// buffered content send and close below never block once the rendezvous
-	// succeeds.
+	// Hand the file to toClientWorker, then feed it. Guard every send with done:
+	// if the session is torn down first, return rather than wedge the module
+	// goroutine.
 	select {
-	case s.fileCh <- file:
+	case s.downloadCh <- file:
 	case <-s.done:
 		return fmt.Errorf("send file %q: %w", name, errSessionClosed)
 	}
 
-	file <- content
+	err := feed(file, r, s.done)
 
-	close(file) // indicate EOF.
+	close(file.chunks) // indicate EOF, or the failure recorded by feed.
+
+	if err != nil {
+		return fmt.Errorf("send file %q: %w", name, err)
+	}
+
+	select {
+	case err = <-file.delivered:
+	case <-s.done:
+		return fmt.Errorf("send file %q: %w", name, errSessionClosed)
+	}
+
+	if err != nil {
+		return fmt.Errorf("send file %q: %w", name, err)
+	}
 
 	return nil
 }
+
+// feed reads r in chunks of at most fileChunkSize and passes them to the
+// transfer's consumer. A read failure is recorded on the transfer, so the
+// consumer does not complete it, and returned. The caller closes the chunk
+// channel.
+func feed(file *transfer, r io.Reader, done <-chan struct{}) error {
+	for {
+		// Each chunk gets its own buffer: the consumer may still hold the previous one.
+		buf := make([]byte, fileChunkSize)
+
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			select {
+			case file.chunks <- buf[:n]:
+			case <-done:
+				file.fail(errSessionClosed)
+
+				return errSessionClosed
+			}
+		}
+
+		switch {
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return nil
+		case err != nil:
+			err = fmt.Errorf("read source: %w", err)
+			file.fail(err)
+
+			return err
+		}
+	}
+}
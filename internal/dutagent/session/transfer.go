@@ -0,0 +1,115 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/BlindspotSoftware/dutctl/internal/chanio"
+)
+
+// fileChunkSize is the largest payload the agent puts into a single File message.
+// Together with the unbuffered chunk channel of a transfer it bounds the memory a
+// file transfer holds at any time, regardless of the size of the file.
+const fileChunkSize = 64 * 1024
+
+// transfer is a single file in flight between a module and a broker worker.
+//
+// The producing side feeds the file's content as chunks and closes the channel
+// after the last one, so the consuming side holds at most one chunk at a time.
+// A producer that cannot complete the transfer records the reason with fail
+// before closing the channel; the consumer then observes that error in place of
+// a clean end of file.
+type transfer struct {
+	name   string
+	size   int64 // total size in bytes, 0 if unknown
+	chunks chan []byte
+
+	// delivered reports the outcome of a download (module → client) back to
+	// SendFile once toClientWorker has sent the final chunk or given up. It is
+	// buffered so the worker never blocks on a module that is already gone.
+	delivered chan error
+
+	mu  sync.Mutex
+	err error
+}
+
+func newTransfer(name string, size int64) *transfer {
+	return &transfer{
+		name:      name,
+		size:      size,
+		chunks:    make(chan []byte),
+		delivered: make(chan error, 1),
+	}
+}
+
+// fail records why the transfer could not be completed. It must be called
+// before the producer closes the chunk channel; the first reason wins.
+func (t *transfer) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// failure returns the reason recorded by fail, or nil for an intact transfer.
+func (t *transfer) failure() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// reader returns an io.Reader over the transfer's chunks. It reports io.EOF once
+// the producer closed the chunk channel, or the recorded failure if the transfer
+// was not completed. The producer must always close the channel, so the reader
+// is not bound to the session's done signal.
+func (t *transfer) reader(logger *slog.Logger) (io.Reader, error) {
+	r, err := chanio.NewChanReader(t.chunks, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferReader{t: t, r: r}, nil
+}
+
+// transferReader is the module-facing reader of an upload (client → module).
+type transferReader struct {
+	t *transfer
+	r io.Reader
+}
+
+func (tr *transferReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		if failure := tr.t.failure(); failure != nil {
+			return n, failure
+		}
+	}
+
+	return n, err
+}
+
+// Size returns the size of the file as announced by the client, or 0 if it
+// announced none.
+func (tr *transferReader) Size() int64 {
+	return tr.t.size
+}
+
+// sizeHint reports the number of bytes left in r if it can be learned without
+// consuming r, and 0 otherwise. Readers over in-memory data (bytes.Buffer,
+// bytes.Reader, strings.Reader) expose it through a Len method.
+func sizeHint(r io.Reader) int64 {
+	if l, ok := r.(interface{ Len() int }); ok {
+		return int64(l.Len())
+	}
+
+	return 0
+}
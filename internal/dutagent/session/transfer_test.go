@@ -0,0 +1,368 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
+)
+
+// chanStream is a Stream driven by the test through channels, so a test can
+// answer the agent's messages as they arrive. Receive reports io.EOF once recv
+// is closed.
+type chanStream struct {
+	recv chan *pb.RunRequest
+	sent chan *pb.RunResponse
+}
+
+func newChanStream() *chanStream {
+	return &chanStream{
+		recv: make(chan *pb.RunRequest),
+		sent: make(chan *pb.RunResponse, 64),
+	}
+}
+
+func (s *chanStream) Send(msg *pb.RunResponse) error {
+	s.sent <- msg
+
+	return nil
+}
+
+func (s *chanStream) Receive() (*pb.RunRequest, error) {
+	req, ok := <-s.recv
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return req, nil
+}
+
+func (s *chanStream) next(t *testing.T) *pb.RunResponse {
+	t.Helper()
+
+	select {
+	case res := <-s.sent:
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the agent to send a message")
+	}
+
+	return nil
+}
+
+func fileRequest(file *pb.File) *pb.RunRequest {
+	return &pb.RunRequest{Msg: &pb.RunRequest_File{File: file}}
+}
+
+// chunked splits content into File messages of at most size bytes, the way a
+// client uploads a file.
+func chunked(path string, content []byte, size int) []*pb.File {
+	var (
+		files  []*pb.File
+		offset int
+	)
+
+	for {
+		end := min(offset+size, len(content))
+		files = append(files, &pb.File{
+			Path:    path,
+			Content: content[offset:end],
+			Offset:  int64(offset),
+			Size:    int64(len(content)),
+			More:    end < len(content),
+		})
+
+		offset = end
+		if offset == len(content) {
+			break
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	files[len(files)-1].Sha256 = sum[:]
+
+	return files
+}
+
+// startUpload starts a broker, lets a module request name and answers the file
+// request with files. It returns the module's read result and the broker's errors.
+func startUpload(t *testing.T, name string, files []*pb.File) ([]byte, error, []error) {
+	t.Helper()
+
+	stream := newChanStream()
+	b := &Broker{}
+	sess, errCh := b.Start(context.Background(), stream)
+
+	type result struct {
+		content []byte
+		err     error
+	}
+
+	resCh := make(chan result, 1)
+
+	go func() {
+		r, err := sess.RequestFile(name)
+		if err != nil {
+			resCh <- result{err: err}
+
+			return
+		}
+
+		// The reader tells the size the client announced with the first chunk.
+		if sized, ok := r.(interface{ Size() int64 }); !ok || sized.Size() != files[0].GetSize() {
+			t.Errorf("reader of the requested file does not tell the announced size %d", files[0].GetSize())
+		}
+
+		content, err := io.ReadAll(r)
+		resCh <- result{content: content, err: err}
+	}()
+
+	if got := stream.next(t).GetFileRequest().GetPath(); got != name {
+		t.Fatalf("file request path = %q, want %q", got, name)
+	}
+
+	go func() {
+		for _, f := range files {
+			stream.recv <- fileRequest(f)
+		}
+
+		close(stream.recv)
+	}()
+
+	var res result
+
+	select {
+	case res = <-resCh:
+	case <-time.After(time.Second):
+		t.Fatal("module read did not terminate")
+	}
+
+	return res.content, res.err, collectErrors(t, errCh, time.Second)
+}
+
+func TestRequestFileChunked(t *testing.T) {
+	content := bytes.Repeat([]byte("firmware"), 1000)
+
+	got, err, errs := startUpload(t, "fw.bin", chunked("fw.bin", content, 1024))
+	if err != nil {
+		t.Fatalf("reading requested file: %v", err)
+	}
+
+	if !bytes.Equal(got, content) {
+		t.Fatalf("received %d bytes, want %d matching bytes", len(got), len(content))
+	}
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected broker errors: %v", errs)
+	}
+}
+
+func TestRequestFileSingleMessage(t *testing.T) {
+	// A file sent as a single message without size or digest is a valid transfer.
+	file := &pb.File{Path: "cfg.txt", Content: []byte("single message")}
+
+	got, err, errs := startUpload(t, "cfg.txt", []*pb.File{file})
+	if err != nil || string(got) != "single message" {
+		t.Fatalf("got %q, %v; want %q, nil", got, err, "single message")
+	}
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected broker errors: %v", errs)
+	}
+}
+
+func TestRequestFileEmpty(t *testing.T) {
+	got, err, errs := startUpload(t, "empty", chunked("empty", nil, 1024))
+	if err != nil || len(got) != 0 {
+		t.Fatalf("got %q, %v; want empty file", got, err)
+	}
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected broker errors: %v", errs)
+	}
+}
+
+func TestRequestFileBadTransfer(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 4096)
+
+	tests := []struct {
+		name  string
+		files func() []*pb.File
+	}{
+		{
+			name: "checksum mismatch",
+			files: func() []*pb.File {
+				files := chunked("fw.bin", content, 1024)
+				files[len(files)-1].Sha256 = make([]byte, sha256.Size)
+
+				return files
+			},
+		},
+		{
+			name: "size mismatch",
+			files: func() []*pb.File {
+				files := chunked("fw.bin", content, 1024)
+				for _, f := range files {
+					f.Size++
+				}
+
+				return files
+			},
+		},
+		{
+			name: "offset gap",
+			files: func() []*pb.File {
+				files := chunked("fw.bin", content, 1024)
+
+				return append(files[:1], files[2:]...)
+			},
+		},
+		{
+			name: "wrong path",
+			files: func() []*pb.File {
+				files := chunked("fw.bin", content, 1024)
+				files[1].Path = "other.bin"
+
+				return files
+			},
+		},
+		{
+			name: "missing final chunk",
+			files: func() []*pb.File {
+				files := chunked("fw.bin", content, 1024)
+
+				return files[:len(files)-1]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err, errs := startUpload(t, "fw.bin", tt.files())
+			if err == nil {
+				t.Fatal("module read an incomplete or corrupted file without error")
+			}
+
+			if tt.name == "missing final chunk" {
+				return // the client closing the stream is not a protocol violation
+			}
+
+			if len(errs) != 1 || !errors.Is(errs[0], ErrBadFileTransfer) {
+				t.Fatalf("broker errors = %v, want one ErrBadFileTransfer", errs)
+			}
+		})
+	}
+}
+
+func TestSendFileChunked(t *testing.T) {
+	content := bytes.Repeat([]byte("result"), fileChunkSize/2)
+
+	stream := newChanStream()
+	b := &Broker{}
+	ctx, cancel := context.WithCancel(context.Background())
+	sess, errCh := b.Start(ctx, stream)
+
+	sendErr := make(chan error, 1)
+
+	go func() { sendErr <- sess.SendFile("out.log", bytes.NewReader(content)) }()
+
+	var (
+		got   []byte
+		final *pb.File
+	)
+
+	for final == nil {
+		file := stream.next(t).GetFile()
+		if file == nil {
+			t.Fatal("agent sent a non-file message during the transfer")
+		}
+
+		if file.GetPath() != "out.log" || file.GetSize() != int64(len(content)) {
+			t.Fatalf("chunk path/size = %q/%d, want %q/%d", file.GetPath(), file.GetSize(), "out.log", len(content))
+		}
+
+		if file.GetOffset() != int64(len(got)) {
+			t.Fatalf("chunk offset = %d, want %d", file.GetOffset(), len(got))
+		}
+
+		if len(file.GetContent()) > fileChunkSize {
+			t.Fatalf("chunk of %d bytes exceeds the chunk size", len(file.GetContent()))
+		}
+
+		got = append(got, file.GetContent()...)
+
+		if !file.GetMore() {
+			final = file
+		}
+	}
+
+	if !bytes.Equal(got, content) {
+		t.Fatalf("received %d bytes, want %d matching bytes", len(got), len(content))
+	}
+
+	if sum := sha256.Sum256(content); !bytes.Equal(final.GetSha256(), sum[:]) {
+		t.Fatal("final chunk carries a wrong digest")
+	}
+
+	select {
+	case err := <-sendErr:
+		if err != nil {
+			t.Fatalf("SendFile: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendFile did not return after the final chunk")
+	}
+
+	cancel()
+	close(stream.recv)
+
+	if errs := collectErrors(t, errCh, time.Second); len(errs) != 0 {
+		t.Fatalf("unexpected broker errors: %v", errs)
+	}
+}
+
+func TestSendFileSourceError(t *testing.T) {
+	stream := newChanStream()
+	b := &Broker{}
+	sess, errCh := b.Start(context.Background(), stream)
+
+	readErr := errors.New("disk on fire")
+	src := io.MultiReader(bytes.NewReader([]byte("partial")), &failingReader{err: readErr})
+
+	err := sess.SendFile("out.log", src)
+	if !errors.Is(err, readErr) {
+		t.Fatalf("SendFile err = %v, want %v", err, readErr)
+	}
+
+	if name := sess.(*backend).currentFileName(); name != "" {
+		t.Fatalf("in-flight file after the failed transfer = %q, want none", name)
+	}
+
+	close(stream.recv)
+
+	// The client must never be sent a final chunk for the incomplete file.
+	for _, e := range collectErrors(t, errCh, time.Second) {
+		if !errors.Is(e, readErr) {
+			t.Fatalf("unexpected broker error: %v", e)
+		}
+	}
+
+	for len(stream.sent) > 0 {
+		if file := (<-stream.sent).GetFile(); file != nil && !file.GetMore() {
+			t.Fatal("agent completed a file whose source failed")
+		}
+	}
+}
+
+type failingReader struct{ err error }
+
+func (r *failingReader) Read([]byte) (int, error) { return 0, r.err }
@@ -5,13 +5,17 @@
 package session
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"math"
 
-	"github.com/BlindspotSoftware/dutctl/internal/chanio"
 	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 
 	pb "github.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1"
 )
@@ -22,13 +26,11 @@ import (
 var ErrBadFileTransfer = errors.New("bad file transfer")
 
 // toClientWorker sends messages from the module session to the client.
-// It loops until ctx is cancelled (returning nil) or a stream send fails
-// (returning that error).
+// It loops until ctx is cancelled (returning nil) or a stream send or a file
+// download fails (returning that error).
 //
 //nolint:cyclop, funlen
 func toClientWorker(ctx context.Context, stream Stream, s *backend) error {
-	l := log.FromContext(ctx)
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -75,40 +77,175 @@ func toClientWorker(ctx context.Context, stream Stream, s *backend) error {
 			if err != nil {
 				return err
 			}
-		case file := <-s.fileCh:
-			// The channel is fed and closed by SendFile right after the
-			// rendezvous, so this read always terminates: pass a nil done.
-			r, err := chanio.NewChanReader(file, nil, l)
-			if err != nil {
-				return err
-			}
+		case file := <-s.downloadCh:
+			err := sendTransfer(ctx, stream, s, file)
+
+			// Report the outcome to the module's SendFile; the channel is
+			// buffered, so this never blocks.
+			file.delivered <- err
 
-			content, err := io.ReadAll(r)
 			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
 				return err
 			}
+		}
+	}
+}
 
-			name := s.currentFileName()
+// sendTransfer sends a file the module passed to SendFile to the client, one File
+// message per chunk, and concludes it with a final chunk carrying the size and
+// SHA-256 digest of the whole file. It returns an error without sending the final
+// chunk if ctx is cancelled (errSessionClosed), a stream send fails or the module
+// could not read the file, so the client never sees an incomplete file completed.
+func sendTransfer(ctx context.Context, stream Stream, s *backend, file *transfer) error {
+	l := log.FromContext(ctx)
 
-			l.Debug("file received from module", "name", name, "bytes", len(content))
+	var offset int64
 
-			res := &pb.RunResponse{
-				Msg: &pb.RunResponse_File{
-					File: &pb.File{
-						Path:    name,
-						Content: content,
-					},
-				},
-			}
+	digest := sha256.New()
 
-			err = stream.Send(res)
-			if err != nil {
-				return err
-			}
+	for {
+		var (
+			chunk []byte
+			ok    bool
+		)
+
+		select {
+		case <-ctx.Done():
+			return errSessionClosed
+		case chunk, ok = <-file.chunks:
+		}
+
+		if !ok {
+			break
+		}
+
+		digest.Write(chunk)
+
+		err := stream.Send(fileResponse(&pb.File{
+			Path:    file.name,
+			Content: chunk,
+			Offset:  offset,
+			Size:    file.size,
+			More:    true,
+		}))
+		if err != nil {
+			return err
+		}
+
+		offset += int64(len(chunk))
+	}
+
+	err := file.failure()
+	if err != nil {
+		return fmt.Errorf("send file %q: %w", file.name, err)
+	}
+
+	l.Debug("file sent to client", "name", file.name, "bytes", offset)
+
+	return stream.Send(fileResponse(&pb.File{
+		Path:   file.name,
+		Offset: offset,
+		Size:   offset,
+		Sha256: digest.Sum(nil),
+	}))
+}
+
+func fileResponse(file *pb.File) *pb.RunResponse {
+	return &pb.RunResponse{Msg: &pb.RunResponse_File{File: file}}
+}
+
+// upload tracks the file the client is currently sending in chunks, upon the
+// module's RequestFile.
+type upload struct {
+	*transfer
+
+	offset int64
+	hash   hash.Hash
+}
+
+// receiveChunk validates a File message from the client against the requested
+// file and the chunks received so far, and passes its content on to the module.
+// The first chunk starts the upload and hands it to the module's RequestFile; the
+// final chunk is checked against the announced size and digest and completes it.
+// It returns the upload still in flight, or nil once the upload is complete.
+//
+// A malformed or corrupted transfer is reported as ErrBadFileTransfer; the upload
+// is then left to the caller to abort. A cancelled ctx is reported as ctx.Err().
+func receiveChunk(ctx context.Context, s *backend, up *upload, msg *pb.File) (*upload, error) {
+	if msg == nil {
+		return up, fmt.Errorf("%w: received empty file-message", ErrBadFileTransfer)
+	}
+
+	want := s.currentFileName()
+	if want == "" {
+		return up, fmt.Errorf("%w: received file-message without a former request", ErrBadFileTransfer)
+	}
+
+	path := msg.GetPath()
+	if path != want {
+		return up, fmt.Errorf("%w: received file-message %q but requested %q", ErrBadFileTransfer, path, want)
+	}
+
+	if up == nil {
+		up = &upload{transfer: newTransfer(path, msg.GetSize()), hash: sha256.New()}
 
-			s.setCurrentFile("")
+		// Hand the file to the module's RequestFile. Unlike the stdin send in
+		// fromClientWorker, the receiver is the module goroutine, which may
+		// already be gone on teardown; guard the send with ctx.Done so an
+		// abandoned transfer cannot wedge this worker (and, through wg.Wait, the
+		// broker) forever. The upload is only returned to the caller once the
+		// module holds it, so an aborted hand-off leaves nothing to clean up.
+		select {
+		case s.uploadCh <- up.transfer:
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
+
+	if msg.GetOffset() != up.offset {
+		return up, fmt.Errorf("%w: received chunk of %q at offset %d, expected %d",
+			ErrBadFileTransfer, path, msg.GetOffset(), up.offset)
+	}
+
+	content := msg.GetContent()
+	if len(content) > 0 {
+		up.hash.Write(content)
+
+		// The module reads at its own pace; blocking here is the backpressure
+		// that keeps the transfer's memory bounded.
+		select {
+		case up.chunks <- content:
+		case <-ctx.Done():
+			return up, ctx.Err()
+		}
+
+		up.offset += int64(len(content))
+	}
+
+	if msg.GetMore() {
+		return up, nil
+	}
+
+	if size := up.size; size != 0 && up.offset != size {
+		return up, fmt.Errorf("%w: received %d bytes of %q, announced %d", ErrBadFileTransfer, up.offset, path, size)
+	}
+
+	if sum := msg.GetSha256(); len(sum) > 0 && !bytes.Equal(sum, up.hash.Sum(nil)) {
+		return up, fmt.Errorf("%w: checksum mismatch for %q", ErrBadFileTransfer, path)
+	}
+
+	log.FromContext(ctx).Debug("received file from client", "name", path, "bytes", up.offset)
+
+	// Clear the in-flight file before closing the chunk channel, so a module
+	// that sees the end of the file may start the next transfer right away.
+	s.setCurrentFile("")
+	close(up.chunks)
+
+	return nil, nil //nolint:nilnil // a completed upload leaves nothing in flight
 }
 
 // fromClientWorker reads messages from the client and passes them to the module session.
@@ -119,6 +256,17 @@ func toClientWorker(ctx context.Context, stream Stream, s *backend) error {
 func fromClientWorker(ctx context.Context, stream Stream, s *backend) error {
 	l := log.FromContext(ctx)
 
+	// up is the upload in flight, if any. Whatever ends this worker before the
+	// final chunk arrived, the module must not read the partial file as complete.
+	var up *upload
+
+	defer func() {
+		if up != nil {
+			up.fail(fmt.Errorf("transfer of %q interrupted: %w", up.name, errSessionClosed))
+			close(up.chunks)
+		}
+	}()
+
 	type recvResult struct {
 		req *pb.RunRequest
 		err error
@@ -207,56 +355,84 @@ func fromClientWorker(ctx context.Context, stream Stream, s *backend) error {
 						return nil
 					case s.stdinCh <- stdin:
 					}
+				case *pb.Console_WindowSize:
+					size, ok := windowSize(consoleMsg.WindowSize)
+					if !ok {
+						l.Warn("ignoring invalid window size", "rows", consoleMsg.WindowSize.GetRows(),
+							"cols", consoleMsg.WindowSize.GetCols())
+
+						continue
+					}
 
+					l.Debug("received window size from client", "rows", size.Rows, "cols", size.Cols)
+					s.resize(size)
 				default:
 					l.Warn("unexpected console message", "type", fmt.Sprintf("%T", consoleMsg))
 				}
 			case *pb.RunRequest_File:
-				fileMsg := msg.File
-				if fileMsg == nil {
-					return fmt.Errorf("%w: received empty file-message", ErrBadFileTransfer)
-				}
+				var err error
 
-				want := s.currentFileName()
-				if want == "" {
-					return fmt.Errorf("%w: received file-message without a former request", ErrBadFileTransfer)
-				}
+				up, err = receiveChunk(ctx, s, up, msg.File)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
 
-				path := fileMsg.GetPath()
-				content := fileMsg.GetContent()
+					// Fail the upload with the actual reason before the
+					// deferred abort closes it.
+					if up != nil {
+						up.fail(err)
+					}
 
-				if content == nil {
-					return fmt.Errorf("%w: received file-message without content", ErrBadFileTransfer)
+					return err
 				}
+			case *pb.RunRequest_Signal:
+				sig, ok := moduleSignal(msg.Signal.GetKind())
+				if !ok {
+					l.Warn("ignoring unknown signal", "kind", msg.Signal.GetKind())
 
-				if path != want {
-					return fmt.Errorf("%w: received file-message %q but requested %q", ErrBadFileTransfer, path, want)
+					continue
 				}
 
-				l.Debug("received file from client", "name", path, "bytes", len(content))
+				if !s.signal(sig) {
+					l.Warn("dropping signal, module is not receiving signals", "signal", sig)
 
-				file := make(chan []byte, 1)
-
-				// Hand the file to the module's RequestFile. Unlike the stdin
-				// send above, the receiver is the module goroutine, which may
-				// already be gone on teardown; guard the send with ctx.Done so an
-				// abandoned transfer cannot wedge this worker (and, through
-				// wg.Wait, the broker) forever. The buffered content send and
-				// close below never block once the rendezvous succeeds.
-				select {
-				case s.fileCh <- file:
-				case <-ctx.Done():
-					return nil
+					continue
 				}
 
-				file <- content
-
-				close(file)
-
-				s.setCurrentFile("")
+				l.Debug("received signal from client", "signal", sig)
+			case *pb.RunRequest_Cancel:
+				l.Info("client canceled the command execution")
+				s.cancel()
 			default:
 				l.Warn("unexpected message type", "type", fmt.Sprintf("%T", msg))
 			}
 		}
 	}
 }
+
+// moduleSignal maps a signal from the client to the module.Signal passed on to
+// the module. It reports false for an unknown signal.
+func moduleSignal(kind pb.SignalKind) (module.Signal, bool) {
+	switch kind {
+	case pb.SignalKind_SIGNAL_KIND_INTERRUPT:
+		return module.Interrupt, true
+	case pb.SignalKind_SIGNAL_KIND_QUIT:
+		return module.Quit, true
+	case pb.SignalKind_SIGNAL_KIND_EOF:
+		return module.EOF, true
+	default:
+		return 0, false
+	}
+}
+
+// windowSize maps the size of the client's terminal to its module representation.
+// It reports false for an empty or out-of-range size.
+func windowSize(msg *pb.WindowSize) (module.WindowSize, bool) {
+	rows, cols := msg.GetRows(), msg.GetCols()
+	if rows == 0 || cols == 0 || rows > math.MaxUint16 || cols > math.MaxUint16 {
+		return module.WindowSize{}, false
+	}
+
+	return module.WindowSize{Rows: uint16(rows), Cols: uint16(cols)}, true
+}
@@ -10,10 +10,12 @@
 // Reservation is scoped by grammar position, so it restricts device and module
 // command naming no more than necessary. A device is addressed by the first
 // positional argument, so a device named like a device-position keyword (list,
-// version) is unreachable and rejected. A command is the second positional, so
-// a command named like a command-position keyword (lock, unlock) is unreachable
-// and rejected; help is additionally reserved as a command name so that
-// "dutctl <device> help" is never ambiguous. Names outside their colliding
+// version, jobs, attach, cancel) is unreachable and rejected. A command is the
+// second positional, so a command named like a command-position keyword (lock,
+// unlock) is unreachable and rejected; help is additionally reserved as a command
+// name so that "dutctl <device> help" is never ambiguous. power is the exception:
+// a device without power controller may keep a command named power, which then
+// takes the place of the keyword (see pkg/dut). Names outside their colliding
 // position stay usable: a device may be named "lock", a command "list".
 package keyword
 
@@ -27,10 +29,19 @@ const (
 	Version = "version"
 	// List lists all available devices: "dutctl list".
 	List = "list"
+	// Jobs lists the detached jobs on the agent: "dutctl jobs".
+	Jobs = "jobs"
+	// Attach reattaches to a detached job: "dutctl attach <job>".
+	Attach = "attach"
+	// Cancel cancels a detached job: "dutctl cancel <job>".
+	Cancel = "cancel"
 	// Lock reserves a device: "dutctl <device> lock [duration]".
 	Lock = "lock"
 	// Unlock releases a device: "dutctl <device> unlock [force]".
 	Unlock = "unlock"
+	// Power switches or queries the power of a device through its power
+	// controller: "dutctl <device> power on|off|cycle|status".
+	Power = "power"
 	// Help shows a command's usage: "dutctl <device> <command> help".
 	Help = "help"
 	// Force breaks another owner's lock: "dutctl <device> unlock force".
@@ -42,11 +53,11 @@ const (
 var ErrReservedName = errors.New("name is reserved")
 
 // IsReservedDeviceName reports whether name is reserved from use as a device
-// name. list and version are dispatched in the device position (the first
-// positional argument) and would shadow a device so named.
+// name. list, version and the job keywords are dispatched in the device position
+// (the first positional argument) and would shadow a device so named.
 func IsReservedDeviceName(name string) bool {
 	switch name {
-	case List, Version:
+	case List, Version, Jobs, Attach, Cancel:
 		return true
 	default:
 		return false
@@ -57,7 +68,8 @@ func IsReservedDeviceName(name string) bool {
 // command name. lock and unlock are dispatched in the command position and
 // would shadow a command so named; help is additionally reserved so that
 // "dutctl <device> help" is never ambiguous between a command and the help
-// keyword.
+// keyword. power is not reserved: it only collides with the power controller of
+// a device, which the configuration validation checks per device.
 func IsReservedCommandName(name string) bool {
 	switch name {
 	case Lock, Unlock, Help:
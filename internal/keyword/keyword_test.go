@@ -13,9 +13,13 @@ func TestIsReservedDeviceName(t *testing.T) {
 	}{
 		{List, true},
 		{Version, true},
+		{Jobs, true},
+		{Attach, true},
+		{Cancel, true},
 		// A command-position keyword is a valid device name.
 		{Lock, false},
 		{Unlock, false},
+		{Power, false},
 		{Help, false},
 		{"my-board", false},
 		{"", false},
@@ -38,10 +42,14 @@ func TestIsReservedCommandName(t *testing.T) {
 		{Lock, true},
 		{Unlock, true},
 		{Help, true},
+		// power only collides with a power controller, checked per device.
+		{Power, false},
 		// A device-position keyword is a valid command name.
 		{List, false},
 		{Version, false},
-		{"power", false},
+		{Jobs, false},
+		{Cancel, false},
+		{"power-cycle", false},
 		{"", false},
 	}
 
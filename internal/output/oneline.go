@@ -29,7 +29,9 @@ import (
 // Fields are separated by a comma; any field containing a comma or a space is
 // wrapped in double quotes with embedded quotes doubled. The data column packs
 // structured payloads: a device list is a '|'-joined list of device tokens
-// (see deviceEntryString) and a file transfer is "direction bytes path".
+// (see deviceEntryString), a file transfer is "direction bytes path", a run
+// result is a '|'-joined list of "index:name=outcome:durationms" module tokens
+// and a job list is a '|'-joined list of "id=state:device:command" job tokens.
 type OneLineFormatter struct {
 	stdout    io.Writer
 	stderr    io.Writer
@@ -146,6 +148,24 @@ func formatDataValue(data interface{}, separator string) string {
 		token := fmt.Sprintf("%s %d %s", dataValue.Direction, dataValue.Bytes, dataValue.Path)
 
 		return formatQuotedString(token, separator)
+	case RunResult:
+		modules := make([]string, 0, len(dataValue.Modules))
+		for _, m := range dataValue.Modules {
+			modules = append(modules, fmt.Sprintf("%d:%s=%s:%dms", m.Index, m.Name, m.Outcome, m.DurationMs))
+		}
+
+		return formatQuotedString(strings.Join(modules, "|"), separator)
+	case JobEntry:
+		return formatQuotedString(jobEntryString(dataValue), separator)
+	case []JobEntry:
+		entries := make([]string, 0, len(dataValue))
+		for _, j := range dataValue {
+			entries = append(entries, jobEntryString(j))
+		}
+
+		return formatQuotedString(strings.Join(entries, "|"), separator)
+	case PowerState:
+		return formatQuotedString(fmt.Sprintf("%s=%s", dataValue.Device, dataValue.State), separator)
 	default:
 		// Convert anything else to string
 		return formatQuotedString(fmt.Sprintf("%v", dataValue), separator)
@@ -170,6 +190,13 @@ func deviceEntryString(entry DeviceEntry) string {
 	return fmt.Sprintf("%s=%s:%s", entry.Name, state, entry.Owner)
 }
 
+// jobEntryString renders a JobEntry as a compact token for single-line output:
+// "id=state:device:command". Owner, arguments and times are left out; a consumer
+// needing them should use -f json or -f yaml.
+func jobEntryString(entry JobEntry) string {
+	return fmt.Sprintf("%s=%s:%s:%s", entry.ID, entry.State, entry.Device, entry.Command)
+}
+
 // output writes the formatted line to the appropriate destination.
 func (f *OneLineFormatter) output(line string, isError bool) {
 	if f.buffering {
@@ -42,6 +42,22 @@ func TestDeviceEntryString(t *testing.T) {
 	}
 }
 
+func TestJobEntryString(t *testing.T) {
+	entry := JobEntry{ID: "3fa2c1d0", Owner: "alice@host", Device: "board1", Command: "flash", State: JobRunning}
+
+	if got, want := jobEntryString(entry), "3fa2c1d0=running:board1:flash"; got != want {
+		t.Errorf("jobEntryString(%+v) = %q, want %q", entry, got, want)
+	}
+}
+
+func TestPowerStateValue(t *testing.T) {
+	data := PowerState{Device: "board1", Controller: "pdu", Action: "cycle", State: PowerOn}
+
+	if got, want := formatDataValue(data, ","), "board1=on"; got != want {
+		t.Errorf("formatDataValue(%+v) = %q, want %q", data, got, want)
+	}
+}
+
 func TestOneLineFormatter(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 
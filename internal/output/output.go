@@ -37,6 +37,18 @@ const (
 
 	// TypeFileTransfer represents a file transferred between client and agent.
 	TypeFileTransfer ContentType = "file-transfer"
+
+	// TypeRunResult represents the per-module outcome that concludes a command execution.
+	TypeRunResult ContentType = "run-result"
+
+	// TypeJob represents a detached job that was started or canceled.
+	TypeJob ContentType = "job"
+
+	// TypeJobList represents a list of detached jobs.
+	TypeJobList ContentType = "job-list"
+
+	// TypePowerState represents the power state of a device after a power action.
+	TypePowerState ContentType = "power-state"
 )
 
 // DeviceEntry describes a device and its lock state for TypeDeviceList output.
@@ -55,6 +67,73 @@ type FileTransfer struct {
 	Bytes     int    `json:"bytes"     yaml:"bytes"`
 }
 
+// RunResult describes the outcome of a command execution for TypeRunResult output.
+type RunResult struct {
+	Modules []ModuleResult `json:"modules" yaml:"modules"`
+}
+
+// ModuleResult describes the outcome of a single module of a command. Outcome is
+// "succeeded", "failed", "canceled" or "skipped". StartedAt and FinishedAt are
+// RFC 3339 timestamps, empty for a module that did not run.
+type ModuleResult struct {
+	Name       string `json:"name"                 yaml:"name"`
+	Index      int    `json:"index"                yaml:"index"`
+	Outcome    string `json:"outcome"              yaml:"outcome"`
+	Error      string `json:"error,omitempty"      yaml:"error,omitempty"`
+	StartedAt  string `json:"startedAt,omitempty"  yaml:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty" yaml:"finishedAt,omitempty"`
+	DurationMs int64  `json:"durationMs"           yaml:"durationMs"`
+}
+
+// Module outcomes as reported in ModuleResult.Outcome.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeCanceled  = "canceled"
+	OutcomeSkipped   = "skipped"
+)
+
+// JobEntry describes a detached job for TypeJob and TypeJobList output. State is
+// "running", "succeeded", "failed" or "canceled". CreatedAt and FinishedAt are
+// Unix seconds, FinishedAt is 0 while the job is running.
+type JobEntry struct {
+	ID         string   `json:"id"                   yaml:"id"`
+	Owner      string   `json:"owner,omitempty"      yaml:"owner,omitempty"`
+	Device     string   `json:"device"               yaml:"device"`
+	Command    string   `json:"command"              yaml:"command"`
+	Args       []string `json:"args,omitempty"       yaml:"args,omitempty"`
+	State      string   `json:"state"                yaml:"state"`
+	Error      string   `json:"error,omitempty"      yaml:"error,omitempty"`
+	CreatedAt  int64    `json:"createdAt,omitempty"  yaml:"createdAt,omitempty"`
+	FinishedAt int64    `json:"finishedAt,omitempty" yaml:"finishedAt,omitempty"`
+	Attached   bool     `json:"attached"             yaml:"attached"`
+}
+
+// Job states as reported in JobEntry.State.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// PowerState describes the power state of a device for TypePowerState output.
+// Action is the power action requested: "on", "off", "cycle" or "status". State
+// is "on", "off" or "unknown" if the power controller cannot tell.
+type PowerState struct {
+	Device     string `json:"device"     yaml:"device"`
+	Controller string `json:"controller" yaml:"controller"`
+	Action     string `json:"action"     yaml:"action"`
+	State      string `json:"state"      yaml:"state"`
+}
+
+// Power states as reported in PowerState.State.
+const (
+	PowerOn      = "on"
+	PowerOff     = "off"
+	PowerUnknown = "unknown"
+)
+
 // Content is a structured data unit to be formatted and displayed.
 type Content struct {
 	// Type identifies the category of this content.
@@ -82,6 +82,14 @@ func (f *TextFormatter) WriteContent(content Content) {
 		f.writeLockResultTo(content, writer)
 	case TypeFileTransfer:
 		f.writeFileTransferTo(content, writer)
+	case TypeJob:
+		f.writeJobTo(content, writer)
+	case TypeJobList:
+		f.writeJobListTo(content, writer)
+	case TypeRunResult:
+		f.writeRunResultTo(content, writer)
+	case TypePowerState:
+		f.writePowerStateTo(content, writer)
 	default:
 		// For general text or unrecognized types
 		f.writeGeneralTo(content, writer)
@@ -248,6 +256,34 @@ func (f *TextFormatter) writeLockResultTo(content Content, writer io.Writer) {
 	fmt.Fprintln(writer, style.Colorize(f.useColor, style.Green, line))
 }
 
+// writePowerStateTo formats and writes the power state of a device, e.g.
+// `✓ Device "board" powered on` after switching it, or `Device "board" is off`
+// for a status query.
+func (f *TextFormatter) writePowerStateTo(content Content, writer io.Writer) {
+	power, ok := content.Data.(PowerState)
+	if !ok {
+		f.writeGeneralTo(content, writer)
+
+		return
+	}
+
+	f.writeMetadata(content, writer)
+
+	switch {
+	case power.Action == "status":
+		fmt.Fprintf(writer, "Device %q is %s\n", power.Device, power.State)
+	case power.State == PowerUnknown:
+		line := fmt.Sprintf("%s Device %q power %s done, state unknown", style.MarkerWarning, power.Device, power.Action)
+		fmt.Fprintln(writer, style.Colorize(f.useColor, style.Yellow, line))
+	case power.Action == "cycle":
+		line := fmt.Sprintf("%s Device %q power cycled, now %s", style.MarkerSuccess, power.Device, power.State)
+		fmt.Fprintln(writer, style.Colorize(f.useColor, style.Green, line))
+	default:
+		line := fmt.Sprintf("%s Device %q powered %s", style.MarkerSuccess, power.Device, power.State)
+		fmt.Fprintln(writer, style.Colorize(f.useColor, style.Green, line))
+	}
+}
+
 // writeFileTransferTo formats and writes a file-transfer progress line, e.g.
 // `↑ sent "firmware.bin" (1.2 MiB)` / `↓ received "result.log" (4.0 KiB)`.
 func (f *TextFormatter) writeFileTransferTo(content Content, writer io.Writer) {
@@ -269,6 +305,127 @@ func (f *TextFormatter) writeFileTransferTo(content Content, writer io.Writer) {
 	fmt.Fprintln(writer, style.Colorize(f.useColor, style.Cyan, line))
 }
 
+// writeRunResultTo formats and writes the per-module outcome of a command
+// execution, one line per module, e.g. `✗ module 2/3 "serial" failed after
+// 30s: expect timed out`. Outside verbose mode modules that succeeded are left
+// out, so a successful run prints nothing and a failed one only what went wrong.
+func (f *TextFormatter) writeRunResultTo(content Content, writer io.Writer) {
+	result, ok := content.Data.(RunResult)
+	if !ok {
+		f.writeGeneralTo(content, writer)
+
+		return
+	}
+
+	f.writeMetadata(content, writer)
+
+	total := len(result.Modules)
+
+	for _, mod := range result.Modules {
+		if !f.verbose && mod.Outcome == OutcomeSucceeded {
+			continue
+		}
+
+		duration := (time.Duration(mod.DurationMs) * time.Millisecond).Round(100 * time.Millisecond)
+		prefix := fmt.Sprintf("module %d/%d %q", mod.Index, total, mod.Name)
+
+		var marker, color, line string
+
+		switch mod.Outcome {
+		case OutcomeSucceeded:
+			marker, color = style.MarkerSuccess, style.Green
+			line = fmt.Sprintf("%s succeeded (%s)", prefix, duration)
+		case OutcomeFailed:
+			marker, color = style.MarkerError, style.Red
+			line = fmt.Sprintf("%s failed after %s: %s", prefix, duration, mod.Error)
+		case OutcomeCanceled:
+			marker, color = style.MarkerWarning, style.Yellow
+			line = fmt.Sprintf("%s canceled after %s", prefix, duration)
+		default:
+			marker, color = style.MarkerContext, style.Gray
+			line = fmt.Sprintf("%s %s", prefix, mod.Outcome)
+		}
+
+		fmt.Fprintln(writer, style.Colorize(f.useColor, color, marker+" "+line))
+	}
+}
+
+// writeJobTo formats and writes a job announcement: how to reattach to a job
+// that was started, e.g. `# Job 3fa2c1d0 started, reattach with: dutctl attach
+// 3fa2c1d0`, or the confirmation that a job was canceled.
+func (f *TextFormatter) writeJobTo(content Content, writer io.Writer) {
+	job, ok := content.Data.(JobEntry)
+	if !ok {
+		f.writeGeneralTo(content, writer)
+
+		return
+	}
+
+	f.writeMetadata(content, writer)
+
+	var line string
+
+	switch job.State {
+	case JobRunning:
+		line = style.Colorize(f.useColor, style.Cyan, fmt.Sprintf("%s Job %s started, reattach with: dutctl attach %s",
+			style.MarkerContext, job.ID, job.ID))
+	case JobCanceled:
+		line = style.Colorize(f.useColor, style.Green, fmt.Sprintf("%s Job %s canceled", style.MarkerSuccess, job.ID))
+	default:
+		line = fmt.Sprintf("Job %s %s", job.ID, job.State)
+	}
+
+	fmt.Fprintln(writer, line)
+}
+
+// jobAnnotation renders the bracketed state note of a listed job, e.g.
+// ` [running for 12m by "alice@host", attached]` or ` [failed 3m ago by
+// "alice@host": boom]`.
+func jobAnnotation(job JobEntry) string {
+	var note string
+
+	if job.State == JobRunning {
+		note = fmt.Sprintf("running for %s by %q", humanDuration(time.Since(time.Unix(job.CreatedAt, 0))), job.Owner)
+	} else {
+		note = fmt.Sprintf("%s %s ago by %q", job.State, humanDuration(time.Since(time.Unix(job.FinishedAt, 0))), job.Owner)
+	}
+
+	if job.Attached {
+		note += ", attached"
+	}
+
+	if job.Error != "" {
+		note += ": " + job.Error
+	}
+
+	return " [" + note + "]"
+}
+
+// writeJobListTo formats and writes a list of jobs with bullet points, e.g.
+// `- 3fa2c1d0: my-board flash fw.bin [running for 12m by "alice@host"]`.
+func (f *TextFormatter) writeJobListTo(content Content, writer io.Writer) {
+	jobs, ok := content.Data.([]JobEntry)
+	if !ok {
+		f.writeGeneralTo(content, writer)
+
+		return
+	}
+
+	f.writeMetadata(content, writer)
+
+	if len(jobs) == 0 {
+		fmt.Fprintln(writer, style.Colorize(f.useColor, style.Gray, "no jobs"))
+
+		return
+	}
+
+	for _, job := range jobs {
+		cmdline := strings.Join(append([]string{job.Device, job.Command}, job.Args...), " ")
+		annotation := style.Colorize(f.useColor, style.Gray, jobAnnotation(job))
+		fmt.Fprintf(writer, "- %s: %s%s\n", job.ID, cmdline, annotation)
+	}
+}
+
 // writeCommandListTo formats and writes a list of commands with bullet points.
 func (f *TextFormatter) writeCommandListTo(content Content, writer io.Writer) {
 	if commands, ok := content.Data.([]string); ok {
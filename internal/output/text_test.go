@@ -465,3 +465,154 @@ func TestHumanBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteRunResult(t *testing.T) {
+	result := RunResult{Modules: []ModuleResult{
+		{Name: "power", Index: 1, Outcome: OutcomeSucceeded, DurationMs: 1520},
+		{Name: "serial", Index: 2, Outcome: OutcomeFailed, Error: "expect timed out", DurationMs: 30000},
+		{Name: "flash", Index: 3, Outcome: OutcomeSkipped},
+	}}
+
+	tests := []struct {
+		name    string
+		verbose bool
+		want    string
+	}{
+		{
+			name: "default",
+			want: "✗ module 2/3 \"serial\" failed after 30s: expect timed out\n" +
+				"# module 3/3 \"flash\" skipped\n",
+		},
+		{
+			name:    "verbose",
+			verbose: true,
+			want: "✓ module 1/3 \"power\" succeeded (1.5s)\n" +
+				"✗ module 2/3 \"serial\" failed after 30s: expect timed out\n" +
+				"# module 3/3 \"flash\" skipped\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			f := newTextFormatter(Config{Stdout: &stdout, Stderr: &stderr, NoColor: true, Verbose: tt.verbose})
+			f.WriteContent(Content{Type: TypeRunResult, Data: result})
+
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("run-result output = %q, want %q", got, tt.want)
+			}
+
+			if stderr.Len() != 0 {
+				t.Errorf("unexpected stderr output: %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestWriteJob(t *testing.T) {
+	tests := []struct {
+		name string
+		data JobEntry
+		want string
+	}{
+		{
+			name: "started",
+			data: JobEntry{ID: "3fa2c1d0", State: JobRunning},
+			want: "# Job 3fa2c1d0 started, reattach with: dutctl attach 3fa2c1d0\n",
+		},
+		{
+			name: "canceled",
+			data: JobEntry{ID: "3fa2c1d0", State: JobCanceled},
+			want: "✓ Job 3fa2c1d0 canceled\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			formatter := newTextFormatter(Config{Stdout: stdout, Stderr: &bytes.Buffer{}, NoColor: true})
+
+			formatter.WriteContent(Content{Type: TypeJob, Data: tt.data})
+
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("job output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePowerState(t *testing.T) {
+	tests := []struct {
+		name string
+		data PowerState
+		want string
+	}{
+		{
+			name: "on",
+			data: PowerState{Device: "my-board", Action: "on", State: PowerOn},
+			want: "✓ Device \"my-board\" powered on\n",
+		},
+		{
+			name: "cycle",
+			data: PowerState{Device: "my-board", Action: "cycle", State: PowerOn},
+			want: "✓ Device \"my-board\" power cycled, now on\n",
+		},
+		{
+			name: "off with unknown state",
+			data: PowerState{Device: "my-board", Action: "off", State: PowerUnknown},
+			want: "⚠ Device \"my-board\" power off done, state unknown\n",
+		},
+		{
+			name: "status",
+			data: PowerState{Device: "my-board", Action: "status", State: PowerOff},
+			want: "Device \"my-board\" is off\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			formatter := newTextFormatter(Config{Stdout: stdout, Stderr: &bytes.Buffer{}, NoColor: true})
+
+			formatter.WriteContent(Content{Type: TypePowerState, Data: tt.data})
+
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("power state output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJobList(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	formatter := newTextFormatter(Config{Stdout: stdout, Stderr: &bytes.Buffer{}, NoColor: true})
+
+	now := time.Now()
+
+	formatter.WriteContent(Content{
+		Type: TypeJobList,
+		Data: []JobEntry{
+			{
+				ID: "3fa2c1d0", Owner: "alice@host", Device: "my-board", Command: "flash", Args: []string{"fw.bin"},
+				State: JobRunning, CreatedAt: now.Add(-12 * time.Minute).Unix(), Attached: true,
+			},
+			{
+				ID: "77e01b2c", Owner: "bob@host", Device: "other-board", Command: "boot",
+				State: JobFailed, Error: "expect timed out",
+				CreatedAt: now.Add(-10 * time.Minute).Unix(), FinishedAt: now.Add(-3 * time.Minute).Unix(),
+			},
+		},
+	})
+
+	got := stdout.String()
+
+	for _, want := range []string{
+		`- 3fa2c1d0: my-board flash fw.bin [running for 12m by "alice@host", attached]`,
+		`- 77e01b2c: other-board boot [failed 3m ago by "bob@host": expect timed out]`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("job list output missing %q.\nGot:\n%s", want, got)
+		}
+	}
+}
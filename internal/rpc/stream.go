@@ -32,3 +32,34 @@ func NewRunStream(inner *connect.BidiStream[pb.RunRequest, pb.RunResponse]) *Run
 
 func (s *RunStream) Send(msg *pb.RunResponse) error   { return s.inner.Send(msg) }
 func (s *RunStream) Receive() (*pb.RunRequest, error) { return s.inner.Receive() }
+
+// AttachStream adapts a connect.BidiStream for the Attach RPC to the same
+// Send/Receive surface as RunStream, unwrapping the RunRequest and RunResponse
+// messages the Attach messages carry. Errors pass through verbatim, as with
+// RunStream. An AttachRequest without a RunRequest, such as a second job
+// message, is returned as an empty RunRequest, which the session logs and ignores.
+type AttachStream struct {
+	inner *connect.BidiStream[pb.AttachRequest, pb.AttachResponse]
+}
+
+// NewAttachStream wraps the Attach RPC's bidirectional stream.
+func NewAttachStream(inner *connect.BidiStream[pb.AttachRequest, pb.AttachResponse]) *AttachStream {
+	return &AttachStream{inner: inner}
+}
+
+func (s *AttachStream) Send(msg *pb.RunResponse) error {
+	return s.inner.Send(&pb.AttachResponse{Run: msg})
+}
+
+func (s *AttachStream) Receive() (*pb.RunRequest, error) {
+	req, err := s.inner.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	if run := req.GetRun(); run != nil {
+		return run, nil
+	}
+
+	return &pb.RunRequest{}, nil
+}
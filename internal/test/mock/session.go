@@ -32,6 +32,8 @@ type Session struct {
 	SendFileCalled        bool
 	SentFileName          string
 	SentFileContent       []byte
+	SignalCh              chan module.Signal
+	WindowSizeCh          chan module.WindowSize
 }
 
 var _ module.Session = &Session{}
@@ -109,3 +111,15 @@ func (m *Session) SendFile(name string, r io.Reader) error {
 
 	return nil
 }
+
+// Signals returns SignalCh, on which a test delivers signals to the module. An unset
+// SignalCh delivers none.
+func (m *Session) Signals() <-chan module.Signal {
+	return m.SignalCh
+}
+
+// WindowSizes returns WindowSizeCh, on which a test delivers window sizes to the module.
+// An unset WindowSizeCh delivers none.
+func (m *Session) WindowSizes() <-chan module.WindowSize {
+	return m.WindowSizeCh
+}
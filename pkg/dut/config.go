@@ -101,7 +101,9 @@ func (d *Devlist) UnmarshalYAML(node *yaml.Node) error {
 		devName := node.Content[idx].Value
 
 		if keyword.IsReservedDeviceName(devName) {
-			return &ConfigError{Device: devName, Line: node.Content[idx].Line, Err: keyword.ErrReservedName}
+			err := fmt.Errorf("%w: %q is a dutctl keyword, rename the device", keyword.ErrReservedName, devName)
+
+			return &ConfigError{Device: devName, Line: node.Content[idx].Line, Err: err}
 		}
 
 		var dev Device
@@ -158,6 +160,8 @@ func (d *Device) UnmarshalYAML(node *yaml.Node) error {
 
 		*d = Device(dev)
 	} else {
+		var cmdsNode *yaml.Node
+
 		// Walk the Device fields manually.
 		for i := 0; i < len(node.Content); i += 2 {
 			key := node.Content[i].Value
@@ -173,8 +177,23 @@ func (d *Device) UnmarshalYAML(node *yaml.Node) error {
 				}
 
 				d.Cmds = cmds
+				cmdsNode = val
+			case "power":
+				var power Power
+
+				err := val.Decode(&power)
+				if err != nil {
+					return err
+				}
+
+				d.Power = &power
 			}
 		}
+
+		err := d.checkPowerCommand(cmdsNode)
+		if err != nil {
+			return err
+		}
 	}
 
 	if len(d.Cmds) == 0 {
@@ -184,6 +203,24 @@ func (d *Device) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+// checkPowerCommand rejects a command named power next to a power controller.
+// The controller serves "dutctl <device> power", so such a command is only
+// reachable on a device without one. cmds is the YAML node of the commands, nil
+// if the device has none.
+func (d *Device) checkPowerCommand(cmds *yaml.Node) error {
+	if d.Power == nil || cmds == nil {
+		return nil
+	}
+
+	for i := 0; i < len(cmds.Content); i += 2 {
+		if cmds.Content[i].Value == keyword.Power {
+			return &ConfigError{Command: keyword.Power, Line: cmds.Content[i].Line, Err: ErrPowerCommandShadowed}
+		}
+	}
+
+	return nil
+}
+
 // decodeCmds decodes a YAML mapping node into a command map, annotating
 // errors with the command name that caused them.
 func decodeCmds(node *yaml.Node) (map[string]Command, error) {
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -158,6 +159,38 @@ func TestInvalidConfig(t *testing.T) {
 			wantDevice:   "version",
 			wantLine:     1,
 		},
+		{
+			name:         "reserved_job_device_name",
+			file:         "invalid_reserved_job_device.yaml",
+			wantSentinel: keyword.ErrReservedName,
+			wantDevice:   "jobs",
+			wantLine:     1,
+			errKeywords:  []string{"dutctl keyword", "rename the device"},
+		},
+
+		// Power controller
+		{
+			name:         "power_not_a_controller",
+			file:         "invalid_power_not_controller.yaml",
+			wantSentinel: ErrNoPowerController,
+			wantDevice:   "device1",
+			wantLine:     3,
+		},
+		{
+			name:         "power_command_next_to_controller",
+			file:         "invalid_power_command.yaml",
+			wantSentinel: ErrPowerCommandShadowed,
+			wantDevice:   "device1",
+			wantCommand:  "power",
+			wantLine:     5,
+		},
+		{
+			name:         "power_invalid_offtime",
+			file:         "invalid_power_offtime.yaml",
+			wantSentinel: ErrInvalidOffTime,
+			wantDevice:   "device1",
+			wantLine:     3,
+		},
 
 		// Null device value
 		{
@@ -338,6 +371,47 @@ func TestValidConfig(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "power_controller",
+			file:     "valid_power.yaml",
+			wantDevs: 2,
+			checkFunc: func(t *testing.T, devs Devlist) {
+				t.Helper()
+
+				power := devs["device1"].Power
+				if power == nil {
+					t.Fatal("expected a power controller for device1")
+				}
+
+				if power.Config.Name != "dummy-power" {
+					t.Errorf("power module: want %q, got %q", "dummy-power", power.Config.Name)
+				}
+
+				if power.OffTime != 10*time.Second {
+					t.Errorf("OffTime: want 10s, got %s", power.OffTime)
+				}
+
+				if got := devs["device2"].Power.OffTime; got != DefaultOffTime {
+					t.Errorf("default OffTime: want %s, got %s", DefaultOffTime, got)
+				}
+
+				if devs["device1"].Cmds["status"].Modules[0].Config.Name != "dummy-status" {
+					t.Error("commands must be decoded alongside the power controller")
+				}
+			},
+		},
+		{
+			name:     "power_command_without_controller",
+			file:     "valid_power_command.yaml",
+			wantDevs: 1,
+			checkFunc: func(t *testing.T, devs Devlist) {
+				t.Helper()
+
+				if _, ok := devs["device1"].Cmds["power"]; !ok {
+					t.Error("expected the power command of a device without power controller")
+				}
+			},
+		},
 		{
 			name:     "all_dummy_modules",
 			file:     "valid_all_dummies.yaml",
@@ -110,13 +110,18 @@ type ModuleRef struct {
 	Module  Module
 }
 
-// AllModules iterates every module across all devices and commands. Iteration
-// order is unspecified (it follows Go map iteration). It serves whole-system
-// sweeps such as agent startup/shutdown; request-path logic addresses a specific
-// module through FindCmd instead.
+// AllModules iterates every module across all devices and commands, including
+// the power controllers of the devices, listed under the power keyword in place
+// of a command. Iteration order is unspecified (it follows Go map iteration). It
+// serves whole-system sweeps such as agent startup/shutdown; request-path logic
+// addresses a specific module through FindCmd or FindPower instead.
 func (devs *Devlist) AllModules() iter.Seq[ModuleRef] {
 	return func(yield func(ModuleRef) bool) {
 		for devName, dev := range *devs {
+			if dev.Power != nil && !yield(powerRef(devName, dev.Power)) {
+				return
+			}
+
 			for cmdName, cmd := range dev.Cmds {
 				for _, mod := range cmd.Modules {
 					if !yield(ModuleRef{Device: devName, Command: cmdName, Module: mod}) {
@@ -130,8 +135,9 @@ func (devs *Devlist) AllModules() iter.Seq[ModuleRef] {
 
 // Device is the representation of a device-under-test (DUT).
 type Device struct {
-	Desc string
-	Cmds map[string]Command
+	Desc  string
+	Cmds  map[string]Command
+	Power *Power // Power is the power controller of the device, nil if none is configured.
 }
 
 // Command represents a task that can be executed on a device-under-test (DUT).
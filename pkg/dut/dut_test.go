@@ -215,11 +215,14 @@ func TestAllModules(t *testing.T) {
 				{Config: ModuleConfig{Name: "modC"}},
 			}},
 		}},
-		"device2": {Cmds: map[string]Command{
-			"cmd3": {Modules: []Module{
-				{Config: ModuleConfig{Name: "modD"}},
-			}},
-		}},
+		"device2": {
+			Cmds: map[string]Command{
+				"cmd3": {Modules: []Module{
+					{Config: ModuleConfig{Name: "modD"}},
+				}},
+			},
+			Power: &Power{Module: Module{Config: ModuleConfig{Name: "modP"}}},
+		},
 	}
 
 	// AllModules yields every module across all devices/commands; iteration order
@@ -227,10 +230,11 @@ func TestAllModules(t *testing.T) {
 	type key struct{ dev, cmd, mod string }
 
 	want := map[key]bool{
-		{"device1", "cmd1", "modA"}: true,
-		{"device1", "cmd1", "modB"}: true,
-		{"device1", "cmd2", "modC"}: true,
-		{"device2", "cmd3", "modD"}: true,
+		{"device1", "cmd1", "modA"}:  true,
+		{"device1", "cmd1", "modB"}:  true,
+		{"device1", "cmd2", "modC"}:  true,
+		{"device2", "cmd3", "modD"}:  true,
+		{"device2", "power", "modP"}: true,
 	}
 
 	got := make(map[key]bool)
@@ -0,0 +1,116 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dut
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/keyword"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOffTime is the time a device is kept off during a power cycle if its
+// power configuration does not set one.
+const DefaultOffTime = 5 * time.Second
+
+// Sentinel errors of the power configuration of a device. The UnmarshalYAML
+// methods return ErrNoPowerController, ErrInvalidOffTime and
+// ErrPowerCommandShadowed wrapped in a *ConfigError; Devlist.FindPower returns
+// ErrNoPower and ErrPowerIsCommand unwrapped. Match them with errors.Is.
+var (
+	ErrNoPower              = errors.New("no power controller configured")
+	ErrPowerIsCommand       = errors.New("no power controller configured, power is a command of the device")
+	ErrNoPowerController    = errors.New("module is not a power controller")
+	ErrInvalidOffTime       = errors.New("invalid off-time")
+	ErrPowerCommandShadowed = errors.New("a device with a power controller must not have a command named power")
+)
+
+// Power is the power controller of a device: a module implementing
+// module.PowerController, driven by the device-level power command. The module
+// is configured like the modules of a command, but it is not part of one and
+// takes no arguments.
+type Power struct {
+	Module
+
+	// OffTime is the time the device is kept off during a power cycle.
+	OffTime time.Duration
+}
+
+// Controller returns the module as a power controller.
+func (p *Power) Controller() module.PowerController {
+	//nolint:forcetypeassert // UnmarshalYAML only accepts power controllers
+	return p.Module.Module.(module.PowerController)
+}
+
+// UnmarshalYAML unmarshals a Power from a YAML node. The node is a module entry
+// with the additional key offtime, a duration defaulting to DefaultOffTime.
+//
+// A validation failure returns a *ConfigError carrying the YAML line and wrapping
+// ErrNoPowerController, ErrInvalidOffTime, or an error of Module.UnmarshalYAML.
+func (p *Power) UnmarshalYAML(node *yaml.Node) error {
+	// Module.UnmarshalYAML ignores the offtime key, which is decoded here.
+	err := node.Decode(&p.Module)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := p.Module.Module.(module.PowerController); !ok {
+		return &ConfigError{Line: node.Line, Err: fmt.Errorf("%w: %q", ErrNoPowerController, p.Config.Name)}
+	}
+
+	if p.Config.Passthrough || len(p.Config.Args) > 0 {
+		return &ConfigError{Line: node.Line, Err: fmt.Errorf("power controller %q takes no passthrough or args", p.Config.Name)}
+	}
+
+	var opts struct {
+		OffTime string `yaml:"offtime"`
+	}
+
+	err = node.Decode(&opts)
+	if err != nil {
+		return err
+	}
+
+	p.OffTime = DefaultOffTime
+
+	if opts.OffTime != "" {
+		p.OffTime, err = time.ParseDuration(opts.OffTime)
+		if err != nil || p.OffTime < 0 {
+			return &ConfigError{Line: node.Line, Err: fmt.Errorf("%w %q: want a non-negative duration like 5s", ErrInvalidOffTime, opts.OffTime)}
+		}
+	}
+
+	return nil
+}
+
+// FindPower returns the power controller of the named device. It returns
+// ErrDeviceNotFound if the device is not present, ErrPowerIsCommand if it has no
+// power controller but a command named power, which serves power requests in its
+// place, and ErrNoPower if it has neither.
+func (devs *Devlist) FindPower(device string) (*Power, error) {
+	dev, ok := (*devs)[device]
+	if !ok {
+		return nil, ErrDeviceNotFound
+	}
+
+	if _, ok := dev.Cmds[keyword.Power]; ok && dev.Power == nil {
+		return nil, ErrPowerIsCommand
+	}
+
+	if dev.Power == nil {
+		return nil, ErrNoPower
+	}
+
+	return dev.Power, nil
+}
+
+// powerRef is the ModuleRef of the power controller of device, which is listed
+// under the power keyword in place of a command.
+func powerRef(device string, p *Power) ModuleRef {
+	return ModuleRef{Device: device, Command: keyword.Power, Module: p.Module}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dummy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func init() {
+	module.Register(module.Record{
+		ID:  "dummy-power",
+		New: func() module.Module { return &Power{} },
+	})
+}
+
+// Power simulates the power switch of a DUT, which is off initially.
+// It demonstrates the module.PowerController interface, which lets the module act
+// as the power controller of a device.
+type Power struct {
+	on bool
+}
+
+// Ensure implementing the Module and PowerController interfaces.
+var (
+	_ module.Module          = &Power{}
+	_ module.PowerController = &Power{}
+)
+
+func (d *Power) Help() string {
+	return "This dummy module simulates a power switch. Arguments: [on|off|status]"
+}
+
+func (d *Power) Init(_ context.Context) error {
+	return nil
+}
+
+func (d *Power) Deinit(_ context.Context) error {
+	return nil
+}
+
+func (d *Power) Run(ctx context.Context, s module.Session, args ...string) error {
+	if len(args) == 0 {
+		args = []string{"status"}
+	}
+
+	switch args[0] {
+	case "on":
+		return d.PowerOn(ctx)
+	case "off":
+		return d.PowerOff(ctx)
+	case "status":
+		state, _ := d.PowerState(ctx)
+		s.Printf("Power is %s\n", state)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown argument: %s", args[0])
+	}
+}
+
+func (d *Power) PowerOn(ctx context.Context) error {
+	log.FromContext(ctx).Info("power on")
+
+	d.on = true
+
+	return nil
+}
+
+func (d *Power) PowerOff(ctx context.Context) error {
+	log.FromContext(ctx).Info("power off")
+
+	d.on = false
+
+	return nil
+}
+
+func (d *Power) PowerState(_ context.Context) (module.PowerState, error) {
+	if d.on {
+		return module.PowerOn, nil
+	}
+
+	return module.PowerOff, nil
+}
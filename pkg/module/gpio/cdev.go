@@ -0,0 +1,417 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package gpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// consumer is the label of the lines requested by the cdev backend, as shown
+// by tools like gpioinfo.
+const consumer = "dutctl"
+
+// Line flags of the GPIO v2 uAPI, see linux/gpio.h.
+const (
+	lineFlagInput       = 1 << 2
+	lineFlagOutput      = 1 << 3
+	lineFlagEdgeRising  = 1 << 4
+	lineFlagEdgeFalling = 1 << 5
+)
+
+// eventPollInterval bounds the wait for an edge event before the context is
+// checked again.
+const eventPollInterval = 50 * time.Millisecond
+
+// cdev accesses GPIO lines through the Linux GPIO character device, using the
+// v2 uAPI. Unlike devmem it works with any GPIO chip the kernel drives, e.g.
+// the GPIOs of other SBCs or USB GPIO expanders.
+//
+// A line is requested on first use, as an output when it is set and as an
+// input when it is read, and held until Close, so no other consumer can take
+// it meanwhile. Edges are detected by the kernel, so even short pulses are not
+// missed.
+//
+// The modules using a chip share its backend, so they can use the same line,
+// e.g. one pressing a button and one reading the level.
+type cdev struct {
+	chip string // chip is the path of the GPIO character device.
+	refs int    // guarded by chipsMu
+
+	mu    sync.Mutex
+	lines map[Pin]cdevLine // lines holds the requested lines by offset.
+}
+
+// chips holds the cdev backend of every chip in use, keyed by path.
+//
+//nolint:gochecknoglobals // the agent-wide registry of GPIO chips in use
+var (
+	chips   = make(map[string]*cdev)
+	chipsMu sync.Mutex
+)
+
+// cdevLine is a line requested from the kernel.
+type cdevLine struct {
+	fd    int
+	flags uint64 // flags are the flags the line was requested with.
+}
+
+var (
+	_ gpio        = &cdev{}
+	_ edgeWaiter  = &cdev{}
+	_ levelReader = &cdev{}
+)
+
+// newCdev returns the cdev backend of chip, which is the name of a GPIO chip
+// (e.g. gpiochip0) or the path of its character device. Each call must be
+// matched by a call of Close.
+//
+//nolint:unparam // fails on platforms without GPIO character devices, see cdev_other.go
+func newCdev(chip string) (*cdev, error) {
+	if chip == "" {
+		chip = DefaultChip
+	}
+
+	if !strings.Contains(chip, "/") {
+		chip = "/dev/" + chip
+	}
+
+	chipsMu.Lock()
+	defer chipsMu.Unlock()
+
+	if c, ok := chips[chip]; ok {
+		c.refs++
+
+		return c, nil
+	}
+
+	c := &cdev{chip: chip, refs: 1, lines: make(map[Pin]cdevLine)}
+	chips[chip] = c
+
+	return c, nil
+}
+
+func (c *cdev) Low(p Pin) error {
+	return c.set(p, Low)
+}
+
+func (c *cdev) High(p Pin) error {
+	return c.set(p, High)
+}
+
+// Toggle inverts the level of a line. A line not used before is driven low
+// when it is requested, so toggling it drives it high.
+func (c *cdev) Toggle(p Pin) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	line, err := c.output(p, Low)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.read(line, p)
+	if err != nil {
+		return err
+	}
+
+	return c.write(line, p, s^1)
+}
+
+// Read returns the level of a line: the level it is driven to, if it is set
+// by the backend, else its input level.
+func (c *cdev) Read(p Pin) (State, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	line, ok := c.lines[p]
+	if !ok {
+		var err error
+
+		line, err = c.request(p, lineFlagInput, Low)
+		if err != nil {
+			return Low, err
+		}
+	}
+
+	return c.read(line, p)
+}
+
+// Level returns the level an output line is driven to. A line not held by the
+// backend is requested as is, so its direction and level are left unchanged,
+// and released right away. This way the level set before a restart of the
+// agent is read back.
+func (c *cdev) Level(p Pin) (State, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if line, ok := c.lines[p]; ok {
+		if line.flags != lineFlagOutput {
+			return Low, errNotOutput
+		}
+
+		return c.read(line, p)
+	}
+
+	info, err := c.info(p)
+	if err != nil {
+		return Low, err
+	}
+
+	if info.Flags&lineFlagOutput == 0 {
+		return Low, errNotOutput
+	}
+
+	line, err := c.request(p, 0, Low)
+	if err != nil {
+		return Low, err
+	}
+
+	defer func() {
+		_ = unix.Close(line.fd)
+		delete(c.lines, p)
+	}()
+
+	return c.read(line, p)
+}
+
+// WaitEdge blocks until an edge of the input line p, or ctx is done. Edges that
+// occurred before the call are ignored.
+func (c *cdev) WaitEdge(ctx context.Context, p Pin, e Edge) error {
+	line, err := c.edgeInput(p)
+	if err != nil {
+		return err
+	}
+
+	var (
+		event unix.GPIOV2LineEvent
+		buf   = make([]byte, unsafe.Sizeof(event))
+	)
+
+	for {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		fds := []unix.PollFd{{Fd: int32(line.fd), Events: unix.POLLIN}}
+
+		_, err = unix.Poll(fds, int(eventPollInterval.Milliseconds()))
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			return fmt.Errorf("waiting for an edge on GPIO line %d of %s: %w", p, c.chip, err)
+		}
+
+		if fds[0].Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		_, err = unix.Read(line.fd, buf)
+		if err != nil {
+			return fmt.Errorf("reading edge events of GPIO line %d of %s: %w", p, c.chip, err)
+		}
+
+		event = *(*unix.GPIOV2LineEvent)(unsafe.Pointer(&buf[0]))
+
+		switch {
+		case event.Id == unix.GPIO_V2_LINE_EVENT_RISING_EDGE && e != Falling,
+			event.Id == unix.GPIO_V2_LINE_EVENT_FALLING_EDGE && e != Rising:
+			return nil
+		}
+	}
+}
+
+// edgeInput returns the line p requested as an input detecting both edges,
+// without any edge events pending. A line requested otherwise is requested
+// anew.
+func (c *cdev) edgeInput(p Pin) (cdevLine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	const flags = lineFlagInput | lineFlagEdgeRising | lineFlagEdgeFalling
+
+	line, ok := c.lines[p]
+	if ok && line.flags == flags {
+		return line, discardEvents(line.fd)
+	}
+
+	if ok {
+		_ = unix.Close(line.fd)
+		delete(c.lines, p)
+	}
+
+	line, err := c.request(p, flags, Low)
+	if err != nil {
+		return line, err
+	}
+
+	return line, unix.SetNonblock(line.fd, true)
+}
+
+// discardEvents reads the edge events pending on a non-blocking line.
+func discardEvents(fd int) error {
+	var event unix.GPIOV2LineEvent
+
+	buf := make([]byte, unsafe.Sizeof(event))
+
+	for {
+		_, err := unix.Read(fd, buf)
+		if errors.Is(err, unix.EAGAIN) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("discarding edge events: %w", err)
+		}
+	}
+}
+
+// Close ends a user of the backend. The last one releases the requested lines.
+func (c *cdev) Close() error {
+	chipsMu.Lock()
+	defer chipsMu.Unlock()
+
+	c.refs--
+	if c.refs > 0 {
+		return nil
+	}
+
+	delete(chips, c.chip)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+
+	for p, line := range c.lines {
+		err := unix.Close(line.fd)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("releasing GPIO line %d of %s: %w", p, c.chip, err)
+		}
+
+		delete(c.lines, p)
+	}
+
+	return firstErr
+}
+
+func (c *cdev) set(p Pin, s State) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if line, ok := c.lines[p]; ok && line.flags == lineFlagOutput {
+		return c.write(line, p, s)
+	}
+
+	_, err := c.output(p, s)
+
+	return err
+}
+
+// output returns the line p requested as an output, requesting it driven to
+// initial if it is not. It must be called with mu held.
+func (c *cdev) output(p Pin, initial State) (cdevLine, error) {
+	line, ok := c.lines[p]
+	if ok && line.flags == lineFlagOutput {
+		return line, nil
+	}
+
+	if ok {
+		_ = unix.Close(line.fd)
+		delete(c.lines, p)
+	}
+
+	return c.request(p, lineFlagOutput, initial)
+}
+
+func (c *cdev) read(line cdevLine, p Pin) (State, error) {
+	values := unix.GPIOV2LineValues{Mask: 1}
+
+	err := ioctl(line.fd, unix.GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&values))
+	if err != nil {
+		return Low, fmt.Errorf("reading GPIO line %d of %s: %w", p, c.chip, err)
+	}
+
+	return State(values.Bits & 1), nil
+}
+
+func (c *cdev) write(line cdevLine, p Pin, s State) error {
+	values := unix.GPIOV2LineValues{Bits: uint64(s), Mask: 1}
+
+	err := ioctl(line.fd, unix.GPIO_V2_LINE_SET_VALUES_IOCTL, unsafe.Pointer(&values))
+	if err != nil {
+		return fmt.Errorf("setting GPIO line %d of %s: %w", p, c.chip, err)
+	}
+
+	return nil
+}
+
+// info returns the kernel's information about line p, e.g. its direction.
+func (c *cdev) info(p Pin) (unix.GPIOV2LineInfo, error) {
+	info := unix.GPIOV2LineInfo{Offset: uint32(p)}
+
+	chip, err := unix.Open(c.chip, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return info, fmt.Errorf("opening GPIO chip %s: %w", c.chip, err)
+	}
+	defer unix.Close(chip)
+
+	err = ioctl(chip, unix.GPIO_V2_GET_LINEINFO_IOCTL, unsafe.Pointer(&info))
+	if err != nil {
+		return info, fmt.Errorf("getting info of GPIO line %d of %s: %w", p, c.chip, err)
+	}
+
+	return info, nil
+}
+
+// request requests line p with flags, driving an output to initial. It must be
+// called with mu held.
+func (c *cdev) request(p Pin, flags uint64, initial State) (cdevLine, error) {
+	chip, err := unix.Open(c.chip, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return cdevLine{}, fmt.Errorf("opening GPIO chip %s: %w", c.chip, err)
+	}
+	defer unix.Close(chip)
+
+	req := unix.GPIOV2LineRequest{Num_lines: 1}
+	req.Offsets[0] = uint32(p)
+	copy(req.Consumer[:], consumer)
+	req.Config.Flags = flags
+
+	if flags&lineFlagOutput != 0 {
+		req.Config.Num_attrs = 1
+		req.Config.Attrs[0] = unix.GPIOV2LineConfigAttribute{
+			Attr: unix.GPIOV2LineAttribute{Id: unix.GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES, Flags: uint64(initial)},
+			Mask: 1,
+		}
+	}
+
+	err = ioctl(chip, unix.GPIO_V2_GET_LINE_IOCTL, unsafe.Pointer(&req))
+	if err != nil {
+		return cdevLine{}, fmt.Errorf("requesting GPIO line %d of %s: %w", p, c.chip, err)
+	}
+
+	line := cdevLine{fd: int(req.Fd), flags: flags}
+	c.lines[p] = line
+
+	return line, nil
+}
+
+func ioctl(fd int, req uint, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
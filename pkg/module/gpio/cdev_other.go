@@ -0,0 +1,23 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package gpio
+
+import "errors"
+
+// errCdevUnsupported is returned by newCdev on platforms without the GPIO
+// character device.
+var errCdevUnsupported = errors.New("the cdev backend is unsupported on this OS: it needs the Linux GPIO character device")
+
+// cdev is the backend of the Linux GPIO character device, which is not
+// available on this platform. It is never instantiated.
+type cdev struct {
+	gpio
+}
+
+func newCdev(string) (*cdev, error) {
+	return nil, errCdevUnsupported
+}
@@ -0,0 +1,273 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package gpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCdev returns the cdev backend of chip.
+func testCdev(t *testing.T, chip string) *cdev {
+	t.Helper()
+
+	c, err := newCdev(chip)
+	if err != nil {
+		t.Fatalf("newCdev(%q): %v", chip, err)
+	}
+
+	return c
+}
+
+// gpioSim sets up a simulated GPIO chip with the kernel's gpio-sim module and
+// returns its name and a function returning the sysfs directory of a line, with
+// the attributes value (the level the line is driven to) and pull (pull-up or
+// pull-down, the level of an input). It skips the test if gpio-sim is not
+// available, which needs configfs, the module and root.
+func gpioSim(t *testing.T, lines int) (string, func(offset int) string) {
+	t.Helper()
+
+	const configfs = "/sys/kernel/config/gpio-sim"
+
+	_, err := os.Stat(configfs)
+	if err != nil {
+		t.Skipf("gpio-sim not available: %v", err)
+	}
+
+	dir := filepath.Join(configfs, fmt.Sprintf("dutctl-%d", os.Getpid()))
+	bank := filepath.Join(dir, "bank0")
+
+	write := func(path, value string) {
+		t.Helper()
+
+		err := os.WriteFile(path, []byte(value), 0o644)
+		if err != nil {
+			t.Fatalf("setting up gpio-sim: %v", err)
+		}
+	}
+
+	err = os.Mkdir(dir, 0o755)
+	if err != nil {
+		t.Skipf("gpio-sim not usable: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = os.WriteFile(filepath.Join(dir, "live"), []byte("0"), 0o644)
+		_ = os.Remove(bank)
+		_ = os.Remove(dir)
+	})
+
+	err = os.Mkdir(bank, 0o755)
+	if err != nil {
+		t.Fatalf("setting up gpio-sim: %v", err)
+	}
+
+	write(filepath.Join(bank, "num_lines"), fmt.Sprint(lines))
+	write(filepath.Join(dir, "live"), "1")
+
+	chip := readSim(t, filepath.Join(bank, "chip_name"))
+	device := filepath.Join("/sys/devices/platform", readSim(t, filepath.Join(dir, "dev_name")), chip)
+
+	return chip, func(offset int) string {
+		return filepath.Join(device, fmt.Sprintf("sim_gpio%d", offset))
+	}
+}
+
+func readSim(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading gpio-sim: %v", err)
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+func pullSim(t *testing.T, path, pull string) {
+	t.Helper()
+
+	err := os.WriteFile(filepath.Join(path, "pull"), []byte(pull), 0o644)
+	if err != nil {
+		t.Fatalf("pulling gpio-sim line: %v", err)
+	}
+}
+
+func TestCdev(t *testing.T) {
+	chip, line := gpioSim(t, 4)
+
+	c := testCdev(t, chip)
+	t.Cleanup(func() { _ = c.Close() })
+
+	steps := []struct {
+		name string
+		op   func(Pin) error
+		pin  Pin
+		want string
+	}{
+		{name: "high", op: c.High, pin: 1, want: "1"},
+		{name: "low", op: c.Low, pin: 1, want: "0"},
+		{name: "toggle", op: c.Toggle, pin: 1, want: "1"},
+		{name: "toggle back", op: c.Toggle, pin: 1, want: "0"},
+		{name: "toggle unused line", op: c.Toggle, pin: 2, want: "1"},
+	}
+
+	for _, st := range steps {
+		err := st.op(st.pin)
+		if err != nil {
+			t.Fatalf("%s: %v", st.name, err)
+		}
+
+		if got := readSim(t, filepath.Join(line(int(st.pin)), "value")); got != st.want {
+			t.Errorf("%s: line %d is %s, want %s", st.name, st.pin, got, st.want)
+		}
+	}
+
+	err := c.High(4)
+	if err == nil {
+		t.Error("setting a line beyond the chip succeeded")
+	}
+}
+
+func TestCdevInput(t *testing.T) {
+	chip, line := gpioSim(t, 4)
+
+	c := testCdev(t, chip)
+	t.Cleanup(func() { _ = c.Close() })
+
+	for _, pull := range []string{"pull-up", "pull-down"} {
+		pullSim(t, line(0), pull)
+
+		level, err := c.Read(0)
+		if err != nil {
+			t.Fatalf("Read = %v", err)
+		}
+
+		if want := map[string]State{"pull-up": High, "pull-down": Low}[pull]; level != want {
+			t.Errorf("Read with %s = %s, want %s", pull, level, want)
+		}
+	}
+
+	// An edge before the wait is ignored, one during the wait ends it.
+	pullSim(t, line(0), "pull-up")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := c.WaitEdge(ctx, 0, Rising)
+	if err == nil {
+		t.Fatal("WaitEdge returned for an edge before the wait")
+	}
+
+	pullSim(t, line(0), "pull-down")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(line(0), "pull"), []byte("pull-up"), 0o644)
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err = c.WaitEdge(ctx, 0, Rising)
+	if err != nil {
+		t.Errorf("WaitEdge = %v, want the rising edge", err)
+	}
+}
+
+func TestCdevLevel(t *testing.T) {
+	chip, _ := gpioSim(t, 4)
+
+	c := testCdev(t, chip)
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err := c.Level(1)
+	if !errors.Is(err, errNotOutput) {
+		t.Errorf("Level of an unused line = %v, want errNotOutput", err)
+	}
+
+	err = c.High(1)
+	if err != nil {
+		t.Fatalf("High = %v", err)
+	}
+
+	level, err := c.Level(1)
+	if err != nil || level != High {
+		t.Errorf("Level of a held output = %s, %v, want high", level, err)
+	}
+
+	_, err = c.Read(2)
+	if err != nil {
+		t.Fatalf("Read = %v", err)
+	}
+
+	_, err = c.Level(2)
+	if !errors.Is(err, errNotOutput) {
+		t.Errorf("Level of an input = %v, want errNotOutput", err)
+	}
+}
+
+func TestCdevShared(t *testing.T) {
+	a := testCdev(t, "/dev/gpiochip-shared")
+	b := testCdev(t, "/dev/gpiochip-shared")
+
+	if a != b {
+		t.Fatal("modules using the same chip got different backends")
+	}
+
+	a.lines[1] = cdevLine{fd: -1}
+
+	_ = a.Close()
+
+	if len(b.lines) != 1 {
+		t.Error("closing one user released the lines of the other")
+	}
+
+	delete(b.lines, 1)
+	_ = b.Close()
+
+	c := testCdev(t, "/dev/gpiochip-shared")
+	defer c.Close()
+
+	if c == a {
+		t.Error("the backend was not released by its last user")
+	}
+}
+
+func TestCdevChip(t *testing.T) {
+	tests := []struct {
+		chip string
+		want string
+	}{
+		{chip: "", want: "/dev/gpiochip0"},
+		{chip: "gpiochip2", want: "/dev/gpiochip2"},
+		{chip: "/dev/gpiochip3", want: "/dev/gpiochip3"},
+	}
+
+	for _, tt := range tests {
+		c := testCdev(t, tt.chip)
+		if c.chip != tt.want {
+			t.Errorf("newCdev(%q).chip = %q, want %q", tt.chip, c.chip, tt.want)
+		}
+
+		_ = c.Close()
+	}
+
+	c := testCdev(t, "/nonexistent/gpiochip0")
+	defer c.Close()
+
+	err := c.High(1)
+	if err == nil || !strings.Contains(err.Error(), "/nonexistent/gpiochip0") {
+		t.Errorf("High on a missing chip = %v, want an error naming the chip", err)
+	}
+}
@@ -6,13 +6,31 @@ package gpio
 
 import (
 	"fmt"
+	"os"
+	"unsafe"
 
 	"github.com/stianeikeland/go-rpio/v4"
+	"golang.org/x/sys/unix"
 )
 
-type devmem struct{}
+const (
+	gpiomemPath  = "/dev/gpiomem" // gpiomemPath maps the GPIO registers, starting with GPFSEL0.
+	gpfselPins   = 10             // gpfselPins is the number of pins per GPFSEL register.
+	gpfselWidth  = 3              // gpfselWidth is the number of bits of the function of a pin.
+	gpfselMask   = 0b111
+	gpfselOutput = 0b001 // gpfselOutput is the function of an output pin.
+)
 
-var _ gpio = &devmem{}
+type devmem struct {
+	// function returns the function of a pin, as set in its GPFSEL register. If
+	// nil, the register is read from /dev/gpiomem.
+	function func(pin Pin) (uint32, error)
+}
+
+var (
+	_ gpio        = &devmem{}
+	_ levelReader = &devmem{}
+)
 
 func (d *devmem) Low(p Pin) error {
 	return memmapDo(func() {
@@ -38,6 +56,70 @@ func (d *devmem) Toggle(pin Pin) error {
 	})
 }
 
+func (d *devmem) Read(pin Pin) (State, error) {
+	var s State
+
+	err := memmapDo(func() {
+		p := rpio.Pin(pin)
+		p.Input()
+		s = State(p.Read())
+	})
+
+	return s, err
+}
+
+// Level returns the level a pin is driven to, without switching it to input. It
+// fails with errNotOutput if the function of the pin is not output, like that of
+// a pin not used since boot.
+func (d *devmem) Level(pin Pin) (State, error) {
+	function := d.function
+	if function == nil {
+		function = gpfsel
+	}
+
+	f, err := function(pin)
+	if err != nil {
+		return Low, fmt.Errorf("reading the function of pin %d: %w", pin, err)
+	}
+
+	if f != gpfselOutput {
+		return Low, errNotOutput
+	}
+
+	var s State
+
+	err = memmapDo(func() {
+		s = State(rpio.Pin(pin).Read())
+	})
+
+	return s, err
+}
+
+// gpfsel reads the function of pin from its GPFSEL register, mapped read-only
+// from /dev/gpiomem. go-rpio only provides setting it.
+func gpfsel(pin Pin) (uint32, error) {
+	file, err := os.Open(gpiomemPath)
+	if err != nil {
+		return 0, err
+	}
+
+	defer file.Close()
+
+	mem, err := unix.Mmap(int(file.Fd()), 0, os.Getpagesize(), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return 0, fmt.Errorf("mapping %s: %w", gpiomemPath, err)
+	}
+
+	defer func() { _ = unix.Munmap(mem) }()
+
+	// The registers must be read with 32-bit accesses.
+	regs := unsafe.Slice((*uint32)(unsafe.Pointer(&mem[0])), len(mem)/int(unsafe.Sizeof(uint32(0))))
+	reg := regs[int(pin)/gpfselPins]
+	shift := uint(pin) % gpfselPins * gpfselWidth
+
+	return (reg >> shift) & gpfselMask, nil
+}
+
 func memmapDo(op func()) error {
 	err := rpio.Open()
 	if err != nil {
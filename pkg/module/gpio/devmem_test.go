@@ -0,0 +1,45 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// inputFunction reports every pin as an input, like after boot.
+func inputFunction(Pin) (uint32, error) {
+	return 0b000, nil
+}
+
+func TestDevmemLevelOfInput(t *testing.T) {
+	d := &devmem{function: inputFunction}
+
+	_, err := d.Level(4)
+	if !errors.Is(err, errNotOutput) {
+		t.Errorf("Level of an input = %v, want errNotOutput", err)
+	}
+
+	d.function = func(Pin) (uint32, error) { return 0, errors.New("no such device") }
+
+	_, err = d.Level(4)
+	if err == nil || errors.Is(err, errNotOutput) {
+		t.Errorf("Level with unreadable registers = %v, want the read error", err)
+	}
+}
+
+func TestDevmemRestoreOfInput(t *testing.T) {
+	s := &Switch{Pin: 4, Backend: "devmem", StateDir: t.TempDir(), gpio: &devmem{function: inputFunction}}
+
+	err := saveState(stateFile(s.StateDir, s.Backend, s.Chip, s.Pin), on)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.restore(context.Background()); got != on {
+		t.Errorf("restored state %q of an input, want the persisted %q", got, on)
+	}
+}
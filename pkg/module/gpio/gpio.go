@@ -3,15 +3,22 @@
 // license that can be found in the LICENSE file.
 
 // Package gpio provides two modules that simulate buttons and switches
-// respectively, using the GPIO pins of the Raspberry Pi. The pins used must be
-// wired to the respective pads or connections on the DUT.
+// respectively, using the GPIO pins of the Raspberry Pi or, through the Linux
+// GPIO character device, of any GPIO chip. The pins used must be wired to the
+// respective pads or connections on the DUT.
 //
 // For example, these modules can be used to pull down the reset line of the DUT.
+//
+// Two more modules use pins as inputs: one reports the level of a pin, the other
+// waits for a level or an edge, e.g. of the DUT's power-good signal.
 package gpio
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"strings"
 	"time"
 
@@ -34,7 +41,8 @@ func init() {
 	})
 }
 
-// Pin is a raw BCM2835/BCM2711 GPIO pin number.
+// Pin is a raw BCM2835/BCM2711 GPIO pin number, or the offset of a line on a
+// GPIO chip with the cdev backend.
 type Pin uint8
 
 // State is the logic level of a GPIO pin, either [Low] or [High].
@@ -45,20 +53,58 @@ type gpio interface {
 	Low(pin Pin) error
 	High(pin Pin) error
 	Toggle(pin Pin) error
+	// Read returns the level of a pin. Depending on the backend, it switches
+	// the pin to input.
+	Read(pin Pin) (State, error)
 }
 
-// backendParser is a function that returns a gpio backend for the given name.
-type backendParser func(name string) gpio
+// DefaultChip is the GPIO chip used by the cdev backend if none is configured.
+const DefaultChip = "gpiochip0"
+
+// backendParser is a function that returns a gpio backend for the given name,
+// using chip if the backend addresses GPIO chips. It fails if the backend is not
+// available on this platform.
+type backendParser func(name, chip string) (gpio, error)
 
 // backendFromOption is the default [backendParser] function.
-// Currently only "devmem" is supported; any unrecognized name falls back to "devmem".
-func backendFromOption(name string) gpio {
+// It supports "devmem" and "cdev"; any unrecognized name falls back to "devmem".
+func backendFromOption(name, chip string) (gpio, error) {
 	switch name {
+	case "cdev":
+		c, err := newCdev(chip)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
 	case "devmem":
-		return &devmem{}
+		return &devmem{}, nil
 	default:
-		return &devmem{}
+		return &devmem{}, nil
+	}
+}
+
+// release releases the resources held by a backend, like the lines requested
+// by cdev.
+func release(g gpio) error {
+	if c, ok := g.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// pinHelp describes the pin used for the help of the modules.
+func pinHelp(backend, chip string, pin Pin) string {
+	if backend == "cdev" {
+		if chip == "" {
+			chip = DefaultChip
+		}
+
+		return fmt.Sprintf("The used GPIO line is line %d of %s.\n", pin, chip)
 	}
+
+	return fmt.Sprintf("The used GPIO pin is pin %d. (Raw BCM2835/BCM2711 pin number)\n", pin)
 }
 
 // Low and High are the logic levels of a GPIO [Pin].
@@ -76,9 +122,10 @@ type Button struct {
 	gpio
 	backendParser
 
-	Pin       Pin    // Raw BCM2835/BCM2711 pin number
+	Pin       Pin    // Raw BCM2835/BCM2711 pin number, or the line offset on Chip with the cdev backend
 	ActiveLow bool   // If set, the idle state is high, and low when pressed. Default is false
-	Backend   string // For future use. Name of the backend to use. Default and fallback is "devmem"
+	Backend   string // Name of the backend to use: "devmem" or "cdev". Default and fallback is "devmem"
+	Chip      string // GPIO chip of the cdev backend, e.g. "gpiochip0" or "/dev/gpiochip1". Default is "gpiochip0"
 }
 
 // Ensure implementing the Module interface.
@@ -121,14 +168,19 @@ func (b *Button) Help() string {
 		help.WriteString("The button is active high. Thus 'Idle' mean 'Low', 'Pressed' means 'High'\n")
 	}
 
-	help.WriteString(fmt.Sprintf("The used GPIO pin is pin %d. (Raw BCM2835/BCM2711 pin number)\n", b.Pin))
+	help.WriteString(pinHelp(b.Backend, b.Chip, b.Pin))
 	help.WriteString(description3Button)
 
 	return help.String()
 }
 
 func (b *Button) Init(ctx context.Context) error {
-	b.gpio = b.backendParser(b.Backend)
+	g, err := b.backendParser(b.Backend, b.Chip)
+	if err != nil {
+		return err
+	}
+
+	b.gpio = g
 
 	log.FromContext(ctx).Debug(fmt.Sprintf("initializing pin %d to idle", b.Pin))
 
@@ -145,7 +197,12 @@ func (b *Button) Deinit(_ context.Context) error {
 		return nil
 	}
 
-	return b.Low(b.Pin)
+	err := b.Low(b.Pin)
+	if err != nil {
+		return err
+	}
+
+	return release(b.gpio)
 }
 
 func (b *Button) Run(ctx context.Context, s module.Session, args ...string) error {
@@ -215,34 +272,46 @@ const (
 
 // A Switch simulates an on/off switch by changing the state of a GPIO pin.
 // By default, the switch is off and off means the pin is low.
+//
+// The state of the switch is persisted in a file, so it is known after a
+// restart of the agent even if the backend cannot read back the pin.
 type Switch struct {
 	gpio
 	backendParser
 
-	// Raw BCM2835/BCM2711 pin number
+	// Raw BCM2835/BCM2711 pin number, or the line offset on Chip with the cdev backend
 	Pin Pin
-	// Initial state of the switch: "on" or "off" (case insensitive). Default and fallback is "off".
+	// Initial state of the switch: "on", "off" or "restore" (case insensitive). "restore" keeps the state
+	// the switch had before the agent started. Default and fallback is "off".
 	Initial string
 	// If true, the switch is active low (switch on means gpio pin low). Default is false.
 	ActiveLow bool
-	// For future use. Name of the backend to use. Default is "devmem"
+	// Name of the backend to use: "devmem" or "cdev". Default and fallback is "devmem"
 	Backend string
+	// GPIO chip of the cdev backend, e.g. "gpiochip0" or "/dev/gpiochip1". Default is "gpiochip0"
+	Chip string
+	// Directory the state of the switch is persisted in. Default is DefaultStateDir
+	StateDir string
 
 	state switchState
 }
 
-// Ensure implementing the Module interface.
-var _ module.Module = &Switch{}
+// Ensure implementing the Module and PowerController interfaces.
+var (
+	_ module.Module          = &Switch{}
+	_ module.PowerController = &Switch{}
+)
 
 const abstractSwitch = `Simulate an on/off switch by changing the state of a GPIO pin
 `
 const usageSwitch = `
 ARGUMENTS:
-	[on|off|toggle]
+	[on|off|toggle|status]
 `
 const description1Switch = `
 The on, off and toggle commands control the state of the switch.
-If no argument is passed, the current state is printed.
+The status command, or no argument, prints the current state. It is read back from the pin
+if the backend allows it, else the state last set is reported.
 
 `
 const description2Switch = `
@@ -262,27 +331,44 @@ func (s *Switch) Help() string {
 		help.WriteString("The switch is active high. Thus 'On' mean 'High', 'Off' means 'Low'\n")
 	}
 
-	help.WriteString(fmt.Sprintf("The used GPIO pin is pin %d. (Raw BCM2835/BCM2711 pin number)\n", s.Pin))
+	help.WriteString(pinHelp(s.Backend, s.Chip, s.Pin))
 	help.WriteString(description2Switch)
 
 	return help.String()
 }
 
 func (s *Switch) Init(ctx context.Context) error {
-	s.gpio = s.backendParser(s.Backend)
+	g, err := s.backendParser(s.Backend, s.Chip)
+	if err != nil {
+		return err
+	}
+
+	s.gpio = g
 
-	initial := strings.ToLower(s.Initial)
-	if initial == "on" {
+	switch strings.ToLower(s.Initial) {
+	case "on":
 		s.state = on
-		log.FromContext(ctx).Debug(fmt.Sprintf("initializing pin %d to on", s.Pin))
+	case "restore":
+		s.state = s.restore(ctx)
+	default:
+		s.state = off
+	}
+
+	log.FromContext(ctx).Debug(fmt.Sprintf("initializing pin %d to %s", s.Pin, s.state))
 
-		return s.on()
+	if s.state == on {
+		err = s.on()
+	} else {
+		err = s.off()
+	}
+
+	if err != nil {
+		return err
 	}
 
-	s.state = off
-	log.FromContext(ctx).Debug(fmt.Sprintf("initializing pin %d to off", s.Pin))
+	s.persist(ctx)
 
-	return s.off()
+	return nil
 }
 
 func (s *Switch) Deinit(_ context.Context) error {
@@ -290,7 +376,15 @@ func (s *Switch) Deinit(_ context.Context) error {
 		return nil
 	}
 
-	return s.Low(s.Pin)
+	// A restoring switch keeps its state, so a restart of the agent does not affect the DUT.
+	if !strings.EqualFold(s.Initial, "restore") {
+		err := s.Low(s.Pin)
+		if err != nil {
+			return err
+		}
+	}
+
+	return release(s.gpio)
 }
 
 //nolint:cyclop,funlen // on/off/toggle branches each set state and log; long but linear and readable.
@@ -298,12 +392,16 @@ func (s *Switch) Run(ctx context.Context, sesh module.Session, args ...string) e
 	l := log.FromContext(ctx)
 
 	if len(args) == 0 {
-		sesh.Printf("Current state: %s\n", s.state)
+		s.status(ctx, sesh)
 
 		return nil
 	}
 
 	switch args[0] {
+	case "status":
+		s.status(ctx, sesh)
+
+		return nil
 	case "on":
 		err := s.on()
 		if err != nil {
@@ -319,6 +417,7 @@ func (s *Switch) Run(ctx context.Context, sesh module.Session, args ...string) e
 		}
 
 		s.state = on
+		s.persist(ctx)
 
 		return nil
 	case "off":
@@ -336,6 +435,7 @@ func (s *Switch) Run(ctx context.Context, sesh module.Session, args ...string) e
 		}
 
 		s.state = off
+		s.persist(ctx)
 
 		return nil
 	case "toggle":
@@ -354,6 +454,7 @@ func (s *Switch) Run(ctx context.Context, sesh module.Session, args ...string) e
 			s.state = on
 		}
 
+		s.persist(ctx)
 		l.Info(fmt.Sprintf("switch %s (pin %d)", s.state, s.Pin))
 	default:
 		return fmt.Errorf("unknown argument: %s", args[0])
@@ -377,3 +478,131 @@ func (s *Switch) off() error {
 
 	return s.Low(s.Pin)
 }
+
+// status prints the current state of the switch.
+func (s *Switch) status(ctx context.Context, sesh module.Session) {
+	sesh.Printf("Current state: %s\n", s.current(ctx))
+}
+
+// current returns the state of the switch. If the backend can read back the pin,
+// the state read replaces the one tracked, which may be stale, e.g. if the pin
+// was changed by someone else.
+func (s *Switch) current(ctx context.Context) switchState {
+	state, err := s.readBack()
+	if err == nil && state != s.state {
+		log.FromContext(ctx).Warn(fmt.Sprintf("pin %d reads %s, but the switch was %s", s.Pin, state, s.state))
+
+		s.state = state
+		s.persist(ctx)
+	}
+
+	return s.state
+}
+
+// PowerOn turns the switch on.
+func (s *Switch) PowerOn(ctx context.Context) error {
+	return s.set(ctx, on)
+}
+
+// PowerOff turns the switch off.
+func (s *Switch) PowerOff(ctx context.Context) error {
+	return s.set(ctx, off)
+}
+
+// PowerState reports the current state of the switch.
+func (s *Switch) PowerState(ctx context.Context) (module.PowerState, error) {
+	if s.gpio == nil {
+		return module.PowerUnknown, errors.New("switch not initialized")
+	}
+
+	if s.current(ctx) == on {
+		return module.PowerOn, nil
+	}
+
+	return module.PowerOff, nil
+}
+
+// set drives the pin to state on behalf of the power controller and persists it.
+func (s *Switch) set(ctx context.Context, state switchState) error {
+	if s.gpio == nil {
+		return errors.New("switch not initialized")
+	}
+
+	var err error
+
+	if state == on {
+		err = s.on()
+	} else {
+		err = s.off()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("switch %s (pin %d)", state, s.Pin))
+
+	s.state = state
+	s.persist(ctx)
+
+	return nil
+}
+
+// restore returns the state the switch had before the agent started: read back
+// from the pin if the backend allows it, else the persisted one. Without
+// either, the switch is off.
+func (s *Switch) restore(ctx context.Context) switchState {
+	l := log.FromContext(ctx)
+
+	state, err := s.readBack()
+	if err == nil {
+		l.Debug(fmt.Sprintf("pin %d read back as %s", s.Pin, state))
+
+		return state
+	}
+
+	l.Debug(fmt.Sprintf("pin %d not read back: %v", s.Pin, err))
+
+	file := stateFile(s.StateDir, s.Backend, s.Chip, s.Pin)
+
+	state, err = loadState(file)
+	if err == nil {
+		l.Debug(fmt.Sprintf("pin %d restored as %s from %s", s.Pin, state, file))
+
+		return state
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		l.Warn(fmt.Sprintf("restoring state of pin %d: %v", s.Pin, err))
+	}
+
+	return off
+}
+
+// readBack returns the state of the switch read back from the pin.
+func (s *Switch) readBack() (switchState, error) {
+	r, ok := s.gpio.(levelReader)
+	if !ok {
+		return off, errors.New("the backend cannot read back pins")
+	}
+
+	level, err := r.Level(s.Pin)
+	if err != nil {
+		return off, err
+	}
+
+	if (level == High) != s.ActiveLow {
+		return on, nil
+	}
+
+	return off, nil
+}
+
+// persist saves the state of the switch. A failure is only logged, as the
+// switch works without.
+func (s *Switch) persist(ctx context.Context) {
+	err := saveState(stateFile(s.StateDir, s.Backend, s.Chip, s.Pin), s.state)
+	if err != nil {
+		log.FromContext(ctx).Warn(fmt.Sprintf("persisting state of pin %d: %v", s.Pin, err))
+	}
+}
@@ -7,18 +7,23 @@ package gpio
 import (
 	"context"
 	"errors"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 )
 
 type MockGpio struct {
 	LowCalled    bool
 	HighCalled   bool
 	ToggleCalled bool
+	ReadCalled   bool
 
-	err error
+	level State
+	err   error
 }
 
 func (m *MockGpio) Low(pin Pin) error {
@@ -45,14 +50,22 @@ func (m *MockGpio) Toggle(pin Pin) error {
 	return nil
 }
 
-func mockBackend(_ string) gpio {
-	return &MockGpio{}
+func (m *MockGpio) Read(pin Pin) (State, error) {
+	m.ReadCalled = true
+	if m.err != nil {
+		return Low, m.err
+	}
+	return m.level, nil
+}
+
+func mockBackend(_, _ string) (gpio, error) {
+	return &MockGpio{}, nil
 }
 
-func mockErrBackend(_ string) gpio {
+func mockErrBackend(_, _ string) (gpio, error) {
 	return &MockGpio{
 		err: errors.New("fake GPIO error"),
-	}
+	}, nil
 }
 
 func TestButtonInit(t *testing.T) {
@@ -434,6 +447,7 @@ func TestSwitchInit(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.swtch.backendParser = mockBackend
+			tt.swtch.StateDir = t.TempDir()
 
 			err := tt.swtch.Init(context.Background())
 			if (err != nil) != tt.expectErr {
@@ -638,6 +652,14 @@ func TestSwitchRun(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "Run with args 'status'",
+			swtch: Switch{
+				state: on,
+			},
+			args:        []string{"status"},
+			expectPrint: "Current state: on\n",
+		},
 		{
 			name:    "Run with args 'toggle' with GPIO error",
 			swtch:   Switch{},
@@ -656,6 +678,7 @@ func TestSwitchRun(t *testing.T) {
 				err: tt.mockErr,
 			}
 			tt.swtch.gpio = mockGpio
+			tt.swtch.StateDir = t.TempDir()
 			ctx := context.Background()
 			sesh := &mock.Session{}
 
@@ -673,6 +696,225 @@ func TestSwitchRun(t *testing.T) {
 	}
 }
 
+// levelGpio is a MockGpio whose backend can read back the level of a pin.
+type levelGpio struct {
+	MockGpio
+
+	levelErr error
+}
+
+func (m *levelGpio) Level(pin Pin) (State, error) {
+	return m.level, m.levelErr
+}
+
+func TestSwitchRestore(t *testing.T) {
+	tests := []struct {
+		name      string
+		backend   gpio
+		activeLow bool
+		persisted string // persisted is the content of the state file, if any.
+		want      switchState
+	}{
+		{
+			name:    "read back high",
+			backend: &levelGpio{MockGpio: MockGpio{level: High}},
+			want:    on,
+		},
+		{
+			name:      "read back high, active low",
+			backend:   &levelGpio{MockGpio: MockGpio{level: High}},
+			activeLow: true,
+			want:      off,
+		},
+		{
+			name:      "read back preferred",
+			backend:   &levelGpio{MockGpio: MockGpio{level: Low}},
+			persisted: "on\n",
+			want:      off,
+		},
+		{
+			name:      "not an output",
+			backend:   &levelGpio{levelErr: errNotOutput},
+			persisted: "on\n",
+			want:      on,
+		},
+		{
+			name:      "no read back",
+			backend:   &MockGpio{},
+			persisted: "on\n",
+			want:      on,
+		},
+		{
+			name:    "nothing to restore",
+			backend: &MockGpio{},
+			want:    off,
+		},
+		{
+			name:      "invalid state file",
+			backend:   &MockGpio{},
+			persisted: "maybe\n",
+			want:      off,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swtch := Switch{
+				Pin:           7,
+				Initial:       "restore",
+				ActiveLow:     tt.activeLow,
+				StateDir:      t.TempDir(),
+				backendParser: func(_, _ string) (gpio, error) { return tt.backend, nil },
+			}
+			file := stateFile(swtch.StateDir, "", "", swtch.Pin)
+
+			if tt.persisted != "" {
+				err := os.WriteFile(file, []byte(tt.persisted), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := swtch.Init(context.Background())
+			if err != nil {
+				t.Fatalf("Init = %v", err)
+			}
+
+			if swtch.state != tt.want {
+				t.Errorf("restored state %q, want %q", swtch.state, tt.want)
+			}
+
+			got, err := loadState(file)
+			if err != nil || got != tt.want {
+				t.Errorf("persisted state %q (%v), want %q", got, err, tt.want)
+			}
+
+			m, ok := tt.backend.(*MockGpio)
+			if l, isLevel := tt.backend.(*levelGpio); isLevel {
+				m, ok = &l.MockGpio, true
+			}
+
+			if ok {
+				m.LowCalled = false
+			}
+
+			err = swtch.Deinit(context.Background())
+			if err != nil {
+				t.Fatalf("Deinit = %v", err)
+			}
+
+			if ok && m.LowCalled {
+				t.Error("Deinit changed the pin of a restoring switch")
+			}
+		})
+	}
+}
+
+func TestSwitchPersist(t *testing.T) {
+	swtch := Switch{Pin: 5, StateDir: t.TempDir(), backendParser: mockBackend}
+	ctx := context.Background()
+	file := stateFile(swtch.StateDir, "", "", swtch.Pin)
+
+	err := swtch.Init(ctx)
+	if err != nil {
+		t.Fatalf("Init = %v", err)
+	}
+
+	for _, args := range [][]string{{"on"}, {"toggle"}, {"toggle"}, {"off"}} {
+		err = swtch.Run(ctx, &mock.Session{}, args...)
+		if err != nil {
+			t.Fatalf("Run(%q) = %v", args, err)
+		}
+
+		got, err := loadState(file)
+		if err != nil || got != swtch.state {
+			t.Errorf("after %q persisted state %q (%v), want %q", args, got, err, swtch.state)
+		}
+	}
+}
+
+func TestSwitchStatusReadBack(t *testing.T) {
+	backend := &levelGpio{MockGpio: MockGpio{level: High}}
+	swtch := Switch{Pin: 3, StateDir: t.TempDir(), state: off}
+	swtch.gpio = backend
+	sesh := &mock.Session{}
+
+	err := swtch.Run(context.Background(), sesh, "status")
+	if err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+
+	if want := "Current state: on\n"; sesh.PrintText != want {
+		t.Errorf("printed %q, want %q", sesh.PrintText, want)
+	}
+
+	if swtch.state != on {
+		t.Errorf("tracked state %q, want %q", swtch.state, on)
+	}
+}
+
+func TestSwitchPowerController(t *testing.T) {
+	tests := []struct {
+		name      string
+		activeLow bool
+		on        bool
+		wantHigh  bool
+		wantLow   bool
+		wantState module.PowerState
+	}{
+		{name: "on active high", on: true, wantHigh: true, wantState: module.PowerOn},
+		{name: "off active high", on: false, wantLow: true, wantState: module.PowerOff},
+		{name: "on active low", activeLow: true, on: true, wantLow: true, wantState: module.PowerOn},
+		{name: "off active low", activeLow: true, on: false, wantHigh: true, wantState: module.PowerOff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockGpio{}
+			swtch := Switch{Pin: 4, ActiveLow: tt.activeLow, StateDir: t.TempDir()}
+			swtch.gpio = m
+			ctx := context.Background()
+
+			var err error
+			if tt.on {
+				err = swtch.PowerOn(ctx)
+			} else {
+				err = swtch.PowerOff(ctx)
+			}
+
+			if err != nil {
+				t.Fatalf("switching = %v", err)
+			}
+
+			if m.HighCalled != tt.wantHigh || m.LowCalled != tt.wantLow {
+				t.Errorf("High called %v, Low called %v, want %v, %v", m.HighCalled, m.LowCalled, tt.wantHigh, tt.wantLow)
+			}
+
+			got, err := swtch.PowerState(ctx)
+			if err != nil || got != tt.wantState {
+				t.Errorf("PowerState = %v, %v, want %v", got, err, tt.wantState)
+			}
+
+			persisted, err := loadState(stateFile(swtch.StateDir, "", "", swtch.Pin))
+			if err != nil || persisted != swtch.state {
+				t.Errorf("persisted state %q (%v), want %q", persisted, err, swtch.state)
+			}
+		})
+	}
+}
+
+func TestSwitchPowerControllerUninitialized(t *testing.T) {
+	swtch := Switch{Pin: 4}
+
+	if err := swtch.PowerOn(context.Background()); err == nil {
+		t.Error("PowerOn: expected error before Init")
+	}
+
+	if _, err := swtch.PowerState(context.Background()); err == nil {
+		t.Error("PowerState: expected error before Init")
+	}
+}
+
 func TestSwitchHelp(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -763,13 +1005,22 @@ func TestBackendFromOption(t *testing.T) {
 			input:    "",
 			expected: &devmem{},
 		},
+		{
+			name:     "Valid backend 'cdev'",
+			input:    "cdev",
+			expected: &cdev{},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := backendFromOption(tt.input)
-			if _, ok := result.(*devmem); !ok {
-				t.Errorf("expected type *devmem, got %T", result)
+			result, err := backendFromOption(tt.input, "")
+			if err != nil {
+				t.Fatalf("backendFromOption(%q): %v", tt.input, err)
+			}
+
+			if reflect.TypeOf(result) != reflect.TypeOf(tt.expected) {
+				t.Errorf("expected type %T, got %T", tt.expected, result)
 			}
 		})
 	}
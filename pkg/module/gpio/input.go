@@ -0,0 +1,316 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func init() {
+	module.Register(module.Record{
+		ID: "gpio-read",
+		New: func() module.Module {
+			return &Input{backendParser: backendFromOption}
+		},
+	})
+	module.Register(module.Record{
+		ID: "gpio-wait",
+		New: func() module.Module {
+			return &Wait{backendParser: backendFromOption}
+		},
+	})
+}
+
+// DefaultWaitTimeout is the timeout used when Wait.Run is invoked without a
+// timeout argument.
+const DefaultWaitTimeout = 10 * time.Second
+
+// pollInterval is the pause between the reads of a pin while waiting for a
+// level, or for an edge with a backend not detecting edges itself.
+const pollInterval = 10 * time.Millisecond
+
+// Edge is a change of the logic level of a GPIO pin.
+type Edge uint8
+
+// Rising and Falling are the edges of a GPIO [Pin]; AnyEdge is either.
+const (
+	AnyEdge Edge = iota
+	Rising
+	Falling
+)
+
+// edgeWaiter is implemented by backends detecting edges themselves, so even
+// pulses shorter than pollInterval are not missed.
+type edgeWaiter interface {
+	// WaitEdge blocks until edge e of pin p, or until ctx is done.
+	WaitEdge(ctx context.Context, p Pin, e Edge) error
+}
+
+func (s State) String() string {
+	if s == High {
+		return "high"
+	}
+
+	return "low"
+}
+
+// condition is what Wait waits for: a level or an edge.
+type condition struct {
+	name  string
+	edge  bool
+	level State // level is the level waited for, if not edge.
+	which Edge  // which is the edge waited for, if edge.
+}
+
+// conditions holds the conditions by argument.
+//
+//nolint:gochecknoglobals // the table of Wait's arguments
+var conditions = map[string]condition{
+	"high":    {name: "high", level: High},
+	"low":     {name: "low", level: Low},
+	"rising":  {name: "rising edge", edge: true, which: Rising},
+	"falling": {name: "falling edge", edge: true, which: Falling},
+	"edge":    {name: "edge", edge: true, which: AnyEdge},
+}
+
+// waitFor blocks until cond is met on pin p, or ctx is done. A level is met
+// right away if the pin is at it already; an edge must occur after the call.
+func waitFor(ctx context.Context, g gpio, p Pin, cond condition) error {
+	if cond.edge {
+		if w, ok := g.(edgeWaiter); ok {
+			return w.WaitEdge(ctx, p, cond.which)
+		}
+	}
+
+	last, err := g.Read(p)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if !cond.edge && last == cond.level {
+			return nil
+		}
+
+		err = sleepCtx(ctx, pollInterval)
+		if err != nil {
+			return err
+		}
+
+		level, err := g.Read(p)
+		if err != nil {
+			return err
+		}
+
+		if cond.edge && level != last && (cond.which == AnyEdge || (cond.which == Rising) == (level == High)) {
+			return nil
+		}
+
+		last = level
+	}
+}
+
+// An Input reports the logic level of a GPIO pin, e.g. of a power-good LED or
+// a boot-status pin of the DUT.
+type Input struct {
+	gpio
+	backendParser
+
+	// Raw BCM2835/BCM2711 pin number, or the line offset on Chip with the cdev backend
+	Pin Pin
+	// Name of the backend to use: "devmem" or "cdev". Default and fallback is "devmem"
+	Backend string
+	// GPIO chip of the cdev backend, e.g. "gpiochip0" or "/dev/gpiochip1". Default is "gpiochip0"
+	Chip string
+}
+
+// Ensure implementing the Module interface.
+var _ module.Module = &Input{}
+
+const abstractInput = `Report the logic level of a GPIO pin
+`
+const usageInput = `
+ARGUMENTS:
+	(none)
+
+`
+const descriptionInput = `
+The pin is used as an input. It is the users responsibility to ensure that the used GPIO pin
+is not also used by other modules or otherwise occupied by the system!
+`
+
+func (i *Input) Help() string {
+	help := strings.Builder{}
+	help.WriteString(abstractInput)
+	help.WriteString(usageInput)
+	help.WriteString(pinHelp(i.Backend, i.Chip, i.Pin))
+	help.WriteString(descriptionInput)
+
+	return help.String()
+}
+
+func (i *Input) Init(ctx context.Context) error {
+	g, err := i.backendParser(i.Backend, i.Chip)
+	if err != nil {
+		return err
+	}
+
+	i.gpio = g
+
+	level, err := i.Read(i.Pin)
+	if err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Debug(fmt.Sprintf("pin %d is %s", i.Pin, level))
+
+	return nil
+}
+
+func (i *Input) Deinit(_ context.Context) error {
+	if i.gpio == nil {
+		return nil
+	}
+
+	return release(i.gpio)
+}
+
+func (i *Input) Run(ctx context.Context, s module.Session, args ...string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+
+	level, err := i.Read(i.Pin)
+	if err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("pin %d is %s", i.Pin, level))
+	s.Printf("Pin %d is %s\n", i.Pin, level)
+
+	return nil
+}
+
+// A Wait blocks until a GPIO pin reaches a level or sees an edge, e.g. until
+// the DUT signals power good after a button press.
+type Wait struct {
+	gpio
+	backendParser
+
+	// Raw BCM2835/BCM2711 pin number, or the line offset on Chip with the cdev backend
+	Pin Pin
+	// Name of the backend to use: "devmem" or "cdev". Default and fallback is "devmem"
+	Backend string
+	// GPIO chip of the cdev backend, e.g. "gpiochip0" or "/dev/gpiochip1". Default is "gpiochip0"
+	Chip string
+}
+
+// Ensure implementing the Module interface.
+var _ module.Module = &Wait{}
+
+const abstractWait = `Wait for a level or an edge of a GPIO pin
+`
+const usageWait = `
+ARGUMENTS:
+	high|low|rising|falling|edge [timeout]
+
+`
+const description1Wait = `
+high and low wait until the pin is at that level, which may be the case right away.
+rising, falling and edge wait for the respective edge, ignoring edges before the call.
+The module fails if the timeout passes first. If no timeout is passed, a default is used.
+`
+const description2Wait = `
+The pin is used as an input. It is the users responsibility to ensure that the used GPIO pin
+is not also used by other modules or otherwise occupied by the system!
+`
+
+func (w *Wait) Help() string {
+	help := strings.Builder{}
+	help.WriteString(abstractWait)
+	help.WriteString(usageWait)
+	help.WriteString(description1Wait)
+	help.WriteString(fmt.Sprintf("Default timeout is %s.\n", DefaultWaitTimeout))
+	help.WriteString(pinHelp(w.Backend, w.Chip, w.Pin))
+	help.WriteString(description2Wait)
+
+	return help.String()
+}
+
+func (w *Wait) Init(ctx context.Context) error {
+	g, err := w.backendParser(w.Backend, w.Chip)
+	if err != nil {
+		return err
+	}
+
+	w.gpio = g
+
+	level, err := w.Read(w.Pin)
+	if err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Debug(fmt.Sprintf("pin %d is %s", w.Pin, level))
+
+	return nil
+}
+
+func (w *Wait) Deinit(_ context.Context) error {
+	if w.gpio == nil {
+		return nil
+	}
+
+	return release(w.gpio)
+}
+
+func (w *Wait) Run(ctx context.Context, s module.Session, args ...string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("want high, low, rising, falling or edge and an optional timeout")
+	}
+
+	cond, ok := conditions[strings.ToLower(args[0])]
+	if !ok {
+		return fmt.Errorf("unknown condition %q: want high, low, rising, falling or edge", args[0])
+	}
+
+	timeout := DefaultWaitTimeout
+
+	if len(args) == 2 {
+		var err error
+
+		timeout, err = time.ParseDuration(args[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	err := waitFor(waitCtx, w.gpio, w.Pin, cond)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("no %s on pin %d within %s", cond.name, w.Pin, timeout)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	log.FromContext(ctx).Info(fmt.Sprintf("%s on pin %d after %s", cond.name, w.Pin, elapsed))
+	s.Printf("Pin %d: %s after %s\n", w.Pin, cond.name, elapsed)
+
+	return nil
+}
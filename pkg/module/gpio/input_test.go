@@ -0,0 +1,200 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+)
+
+// scriptedGpio is a gpio backend whose pin reads the levels of a script, one
+// per read, and then stays at the last one.
+type scriptedGpio struct {
+	MockGpio
+
+	levels []State
+	reads  int
+}
+
+func (s *scriptedGpio) Read(_ Pin) (State, error) {
+	if s.err != nil {
+		return Low, s.err
+	}
+
+	level := s.levels[min(s.reads, len(s.levels)-1)]
+	s.reads++
+
+	return level, nil
+}
+
+// edgeGpio is a gpio backend detecting edges itself.
+type edgeGpio struct {
+	MockGpio
+
+	waited []Edge
+}
+
+func (e *edgeGpio) WaitEdge(_ context.Context, _ Pin, edge Edge) error {
+	e.waited = append(e.waited, edge)
+
+	return e.err
+}
+
+func TestWaitFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		levels  []State
+		cond    string
+		wantErr bool
+	}{
+		{name: "high right away", levels: []State{High}, cond: "high"},
+		{name: "low later", levels: []State{High, High, Low}, cond: "low"},
+		{name: "never high", levels: []State{Low}, cond: "high", wantErr: true},
+		{name: "rising", levels: []State{Low, Low, High}, cond: "rising"},
+		{name: "rising after falling", levels: []State{High, Low, High}, cond: "rising"},
+		{name: "no rising edge at high level", levels: []State{High}, cond: "rising", wantErr: true},
+		{name: "falling", levels: []State{High, Low}, cond: "falling"},
+		{name: "any edge", levels: []State{Low, High}, cond: "edge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := waitFor(ctx, &scriptedGpio{levels: tt.levels}, 1, conditions[tt.cond])
+			if (err != nil) != tt.wantErr {
+				t.Errorf("waitFor(%s) = %v, wantErr %v", tt.cond, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWaitForEdgeWaiter(t *testing.T) {
+	g := &edgeGpio{}
+
+	for _, cond := range []string{"rising", "falling", "edge"} {
+		err := waitFor(context.Background(), g, 1, conditions[cond])
+		if err != nil {
+			t.Fatalf("waitFor(%s) = %v", cond, err)
+		}
+	}
+
+	want := []Edge{Rising, Falling, AnyEdge}
+	if len(g.waited) != len(want) || g.waited[0] != want[0] || g.waited[1] != want[1] || g.waited[2] != want[2] {
+		t.Errorf("waited for %v, want %v", g.waited, want)
+	}
+
+	// A level is polled even if the backend detects edges.
+	err := waitFor(context.Background(), g, 1, conditions["low"])
+	if err != nil || !g.ReadCalled {
+		t.Errorf("waitFor(low) = %v, read %t, want nil and a read", err, g.ReadCalled)
+	}
+}
+
+func TestInputRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     State
+		args      []string
+		mockErr   error
+		expectOut string
+		expectErr bool
+	}{
+		{name: "high", level: High, expectOut: "Pin 4 is high\n"},
+		{name: "low", level: Low, expectOut: "Pin 4 is low\n"},
+		{name: "arguments", args: []string{"now"}, expectErr: true},
+		{name: "error", mockErr: errors.New("fake GPIO error"), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &Input{Pin: 4, gpio: &MockGpio{level: tt.level, err: tt.mockErr}}
+			sesh := &mock.Session{}
+
+			err := in.Run(context.Background(), sesh, tt.args...)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Run = %v, expectErr %v", err, tt.expectErr)
+			}
+
+			if sesh.PrintText != tt.expectOut {
+				t.Errorf("printed %q, want %q", sesh.PrintText, tt.expectOut)
+			}
+		})
+	}
+}
+
+func TestInputInit(t *testing.T) {
+	in := &Input{Pin: 4, backendParser: mockBackend}
+
+	err := in.Init(context.Background())
+	if err != nil {
+		t.Fatalf("Init = %v", err)
+	}
+
+	if !in.gpio.(*MockGpio).ReadCalled {
+		t.Error("Init did not read the pin")
+	}
+
+	in = &Input{Pin: 4, backendParser: mockErrBackend}
+
+	err = in.Init(context.Background())
+	if err == nil {
+		t.Error("Init with a failing backend = nil, want an error")
+	}
+}
+
+func TestWaitRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		levels    []State
+		args      []string
+		expectOut string
+		expectErr string
+	}{
+		{name: "level", levels: []State{Low, High}, args: []string{"high"}, expectOut: "Pin 7: high after"},
+		{name: "edge", levels: []State{High, Low}, args: []string{"Falling", "1s"}, expectOut: "Pin 7: falling edge after"},
+		{name: "timeout", levels: []State{Low}, args: []string{"high", "50ms"}, expectErr: "no high on pin 7 within 50ms"},
+		{name: "missing condition", expectErr: "want high, low"},
+		{name: "unknown condition", args: []string{"up"}, expectErr: "unknown condition"},
+		{name: "invalid timeout", args: []string{"high", "soon"}, expectErr: "invalid duration"},
+		{name: "too many arguments", args: []string{"high", "1s", "2s"}, expectErr: "want high, low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Wait{Pin: 7, gpio: &scriptedGpio{levels: append(tt.levels, Low)}}
+			sesh := &mock.Session{}
+
+			err := w.Run(context.Background(), sesh, tt.args...)
+			if tt.expectErr == "" && err != nil {
+				t.Fatalf("Run = %v, want nil", err)
+			}
+
+			if tt.expectErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectErr)) {
+				t.Fatalf("Run = %v, want error containing %q", err, tt.expectErr)
+			}
+
+			if !strings.HasPrefix(sesh.PrintText, tt.expectOut) {
+				t.Errorf("printed %q, want %q first", sesh.PrintText, tt.expectOut)
+			}
+		})
+	}
+}
+
+func TestWaitHelp(t *testing.T) {
+	help := strings.ToLower((&Wait{Pin: 3, Backend: "cdev", Chip: "gpiochip1"}).Help())
+
+	for _, want := range []string{"rising", "timeout", "line 3 of gpiochip1"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("expected string %q not found in help output", want)
+		}
+	}
+}
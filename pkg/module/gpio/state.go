@@ -0,0 +1,89 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultStateDir is the directory a Switch persists its state in if none is
+// configured.
+const DefaultStateDir = "/var/lib/dutagent/gpio"
+
+// Permissions of the state directory and files.
+const (
+	stateDirPerm  = 0o755
+	stateFilePerm = 0o644
+)
+
+// errNotOutput is returned by a [levelReader] if a pin is not driven, so the
+// level it reads would not tell the state of a switch.
+var errNotOutput = errors.New("pin is not an output")
+
+// levelReader is implemented by backends that can read back the level a pin
+// is driven to, without changing its direction.
+type levelReader interface {
+	// Level returns the level pin is driven to. It fails with errNotOutput
+	// if the backend knows the pin is not an output.
+	Level(pin Pin) (State, error)
+}
+
+// stateFile returns the path of the file the state of a switch on pin is
+// persisted in. The name identifies the pin, so switches using the same
+// directory do not clash.
+func stateFile(dir, backend, chip string, pin Pin) string {
+	if dir == "" {
+		dir = DefaultStateDir
+	}
+
+	name := fmt.Sprintf("devmem-%d", pin)
+
+	if backend == "cdev" {
+		if chip == "" {
+			chip = DefaultChip
+		}
+
+		name = fmt.Sprintf("cdev-%s-%d", filepath.Base(chip), pin)
+	}
+
+	return filepath.Join(dir, name)
+}
+
+// loadState returns the state persisted in file.
+func loadState(file string) (switchState, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return off, err
+	}
+
+	switch s := switchState(strings.TrimSpace(string(data))); s {
+	case on, off:
+		return s, nil
+	default:
+		return off, fmt.Errorf("invalid switch state %q in %s", s, file)
+	}
+}
+
+// saveState persists state in file, creating its directory if needed. The file
+// is replaced atomically, so it is never read half-written.
+func saveState(file string, state switchState) error {
+	err := os.MkdirAll(filepath.Dir(file), stateDirPerm)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+
+	err = os.WriteFile(tmp, []byte(string(state)+"\n"), stateFilePerm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		backend string
+		chip    string
+		pin     Pin
+		want    string
+	}{
+		{name: "defaults", pin: 17, want: filepath.Join(DefaultStateDir, "devmem-17")},
+		{name: "devmem", dir: "/tmp/s", backend: "devmem", chip: "gpiochip1", pin: 4, want: "/tmp/s/devmem-4"},
+		{name: "cdev default chip", dir: "/tmp/s", backend: "cdev", pin: 3, want: "/tmp/s/cdev-gpiochip0-3"},
+		{name: "cdev chip path", dir: "/tmp/s", backend: "cdev", chip: "/dev/gpiochip2", pin: 3, want: "/tmp/s/cdev-gpiochip2-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stateFile(tt.dir, tt.backend, tt.chip, tt.pin)
+			if got != tt.want {
+				t.Errorf("stateFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoadState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "devmem-1")
+
+	_, err := loadState(file)
+	if !os.IsNotExist(err) {
+		t.Fatalf("loadState of a missing file = %v, want not exist", err)
+	}
+
+	for _, state := range []switchState{on, off} {
+		err = saveState(file, state)
+		if err != nil {
+			t.Fatalf("saveState(%q) = %v", state, err)
+		}
+
+		got, err := loadState(file)
+		if err != nil || got != state {
+			t.Errorf("loadState() = %q, %v, want %q", got, err, state)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"github.com/bougou/go-ipmi"
+)
+
+const bootdev = "bootdev"
+
+// bootDevices maps the devices of the bootdev command to the boot device
+// selectors of the system boot options.
+//
+//nolint:gochecknoglobals // the table of boot devices
+var bootDevices = map[string]ipmi.BootDeviceSelector{
+	"pxe":   ipmi.BootDeviceSelectorForcePXE,
+	"disk":  ipmi.BootDeviceSelectorForceHardDrive,
+	"bios":  ipmi.BootDeviceSelectorForceBIOSSetup,
+	"cdrom": ipmi.BootDeviceSelectorForceCDROM,
+}
+
+// bootdevConfig holds the arguments of the bootdev command.
+type bootdevConfig struct {
+	device     string
+	persistent bool
+	efi        bool
+}
+
+// parseBootdevArgs parses the arguments of the bootdev command: the device,
+// followed by the options persistent and efi in any order.
+func parseBootdevArgs(args []string) (bootdevConfig, error) {
+	if len(args) == 0 {
+		return bootdevConfig{}, errors.New("bootdev needs a device: pxe, disk, bios or cdrom")
+	}
+
+	cfg := bootdevConfig{device: strings.ToLower(args[0])}
+
+	if _, ok := bootDevices[cfg.device]; !ok {
+		return bootdevConfig{}, fmt.Errorf("unknown boot device %q, available devices: pxe, disk, bios, cdrom", args[0])
+	}
+
+	for _, opt := range args[1:] {
+		switch strings.ToLower(opt) {
+		case "persistent":
+			cfg.persistent = true
+		case "efi":
+			cfg.efi = true
+		default:
+			return bootdevConfig{}, fmt.Errorf("unknown bootdev option %q, available options: persistent, efi", opt)
+		}
+	}
+
+	return cfg, nil
+}
+
+// handleBootdevCommand sets the boot device for the next boot, or for all
+// future boots with the persistent option.
+func (i *IPMI) handleBootdevCommand(ctx context.Context, s module.Session, args []string) error {
+	cfg, err := parseBootdevArgs(args)
+	if err != nil {
+		return err
+	}
+
+	bootType := ipmi.BIOSBootTypeLegacy
+	if cfg.efi {
+		bootType = ipmi.BIOSBootTypeEFI
+	}
+
+	err = i.withReconnect(ctx, func() error {
+		return i.client.SetBootDevice(ctx, bootDevices[cfg.device], bootType, cfg.persistent)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set boot device: %v", err)
+	}
+
+	scope := "next boot"
+	if cfg.persistent {
+		scope = "all future boots"
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("boot device %s set for %s (BMC %s)", cfg.device, scope, i.Host))
+	s.Printf("Boot device set to %s for %s\n", cfg.device, scope)
+
+	return nil
+}
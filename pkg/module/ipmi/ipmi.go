@@ -7,8 +7,10 @@ package ipmi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/BlindspotSoftware/dutctl/internal/log"
@@ -26,7 +28,10 @@ func init() {
 }
 
 // IPMI is a module that provides basic power management functions for a DUT via IPMI.
-// It allows sending power on, off, cycle, reset, and status commands to the device's BMC.
+// It allows sending power on, off, cycle, reset, and status commands to the device's BMC,
+// and connects to the serial console of the device via Serial-over-LAN (SOL). Beyond
+// power, it sets the boot device, reads sensors and the System Event Log (SEL), and
+// passes raw IPMI commands on.
 type IPMI struct {
 	Host     string // Host is the hostname or IP address of the DUT's BMC
 	Port     int    // Port is the port of the IPMI interface on the BMC. Default: 623
@@ -37,24 +42,45 @@ type IPMI struct {
 	timeout   time.Duration // timeout is the resolved command timeout; set in Init
 	client    *ipmi.Client  // client is the current IPMI session; nil until the first command
 	connected bool          // connected tracks whether client holds a live session
+	solActive atomic.Bool   // solActive tracks whether the SOL payload is active on the session
 }
 
-// Ensure implementing the Module interface.
-var _ module.Module = &IPMI{}
+// Ensure implementing the Module and PowerController interfaces.
+var (
+	_ module.Module          = &IPMI{}
+	_ module.PowerController = &IPMI{}
+)
 
 func (i *IPMI) Help() string {
 	help := strings.Builder{}
 	help.WriteString("IPMI Power Management Module\n")
 	help.WriteString("\nUsage:\n")
-	help.WriteString("  ipmi [on|off|cycle|reset|status]\n\n")
+	help.WriteString("  ipmi [on|off|cycle|reset|status]\n")
+	help.WriteString("  ipmi sol [-i] [-t <duration>]\n")
+	help.WriteString("  ipmi bootdev <pxe|disk|bios|cdrom> [persistent] [efi]\n")
+	help.WriteString("  ipmi sensors\n")
+	help.WriteString("  ipmi sel <list|clear>\n")
+	help.WriteString("  ipmi raw <netfn> <cmd> [data...]\n\n")
 	help.WriteString("Commands:\n")
 	help.WriteString("  on      - Power on the device\n")
 	help.WriteString("  off     - Power off the device\n")
 	help.WriteString("  cycle   - Power cycle (off, then on)\n")
 	help.WriteString("  reset   - Reset the device (if supported)\n")
 	help.WriteString("  status  - Show current power status\n")
+	help.WriteString("  sol     - Show the serial console via Serial-over-LAN (IPMI v2.0)\n")
+	help.WriteString("  bootdev - Boot from the device on next boot; persistent: on all future boots\n")
+	help.WriteString("  sensors - Show the sensor readings with their thresholds\n")
+	help.WriteString("  sel     - List or clear the System Event Log\n")
+	help.WriteString("  raw     - Send a raw IPMI command, bytes in hex (e.g. raw 0x06 0x01)\n")
+	help.WriteString("\n")
+	help.WriteString("SOL flags:\n")
+	help.WriteString("  -i             Interactive: pass the client's console input on, too; ^T ends the session\n")
+	help.WriteString("  -t <duration>  End the session after the duration (e.g. 2m); default: until canceled\n")
 	help.WriteString("\n")
-	help.WriteString("This module provides basic power control functions via IPMI.\n")
+	help.WriteString("Threshold columns of sensors: LNR/LCR/LNC lower non-recoverable/critical/non-critical,\n")
+	help.WriteString("UNC/UCR/UNR the upper ones. The efi option of bootdev requests an EFI boot.\n")
+	help.WriteString("\n")
+	help.WriteString("This module provides power control, the SOL console and BMC diagnostics via IPMI.\n")
 	help.WriteString("\n")
 	help.WriteString("Commands are sent to BMC with hostname/ip: " + i.Host + "\n")
 
@@ -151,6 +177,7 @@ func (i *IPMI) disconnect(ctx context.Context) {
 
 	i.client = nil
 	i.connected = false
+	i.solActive.Store(false) // closing the session ends its payloads, too
 }
 
 // withReconnect runs op against a live IPMI session, transparently re-opening the
@@ -186,13 +213,13 @@ func (i *IPMI) withReconnect(ctx context.Context, op func() error) error {
 
 // Deinit closes the IPMI session if one is open, and is a no-op otherwise. The
 // session is opened lazily on the first command, so a module that never ran a
-// command has nothing to close.
+// command has nothing to close. An active SOL payload is deactivated first.
 func (i *IPMI) Deinit(ctx context.Context) error {
 	if i.client == nil || !i.connected {
 		return nil
 	}
 
-	err := i.client.Close(ctx)
+	err := errors.Join(i.deactivateSOL(ctx), i.client.Close(ctx))
 	i.client = nil
 	i.connected = false
 
@@ -202,9 +229,9 @@ func (i *IPMI) Deinit(ctx context.Context) error {
 }
 
 // Run executes a single IPMI command taken from the first argument: on, off,
-// cycle, reset or status. A missing or unknown command is reported to the
-// session and returns a nil error; a failure talking to the BMC returns an
-// error.
+// cycle, reset, status, sol, bootdev, sensors, sel or raw. A missing or unknown
+// command is reported to the session and returns a nil error; invalid arguments
+// to a command or a failure talking to the BMC return an error.
 func (i *IPMI) Run(ctx context.Context, s module.Session, args ...string) error {
 	if len(args) == 0 {
 		s.Println("No command specified. Try 'help' for usage.")
@@ -219,9 +246,19 @@ func (i *IPMI) Run(ctx context.Context, s module.Session, args ...string) error
 		return i.handlePowerCommand(ctx, s, command)
 	case status:
 		return i.handleStatusCommand(ctx, s)
+	case sol:
+		return i.handleSOLCommand(ctx, s, args[1:])
+	case bootdev:
+		return i.handleBootdevCommand(ctx, s, args[1:])
+	case sensors:
+		return i.handleSensorsCommand(ctx, s)
+	case selCmd:
+		return i.handleSELCommand(ctx, s, args[1:])
+	case raw:
+		return i.handleRawCommand(ctx, s, args[1:])
 	default:
 		s.Println("Unknown command: " + command)
-		s.Println("Available commands: on, off, cycle, reset, status")
+		s.Println("Available commands: on, off, cycle, reset, status, sol, bootdev, sensors, sel, raw")
 
 		return nil
 	}
@@ -248,6 +285,58 @@ func (i *IPMI) handlePowerCommand(ctx context.Context, s module.Session, command
 		message = "Power RESET command sent"
 	}
 
+	err := i.chassisControl(ctx, command, controlType)
+	if err != nil {
+		return err
+	}
+
+	s.Println(message)
+
+	return nil
+}
+
+func (i *IPMI) handleStatusCommand(ctx context.Context, s module.Session) error {
+	powerIsOn, err := i.chassisPowerIsOn(ctx)
+	if err != nil {
+		return err
+	}
+
+	powerStatus := "Off"
+	if powerIsOn {
+		powerStatus = "On"
+	}
+
+	s.Printf("Device power status: %s\n", powerStatus)
+
+	return nil
+}
+
+// PowerOn switches the chassis on.
+func (i *IPMI) PowerOn(ctx context.Context) error {
+	return i.chassisControl(ctx, on, ipmi.ChassisControlPowerUp)
+}
+
+// PowerOff switches the chassis off hard, like the off command.
+func (i *IPMI) PowerOff(ctx context.Context) error {
+	return i.chassisControl(ctx, off, ipmi.ChassisControlPowerDown)
+}
+
+// PowerState reports the chassis power state read from the BMC.
+func (i *IPMI) PowerState(ctx context.Context) (module.PowerState, error) {
+	powerIsOn, err := i.chassisPowerIsOn(ctx)
+	if err != nil {
+		return module.PowerUnknown, err
+	}
+
+	if powerIsOn {
+		return module.PowerOn, nil
+	}
+
+	return module.PowerOff, nil
+}
+
+// chassisControl sends the chassis control for command to the BMC.
+func (i *IPMI) chassisControl(ctx context.Context, command string, controlType ipmi.ChassisControl) error {
 	err := i.withReconnect(ctx, func() error {
 		_, cerr := i.client.ChassisControl(ctx, controlType)
 
@@ -258,12 +347,12 @@ func (i *IPMI) handlePowerCommand(ctx context.Context, s module.Session, command
 	}
 
 	log.FromContext(ctx).Info(fmt.Sprintf("chassis %s (BMC %s)", command, i.Host))
-	s.Println(message)
 
 	return nil
 }
 
-func (i *IPMI) handleStatusCommand(ctx context.Context, s module.Session) error {
+// chassisPowerIsOn reads the chassis power state from the BMC.
+func (i *IPMI) chassisPowerIsOn(ctx context.Context) (bool, error) {
 	var powerIsOn bool
 
 	err := i.withReconnect(ctx, func() error {
@@ -277,15 +366,8 @@ func (i *IPMI) handleStatusCommand(ctx context.Context, s module.Session) error
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get chassis status: %v", err)
-	}
-
-	powerStatus := "Off"
-	if powerIsOn {
-		powerStatus = "On"
+		return false, fmt.Errorf("failed to get chassis status: %v", err)
 	}
 
-	s.Printf("Device power status: %s\n", powerStatus)
-
-	return nil
+	return powerIsOn, nil
 }
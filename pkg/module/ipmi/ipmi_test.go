@@ -0,0 +1,130 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bougou/go-ipmi"
+)
+
+func TestParseBootdevArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    bootdevConfig
+		wantErr bool
+	}{
+		{"next boot", []string{"pxe"}, bootdevConfig{device: "pxe"}, false},
+		{"persistent", []string{"bios", "persistent"}, bootdevConfig{device: "bios", persistent: true}, false},
+		{"options in any order", []string{"DISK", "efi", "persistent"}, bootdevConfig{device: "disk", persistent: true, efi: true}, false},
+		{"no device", nil, bootdevConfig{}, true},
+		{"unknown device", []string{"floppy"}, bootdevConfig{}, true},
+		{"unknown option", []string{"cdrom", "once"}, bootdevConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBootdevArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBootdevArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseBootdevArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRawArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    rawRequest
+		wantErr bool
+	}{
+		{"without data", []string{"0x06", "0x01"}, rawRequest{netFn: ipmi.NetFnAppRequest, cmd: 0x01, data: []byte{}}, false},
+		{"with data", []string{"0x0a", "0x43", "00", "00", "FF"}, rawRequest{netFn: ipmi.NetFnStorageRequest, cmd: 0x43, data: []byte{0x00, 0x00, 0xff}}, false},
+		{"missing cmd", []string{"0x06"}, rawRequest{}, true},
+		{"not hex", []string{"0x06", "xyz"}, rawRequest{}, true},
+		{"more than a byte", []string{"0x06", "0x100"}, rawRequest{}, true},
+		{"netfn out of range", []string{"0x40", "0x01"}, rawRequest{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRawArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRawArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got.netFn != tt.want.netFn || got.cmd != tt.want.cmd || !bytes.Equal(got.data, tt.want.data) {
+				t.Errorf("parseRawArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRawResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "(no response data)"},
+		{"one line", []byte{0x20, 0x01, 0x0a}, "20 01 0a"},
+		{"wrapped", bytes.Repeat([]byte{0xff}, 17), strings.Repeat("ff ", 15) + "ff\nff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatRawResponse(tt.data)
+			if got != tt.want {
+				t.Errorf("formatRawResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSEL(t *testing.T) {
+	entries := []*ipmi.SEL{
+		{
+			RecordID:   1,
+			RecordType: 0x02, // system event
+			Standard: &ipmi.SELStandard{
+				Timestamp:    time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+				SensorType:   ipmi.SensorTypeTemperature,
+				SensorNumber: 0x30,
+				EventDir:     ipmi.EventDirAssertion,
+			},
+		},
+		{RecordID: 2, RecordType: 0xe0},
+	}
+
+	lines := strings.Split(strings.TrimSuffix(formatSEL(entries), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header and 2 entries:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	for _, field := range []string{"ID", "TIME", "SENSOR", "EVENT", "DIRECTION", "SEVERITY"} {
+		if !strings.Contains(lines[0], field) {
+			t.Errorf("header %q misses column %s", lines[0], field)
+		}
+	}
+
+	for _, field := range []string{"0x0001", "2025-03-01T12:00:00Z", "Temperature 0x30", "Assertion"} {
+		if !strings.Contains(lines[1], field) {
+			t.Errorf("standard entry %q misses %q", lines[1], field)
+		}
+	}
+
+	if !strings.HasPrefix(lines[2], "0x0002") {
+		t.Errorf("OEM entry %q does not start with its record ID", lines[2])
+	}
+}
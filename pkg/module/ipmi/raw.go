@@ -0,0 +1,108 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"github.com/bougou/go-ipmi"
+)
+
+const raw = "raw"
+
+// rawRequest is a raw IPMI request given as arguments of the raw command.
+type rawRequest struct {
+	netFn ipmi.NetFn
+	cmd   uint8
+	data  []byte
+}
+
+// parseRawArgs parses the arguments of the raw command: the network function,
+// the command and the request data, each a byte like 0x06 or 06.
+func parseRawArgs(args []string) (rawRequest, error) {
+	const minArgs = 2 // netfn and cmd
+
+	if len(args) < minArgs {
+		return rawRequest{}, errors.New("usage: raw <netfn> <cmd> [data...]")
+	}
+
+	bytes := make([]byte, len(args))
+
+	for i, arg := range args {
+		b, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(arg), "0x"), 16, 8)
+		if err != nil {
+			return rawRequest{}, fmt.Errorf("invalid byte %q: must be hex, e.g. 0x06", arg)
+		}
+
+		bytes[i] = byte(b)
+	}
+
+	const maxNetFn = 0x3f // the network function has 6 bits
+
+	if bytes[0] > maxNetFn {
+		return rawRequest{}, fmt.Errorf("invalid netfn %#02x: must be at most %#02x", bytes[0], maxNetFn)
+	}
+
+	return rawRequest{netFn: ipmi.NetFn(bytes[0]), cmd: bytes[1], data: bytes[minArgs:]}, nil
+}
+
+// handleRawCommand sends a raw request to the BMC and shows the response data.
+// A completion code other than success is returned as an error.
+func (i *IPMI) handleRawCommand(ctx context.Context, s module.Session, args []string) error {
+	req, err := parseRawArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var res *ipmi.CommandRawResponse
+
+	err = i.withReconnect(ctx, func() error {
+		var rerr error
+
+		res, rerr = i.client.RawCommand(ctx, req.netFn, req.cmd, req.data, "raw")
+
+		return rerr
+	})
+	if err != nil {
+		return fmt.Errorf("raw command failed: %v", err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("raw command netfn %#02x cmd %#02x sent (BMC %s)", uint8(req.netFn), req.cmd, i.Host))
+	s.Println(formatRawResponse(res.Response))
+
+	return nil
+}
+
+// formatRawResponse renders the response data as hex bytes, 16 per line like
+// ipmitool does.
+func formatRawResponse(data []byte) string {
+	const perLine = 16
+
+	if len(data) == 0 {
+		return "(no response data)"
+	}
+
+	out := strings.Builder{}
+
+	for i, b := range data {
+		switch {
+		case i == 0:
+		case i%perLine == 0:
+			out.WriteString("\n")
+		default:
+			out.WriteString(" ")
+		}
+
+		fmt.Fprintf(&out, "%02x", b)
+	}
+
+	return out.String()
+}
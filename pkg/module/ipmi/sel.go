@@ -0,0 +1,124 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"github.com/bougou/go-ipmi"
+)
+
+const selCmd = "sel"
+
+// handleSELCommand lists or clears the System Event Log.
+func (i *IPMI) handleSELCommand(ctx context.Context, s module.Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: sel list|clear")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		return i.listSEL(ctx, s)
+	case "clear":
+		return i.clearSEL(ctx, s)
+	default:
+		return fmt.Errorf("unknown sel command %q, available commands: list, clear", args[0])
+	}
+}
+
+// listSEL shows the entries of the SEL, oldest first.
+func (i *IPMI) listSEL(ctx context.Context, s module.Session) error {
+	var entries []*ipmi.SEL
+
+	err := i.withReconnect(ctx, func() error {
+		info, serr := i.client.GetSELInfo(ctx)
+		if serr != nil {
+			return serr
+		}
+
+		// Reading the first entry of an empty SEL fails.
+		if info.Entries == 0 {
+			entries = nil
+
+			return nil
+		}
+
+		entries, serr = i.client.GetSELEntries(ctx, 0)
+
+		return serr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the SEL: %v", err)
+	}
+
+	if len(entries) == 0 {
+		s.Println("SEL is empty")
+
+		return nil
+	}
+
+	s.Print(formatSEL(entries))
+
+	return nil
+}
+
+// clearSEL erases all entries of the SEL.
+func (i *IPMI) clearSEL(ctx context.Context, s module.Session) error {
+	err := i.withReconnect(ctx, func() error {
+		reservation, serr := i.client.ReserveSEL(ctx)
+		if serr != nil {
+			return serr
+		}
+
+		_, serr = i.client.ClearSEL(ctx, reservation.ReservationID)
+
+		return serr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear the SEL: %v", err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("SEL cleared (BMC %s)", i.Host))
+	s.Println("SEL cleared")
+
+	return nil
+}
+
+// formatSEL renders the SEL entries as a table. OEM entries have no standard
+// format and show their record type only.
+func formatSEL(entries []*ipmi.SEL) string {
+	out := strings.Builder{}
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0) //nolint:mnd // padding between columns
+
+	fmt.Fprintln(w, "ID\tTIME\tSENSOR\tEVENT\tDIRECTION\tSEVERITY")
+
+	for _, entry := range entries {
+		ev := entry.Standard
+		if ev == nil {
+			fmt.Fprintf(w, "%#04x\t-\t-\t%s\t-\t-\n", entry.RecordID, entry.RecordType)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "%#04x\t%s\t%s %#02x\t%s\t%s\t%s\n",
+			entry.RecordID,
+			ev.Timestamp.UTC().Format(time.RFC3339),
+			ev.SensorType, uint8(ev.SensorNumber),
+			ev.EventString(),
+			ev.EventDir,
+			ev.EventSeverity())
+	}
+
+	_ = w.Flush()
+
+	return out.String()
+}
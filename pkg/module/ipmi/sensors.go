@@ -0,0 +1,79 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"github.com/bougou/go-ipmi"
+)
+
+const sensors = "sensors"
+
+// sensorThresholds are the thresholds shown for a sensor, from the lowest to the highest.
+//
+//nolint:gochecknoglobals // the columns of the sensor table
+var sensorThresholds = []ipmi.SensorThresholdType{
+	ipmi.SensorThresholdType_LNR,
+	ipmi.SensorThresholdType_LCR,
+	ipmi.SensorThresholdType_LNC,
+	ipmi.SensorThresholdType_UNC,
+	ipmi.SensorThresholdType_UCR,
+	ipmi.SensorThresholdType_UNR,
+}
+
+// handleSensorsCommand shows the readings of the sensors listed in the SDR
+// repository, with their thresholds.
+func (i *IPMI) handleSensorsCommand(ctx context.Context, s module.Session) error {
+	var list []*ipmi.Sensor
+
+	err := i.withReconnect(ctx, func() error {
+		var serr error
+
+		list, serr = i.client.GetSensors(ctx)
+
+		return serr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read sensors: %v", err)
+	}
+
+	s.Print(formatSensors(list))
+
+	return nil
+}
+
+// formatSensors renders the sensors as a table. A threshold the sensor does not
+// have, and the reading of a sensor without one, read N/A. Discrete sensors have
+// no thresholds and show their raw reading.
+func formatSensors(list []*ipmi.Sensor) string {
+	out := strings.Builder{}
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0) //nolint:mnd // padding between columns
+
+	fmt.Fprintln(w, "SENSOR\tREADING\tUNIT\tSTATUS\tLNR\tLCR\tLNC\tUNC\tUCR\tUNR")
+
+	for _, sensor := range list {
+		unit := "discrete"
+		if sensor.IsThreshold() {
+			unit = sensor.SensorUnit.String()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s", sensor.Name, sensor.ReadingStr(), unit, sensor.Status())
+
+		for _, threshold := range sensorThresholds {
+			fmt.Fprintf(w, "\t%s", sensor.ThresholdStr(threshold))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	_ = w.Flush()
+
+	return out.String()
+}
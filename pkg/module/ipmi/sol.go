@@ -0,0 +1,358 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"github.com/bougou/go-ipmi"
+)
+
+const (
+	sol = "sol"
+
+	solInstance     = 1                      // SOL payload instance, the first serial port of the BMC
+	solPollInterval = 100 * time.Millisecond // how often the BMC is polled for console output
+	solMaxSeq       = 0x0f                   // SOL sequence numbers run from 1 to 15; 0 marks an ACK-only packet
+	solMaxChars     = 255                    // most console input sent in one packet
+	solEscape       = 0x14                   // ^T, the key ending an interactive session
+	solDeactivating = 0x10                   // status bit of a BMC packet: the BMC deactivates SOL
+
+	ctrlC         = 0x03 // written to the console for an interrupt from the client
+	ctrlBackslash = 0x1c // written to the console for a quit from the client
+)
+
+// errSOLDeactivated is returned when the BMC ends the SOL session, e.g. because
+// another session took the console over.
+var errSOLDeactivated = errors.New("SOL deactivated by the BMC")
+
+// solConfig holds the arguments of the sol command.
+type solConfig struct {
+	interactive bool
+	timeout     time.Duration
+}
+
+// parseSOLArgs parses the arguments of the sol command.
+func parseSOLArgs(args []string) (solConfig, error) {
+	fs := flag.NewFlagSet(sol, flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output.
+
+	var cfg solConfig
+
+	fs.BoolVar(&cfg.interactive, "i", false, "connect the SOL console to the client's console")
+	fs.DurationVar(&cfg.timeout, "t", 0, "end the session after this duration; 0 = until canceled")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return solConfig{}, fmt.Errorf("failed to parse sol arguments: %w", err)
+	}
+
+	if fs.NArg() > 0 {
+		return solConfig{}, fmt.Errorf("unexpected sol arguments: %v", fs.Args())
+	}
+
+	if cfg.timeout < 0 {
+		return solConfig{}, fmt.Errorf("invalid timeout %s: must not be negative", cfg.timeout)
+	}
+
+	return cfg, nil
+}
+
+// handleSOLCommand activates the SOL payload and streams the console output to
+// the client until the timeout elapses or the command is canceled. In
+// interactive mode the client's console input is passed on, too, and the
+// session also ends with ^T. The payload is deactivated in any case, so the
+// next session is not locked out of the console.
+func (i *IPMI) handleSOLCommand(ctx context.Context, s module.Session, args []string) error {
+	cfg, err := parseSOLArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	link, err := i.activateSOL(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		derr := i.deactivateSOL(ctx)
+		if derr != nil {
+			log.FromContext(ctx).Warn(derr.Error())
+		}
+	}()
+
+	var (
+		out   io.Writer = printWriter{s}
+		con   solConsole
+		ended = "--- SOL session ended ---\n"
+	)
+
+	if cfg.interactive {
+		stdin, stdout, _ := s.Console()
+		out = stdout
+		con = solConsole{input: readInput(ctx, stdin), signals: s.Signals(), escape: solEscape}
+		ended = "\r\n" + ended
+
+		fmt.Fprintf(out, "--- SOL console of %s, press ^T to end it ---\r\n", i.Host)
+	} else {
+		fmt.Fprintf(out, "--- SOL console of %s ---\n", i.Host)
+	}
+
+	err = link.run(ctx, out, con)
+	if err != nil {
+		return fmt.Errorf("SOL session: %w", err)
+	}
+
+	fmt.Fprint(out, ended)
+
+	return nil
+}
+
+// activateSOL activates the SOL payload on the IPMI session.
+func (i *IPMI) activateSOL(ctx context.Context) (*solLink, error) {
+	var res *ipmi.ActivatePayloadResponse
+
+	err := i.withReconnect(ctx, func() error {
+		var aerr error
+
+		res, aerr = i.client.ActivatePayload(ctx, &ipmi.ActivatePayloadRequest{
+			PayloadType:     ipmi.PayloadTypeSOL,
+			PayloadInstance: solInstance,
+		})
+
+		return aerr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate SOL (a stale SOL session on the BMC may hold the console): %v", err)
+	}
+
+	i.solActive.Store(true)
+
+	log.FromContext(ctx).Info(fmt.Sprintf("SOL activated (BMC %s)", i.Host))
+
+	client := i.client
+	maxChars := solMaxChars
+
+	// The payload size includes the 4 byte packet header.
+	if size := int(res.InboundPayloadSize) - 4; size > 0 && size < maxChars {
+		maxChars = size
+	}
+
+	return &solLink{send: client.SOLPayload, maxChars: maxChars, seq: 1}, nil
+}
+
+// deactivateSOL deactivates the SOL payload if it is active. It also runs when
+// ctx is done already, as a payload left active locks other sessions out of the
+// console until the BMC times it out.
+func (i *IPMI) deactivateSOL(ctx context.Context) error {
+	if !i.solActive.Swap(false) || i.client == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), i.timeout)
+	defer cancel()
+
+	_, err := i.client.DeactivatePayload(ctx, &ipmi.DeactivatePayloadRequest{
+		PayloadType:     ipmi.PayloadTypeSOL,
+		PayloadInstance: solInstance,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deactivate SOL: %v", err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("SOL deactivated (BMC %s)", i.Host))
+
+	return nil
+}
+
+// solLink is an active SOL payload. It exchanges SOL packets with the BMC,
+// keeping track of the sequence numbers of both sides.
+type solLink struct {
+	send     func(context.Context, *ipmi.SOLPayloadRequest) (*ipmi.SOLPayloadResponse, error)
+	maxChars int // most console input sent in one packet
+
+	pending   []byte // console input not yet accepted by the BMC
+	seq       uint8  // sequence number of the next packet
+	remoteSeq uint8  // sequence number of the last packet received, acknowledged with the next one
+	accepted  uint8  // characters of that packet
+}
+
+// solConsole is the client's side of an interactive SOL session. The zero
+// value is the side of a session capturing the output only.
+type solConsole struct {
+	input   <-chan []byte
+	signals <-chan module.Signal
+	escape  byte // the key ending the session; 0 disables it
+}
+
+// run polls the BMC for console output and writes it to out until ctx is done
+// or the console's input contains the escape key, both returning nil. The
+// console's input and signals are sent to the BMC as they arrive.
+func (l *solLink) run(ctx context.Context, out io.Writer, con solConsole) error {
+	ticker := time.NewTicker(solPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var (
+			input   []byte
+			escaped bool
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case chunk := <-con.input:
+			input, escaped = chunk, false
+			if con.escape != 0 {
+				if i := bytes.IndexByte(chunk, con.escape); i >= 0 {
+					input, escaped = chunk[:i], true
+				}
+			}
+		case sig := <-con.signals:
+			key, ok := signalKey(sig)
+			if !ok {
+				continue
+			}
+
+			input = []byte{key}
+		case <-ticker.C:
+		}
+
+		output, err := l.exchange(ctx, input)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(output) > 0 {
+			_, err = out.Write(output)
+			if err != nil {
+				return err
+			}
+		}
+
+		if escaped {
+			return nil
+		}
+	}
+}
+
+// exchange sends a packet with the pending console input and returns the
+// console output the BMC replied with. Without pending input, the packet polls
+// the BMC for output. Input the BMC does not accept stays pending for the next
+// packet; output the BMC sent again, because the acknowledgement got lost, is
+// dropped.
+func (l *solLink) exchange(ctx context.Context, input []byte) ([]byte, error) {
+	l.pending = append(l.pending, input...)
+	chars := l.pending[:min(len(l.pending), l.maxChars)]
+
+	res, err := l.send(ctx, &ipmi.SOLPayloadRequest{SOLPayloadPacket: ipmi.SOLPayloadPacket{
+		SequenceNumber:         l.seq,
+		AckedSequenceNumber:    l.remoteSeq,
+		AcceptedCharacterCount: l.accepted,
+		CharacterData:          chars,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	l.seq = l.seq%solMaxSeq + 1
+
+	if res.ControlByte&solDeactivating != 0 {
+		return nil, errSOLDeactivated
+	}
+
+	if !res.NACK {
+		l.pending = l.pending[len(chars):]
+	}
+
+	// A packet with sequence number 0 only acknowledges ours.
+	if res.SequenceNumber == 0 {
+		return nil, nil
+	}
+
+	output := res.CharacterData
+	if res.SequenceNumber == l.remoteSeq {
+		output = nil
+	}
+
+	l.remoteSeq = res.SequenceNumber
+	l.accepted = uint8(min(len(res.CharacterData), solMaxChars)) //nolint:gosec // bounded by solMaxChars
+
+	return output, nil
+}
+
+// readInput reads the console's input on its own goroutine, as a read from the
+// console cannot be interrupted. The goroutine exits once ctx is done and the
+// pending read returned.
+func readInput(ctx context.Context, r io.Reader) <-chan []byte {
+	const readChunk = 256
+
+	input := make(chan []byte)
+
+	go func() {
+		for {
+			buf := make([]byte, readChunk)
+
+			n, err := r.Read(buf)
+			if n > 0 {
+				select {
+				case input <- buf[:n]:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return input
+}
+
+// signalKey returns the control character a signal from the client is written
+// to the console as. It reports false for a signal without one.
+func signalKey(sig module.Signal) (byte, bool) {
+	switch sig {
+	case module.Interrupt:
+		return ctrlC, true
+	case module.Quit:
+		return ctrlBackslash, true
+	default:
+		return 0, false
+	}
+}
+
+// printWriter writes the console output captured in non-interactive mode to
+// the client as messages.
+type printWriter struct {
+	s module.Session
+}
+
+func (w printWriter) Write(p []byte) (int, error) {
+	w.s.Print(string(p))
+
+	return len(p), nil
+}
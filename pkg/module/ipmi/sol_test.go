@@ -0,0 +1,194 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipmi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"github.com/bougou/go-ipmi"
+)
+
+// fakeBMC answers SOL packets with the queued replies and records the packets
+// it receives. Once the replies are used up, it acknowledges without output.
+type fakeBMC struct {
+	replies []ipmi.SOLPayloadPacket
+	packets []ipmi.SOLPayloadPacket
+}
+
+func (b *fakeBMC) send(_ context.Context, req *ipmi.SOLPayloadRequest) (*ipmi.SOLPayloadResponse, error) {
+	pkt := req.SOLPayloadPacket
+	pkt.CharacterData = bytes.Clone(pkt.CharacterData)
+	b.packets = append(b.packets, pkt)
+
+	if len(b.replies) == 0 {
+		return &ipmi.SOLPayloadResponse{}, nil
+	}
+
+	res := &ipmi.SOLPayloadResponse{SOLPayloadPacket: b.replies[0]}
+	b.replies = b.replies[1:]
+
+	return res, nil
+}
+
+func TestParseSOLArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    solConfig
+		wantErr bool
+	}{
+		{"capture", nil, solConfig{}, false},
+		{"capture with timeout", []string{"-t", "30s"}, solConfig{timeout: 30 * time.Second}, false},
+		{"interactive", []string{"-i"}, solConfig{interactive: true}, false},
+		{"negative timeout", []string{"-t", "-1s"}, solConfig{}, true},
+		{"unknown flag", []string{"-x"}, solConfig{}, true},
+		{"extra argument", []string{"-i", "now"}, solConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSOLArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSOLArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseSOLArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSOLExchange(t *testing.T) {
+	bmc := &fakeBMC{replies: []ipmi.SOLPayloadPacket{
+		{SequenceNumber: 1, CharacterData: []byte("login: ")},
+		{SequenceNumber: 1, CharacterData: []byte("login: ")}, // sent again, the ack got lost
+		{NACK: true},
+		{SequenceNumber: 2, AckedSequenceNumber: 2, CharacterData: []byte("root\r\n")},
+	}}
+	link := &solLink{send: bmc.send, maxChars: 3, seq: solMaxSeq}
+	ctx := context.Background()
+
+	var out []byte
+
+	for _, input := range []string{"", "", "root", ""} {
+		output, err := link.exchange(ctx, []byte(input))
+		if err != nil {
+			t.Fatalf("exchange(%q): %v", input, err)
+		}
+
+		out = append(out, output...)
+	}
+
+	if string(out) != "login: root\r\n" {
+		t.Errorf("output = %q, want %q", out, "login: root\r\n")
+	}
+
+	want := []ipmi.SOLPayloadPacket{
+		{SequenceNumber: 15},
+		{SequenceNumber: 1, AckedSequenceNumber: 1, AcceptedCharacterCount: 7},
+		{SequenceNumber: 2, AckedSequenceNumber: 1, AcceptedCharacterCount: 7, CharacterData: []byte("roo")},
+		// The rejected input is sent again, limited to maxChars.
+		{SequenceNumber: 3, AckedSequenceNumber: 1, AcceptedCharacterCount: 7, CharacterData: []byte("roo")},
+	}
+
+	if len(bmc.packets) != len(want) {
+		t.Fatalf("sent %d packets, want %d", len(bmc.packets), len(want))
+	}
+
+	for i := range want {
+		got := bmc.packets[i]
+		if got.SequenceNumber != want[i].SequenceNumber || got.AckedSequenceNumber != want[i].AckedSequenceNumber ||
+			got.AcceptedCharacterCount != want[i].AcceptedCharacterCount || !bytes.Equal(got.CharacterData, want[i].CharacterData) {
+			t.Errorf("packet %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	if string(link.pending) != "t" {
+		t.Errorf("pending = %q, want %q", link.pending, "t")
+	}
+}
+
+func TestSOLExchangeDeactivated(t *testing.T) {
+	bmc := &fakeBMC{replies: []ipmi.SOLPayloadPacket{{ControlByte: solDeactivating}}}
+	link := &solLink{send: bmc.send, maxChars: solMaxChars, seq: 1}
+
+	_, err := link.exchange(context.Background(), nil)
+	if !errors.Is(err, errSOLDeactivated) {
+		t.Errorf("err = %v, want errSOLDeactivated", err)
+	}
+}
+
+func TestSOLRunInteractive(t *testing.T) {
+	bmc := &fakeBMC{replies: []ipmi.SOLPayloadPacket{{SequenceNumber: 1, CharacterData: []byte("# ")}}}
+	link := &solLink{send: bmc.send, maxChars: solMaxChars, seq: 1}
+
+	input := make(chan []byte, 1)
+	signals := make(chan module.Signal, 1)
+	out := &bytes.Buffer{}
+
+	signals <- module.Interrupt
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- link.run(context.Background(), out, solConsole{input: input, signals: signals, escape: solEscape})
+	}()
+
+	// Wait for the signal to be sent before ending the session.
+	for len(signals) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	input <- []byte("ls\r\x14ignored")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not end on the escape key")
+	}
+
+	if out.String() != "# " {
+		t.Errorf("output = %q, want %q", out.String(), "# ")
+	}
+
+	var sent []byte
+	for _, pkt := range bmc.packets {
+		sent = append(sent, pkt.CharacterData...)
+	}
+
+	if string(sent) != "\x03ls\r" {
+		t.Errorf("sent %q, want %q", sent, "\x03ls\r")
+	}
+}
+
+func TestSOLRunCapture(t *testing.T) {
+	bmc := &fakeBMC{replies: []ipmi.SOLPayloadPacket{
+		{SequenceNumber: 1, CharacterData: []byte("Booting")},
+		{SequenceNumber: 2, CharacterData: []byte("...")},
+	}}
+	link := &solLink{send: bmc.send, maxChars: solMaxChars, seq: 1}
+	out := &bytes.Buffer{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*solPollInterval)
+	defer cancel()
+
+	err := link.run(ctx, out, solConsole{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if out.String() != "Booting..." {
+		t.Errorf("output = %q, want %q", out.String(), "Booting...")
+	}
+}
@@ -87,7 +87,9 @@ type Module interface {
 // or the transfer stream failed.
 //
 // Console, Print, RequestFile and SendFile must be called only from the module's
-// Run goroutine.
+// Run goroutine. The exception is SendFile, which may be called from a goroutine
+// Run waits for, so a module can stream a file it produces through an [io.Pipe]
+// while it keeps printing. Signals and WindowSizes may be received from any goroutine.
 type Session interface {
 	// Print sends a message to the client. Implementations should wrap [fmt.Sprint].
 	// The message is displayed in the console or GUI of the client.
@@ -100,13 +102,62 @@ type Session interface {
 	Println(a ...any)
 	// Console returns the stdin, stdout and stderr streams for the module.
 	// It thus indicates to the client that the module may want to interact with the user
-	// via standard input and output streams.
+	// via standard input and output streams. A client with a terminal switches it to raw
+	// mode then: stdin carries every keystroke as typed, without local echo, and control
+	// keys like Ctrl-C arrive as bytes rather than signals.
 	Console() (stdin io.Reader, stdout, stderr io.Writer)
 	// RequestFile requests a file from the client.
 	// The file is identified by its name and is made available to the module via the returned io.Reader.
+	// The file is streamed: read it as it arrives rather than loading it into memory as a whole. If
+	// the client announced the size of the file, the reader tells it with a Size() int64 method.
 	RequestFile(name string) (io.Reader, error)
 	// SendFile sends a file to the client.
 	SendFile(name string, r io.Reader) error
+	// Signals returns the channel on which signals from the client are delivered, e.g.
+	// an interrupt the user typed at the client's terminal. A module that wants to pass
+	// control keys on to the DUT or to interrupt a step receives from it; other modules
+	// ignore it. The channel is shared by all modules of a command and is never closed.
+	// A signal is dropped if the module does not keep up with receiving.
+	Signals() <-chan Signal
+	// WindowSizes returns the channel on which the size of the client's terminal is
+	// delivered, once the console was opened and whenever the terminal is resized. A
+	// module that runs a full-screen program passes it on to the DUT, e.g. to a pty;
+	// other modules ignore it. Only the latest size is kept until the module receives
+	// it, and nothing is delivered if the client has no terminal. The channel is shared
+	// by all modules of a command and is never closed.
+	WindowSizes() <-chan WindowSize
+}
+
+// WindowSize is the size of the client's terminal in character cells.
+type WindowSize struct {
+	Rows uint16
+	Cols uint16
+}
+
+// Signal is a signal the client passes on to the running module.
+type Signal int
+
+const (
+	// Interrupt asks the module to interrupt what it is doing (Ctrl-C).
+	Interrupt Signal = iota + 1
+	// Quit asks the module to quit what it is doing (Ctrl-\).
+	Quit
+	// EOF marks the end of the client's input (Ctrl-D).
+	EOF
+)
+
+// String renders a Signal for logs and diagnostics.
+func (s Signal) String() string {
+	switch s {
+	case Interrupt:
+		return "interrupt"
+	case Quit:
+		return "quit"
+	case EOF:
+		return "eof"
+	default:
+		return "unknown"
+	}
 }
 
 // Record holds the information required to register a module.
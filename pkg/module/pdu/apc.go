@@ -0,0 +1,117 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// vendorAPC selects the APC backend.
+const vendorAPC = "apc"
+
+func init() {
+	registerBackend(backendRecord{
+		vendor: vendorAPC,
+		new: func(cfg backendConfig) (backend, error) {
+			client, err := newSNMPClient(cfg.host, cfg.community)
+			if err != nil {
+				return nil, err
+			}
+
+			return apc{snmp: client}, nil
+		},
+	})
+}
+
+// apcOutletCtl is the sPDUOutletCtl object of the APC PowerNet MIB, indexed by
+// the 1-based outlet number. It reports the outlet state and switches the outlet
+// when written, on both the older AP79xx and the current rack PDUs.
+const apcOutletCtl = "1.3.6.1.4.1.318.1.1.4.4.2.1.3"
+
+// apc drives APC switched rack PDUs via SNMPv1.
+type apc struct {
+	snmp *snmpClient
+}
+
+// setPower implements the backend interface for APC PDUs. The outlet control
+// has no toggle, so toggle is emulated by reading the current state and
+// switching to its opposite, like for Gude.
+func (a apc) setPower(ctx context.Context, outlet int, act action) (state, error) {
+	var target state
+
+	switch act {
+	case turnOn:
+		target = on
+	case turnOff:
+		target = off
+	case toggle:
+		current, err := a.outletState(ctx, outlet)
+		if err != nil {
+			return off, fmt.Errorf("could not read outlet %d state to toggle it: %w", outlet, err)
+		}
+
+		target = on
+		if current == on {
+			target = off
+		}
+	default:
+		return off, fmt.Errorf("invalid PDU operation: %s", act)
+	}
+
+	value, err := a.snmp.set(ctx, apcOutletOID(outlet), apcControlFor(target))
+	if err != nil {
+		return off, fmt.Errorf("failed to switch outlet %d: %w", outlet, err)
+	}
+
+	// The agent echoes the written command, which is the resulting state.
+	return apcStateFrom(value)
+}
+
+// outletState implements the backend interface for APC PDUs.
+func (a apc) outletState(ctx context.Context, outlet int) (state, error) {
+	value, err := a.snmp.get(ctx, apcOutletOID(outlet))
+	if err != nil {
+		return off, fmt.Errorf("failed to read outlet %d state: %w", outlet, err)
+	}
+
+	return apcStateFrom(value)
+}
+
+// apcOutletOID returns the sPDUOutletCtl object of the outlet. Outlet is a
+// 0-based index (0 = first outlet); APC numbers outlets from 1.
+func apcOutletOID(outlet int) string {
+	return apcOutletCtl + "." + strconv.Itoa(outlet+1)
+}
+
+// The sPDUOutletCtl values used by this module. Read, the object reports
+// outletOn (1) or outletOff (2), or another value while a delayed command is
+// pending; written, 1 is immediateOn and 2 immediateOff.
+const (
+	apcOutletOn  = 1
+	apcOutletOff = 2
+)
+
+// apcControlFor returns the sPDUOutletCtl command switching to the state.
+func apcControlFor(target state) int {
+	if target == on {
+		return apcOutletOn
+	}
+
+	return apcOutletOff
+}
+
+// apcStateFrom maps an sPDUOutletCtl value to a state.
+func apcStateFrom(value int) (state, error) {
+	switch value {
+	case apcOutletOn:
+		return on, nil
+	case apcOutletOff:
+		return off, nil
+	default:
+		return off, fmt.Errorf("unexpected APC outlet state %d (the outlet may be switching)", value)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"testing"
+)
+
+// newTestAPC builds an apc backend pointed at agent, so the tests exercise the
+// real SNMP request path.
+func newTestAPC(t *testing.T, agent *fakeAgent) apc {
+	t.Helper()
+
+	client, err := newSNMPClient(agent.addr(), agent.community)
+	if err != nil {
+		t.Fatalf("newSNMPClient(%q): %v", agent.addr(), err)
+	}
+
+	return apc{snmp: client}
+}
+
+func TestAPCStateFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   int
+		want    state
+		wantErr bool
+	}{
+		{name: "outletOn", value: 1, want: on},
+		{name: "outletOff", value: 2, want: off},
+		{name: "outletReboot is transitional", value: 3, wantErr: true},
+		{name: "outletUnknown", value: 4, wantErr: true},
+		{name: "invalid", value: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apcStateFrom(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apcStateFrom(%d) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("apcStateFrom(%d) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAPCOutletState pins APC's status contract: the state is read from
+// sPDUOutletCtl indexed by the 1-based outlet number.
+func TestAPCOutletState(t *testing.T) {
+	agent := newFakeAgent(t, "private", map[string]int{
+		apcOutletCtl + ".1": apcOutletOff,
+		apcOutletCtl + ".2": apcOutletOn,
+	})
+
+	got, err := newTestAPC(t, agent).outletState(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("outletState() unexpected error: %v", err)
+	}
+
+	if got != on {
+		t.Errorf("outletState() = %v, want on", got)
+	}
+
+	if _, err := newTestAPC(t, agent).outletState(context.Background(), 5); err == nil {
+		t.Error("outletState() expected error for an outlet the PDU does not have")
+	}
+}
+
+// TestAPCSetPowerRequests pins APC's switch contract: a set of sPDUOutletCtl
+// at the 1-based outlet number to immediateOn (1) or immediateOff (2). The
+// control has no toggle, so a toggle first reads the current state.
+func TestAPCSetPowerRequests(t *testing.T) {
+	const outlet = 2 // APC numbers outlets from 1, so requests must address .3.
+
+	tests := []struct {
+		name      string
+		act       action
+		current   int // sPDUOutletCtl value before the switch
+		wantSet   int
+		wantState state
+	}{
+		{name: "on", act: turnOn, current: apcOutletOff, wantSet: apcOutletOn, wantState: on},
+		{name: "off", act: turnOff, current: apcOutletOn, wantSet: apcOutletOff, wantState: off},
+		{name: "toggle from on switches off", act: toggle, current: apcOutletOn, wantSet: apcOutletOff, wantState: off},
+		{name: "toggle from off switches on", act: toggle, current: apcOutletOff, wantSet: apcOutletOn, wantState: on},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := newFakeAgent(t, "private", map[string]int{apcOutletCtl + ".3": tt.current})
+
+			got, err := newTestAPC(t, agent).setPower(context.Background(), outlet, tt.act)
+			if err != nil {
+				t.Fatalf("setPower() unexpected error: %v", err)
+			}
+
+			if got != tt.wantState {
+				t.Errorf("setPower() = %v, want %v", got, tt.wantState)
+			}
+
+			sets := agent.setRequests()
+			if len(sets) != 1 {
+				t.Fatalf("agent received %d set requests, want 1", len(sets))
+			}
+
+			if sets[0].oid != apcOutletCtl+".3" {
+				t.Errorf("set OID = %s, want %s.3 (1-based index of outlet %d)", sets[0].oid, apcOutletCtl, outlet)
+			}
+
+			if sets[0].value != tt.wantSet {
+				t.Errorf("set value = %d, want %d", sets[0].value, tt.wantSet)
+			}
+		})
+	}
+}
@@ -10,9 +10,27 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"slices"
 	"strconv"
 )
 
+// vendorGude selects the Gude backend.
+const vendorGude = "gude"
+
+func init() {
+	registerBackend(backendRecord{
+		vendor: vendorGude,
+		new: func(cfg backendConfig) (backend, error) {
+			base, err := httpBase(cfg.host)
+			if err != nil {
+				return nil, err
+			}
+
+			return gude{req: cfg.req, base: base}, nil
+		},
+	})
+}
+
 // gudeCmdSwitch is the Gude "cmd" query value that switches an outlet on or off.
 const gudeCmdSwitch = "1"
 
@@ -22,6 +40,9 @@ type gude struct {
 	base *url.URL
 }
 
+// Ensure implementing the optional meter interface.
+var _ meter = gude{}
+
 // setPower implements the backend interface for Gude PDUs. Gude has no native
 // toggle, so toggle is emulated by reading the current state and switching to
 // its opposite via the ov.html endpoint.
@@ -81,7 +102,7 @@ func (g gude) outletState(ctx context.Context, outlet int) (state, error) {
 // own encoding, which setPower needs to compute a toggle.
 func (g gude) readState(ctx context.Context, outlet int) (gudeState, error) {
 	endpoint := g.base.JoinPath("statusjsn.js")
-	endpoint.RawQuery = "components=1"
+	endpoint.RawQuery = "components=" + strconv.Itoa(gudeComponentOutputs)
 
 	resp, err := g.req.get(ctx, endpoint.String())
 	if err != nil {
@@ -117,6 +138,110 @@ func (g gude) parseOutlet(body []byte, outlet int) (gudeState, error) {
 	return gudeStateFromInt(response.Outputs[outlet].State)
 }
 
+// Gude status components, selected by the bits of the "components" query
+// value of statusjsn.js.
+const (
+	gudeComponentOutputs      = 0x1     // the outputs and their states
+	gudeComponentSensorValues = 0x4000  // the current values of all sensors
+	gudeComponentSensorDescr  = 0x10000 // the description of the sensors: their fields and units
+)
+
+// gudeSensorOutletMeter is the sensor type of the per-outlet energy meters of
+// Gude PDUs with outlet metering. Each meter has one property per outlet.
+const gudeSensorOutletMeter = 9
+
+// measure implements the meter interface for Gude PDUs with outlet metering,
+// reading the outlet's energy meter from the statusjsn.js endpoint. The values
+// are looked up by the field names of the sensor description, as the fields
+// differ between models.
+func (g gude) measure(ctx context.Context, outlet int) (measurement, error) {
+	endpoint := g.base.JoinPath("statusjsn.js")
+	endpoint.RawQuery = "components=" + strconv.Itoa(gudeComponentSensorValues|gudeComponentSensorDescr)
+
+	resp, err := g.req.get(ctx, endpoint.String())
+	if err != nil {
+		return newMeasurement(), err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return newMeasurement(), err
+	}
+
+	return g.parseMeter(body, outlet)
+}
+
+// parseMeter extracts the outlet's meter values from a statusjsn.js JSON
+// response body with the sensor values and descriptions.
+func (g gude) parseMeter(body []byte, outlet int) (measurement, error) {
+	var response gudeSensorResponse
+
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return newMeasurement(), fmt.Errorf("failed to parse Gude sensor response: %w", err)
+	}
+
+	descr := slices.IndexFunc(response.SensorDescr, func(d gudeSensorDescr) bool { return d.Type == gudeSensorOutletMeter })
+	values := slices.IndexFunc(response.SensorValues, func(v gudeSensorValues) bool { return v.Type == gudeSensorOutletMeter })
+
+	if descr == -1 || values == -1 {
+		return newMeasurement(), fmt.Errorf("the PDU has no outlet meters")
+	}
+
+	readings := response.SensorValues[values].Values
+	if outlet < 0 || outlet >= len(readings) {
+		return newMeasurement(), fmt.Errorf("outlet %d not found in PDU meters (only %d outlets metered)", outlet, len(readings))
+	}
+
+	result := newMeasurement()
+
+	for i, field := range response.SensorDescr[descr].Fields {
+		if i >= len(readings[outlet]) {
+			break
+		}
+
+		value := readings[outlet][i].V
+
+		switch field.Name {
+		case "Current":
+			result.current = value
+		case "Voltage":
+			result.voltage = value
+		case "PowerActive":
+			result.power = value
+		case "AbsEnergyActive":
+			result.energy = value
+		}
+	}
+
+	return result, nil
+}
+
+// gudeSensorResponse is the subset of the Gude status endpoint's JSON response
+// with sensor components that this module consumes.
+type gudeSensorResponse struct {
+	SensorDescr  []gudeSensorDescr  `json:"sensor_descr"`
+	SensorValues []gudeSensorValues `json:"sensor_values"`
+}
+
+type gudeSensorDescr struct {
+	Type   int `json:"type"`
+	Fields []struct {
+		Name string `json:"name"`
+		Unit string `json:"unit"`
+	} `json:"fields"`
+}
+
+// gudeSensorValues holds the values of the sensors of a type, indexed by the
+// property (for outlet meters, the outlet) and then by the field.
+type gudeSensorValues struct {
+	Type   int `json:"type"`
+	Values [][]struct {
+		V float64 `json:"v"`
+	} `json:"values"`
+}
+
 // gudeStatusResponse is the subset of the Gude status endpoint's JSON response
 // that this module consumes.
 type gudeStatusResponse struct {
@@ -331,3 +331,82 @@ func TestGudeSetPowerRequests(t *testing.T) {
 		})
 	}
 }
+
+// gudeSensorBody is a statusjsn.js response with sensor components of a PDU
+// with two metered outlets.
+const gudeSensorBody = `{
+	"sensor_descr": [
+		{"type": 8, "num": 1, "fields": [{"name": "Voltage", "unit": "V"}]},
+		{"type": 9, "num": 2, "properties": [{"id": "", "name": "Port 1"}, {"id": "", "name": "Port 2"}],
+		 "fields": [
+			{"name": "AbsEnergyActive", "unit": "kWh"},
+			{"name": "PowerActive", "unit": "W"},
+			{"name": "Voltage", "unit": "V"},
+			{"name": "Current", "unit": "A"},
+			{"name": "Frequency", "unit": "Hz"}
+		 ]}
+	],
+	"sensor_values": [
+		{"type": 8, "num": 1, "values": [[{"v": 231.0}]]},
+		{"type": 9, "num": 2, "values": [
+			[{"v": 1.5}, {"v": 0}, {"v": 230.4}, {"v": 0}, {"v": 50}],
+			[{"v": 12.345}, {"v": 96.5}, {"v": 230.1}, {"v": 0.42}, {"v": 50}]
+		]}
+	]
+}`
+
+func TestGudeParseMeter(t *testing.T) {
+	got, err := gude{}.parseMeter([]byte(gudeSensorBody), 1)
+	if err != nil {
+		t.Fatalf("parseMeter() unexpected error: %v", err)
+	}
+
+	want := measurement{current: 0.42, voltage: 230.1, power: 96.5, energy: 12.345}
+	if got != want {
+		t.Errorf("parseMeter() = %+v, want %+v", got, want)
+	}
+
+	errTests := []struct {
+		name     string
+		outlet   int
+		jsonBody string
+	}{
+		{name: "outlet not metered", outlet: 2, jsonBody: gudeSensorBody},
+		{name: "no outlet meters", outlet: 0, jsonBody: `{"sensor_descr": [{"type": 8}], "sensor_values": [{"type": 8}]}`},
+		{name: "malformed JSON", outlet: 0, jsonBody: `{"sensor_descr": [`},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (gude{}).parseMeter([]byte(tt.jsonBody), tt.outlet); err == nil {
+				t.Error("parseMeter() expected error but got none")
+			}
+		})
+	}
+}
+
+// TestGudeMeasure pins Gude's meter contract: the values are read from
+// GET /statusjsn.js with the sensor values and descriptions components.
+func TestGudeMeasure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/statusjsn.js" {
+			t.Errorf("request path = %q, want /statusjsn.js", r.URL.Path)
+		}
+
+		if got := r.URL.Query().Get("components"); got != "81920" {
+			t.Errorf("components query = %q, want %q (sensor values and descriptions)", got, "81920")
+		}
+
+		fmt.Fprint(w, gudeSensorBody)
+	}))
+	defer srv.Close()
+
+	got, err := newTestGude(t, srv).measure(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("measure() unexpected error: %v", err)
+	}
+
+	if got.current != 0 || got.energy != 1.5 {
+		t.Errorf("measure() = %+v, want the values of the first outlet", got)
+	}
+}
@@ -12,6 +12,20 @@ import (
 	"strings"
 )
 
+func init() {
+	registerBackend(backendRecord{
+		vendor: vendorIntellinet,
+		new: func(cfg backendConfig) (backend, error) {
+			base, err := httpBase(cfg.host)
+			if err != nil {
+				return nil, err
+			}
+
+			return intellinet{req: cfg.req, base: base}, nil
+		},
+	})
+}
+
 // intellinet drives Intellinet-style PDUs (also the LogiLink PDU8P01 and
 // compatibles) via their control_outlet.htm and status.xml HTTP endpoints.
 type intellinet struct {
@@ -0,0 +1,201 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+// measure is the query command that reports the electrical values of the
+// outlets. Like status, it does not change state.
+const measure = "measure"
+
+const (
+	defaultSettle   = 5 * time.Second        // Default time for the current to reach MinCurrent after switching on.
+	powerGoodPoll   = 500 * time.Millisecond // Interval of the current readings of the power-good check.
+	currentDecimals = 3                      // Current and energy are shown in mA and Wh resolution.
+)
+
+// meter is implemented by backends of PDUs that measure the electrical values
+// of their outlets. It is optional: the measure command and the power-good
+// check are available with such backends only.
+type meter interface {
+	// measure reads the electrical values of the outlet.
+	measure(ctx context.Context, outlet int) (measurement, error)
+}
+
+// measurement holds the electrical values of an outlet. A value the PDU does
+// not report is NaN; use newMeasurement to start with all values unknown.
+type measurement struct {
+	current float64 // current is the RMS current in A.
+	voltage float64 // voltage is the RMS voltage in V.
+	power   float64 // power is the active power in W.
+	energy  float64 // energy is the active energy in kWh, counted since the PDU's last reset.
+}
+
+// newMeasurement returns a measurement with all values unknown.
+func newMeasurement() measurement {
+	return measurement{current: math.NaN(), voltage: math.NaN(), power: math.NaN(), energy: math.NaN()}
+}
+
+// String renders the known values, e.g. "current 0.420 A, voltage 230.1 V".
+func (m measurement) String() string {
+	var parts []string
+
+	for _, v := range []struct {
+		name, unit string
+		value      float64
+		decimals   int
+	}{
+		{"current", "A", m.current, currentDecimals},
+		{"voltage", "V", m.voltage, 1},
+		{"power", "W", m.power, 1},
+		{"energy", "kWh", m.energy, currentDecimals},
+	} {
+		if !math.IsNaN(v.value) {
+			parts = append(parts, fmt.Sprintf("%s %.*f %s", v.name, v.decimals, v.value, v.unit))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "no values reported"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// logArgs returns the known values as structured log attributes.
+func (m measurement) logArgs() []any {
+	var args []any
+
+	for _, v := range []struct {
+		key   string
+		value float64
+	}{
+		{"current_a", m.current},
+		{"voltage_v", m.voltage},
+		{"power_w", m.power},
+		{"energy_kwh", m.energy},
+	} {
+		if !math.IsNaN(v.value) {
+			args = append(args, v.key, v.value)
+		}
+	}
+
+	return args
+}
+
+// meter returns the backend as a meter, or an error if the PDU cannot measure.
+//
+//nolint:ireturn // the optional interface of the backend
+func (p *PDU) meter() (meter, error) {
+	m, ok := p.backend.(meter)
+	if !ok {
+		return nil, fmt.Errorf("the %s PDU backend does not support measuring outlets", p.vendorLabel())
+	}
+
+	return m, nil
+}
+
+// reportMeasurement measures every outlet via the backend and reports the
+// values to the client, one line per outlet. Every measurement is logged, too,
+// so the consumption during a long test can be followed in the agent's log.
+func (p *PDU) reportMeasurement(ctx context.Context, s module.Session) error {
+	m, err := p.meter()
+	if err != nil {
+		return err
+	}
+
+	out := strings.Builder{}
+
+	for _, o := range p.sequence() {
+		values, err := m.measure(ctx, o.index)
+		if err != nil {
+			return p.outletErr(o, err)
+		}
+
+		log.FromContext(ctx).Info("outlet measured", append([]any{"outlet", o.index}, values.logArgs()...)...)
+		fmt.Fprintf(&out, "PDU outlet %d: %s\n", o.index, values)
+	}
+
+	s.Printf("%s", out.String())
+
+	return nil
+}
+
+// totalCurrent measures every outlet and returns the sum of their currents.
+func (p *PDU) totalCurrent(ctx context.Context, m meter) (float64, error) {
+	var total float64
+
+	for _, o := range p.sequence() {
+		values, err := m.measure(ctx, o.index)
+		if err != nil {
+			return 0, p.outletErr(o, err)
+		}
+
+		if math.IsNaN(values.current) {
+			return 0, fmt.Errorf("the PDU does not report the current of outlet %d", o.index)
+		}
+
+		total += values.current
+	}
+
+	return total, nil
+}
+
+// checkPowerGood waits up to the settle time for the total current of the
+// outlets to reach MinCurrent after switching on, and returns an error if it
+// does not. A DUT that draws no current was not switched on, e.g. because of a
+// wrong outlet or a broken power supply. The check is disabled if MinCurrent
+// is 0.
+func (p *PDU) checkPowerGood(ctx context.Context) error {
+	if p.MinCurrent == 0 {
+		return nil
+	}
+
+	m, err := p.meter()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(p.settle)
+
+	for {
+		current, err := p.totalCurrent(ctx, m)
+		if err != nil {
+			return fmt.Errorf("power-good check failed: %w", err)
+		}
+
+		if current >= p.MinCurrent {
+			log.FromContext(ctx).Debug("power good", "outlets", p.outletLabel(), "current_a", current)
+
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			verb := "draws"
+			if len(p.sequence()) > 1 {
+				verb = "draw"
+			}
+
+			return fmt.Errorf("power-good check failed: %s %s %.*f A after %s, want at least %.*f A",
+				p.outletLabel(), verb, currentDecimals, current, p.settle, currentDecimals, p.MinCurrent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(powerGoodPoll, remaining)):
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+)
+
+// fakeMeter is a fakeBackend that measures the outlet. It reports the currents
+// in turn, repeating the last one.
+type fakeMeter struct {
+	fakeBackend
+
+	currents []float64
+	calls    int
+	err      error
+}
+
+func (f *fakeMeter) measure(_ context.Context, _ int) (measurement, error) {
+	if f.err != nil {
+		return newMeasurement(), f.err
+	}
+
+	m := newMeasurement()
+	m.voltage = 230
+
+	if len(f.currents) > 0 {
+		m.current = f.currents[min(f.calls, len(f.currents)-1)]
+	}
+
+	f.calls++
+
+	return m, nil
+}
+
+func TestMeasurementString(t *testing.T) {
+	tests := []struct {
+		name string
+		m    measurement
+		want string
+	}{
+		{
+			name: "all values",
+			m:    measurement{current: 0.42, voltage: 230.14, power: 96.5, energy: 12.3456},
+			want: "current 0.420 A, voltage 230.1 V, power 96.5 W, energy 12.346 kWh",
+		},
+		{
+			name: "unknown values are left out",
+			m:    measurement{current: 1.5, voltage: math.NaN(), power: math.NaN(), energy: 2},
+			want: "current 1.500 A, energy 2.000 kWh",
+		},
+		{
+			name: "no values",
+			m:    newMeasurement(),
+			want: "no values reported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasureCommand(t *testing.T) {
+	p := &PDU{Outlet: 3, backend: &fakeMeter{currents: []float64{0.25}}}
+	s := &mock.Session{}
+
+	if err := p.Run(context.Background(), s, "measure"); err != nil {
+		t.Fatalf("Run(measure): %v", err)
+	}
+
+	want := "PDU outlet 3: current 0.250 A, voltage 230.0 V\n"
+	if s.PrintText != want {
+		t.Errorf("output = %q, want %q", s.PrintText, want)
+	}
+
+	p.backend = &fakeBackend{}
+
+	if err := p.Run(context.Background(), s, "measure"); err == nil {
+		t.Error("Run(measure): expected error with a backend that cannot measure")
+	}
+}
+
+func TestPowerGood(t *testing.T) {
+	tests := []struct {
+		name     string
+		currents []float64
+		err      error
+		settle   time.Duration
+		wantErr  string
+	}{
+		{name: "draws current at once", currents: []float64{1.2}},
+		{name: "draws current after a while", currents: []float64{0, 0.01, 0.5}, settle: 2 * time.Second},
+		{name: "stays below the threshold", currents: []float64{0, 0.05}, settle: 10 * time.Millisecond, wantErr: "draws 0.050 A"},
+		{name: "no settle time", currents: []float64{0}, wantErr: "draws 0.000 A"},
+		{name: "current not reported", wantErr: "does not report the current"},
+		{name: "meter fails", err: errors.New("timeout"), wantErr: "timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMeter{currents: tt.currents, err: tt.err}
+			p := &PDU{backend: fake, MinCurrent: 0.1, settle: tt.settle}
+
+			err := p.PowerOn(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("PowerOn: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("PowerOn error = %v, want one containing %q", err, tt.wantErr)
+			}
+
+			if fake.current != on {
+				t.Error("the outlet was not switched on")
+			}
+		})
+	}
+}
+
+func TestPowerGoodOnlyWhenSwitchedOn(t *testing.T) {
+	fake := &fakeMeter{currents: []float64{0}}
+	p := &PDU{backend: fake, MinCurrent: 0.1}
+	s := &mock.Session{}
+
+	if err := p.Run(context.Background(), s, "off"); err != nil {
+		t.Fatalf("Run(off): %v", err)
+	}
+
+	if err := p.Run(context.Background(), s, "status"); err != nil {
+		t.Fatalf("Run(status): %v", err)
+	}
+
+	if fake.calls != 0 {
+		t.Errorf("measured %d times, want no power-good check", fake.calls)
+	}
+
+	if err := p.Run(context.Background(), s, "toggle"); err == nil {
+		t.Error("Run(toggle): expected the power-good check to fail when toggled on")
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// vendorNETIO selects the NETIO backend.
+const vendorNETIO = "netio"
+
+func init() {
+	registerBackend(backendRecord{
+		vendor: vendorNETIO,
+		new: func(cfg backendConfig) (backend, error) {
+			base, err := httpBase(cfg.host)
+			if err != nil {
+				return nil, err
+			}
+
+			return netio{req: cfg.req, base: base}, nil
+		},
+	})
+}
+
+// netio drives NETIO PDUs via their JSON M2M API: a GET of netio.json reports
+// all outputs, a POST of netio.json switches them and reports them afterwards.
+// The JSON API must be enabled with read-write access in the device's web UI.
+type netio struct {
+	req  *requester
+	base *url.URL
+}
+
+// Ensure implementing the optional meter interface.
+var _ meter = netio{}
+
+// setPower implements the backend interface for NETIO PDUs, using the native
+// on/off/toggle actions of the JSON API.
+func (n netio) setPower(ctx context.Context, outlet int, act action) (state, error) {
+	netioAct, ok := netioActionFor(act)
+	if !ok {
+		return off, fmt.Errorf("invalid PDU operation: %s", act)
+	}
+
+	body, err := json.Marshal(netioRequest{Outputs: []netioControl{{ID: netioID(outlet), Action: netioAct}}})
+	if err != nil {
+		return off, err
+	}
+
+	resp, err := n.req.postJSON(ctx, n.endpoint(), body)
+	if err != nil {
+		return off, err
+	}
+	defer resp.Body.Close()
+
+	// The response reports the outputs after the switch, so a toggle needs no
+	// separate read-back.
+	return n.readOutlet(resp, outlet)
+}
+
+// outletState implements the backend interface for NETIO PDUs.
+func (n netio) outletState(ctx context.Context, outlet int) (state, error) {
+	resp, err := n.req.get(ctx, n.endpoint())
+	if err != nil {
+		return off, err
+	}
+	defer resp.Body.Close()
+
+	return n.readOutlet(resp, outlet)
+}
+
+func (n netio) endpoint() string {
+	return n.base.JoinPath("netio.json").String()
+}
+
+// readOutlet extracts the outlet's state from a netio.json response.
+func (n netio) readOutlet(resp *http.Response, outlet int) (state, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return off, err
+	}
+
+	return n.parseOutlet(body, outlet)
+}
+
+// parseOutlet extracts the outlet's state from a netio.json response body. The
+// outputs are looked up by their ID rather than their position.
+func (n netio) parseOutlet(body []byte, outlet int) (state, error) {
+	var response netioStatusResponse
+
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return off, fmt.Errorf("failed to parse NETIO status response: %w", err)
+	}
+
+	for _, output := range response.Outputs {
+		if output.ID != netioID(outlet) {
+			continue
+		}
+
+		switch output.State {
+		case netioStateOff:
+			return off, nil
+		case netioStateOn:
+			return on, nil
+		default:
+			return off, fmt.Errorf("invalid NETIO outlet state: %d", output.State)
+		}
+	}
+
+	return off, fmt.Errorf("outlet %d not found in PDU status (%d outlets available)", outlet, len(response.Outputs))
+}
+
+// measure implements the meter interface for NETIO PDUs with metering, which
+// report the outlet's current, power and energy, and the voltage of the PDU.
+func (n netio) measure(ctx context.Context, outlet int) (measurement, error) {
+	resp, err := n.req.get(ctx, n.endpoint())
+	if err != nil {
+		return newMeasurement(), err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return newMeasurement(), err
+	}
+
+	return n.parseMeter(body, outlet)
+}
+
+// parseMeter extracts the outlet's meter values from a netio.json response
+// body. Models without metering omit the values.
+func (n netio) parseMeter(body []byte, outlet int) (measurement, error) {
+	var response netioMeterResponse
+
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return newMeasurement(), fmt.Errorf("failed to parse NETIO status response: %w", err)
+	}
+
+	idx := slices.IndexFunc(response.Outputs, func(o netioOutputMeter) bool { return o.ID == netioID(outlet) })
+	if idx == -1 {
+		return newMeasurement(), fmt.Errorf("outlet %d not found in PDU status (%d outlets available)", outlet, len(response.Outputs))
+	}
+
+	output := response.Outputs[idx]
+	if output.Current == nil {
+		return newMeasurement(), fmt.Errorf("the PDU does not measure its outlets")
+	}
+
+	const milli = 1000
+
+	result := newMeasurement()
+	result.current = *output.Current / milli
+
+	if output.Load != nil {
+		result.power = *output.Load
+	}
+
+	if output.Energy != nil {
+		result.energy = *output.Energy / milli
+	}
+
+	if response.GlobalMeasure.Voltage != nil {
+		result.voltage = *response.GlobalMeasure.Voltage
+	}
+
+	return result, nil
+}
+
+// netioMeterResponse is the subset of the netio.json response of metering
+// models that measure consumes. Values are nil if the model does not report them.
+type netioMeterResponse struct {
+	GlobalMeasure struct {
+		Voltage *float64 `json:"Voltage"` // in V
+	} `json:"GlobalMeasure"`
+	Outputs []netioOutputMeter `json:"Outputs"`
+}
+
+type netioOutputMeter struct {
+	ID      int      `json:"ID"`
+	Current *float64 `json:"Current"` // in mA
+	Load    *float64 `json:"Load"`    // in W
+	Energy  *float64 `json:"Energy"`  // in Wh
+}
+
+// netioID returns the output ID of the outlet. Outlet is a 0-based index
+// (0 = first outlet); NETIO numbers outputs from 1.
+func netioID(outlet int) int {
+	return outlet + 1
+}
+
+// netioStatusResponse is the subset of the netio.json response that this
+// module consumes.
+type netioStatusResponse struct {
+	Outputs []struct {
+		ID    int `json:"ID"`
+		State int `json:"State"` // 0 = off, 1 = on.
+	} `json:"Outputs"`
+}
+
+// The output states reported by the JSON API.
+const (
+	netioStateOff = 0
+	netioStateOn  = 1
+)
+
+// netioRequest is the body of a netio.json POST switching outputs.
+type netioRequest struct {
+	Outputs []netioControl `json:"Outputs"`
+}
+
+type netioControl struct {
+	ID     int         `json:"ID"`
+	Action netioAction `json:"Action"`
+}
+
+// netioAction is the "Action" value of the JSON API. Besides the ones used here
+// it has short on/off pulses (2, 3) and no change (5).
+type netioAction int
+
+const (
+	netioOff    netioAction = 0
+	netioOn     netioAction = 1
+	netioToggle netioAction = 4
+)
+
+// netioActionFor maps a power action to its NETIO action; ok is false for an
+// unknown action.
+func netioActionFor(act action) (netioAction, bool) {
+	switch act {
+	case turnOn:
+		return netioOn, true
+	case turnOff:
+		return netioOff, true
+	case toggle:
+		return netioToggle, true
+	default:
+		return 0, false
+	}
+}
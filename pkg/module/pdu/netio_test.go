@@ -0,0 +1,262 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newTestNETIO builds a netio backend pointed at srv, sharing its client so the
+// tests exercise the real request-building path.
+func newTestNETIO(t *testing.T, srv *httptest.Server) netio {
+	t.Helper()
+
+	base, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", srv.URL, err)
+	}
+
+	return netio{req: &requester{client: srv.Client()}, base: base}
+}
+
+func TestNETIOParseOutlet(t *testing.T) {
+	tests := []struct {
+		name     string
+		outlet   int
+		jsonBody string
+		want     state
+		wantErr  bool
+	}{
+		{
+			name:   "outlet 0 on",
+			outlet: 0,
+			jsonBody: `{
+				"Outputs": [
+					{"ID": 1, "Name": "Power output 1", "State": 1, "Action": 6},
+					{"ID": 2, "Name": "Power output 2", "State": 0, "Action": 6}
+				]
+			}`,
+			want: on,
+		},
+		{
+			name:   "outlet 1 off",
+			outlet: 1,
+			jsonBody: `{
+				"Outputs": [
+					{"ID": 1, "State": 1},
+					{"ID": 2, "State": 0}
+				]
+			}`,
+			want: off,
+		},
+		{
+			name:   "real PDU response - outputs looked up by ID",
+			outlet: 2,
+			jsonBody: `{
+				"Agent": {"Model": "NETIO 4All", "Version": "3.4.0", "NumOutputs": 4},
+				"GlobalMeasure": {"Voltage": 230.1, "Frequency": 50.0},
+				"Outputs": [
+					{"ID": 4, "Name": "Output 4", "State": 0, "Current": 0, "Load": 0},
+					{"ID": 3, "Name": "Output 3", "State": 1, "Current": 120, "Load": 27},
+					{"ID": 2, "Name": "Output 2", "State": 0, "Current": 0, "Load": 0},
+					{"ID": 1, "Name": "Output 1", "State": 0, "Current": 0, "Load": 0}
+				]
+			}`,
+			want: on,
+		},
+		{
+			name:     "outlet not found",
+			outlet:   4,
+			jsonBody: `{"Outputs": [{"ID": 1, "State": 1}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "malformed JSON",
+			outlet:   0,
+			jsonBody: `{"Outputs": [{"ID": 1, "State": 1}`,
+			wantErr:  true,
+		},
+		{
+			name:     "missing outputs field",
+			outlet:   0,
+			jsonBody: `{"Agent": {}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "unexpected state value returns error",
+			outlet:   0,
+			jsonBody: `{"Outputs": [{"ID": 1, "State": 3}]}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := netio{}.parseOutlet([]byte(tt.jsonBody), tt.outlet)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseOutlet() expected error but got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("parseOutlet() unexpected error: %v", err)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("parseOutlet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNETIOOutletState pins NETIO's status contract: the state is read from
+// GET /netio.json and taken from the output with the 1-based ID.
+func TestNETIOOutletState(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/netio.json" {
+			t.Errorf("request = %s %s, want GET /netio.json", r.Method, r.URL.Path)
+		}
+
+		fmt.Fprint(w, `{"Outputs":[{"ID":1,"State":0},{"ID":2,"State":1}]}`)
+	}))
+	defer srv.Close()
+
+	got, err := newTestNETIO(t, srv).outletState(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("outletState() unexpected error: %v", err)
+	}
+
+	if got != on {
+		t.Errorf("outletState() = %v, want on", got)
+	}
+}
+
+// TestNETIOSetPowerRequests pins NETIO's switch contract: a POST of
+// /netio.json with the 1-based output ID and the native action (0 = off,
+// 1 = on, 4 = toggle). The resulting state is taken from the response.
+func TestNETIOSetPowerRequests(t *testing.T) {
+	const outlet = 2 // NETIO output IDs are 1-based, so requests must carry ID 3.
+
+	tests := []struct {
+		name       string
+		act        action
+		after      int // state the response reports for the outlet
+		wantAction netioAction
+		wantState  state
+	}{
+		{name: "on", act: turnOn, after: 1, wantAction: netioOn, wantState: on},
+		{name: "off", act: turnOff, after: 0, wantAction: netioOff, wantState: off},
+		{name: "toggle reports the state from the response", act: toggle, after: 1, wantAction: netioToggle, wantState: on},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got netioRequest
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/netio.json" {
+					t.Errorf("request = %s %s, want POST /netio.json", r.Method, r.URL.Path)
+				}
+
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding request body: %v", err)
+				}
+
+				fmt.Fprintf(w, `{"Outputs":[{"ID":1,"State":0},{"ID":2,"State":0},{"ID":3,"State":%d}]}`, tt.after)
+			}))
+			defer srv.Close()
+
+			result, err := newTestNETIO(t, srv).setPower(context.Background(), outlet, tt.act)
+			if err != nil {
+				t.Fatalf("setPower() unexpected error: %v", err)
+			}
+
+			if result != tt.wantState {
+				t.Errorf("setPower() = %v, want %v", result, tt.wantState)
+			}
+
+			if len(got.Outputs) != 1 {
+				t.Fatalf("request switches %d outputs, want 1", len(got.Outputs))
+			}
+
+			if got.Outputs[0].ID != 3 {
+				t.Errorf("request ID = %d, want 3 (1-based ID of outlet %d)", got.Outputs[0].ID, outlet)
+			}
+
+			if got.Outputs[0].Action != tt.wantAction {
+				t.Errorf("request Action = %d, want %d", got.Outputs[0].Action, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestNETIOSetPowerErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "read-only access", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if _, err := newTestNETIO(t, srv).setPower(context.Background(), 0, turnOn); err == nil {
+		t.Error("setPower() expected error for a rejected request")
+	}
+
+	if _, err := newTestNETIO(t, srv).setPower(context.Background(), 0, action(99)); err == nil {
+		t.Error("setPower() expected error for an invalid action")
+	}
+}
+
+func TestNETIOParseMeter(t *testing.T) {
+	const body = `{
+		"GlobalMeasure": {"Voltage": 230.5, "Frequency": 50.0, "TotalCurrent": 420, "TotalLoad": 97},
+		"Outputs": [
+			{"ID": 1, "State": 0, "Current": 0, "PowerFactor": 0.00, "Load": 0, "Energy": 1291},
+			{"ID": 2, "State": 1, "Current": 420, "PowerFactor": 0.98, "Load": 97, "Energy": 12345}
+		]
+	}`
+
+	got, err := netio{}.parseMeter([]byte(body), 1)
+	if err != nil {
+		t.Fatalf("parseMeter() unexpected error: %v", err)
+	}
+
+	want := measurement{current: 0.42, voltage: 230.5, power: 97, energy: 12.345}
+	if got != want {
+		t.Errorf("parseMeter() = %+v, want %+v", got, want)
+	}
+
+	errTests := []struct {
+		name     string
+		outlet   int
+		jsonBody string
+	}{
+		{name: "outlet not found", outlet: 4, jsonBody: body},
+		{name: "model without metering", outlet: 0, jsonBody: `{"Outputs": [{"ID": 1, "State": 1}]}`},
+		{name: "malformed JSON", outlet: 0, jsonBody: `{"Outputs": [`},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (netio{}).parseMeter([]byte(tt.jsonBody), tt.outlet); err == nil {
+				t.Error("parseMeter() expected error but got none")
+			}
+		})
+	}
+}
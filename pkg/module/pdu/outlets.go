@@ -0,0 +1,242 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+// cycle is the command that switches all outlets off and on again. Unlike
+// toggle it always ends with the outlets on.
+const cycle = "cycle"
+
+const (
+	defaultOffTime = 5 * time.Second  // Default time the outlets stay off during a cycle.
+	cycleOnTimeout = 30 * time.Second // Bounds switching on at the end of a cycle, on top of the delays and settle time.
+)
+
+// OutletConfig is an entry of the Outlets option.
+type OutletConfig struct {
+	// Outlet is the outlet to control (0-based).
+	Outlet int
+	// Delay is the time to wait after switching on the previous outlet of the list
+	// before switching on this one. Switching off waits the same time in reverse
+	// order. Must be empty for the first outlet.
+	Delay string
+}
+
+// outlet is a resolved entry of the outlet sequence.
+type outlet struct {
+	index int           // index is the 0-based outlet number.
+	delay time.Duration // delay is the pause before switching the outlet on.
+}
+
+// resolveOutlets validates the Outlet and Outlets options and returns the
+// outlet sequence they describe.
+func (p *PDU) resolveOutlets() ([]outlet, error) {
+	if len(p.Outlets) == 0 {
+		if p.Outlet < 0 {
+			return nil, fmt.Errorf("invalid outlet number %d: outlet must be 0 or greater", p.Outlet)
+		}
+
+		return []outlet{{index: p.Outlet}}, nil
+	}
+
+	if p.Outlet != 0 {
+		return nil, fmt.Errorf("outlet and outlets are mutually exclusive: add outlet %d to the outlets list", p.Outlet)
+	}
+
+	outlets := make([]outlet, 0, len(p.Outlets))
+
+	for i, cfg := range p.Outlets {
+		if cfg.Outlet < 0 {
+			return nil, fmt.Errorf("invalid outlet number %d: outlet must be 0 or greater", cfg.Outlet)
+		}
+
+		if slices.ContainsFunc(outlets, func(o outlet) bool { return o.index == cfg.Outlet }) {
+			return nil, fmt.Errorf("outlet %d is listed more than once", cfg.Outlet)
+		}
+
+		var delay time.Duration
+
+		if cfg.Delay != "" {
+			d, err := time.ParseDuration(cfg.Delay)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid delay %q of outlet %d: must be a non-negative duration like 2s", cfg.Delay, cfg.Outlet)
+			}
+
+			if i == 0 && d > 0 {
+				return nil, fmt.Errorf("delay of outlet %d has no effect: the first outlet is switched on at once", cfg.Outlet)
+			}
+
+			delay = d
+		}
+
+		outlets = append(outlets, outlet{index: cfg.Outlet, delay: delay})
+	}
+
+	return outlets, nil
+}
+
+// sequence returns the outlets in the order they are switched on. Without a
+// resolved sequence, it is the single configured Outlet.
+func (p *PDU) sequence() []outlet {
+	if len(p.outlets) == 0 {
+		return []outlet{{index: p.Outlet}}
+	}
+
+	return p.outlets
+}
+
+// outletLabel names the controlled outlets for messages, e.g. "outlet 3" or
+// "outlets 0, 1".
+func (p *PDU) outletLabel() string {
+	outlets := p.sequence()
+	if len(outlets) == 1 {
+		return "outlet " + strconv.Itoa(outlets[0].index)
+	}
+
+	indices := make([]string, len(outlets))
+	for i, o := range outlets {
+		indices[i] = strconv.Itoa(o.index)
+	}
+
+	return "outlets " + strings.Join(indices, ", ")
+}
+
+// sequenceTime returns the sum of the delays of the sequence.
+func (p *PDU) sequenceTime() time.Duration {
+	var total time.Duration
+
+	for _, o := range p.sequence() {
+		total += o.delay
+	}
+
+	return total
+}
+
+// states queries the state of every outlet, in sequence order.
+func (p *PDU) states(ctx context.Context) ([]state, error) {
+	outlets := p.sequence()
+	result := make([]state, len(outlets))
+
+	for i, o := range outlets {
+		current, err := p.backend.outletState(ctx, o.index)
+		if err != nil {
+			return nil, p.outletErr(o, err)
+		}
+
+		result[i] = current
+	}
+
+	return result, nil
+}
+
+// switchOutlets applies act to every outlet: on in sequence order, waiting each
+// outlet's delay before switching it, off in reverse order, waiting the delay of
+// the outlet just switched off before the next one. It returns the state of the
+// outlet switched last.
+func (p *PDU) switchOutlets(ctx context.Context, act action) (state, error) {
+	outlets := slices.Clone(p.sequence())
+	if act == turnOff {
+		slices.Reverse(outlets)
+	}
+
+	var result state
+
+	for i, o := range outlets {
+		if i > 0 {
+			delay := o.delay
+			if act == turnOff {
+				delay = outlets[i-1].delay
+			}
+
+			if err := pause(ctx, delay); err != nil {
+				return result, err
+			}
+		}
+
+		var err error
+
+		result, err = p.backend.setPower(ctx, o.index, act)
+		if err != nil {
+			return result, p.outletErr(o, err)
+		}
+
+		log.FromContext(ctx).Info("power command applied", "outlet", o.index, "action", act, "state", result)
+	}
+
+	return result, nil
+}
+
+// outletErr adds the outlet to an error of the backend if several outlets are
+// controlled, so the failing one can be told apart.
+func (p *PDU) outletErr(o outlet, err error) error {
+	if len(p.sequence()) == 1 {
+		return err
+	}
+
+	return fmt.Errorf("outlet %d: %w", o.index, err)
+}
+
+// powerCycle switches the outlets off, waits the off time given in args (default
+// 5s) and switches them on again. Like a device power cycle, the outlets are
+// switched on even if the request is aborted while they are off, so a cycle
+// never leaves the DUT without power.
+func (p *PDU) powerCycle(ctx context.Context, s module.Session, args []string) error {
+	offTime := defaultOffTime
+
+	if len(args) > 0 {
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid off time %q: must be a non-negative duration like 5s", args[0])
+		}
+
+		offTime = d
+	}
+
+	_, err := p.setPower(ctx, turnOff)
+	if err != nil {
+		return err
+	}
+
+	if err := pause(ctx, offTime); err != nil {
+		log.FromContext(ctx).Warn("power cycle aborted, switching the outlets on again")
+	}
+
+	onCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cycleOnTimeout+p.sequenceTime()+p.settle)
+	defer cancel()
+
+	_, err = p.setPower(onCtx, turnOn)
+	if err != nil {
+		return err
+	}
+
+	s.Printf("PDU %s power-cycled (off for %s)\n", p.outletLabel(), offTime)
+
+	return ctx.Err()
+}
+
+// pause waits for d, or until ctx is done.
+func pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
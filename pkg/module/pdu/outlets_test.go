@@ -0,0 +1,274 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeOutlets is a backend keeping the state of several outlets in memory and
+// recording the switch operations, e.g. "2 on".
+type fakeOutlets struct {
+	states map[int]state
+	calls  []string
+}
+
+func newFakeOutlets(states map[int]state) *fakeOutlets {
+	if states == nil {
+		states = make(map[int]state)
+	}
+
+	return &fakeOutlets{states: states}
+}
+
+func (f *fakeOutlets) setPower(_ context.Context, outlet int, act action) (state, error) {
+	switch act {
+	case turnOn:
+		f.states[outlet] = on
+	case turnOff:
+		f.states[outlet] = off
+	case toggle:
+		f.states[outlet] = 1 - f.states[outlet]
+	}
+
+	f.calls = append(f.calls, fmt.Sprintf("%d %s", outlet, f.states[outlet]))
+
+	return f.states[outlet], nil
+}
+
+func (f *fakeOutlets) outletState(_ context.Context, outlet int) (state, error) {
+	return f.states[outlet], nil
+}
+
+func TestOutletsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		want    []outlet
+		initErr bool
+	}{
+		{
+			name:    "single outlet",
+			options: "outlet: 3\n",
+			want:    []outlet{{index: 3}},
+		},
+		{
+			name:    "outlets with delays",
+			options: "outlets:\n  - outlet: 2\n  - outlet: 0\n    delay: 2s\n  - outlet: 1\n",
+			want:    []outlet{{index: 2}, {index: 0, delay: 2 * time.Second}, {index: 1}},
+		},
+		{
+			name:    "outlet and outlets together are rejected",
+			options: "outlet: 3\noutlets:\n  - outlet: 0\n",
+			initErr: true,
+		},
+		{
+			name:    "duplicate outlet is rejected",
+			options: "outlets:\n  - outlet: 0\n  - outlet: 0\n",
+			initErr: true,
+		},
+		{
+			name:    "negative outlet is rejected",
+			options: "outlets:\n  - outlet: 0\n  - outlet: -1\n",
+			initErr: true,
+		},
+		{
+			name:    "invalid delay is rejected",
+			options: "outlets:\n  - outlet: 0\n  - outlet: 1\n    delay: later\n",
+			initErr: true,
+		},
+		{
+			name:    "delay of the first outlet is rejected",
+			options: "outlets:\n  - outlet: 0\n    delay: 1s\n  - outlet: 1\n",
+			initErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p PDU
+			if err := yaml.Unmarshal([]byte("vendor: gude\nhost: http://10.0.0.1\n"+tt.options), &p); err != nil {
+				t.Fatalf("yaml.Unmarshal() unexpected error: %v", err)
+			}
+
+			err := p.Init(context.Background())
+
+			if tt.initErr {
+				if err == nil {
+					t.Fatal("Init() expected error but got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Init() unexpected error: %v", err)
+			}
+
+			if !slices.Equal(p.outlets, tt.want) {
+				t.Errorf("outlets = %+v, want %+v", p.outlets, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequencing(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	fake := newFakeOutlets(nil)
+	p := &PDU{backend: fake, outlets: []outlet{{index: 2}, {index: 0, delay: delay}, {index: 1, delay: delay}}}
+	s := &mock.Session{}
+
+	start := time.Now()
+
+	if err := p.Run(context.Background(), s, "on"); err != nil {
+		t.Fatalf("Run(on): %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("switching on took %s, want at least the delays of %s", elapsed, 2*delay)
+	}
+
+	if want := "PDU outlets 2, 0, 1 powered on\n"; s.PrintText != want {
+		t.Errorf("output = %q, want %q", s.PrintText, want)
+	}
+
+	if err := p.Run(context.Background(), s, "off"); err != nil {
+		t.Fatalf("Run(off): %v", err)
+	}
+
+	want := []string{"2 on", "0 on", "1 on", "1 off", "0 off", "2 off"}
+	if !slices.Equal(fake.calls, want) {
+		t.Errorf("calls = %v, want %v", fake.calls, want)
+	}
+}
+
+func TestSequencingAborted(t *testing.T) {
+	fake := newFakeOutlets(nil)
+	p := &PDU{backend: fake, outlets: []outlet{{index: 0}, {index: 1, delay: time.Minute}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := p.PowerOn(ctx); err == nil {
+		t.Fatal("PowerOn: expected error when aborted during a delay")
+	}
+
+	if want := []string{"0 on"}; !slices.Equal(fake.calls, want) {
+		t.Errorf("calls = %v, want %v", fake.calls, want)
+	}
+}
+
+func TestStatusReportsEveryOutlet(t *testing.T) {
+	fake := newFakeOutlets(map[int]state{0: on, 4: off})
+	p := &PDU{backend: fake, outlets: []outlet{{index: 0}, {index: 4}}}
+	s := &mock.Session{}
+
+	if err := p.Run(context.Background(), s, "status"); err != nil {
+		t.Fatalf("Run(status): %v", err)
+	}
+
+	want := "PDU outlet 0 state: on\nPDU outlet 4 state: off\n"
+	if s.PrintText != want {
+		t.Errorf("output = %q, want %q", s.PrintText, want)
+	}
+}
+
+func TestMultiOutletPowerState(t *testing.T) {
+	tests := []struct {
+		name   string
+		states map[int]state
+		want   module.PowerState
+	}{
+		{name: "all on", states: map[int]state{0: on, 1: on}, want: module.PowerOn},
+		{name: "all off", states: map[int]state{0: off, 1: off}, want: module.PowerOff},
+		{name: "mixed", states: map[int]state{0: on, 1: off}, want: module.PowerUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PDU{backend: newFakeOutlets(tt.states), outlets: []outlet{{index: 0}, {index: 1}}}
+
+			got, err := p.PowerState(context.Background())
+			if err != nil {
+				t.Fatalf("PowerState: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("PowerState = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiOutletToggle(t *testing.T) {
+	fake := newFakeOutlets(map[int]state{0: off, 1: on})
+	p := &PDU{backend: fake, outlets: []outlet{{index: 0}, {index: 1}}}
+	s := &mock.Session{}
+
+	if err := p.Run(context.Background(), s, "toggle"); err != nil {
+		t.Fatalf("Run(toggle): %v", err)
+	}
+
+	if want := []string{"1 off", "0 off"}; !slices.Equal(fake.calls, want) {
+		t.Errorf("toggle with an outlet on: calls = %v, want %v", fake.calls, want)
+	}
+
+	fake.calls = nil
+
+	if err := p.Run(context.Background(), s, "toggle"); err != nil {
+		t.Fatalf("Run(toggle): %v", err)
+	}
+
+	if want := []string{"0 on", "1 on"}; !slices.Equal(fake.calls, want) {
+		t.Errorf("toggle with all outlets off: calls = %v, want %v", fake.calls, want)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	fake := newFakeOutlets(map[int]state{0: on, 1: on})
+	p := &PDU{backend: fake, outlets: []outlet{{index: 0}, {index: 1}}}
+	s := &mock.Session{}
+
+	if err := p.Run(context.Background(), s, "cycle", "0s"); err != nil {
+		t.Fatalf("Run(cycle): %v", err)
+	}
+
+	if want := []string{"1 off", "0 off", "0 on", "1 on"}; !slices.Equal(fake.calls, want) {
+		t.Errorf("calls = %v, want all outlets off before any on: %v", fake.calls, want)
+	}
+
+	if want := "PDU outlets 0, 1 power-cycled (off for 0s)\n"; s.PrintText != want {
+		t.Errorf("output = %q, want %q", s.PrintText, want)
+	}
+
+	if err := p.Run(context.Background(), s, "cycle", "soon"); err == nil {
+		t.Error("Run(cycle soon): expected error for an invalid off time")
+	}
+}
+
+func TestCycleAborted(t *testing.T) {
+	fake := newFakeOutlets(map[int]state{0: on})
+	p := &PDU{backend: fake, Outlet: 0}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := p.Run(ctx, &mock.Session{}, "cycle", "1m"); err == nil {
+		t.Error("Run(cycle): expected the error of the aborted request")
+	}
+
+	if fake.states[0] != on {
+		t.Error("an aborted cycle left the outlet off")
+	}
+}
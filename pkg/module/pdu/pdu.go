@@ -6,11 +6,15 @@
 package pdu
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,13 +31,9 @@ func init() {
 	})
 }
 
-// Supported PDU vendors, selected via the "vendor" configuration option. A
-// value names the device's HTTP API family rather than a single product:
-// "intellinet" also covers compatible units such as the LogiLink PDU8P01.
-const (
-	vendorIntellinet = "intellinet"
-	vendorGude       = "gude"
-)
+// vendorIntellinet is the vendor used when the "vendor" option is not set, which
+// keeps configurations written before the option existed working.
+const vendorIntellinet = "intellinet"
 
 const defaultTimeout = 10 * time.Second // Default timeout for HTTP requests.
 
@@ -112,38 +112,66 @@ func parseAction(word string) (action, bool) {
 }
 
 // commandList returns the supported commands as a comma-separated string,
-// derived from the action and status commands so usage messages cannot drift.
+// derived from the action and query commands so usage messages cannot drift.
 func commandList() string {
-	return strings.Join([]string{turnOn.String(), turnOff.String(), toggle.String(), status}, ", ")
+	return strings.Join([]string{turnOn.String(), turnOff.String(), toggle.String(), cycle, status, measure}, ", ")
 }
 
 // PDU is a module that provides basic power management functions for a PDU
 // (Power Distribution Unit). It supports Intellinet-style PDUs (e.g. Intellinet
-// 163682, LogiLink PDU8P01) and Gude PDUs; the concrete device is selected via
-// the Vendor option.
+// 163682, LogiLink PDU8P01), Gude and NETIO PDUs via HTTP, and APC PDUs via
+// SNMP; the concrete device is selected via the Vendor option.
 type PDU struct {
-	Vendor   string `yaml:"vendor"` // Vendor selects the PDU HTTP API: "intellinet" (default) or "gude".
-	Host     string // Host is the base address of the PDU.
-	User     string // User for HTTP Basic Auth; set together with Password, or leave both empty for no auth.
-	Password string // Password for HTTP Basic Auth; set together with User, or leave both empty for no auth.
-	Outlet   int    // Outlet is the outlet to control, if the PDU supports multiple outlets. Defaults to 0 (first outlet).
-
-	backend backend // vendor-specific API, selected in Init.
+	Vendor    string `yaml:"vendor"` // Vendor selects the PDU backend: "intellinet" (default), "gude", "netio" or "apc".
+	Host      string // Host is the base address of the PDU: a URL for HTTP backends, host[:port] for SNMP backends.
+	User      string // User for HTTP Basic Auth; set together with Password, or leave both empty for no auth.
+	Password  string // Password for HTTP Basic Auth; set together with User, or leave both empty for no auth.
+	Community string // Community is the SNMP write community of SNMP backends. Defaults to "private".
+	Outlet    int    // Outlet is the outlet to control, if the PDU supports multiple outlets. Defaults to 0 (first outlet).
+
+	// Outlets replaces Outlet for DUTs powered by several outlets, e.g. redundant
+	// power supplies or a separate fan tray. The outlets are switched on in list
+	// order and off in reverse order, with optional delays in between.
+	Outlets []OutletConfig
+
+	// MinCurrent enables the power-good check: switching the outlets on fails if
+	// their total current stays below MinCurrent (in A) for the settle time. Needs
+	// a PDU that measures its outlets. Defaults to 0 (no check).
+	MinCurrent float64 `yaml:"mincurrent"`
+	// Settle is the time the current may take to reach MinCurrent. Default: 5s.
+	Settle string
+
+	backend backend       // vendor-specific API, selected in Init.
+	outlets []outlet      // resolved Outlet or Outlets; set in Init.
+	settle  time.Duration // resolved Settle; set in Init.
 }
 
+// Ensure implementing the PowerController interface.
+var _ module.PowerController = &PDU{}
+
 func (p *PDU) Help() string {
 	help := strings.Builder{}
 
-	help.WriteString("PDU module: control of a Power Distribution Unit (PDU) via HTTP.\n")
+	help.WriteString("PDU module: control of a Power Distribution Unit (PDU) via HTTP or SNMP.\n")
 	help.WriteString("\nUsage:\n")
-	help.WriteString("  pdu [on|off|toggle|status]\n\n")
+	help.WriteString("  pdu [on|off|toggle|cycle [off-time]|status|measure]\n\n")
 	help.WriteString("Commands:\n")
-	help.WriteString("  on      - Power on the outlet\n")
-	help.WriteString("  off     - Power off the outlet\n")
-	help.WriteString("  toggle  - Toggle the outlet power\n")
-	help.WriteString("  status  - Report the current power state\n")
+	help.WriteString("  on      - Power on the outlets\n")
+	help.WriteString("  off     - Power off the outlets\n")
+	help.WriteString("  toggle  - Toggle the outlet power; several outlets are switched off if any is on\n")
+	help.WriteString("  cycle   - Power off all outlets, wait the off time (default 5s), then power them on\n")
+	help.WriteString("  status  - Report the current power state of every outlet\n")
+	help.WriteString("  measure - Report the current, voltage, power and energy of every outlet (if supported)\n")
 	help.WriteString("\n")
-	fmt.Fprintf(&help, "Controls outlet %d of the PDU at %s via the %s API.\n", p.Outlet, p.Host, p.vendorLabel())
+	fmt.Fprintf(&help, "Controls %s of the PDU at %s via the %s API.\n", p.outletLabel(), p.Host, p.vendorLabel())
+
+	if len(p.sequence()) > 1 {
+		help.WriteString("The outlets are switched on in this order and off in reverse order.\n")
+	}
+
+	if p.MinCurrent > 0 {
+		fmt.Fprintf(&help, "Switching on fails if the outlets draw less than %g A after %s.\n", p.MinCurrent, p.settle)
+	}
 
 	return help.String()
 }
@@ -163,29 +191,56 @@ func (p *PDU) Init(_ context.Context) error {
 		return fmt.Errorf("PDU host address not configured")
 	}
 
-	if p.Outlet < 0 {
-		return fmt.Errorf("invalid outlet number %d: outlet must be 0 or greater", p.Outlet)
+	outlets, err := p.resolveOutlets()
+	if err != nil {
+		return err
 	}
 
+	p.outlets = outlets
+
 	// Basic Auth needs both parts; only one set is a misconfiguration that would
 	// otherwise send no credentials and fail with an opaque 401 at request time.
 	if (p.User == "") != (p.Password == "") {
 		return fmt.Errorf("PDU authentication requires both user and password to be set, or neither")
 	}
 
-	req := &requester{
-		client:   &http.Client{Timeout: defaultTimeout},
-		user:     p.User,
-		password: p.Password,
+	if p.MinCurrent < 0 {
+		return fmt.Errorf("invalid mincurrent %g: must be 0 (no power-good check) or greater", p.MinCurrent)
 	}
 
-	backend, err := newBackend(p.Vendor, req, p.Host)
+	p.settle = defaultSettle
+
+	if p.Settle != "" {
+		settle, err := time.ParseDuration(p.Settle)
+		if err != nil || settle < 0 {
+			return fmt.Errorf("invalid settle time %q: must be a non-negative duration like 5s", p.Settle)
+		}
+
+		p.settle = settle
+	}
+
+	backend, err := newBackend(p.Vendor, backendConfig{
+		host:      p.Host,
+		community: p.Community,
+		req: &requester{
+			client:   &http.Client{Timeout: defaultTimeout},
+			user:     p.User,
+			password: p.Password,
+		},
+	})
 	if err != nil {
 		return err
 	}
 
 	p.backend = backend
 
+	// Fail early rather than on the first "on" if the check cannot work.
+	if p.MinCurrent > 0 {
+		if _, err := p.meter(); err != nil {
+			return fmt.Errorf("mincurrent is set, but %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -204,8 +259,13 @@ func (p *PDU) Run(ctx context.Context, s module.Session, args ...string) error {
 
 	cmd := strings.ToLower(args[0])
 
-	if cmd == status {
+	switch cmd {
+	case status:
 		return p.report(ctx, s)
+	case measure:
+		return p.reportMeasurement(ctx, s)
+	case cycle:
+		return p.powerCycle(ctx, s, args[1:])
 	}
 
 	act, ok := parseAction(cmd)
@@ -218,33 +278,115 @@ func (p *PDU) Run(ctx context.Context, s module.Session, args ...string) error {
 
 // apply performs the power action via the backend and reports the resulting state to the client.
 func (p *PDU) apply(ctx context.Context, s module.Session, act action) error {
-	result, err := p.backend.setPower(ctx, p.Outlet, act)
+	result, err := p.setPower(ctx, act)
 	if err != nil {
 		return err
 	}
 
-	log.FromContext(ctx).Info("power command applied", "outlet", p.Outlet, "action", act, "state", result)
-	s.Printf("PDU outlet %d powered %s\n", p.Outlet, result)
+	s.Printf("PDU %s powered %s\n", p.outletLabel(), result)
 
 	return nil
 }
 
-// report queries the outlet state via the backend and reports it to the client.
+// report queries the state of every outlet via the backend and reports them to
+// the client, one line per outlet.
 func (p *PDU) report(ctx context.Context, s module.Session) error {
-	current, err := p.backend.outletState(ctx, p.Outlet)
+	states, err := p.states(ctx)
 	if err != nil {
 		return err
 	}
 
-	log.FromContext(ctx).Debug("power state queried", "outlet", p.Outlet, "state", current)
-	s.Printf("PDU outlet %d state: %s\n", p.Outlet, current)
+	out := strings.Builder{}
+
+	for i, o := range p.sequence() {
+		log.FromContext(ctx).Debug("power state queried", "outlet", o.index, "state", states[i])
+		fmt.Fprintf(&out, "PDU outlet %d state: %s\n", o.index, states[i])
+	}
+
+	s.Printf("%s", out.String())
 
 	return nil
 }
 
-// backend abstracts a vendor-specific PDU HTTP API. Implementations are chosen
-// by newBackend and carry everything they need to reach the device, so the PDU
-// module can drive any supported vendor through the same two operations.
+// PowerOn switches the outlets on.
+func (p *PDU) PowerOn(ctx context.Context) error {
+	return p.switchPower(ctx, turnOn)
+}
+
+// PowerOff switches the outlets off.
+func (p *PDU) PowerOff(ctx context.Context) error {
+	return p.switchPower(ctx, turnOff)
+}
+
+// PowerState reports the state of the outlets. It is unknown if some outlets
+// are on and others off.
+func (p *PDU) PowerState(ctx context.Context) (module.PowerState, error) {
+	if p.backend == nil {
+		return module.PowerUnknown, fmt.Errorf("PDU backend not initialized")
+	}
+
+	states, err := p.states(ctx)
+	if err != nil {
+		return module.PowerUnknown, err
+	}
+
+	switch {
+	case !slices.Contains(states, off):
+		return module.PowerOn, nil
+	case !slices.Contains(states, on):
+		return module.PowerOff, nil
+	default:
+		return module.PowerUnknown, nil
+	}
+}
+
+// switchPower applies act to the outlets on behalf of the power controller.
+func (p *PDU) switchPower(ctx context.Context, act action) error {
+	if p.backend == nil {
+		return fmt.Errorf("PDU backend not initialized")
+	}
+
+	_, err := p.setPower(ctx, act)
+
+	return err
+}
+
+// setPower applies act to the outlets via the backend. A single outlet is
+// toggled natively; several outlets are switched off if any of them is on, and
+// on otherwise. If the outlets were switched on, it runs the power-good check
+// before returning.
+func (p *PDU) setPower(ctx context.Context, act action) (state, error) {
+	if act == toggle && len(p.sequence()) > 1 {
+		states, err := p.states(ctx)
+		if err != nil {
+			return off, err
+		}
+
+		act = turnOn
+		if slices.Contains(states, on) {
+			act = turnOff
+		}
+	}
+
+	result, err := p.switchOutlets(ctx, act)
+	if err != nil {
+		return off, err
+	}
+
+	if result == on {
+		err = p.checkPowerGood(ctx)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// backend abstracts a vendor-specific PDU API. Implementations are created by
+// the factory of their backendRecord and carry everything they need to reach the
+// device, so the PDU module can drive any supported vendor through the same two
+// operations.
 type backend interface {
 	// setPower applies the action to the outlet and returns the resulting state.
 	// Backends without a native toggle implement it as a read-modify-write.
@@ -253,8 +395,81 @@ type backend interface {
 	outletState(ctx context.Context, outlet int) (state, error)
 }
 
+// backendConfig is the part of the module configuration a backend factory
+// needs to reach the device.
+type backendConfig struct {
+	host      string     // host is the Host option as configured.
+	community string     // community is the Community option as configured.
+	req       *requester // req performs HTTP requests with the configured credentials.
+}
+
+// backendRecord holds the information required to register a PDU backend, the
+// way module.Record does for modules.
+type backendRecord struct {
+	// vendor is the unique name of the backend, selected via the "vendor" option.
+	vendor string
+	// new creates the backend from the module configuration. It validates the
+	// options the backend uses, so a misconfiguration is reported at Init.
+	new func(cfg backendConfig) (backend, error)
+}
+
+// backends holds the registered PDU backends by vendor.
+//
+//nolint:gochecknoglobals // the registry is filled by the init functions of the backends
+var backends = make(map[string]backendRecord)
+
+// registerBackend registers a PDU backend. It is meant to be called from the
+// init function of the backend's file, so a misuse is a programming error
+// surfaced at startup, like with module.Register.
+//
+// registerBackend panics if r.vendor is empty, if r.new is nil, or if a backend
+// for the same vendor is already registered.
+func registerBackend(r backendRecord) {
+	if r.vendor == "" {
+		panic("PDU backend vendor missing")
+	}
+
+	if r.new == nil {
+		panic("missing PDU backend factory function")
+	}
+
+	if _, ok := backends[r.vendor]; ok {
+		panic(fmt.Sprintf("PDU backend already registered: %s", r.vendor))
+	}
+
+	backends[r.vendor] = r
+}
+
+// newBackend creates the backend registered for vendor. An empty vendor selects
+// the Intellinet backend.
+//
 //nolint:ireturn // factory returns different backends behind one interface for vendor polymorphism.
-func newBackend(vendor string, req *requester, host string) (backend, error) {
+func newBackend(vendor string, cfg backendConfig) (backend, error) {
+	if vendor == "" {
+		vendor = vendorIntellinet // An empty vendor keeps legacy configs on the Intellinet API.
+	}
+
+	r, ok := backends[vendor]
+	if !ok {
+		return nil, fmt.Errorf("unknown PDU vendor %q (supported: %s)", vendor, vendorList())
+	}
+
+	return r.new(cfg)
+}
+
+// vendorList returns the registered vendors as a sorted, comma-separated string.
+func vendorList() string {
+	vendors := slices.Sorted(maps.Keys(backends))
+	for i, v := range vendors {
+		vendors[i] = strconv.Quote(v)
+	}
+
+	return strings.Join(vendors, ", ")
+}
+
+// httpBase parses the Host option of an HTTP backend, which must be an absolute
+// URL including the scheme.
+func httpBase(host string) (*url.URL, error) {
 	base, err := url.Parse(host)
 	if err != nil {
 		return nil, fmt.Errorf("invalid PDU host %q: %w", host, err)
@@ -264,17 +479,10 @@ func newBackend(vendor string, req *requester, host string) (backend, error) {
 		return nil, fmt.Errorf("invalid PDU host %q: must be an absolute URL including scheme (e.g. http://10.0.0.5)", host)
 	}
 
-	switch vendor {
-	case "", vendorIntellinet: // An empty vendor keeps legacy configs on the Intellinet API.
-		return intellinet{req: req, base: base}, nil
-	case vendorGude:
-		return gude{req: req, base: base}, nil
-	default:
-		return nil, fmt.Errorf("unknown PDU vendor %q (supported: %q, %q)", vendor, vendorIntellinet, vendorGude)
-	}
+	return base, nil
 }
 
-// requester performs authenticated GET requests against a PDU's HTTP API. It
+// requester performs authenticated requests against a PDU's HTTP API. It
 // carries the credentials so vendor backends need not repeat the request setup.
 type requester struct {
 	client   *http.Client
@@ -286,15 +494,34 @@ type requester struct {
 // response and must close its Body; on any error the returned response is nil (its
 // body already closed if one existed). A non-200 status is reported as an error.
 func (r *requester) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	return r.do(ctx, http.MethodGet, endpoint, nil)
+}
+
+// postJSON issues an authenticated POST of the JSON body against endpoint. The
+// response is handled like the one of get.
+func (r *requester) postJSON(ctx context.Context, endpoint string, body []byte) (*http.Response, error) {
+	return r.do(ctx, http.MethodPost, endpoint, body)
+}
+
+func (r *requester) do(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
 	authenticated := r.user != "" && r.password != ""
 
-	log.FromContext(ctx).Debug("GET "+endpoint, "auth", authenticated)
+	log.FromContext(ctx).Debug(method+" "+endpoint, "auth", authenticated)
+
+	var content io.Reader
+	if body != nil {
+		content = bytes.NewReader(body)
+	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, content)
 	if err != nil {
 		return nil, err
 	}
 
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
 	if authenticated {
 		request.SetBasicAuth(r.user, r.password)
 	}
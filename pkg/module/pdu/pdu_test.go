@@ -6,10 +6,12 @@ package pdu
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,6 +34,46 @@ func TestBackendSelection(t *testing.T) {
 			options: "vendor: gude\nhost: http://10.0.0.1\noutlet: 0\n",
 			want:    gude{},
 		},
+		{
+			name:    "netio",
+			options: "vendor: netio\nhost: http://10.0.0.1\noutlet: 0\n",
+			want:    netio{},
+		},
+		{
+			name:    "apc",
+			options: "vendor: apc\nhost: 10.0.0.1\ncommunity: lab\noutlet: 0\n",
+			want:    apc{},
+		},
+		{
+			name:    "HTTP backend rejects a host without scheme",
+			options: "vendor: netio\nhost: 10.0.0.1\n",
+			initErr: true,
+		},
+		{
+			name:    "SNMP backend rejects a URL host",
+			options: "vendor: apc\nhost: http://10.0.0.1\n",
+			initErr: true,
+		},
+		{
+			name:    "power-good check with a metering backend",
+			options: "vendor: gude\nhost: http://10.0.0.1\nmincurrent: 0.1\nsettle: 3s\n",
+			want:    gude{},
+		},
+		{
+			name:    "power-good check needs a metering backend",
+			options: "vendor: intellinet\nhost: http://10.0.0.1\nmincurrent: 0.1\n",
+			initErr: true,
+		},
+		{
+			name:    "negative mincurrent is rejected",
+			options: "vendor: gude\nhost: http://10.0.0.1\nmincurrent: -1\n",
+			initErr: true,
+		},
+		{
+			name:    "invalid settle time is rejected",
+			options: "vendor: gude\nhost: http://10.0.0.1\nmincurrent: 0.1\nsettle: soon\n",
+			initErr: true,
+		},
 		{
 			name:    "legacy config without vendor defaults to intellinet",
 			options: "host: http://10.0.0.1\noutlet: 0\n",
@@ -93,11 +135,40 @@ func typeName(b backend) string {
 		return "intellinet"
 	case gude:
 		return "gude"
+	case netio:
+		return "netio"
+	case apc:
+		return "apc"
 	default:
 		return "nil"
 	}
 }
 
+func TestRegisterBackend(t *testing.T) {
+	newFake := func(backendConfig) (backend, error) { return &fakeBackend{}, nil }
+
+	tests := []struct {
+		name string
+		r    backendRecord
+	}{
+		{name: "empty vendor", r: backendRecord{new: newFake}},
+		{name: "missing factory", r: backendRecord{vendor: "acme"}},
+		{name: "duplicate vendor", r: backendRecord{vendor: vendorGude, new: newFake}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("registerBackend() did not panic")
+				}
+			}()
+
+			registerBackend(tt.r)
+		})
+	}
+}
+
 func TestParseAction(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -250,3 +321,74 @@ func TestRequesterGet(t *testing.T) {
 		}
 	})
 }
+
+// fakeBackend is a backend keeping the outlet state in memory.
+type fakeBackend struct {
+	current state
+	err     error
+}
+
+func (f *fakeBackend) setPower(_ context.Context, _ int, act action) (state, error) {
+	if f.err != nil {
+		return off, f.err
+	}
+
+	switch act {
+	case turnOn:
+		f.current = on
+	case turnOff:
+		f.current = off
+	case toggle:
+		f.current = 1 - f.current
+	}
+
+	return f.current, nil
+}
+
+func (f *fakeBackend) outletState(_ context.Context, _ int) (state, error) {
+	return f.current, f.err
+}
+
+func TestPowerController(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeBackend{current: off}
+	p := &PDU{backend: fake}
+
+	if err := p.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+
+	if got, err := p.PowerState(ctx); err != nil || got != module.PowerOn {
+		t.Errorf("PowerState after PowerOn = %v, %v, want on", got, err)
+	}
+
+	if err := p.PowerOff(ctx); err != nil {
+		t.Fatalf("PowerOff: %v", err)
+	}
+
+	if got, err := p.PowerState(ctx); err != nil || got != module.PowerOff {
+		t.Errorf("PowerState after PowerOff = %v, %v, want off", got, err)
+	}
+
+	fake.err = errors.New("connection refused")
+
+	if err := p.PowerOn(ctx); err == nil {
+		t.Error("PowerOn: expected error from the backend")
+	}
+
+	if got, err := p.PowerState(ctx); err == nil || got != module.PowerUnknown {
+		t.Errorf("PowerState = %v, %v, want unknown and an error", got, err)
+	}
+}
+
+func TestPowerControllerUninitialized(t *testing.T) {
+	p := &PDU{}
+
+	if err := p.PowerOn(context.Background()); err == nil {
+		t.Error("PowerOn: expected error before Init")
+	}
+
+	if _, err := p.PowerState(context.Background()); err == nil {
+		t.Error("PowerState: expected error before Init")
+	}
+}
@@ -0,0 +1,485 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+)
+
+// SNMP message encoding (RFC 1157), limited to what reading and writing an
+// integer object needs. Values are BER encoded as tag, length and content.
+const (
+	berInteger     byte = 0x02
+	berOctetString byte = 0x04
+	berNull        byte = 0x05
+	berOID         byte = 0x06
+	berSequence    byte = 0x30
+
+	snmpGetRequest  byte = 0xa0
+	snmpGetResponse byte = 0xa2
+	snmpSetRequest  byte = 0xa3
+
+	snmpVersion1 = 0 // the version field of SNMPv1 messages
+)
+
+const (
+	defaultSNMPPort      = "161"
+	defaultSNMPCommunity = "private"       // the factory default write community of most PDUs
+	snmpResendInterval   = 1 * time.Second // time to wait for a response before sending the request again
+	snmpMaxMessageSize   = 1472            // a message fits a single UDP datagram on Ethernet
+	snmpRequestIDMask    = 0x7fffffff      // keep request IDs positive, some agents mishandle negative ones
+)
+
+// snmpErrorStatus are the names of the SNMPv1 error-status values.
+//
+//nolint:gochecknoglobals // lookup table of the protocol
+var snmpErrorStatus = []string{"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr"}
+
+// snmpClient is a minimal SNMPv1 client that reads and writes integer objects,
+// which is all switching PDU outlets needs.
+type snmpClient struct {
+	addr      string        // addr is the host:port of the SNMP agent.
+	community string        // community authenticates get and set requests.
+	timeout   time.Duration // timeout bounds a request when ctx has no deadline.
+}
+
+// newSNMPClient creates a client for the agent at host, given as host or
+// host:port. The default port is 161, the default community "private".
+func newSNMPClient(host, community string) (*snmpClient, error) {
+	if host == "" || strings.Contains(host, "/") {
+		return nil, fmt.Errorf("invalid PDU host %q: must be host or host:port of the SNMP agent (e.g. 10.0.0.5)", host)
+	}
+
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, defaultSNMPPort)
+	}
+
+	if community == "" {
+		community = defaultSNMPCommunity
+	}
+
+	return &snmpClient{addr: addr, community: community, timeout: defaultTimeout}, nil
+}
+
+// get reads the integer object oid.
+func (c *snmpClient) get(ctx context.Context, oid string) (int, error) {
+	return c.request(ctx, snmpMessage{pduType: snmpGetRequest, oid: oid, valueType: berNull})
+}
+
+// set writes value to the integer object oid and returns the value the agent
+// reports back.
+func (c *snmpClient) set(ctx context.Context, oid string, value int) (int, error) {
+	return c.request(ctx, snmpMessage{pduType: snmpSetRequest, oid: oid, valueType: berInteger, value: value})
+}
+
+// request sends the request and waits for the response. A request without a
+// response is sent again every snmpResendInterval, as UDP does not guarantee
+// delivery, until the timeout or the deadline of ctx.
+func (c *snmpClient) request(ctx context.Context, req snmpMessage) (int, error) {
+	req.community = c.community
+	req.requestID = int(rand.Uint32() & snmpRequestIDMask) //nolint:gosec // request IDs only match responses
+
+	msg, err := req.encode()
+	if err != nil {
+		return 0, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+
+	dialer := net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "udp", c.addr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach SNMP agent %s: %w", c.addr, err)
+	}
+	defer conn.Close()
+
+	log.FromContext(ctx).Debug("SNMP request", "agent", c.addr, "oid", req.oid, "set", req.pduType == snmpSetRequest)
+
+	buf := make([]byte, snmpMaxMessageSize)
+
+	for {
+		_, err = conn.Write(msg)
+		if err != nil {
+			return 0, fmt.Errorf("failed to send SNMP request to %s: %w", c.addr, err)
+		}
+
+		readDeadline := time.Now().Add(snmpResendInterval)
+		if readDeadline.After(deadline) {
+			readDeadline = deadline
+		}
+
+		_ = conn.SetReadDeadline(readDeadline)
+
+		result, err := c.await(conn, buf, req.requestID)
+		if err == nil {
+			return result, nil
+		}
+
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			return 0, err
+		}
+
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		if !time.Now().Before(deadline) {
+			return 0, fmt.Errorf("no response from SNMP agent %s", c.addr)
+		}
+	}
+}
+
+// await reads responses until the one to requestID arrives, discarding late
+// responses to earlier requests.
+func (c *snmpClient) await(conn net.Conn, buf []byte, requestID int) (int, error) {
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := decodeSNMPMessage(buf[:n])
+		if err != nil {
+			return 0, fmt.Errorf("invalid SNMP response from %s: %w", c.addr, err)
+		}
+
+		if res.pduType != snmpGetResponse || res.requestID != requestID {
+			continue
+		}
+
+		if res.errorStatus != 0 {
+			return 0, fmt.Errorf("SNMP agent %s reported %s for %s", c.addr, snmpErrorName(res.errorStatus), res.oid)
+		}
+
+		if res.valueType != berInteger {
+			return 0, fmt.Errorf("SNMP object %s is not an integer (type %#02x)", res.oid, res.valueType)
+		}
+
+		return res.value, nil
+	}
+}
+
+// snmpErrorName returns the name of an SNMPv1 error-status.
+func snmpErrorName(status int) string {
+	if status < 0 || status >= len(snmpErrorStatus) {
+		return "error " + strconv.Itoa(status)
+	}
+
+	return snmpErrorStatus[status]
+}
+
+// snmpMessage is an SNMPv1 message carrying a single variable binding.
+type snmpMessage struct {
+	community   string
+	pduType     byte
+	requestID   int
+	errorStatus int
+	oid         string
+	valueType   byte // valueType is berInteger, or berNull for the value of a get request.
+	value       int  // value is the value of an integer object.
+}
+
+// encode encodes the message. Only integer and NULL values are supported.
+func (m snmpMessage) encode() ([]byte, error) {
+	name, err := encodeOID(m.oid)
+	if err != nil {
+		return nil, err
+	}
+
+	var val []byte
+
+	switch m.valueType {
+	case berNull:
+		val = berTLV(berNull, nil)
+	case berInteger:
+		val = berTLV(berInteger, encodeInteger(m.value))
+	default:
+		return nil, fmt.Errorf("unsupported SNMP value type %#02x", m.valueType)
+	}
+
+	varbind := berTLV(berSequence, concat(berTLV(berOID, name), val))
+	pdu := berTLV(m.pduType, concat(
+		berTLV(berInteger, encodeInteger(m.requestID)),
+		berTLV(berInteger, encodeInteger(m.errorStatus)),
+		berTLV(berInteger, encodeInteger(0)), // error-index, ignored by this client
+		berTLV(berSequence, varbind),
+	))
+
+	return berTLV(berSequence, concat(
+		berTLV(berInteger, encodeInteger(snmpVersion1)),
+		berTLV(berOctetString, []byte(m.community)),
+		pdu,
+	)), nil
+}
+
+// decodeSNMPMessage decodes an SNMPv1 message with a single variable binding.
+func decodeSNMPMessage(data []byte) (snmpMessage, error) {
+	var msg snmpMessage
+
+	content, err := berExpect(data, berSequence)
+	if err != nil {
+		return msg, err
+	}
+
+	version, content, err := berInt(content)
+	if err != nil {
+		return msg, err
+	}
+
+	if version != snmpVersion1 {
+		return msg, fmt.Errorf("unsupported SNMP version %d", version)
+	}
+
+	tag, community, content, err := berNext(content)
+	if err != nil || tag != berOctetString {
+		return msg, errors.New("missing community")
+	}
+
+	msg.community = string(community)
+
+	msg.pduType, content, _, err = berNext(content)
+	if err != nil {
+		return msg, err
+	}
+
+	msg.requestID, content, err = berInt(content)
+	if err != nil {
+		return msg, err
+	}
+
+	msg.errorStatus, content, err = berInt(content)
+	if err != nil {
+		return msg, err
+	}
+
+	_, content, err = berInt(content) // error-index
+	if err != nil {
+		return msg, err
+	}
+
+	varbinds, err := berExpect(content, berSequence)
+	if err != nil {
+		return msg, err
+	}
+
+	varbind, err := berExpect(varbinds, berSequence)
+	if err != nil {
+		return msg, err
+	}
+
+	tag, name, rest, err := berNext(varbind)
+	if err != nil || tag != berOID {
+		return msg, errors.New("missing object name")
+	}
+
+	msg.oid = decodeOID(name)
+
+	tag, value, _, err := berNext(rest)
+	if err != nil {
+		return msg, errors.New("missing value")
+	}
+
+	msg.valueType = tag
+	if tag == berInteger {
+		msg.value = decodeInteger(value)
+	}
+
+	return msg, nil
+}
+
+// berTLV encodes a value of the type tag.
+func berTLV(tag byte, content []byte) []byte {
+	out := []byte{tag}
+
+	const shortForm = 0x80 // lengths below are encoded in a single byte
+
+	if len(content) < shortForm {
+		out = append(out, byte(len(content)))
+	} else {
+		var length []byte
+		for n := len(content); n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+
+		out = append(out, shortForm|byte(len(length)))
+		out = append(out, length...)
+	}
+
+	return append(out, content...)
+}
+
+// berNext splits the first value off data, returning its tag and content.
+func berNext(data []byte) (tag byte, content, rest []byte, err error) {
+	const header = 2 // tag and length
+
+	if len(data) < header {
+		return 0, nil, nil, errors.New("truncated message")
+	}
+
+	tag = data[0]
+	length := int(data[1])
+	data = data[header:]
+
+	if length&0x80 != 0 {
+		n := length &^ 0x80
+		if n == 0 || n > 4 || len(data) < n {
+			return 0, nil, nil, errors.New("invalid length")
+		}
+
+		length = 0
+		for _, b := range data[:n] {
+			length = length<<8 | int(b)
+		}
+
+		data = data[n:]
+	}
+
+	if length > len(data) {
+		return 0, nil, nil, errors.New("truncated message")
+	}
+
+	return tag, data[:length], data[length:], nil
+}
+
+// berExpect returns the content of the first value of data, which must be of
+// the type tag.
+func berExpect(data []byte, tag byte) ([]byte, error) {
+	got, content, _, err := berNext(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if got != tag {
+		return nil, fmt.Errorf("unexpected type %#02x, want %#02x", got, tag)
+	}
+
+	return content, nil
+}
+
+// berInt splits the first value off data, which must be an integer.
+func berInt(data []byte) (int, []byte, error) {
+	tag, content, rest, err := berNext(data)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if tag != berInteger {
+		return 0, nil, fmt.Errorf("unexpected type %#02x, want an integer", tag)
+	}
+
+	return decodeInteger(content), rest, nil
+}
+
+// encodeInteger encodes v in the fewest two's complement bytes.
+func encodeInteger(v int) []byte {
+	out := []byte{byte(v)}
+
+	for v > 0x7f || v < -0x80 {
+		v >>= 8
+		out = append([]byte{byte(v)}, out...)
+	}
+
+	return out
+}
+
+// decodeInteger decodes a two's complement integer.
+func decodeInteger(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+
+	v := int(int8(content[0]))
+	for _, b := range content[1:] {
+		v = v<<8 | int(b)
+	}
+
+	return v
+}
+
+// encodeOID encodes a dotted object identifier like 1.3.6.1.2.1.1.
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 { //nolint:mnd // the first two arcs are encoded together
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+
+	arcs := make([]uint64, len(parts))
+
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+
+		arcs[i] = arc
+	}
+
+	const firstArcs = 40 // the first two arcs x.y are encoded as 40x+y
+
+	out := []byte{}
+
+	for _, arc := range append([]uint64{arcs[0]*firstArcs + arcs[1]}, arcs[2:]...) {
+		chunk := []byte{byte(arc & 0x7f)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			chunk = append([]byte{byte(arc&0x7f) | 0x80}, chunk...)
+		}
+
+		out = append(out, chunk...)
+	}
+
+	return out, nil
+}
+
+// decodeOID decodes an object identifier into its dotted form.
+func decodeOID(content []byte) string {
+	const firstArcs = 40
+
+	var (
+		arcs []string
+		arc  uint64
+	)
+
+	for _, b := range content {
+		arc = arc<<7 | uint64(b&0x7f)
+		if b&0x80 != 0 {
+			continue
+		}
+
+		if arcs == nil {
+			first := min(arc/firstArcs, 2) //nolint:mnd // the first arc is 0, 1 or 2
+			arcs = append(arcs, strconv.FormatUint(first, 10), strconv.FormatUint(arc-first*firstArcs, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(arc, 10))
+		}
+
+		arc = 0
+	}
+
+	return strings.Join(arcs, ".")
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+
+	return out
+}
@@ -0,0 +1,326 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdu
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAgent is a local SNMPv1 agent holding integer objects. It answers get and
+// set requests with the given community and ignores others, like real agents.
+type fakeAgent struct {
+	conn      net.PacketConn
+	community string
+
+	mu      sync.Mutex
+	objects map[string]int
+	sets    []snmpMessage // sets are the set requests received
+	drop    int           // drop is the number of requests to leave unanswered
+}
+
+func newFakeAgent(t *testing.T, community string, objects map[string]int) *fakeAgent {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	a := &fakeAgent{conn: conn, community: community, objects: objects}
+
+	go a.serve()
+
+	t.Cleanup(func() { conn.Close() })
+
+	return a
+}
+
+func (a *fakeAgent) addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *fakeAgent) serve() {
+	buf := make([]byte, snmpMaxMessageSize)
+
+	for {
+		n, from, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		req, err := decodeSNMPMessage(buf[:n])
+		if err != nil || req.community != a.community {
+			continue
+		}
+
+		res, ok := a.handle(req)
+		if !ok {
+			continue
+		}
+
+		msg, err := res.encode()
+		if err != nil {
+			continue
+		}
+
+		_, _ = a.conn.WriteTo(msg, from)
+	}
+}
+
+func (a *fakeAgent) handle(req snmpMessage) (snmpMessage, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.drop > 0 {
+		a.drop--
+
+		return snmpMessage{}, false
+	}
+
+	res := req
+	res.pduType = snmpGetResponse
+
+	value, ok := a.objects[req.oid]
+	if !ok {
+		res.errorStatus = 2 // noSuchName
+
+		return res, true
+	}
+
+	if req.pduType == snmpSetRequest {
+		a.sets = append(a.sets, req)
+		a.objects[req.oid] = req.value
+		value = req.value
+	}
+
+	res.valueType = berInteger
+	res.value = value
+
+	return res, true
+}
+
+func (a *fakeAgent) setRequests() []snmpMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]snmpMessage(nil), a.sets...)
+}
+
+func TestBERInteger(t *testing.T) {
+	tests := []struct {
+		value int
+		want  []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{256, []byte{0x01, 0x00}},
+		{-1, []byte{0xff}},
+		{-129, []byte{0xff, 0x7f}},
+		{0x7fffffff, []byte{0x7f, 0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range tests {
+		got := encodeInteger(tt.value)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeInteger(%d) = % x, want % x", tt.value, got, tt.want)
+		}
+
+		if back := decodeInteger(got); back != tt.value {
+			t.Errorf("decodeInteger(% x) = %d, want %d", got, back, tt.value)
+		}
+	}
+}
+
+func TestBEROID(t *testing.T) {
+	got, err := encodeOID("1.3.6.1.4.1.318.1.1.4.4.2.1.3.8")
+	if err != nil {
+		t.Fatalf("encodeOID: %v", err)
+	}
+
+	want := []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x3e, 0x01, 0x01, 0x04, 0x04, 0x02, 0x01, 0x03, 0x08}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encodeOID() = % x, want % x", got, want)
+	}
+
+	if back := decodeOID(got); back != "1.3.6.1.4.1.318.1.1.4.4.2.1.3.8" {
+		t.Errorf("decodeOID() = %q", back)
+	}
+
+	for _, oid := range []string{"", "1", "1.3.x"} {
+		if _, err := encodeOID(oid); err == nil {
+			t.Errorf("encodeOID(%q) expected error", oid)
+		}
+	}
+}
+
+func TestBERLongLength(t *testing.T) {
+	content := bytes.Repeat([]byte{0xaa}, 300)
+	encoded := berTLV(berOctetString, content)
+
+	if !bytes.Equal(encoded[:4], []byte{berOctetString, 0x82, 0x01, 0x2c}) {
+		t.Errorf("header = % x, want 04 82 01 2c", encoded[:4])
+	}
+
+	tag, got, rest, err := berNext(encoded)
+	if err != nil || tag != berOctetString || !bytes.Equal(got, content) || len(rest) != 0 {
+		t.Errorf("berNext() = %#02x, %d bytes, %d rest, %v", tag, len(got), len(rest), err)
+	}
+
+	if _, _, _, err := berNext(encoded[:100]); err == nil {
+		t.Error("berNext() expected error for a truncated value")
+	}
+}
+
+// TestSNMPMessage pins the wire format against a get request as sent by
+// net-snmp's snmpget -v1 -c public.
+func TestSNMPMessage(t *testing.T) {
+	msg := snmpMessage{
+		community: "public",
+		pduType:   snmpGetRequest,
+		requestID: 0x1234,
+		oid:       "1.3.6.1.2.1.1.5.0",
+		valueType: berNull,
+	}
+
+	want := []byte{
+		0x30, 0x27,
+		0x02, 0x01, 0x00,
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x1a,
+		0x02, 0x02, 0x12, 0x34,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00,
+		0x05, 0x00,
+	}
+
+	got, err := msg.encode()
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("encode() =\n% x\nwant\n% x", got, want)
+	}
+
+	back, err := decodeSNMPMessage(got)
+	if err != nil {
+		t.Fatalf("decodeSNMPMessage: %v", err)
+	}
+
+	if back != msg {
+		t.Errorf("decodeSNMPMessage() = %+v, want %+v", back, msg)
+	}
+
+	if _, err := decodeSNMPMessage(got[:20]); err == nil {
+		t.Error("decodeSNMPMessage() expected error for a truncated message")
+	}
+}
+
+func TestNewSNMPClient(t *testing.T) {
+	tests := []struct {
+		host, community string
+		wantAddr        string
+		wantCommunity   string
+		wantErr         bool
+	}{
+		{host: "10.0.0.5", wantAddr: "10.0.0.5:161", wantCommunity: "private"},
+		{host: "10.0.0.5:1161", community: "lab", wantAddr: "10.0.0.5:1161", wantCommunity: "lab"},
+		{host: "pdu.lab", wantAddr: "pdu.lab:161", wantCommunity: "private"},
+		{host: "", wantErr: true},
+		{host: "http://10.0.0.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		c, err := newSNMPClient(tt.host, tt.community)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("newSNMPClient(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+		}
+
+		if err == nil && (c.addr != tt.wantAddr || c.community != tt.wantCommunity) {
+			t.Errorf("newSNMPClient(%q) = %s %s, want %s %s", tt.host, c.addr, c.community, tt.wantAddr, tt.wantCommunity)
+		}
+	}
+}
+
+func TestSNMPClient(t *testing.T) {
+	const oid = "1.3.6.1.4.1.99.1"
+
+	agent := newFakeAgent(t, "private", map[string]int{oid: 7})
+
+	c, err := newSNMPClient(agent.addr(), "")
+	if err != nil {
+		t.Fatalf("newSNMPClient: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if got, err := c.get(ctx, oid); err != nil || got != 7 {
+		t.Errorf("get() = %d, %v, want 7", got, err)
+	}
+
+	if got, err := c.set(ctx, oid, 300); err != nil || got != 300 {
+		t.Errorf("set() = %d, %v, want 300", got, err)
+	}
+
+	if got, err := c.get(ctx, oid); err != nil || got != 300 {
+		t.Errorf("get() after set = %d, %v, want 300", got, err)
+	}
+
+	if _, err := c.get(ctx, oid+".1"); err == nil {
+		t.Error("get() expected error for an unknown object")
+	}
+}
+
+func TestSNMPClientResends(t *testing.T) {
+	const oid = "1.3.6.1.4.1.99.1"
+
+	agent := newFakeAgent(t, "private", map[string]int{oid: 1})
+
+	agent.mu.Lock()
+	agent.drop = 1
+	agent.mu.Unlock()
+
+	c, err := newSNMPClient(agent.addr(), "private")
+	if err != nil {
+		t.Fatalf("newSNMPClient: %v", err)
+	}
+
+	if got, err := c.get(context.Background(), oid); err != nil || got != 1 {
+		t.Errorf("get() = %d, %v, want 1 after a lost request", got, err)
+	}
+}
+
+func TestSNMPClientTimeout(t *testing.T) {
+	// An agent ignores requests with a wrong community.
+	agent := newFakeAgent(t, "secret", map[string]int{"1.3.6.1.4.1.99.1": 1})
+
+	c, err := newSNMPClient(agent.addr(), "private")
+	if err != nil {
+		t.Fatalf("newSNMPClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = c.get(ctx, "1.3.6.1.4.1.99.1")
+	if err == nil {
+		t.Fatal("get() expected error without a response")
+	}
+
+	if elapsed := time.Since(start); elapsed >= snmpResendInterval {
+		t.Errorf("get() returned after %s, want the deadline of ctx to end it", elapsed)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package module
+
+import "context"
+
+// PowerState is the power state of a device-under-test (DUT).
+type PowerState int
+
+const (
+	// PowerUnknown is reported if the state cannot be determined.
+	PowerUnknown PowerState = iota
+	// PowerOff means the DUT is switched off.
+	PowerOff
+	// PowerOn means the DUT is switched on.
+	PowerOn
+)
+
+// String renders a PowerState as "on", "off" or "unknown".
+func (s PowerState) String() string {
+	switch s {
+	case PowerOn:
+		return "on"
+	case PowerOff:
+		return "off"
+	default:
+		return "unknown"
+	}
+}
+
+// PowerController is implemented by modules that switch the power of a DUT, like
+// a PDU outlet, a BMC via IPMI, a WiFi socket or a GPIO wired to a power switch.
+// A module implementing it can be configured as the power controller of a
+// device, which the dutagent drives for the device-level power command, so
+// on, off, cycle and status behave the same whatever the hardware.
+//
+// The methods are called only after a successful Init. Like Run, they receive a
+// context carrying a logger scoped to the module, which is cancelled when the
+// request is aborted. The dutagent serializes the calls for a device, so they
+// are never concurrent.
+type PowerController interface {
+	// PowerOn switches the DUT on. Switching on a DUT that is on already is not
+	// an error.
+	PowerOn(ctx context.Context) error
+	// PowerOff switches the DUT off. Switching off a DUT that is off already is
+	// not an error.
+	PowerOff(ctx context.Context) error
+	// PowerState reports whether the DUT is switched on. A controller that
+	// cannot tell reports PowerUnknown rather than an error.
+	PowerState(ctx context.Context) (PowerState, error)
+}
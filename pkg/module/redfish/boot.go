@@ -0,0 +1,91 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+// bootTargets maps the targets of the boot command to BootSourceOverrideTarget
+// values. The target none clears a pending override.
+//
+//nolint:gochecknoglobals // the table of boot targets
+var bootTargets = map[string]string{
+	"pxe":   "Pxe",
+	"hdd":   "Hdd",
+	"cd":    "Cd",
+	"usb":   "Usb",
+	"bios":  "BiosSetup",
+	"shell": "UefiShell",
+	"http":  "UefiHttp",
+	"none":  "None",
+}
+
+// handleBoot sets a one-time boot source override, or shows the current one
+// without a target.
+func (r *Redfish) handleBoot(ctx context.Context, s module.Session, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("boot takes at most one target")
+	}
+
+	sys, uri, err := r.getSystem(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if sys.Boot.Enabled == "" || sys.Boot.Enabled == "Disabled" {
+			s.Println("No boot override set")
+		} else {
+			s.Printf("Boot override: %s (%s)\n", sys.Boot.Target, sys.Boot.Enabled)
+		}
+
+		return nil
+	}
+
+	name := strings.ToLower(args[0])
+
+	target, ok := bootTargets[name]
+	if !ok {
+		return fmt.Errorf("unknown boot target %q, available targets: %s",
+			args[0], strings.Join(slices.Sorted(maps.Keys(bootTargets)), ", "))
+	}
+
+	if allowed := sys.Boot.AllowableTargets; len(allowed) > 0 && !slices.Contains(allowed, target) {
+		return fmt.Errorf("the BMC does not support boot target %s, supported: %s", target, strings.Join(allowed, ", "))
+	}
+
+	enabled := "Once"
+	if target == "None" {
+		enabled = "Disabled"
+	}
+
+	err = r.client.patch(ctx, uri, map[string]any{
+		"Boot": map[string]string{
+			"BootSourceOverrideTarget":  target,
+			"BootSourceOverrideEnabled": enabled,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("setting boot override failed: %w", err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("boot override %s (%s) set (BMC %s)", target, enabled, r.Host))
+
+	if target == "None" {
+		s.Println("Boot override cleared")
+	} else {
+		s.Printf("Next boot from %s\n", target)
+	}
+
+	return nil
+}
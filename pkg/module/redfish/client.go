@@ -0,0 +1,307 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redfish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+)
+
+const (
+	serviceRoot     = "/redfish/v1"
+	sessionsPath    = serviceRoot + "/SessionService/Sessions"
+	authTokenHeader = "X-Auth-Token"
+)
+
+// errNotFound is returned for a resource the BMC does not have (HTTP 404).
+var errNotFound = errors.New("resource not found")
+
+// client performs requests against the Redfish service of a BMC. It
+// authenticates them with HTTP Basic Auth, or with the token of a Redfish
+// session. The session is created on the first request rather than up front,
+// so an unreachable BMC surfaces as a failing command instead of a failing
+// Init, and it is re-created once if the BMC rejects the token, e.g. because
+// the session timed out between two commands.
+type client struct {
+	http     *http.Client
+	base     *url.URL
+	user     string
+	password string
+	basic    bool // authenticate with HTTP Basic Auth instead of a session
+
+	mu      sync.Mutex
+	token   string // token of the open session, empty if none is open
+	session string // URI of the open session, used to delete it
+}
+
+// get fetches the resource at uri into out.
+func (c *client) get(ctx context.Context, uri string, out any) error {
+	return c.do(ctx, http.MethodGet, uri, nil, out)
+}
+
+// post sends body to uri, typically the target of an action.
+func (c *client) post(ctx context.Context, uri string, body any) error {
+	return c.do(ctx, http.MethodPost, uri, body, nil)
+}
+
+// patch updates the resource at uri with the properties in body.
+func (c *client) patch(ctx context.Context, uri string, body any) error {
+	return c.do(ctx, http.MethodPatch, uri, body, nil)
+}
+
+// do performs an authenticated request. The body, if any, is sent as JSON and
+// a JSON response is decoded into out, if not nil. A response with a status
+// other than 2xx is an error carrying the message of the Redfish error object,
+// if the BMC sent one.
+func (c *client) do(ctx context.Context, method, uri string, body, out any) error {
+	var payload []byte
+
+	if body != nil {
+		var err error
+
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.send(ctx, method, uri, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && !c.basic {
+		// The session most likely expired. Open a new one and retry once.
+		resp.Body.Close()
+		c.dropSession()
+
+		resp, err = c.send(ctx, method, uri, payload)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	}
+
+	err = checkStatus(method, uri, resp)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return fmt.Errorf("%s %s: empty response", method, uri)
+	}
+
+	err = json.Unmarshal(data, out)
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid response: %v", method, uri, err)
+	}
+
+	return nil
+}
+
+// send issues a single request with the credentials applied, opening a session
+// first if needed.
+func (c *client) send(ctx context.Context, method, uri string, payload []byte) (*http.Response, error) {
+	token := ""
+
+	if !c.basic {
+		var err error
+
+		token, err = c.sessionToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.newRequest(ctx, method, uri, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.basic {
+		req.SetBasicAuth(c.user, c.password)
+	} else {
+		req.Header.Set(authTokenHeader, token)
+	}
+
+	log.FromContext(ctx).Debug(method + " " + req.URL.String())
+
+	return c.http.Do(req)
+}
+
+// newRequest builds a request for uri, resolved against the base URL of the BMC.
+func (c *client) newRequest(ctx context.Context, method, uri string, payload []byte) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource URI %q: %v", uri, err)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.base.ResolveReference(ref).String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+// sessionToken returns the token of the open session, opening one if none is.
+func (c *client) sessionToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{"UserName": c.user, "Password": c.password})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, sessionsPath, payload)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("opening Redfish session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = checkStatus(http.MethodPost, sessionsPath, resp)
+	if err != nil {
+		return "", fmt.Errorf("opening Redfish session: %w", err)
+	}
+
+	token := resp.Header.Get(authTokenHeader)
+	if token == "" {
+		return "", errors.New("opening Redfish session: no token in the response")
+	}
+
+	c.token = token
+	c.session = resp.Header.Get("Location")
+
+	log.FromContext(ctx).Debug("Redfish session opened", "session", c.session)
+
+	return c.token, nil
+}
+
+// dropSession forgets the open session, so the next request opens a new one.
+func (c *client) dropSession() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+	c.session = ""
+}
+
+// close deletes the open session, if any. BMCs allow only a few concurrent
+// sessions, so a session is not left to time out.
+func (c *client) close(ctx context.Context) error {
+	c.mu.Lock()
+	token, session := c.token, c.session
+	c.token, c.session = "", ""
+	c.mu.Unlock()
+
+	if token == "" || session == "" {
+		return nil
+	}
+
+	req, err := c.newRequest(ctx, http.MethodDelete, session, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(authTokenHeader, token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("closing Redfish session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// A session that is gone already needs no closing.
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized {
+		return nil
+	}
+
+	return checkStatus(http.MethodDelete, session, resp)
+}
+
+// redfishError is the error object of a Redfish error response.
+type redfishError struct {
+	Error struct {
+		Message      string `json:"message"`
+		ExtendedInfo []struct {
+			Message string `json:"Message"`
+		} `json:"@Message.ExtendedInfo"`
+	} `json:"error"`
+}
+
+// checkStatus returns an error for a response with a status other than 2xx,
+// carrying the most specific message of the Redfish error object, if any.
+func checkStatus(method, uri string, resp *http.Response) error {
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, uri, errNotFound)
+	}
+
+	msg := resp.Status
+
+	data, _ := io.ReadAll(resp.Body)
+
+	var rfErr redfishError
+
+	if json.Unmarshal(data, &rfErr) == nil {
+		details := make([]string, 0, len(rfErr.Error.ExtendedInfo))
+		for _, info := range rfErr.Error.ExtendedInfo {
+			details = append(details, info.Message)
+		}
+
+		switch {
+		case len(details) > 0:
+			msg += ": " + strings.Join(details, "; ")
+		case rfErr.Error.Message != "":
+			msg += ": " + rfErr.Error.Message
+		}
+	}
+
+	return fmt.Errorf("%s %s: %s", method, uri, msg)
+}
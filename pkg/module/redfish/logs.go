@@ -0,0 +1,199 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+const defaultLogCount = 20 // Number of event log entries shown by default.
+
+// logService is the part of a LogService resource used by the module.
+type logService struct {
+	ID           string `json:"Id"`
+	LogEntryType string `json:"LogEntryType"`
+	Entries      link   `json:"Entries"`
+}
+
+// logEntry is the part of a LogEntry resource used by the module.
+type logEntry struct {
+	URI      string `json:"@odata.id"`
+	Created  string `json:"Created"`
+	Severity string `json:"Severity"`
+	Message  string `json:"Message"`
+}
+
+// logEntries is a LogEntryCollection. Unlike most collections, BMCs commonly
+// embed the entries rather than only linking them.
+type logEntries struct {
+	Members  []logEntry `json:"Members"`
+	NextLink string     `json:"Members@odata.nextLink"`
+}
+
+// handleLog shows the latest entries of the system event log, oldest first.
+func (r *Redfish) handleLog(ctx context.Context, s module.Session, args []string) error {
+	count := defaultLogCount
+
+	switch len(args) {
+	case 0:
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid count %q: must be a positive number", args[0])
+		}
+
+		count = n
+	default:
+		return errors.New("usage: log [<count>]")
+	}
+
+	svc, err := r.eventLog(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries, err := r.latestEntries(ctx, svc.Entries.ID, count)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		s.Printf("Event log %s is empty\n", svc.ID)
+
+		return nil
+	}
+
+	out := strings.Builder{}
+	for _, e := range entries {
+		fmt.Fprintf(&out, "%-25s  %-8s  %s\n", e.Created, e.Severity, e.Message)
+	}
+
+	s.Print(out.String())
+
+	return nil
+}
+
+// eventLog finds the log service of the system event log. The SEL is looked for
+// among the log services of the system, then among those of its managers. If
+// there is no SEL, the first log service of the system is taken.
+func (r *Redfish) eventLog(ctx context.Context) (*logService, error) {
+	sys, _, err := r.getSystem(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []string{sys.LogServices.ID}
+
+	for _, ref := range sys.Links.ManagedBy {
+		var m manager
+
+		err = r.client.get(ctx, ref.ID, &m)
+		if err != nil {
+			return nil, fmt.Errorf("reading manager: %w", err)
+		}
+
+		collections = append(collections, m.LogServices.ID)
+	}
+
+	var fallback *logService
+
+	for i, collection := range collections {
+		if collection == "" {
+			continue
+		}
+
+		services, err := r.members(ctx, collection)
+		if err != nil {
+			return nil, fmt.Errorf("listing log services: %w", err)
+		}
+
+		for _, ref := range services {
+			var svc logService
+
+			err = r.client.get(ctx, ref.ID, &svc)
+			if err != nil {
+				return nil, fmt.Errorf("reading log service: %w", err)
+			}
+
+			if svc.LogEntryType == "SEL" || strings.EqualFold(svc.ID, "SEL") {
+				return &svc, nil
+			}
+
+			if i == 0 && fallback == nil {
+				fallback = &svc
+			}
+		}
+	}
+
+	if fallback == nil || fallback.Entries.ID == "" {
+		return nil, errors.New("the BMC offers no event log")
+	}
+
+	return fallback, nil
+}
+
+// latestEntries returns the count most recent entries of the log at uri, oldest
+// first. Entries only linked by the collection are fetched one by one.
+//
+//nolint:cyclop // paging, fetching and ordering of the entries
+func (r *Redfish) latestEntries(ctx context.Context, uri string, count int) ([]logEntry, error) {
+	var entries []logEntry
+
+	for uri != "" {
+		var page logEntries
+
+		err := r.client.get(ctx, uri, &page)
+		if err != nil {
+			return nil, fmt.Errorf("reading event log: %w", err)
+		}
+
+		entries = append(entries, page.Members...)
+		uri = page.NextLink
+	}
+
+	if len(entries) > 0 && entries[0].Created == "" && entries[0].URI != "" {
+		// Fetching a full SEL entry by entry takes thousands of requests, so
+		// only the last ones of the listing are fetched.
+		if len(entries) > count {
+			entries = entries[len(entries)-count:]
+		}
+
+		for i := range entries {
+			err := r.client.get(ctx, entries[i].URI, &entries[i])
+			if err != nil {
+				return nil, fmt.Errorf("reading event log entry: %w", err)
+			}
+		}
+	}
+
+	// BMCs differ in whether they list the newest or the oldest entry first.
+	slices.SortStableFunc(entries, func(a, b logEntry) int {
+		return created(a).Compare(created(b))
+	})
+
+	if len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+
+	return entries, nil
+}
+
+// created returns the creation time of e, or the zero time if it has none.
+func created(e logEntry) time.Time {
+	t, err := time.Parse(time.RFC3339, e.Created)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
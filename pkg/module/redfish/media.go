@@ -0,0 +1,216 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+// virtualMedia is the part of a VirtualMedia resource used by the module.
+type virtualMedia struct {
+	ID         string   `json:"Id"`
+	MediaTypes []string `json:"MediaTypes"`
+	Image      string   `json:"Image"`
+	Inserted   bool     `json:"Inserted"`
+	Actions    struct {
+		Insert actionTarget `json:"#VirtualMedia.InsertMedia"`
+		Eject  actionTarget `json:"#VirtualMedia.EjectMedia"`
+	} `json:"Actions"`
+}
+
+// actionTarget is the URI an action is invoked at.
+type actionTarget struct {
+	Target string `json:"target"`
+}
+
+// manager is the part of a Manager resource used by the module.
+type manager struct {
+	VirtualMedia link `json:"VirtualMedia"`
+	LogServices  link `json:"LogServices"`
+}
+
+// handleMedia inserts an ISO image, ejects it or shows the virtual CD.
+//
+//nolint:cyclop // one case per subcommand
+func (r *Redfish) handleMedia(ctx context.Context, s module.Session, args []string) error {
+	vm, uri, err := r.virtualCD(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == status):
+		if vm.Inserted {
+			s.Printf("Virtual media %s: %s\n", vm.ID, vm.Image)
+		} else {
+			s.Printf("Virtual media %s: empty\n", vm.ID)
+		}
+
+		return nil
+	case len(args) == 2 && args[0] == "insert":
+		err = r.insertMedia(ctx, vm, uri, args[1])
+		if err != nil {
+			return err
+		}
+
+		s.Printf("Inserted %s as virtual media %s\n", args[1], vm.ID)
+
+		return nil
+	case len(args) == 1 && args[0] == "eject":
+		if !vm.Inserted {
+			s.Printf("Virtual media %s is empty\n", vm.ID)
+
+			return nil
+		}
+
+		err = r.ejectMedia(ctx, vm, uri)
+		if err != nil {
+			return err
+		}
+
+		s.Printf("Ejected virtual media %s\n", vm.ID)
+
+		return nil
+	default:
+		return errors.New("usage: media [insert <url>|eject|status]")
+	}
+}
+
+// insertMedia inserts the image at imageURL, ejecting any image inserted before.
+// BMCs without the InsertMedia action take the image as a property update.
+func (r *Redfish) insertMedia(ctx context.Context, vm *virtualMedia, uri, imageURL string) error {
+	image, err := url.Parse(imageURL)
+	if err != nil || image.Scheme == "" || image.Host == "" {
+		return fmt.Errorf("invalid image URL %q: must be an absolute URL the BMC can reach", imageURL)
+	}
+
+	if vm.Inserted {
+		err = r.ejectMedia(ctx, vm, uri)
+		if err != nil {
+			return err
+		}
+	}
+
+	if vm.Actions.Insert.Target != "" {
+		err = r.client.post(ctx, vm.Actions.Insert.Target, map[string]any{
+			"Image":          imageURL,
+			"Inserted":       true,
+			"WriteProtected": true,
+		})
+	} else {
+		err = r.client.patch(ctx, uri, map[string]any{"Image": imageURL, "Inserted": true})
+	}
+
+	if err != nil {
+		return fmt.Errorf("inserting virtual media failed: %w", err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("virtual media %s inserted: %s (BMC %s)", vm.ID, imageURL, r.Host))
+
+	return nil
+}
+
+// ejectMedia ejects the inserted image. BMCs without the EjectMedia action take
+// the ejection as a property update.
+func (r *Redfish) ejectMedia(ctx context.Context, vm *virtualMedia, uri string) error {
+	var err error
+
+	if vm.Actions.Eject.Target != "" {
+		err = r.client.post(ctx, vm.Actions.Eject.Target, map[string]any{})
+	} else {
+		err = r.client.patch(ctx, uri, map[string]any{"Image": nil, "Inserted": false})
+	}
+
+	if err != nil {
+		return fmt.Errorf("ejecting virtual media failed: %w", err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("virtual media %s ejected (BMC %s)", vm.ID, r.Host))
+
+	return nil
+}
+
+// virtualCD finds the virtual media slot for CD/DVD images. Newer BMCs list
+// virtual media at the system, older ones at the manager of the system. A slot
+// without media types is taken if there is no dedicated CD/DVD slot.
+func (r *Redfish) virtualCD(ctx context.Context) (*virtualMedia, string, error) {
+	collection, err := r.mediaCollection(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if collection == "" {
+		return nil, "", errors.New("the BMC offers no virtual media")
+	}
+
+	slots, err := r.members(ctx, collection)
+	if err != nil {
+		return nil, "", fmt.Errorf("listing virtual media: %w", err)
+	}
+
+	var (
+		fallback    *virtualMedia
+		fallbackURI string
+	)
+
+	for _, slot := range slots {
+		var vm virtualMedia
+
+		err = r.client.get(ctx, slot.ID, &vm)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading virtual media: %w", err)
+		}
+
+		if slices.Contains(vm.MediaTypes, "CD") || slices.Contains(vm.MediaTypes, "DVD") {
+			return &vm, slot.ID, nil
+		}
+
+		if len(vm.MediaTypes) == 0 && fallback == nil {
+			fallback, fallbackURI = &vm, slot.ID
+		}
+	}
+
+	if fallback == nil {
+		return nil, "", errors.New("the BMC offers no virtual media for CD/DVD images")
+	}
+
+	return fallback, fallbackURI, nil
+}
+
+// mediaCollection returns the URI of the VirtualMedia collection of the system
+// or, if the system has none, of the first of its managers that has one. It
+// returns an empty URI if neither has virtual media.
+func (r *Redfish) mediaCollection(ctx context.Context) (string, error) {
+	sys, _, err := r.getSystem(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if sys.VirtualMedia.ID != "" {
+		return sys.VirtualMedia.ID, nil
+	}
+
+	for _, ref := range sys.Links.ManagedBy {
+		var m manager
+
+		err = r.client.get(ctx, ref.ID, &m)
+		if err != nil {
+			return "", fmt.Errorf("reading manager: %w", err)
+		}
+
+		if m.VirtualMedia.ID != "" {
+			return m.VirtualMedia.ID, nil
+		}
+	}
+
+	return "", nil
+}
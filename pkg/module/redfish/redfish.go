@@ -0,0 +1,426 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package redfish provides a dutagent module that manages a DUT via the Redfish service of its BMC.
+package redfish
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func init() {
+	module.Register(module.Record{
+		ID: "redfish",
+		New: func() module.Module {
+			return &Redfish{}
+		},
+	})
+}
+
+// Authentication schemes, selected via the "auth" configuration option.
+const (
+	authSession = "session"
+	authBasic   = "basic"
+)
+
+const defaultTimeout = 30 * time.Second // Default timeout for a single Redfish request.
+
+const (
+	on       = "on"
+	off      = "off"
+	shutdown = "shutdown"
+	cycle    = "cycle"
+	reset    = "reset"
+	status   = "status"
+	boot     = "boot"
+	media    = "media"
+	logs     = "log"
+)
+
+// resetTypes maps the power commands to the ResetType of ComputerSystem.Reset.
+//
+//nolint:gochecknoglobals // the table of power commands
+var resetTypes = map[string]string{
+	on:       "On",
+	off:      "ForceOff",
+	shutdown: "GracefulShutdown",
+	cycle:    "PowerCycle",
+	reset:    "ForceRestart",
+}
+
+// Redfish is a module that manages a DUT via the Redfish service of its BMC: it
+// controls the power, overrides the boot source for the next boot, inserts and
+// ejects virtual media and reads the system event log.
+type Redfish struct {
+	Host     string // Host is the base URL of the BMC, e.g. https://10.0.0.5. Without a scheme, HTTPS is used.
+	User     string // User is used for Redfish authentication
+	Password string // Password is used for Redfish authentication. WARNING: stored unsafely as plaintext.
+	Auth     string // Auth selects the authentication: "session" (default) or "basic"
+	System   string // System is the Id of the ComputerSystem to manage. Default: the only system of the BMC
+	Insecure bool   // Insecure skips the verification of the BMC's TLS certificate
+	Timeout  string // Timeout is the duration for a single Redfish request. Default: 30 seconds
+
+	client *client // client talks to the BMC; set in Init
+	system string  // system is the URI of the managed ComputerSystem; resolved on first use
+}
+
+// Ensure implementing the Module and PowerController interfaces.
+var (
+	_ module.Module          = &Redfish{}
+	_ module.PowerController = &Redfish{}
+)
+
+func (r *Redfish) Help() string {
+	help := strings.Builder{}
+	help.WriteString("Redfish BMC Management Module\n")
+	help.WriteString("\nUsage:\n")
+	help.WriteString("  redfish [on|off|shutdown|cycle|reset|status]\n")
+	help.WriteString("  redfish boot [<target>]\n")
+	help.WriteString("  redfish media [insert <url>|eject]\n")
+	help.WriteString("  redfish log [<count>]\n\n")
+	help.WriteString("Commands:\n")
+	help.WriteString("  on        - Power on the system\n")
+	help.WriteString("  off       - Power off the system immediately\n")
+	help.WriteString("  shutdown  - Shut the operating system down gracefully\n")
+	help.WriteString("  cycle     - Power cycle the system (off, then on)\n")
+	help.WriteString("  reset     - Reset the system immediately\n")
+	help.WriteString("  status    - Show the current power state\n")
+	help.WriteString("  boot      - Boot from <target> once on the next boot, or show the boot override.\n")
+	help.WriteString("              Targets: " + strings.Join(slices.Sorted(maps.Keys(bootTargets)), ", ") + "\n")
+	help.WriteString("  media     - Insert the ISO image at <url> as virtual CD, eject it, or show the virtual media\n")
+	help.WriteString("  log       - Show the latest <count> entries of the system event log (default: 20)\n")
+	help.WriteString("\n")
+	help.WriteString("Commands are sent to the BMC at: " + r.Host + "\n")
+
+	return help.String()
+}
+
+// Init validates the configuration and sets up the client. No request is sent
+// here: the BMC may be unreachable at agent startup and a failing Init is fatal
+// to the whole agent. The session is opened and the system is looked up on the
+// first command.
+func (r *Redfish) Init(ctx context.Context) error {
+	if r.Host == "" {
+		return fmt.Errorf("redfish host is not set")
+	}
+
+	base, err := baseURL(r.Host)
+	if err != nil {
+		return err
+	}
+
+	if r.User == "" || r.Password == "" {
+		return fmt.Errorf("redfish authentication requires both user and password to be set")
+	}
+
+	if r.Auth != "" && r.Auth != authSession && r.Auth != authBasic {
+		return fmt.Errorf("invalid auth %q: must be %q or %q", r.Auth, authSession, authBasic)
+	}
+
+	timeout := defaultTimeout
+
+	if r.Timeout != "" {
+		timeout, err = time.ParseDuration(r.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q: must be a positive duration", r.Timeout)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // documented type
+	if r.Insecure {
+		// BMCs commonly ship with self-signed certificates; trusting them is opt-in.
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // opt-in via the insecure option
+	}
+
+	r.client = &client{
+		http:     &http.Client{Timeout: timeout, Transport: transport},
+		base:     base,
+		user:     r.User,
+		password: r.Password,
+		basic:    r.Auth == authBasic,
+	}
+
+	log.FromContext(ctx).Debug(fmt.Sprintf("init completed for %s (Redfish session deferred to first use)", base))
+
+	return nil
+}
+
+// baseURL parses the configured host, defaulting to HTTPS.
+func baseURL(host string) (*url.URL, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	base, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redfish host %q: %v", host, err)
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid redfish host %q: scheme must be http or https", host)
+	}
+
+	return base, nil
+}
+
+// Deinit closes the Redfish session if one is open.
+func (r *Redfish) Deinit(ctx context.Context) error {
+	if r.client == nil {
+		return nil
+	}
+
+	return r.client.close(ctx)
+}
+
+// Run executes the command taken from the first argument.
+func (r *Redfish) Run(ctx context.Context, s module.Session, args ...string) error {
+	if r.client == nil {
+		return fmt.Errorf("redfish client not initialized: Init must run successfully before Run")
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("no command specified, available commands: %s", commandList())
+	}
+
+	command := strings.ToLower(args[0])
+	args = args[1:]
+
+	switch command {
+	case on, off, shutdown, cycle, reset:
+		return r.handleReset(ctx, s, command)
+	case status:
+		return r.handleStatus(ctx, s)
+	case boot:
+		return r.handleBoot(ctx, s, args)
+	case media:
+		return r.handleMedia(ctx, s, args)
+	case logs:
+		return r.handleLog(ctx, s, args)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: %s", command, commandList())
+	}
+}
+
+// commandList returns the supported commands as a comma-separated string.
+func commandList() string {
+	return strings.Join([]string{on, off, shutdown, cycle, reset, status, boot, media, logs}, ", ")
+}
+
+// system is the part of a ComputerSystem resource used by the module.
+type system struct {
+	ID         string `json:"Id"`
+	PowerState string `json:"PowerState"`
+	Boot       struct {
+		Target           string   `json:"BootSourceOverrideTarget"`
+		Enabled          string   `json:"BootSourceOverrideEnabled"`
+		AllowableTargets []string `json:"BootSourceOverrideTarget@Redfish.AllowableValues"`
+	} `json:"Boot"`
+	Actions struct {
+		Reset struct {
+			Target         string   `json:"target"`
+			AllowableTypes []string `json:"ResetType@Redfish.AllowableValues"`
+		} `json:"#ComputerSystem.Reset"`
+	} `json:"Actions"`
+	LogServices  link `json:"LogServices"`
+	VirtualMedia link `json:"VirtualMedia"`
+	Links        struct {
+		ManagedBy []link `json:"ManagedBy"`
+	} `json:"Links"`
+}
+
+// link is a reference to another resource.
+type link struct {
+	ID string `json:"@odata.id"`
+}
+
+// collection is a resource collection, possibly split into pages.
+type collection struct {
+	Members  []link `json:"Members"`
+	NextLink string `json:"Members@odata.nextLink"`
+}
+
+// members returns the links of all members of the collection at uri, following
+// the pages of the collection.
+func (r *Redfish) members(ctx context.Context, uri string) ([]link, error) {
+	var all []link
+
+	for uri != "" {
+		var page collection
+
+		err := r.client.get(ctx, uri, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, page.Members...)
+		uri = page.NextLink
+	}
+
+	return all, nil
+}
+
+// systemURI returns the URI of the managed ComputerSystem, looking it up in the
+// Systems collection on first use.
+func (r *Redfish) systemURI(ctx context.Context) (string, error) {
+	if r.system != "" {
+		return r.system, nil
+	}
+
+	systems, err := r.members(ctx, serviceRoot+"/Systems")
+	if err != nil {
+		return "", fmt.Errorf("listing systems: %w", err)
+	}
+
+	switch {
+	case r.System != "":
+		for _, sys := range systems {
+			if path.Base(strings.TrimSuffix(sys.ID, "/")) == r.System {
+				r.system = sys.ID
+			}
+		}
+
+		if r.system == "" {
+			return "", fmt.Errorf("system %q not found on the BMC", r.System)
+		}
+	case len(systems) == 1:
+		r.system = systems[0].ID
+	case len(systems) == 0:
+		return "", fmt.Errorf("the BMC reports no systems")
+	default:
+		return "", fmt.Errorf("the BMC manages %d systems, select one with the system option", len(systems))
+	}
+
+	return r.system, nil
+}
+
+// getSystem fetches the managed ComputerSystem.
+func (r *Redfish) getSystem(ctx context.Context) (*system, string, error) {
+	uri, err := r.systemURI(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var sys system
+
+	err = r.client.get(ctx, uri, &sys)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading system: %w", err)
+	}
+
+	return &sys, uri, nil
+}
+
+func (r *Redfish) handleReset(ctx context.Context, s module.Session, command string) error {
+	err := r.resetSystem(ctx, resetTypes[command])
+	if err != nil {
+		return err
+	}
+
+	s.Printf("Power %s command sent\n", strings.ToUpper(command))
+
+	return nil
+}
+
+func (r *Redfish) handleStatus(ctx context.Context, s module.Session) error {
+	sys, _, err := r.getSystem(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.Printf("Device power status: %s\n", sys.PowerState)
+
+	return nil
+}
+
+// resetSystem triggers ComputerSystem.Reset with resetType. A reset type the
+// BMC does not list as allowable is rejected up front with the ones it allows.
+func (r *Redfish) resetSystem(ctx context.Context, resetType string) error {
+	sys, uri, err := r.getSystem(ctx)
+	if err != nil {
+		return err
+	}
+
+	action := sys.Actions.Reset
+	if allowed := action.AllowableTypes; len(allowed) > 0 && !slices.Contains(allowed, resetType) {
+		return fmt.Errorf("the BMC does not support reset type %s, supported: %s", resetType, strings.Join(allowed, ", "))
+	}
+
+	target := action.Target
+	if target == "" {
+		target = uri + "/Actions/ComputerSystem.Reset"
+	}
+
+	err = r.client.post(ctx, target, map[string]string{"ResetType": resetType})
+	if err != nil {
+		return fmt.Errorf("reset %s failed: %w", resetType, err)
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("system reset %s (BMC %s)", resetType, r.Host))
+
+	return nil
+}
+
+// PowerOn switches the system on. A system that is on already is left alone,
+// since BMCs commonly reject a reset to the current state.
+func (r *Redfish) PowerOn(ctx context.Context) error {
+	return r.switchPower(ctx, module.PowerOn, resetTypes[on])
+}
+
+// PowerOff switches the system off hard, like the off command.
+func (r *Redfish) PowerOff(ctx context.Context) error {
+	return r.switchPower(ctx, module.PowerOff, resetTypes[off])
+}
+
+// PowerState reports the power state of the system. A system in transition,
+// e.g. "PoweringOn", is reported as unknown.
+func (r *Redfish) PowerState(ctx context.Context) (module.PowerState, error) {
+	if r.client == nil {
+		return module.PowerUnknown, fmt.Errorf("redfish client not initialized")
+	}
+
+	sys, _, err := r.getSystem(ctx)
+	if err != nil {
+		return module.PowerUnknown, err
+	}
+
+	return powerState(sys.PowerState), nil
+}
+
+// switchPower resets the system with resetType unless it is in want already.
+func (r *Redfish) switchPower(ctx context.Context, want module.PowerState, resetType string) error {
+	current, err := r.PowerState(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current == want {
+		return nil
+	}
+
+	return r.resetSystem(ctx, resetType)
+}
+
+// powerState converts the PowerState property of a ComputerSystem.
+func powerState(s string) module.PowerState {
+	switch s {
+	case "On":
+		return module.PowerOn
+	case "Off":
+		return module.PowerOff
+	default:
+		return module.PowerUnknown
+	}
+}
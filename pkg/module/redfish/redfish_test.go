@@ -0,0 +1,562 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redfish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+const (
+	testUser     = "admin"
+	testPassword = "secret"
+	systemURI    = "/redfish/v1/Systems/1"
+	managerURI   = "/redfish/v1/Managers/bmc"
+)
+
+// fakeBMC is an httptest mock of the Redfish tree of a BMC with one system. It
+// records the requests changing its state.
+type fakeBMC struct {
+	mu sync.Mutex
+
+	power      string
+	bootTarget string
+	bootMode   string
+	image      string
+	inserted   bool
+
+	mediaAtManager bool     // list virtual media at the manager, without actions
+	entries        []string // embedded SEL entries as JSON objects
+
+	tokens   map[string]bool // valid session tokens
+	sessions int             // number of sessions opened
+	calls    []string        // "METHOD path" of the requests changing state
+}
+
+func newFakeBMC(t *testing.T) (*fakeBMC, *httptest.Server) {
+	t.Helper()
+
+	bmc := &fakeBMC{power: "Off", bootTarget: "None", bootMode: "Disabled", tokens: map[string]bool{}}
+	srv := httptest.NewServer(bmc)
+	t.Cleanup(srv.Close)
+
+	return bmc, srv
+}
+
+func (b *fakeBMC) authorized(r *http.Request) bool {
+	if user, pass, ok := r.BasicAuth(); ok {
+		return user == testUser && pass == testPassword
+	}
+
+	return b.tokens[r.Header.Get(authTokenHeader)]
+}
+
+//nolint:cyclop,funlen,maintidx // the whole mocked Redfish tree
+func (b *fakeBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.Method == http.MethodPost && r.URL.Path == sessionsPath {
+		var creds map[string]string
+
+		_ = json.NewDecoder(r.Body).Decode(&creds)
+		if creds["UserName"] != testUser || creds["Password"] != testPassword {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+
+			return
+		}
+
+		b.sessions++
+		token := fmt.Sprintf("token-%d", b.sessions)
+		b.tokens[token] = true
+
+		w.Header().Set(authTokenHeader, token)
+		w.Header().Set("Location", fmt.Sprintf("%s/%d", sessionsPath, b.sessions))
+		w.WriteHeader(http.StatusCreated)
+
+		return
+	}
+
+	if !b.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	var body map[string]any
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		b.calls = append(b.calls, r.Method+" "+r.URL.Path)
+	}
+
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mediaURI := systemURI + "/VirtualMedia"
+	if b.mediaAtManager {
+		mediaURI = managerURI + "/VirtualMedia"
+	}
+
+	switch {
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, sessionsPath+"/"):
+		delete(b.tokens, r.Header.Get(authTokenHeader))
+		b.calls = append(b.calls, r.Method+" "+r.URL.Path)
+	case r.URL.Path == "/redfish/v1/Systems":
+		reply(map[string]any{"Members": []any{map[string]string{"@odata.id": systemURI}}})
+	case r.URL.Path == systemURI && r.Method == http.MethodGet:
+		sys := map[string]any{
+			"Id":         "1",
+			"PowerState": b.power,
+			"Boot": map[string]any{
+				"BootSourceOverrideTarget":                         b.bootTarget,
+				"BootSourceOverrideEnabled":                        b.bootMode,
+				"BootSourceOverrideTarget@Redfish.AllowableValues": []string{"None", "Pxe", "Hdd", "Cd", "BiosSetup"},
+			},
+			"Actions": map[string]any{
+				"#ComputerSystem.Reset": map[string]any{
+					"target":                            systemURI + "/Actions/ComputerSystem.Reset",
+					"ResetType@Redfish.AllowableValues": []string{"On", "ForceOff", "GracefulShutdown", "ForceRestart"},
+				},
+			},
+			"LogServices": map[string]string{"@odata.id": systemURI + "/LogServices"},
+			"Links":       map[string]any{"ManagedBy": []any{map[string]string{"@odata.id": managerURI}}},
+		}
+		if !b.mediaAtManager {
+			sys["VirtualMedia"] = map[string]string{"@odata.id": mediaURI}
+		}
+
+		reply(sys)
+	case r.URL.Path == systemURI && r.Method == http.MethodPatch:
+		boot, _ := body["Boot"].(map[string]any)
+		b.bootTarget, _ = boot["BootSourceOverrideTarget"].(string)
+		b.bootMode, _ = boot["BootSourceOverrideEnabled"].(string)
+	case r.URL.Path == systemURI+"/Actions/ComputerSystem.Reset":
+		switch body["ResetType"] {
+		case "On":
+			b.power = "On"
+		case "ForceOff", "GracefulShutdown":
+			b.power = "Off"
+		case "ForceRestart":
+		default:
+			http.Error(w, "unsupported reset type", http.StatusBadRequest)
+		}
+	case r.URL.Path == managerURI:
+		mgr := map[string]any{"LogServices": map[string]string{"@odata.id": managerURI + "/LogServices"}}
+		if b.mediaAtManager {
+			mgr["VirtualMedia"] = map[string]string{"@odata.id": mediaURI}
+		}
+
+		reply(mgr)
+	case r.URL.Path == mediaURI:
+		reply(map[string]any{"Members": []any{
+			map[string]string{"@odata.id": mediaURI + "/USB1"},
+			map[string]string{"@odata.id": mediaURI + "/CD1"},
+		}})
+	case r.URL.Path == mediaURI+"/USB1":
+		reply(map[string]any{"Id": "USB1", "MediaTypes": []string{"USBStick"}})
+	case r.URL.Path == mediaURI+"/CD1" && r.Method == http.MethodGet:
+		vm := map[string]any{"Id": "CD1", "MediaTypes": []string{"CD", "DVD"}, "Image": b.image, "Inserted": b.inserted}
+		if !b.mediaAtManager {
+			vm["Actions"] = map[string]any{
+				"#VirtualMedia.InsertMedia": map[string]string{"target": mediaURI + "/CD1/Actions/VirtualMedia.InsertMedia"},
+				"#VirtualMedia.EjectMedia":  map[string]string{"target": mediaURI + "/CD1/Actions/VirtualMedia.EjectMedia"},
+			}
+		}
+
+		reply(vm)
+	case r.URL.Path == mediaURI+"/CD1/Actions/VirtualMedia.InsertMedia",
+		r.URL.Path == mediaURI+"/CD1" && r.Method == http.MethodPatch:
+		b.image, _ = body["Image"].(string)
+		b.inserted, _ = body["Inserted"].(bool)
+	case r.URL.Path == mediaURI+"/CD1/Actions/VirtualMedia.EjectMedia":
+		b.image, b.inserted = "", false
+	case r.URL.Path == systemURI+"/LogServices":
+		reply(map[string]any{"Members": []any{map[string]string{"@odata.id": systemURI + "/LogServices/Event"}}})
+	case r.URL.Path == systemURI+"/LogServices/Event":
+		reply(map[string]any{"Id": "Event", "LogEntryType": "Event",
+			"Entries": map[string]string{"@odata.id": systemURI + "/LogServices/Event/Entries"}})
+	case r.URL.Path == managerURI+"/LogServices":
+		reply(map[string]any{"Members": []any{map[string]string{"@odata.id": managerURI + "/LogServices/SEL"}}})
+	case r.URL.Path == managerURI+"/LogServices/SEL":
+		reply(map[string]any{"Id": "SEL", "LogEntryType": "SEL",
+			"Entries": map[string]string{"@odata.id": managerURI + "/LogServices/SEL/Entries"}})
+	case r.URL.Path == managerURI+"/LogServices/SEL/Entries":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"Members":[%s]}`, strings.Join(b.entries, ","))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// expireSessions invalidates all session tokens, as a BMC does on a timeout.
+func (b *fakeBMC) expireSessions() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.tokens)
+}
+
+func newTestRedfish(t *testing.T, host, auth string) *Redfish {
+	t.Helper()
+
+	r := &Redfish{Host: host, User: testUser, Password: testPassword, Auth: auth}
+	if err := r.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	return r
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       Redfish
+		wantErr bool
+		wantURL string
+	}{
+		{"defaults to https", Redfish{Host: "10.0.0.5", User: "u", Password: "p"}, false, "https://10.0.0.5"},
+		{"http", Redfish{Host: "http://bmc:8000", User: "u", Password: "p", Auth: authBasic}, false, "http://bmc:8000"},
+		{"no host", Redfish{User: "u", Password: "p"}, true, ""},
+		{"bad scheme", Redfish{Host: "ftp://bmc", User: "u", Password: "p"}, true, ""},
+		{"no password", Redfish{Host: "bmc", User: "u"}, true, ""},
+		{"bad auth", Redfish{Host: "bmc", User: "u", Password: "p", Auth: "digest"}, true, ""},
+		{"bad timeout", Redfish{Host: "bmc", User: "u", Password: "p", Timeout: "soon"}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.r.Init(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && tt.r.client.base.String() != tt.wantURL {
+				t.Errorf("base URL = %s, want %s", tt.r.client.base, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestPowerCommands(t *testing.T) {
+	bmc, srv := newFakeBMC(t)
+	r := newTestRedfish(t, srv.URL, "")
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		command   string
+		wantPower string
+	}{
+		{"on", "On"},
+		{"reset", "On"},
+		{"shutdown", "Off"},
+		{"on", "On"},
+		{"off", "Off"},
+	} {
+		if err := r.Run(ctx, &mock.Session{}, tt.command); err != nil {
+			t.Fatalf("%s: %v", tt.command, err)
+		}
+
+		if bmc.power != tt.wantPower {
+			t.Errorf("after %s: power = %s, want %s", tt.command, bmc.power, tt.wantPower)
+		}
+	}
+
+	s := &mock.Session{}
+	if err := r.Run(ctx, s, "status"); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	if s.PrintText != "Device power status: Off\n" {
+		t.Errorf("status printed %q", s.PrintText)
+	}
+
+	// PowerCycle is not among the allowable reset types of the BMC.
+	err := r.Run(ctx, &mock.Session{}, "cycle")
+	if err == nil || !strings.Contains(err.Error(), "ForceRestart") {
+		t.Errorf("cycle: err = %v, want the supported reset types", err)
+	}
+
+	if err := r.Run(ctx, &mock.Session{}, "explode"); err == nil {
+		t.Error("unknown command: want error")
+	}
+
+	if bmc.sessions != 1 {
+		t.Errorf("sessions opened = %d, want 1", bmc.sessions)
+	}
+}
+
+func TestPowerController(t *testing.T) {
+	bmc, srv := newFakeBMC(t)
+	r := newTestRedfish(t, srv.URL, "")
+	ctx := context.Background()
+
+	if err := r.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+
+	if st, err := r.PowerState(ctx); err != nil || st != module.PowerOn {
+		t.Fatalf("PowerState after PowerOn = %v %v, want on", st, err)
+	}
+
+	// Switching on a system that is on sends no reset.
+	if err := r.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn again: %v", err)
+	}
+
+	if err := r.PowerOff(ctx); err != nil {
+		t.Fatalf("PowerOff: %v", err)
+	}
+
+	if st, err := r.PowerState(ctx); err != nil || st != module.PowerOff {
+		t.Fatalf("PowerState after PowerOff = %v %v, want off", st, err)
+	}
+
+	reset := "POST " + systemURI + "/Actions/ComputerSystem.Reset"
+	if want := []string{reset, reset}; !slices.Equal(bmc.calls, want) {
+		t.Errorf("calls = %v, want %v", bmc.calls, want)
+	}
+
+	bmc.power = "PoweringOn"
+	if st, err := r.PowerState(ctx); err != nil || st != module.PowerUnknown {
+		t.Errorf("PowerState while powering on = %v %v, want unknown", st, err)
+	}
+}
+
+func TestSessionAuth(t *testing.T) {
+	bmc, srv := newFakeBMC(t)
+	r := newTestRedfish(t, srv.URL, authSession)
+	ctx := context.Background()
+
+	if err := r.Run(ctx, &mock.Session{}, "status"); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	// An expired session is replaced transparently.
+	bmc.expireSessions()
+
+	if err := r.Run(ctx, &mock.Session{}, "status"); err != nil {
+		t.Fatalf("status after expiry: %v", err)
+	}
+
+	if bmc.sessions != 2 {
+		t.Errorf("sessions opened = %d, want 2", bmc.sessions)
+	}
+
+	if err := r.Deinit(ctx); err != nil {
+		t.Fatalf("Deinit: %v", err)
+	}
+
+	if want := []string{"DELETE " + sessionsPath + "/2"}; !slices.Equal(bmc.calls, want) {
+		t.Errorf("calls = %v, want %v", bmc.calls, want)
+	}
+
+	if len(bmc.tokens) != 0 {
+		t.Errorf("sessions left open: %v", bmc.tokens)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	bmc, srv := newFakeBMC(t)
+	r := newTestRedfish(t, srv.URL, authBasic)
+	ctx := context.Background()
+
+	if err := r.Run(ctx, &mock.Session{}, "on"); err != nil {
+		t.Fatalf("on: %v", err)
+	}
+
+	if bmc.sessions != 0 {
+		t.Errorf("sessions opened = %d, want none with basic auth", bmc.sessions)
+	}
+
+	if err := r.Deinit(ctx); err != nil {
+		t.Fatalf("Deinit: %v", err)
+	}
+
+	wrong := &Redfish{Host: srv.URL, User: testUser, Password: "wrong", Auth: authBasic}
+	if err := wrong.Init(ctx); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	if err := wrong.Run(ctx, &mock.Session{}, "status"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("wrong password: err = %v, want 401", err)
+	}
+}
+
+func TestBoot(t *testing.T) {
+	bmc, srv := newFakeBMC(t)
+	r := newTestRedfish(t, srv.URL, "")
+	ctx := context.Background()
+
+	s := &mock.Session{}
+	if err := r.Run(ctx, s, "boot"); err != nil {
+		t.Fatalf("boot: %v", err)
+	}
+
+	if s.PrintText != "No boot override set\n" {
+		t.Errorf("boot printed %q", s.PrintText)
+	}
+
+	if err := r.Run(ctx, s, "boot", "PXE"); err != nil {
+		t.Fatalf("boot pxe: %v", err)
+	}
+
+	if bmc.bootTarget != "Pxe" || bmc.bootMode != "Once" {
+		t.Errorf("override = %s (%s), want Pxe (Once)", bmc.bootTarget, bmc.bootMode)
+	}
+
+	if err := r.Run(ctx, s, "boot"); err != nil {
+		t.Fatalf("boot: %v", err)
+	}
+
+	if s.PrintText != "Boot override: Pxe (Once)\n" {
+		t.Errorf("boot printed %q", s.PrintText)
+	}
+
+	if err := r.Run(ctx, s, "boot", "none"); err != nil {
+		t.Fatalf("boot none: %v", err)
+	}
+
+	if bmc.bootTarget != "None" || bmc.bootMode != "Disabled" {
+		t.Errorf("override = %s (%s), want None (Disabled)", bmc.bootTarget, bmc.bootMode)
+	}
+
+	for _, args := range [][]string{{"boot", "floppy"}, {"boot", "usb"}, {"boot", "pxe", "hdd"}} {
+		if err := r.Run(ctx, s, args...); err == nil {
+			t.Errorf("%v: want error", args)
+		}
+	}
+}
+
+func TestMedia(t *testing.T) {
+	for _, atManager := range []bool{false, true} {
+		t.Run(fmt.Sprintf("at manager %v", atManager), func(t *testing.T) {
+			bmc, srv := newFakeBMC(t)
+			bmc.mediaAtManager = atManager
+			r := newTestRedfish(t, srv.URL, "")
+			ctx := context.Background()
+			iso := "http://files.example.com/installer.iso"
+
+			s := &mock.Session{}
+			if err := r.Run(ctx, s, "media"); err != nil {
+				t.Fatalf("media: %v", err)
+			}
+
+			if s.PrintText != "Virtual media CD1: empty\n" {
+				t.Errorf("media printed %q", s.PrintText)
+			}
+
+			if err := r.Run(ctx, s, "media", "insert", iso); err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+
+			if !bmc.inserted || bmc.image != iso {
+				t.Errorf("after insert: inserted %v, image %q", bmc.inserted, bmc.image)
+			}
+
+			if err := r.Run(ctx, s, "media", "status"); err != nil {
+				t.Fatalf("media status: %v", err)
+			}
+
+			if s.PrintText != "Virtual media CD1: "+iso+"\n" {
+				t.Errorf("media status printed %q", s.PrintText)
+			}
+
+			if err := r.Run(ctx, s, "media", "eject"); err != nil {
+				t.Fatalf("eject: %v", err)
+			}
+
+			if bmc.inserted || bmc.image != "" {
+				t.Errorf("after eject: inserted %v, image %q", bmc.inserted, bmc.image)
+			}
+
+			wantMethod := "POST"
+			if atManager {
+				wantMethod = "PATCH"
+			}
+
+			for _, call := range bmc.calls {
+				if !strings.HasPrefix(call, wantMethod+" ") {
+					t.Errorf("call %q, want %s requests only", call, wantMethod)
+				}
+			}
+
+			if err := r.Run(ctx, s, "media", "insert", "installer.iso"); err == nil {
+				t.Error("insert of a relative URL: want error")
+			}
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	bmc, srv := newFakeBMC(t)
+	r := newTestRedfish(t, srv.URL, "")
+	ctx := context.Background()
+
+	// Newest first, as some BMCs list them.
+	for i := 3; i > 0; i-- {
+		bmc.entries = append(bmc.entries, fmt.Sprintf(
+			`{"Id":"%d","Created":"2025-01-0%dT10:00:00Z","Severity":"Warning","Message":"event %d"}`, i, i, i))
+	}
+
+	s := &mock.Session{}
+	if err := r.Run(ctx, s, "log", "2"); err != nil {
+		t.Fatalf("log: %v", err)
+	}
+
+	want := "2025-01-02T10:00:00Z       Warning   event 2\n" +
+		"2025-01-03T10:00:00Z       Warning   event 3\n"
+	if s.PrintText != want {
+		t.Errorf("log printed\n%q\nwant\n%q", s.PrintText, want)
+	}
+
+	bmc.entries = nil
+	if err := r.Run(ctx, s, "log"); err != nil {
+		t.Fatalf("log: %v", err)
+	}
+
+	if s.PrintText != "Event log SEL is empty\n" {
+		t.Errorf("log printed %q", s.PrintText)
+	}
+
+	if err := r.Run(ctx, s, "log", "-1"); err == nil {
+		t.Error("negative count: want error")
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(http.StatusBadRequest)
+	fmt.Fprint(rec, `{"error":{"message":"general error","@Message.ExtendedInfo":[{"Message":"value out of range"}]}}`)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	err := checkStatus(http.MethodPatch, systemURI, res)
+	if err == nil || !strings.HasSuffix(err.Error(), "400 Bad Request: value out of range") {
+		t.Errorf("err = %v, want the extended info message", err)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.WriteHeader(http.StatusNoContent)
+
+	res = rec.Result()
+	defer res.Body.Close()
+
+	if err := checkStatus(http.MethodPost, systemURI, res); err != nil {
+		t.Errorf("204: err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,811 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func init() {
+	module.Register(module.Record{
+		ID:  "bootloader",
+		New: func() module.Module { return &Bootloader{} },
+	})
+}
+
+const (
+	// defaultAutobootTimeout bounds the wait for the autoboot message if no
+	// timeout is requested. It leaves time for a power cycle and the firmware
+	// coming up.
+	defaultAutobootTimeout = 2 * time.Minute
+	// defaultCommandTimeout bounds the wait for the prompt after a command if no
+	// timeout is requested.
+	defaultCommandTimeout = 30 * time.Second
+	// interruptInterval is the pause between the interrupt keys sent to stop
+	// the autoboot.
+	interruptInterval = 100 * time.Millisecond
+	// bootSettle is how long a boot command is watched for returning to the
+	// prompt, unless the bootloader reports the start of the kernel before.
+	bootSettle = 5 * time.Second
+	// defaultLoader is the bootloader assumed if none is configured.
+	defaultLoader = "u-boot"
+)
+
+// loader describes how to drive a bootloader on its console.
+type loader struct {
+	name      string // name is the bootloader's name in messages.
+	prompt    string // prompt matches the command prompt at the end of the output.
+	autoboot  string // autoboot matches the message announcing the autoboot.
+	interrupt string // interrupt is the key stopping the autoboot.
+	// wake is sent to get a fresh prompt, discarding what was typed. The line
+	// ending follows it if wakeEOL is set; U-Boot repeats the last command on
+	// an empty line.
+	wake    string
+	wakeEOL bool
+	failed  string // failed matches the output of a failed command.
+	booting string // booting matches the output of a successful boot command, if the bootloader reports it.
+
+	printAll string // printAll prints all variables.
+	printVar string // printVar is the format of printing a single variable.
+	setVar   string // setVar is the format of setting a variable to a quoted value.
+	quote    byte   // quote encloses values; a value must not contain it.
+	bootCmd  string // bootCmd is the default boot command; empty if there is none.
+
+	// tftp returns the commands loading and booting a kernel over TFTP; nil if
+	// the bootloader cannot.
+	tftp func(o tftpOptions) tftpCommands
+}
+
+// tftpOptions are the options of the tftp-boot operation.
+type tftpOptions struct {
+	server string
+	kernel string
+	initrd string
+	fdt    string
+	args   string
+	cmd    string
+}
+
+// tftpCommands are the commands of a TFTP boot: setup commands, transfers
+// checked by the bootloader's transferred pattern, and the boot command.
+type tftpCommands struct {
+	setup       []string
+	transfers   []string
+	transferred string
+	boot        string
+}
+
+// loaders holds the supported bootloaders by the name configured.
+//
+//nolint:gochecknoglobals // the table of supported bootloaders
+var loaders = map[string]loader{
+	"u-boot": {
+		name:      "U-Boot",
+		prompt:    `(=>|U-Boot>) *$`,
+		autoboot:  `Hit any key to stop autoboot|Press \S+ to (abort|stop) autoboot|[Aa]utoboot(ing)? in \d+ seconds`,
+		interrupt: " ",
+		wake:      "\x03", // Ctrl-C discards the line and shows a new prompt.
+		failed:    `Unknown command|## Error|ERROR|[Ee]rror:|TFTP error|Retry count exceeded|Bad .* magic|Wrong Image Format`,
+		booting:   `Starting kernel`,
+		printAll:  "printenv",
+		printVar:  "printenv %s",
+		setVar:    "setenv %s '%s'",
+		quote:     '\'',
+		bootCmd:   "boot",
+		tftp:      ubootTFTP,
+	},
+	"grub": {
+		name:      "GRUB",
+		prompt:    `grub> *$`,
+		autoboot:  `will be (executed|booted) automatically in \d+s`,
+		interrupt: "c",
+		wake:      "\x15", // Ctrl-U kills the line.
+		wakeEOL:   true,
+		failed:    `(?m)^error: `,
+		printAll:  "set",
+		printVar:  "echo $%s",
+		setVar:    "set %s='%s'",
+		quote:     '\'',
+		bootCmd:   "boot",
+		tftp:      grubTFTP,
+	},
+	"uefi-shell": {
+		name:      "UEFI shell",
+		prompt:    `(Shell|FS\d+:\\[^>\r\n]*)> *$`,
+		autoboot:  `Press ESC in \d+ seconds to skip startup\.nsh`,
+		interrupt: "\x1b",
+		wake:      "\x1b", // Esc clears the line.
+		wakeEOL:   true,
+		failed:    `is not recognized as an internal or external command|: (Not Found|Invalid Parameter|Error)`,
+		printAll:  "set",
+		printVar:  "echo %%%s%%",
+		setVar:    `set %s "%s"`,
+		quote:     '"',
+	},
+}
+
+// ubootTFTP loads the kernel and the optional device tree and initrd to the
+// load addresses U-Boot's environment defines, then boots it. The initrd is
+// loaded last, so ${filesize} is its size.
+func ubootTFTP(o tftpOptions) tftpCommands {
+	var c tftpCommands
+
+	if o.server != "" {
+		c.setup = append(c.setup, "setenv serverip "+o.server)
+	}
+
+	if o.args != "" {
+		c.setup = append(c.setup, fmt.Sprintf("setenv bootargs '%s'", o.args))
+	}
+
+	c.transfers = append(c.transfers, "tftpboot ${kernel_addr_r} "+o.kernel)
+	boot := []string{o.cmd, "${kernel_addr_r}"}
+
+	if o.fdt != "" {
+		c.transfers = append(c.transfers, "tftpboot ${fdt_addr_r} "+o.fdt)
+	}
+
+	switch {
+	case o.initrd != "":
+		c.transfers = append(c.transfers, "tftpboot ${ramdisk_addr_r} "+o.initrd)
+		boot = append(boot, "${ramdisk_addr_r}:${filesize}")
+	case o.fdt != "":
+		boot = append(boot, "-")
+	}
+
+	if o.fdt != "" {
+		boot = append(boot, "${fdt_addr_r}")
+	}
+
+	c.transferred = `Bytes transferred = `
+	c.boot = strings.Join(boot, " ")
+
+	return c
+}
+
+// grubTFTP loads the kernel and the optional initrd and device tree from the
+// TFTP server, or from GRUB's default server, then boots it.
+func grubTFTP(o tftpOptions) tftpCommands {
+	var c tftpCommands
+
+	dev := "(tftp)"
+	if o.server != "" {
+		dev = "(tftp," + o.server + ")"
+	}
+
+	c.transfers = append(c.transfers, strings.TrimSpace(fmt.Sprintf("linux %s/%s %s", dev, strings.TrimPrefix(o.kernel, "/"), o.args)))
+
+	if o.initrd != "" {
+		c.transfers = append(c.transfers, fmt.Sprintf("initrd %s/%s", dev, strings.TrimPrefix(o.initrd, "/")))
+	}
+
+	if o.fdt != "" {
+		c.transfers = append(c.transfers, fmt.Sprintf("devicetree %s/%s", dev, strings.TrimPrefix(o.fdt, "/")))
+	}
+
+	c.boot = "boot"
+
+	return c
+}
+
+// Bootloader drives the bootloader on a DUT's serial console: it stops the
+// autoboot, reads and sets variables and boots, locally or over TFTP. It knows
+// the prompts and autoboot messages of U-Boot, GRUB and the UEFI shell.
+//
+// The port is shared with the serial module, see attach.
+type Bootloader struct {
+	Port  string // Port is the path to the serial device on the dutagent, or a URL of a network serial port.
+	Baud  int    // Baud is the baud rate of the serial device. If unset, DefaultBaudRate is used.
+	EOL   string // EOL is the line ending sent after each command: cr (default), lf or crlf.
+	Delay string // Delay is the pause before each command sent (e.g. "200ms"). Default 50ms; "0s" disables.
+
+	// The line settings, as of the serial module. Commands sharing a port must agree on them.
+	DataBits int `yaml:"data-bits"`
+	Parity   string
+	StopBits string `yaml:"stop-bits"`
+	Flow     string
+
+	Loader       string // Loader is the bootloader: u-boot (default), grub or uefi-shell.
+	Prompt       string // Prompt overrides the regular expression matching the bootloader's prompt.
+	Autoboot     string // Autoboot overrides the regular expression matching the autoboot message.
+	InterruptKey string `yaml:"interrupt-key"` // InterruptKey overrides the key stopping the autoboot.
+
+	line     lineSettings
+	eol      []byte
+	delay    time.Duration
+	loader   loader
+	prompt   *regexp.Regexp
+	autoboot *regexp.Regexp
+	failed   *regexp.Regexp
+
+	// open opens the serial port. It defaults to defaultOpenPort; tests set it
+	// to a fake.
+	open portOpener
+}
+
+// Ensure implementing the Module interface.
+var _ module.Module = &Bootloader{}
+
+const bootloaderAbstract = `Drive the bootloader on the DUT's serial console
+`
+
+const bootloaderUsage = `
+ARGUMENTS:
+	[-t <duration>] interrupt
+	[-t <duration>] printenv [<name>]
+	[-t <duration>] setenv <name> <value>
+	[-t <duration>] boot [<command>]
+	[-t <duration>] tftp-boot [-server <ip>] [-initrd <file>] [-fdt <file>] [-args <bootargs>] [-cmd <command>] <kernel>
+
+`
+
+const bootloaderDescription = `
+OPERATIONS:
+	interrupt   Wait for the autoboot message and stop the autoboot, so the DUT
+	            stays at the bootloader prompt. Power on or reset the DUT right
+	            before, e.g. in a preceding module of the same command.
+	printenv    Print a variable, or all of them.
+	setenv      Set a variable. The value is quoted for the bootloader.
+	boot        Run the boot command, the bootloader's default if none is given.
+	            It fails if the bootloader returns to its prompt.
+	tftp-boot   Load the kernel, and optionally an initrd and a device tree,
+	            from a TFTP server and boot it. -cmd is the U-Boot command booting
+	            the kernel (default booti). Not supported by the UEFI shell.
+
+All operations but interrupt expect the DUT at the bootloader prompt, and fail
+if the prompt does not show within the timeout: by default 2m for the autoboot
+message, 30s for a command.
+
+EXAMPLES:
+	stop the autoboot:         interrupt
+	change the kernel command: setenv bootargs "console=ttyS0,115200 root=/dev/mmcblk0p2"
+	boot from the network:     -t 2m tftp-boot -server 10.0.0.1 -fdt board.dtb Image
+`
+
+func (b *Bootloader) Help() string {
+	help := strings.Builder{}
+	help.WriteString(bootloaderAbstract)
+	help.WriteString(bootloaderUsage)
+	fmt.Fprintf(&help, "Configured for %s on COM port %q with %s.\n", b.loader.name, b.Port, b.line)
+	help.WriteString(bootloaderDescription)
+
+	return help.String()
+}
+
+// Init validates the configuration and compiles the patterns of the bootloader.
+// Like the serial module it does not open the port.
+//
+//nolint:cyclop,funlen // sequential validation of the options
+func (b *Bootloader) Init(ctx context.Context) error {
+	if b.Port == "" {
+		return errors.New("COM port is not set")
+	}
+
+	_, _, err := parsePortName(b.Port)
+	if err != nil {
+		return err
+	}
+
+	if b.Baud == 0 {
+		b.Baud = DefaultBaudRate
+		log.FromContext(ctx).Debug(fmt.Sprintf("no baud rate configured, using default %d", DefaultBaudRate))
+	}
+
+	b.line, err = newLineSettings(b.Baud, b.DataBits, b.Parity, b.StopBits, b.Flow)
+	if err != nil {
+		return err
+	}
+
+	if b.EOL == "" {
+		b.EOL = defaultEOL
+	}
+
+	b.eol, err = resolveEOL(b.EOL)
+	if err != nil {
+		return err
+	}
+
+	if len(b.eol) == 0 {
+		return errors.New("eol must end the commands: cr, lf or crlf")
+	}
+
+	b.delay = defaultDelay
+
+	if b.Delay != "" {
+		b.delay, err = time.ParseDuration(b.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay %q: %w", b.Delay, err)
+		}
+	}
+
+	if b.Loader == "" {
+		b.Loader = defaultLoader
+	}
+
+	var ok bool
+
+	b.loader, ok = loaders[strings.ToLower(b.Loader)]
+	if !ok {
+		names := make([]string, 0, len(loaders))
+		for name := range loaders {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		return fmt.Errorf("unknown loader %q: want one of %s", b.Loader, strings.Join(names, ", "))
+	}
+
+	if b.Prompt != "" {
+		b.loader.prompt = b.Prompt
+	}
+
+	if b.Autoboot != "" {
+		b.loader.autoboot = b.Autoboot
+	}
+
+	if b.InterruptKey != "" {
+		b.loader.interrupt = b.InterruptKey
+	}
+
+	b.prompt, err = regexp.Compile(b.loader.prompt)
+	if err != nil {
+		return fmt.Errorf("invalid prompt pattern %q: %w", b.loader.prompt, err)
+	}
+
+	b.autoboot, err = regexp.Compile(b.loader.autoboot)
+	if err != nil {
+		return fmt.Errorf("invalid autoboot pattern %q: %w", b.loader.autoboot, err)
+	}
+
+	b.failed = regexp.MustCompile(b.loader.failed)
+
+	return nil
+}
+
+// Deinit does nothing: the port is attached within each Run only.
+func (b *Bootloader) Deinit(_ context.Context) error {
+	return nil
+}
+
+// bootOp is an operation of the bootloader module, parsed from the arguments.
+type bootOp struct {
+	name    string
+	timeout time.Duration // zero if not requested
+	args    []string
+	tftp    tftpOptions
+}
+
+// Run performs the requested operation on the bootloader.
+func (b *Bootloader) Run(ctx context.Context, session module.Session, args ...string) error {
+	if b.prompt == nil {
+		return errors.New("module not initialized")
+	}
+
+	op, err := b.parseArgs(args)
+	if err != nil {
+		return err
+	}
+
+	opener := b.open
+	if opener == nil {
+		opener = defaultOpenPort
+	}
+
+	serialPort, err := attach(b.Port, b.line, opener, true)
+	if err != nil {
+		return err
+	}
+	defer serialPort.Close()
+
+	con := &loaderConsole{Bootloader: b, session: session}
+	con.eng = newEngine(serialPort, &con.out, true)
+
+	log.FromContext(ctx).Info(fmt.Sprintf("%s %s on %s", b.loader.name, op.name, b.Port))
+
+	if op.name == "interrupt" {
+		return con.interrupt(ctx, timeoutOr(op.timeout, defaultAutobootTimeout))
+	}
+
+	timeout := timeoutOr(op.timeout, defaultCommandTimeout)
+
+	err = con.awaitPrompt(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch op.name {
+	case "printenv":
+		command := b.loader.printAll
+		if len(op.args) == 1 {
+			command = fmt.Sprintf(b.loader.printVar, op.args[0])
+		}
+
+		_, err = con.command(ctx, command, timeout)
+
+		return err
+	case "setenv":
+		_, err = con.command(ctx, fmt.Sprintf(b.loader.setVar, op.args[0], op.args[1]), timeout)
+
+		return err
+	case "boot":
+		command := b.loader.bootCmd
+		if len(op.args) == 1 {
+			command = op.args[0]
+		}
+
+		return con.boot(ctx, command)
+	default: // tftp-boot
+		return con.tftpBoot(ctx, op.tftp, timeout)
+	}
+}
+
+// parseArgs parses the arguments of the bootloader module into an operation.
+//
+//nolint:cyclop // one case per operation
+func (b *Bootloader) parseArgs(args []string) (bootOp, error) {
+	var op bootOp
+
+	fs := flag.NewFlagSet("bootloader", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output.
+	fs.DurationVar(&op.timeout, "t", 0, "wait for the prompt, or the autoboot message (e.g. 30s, 2m)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return op, fmt.Errorf("failed to parse arguments: %w", err)
+	}
+
+	if fs.NArg() == 0 {
+		return op, errors.New("missing operation: interrupt, printenv, setenv, boot or tftp-boot")
+	}
+
+	op.name, op.args = fs.Arg(0), fs.Args()[1:]
+
+	switch op.name {
+	case "interrupt":
+		return op, wantArgs(op, 0, 0)
+	case "printenv":
+		err = wantArgs(op, 0, 1)
+		if err == nil && len(op.args) == 1 {
+			err = validVarName(op.args[0])
+		}
+
+		return op, err
+	case "setenv":
+		err = wantArgs(op, 2, 2) //nolint:mnd // name and value
+		if err == nil {
+			err = validVarName(op.args[0])
+		}
+
+		if err == nil && strings.ContainsRune(op.args[1], rune(b.loader.quote)) {
+			err = fmt.Errorf("the value must not contain %c for %s", b.loader.quote, b.loader.name)
+		}
+
+		return op, err
+	case "boot":
+		err = wantArgs(op, 0, 1)
+		if err == nil && len(op.args) == 0 && b.loader.bootCmd == "" {
+			err = fmt.Errorf("%s has no default boot command, pass one", b.loader.name)
+		}
+
+		return op, err
+	case "tftp-boot":
+		op.tftp, err = b.parseTFTPArgs(op.args)
+
+		return op, err
+	default:
+		return op, fmt.Errorf("unknown operation %q: want interrupt, printenv, setenv, boot or tftp-boot", op.name)
+	}
+}
+
+func (b *Bootloader) parseTFTPArgs(args []string) (tftpOptions, error) {
+	var o tftpOptions
+
+	if b.loader.tftp == nil {
+		return o, fmt.Errorf("tftp-boot is not supported by the %s", b.loader.name)
+	}
+
+	fs := flag.NewFlagSet("tftp-boot", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output.
+	fs.StringVar(&o.server, "server", "", "TFTP server, the bootloader's default if unset")
+	fs.StringVar(&o.initrd, "initrd", "", "initrd to load")
+	fs.StringVar(&o.fdt, "fdt", "", "device tree to load")
+	fs.StringVar(&o.args, "args", "", "kernel command line")
+	fs.StringVar(&o.cmd, "cmd", "booti", "U-Boot command booting the kernel")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return o, fmt.Errorf("failed to parse tftp-boot arguments: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		return o, errors.New("tftp-boot takes exactly one kernel")
+	}
+
+	o.kernel = fs.Arg(0)
+
+	if strings.ContainsRune(o.args, rune(b.loader.quote)) {
+		return o, fmt.Errorf("the kernel command line must not contain %c for %s", b.loader.quote, b.loader.name)
+	}
+
+	return o, nil
+}
+
+func wantArgs(op bootOp, minArgs, maxArgs int) error {
+	switch {
+	case len(op.args) < minArgs:
+		return fmt.Errorf("%s: missing arguments", op.name)
+	case len(op.args) > maxArgs:
+		return fmt.Errorf("%s: too many arguments - quote a value containing spaces", op.name)
+	}
+
+	return nil
+}
+
+// loaderVarName matches the variable names accepted, which are safe to pass to
+// every bootloader unquoted.
+var loaderVarName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func validVarName(name string) error {
+	if !loaderVarName.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+
+	return nil
+}
+
+func timeoutOr(timeout, fallback time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+
+	return fallback
+}
+
+// loaderConsole is the bootloader's console during a Run. The engine tees the
+// output to out, which holds the output of the current command.
+type loaderConsole struct {
+	*Bootloader
+
+	session module.Session
+	eng     *engine
+	out     bytes.Buffer
+}
+
+// interrupt waits for the autoboot message, then sends the interrupt key until
+// the prompt shows, and clears what the keys left on the command line.
+func (c *loaderConsole) interrupt(ctx context.Context, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := c.eng.readUntil(waitCtx, regexp.MustCompile(c.autoboot.String()+"|"+c.prompt.String()))
+	if err != nil {
+		return c.waitError(ctx, err, "autoboot message", timeout)
+	}
+
+	promptCtx, cancel := context.WithTimeout(ctx, promptTimeout)
+	defer cancel()
+
+	for {
+		err = writeAll(c.eng.p, []byte(c.loader.interrupt))
+		if err != nil {
+			return fmt.Errorf("interrupting the autoboot: %w", err)
+		}
+
+		keyCtx, cancelKey := context.WithTimeout(promptCtx, interruptInterval)
+		_, err = c.eng.readUntil(keyCtx, c.prompt)
+
+		cancelKey()
+
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, context.DeadlineExceeded) || promptCtx.Err() != nil {
+			return c.waitError(ctx, err, "prompt after interrupting the autoboot", promptTimeout)
+		}
+	}
+
+	err = c.awaitPrompt(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.session.Print(fmt.Sprintf("Autoboot stopped at the %s prompt.\n", c.loader.name))
+
+	return nil
+}
+
+// awaitPrompt gets a fresh prompt, discarding what was typed on the command line.
+func (c *loaderConsole) awaitPrompt(ctx context.Context) error {
+	wake := []byte(c.loader.wake)
+	if c.loader.wakeEOL {
+		wake = append(wake, c.eol...)
+	}
+
+	err := c.send(ctx, wake)
+	if err != nil {
+		return err
+	}
+
+	promptCtx, cancel := context.WithTimeout(ctx, promptTimeout)
+	defer cancel()
+
+	_, err = c.eng.readUntil(promptCtx, c.prompt)
+	if err != nil {
+		return c.waitError(ctx, err, "prompt", promptTimeout)
+	}
+
+	return nil
+}
+
+// command runs a command at the prompt and waits for the prompt to return. It
+// prints the command's output to the client and returns it, and fails if the
+// output reports an error.
+func (c *loaderConsole) command(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	err := c.send(ctx, append([]byte(command), c.eol...))
+	if err != nil {
+		return "", err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err = c.eng.readUntil(waitCtx, c.prompt)
+	if err != nil {
+		// Stop the command, e.g. a transfer retrying forever.
+		_ = writeAll(c.eng.p, []byte{ctrlC})
+		c.print(loaderOutput(c.out.String(), false))
+
+		return "", c.waitError(ctx, err, fmt.Sprintf("prompt after %q", command), timeout)
+	}
+
+	output := loaderOutput(c.out.String(), true)
+	c.print(output)
+
+	if c.failed.MatchString(output) {
+		return output, fmt.Errorf("%q failed: %s", command, failureLine(output, c.failed))
+	}
+
+	return output, nil
+}
+
+// boot runs a boot command. The boot succeeds if the bootloader reports the
+// start of the kernel, or does not return to its prompt within bootSettle.
+func (c *loaderConsole) boot(ctx context.Context, command string) error {
+	err := c.send(ctx, append([]byte(command), c.eol...))
+	if err != nil {
+		return err
+	}
+
+	pattern := "(?P<prompt>" + c.prompt.String() + ")"
+	if c.loader.booting != "" {
+		pattern += "|(?P<booting>" + c.loader.booting + ")"
+	}
+
+	settleCtx, cancel := context.WithTimeout(ctx, bootSettle)
+	defer cancel()
+
+	result, err := c.eng.readUntil(settleCtx, regexp.MustCompile(pattern))
+
+	switch {
+	case err == nil && result["prompt"] != "":
+		output := loaderOutput(c.out.String(), true)
+		c.print(output)
+
+		if c.failed.MatchString(output) {
+			return fmt.Errorf("%q failed: %s", command, failureLine(output, c.failed))
+		}
+
+		return fmt.Errorf("%q returned to the %s prompt", command, c.loader.name)
+	case err != nil && (!errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil):
+		return c.waitError(ctx, err, "boot", bootSettle)
+	}
+
+	c.print(loaderOutput(c.out.String(), false))
+	c.session.Print("Boot started.\n")
+
+	return nil
+}
+
+// tftpBoot loads a kernel over TFTP and boots it.
+func (c *loaderConsole) tftpBoot(ctx context.Context, o tftpOptions, timeout time.Duration) error {
+	cmds := c.loader.tftp(o)
+
+	for _, command := range cmds.setup {
+		_, err := c.command(ctx, command, timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, command := range cmds.transfers {
+		output, err := c.command(ctx, command, timeout)
+		if err != nil {
+			return err
+		}
+
+		if cmds.transferred != "" && !regexp.MustCompile(cmds.transferred).MatchString(output) {
+			return fmt.Errorf("%q did not complete the transfer", command)
+		}
+	}
+
+	return c.boot(ctx, cmds.boot)
+}
+
+// send writes data to the port after the configured delay, starting the output
+// of a new command.
+func (c *loaderConsole) send(ctx context.Context, data []byte) error {
+	err := sleepCtx(ctx, c.delay)
+	if err != nil {
+		return err
+	}
+
+	c.out.Reset()
+
+	return c.eng.write(data)
+}
+
+// print passes output on to the client.
+func (c *loaderConsole) print(output string) {
+	if output != "" {
+		c.session.Print(output)
+	}
+}
+
+// waitError describes a failed wait for what, blaming the timeout unless the
+// Run was canceled or the port failed.
+func (c *loaderConsole) waitError(ctx context.Context, err error, what string, timeout time.Duration) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		return fmt.Errorf("no %s %s within %s", c.loader.name, what, timeout)
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		return fmt.Errorf("waiting for the %s %s: %w", c.loader.name, what, err)
+	}
+}
+
+// loaderOutput returns the output of a command from the console text following
+// it: without the echo of the command line and, if atPrompt, without the prompt
+// ending it. Carriage returns are dropped.
+func loaderOutput(text string, atPrompt bool) string {
+	text = strings.ReplaceAll(text, "\r", "")
+
+	_, text, found := strings.Cut(text, "\n")
+	if !found {
+		return ""
+	}
+
+	if atPrompt {
+		end := strings.LastIndexByte(text, '\n')
+		text = text[:end+1]
+	}
+
+	return text
+}
+
+// failureLine returns the line of output reporting a failure.
+func failureLine(output string, failed *regexp.Regexp) string {
+	for line := range strings.Lines(output) {
+		if failed.MatchString(line) {
+			return strings.TrimSpace(line)
+		}
+	}
+
+	return "the bootloader reported an error"
+}
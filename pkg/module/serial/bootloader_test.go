@@ -0,0 +1,351 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeUBoot plays U-Boot on its end of a serial line: it announces the autoboot
+// if autoboot is set, echoes what is typed and answers a few commands. It
+// records the commands run.
+type fakeUBoot struct {
+	p    *linePort
+	env  map[string]string
+	mu   sync.Mutex
+	cmds []string
+}
+
+func (u *fakeUBoot) run(ctx context.Context, autoboot bool) {
+	if autoboot {
+		_, _ = u.p.Write([]byte("U-Boot 2024.01\r\n\r\nHit any key to stop autoboot:  3 "))
+	}
+
+	var (
+		line []byte
+		buf  = make([]byte, readChunk)
+	)
+
+	for ctx.Err() == nil {
+		n, _ := u.p.Read(buf)
+		for _, b := range buf[:n] {
+			switch b {
+			case ctrlC:
+				line = line[:0]
+				_, _ = u.p.Write([]byte("<INTERRUPT>\r\n=> "))
+			case '\r':
+				cmd := strings.TrimSpace(string(line))
+				line = line[:0]
+
+				u.mu.Lock()
+				u.cmds = append(u.cmds, cmd)
+				u.mu.Unlock()
+
+				_, _ = u.p.Write([]byte("\r\n" + u.answer(cmd)))
+			default:
+				if autoboot {
+					// The first key stops the autoboot.
+					autoboot = false
+					_, _ = u.p.Write([]byte("\b\b\b 0 \r\n=> "))
+
+					continue
+				}
+
+				line = append(line, b)
+				_, _ = u.p.Write([]byte{b})
+			}
+		}
+	}
+}
+
+func (u *fakeUBoot) answer(cmd string) string {
+	name, value, _ := strings.Cut(strings.TrimPrefix(cmd, "setenv "), " ")
+
+	switch {
+	case cmd == "":
+	case strings.HasPrefix(cmd, "printenv "):
+		name := strings.TrimPrefix(cmd, "printenv ")
+		if value, ok := u.env[name]; ok {
+			return fmt.Sprintf("%s=%s\r\n=> ", name, value)
+		}
+
+		return fmt.Sprintf("## Error: \"%s\" not defined\r\n=> ", name)
+	case strings.HasPrefix(cmd, "setenv "):
+		u.env[name] = strings.Trim(value, "'")
+	case strings.HasPrefix(cmd, "tftpboot ") && strings.HasSuffix(cmd, "missing"):
+		return "Using ethernet@7d580000 device\r\nTFTP error: 'File not found' (1)\r\nNot retrying...\r\n=> "
+	case strings.HasPrefix(cmd, "tftpboot "):
+		return "Using ethernet@7d580000 device\r\n#####\r\nBytes transferred = 1024 (400 hex)\r\n=> "
+	case strings.HasPrefix(cmd, "booti "):
+		return "## Flattened Device Tree blob at 02600000\r\nStarting kernel ...\r\n\r\n"
+	case cmd == "boot":
+		return "Wrong Image Format for bootm command\r\nERROR: can't get kernel image!\r\n=> "
+	default:
+		return fmt.Sprintf("Unknown command '%s' - try 'help'\r\n=> ", cmd)
+	}
+
+	return "=> "
+}
+
+func (u *fakeUBoot) commands() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return slices.DeleteFunc(slices.Clone(u.cmds), func(cmd string) bool { return cmd == "" })
+}
+
+func TestBootloaderRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		autoboot bool
+		args     []string
+		want     string
+		wantCmds []string
+		wantErr  string
+	}{
+		{
+			name:     "interrupt",
+			autoboot: true,
+			args:     []string{"interrupt"},
+			want:     "Autoboot stopped at the U-Boot prompt.\n",
+		},
+		{
+			name:    "no autoboot",
+			args:    []string{"-t", "200ms", "interrupt"},
+			wantErr: "no U-Boot autoboot message within 200ms",
+		},
+		{
+			name:     "printenv",
+			args:     []string{"printenv", "bootcmd"},
+			want:     "bootcmd=run distro_bootcmd\n",
+			wantCmds: []string{"printenv bootcmd"},
+		},
+		{
+			name:     "printenv undefined",
+			args:     []string{"printenv", "foo"},
+			want:     "## Error: \"foo\" not defined\n",
+			wantCmds: []string{"printenv foo"},
+			wantErr:  `"printenv foo" failed: ## Error: "foo" not defined`,
+		},
+		{
+			name:     "setenv",
+			args:     []string{"setenv", "bootargs", "console=ttyS0 quiet"},
+			wantCmds: []string{"setenv bootargs 'console=ttyS0 quiet'"},
+		},
+		{
+			name: "tftp-boot",
+			args: []string{"tftp-boot", "-server", "10.0.0.1", "-fdt", "board.dtb", "Image"},
+			wantCmds: []string{
+				"setenv serverip 10.0.0.1",
+				"tftpboot ${kernel_addr_r} Image",
+				"tftpboot ${fdt_addr_r} board.dtb",
+				"booti ${kernel_addr_r} - ${fdt_addr_r}",
+			},
+		},
+		{
+			name:     "tftp-boot missing kernel",
+			args:     []string{"tftp-boot", "missing"},
+			wantCmds: []string{"tftpboot ${kernel_addr_r} missing"},
+			wantErr:  "TFTP error: 'File not found' (1)",
+		},
+		{
+			name:     "boot failure",
+			args:     []string{"boot"},
+			wantCmds: []string{"boot"},
+			wantErr:  `"boot" failed: Wrong Image Format for bootm command`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dutSide, agentSide := newLine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			uboot := &fakeUBoot{p: dutSide, env: map[string]string{"bootcmd": "run distro_bootcmd"}}
+			go uboot.run(ctx, tt.autoboot)
+
+			b := &Bootloader{
+				Port:  fmt.Sprintf("/dev/bootloader%d", i),
+				Delay: "0s",
+				open:  func(_ string, _ lineSettings) (port, error) { return agentSide, nil },
+			}
+
+			err := b.Init(context.Background())
+			if err != nil {
+				t.Fatalf("Init = %v", err)
+			}
+
+			sess := &recordingSession{}
+
+			err = b.Run(context.Background(), sess, tt.args...)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run = %v, want nil", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run = %v, want error containing %q", err, tt.wantErr)
+			}
+
+			if tt.want != "" && !strings.HasPrefix(sess.out.String(), tt.want) {
+				t.Errorf("client got %q, want %q first", sess.out.String(), tt.want)
+			}
+
+			if got := uboot.commands(); !slices.Equal(got, tt.wantCmds) {
+				t.Errorf("commands run = %q, want %q", got, tt.wantCmds)
+			}
+		})
+	}
+}
+
+func TestBootloaderParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		loader  string
+		args    []string
+		wantOp  string
+		wantErr bool
+	}{
+		{name: "interrupt", args: []string{"interrupt"}, wantOp: "interrupt"},
+		{name: "printenv all", args: []string{"-t", "5s", "printenv"}, wantOp: "printenv"},
+		{name: "setenv", args: []string{"setenv", "bootdelay", "5"}, wantOp: "setenv"},
+		{name: "boot", args: []string{"boot", "run bootcmd_mmc0"}, wantOp: "boot"},
+		{name: "tftp-boot", args: []string{"tftp-boot", "-initrd", "rootfs.cpio", "Image"}, wantOp: "tftp-boot"},
+		{name: "missing operation", args: []string{}, wantErr: true},
+		{name: "unknown operation", args: []string{"reset"}, wantErr: true},
+		{name: "interrupt with argument", args: []string{"interrupt", "now"}, wantErr: true},
+		{name: "setenv without value", args: []string{"setenv", "bootdelay"}, wantErr: true},
+		{name: "setenv invalid name", args: []string{"setenv", "a;reset", "1"}, wantErr: true},
+		{name: "setenv with quote", args: []string{"setenv", "bootargs", "it's"}, wantErr: true},
+		{name: "tftp-boot without kernel", args: []string{"tftp-boot", "-server", "10.0.0.1"}, wantErr: true},
+		{name: "uefi boot without command", loader: "uefi-shell", args: []string{"boot"}, wantErr: true},
+		{name: "uefi boot", loader: "uefi-shell", args: []string{"boot", `fs0:\EFI\BOOT\BOOTX64.EFI`}, wantOp: "boot"},
+		{name: "uefi tftp-boot", loader: "uefi-shell", args: []string{"tftp-boot", "Image"}, wantErr: true},
+		{name: "grub setenv", loader: "grub", args: []string{"setenv", "root", "(hd0,gpt2)"}, wantOp: "setenv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bootloader{Port: "/dev/ttyUSB0", Loader: tt.loader}
+
+			err := b.Init(context.Background())
+			if err != nil {
+				t.Fatalf("Init = %v", err)
+			}
+
+			op, err := b.parseArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+
+			if err == nil && op.name != tt.wantOp {
+				t.Errorf("parseArgs(%q) = %q, want %q", tt.args, op.name, tt.wantOp)
+			}
+		})
+	}
+}
+
+func TestTFTPCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		tftp func(tftpOptions) tftpCommands
+		opts tftpOptions
+		want []string
+	}{
+		{
+			name: "u-boot kernel",
+			tftp: ubootTFTP,
+			opts: tftpOptions{kernel: "Image", cmd: "booti"},
+			want: []string{"tftpboot ${kernel_addr_r} Image", "booti ${kernel_addr_r}"},
+		},
+		{
+			name: "u-boot all",
+			tftp: ubootTFTP,
+			opts: tftpOptions{server: "10.0.0.1", kernel: "zImage", initrd: "rd", fdt: "dtb", args: "quiet", cmd: "bootz"},
+			want: []string{
+				"setenv serverip 10.0.0.1",
+				"setenv bootargs 'quiet'",
+				"tftpboot ${kernel_addr_r} zImage",
+				"tftpboot ${fdt_addr_r} dtb",
+				"tftpboot ${ramdisk_addr_r} rd",
+				"bootz ${kernel_addr_r} ${ramdisk_addr_r}:${filesize} ${fdt_addr_r}",
+			},
+		},
+		{
+			name: "grub default server",
+			tftp: grubTFTP,
+			opts: tftpOptions{kernel: "/vmlinuz"},
+			want: []string{"linux (tftp)/vmlinuz", "boot"},
+		},
+		{
+			name: "grub all",
+			tftp: grubTFTP,
+			opts: tftpOptions{server: "10.0.0.1", kernel: "vmlinuz", initrd: "initrd.img", args: "console=ttyS0"},
+			want: []string{"linux (tftp,10.0.0.1)/vmlinuz console=ttyS0", "initrd (tftp,10.0.0.1)/initrd.img", "boot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.tftp(tt.opts)
+			got := slices.Concat(c.setup, c.transfers, []string{c.boot})
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("commands = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderOutput(t *testing.T) {
+	tests := []struct {
+		text     string
+		atPrompt bool
+		want     string
+	}{
+		{text: "printenv a\r\na=1\r\n=> ", atPrompt: true, want: "a=1\n"},
+		{text: "setenv a 1\r\n=> ", atPrompt: true, want: ""},
+		{text: "boot\r\nStarting kernel ...\r\n", want: "Starting kernel ...\n"},
+		{text: "boot", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := loaderOutput(tt.text, tt.atPrompt); got != tt.want {
+			t.Errorf("loaderOutput(%q, %t) = %q, want %q", tt.text, tt.atPrompt, got, tt.want)
+		}
+	}
+}
+
+func TestBootloaderInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       Bootloader
+		wantErr bool
+	}{
+		{name: "defaults", b: Bootloader{Port: "/dev/ttyUSB0"}},
+		{name: "grub", b: Bootloader{Port: "/dev/ttyUSB0", Loader: "GRUB"}},
+		{name: "overrides", b: Bootloader{Port: "/dev/ttyUSB0", Prompt: `Marvell>> *$`, Autoboot: `stop autoboot`, InterruptKey: "\x03"}},
+		{name: "missing port", b: Bootloader{}, wantErr: true},
+		{name: "unknown loader", b: Bootloader{Port: "/dev/ttyUSB0", Loader: "lilo"}, wantErr: true},
+		{name: "invalid prompt", b: Bootloader{Port: "/dev/ttyUSB0", Prompt: `(`}, wantErr: true},
+		{name: "invalid autoboot", b: Bootloader{Port: "/dev/ttyUSB0", Autoboot: `[`}, wantErr: true},
+		{name: "no eol", b: Bootloader{Port: "/dev/ttyUSB0", EOL: "none"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.b.Init(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Init = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
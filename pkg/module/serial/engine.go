@@ -5,10 +5,14 @@
 package serial
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"regexp"
 	"time"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 )
 
 // port is the minimal serial-port surface the serial module needs. It is a
@@ -46,6 +50,34 @@ type engine struct {
 	// by -keep-escapes); remainder carries a partial sequence split across reads.
 	filter    bool
 	remainder []byte
+
+	// guards are the expect-not patterns watched so far. seen is a rolling
+	// window of the output read since the first of them, which they are
+	// matched against; unlike buf it is not consumed by a match.
+	guards []guard
+	seen   []byte
+}
+
+// guard is the pattern of an expect-not step, failing the run once the output
+// matches it.
+type guard struct {
+	step  int // the index of the expect-not step
+	label string
+	re    *regexp.Regexp
+	// start is the offset into the engine's seen window the guard applies
+	// from, so it ignores output read before its step.
+	start int
+}
+
+// unwantedOutputError is returned by the engine once the output matched the
+// pattern of an expect-not step.
+type unwantedOutputError struct {
+	step  int
+	label string
+}
+
+func (e *unwantedOutputError) Error() string {
+	return fmt.Sprintf("output matched expect-not %q of step %d", e.label, e.step+1)
 }
 
 func newEngine(p port, sink io.Writer, filter bool) *engine {
@@ -54,20 +86,22 @@ func newEngine(p port, sink io.Writer, filter bool) *engine {
 
 // readUntil reads from the port, forwarding every byte to the sink, until
 // pattern matches the accumulated output or ctx is done (global timeout /
-// cancel).
+// cancel). It returns the text captured by the named groups of pattern.
 // On a match, the matched span and everything before it is consumed from the
 // buffer, so a later expect only sees subsequent output.
-func (e *engine) readUntil(ctx context.Context, pattern *regexp.Regexp) error {
+// It fails with an *unwantedOutputError as soon as the output matches a guard.
+func (e *engine) readUntil(ctx context.Context, pattern *regexp.Regexp) (map[string]string, error) {
 	readBuf := make([]byte, readChunk)
 
 	for {
 		// Check the whole buffer for a match FIRST, so output a prior step left
 		// behind is honored, and so a match is tested before any trimming (it
 		// can never be split at the trim boundary).
-		if loc := pattern.FindIndex(e.buf); loc != nil {
+		if loc := pattern.FindSubmatchIndex(e.buf); loc != nil {
+			captures := capturedGroups(pattern, e.buf, loc)
 			e.buf = e.buf[loc[1]:]
 
-			return nil
+			return captures, nil
 		}
 
 		// No match in the current buffer; bound it before reading more. Safe
@@ -76,7 +110,7 @@ func (e *engine) readUntil(ctx context.Context, pattern *regexp.Regexp) error {
 
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		default:
 		}
 
@@ -88,10 +122,15 @@ func (e *engine) readUntil(ctx context.Context, pattern *regexp.Regexp) error {
 				// at the top of the next iteration).
 				_, werr := e.sink.Write(out)
 				if werr != nil {
-					return werr
+					return nil, werr
 				}
 
 				e.buf = append(e.buf, out...)
+
+				gerr := e.checkGuards(out)
+				if gerr != nil {
+					return nil, gerr
+				}
 			}
 
 			continue
@@ -101,16 +140,104 @@ func (e *engine) readUntil(ctx context.Context, pattern *regexp.Regexp) error {
 		// the ctx check above enforce the overall deadline. Any real error
 		// (port closed / device gone) ends the step.
 		if err != nil {
-			return err
+			return nil, err
+		}
+	}
+}
+
+// feed passes output read from the port outside of the engine on, like output
+// read by readUntil: to the sink, the match buffer and the guards.
+func (e *engine) feed(data []byte) error {
+	out := e.clean(data)
+	if len(out) == 0 {
+		return nil
+	}
+
+	_, err := e.sink.Write(out)
+	if err != nil {
+		return err
+	}
+
+	e.buf = append(e.buf, out...)
+
+	return e.checkGuards(out)
+}
+
+// capturedGroups returns the text matched by the named groups of pattern, given
+// the submatch indices loc of a match in data. It returns nil if pattern has no
+// named groups. A group that did not take part in the match captures "".
+func capturedGroups(pattern *regexp.Regexp, data []byte, loc []int) map[string]string {
+	var captures map[string]string
+
+	for i, name := range pattern.SubexpNames() {
+		if name == "" {
+			continue
+		}
+
+		if captures == nil {
+			captures = make(map[string]string)
+		}
+
+		captures[name] = ""
+
+		if start, end := loc[2*i], loc[2*i+1]; start >= 0 {
+			captures[name] = string(data[start:end])
 		}
 	}
+
+	return captures
+}
+
+// watch adds the pattern of the expect-not step idx as a guard: from now on, the
+// engine fails as soon as the output read matches it.
+func (e *engine) watch(idx int, st step) {
+	e.guards = append(e.guards, guard{step: idx, label: st.label(), re: st.expect, start: len(e.seen)})
+}
+
+// checkGuards adds output to the window the guards are matched against, and
+// returns an *unwantedOutputError if one of them matches. The window is bounded
+// by matchWindow like the match buffer.
+func (e *engine) checkGuards(out []byte) error {
+	if len(e.guards) == 0 {
+		return nil
+	}
+
+	e.seen = append(e.seen, out...)
+
+	for _, g := range e.guards {
+		if g.re.Match(e.seen[g.start:]) {
+			return &unwantedOutputError{step: g.step, label: g.label}
+		}
+	}
+
+	if trim := len(e.seen) - matchWindow; trim > 0 {
+		e.seen = append(e.seen[:0], e.seen[trim:]...)
+
+		for i := range e.guards {
+			e.guards[i].start = max(0, e.guards[i].start-trim)
+		}
+	}
+
+	return nil
 }
 
 // write sends payload to the port, looping over short writes, then resets the
 // match buffer so the next expect starts from output produced after the send.
 func (e *engine) write(payload []byte) error {
+	err := writeAll(e.p, payload)
+	if err != nil {
+		return err
+	}
+
+	e.buf = e.buf[:0]
+
+	return nil
+}
+
+// writeAll writes payload to the port, looping over short writes.
+func writeAll(p port, payload []byte) error {
 	for len(payload) > 0 {
-		n, err := e.p.Write(payload)
+		n, err := p.Write(payload)
 		if err != nil {
 			return err
 		}
@@ -118,8 +245,6 @@ func (e *engine) write(payload []byte) error {
 		payload = payload[n:]
 	}
 
-	e.buf = e.buf[:0]
-
 	return nil
 }
 
@@ -138,7 +263,8 @@ func (e *engine) capBuffer() {
 // done (a normal end for a watch/drain, so it returns nil). On a real read
 // error it returns the error, unless swallowReadErr is set — used by the
 // post-send drain, where the device may have rebooted from the final send and
-// the sequence has already completed successfully.
+// the sequence has already completed successfully. Output matching a guard
+// fails it either way.
 func (e *engine) pump(ctx context.Context, swallowReadErr bool) error {
 	readBuf := make([]byte, readChunk)
 
@@ -157,6 +283,11 @@ func (e *engine) pump(ctx context.Context, swallowReadErr bool) error {
 				if werr != nil {
 					return werr
 				}
+
+				gerr := e.checkGuards(out)
+				if gerr != nil {
+					return gerr
+				}
 			}
 		}
 
@@ -177,6 +308,144 @@ func (e *engine) monitor(ctx context.Context) error {
 	return e.pump(ctx, false)
 }
 
+// console is the client's side of an interactive session: the keys typed by
+// the user and the signals passed on by the client.
+type console struct {
+	input   io.Reader
+	signals <-chan module.Signal
+	// eol replaces every newline in the input, so the Enter key sends what the
+	// DUT expects; nil passes newlines through.
+	eol []byte
+	// escape is the key ending the session; 0 disables it.
+	escape byte
+}
+
+// Control characters written to the port for the signals passed on by a client
+// without a raw terminal; a raw terminal sends them as input instead.
+const (
+	ctrlC         = 0x03
+	ctrlBackslash = 0x1c
+)
+
+// interact connects the port to the client's console in both directions: it
+// forwards the output to the sink like monitor and writes the console's input to
+// the port. It ends when ctx is done or the input contains the escape key, both
+// a normal end returning nil, and fails on a real read or write error. Input the
+// console delivers after the session ended is lost.
+func (e *engine) interact(ctx context.Context, con console) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The input is read on its own goroutine, as a read from the console cannot
+	// be interrupted; it exits with the session once interact returned.
+	input := make(chan []byte)
+
+	go func() {
+		for {
+			buf := make([]byte, readChunk)
+
+			n, err := con.input.Read(buf)
+			if n > 0 {
+				select {
+				case input <- buf[:n]:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// The writer runs until ctx is done, and cancels it on the escape key or a
+	// failed write, which ends the output pump below.
+	writeErr := make(chan error, 1)
+
+	go func() {
+		writeErr <- e.forwardInput(ctx, con, input)
+
+		cancel()
+	}()
+
+	err := e.pump(ctx, false)
+
+	cancel()
+
+	werr := <-writeErr
+	if werr != nil {
+		return werr
+	}
+
+	return err
+}
+
+// forwardInput writes the console's input and signals to the port until ctx is
+// done or the input contains the escape key, both returning nil, or a write
+// fails.
+func (e *engine) forwardInput(ctx context.Context, con console, input <-chan []byte) error {
+	for {
+		var (
+			payload []byte
+			escaped bool
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case chunk := <-input:
+			payload, escaped = con.translate(chunk)
+		case sig := <-con.signals:
+			key, ok := signalKey(sig)
+			if !ok {
+				// The end of the client's input does not end the session;
+				// it stays open until ctx is done or the escape key.
+				continue
+			}
+
+			payload = []byte{key}
+		}
+
+		err := writeAll(e.p, payload)
+		if err != nil || escaped {
+			return err
+		}
+	}
+}
+
+// translate returns the part of a chunk of input to write to the port: up to the
+// escape key, if the chunk contains it, with the newlines replaced by eol. It
+// reports whether the chunk contained the escape key.
+func (con console) translate(chunk []byte) ([]byte, bool) {
+	escaped := false
+
+	if con.escape != 0 {
+		if i := bytes.IndexByte(chunk, con.escape); i >= 0 {
+			chunk, escaped = chunk[:i], true
+		}
+	}
+
+	if con.eol != nil {
+		chunk = bytes.ReplaceAll(chunk, []byte("\n"), con.eol)
+	}
+
+	return chunk, escaped
+}
+
+// signalKey returns the control character a signal from the client is written
+// to the port as. It reports false for a signal without one.
+func signalKey(sig module.Signal) (byte, bool) {
+	switch sig {
+	case module.Interrupt:
+		return ctrlC, true
+	case module.Quit:
+		return ctrlBackslash, true
+	default:
+		return 0, false
+	}
+}
+
 // drain forwards serial output for up to d, so the DUT's reply to a final send
 // is visible before the run closes. It is best-effort: a read error (e.g. the
 // device rebooted from that send) ends it without failing the run.
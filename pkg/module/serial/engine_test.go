@@ -8,9 +8,14 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
+	"maps"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 )
 
 // fakePort is a test double for the port interface.
@@ -25,6 +30,7 @@ type fakePort struct {
 	written    []byte
 	closed     bool
 	resetCount int
+	controls   []string // the control lines set, like "dtr off"
 }
 
 func (f *fakePort) Read(p []byte) (int, error) {
@@ -53,6 +59,20 @@ func (f *fakePort) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+func (f *fakePort) SetDTR(on bool) error { return f.control("dtr", on) }
+func (f *fakePort) SetRTS(on bool) error { return f.control("rts", on) }
+
+func (f *fakePort) control(line string, on bool) error {
+	state := "off"
+	if on {
+		state = "on"
+	}
+
+	f.controls = append(f.controls, line+" "+state)
+
+	return nil
+}
+
 func (f *fakePort) ResetInputBuffer() error { f.resetCount++; return nil }
 func (f *fakePort) Close() error            { f.closed = true; return nil }
 
@@ -66,7 +86,7 @@ func TestEngineReadUntilMatchesAcrossReads(t *testing.T) {
 	sink := &bytes.Buffer{}
 	eng := newEngine(fp, sink, false)
 
-	if err := eng.readUntil(context.Background(), regexp.MustCompile("bar")); err != nil {
+	if _, err := eng.readUntil(context.Background(), regexp.MustCompile("bar")); err != nil {
 		t.Fatalf("readUntil: %v", err)
 	}
 
@@ -86,11 +106,11 @@ func TestEngineSequentialExpectsConsumeBuffer(t *testing.T) {
 	fp := &fakePort{reads: [][]byte{[]byte("foobar baz")}, readErr: errExhausted}
 	eng := newEngine(fp, &bytes.Buffer{}, false)
 
-	if err := eng.readUntil(context.Background(), regexp.MustCompile("bar")); err != nil {
+	if _, err := eng.readUntil(context.Background(), regexp.MustCompile("bar")); err != nil {
 		t.Fatalf("first expect: %v", err)
 	}
 
-	if err := eng.readUntil(context.Background(), regexp.MustCompile("baz")); err != nil {
+	if _, err := eng.readUntil(context.Background(), regexp.MustCompile("baz")); err != nil {
 		t.Fatalf("second expect (should match buffered tail without reading): %v", err)
 	}
 }
@@ -103,7 +123,7 @@ func TestEngineReadUntilTimeout(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
 	defer cancel()
 
-	err := eng.readUntil(ctx, regexp.MustCompile("never"))
+	_, err := eng.readUntil(ctx, regexp.MustCompile("never"))
 	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Errorf("err = %v, want context.DeadlineExceeded", err)
 	}
@@ -113,7 +133,7 @@ func TestEngineReadUntilPortError(t *testing.T) {
 	fp := &fakePort{reads: nil, readErr: errExhausted}
 	eng := newEngine(fp, &bytes.Buffer{}, false)
 
-	err := eng.readUntil(context.Background(), regexp.MustCompile("x"))
+	_, err := eng.readUntil(context.Background(), regexp.MustCompile("x"))
 	if !errors.Is(err, errExhausted) {
 		t.Errorf("err = %v, want errExhausted", err)
 	}
@@ -169,6 +189,70 @@ func TestEngineMonitorForwardsUntilCtxDone(t *testing.T) {
 	}
 }
 
+func TestEngineInteractEndsOnEscapeKey(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("=> ")}} // then quiet
+	sink := &bytes.Buffer{}
+	eng := newEngine(fp, sink, false)
+
+	con := console{input: strings.NewReader("help\nboot\x14ignored"), eol: []byte("\r"), escape: 0x14}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := eng.interact(ctx, con); err != nil {
+		t.Fatalf("interact: %v", err)
+	}
+
+	if ctx.Err() != nil {
+		t.Error("interact did not end on the escape key")
+	}
+
+	if got, want := string(fp.written), "help\rboot"; got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+
+	if got, want := sink.String(), "=> "; got != want {
+		t.Errorf("sink = %q, want %q", got, want)
+	}
+}
+
+func TestEngineInteractWritesSignals(t *testing.T) {
+	fp := &fakePort{} // quiet
+	eng := newEngine(fp, &bytes.Buffer{}, false)
+
+	signals := make(chan module.Signal, 3)
+	signals <- module.Interrupt
+	signals <- module.EOF
+	signals <- module.Quit
+
+	// An input that never delivers anything, like an idle console.
+	input, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := eng.interact(ctx, console{input: input, signals: signals}); err != nil {
+		t.Fatalf("interact: %v", err)
+	}
+
+	if got, want := string(fp.written), "\x03\x1c"; got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+}
+
+func TestEngineInteractReturnsReadError(t *testing.T) {
+	fp := &fakePort{readErr: errExhausted}
+	eng := newEngine(fp, &bytes.Buffer{}, false)
+
+	input, w := io.Pipe()
+	defer w.Close()
+
+	if err := eng.interact(context.Background(), console{input: input}); !errors.Is(err, errExhausted) {
+		t.Errorf("interact err = %v, want errExhausted", err)
+	}
+}
+
 func TestEngineMonitorReturnsReadError(t *testing.T) {
 	fp := &fakePort{reads: nil, readErr: errExhausted}
 	eng := newEngine(fp, &bytes.Buffer{}, false)
@@ -256,7 +340,7 @@ func TestEngineReadUntilFiltersEscapes(t *testing.T) {
 	sink := &bytes.Buffer{}
 	eng := newEngine(fp, sink, true)
 
-	if err := eng.readUntil(context.Background(), regexp.MustCompile("login:")); err != nil {
+	if _, err := eng.readUntil(context.Background(), regexp.MustCompile("login:")); err != nil {
 		t.Fatalf("readUntil: %v", err)
 	}
 
@@ -264,3 +348,40 @@ func TestEngineReadUntilFiltersEscapes(t *testing.T) {
 		t.Errorf("sink = %q, want %q (escapes stripped)", got, want)
 	}
 }
+
+func TestEngineReadUntilCaptures(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("DHCP lease 10.0.0."), []byte("5 obtained\n")}, readErr: errExhausted}
+	eng := newEngine(fp, &bytes.Buffer{}, false)
+
+	captures, err := eng.readUntil(context.Background(), regexp.MustCompile(`lease (?P<ip>[0-9.]+) (?P<how>obtained|renewed)(?P<none>x)?`))
+	if err != nil {
+		t.Fatalf("readUntil: %v", err)
+	}
+
+	want := map[string]string{"ip": "10.0.0.5", "how": "obtained", "none": ""}
+	if !maps.Equal(captures, want) {
+		t.Errorf("captures = %v, want %v", captures, want)
+	}
+}
+
+func TestEngineGuard(t *testing.T) {
+	fp := &fakePort{
+		reads:   [][]byte{[]byte("Kernel panic before the watch\n"), []byte("booting... Kernel pa"), []byte("nic - not syncing")},
+		readErr: errExhausted,
+	}
+	eng := newEngine(fp, &bytes.Buffer{}, false)
+
+	// Read the first line before the guard is in place, so it must not trip it.
+	if _, err := eng.readUntil(context.Background(), regexp.MustCompile(`watch\n`)); err != nil {
+		t.Fatalf("readUntil: %v", err)
+	}
+
+	eng.watch(1, step{kind: stepExpectNot, expect: regexp.MustCompile("Kernel panic"), src: "Kernel panic"})
+
+	_, err := eng.readUntil(context.Background(), regexp.MustCompile("login:"))
+
+	var unwanted *unwantedOutputError
+	if !errors.As(err, &unwanted) || unwanted.step != 1 {
+		t.Fatalf("err = %v, want an unwantedOutputError of step index 1", err)
+	}
+}
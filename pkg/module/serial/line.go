@@ -0,0 +1,204 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.bug.st/serial"
+	"golang.org/x/sys/unix"
+)
+
+// Defaults of the line settings besides the baud rate: 8N1 without flow control.
+const (
+	defaultDataBits = 8
+	minDataBits     = 5
+)
+
+// flowControl is the flow control of a serial line.
+type flowControl int
+
+const (
+	flowNone flowControl = iota
+	flowXonXoff
+	flowRTSCTS
+)
+
+func (f flowControl) String() string {
+	switch f {
+	case flowXonXoff:
+		return "xon-xoff"
+	case flowRTSCTS:
+		return "rts-cts"
+	default:
+		return "none"
+	}
+}
+
+// lineSettings are the settings of a serial line. Commands sharing a port must
+// agree on them.
+type lineSettings struct {
+	baud     int
+	dataBits int
+	parity   serial.Parity
+	stopBits serial.StopBits
+	flow     flowControl
+}
+
+// newLineSettings parses the line settings as configured. Empty values select
+// the defaults: 8 data bits, no parity, 1 stop bit and no flow control.
+//
+//nolint:cyclop // a flat parser of four options
+func newLineSettings(baud, dataBits int, parity, stopBits, flow string) (lineSettings, error) {
+	line := lineSettings{baud: baud, dataBits: dataBits}
+
+	if line.dataBits == 0 {
+		line.dataBits = defaultDataBits
+	}
+
+	if line.dataBits < minDataBits || line.dataBits > defaultDataBits {
+		return lineSettings{}, fmt.Errorf("invalid data-bits %d: want 5 to 8", dataBits)
+	}
+
+	switch strings.ToLower(parity) {
+	case "", "none":
+		line.parity = serial.NoParity
+	case "odd":
+		line.parity = serial.OddParity
+	case "even":
+		line.parity = serial.EvenParity
+	case "mark":
+		line.parity = serial.MarkParity
+	case "space":
+		line.parity = serial.SpaceParity
+	default:
+		return lineSettings{}, fmt.Errorf("invalid parity %q: want none, odd, even, mark or space", parity)
+	}
+
+	switch stopBits {
+	case "", "1":
+		line.stopBits = serial.OneStopBit
+	case "1.5":
+		line.stopBits = serial.OnePointFiveStopBits
+	case "2":
+		line.stopBits = serial.TwoStopBits
+	default:
+		return lineSettings{}, fmt.Errorf("invalid stop-bits %q: want 1, 1.5 or 2", stopBits)
+	}
+
+	switch strings.ToLower(flow) {
+	case "", "none":
+		line.flow = flowNone
+	case "xon-xoff":
+		line.flow = flowXonXoff
+	case "rts-cts":
+		line.flow = flowRTSCTS
+	default:
+		return lineSettings{}, fmt.Errorf("invalid flow %q: want none, xon-xoff or rts-cts", flow)
+	}
+
+	return line, nil
+}
+
+// String describes the line settings like "115200 baud, 7E1, rts-cts flow control".
+func (l lineSettings) String() string {
+	parity := map[serial.Parity]string{
+		serial.NoParity:    "N",
+		serial.OddParity:   "O",
+		serial.EvenParity:  "E",
+		serial.MarkParity:  "M",
+		serial.SpaceParity: "S",
+	}[l.parity]
+
+	stopBits := map[serial.StopBits]string{
+		serial.OneStopBit:           "1",
+		serial.OnePointFiveStopBits: "1.5",
+		serial.TwoStopBits:          "2",
+	}[l.stopBits]
+
+	desc := fmt.Sprintf("%d baud, %d%s%s", l.baud, l.dataBits, parity, stopBits)
+	if l.flow != flowNone {
+		desc += fmt.Sprintf(", %s flow control", l.flow)
+	}
+
+	return desc
+}
+
+// mode returns the mode of a local serial port with the line settings.
+func (l lineSettings) mode() *serial.Mode {
+	return &serial.Mode{
+		BaudRate: l.baud,
+		DataBits: l.dataBits,
+		Parity:   l.parity,
+		StopBits: l.stopBits,
+	}
+}
+
+// setFlowControl sets the flow control of the local serial device open as fd.
+// go.bug.st/serial does not support flow control and disables it when it opens
+// a port, so it is set on another descriptor of the device afterwards: the
+// terminal settings are shared by all descriptors of a device.
+func setFlowControl(fd int, flow flowControl) error {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return err
+	}
+
+	termios.Iflag &^= unix.IXON | unix.IXOFF
+	termios.Cflag &^= unix.CRTSCTS
+
+	switch flow {
+	case flowXonXoff:
+		termios.Iflag |= unix.IXON | unix.IXOFF
+	case flowRTSCTS:
+		termios.Cflag |= unix.CRTSCTS
+	case flowNone:
+	}
+
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, termios)
+}
+
+// controlLines is implemented by ports whose modem control lines can be set:
+// local serial ports and RFC 2217 ports.
+type controlLines interface {
+	SetDTR(on bool) error
+	SetRTS(on bool) error
+}
+
+// errNoControlLines is returned when setting a control line of a port without,
+// like a raw TCP port.
+var errNoControlLines = errors.New("the serial port has no control lines")
+
+// controlLine names a modem control line set by a step.
+type controlLine int
+
+const (
+	lineDTR controlLine = iota
+	lineRTS
+)
+
+func (c controlLine) String() string {
+	if c == lineRTS {
+		return "rts"
+	}
+
+	return "dtr"
+}
+
+// set sets the control line of p.
+func (c controlLine) set(p port, on bool) error {
+	ctl, ok := p.(controlLines)
+	if !ok {
+		return errNoControlLines
+	}
+
+	if c == lineRTS {
+		return ctl.SetRTS(on)
+	}
+
+	return ctl.SetDTR(on)
+}
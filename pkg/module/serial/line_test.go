@@ -0,0 +1,72 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"testing"
+
+	"go.bug.st/serial"
+)
+
+func TestNewLineSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataBits int
+		parity   string
+		stopBits string
+		flow     string
+		want     lineSettings
+		wantErr  bool
+	}{
+		{name: "defaults", want: lineSettings{baud: 9600, dataBits: 8}},
+		{
+			name:     "7E1 with hardware flow control",
+			dataBits: 7, parity: "even", stopBits: "1", flow: "rts-cts",
+			want: lineSettings{baud: 9600, dataBits: 7, parity: serial.EvenParity, stopBits: serial.OneStopBit, flow: flowRTSCTS},
+		},
+		{
+			name:   "odd parity, 2 stop bits, software flow control",
+			parity: "Odd", stopBits: "2", flow: "xon-xoff",
+			want: lineSettings{baud: 9600, dataBits: 8, parity: serial.OddParity, stopBits: serial.TwoStopBits, flow: flowXonXoff},
+		},
+		{name: "too many data bits", dataBits: 9, wantErr: true},
+		{name: "too few data bits", dataBits: 4, wantErr: true},
+		{name: "bad parity", parity: "e", wantErr: true},
+		{name: "bad stop bits", stopBits: "3", wantErr: true},
+		{name: "bad flow", flow: "hardware", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newLineSettings(9600, tt.dataBits, tt.parity, tt.stopBits, tt.flow)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newLineSettings error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("newLineSettings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineSettingsString(t *testing.T) {
+	tests := []struct {
+		line lineSettings
+		want string
+	}{
+		{line: lineSettings{baud: 115200, dataBits: 8}, want: "115200 baud, 8N1"},
+		{
+			line: lineSettings{baud: 9600, dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits, flow: flowRTSCTS},
+			want: "9600 baud, 7E2, rts-cts flow control",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.line.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+)
+
+// observerQueue is the number of output chunks buffered for an observer. An
+// observer falling further behind loses output rather than stalling the others.
+const observerQueue = 256
+
+// errWriteBusy is returned by attach if write access to the port is held by
+// another command. It is wrapped with the port name and reported as-is.
+var errWriteBusy = errors.New("write access is held by another command")
+
+// errNoWriteAccess is returned by a write of an observer attached read-only.
+var errNoWriteAccess = errors.New("no write access to the serial port")
+
+// errPortClosed is returned by a read of an observer that was detached.
+var errPortClosed = errors.New("serial port closed")
+
+// muxes holds the multiplexer of every serial port in use, keyed by port name,
+// so all commands of the agent share one connection per port.
+//
+//nolint:gochecknoglobals // the agent-wide registry of shared ports
+var (
+	muxes   = make(map[string]*portMux)
+	muxesMu sync.Mutex
+)
+
+// attach returns an observer of the named port, opening the port with open if no
+// command uses it yet. With write set, the observer also holds write access to
+// the port, which fails with errWriteBusy if another observer holds it. The
+// observer must be closed to detach from the port; the port is closed once the
+// last observer detached. The returned error is opaque apart from errWriteBusy.
+func attach(name string, line lineSettings, open portOpener, write bool) (*observer, error) {
+	muxesMu.Lock()
+	defer muxesMu.Unlock()
+
+	mux, ok := muxes[name]
+	if ok && mux.failed() {
+		// The port failed (e.g. the device is gone); try to open it afresh.
+		ok = false
+	}
+
+	if !ok {
+		p, err := open(name, line)
+		if err != nil {
+			return nil, err
+		}
+
+		mux = newPortMux(name, line, p)
+		muxes[name] = mux
+	}
+
+	if mux.line != line {
+		return nil, fmt.Errorf("serial port %s is already in use at %s", name, mux.line)
+	}
+
+	obs, err := mux.attach(write)
+	if err != nil {
+		return nil, fmt.Errorf("serial port %s: %w", name, err)
+	}
+
+	return obs, nil
+}
+
+// portMux owns an open serial port and shares it among the commands using it.
+// A pump goroutine reads the port and fans the output out to any number of
+// observers, while write access is granted to a single observer at a time.
+type portMux struct {
+	name string
+	line lineSettings
+	p    port
+	log  *slog.Logger
+
+	mu        sync.Mutex
+	observers map[*observer]struct{}
+	writer    *observer // the observer holding write access, if any
+	err       error     // the read error that ended the pump, if any
+
+	// writeMu serializes writes to the port.
+	writeMu sync.Mutex
+
+	// stop asks the pump to close the port and exit, stopped is closed once it did.
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newPortMux starts sharing the open port p.
+func newPortMux(name string, line lineSettings, p port) *portMux {
+	mux := &portMux{
+		name:      name,
+		line:      line,
+		p:         p,
+		log:       log.Scope(slog.Default(), "serial").With("port", name),
+		observers: make(map[*observer]struct{}),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	// Discard any stale bytes left in the kernel/driver RX buffer from a
+	// previous session, otherwise a step could match data from the last boot.
+	err := p.ResetInputBuffer()
+	if err != nil {
+		mux.log.Warn("reset input buffer failed", "err", err)
+	}
+
+	go mux.pump()
+
+	return mux
+}
+
+// attach adds an observer, holding write access if write is set. It fails with
+// the read error if the pump already ended.
+func (m *portMux) attach(write bool) (*observer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if write && m.writer != nil {
+		return nil, errWriteBusy
+	}
+
+	obs := &observer{mux: m, out: make(chan []byte, observerQueue)}
+	m.observers[obs] = struct{}{}
+
+	if write {
+		m.writer = obs
+	}
+
+	m.log.Debug("observer attached", "write", write, "observers", len(m.observers))
+
+	return obs, nil
+}
+
+// detach removes obs, releasing its write access, and closes the port if it was
+// the last observer.
+func (m *portMux) detach(obs *observer) {
+	muxesMu.Lock()
+	defer muxesMu.Unlock()
+
+	m.mu.Lock()
+
+	delete(m.observers, obs)
+
+	if m.writer == obs {
+		m.writer = nil
+	}
+
+	left := len(m.observers)
+
+	m.mu.Unlock()
+
+	m.log.Debug("observer detached", "observers", left)
+
+	if left > 0 {
+		return
+	}
+
+	if muxes[m.name] == m {
+		delete(muxes, m.name)
+	}
+
+	// Wait for the port to be closed while holding muxesMu, so it is not
+	// opened again before. The pump exits within a read timeout.
+	select {
+	case <-m.stopped:
+	default:
+		close(m.stop)
+		<-m.stopped
+	}
+}
+
+// holds reports whether obs holds write access.
+func (m *portMux) holds(obs *observer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.writer == obs
+}
+
+// failed reports whether the pump ended with a read error.
+func (m *portMux) failed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err != nil
+}
+
+// pump reads the port and fans its output out to the observers until it is
+// stopped or a read fails. Either way it closes the port; a read error is
+// passed on to the observers.
+func (m *portMux) pump() {
+	defer close(m.stopped)
+
+	buf := make([]byte, readChunk)
+
+	for {
+		select {
+		case <-m.stop:
+			m.close()
+
+			return
+		default:
+		}
+
+		n, err := m.p.Read(buf)
+		if n > 0 {
+			m.fanOut(bytes.Clone(buf[:n]))
+		}
+
+		// A timed-out read returns (0, nil); any error means the port is gone.
+		if err != nil {
+			m.log.Warn("reading serial port failed", "err", err)
+			m.fail(err)
+			m.close()
+
+			return
+		}
+	}
+}
+
+// fanOut passes chunk on to every observer, dropping it for an observer whose
+// queue is full.
+func (m *portMux) fanOut(chunk []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for obs := range m.observers {
+		select {
+		case obs.out <- chunk:
+		default:
+			if obs.dropped == 0 {
+				m.log.Warn("observer does not keep up, dropping serial output")
+			}
+
+			obs.dropped += len(chunk)
+		}
+	}
+}
+
+// fail records the read error that ended the pump and ends the observers' output.
+func (m *portMux) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+
+	for obs := range m.observers {
+		close(obs.out)
+	}
+}
+
+// failure returns the read error that ended the pump.
+func (m *portMux) failure() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+func (m *portMux) close() {
+	err := m.p.Close()
+	if err != nil {
+		m.log.Warn("closing serial port failed", "err", err)
+	}
+
+	m.log.Debug("serial port closed")
+}
+
+// observer is a command's handle on a shared serial port. It implements port, so
+// the engine drives it like a port of its own: it reads the output produced since
+// it attached and, if it holds write access, writes to the port.
+type observer struct {
+	mux *portMux
+	out chan []byte
+
+	// pending holds the rest of a chunk that did not fit into the last read.
+	pending []byte
+	// dropped counts the bytes lost because the observer did not keep up. It
+	// is guarded by the mux's mu.
+	dropped int
+
+	closeOnce sync.Once
+	closed    bool
+}
+
+// Read returns the next output of the port. Like a serial port with a read
+// timeout it returns (0, nil) if there was no output for readTimeout, so the
+// caller can re-check its context. It returns the port's read error once the
+// port failed, and errPortClosed after Close.
+func (o *observer) Read(p []byte) (int, error) {
+	if o.closed {
+		return 0, errPortClosed
+	}
+
+	if len(o.pending) == 0 {
+		timer := time.NewTimer(readTimeout)
+		defer timer.Stop()
+
+		select {
+		case chunk, ok := <-o.out:
+			if !ok {
+				return 0, o.mux.failure()
+			}
+
+			o.pending = chunk
+		case <-timer.C:
+			return 0, nil
+		}
+	}
+
+	n := copy(p, o.pending)
+	o.pending = o.pending[n:]
+
+	return n, nil
+}
+
+// Write writes p to the port if the observer holds write access, otherwise it
+// fails with errNoWriteAccess.
+func (o *observer) Write(p []byte) (int, error) {
+	if !o.mux.holds(o) {
+		return 0, errNoWriteAccess
+	}
+
+	o.mux.writeMu.Lock()
+	defer o.mux.writeMu.Unlock()
+
+	return o.mux.p.Write(p)
+}
+
+// SetDTR sets the DTR line of the port if the observer holds write access.
+func (o *observer) SetDTR(on bool) error {
+	return o.control(lineDTR, on)
+}
+
+// SetRTS sets the RTS line of the port if the observer holds write access.
+func (o *observer) SetRTS(on bool) error {
+	return o.control(lineRTS, on)
+}
+
+func (o *observer) control(line controlLine, on bool) error {
+	if !o.mux.holds(o) {
+		return errNoWriteAccess
+	}
+
+	o.mux.writeMu.Lock()
+	defer o.mux.writeMu.Unlock()
+
+	return line.set(o.mux.p, on)
+}
+
+// ResetInputBuffer discards the output the observer did not read yet. It leaves
+// the port itself alone, which other observers may be reading.
+func (o *observer) ResetInputBuffer() error {
+	o.pending = nil
+
+	for {
+		select {
+		case _, ok := <-o.out:
+			if !ok {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// Close detaches the observer from the port. It is safe to call more than once.
+func (o *observer) Close() error {
+	o.closeOnce.Do(func() {
+		o.closed = true
+		o.mux.detach(o)
+	})
+
+	return nil
+}
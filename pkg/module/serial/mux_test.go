@@ -0,0 +1,291 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// feedPort is a port fed by the test through a channel, safe for the mux's pump
+// goroutine to read while the test writes. A closed feed ends reads with io.EOF.
+type feedPort struct {
+	feed chan []byte
+
+	mu      sync.Mutex
+	written []byte
+	closed  bool
+}
+
+func newFeedPort() *feedPort {
+	return &feedPort{feed: make(chan []byte)}
+}
+
+func (f *feedPort) Read(p []byte) (int, error) {
+	select {
+	case chunk, ok := <-f.feed:
+		if !ok {
+			return 0, io.EOF
+		}
+
+		return copy(p, chunk), nil
+	case <-time.After(10 * time.Millisecond):
+		return 0, nil
+	}
+}
+
+func (f *feedPort) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.written = append(f.written, p...)
+
+	return len(p), nil
+}
+
+func (f *feedPort) ResetInputBuffer() error { return nil }
+
+func (f *feedPort) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+
+	return nil
+}
+
+func (f *feedPort) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.closed
+}
+
+// countingOpener returns a portOpener handing out fp and counting the opens.
+func countingOpener(fp *feedPort, opens *int) portOpener {
+	return func(_ string, _ lineSettings) (port, error) {
+		*opens++
+
+		return fp, nil
+	}
+}
+
+// readString reads from obs until it got want or a second passed.
+func readString(t *testing.T, obs *observer, want string) {
+	t.Helper()
+
+	var got []byte
+
+	buf := make([]byte, readChunk)
+	deadline := time.Now().Add(time.Second)
+
+	for len(got) < len(want) && time.Now().Before(deadline) {
+		n, err := obs.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+
+		got = append(got, buf[:n]...)
+	}
+
+	if string(got) != want {
+		t.Fatalf("read %q, want %q", got, want)
+	}
+}
+
+func TestMuxFansOutToObservers(t *testing.T) {
+	fp := newFeedPort()
+	opens := 0
+	open := countingOpener(fp, &opens)
+
+	first, err := attach("/dev/mux-fanout", lineSettings{baud: 115200}, open, false)
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+
+	second, err := attach("/dev/mux-fanout", lineSettings{baud: 115200}, open, false)
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+
+	if opens != 1 {
+		t.Errorf("port opened %d times, want once", opens)
+	}
+
+	fp.feed <- []byte("U-Boot 2024.01\n")
+
+	readString(t, first, "U-Boot 2024.01\n")
+	readString(t, second, "U-Boot 2024.01\n")
+
+	first.Close()
+
+	if fp.isClosed() {
+		t.Fatal("port closed while an observer is still attached")
+	}
+
+	second.Close()
+
+	if !fp.isClosed() {
+		t.Fatal("port not closed after the last observer detached")
+	}
+
+	if _, err := first.Read(make([]byte, 1)); !errors.Is(err, errPortClosed) {
+		t.Errorf("Read after Close = %v, want errPortClosed", err)
+	}
+}
+
+func TestMuxSingleWriter(t *testing.T) {
+	fp := newFeedPort()
+	opens := 0
+	open := countingOpener(fp, &opens)
+
+	writer, err := attach("/dev/mux-writer", lineSettings{baud: 115200}, open, true)
+	if err != nil {
+		t.Fatalf("attach writer: %v", err)
+	}
+
+	_, err = attach("/dev/mux-writer", lineSettings{baud: 115200}, open, true)
+	if !errors.Is(err, errWriteBusy) {
+		t.Fatalf("second writer: err = %v, want errWriteBusy", err)
+	}
+
+	reader, err := attach("/dev/mux-writer", lineSettings{baud: 115200}, open, false)
+	if err != nil {
+		t.Fatalf("attach reader: %v", err)
+	}
+	defer reader.Close()
+
+	if _, err := reader.Write([]byte("x")); !errors.Is(err, errNoWriteAccess) {
+		t.Errorf("reader Write = %v, want errNoWriteAccess", err)
+	}
+
+	if _, err := writer.Write([]byte("boot\r")); err != nil {
+		t.Fatalf("writer Write: %v", err)
+	}
+
+	writer.Close()
+
+	// Write access is free again once the writer detached.
+	next, err := attach("/dev/mux-writer", lineSettings{baud: 115200}, open, true)
+	if err != nil {
+		t.Fatalf("attach after the writer detached: %v", err)
+	}
+	defer next.Close()
+
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	if got := string(fp.written); got != "boot\r" {
+		t.Errorf("written = %q, want %q", got, "boot\r")
+	}
+}
+
+func TestMuxLineMismatch(t *testing.T) {
+	fp := newFeedPort()
+	opens := 0
+
+	obs, err := attach("/dev/mux-baud", lineSettings{baud: 115200}, countingOpener(fp, &opens), false)
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	defer obs.Close()
+
+	if _, err := attach("/dev/mux-baud", lineSettings{baud: 9600}, countingOpener(fp, &opens), false); err == nil {
+		t.Error("attach at another baud rate = nil error, want error")
+	}
+
+	if _, err := attach("/dev/mux-baud", lineSettings{baud: 115200, parity: serial.EvenParity}, countingOpener(fp, &opens), false); err == nil {
+		t.Error("attach with another parity = nil error, want error")
+	}
+}
+
+func TestMuxReadErrorReopens(t *testing.T) {
+	fp := newFeedPort()
+	opens := 0
+	open := countingOpener(fp, &opens)
+
+	obs, err := attach("/dev/mux-gone", lineSettings{baud: 115200}, open, false)
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	defer obs.Close()
+
+	close(fp.feed) // the device is gone
+
+	buf := make([]byte, readChunk)
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		_, err = obs.Read(buf)
+		if err != nil || time.Now().After(deadline) {
+			break
+		}
+	}
+
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("Read = %v, want the port's read error", err)
+	}
+
+	// A later command opens the port afresh.
+	fp.feed = make(chan []byte)
+
+	again, err := attach("/dev/mux-gone", lineSettings{baud: 115200}, open, false)
+	if err != nil {
+		t.Fatalf("attach after the failure: %v", err)
+	}
+	defer again.Close()
+
+	if opens != 2 {
+		t.Errorf("port opened %d times, want twice", opens)
+	}
+}
+
+func TestSerialRunSharesPort(t *testing.T) {
+	fp := newFeedPort()
+	opens := 0
+
+	monitor := &Serial{Port: "/dev/mux-shared", Baud: 115200, open: countingOpener(fp, &opens)}
+	script := &Serial{Port: "/dev/mux-shared", Baud: 115200, open: countingOpener(fp, &opens)}
+
+	monitorSess := &recordingSession{}
+	monitorDone := make(chan error, 1)
+
+	go func() {
+		monitorDone <- monitor.Run(context.Background(), monitorSess, "-t", "300ms")
+	}()
+
+	scriptDone := make(chan error, 1)
+
+	go func() {
+		scriptDone <- script.Run(context.Background(), &recordingSession{}, "-t", "1s", "--", "expect", "login:")
+	}()
+
+	// Give both runs time to attach before the output arrives.
+	time.Sleep(50 * time.Millisecond)
+	fp.feed <- []byte("dut login: ")
+
+	if err := <-scriptDone; err != nil {
+		t.Errorf("scripted run while monitoring = %v, want nil", err)
+	}
+
+	if err := <-monitorDone; err != nil {
+		t.Errorf("monitor run = %v, want nil", err)
+	}
+
+	if opens != 1 {
+		t.Errorf("port opened %d times, want once", opens)
+	}
+
+	if got := monitorSess.out.String(); !strings.Contains(got, "dut login: ") {
+		t.Errorf("monitor output %q misses the shared output", got)
+	}
+}
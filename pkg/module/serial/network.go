@@ -0,0 +1,581 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Schemes of the network serial ports. A port name without a scheme is the
+// path to a local serial device.
+const (
+	schemeTCP     = "tcp"
+	schemeRFC2217 = "rfc2217"
+)
+
+const (
+	// dialTimeout bounds connecting to a terminal server.
+	dialTimeout = 5 * time.Second
+	// negotiationTimeout bounds the terminal server's answer to the settings of
+	// an RFC 2217 port.
+	negotiationTimeout = 3 * time.Second
+	// purgeTimeout is the silence ending the discarding of buffered input.
+	purgeTimeout = 10 * time.Millisecond
+)
+
+// parsePortName splits a port name into the scheme of a network port and its
+// host:port address. For a local device the scheme is empty.
+func parsePortName(name string) (string, string, error) {
+	if !strings.Contains(name, "://") {
+		return "", name, nil
+	}
+
+	u, err := url.Parse(name)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid port %q: %w", name, err)
+	}
+
+	if u.Scheme != schemeTCP && u.Scheme != schemeRFC2217 {
+		return "", "", fmt.Errorf("invalid port %q: want a device path, tcp://host:port or rfc2217://host:port", name)
+	}
+
+	if u.Port() == "" || u.Path != "" {
+		return "", "", fmt.Errorf("invalid port %q: want %s://host:port", name, u.Scheme)
+	}
+
+	return u.Scheme, u.Host, nil
+}
+
+// openNetworkPort connects to a serial port behind a terminal server: raw, where
+// the TCP connection carries the serial data as it is, or by RFC 2217, which
+// also makes the line settings. The line settings of a raw port are configured
+// on the terminal server.
+func openNetworkPort(scheme, addr string, line lineSettings) (port, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to serial port %s://%s: %w", scheme, addr, err)
+	}
+
+	if scheme == schemeTCP {
+		return &tcpPort{conn: conn}, nil
+	}
+
+	p := newRFC2217Port(conn)
+
+	err = p.negotiate(line)
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("serial port %s://%s: %w", scheme, addr, err)
+	}
+
+	return p, nil
+}
+
+// tcpPort is a serial port behind a terminal server in raw mode, e.g. ser2net's
+// raw or a terminal server's TCP port. Like a local port, a read returns (0, nil)
+// after readTimeout without data.
+type tcpPort struct {
+	conn net.Conn
+}
+
+func (t *tcpPort) Read(p []byte) (int, error) {
+	return readConn(t.conn, p, readTimeout)
+}
+
+func (t *tcpPort) Write(p []byte) (int, error) {
+	return t.conn.Write(p)
+}
+
+// ResetInputBuffer discards the data received but not read yet.
+func (t *tcpPort) ResetInputBuffer() error {
+	return discardInput(t.conn)
+}
+
+func (t *tcpPort) Close() error {
+	return t.conn.Close()
+}
+
+// readConn reads from conn for up to timeout, returning (0, nil) if no data
+// arrived, like a serial port with a read timeout.
+func readConn(conn net.Conn, p []byte, timeout time.Duration) (int, error) {
+	err := conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := conn.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, nil
+	}
+
+	return n, err
+}
+
+// discardInput reads and drops what conn received until it is silent.
+func discardInput(conn net.Conn) error {
+	buf := make([]byte, readChunk)
+
+	for {
+		n, err := readConn(conn, buf, purgeTimeout)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// Telnet commands and options used by RFC 2217.
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	optBinary  = 0
+	optEcho    = 1
+	optSGA     = 3 // suppress go ahead
+	optComPort = 44
+)
+
+// Commands of the telnet COM-PORT-OPTION (RFC 2217). The terminal server
+// answers a command with the command plus comPortReply.
+const (
+	comPortSetBaudRate = 1
+	comPortSetDataSize = 2
+	comPortSetParity   = 3
+	comPortSetStopSize = 4
+	comPortSetControl  = 5
+	comPortPurgeData   = 12
+	comPortReply       = 100
+
+	purgeReceiveBuffer = 1 // the terminal server's buffer of data from the DUT
+)
+
+// Values of SET-CONTROL.
+const (
+	controlNoFlow       = 1
+	controlXonXoff      = 2
+	controlHardwareFlow = 3
+	controlDTROn        = 8
+	controlDTROff       = 9
+	controlRTSOn        = 11
+	controlRTSOff       = 12
+)
+
+// rfc2217Port is a serial port behind a terminal server speaking RFC 2217, the
+// telnet COM-PORT-OPTION, e.g. ser2net's telnet mode with remctl. The serial
+// data is carried in a telnet stream, and the serial settings are made with
+// telnet subnegotiations.
+type rfc2217Port struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+
+	// The state of the telnet stream decoder, used by Read only.
+	state   telnetState
+	command byte   // the option command in progress, if state is stateOption
+	sub     []byte // the subnegotiation in progress
+	raw     []byte
+
+	// pending is the serial data that arrived while the port was negotiated.
+	pending []byte
+
+	// comPort is closed once the terminal server agreed to the COM-PORT-OPTION
+	// or refused it, which refused records.
+	comPort     chan struct{}
+	comPortOnce sync.Once
+	refused     bool
+
+	// replies passes the terminal server's answers to COM-PORT-OPTION commands
+	// on while the port is negotiated.
+	replies chan []byte
+}
+
+// telnetState is the state of the telnet stream decoder.
+type telnetState int
+
+const (
+	stateData telnetState = iota
+	stateIAC
+	stateOption
+	stateSub
+	stateSubIAC
+)
+
+func newRFC2217Port(conn net.Conn) *rfc2217Port {
+	return &rfc2217Port{
+		conn:    conn,
+		raw:     make([]byte, readChunk),
+		comPort: make(chan struct{}),
+		replies: make(chan []byte, 8), //nolint:mnd // a few answers in flight
+	}
+}
+
+// comPortSetting is a serial setting made with a COM-PORT-OPTION command.
+type comPortSetting struct {
+	name  string
+	cmd   byte
+	value []byte
+}
+
+// comPortSettings returns the COM-PORT-OPTION commands making the line settings.
+func comPortSettings(line lineSettings) []comPortSetting {
+	parity := map[serial.Parity]byte{
+		serial.NoParity:    1,
+		serial.OddParity:   2, //nolint:mnd // RFC 2217 values
+		serial.EvenParity:  3, //nolint:mnd // RFC 2217 values
+		serial.MarkParity:  4, //nolint:mnd // RFC 2217 values
+		serial.SpaceParity: 5, //nolint:mnd // RFC 2217 values
+	}[line.parity]
+
+	stopSize := map[serial.StopBits]byte{
+		serial.OneStopBit:           1,
+		serial.TwoStopBits:          2, //nolint:mnd // RFC 2217 values
+		serial.OnePointFiveStopBits: 3, //nolint:mnd // RFC 2217 values
+	}[line.stopBits]
+
+	flow := map[flowControl]byte{
+		flowNone:    controlNoFlow,
+		flowXonXoff: controlXonXoff,
+		flowRTSCTS:  controlHardwareFlow,
+	}[line.flow]
+
+	return []comPortSetting{
+		{name: "baud rate", cmd: comPortSetBaudRate, value: binary.BigEndian.AppendUint32(nil, uint32(line.baud))}, //nolint:gosec // a baud rate fits
+		{name: "data size", cmd: comPortSetDataSize, value: []byte{byte(line.dataBits)}},
+		{name: "parity", cmd: comPortSetParity, value: []byte{parity}},
+		{name: "stop size", cmd: comPortSetStopSize, value: []byte{stopSize}},
+		{name: "flow control", cmd: comPortSetControl, value: []byte{flow}},
+	}
+}
+
+// negotiate agrees on a binary connection with the COM-PORT-OPTION and makes
+// the line settings, each of which the terminal server must confirm. Serial
+// data arriving meanwhile is kept for the first reads.
+func (r *rfc2217Port) negotiate(line lineSettings) error {
+	err := r.writeRaw([]byte{
+		telnetIAC, telnetWILL, optBinary, telnetIAC, telnetDO, optBinary,
+		telnetIAC, telnetWILL, optSGA, telnetIAC, telnetDO, optSGA,
+		telnetIAC, telnetWILL, optComPort,
+	})
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(negotiationTimeout)
+
+	err = r.await(deadline, func() bool {
+		select {
+		case <-r.comPort:
+			return true
+		default:
+			return false
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("terminal server does not answer the RFC 2217 negotiation: %w", err)
+	}
+
+	if r.refused {
+		return errors.New("terminal server does not support RFC 2217")
+	}
+
+	settings := comPortSettings(line)
+
+	for _, setting := range settings {
+		err = r.comPortCommand(setting.cmd, setting.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The replies by command, each the value the terminal server set.
+	replies := make(map[byte][]byte)
+
+	err = r.await(deadline, func() bool {
+		for {
+			select {
+			case reply := <-r.replies:
+				replies[reply[0]-comPortReply] = reply[1:]
+			default:
+				for _, setting := range settings {
+					if _, ok := replies[setting.cmd]; !ok {
+						return false
+					}
+				}
+
+				return true
+			}
+		}
+	})
+
+	for _, setting := range settings {
+		got, ok := replies[setting.cmd]
+
+		switch {
+		case !ok:
+			return fmt.Errorf("terminal server does not confirm the %s: %w", setting.name, err)
+		case bytes.Equal(got, setting.value):
+		case setting.cmd == comPortSetBaudRate && len(got) == len(setting.value):
+			return fmt.Errorf("terminal server set %d baud instead of %d", binary.BigEndian.Uint32(got), line.baud)
+		default:
+			return fmt.Errorf("terminal server set the %s to % x instead of % x", setting.name, got, setting.value)
+		}
+	}
+
+	return nil
+}
+
+// await reads the telnet stream until done reports true or the deadline passed.
+func (r *rfc2217Port) await(deadline time.Time, done func() bool) error {
+	for !done() {
+		if time.Now().After(deadline) {
+			return errors.New("timeout")
+		}
+
+		n, err := readConn(r.conn, r.raw, readTimeout)
+		if err != nil {
+			return err
+		}
+
+		r.pending, err = r.decode(r.raw[:n], r.pending)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// comPortCommand sends a COM-PORT-OPTION command with value to the terminal server.
+func (r *rfc2217Port) comPortCommand(cmd byte, value []byte) error {
+	msg := []byte{telnetIAC, telnetSB, optComPort, cmd}
+	msg = append(msg, escapeIAC(value)...)
+	msg = append(msg, telnetIAC, telnetSE)
+
+	return r.writeRaw(msg)
+}
+
+// Read returns the serial data of the telnet stream. Like a local port it
+// returns (0, nil) if no data arrived for readTimeout.
+func (r *rfc2217Port) Read(p []byte) (int, error) {
+	if len(r.pending) > 0 {
+		n := copy(p, r.pending)
+		r.pending = r.pending[n:]
+
+		return n, nil
+	}
+
+	// Decode at most as much as fits into p: data is never more than the raw
+	// stream, so nothing needs to be kept for the next read.
+	n, err := readConn(r.conn, r.raw[:min(len(p), len(r.raw))], readTimeout)
+	if n == 0 {
+		return 0, err
+	}
+
+	out, derr := r.decode(r.raw[:n], p[:0])
+	if derr != nil {
+		return 0, derr
+	}
+
+	return len(out), err
+}
+
+// decode appends the serial data of a chunk of the telnet stream to out and
+// handles the telnet commands in it.
+//
+//nolint:cyclop // a flat state machine
+func (r *rfc2217Port) decode(chunk, out []byte) ([]byte, error) {
+	for _, b := range chunk {
+		switch r.state {
+		case stateData:
+			if b == telnetIAC {
+				r.state = stateIAC
+			} else {
+				out = append(out, b)
+			}
+		case stateIAC:
+			switch b {
+			case telnetIAC:
+				out = append(out, b)
+				r.state = stateData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				r.command = b
+				r.state = stateOption
+			case telnetSB:
+				r.sub = r.sub[:0]
+				r.state = stateSub
+			default:
+				// NOP, GA and the like carry nothing for a serial line.
+				r.state = stateData
+			}
+		case stateOption:
+			r.state = stateData
+
+			err := r.option(r.command, b)
+			if err != nil {
+				return out, err
+			}
+		case stateSub:
+			if b == telnetIAC {
+				r.state = stateSubIAC
+			} else {
+				r.sub = append(r.sub, b)
+			}
+		case stateSubIAC:
+			switch b {
+			case telnetSE:
+				r.state = stateData
+				r.subnegotiation(r.sub)
+			default:
+				r.sub = append(r.sub, b) // an escaped IAC
+				r.state = stateSub
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// option handles an option command of the terminal server. The options offered
+// on connect are taken as agreed, the COM-PORT-OPTION is tracked, and any other
+// option the terminal server asks for is refused. A server offering to echo is
+// fine: the serial line echoes anyway.
+func (r *rfc2217Port) option(command, opt byte) error {
+	switch {
+	case opt == optComPort && (command == telnetDO || command == telnetDONT):
+		r.comPortOnce.Do(func() {
+			r.refused = command == telnetDONT
+			close(r.comPort)
+		})
+	case opt == optBinary || opt == optSGA || opt == optComPort:
+	case command == telnetWILL && opt == optEcho:
+		return r.writeRaw([]byte{telnetIAC, telnetDO, optEcho})
+	case command == telnetDO:
+		return r.writeRaw([]byte{telnetIAC, telnetWONT, opt})
+	case command == telnetWILL:
+		return r.writeRaw([]byte{telnetIAC, telnetDONT, opt})
+	}
+
+	return nil
+}
+
+// subnegotiation passes the terminal server's answers to COM-PORT-OPTION
+// commands on. Notifications, like line and modem state changes, are dropped.
+func (r *rfc2217Port) subnegotiation(sub []byte) {
+	if len(sub) < 2 || sub[0] != optComPort || sub[1] <= comPortReply {
+		return
+	}
+
+	select {
+	case r.replies <- append([]byte(nil), sub[1:]...):
+	default:
+	}
+}
+
+// Write writes p as serial data, escaping the telnet IAC byte.
+func (r *rfc2217Port) Write(p []byte) (int, error) {
+	err := r.writeRaw(escapeIAC(p))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (r *rfc2217Port) writeRaw(p []byte) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	_, err := r.conn.Write(p)
+
+	return err
+}
+
+// SetDTR sets the DTR line of the serial port.
+func (r *rfc2217Port) SetDTR(on bool) error {
+	if on {
+		return r.comPortCommand(comPortSetControl, []byte{controlDTROn})
+	}
+
+	return r.comPortCommand(comPortSetControl, []byte{controlDTROff})
+}
+
+// SetRTS sets the RTS line of the serial port.
+func (r *rfc2217Port) SetRTS(on bool) error {
+	if on {
+		return r.comPortCommand(comPortSetControl, []byte{controlRTSOn})
+	}
+
+	return r.comPortCommand(comPortSetControl, []byte{controlRTSOff})
+}
+
+// ResetInputBuffer asks the terminal server to drop the data it buffered, and
+// drops the data received but not read yet.
+func (r *rfc2217Port) ResetInputBuffer() error {
+	r.pending = nil
+
+	err := r.comPortCommand(comPortPurgeData, []byte{purgeReceiveBuffer})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, readChunk)
+
+	for {
+		err = r.conn.SetReadDeadline(time.Now().Add(purgeTimeout))
+		if err != nil {
+			return err
+		}
+
+		n, err := r.conn.Read(buf)
+		if n > 0 {
+			// Keep handling the telnet commands, but drop the data.
+			_, derr := r.decode(buf[:n], nil)
+			if derr != nil {
+				return derr
+			}
+		}
+
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *rfc2217Port) Close() error {
+	return r.conn.Close()
+}
+
+// escapeIAC doubles the telnet IAC bytes in data, which stand for themselves then.
+func escapeIAC(data []byte) []byte {
+	if !bytes.Contains(data, []byte{telnetIAC}) {
+		return data
+	}
+
+	return bytes.ReplaceAll(data, []byte{telnetIAC}, []byte{telnetIAC, telnetIAC})
+}
@@ -0,0 +1,345 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// terminalServer is a stand-in for a terminal server on a local TCP port. It
+// accepts a single connection and hands it to serve.
+func terminalServer(t *testing.T, serve func(conn net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		serve(conn)
+	}()
+
+	return ln.Addr().String()
+}
+
+// readFor reads from p for up to d and returns what arrived.
+func readFor(p port, d time.Duration) []byte {
+	var (
+		got []byte
+		buf = make([]byte, readChunk)
+	)
+
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		n, err := p.Read(buf)
+		got = append(got, buf[:n]...)
+
+		if err != nil {
+			break
+		}
+	}
+
+	return got
+}
+
+// rfc2217Server answers the client's telnet negotiation like ser2net in telnet
+// mode: it agrees to the COM-PORT-OPTION unless refuse is set, and confirms the
+// COM-PORT-OPTION commands, the baud rate with baud, or not at all if baud is
+// 0. It passes what the client wrote on to written, and answers the first line
+// the client sent with prompt.
+func rfc2217Server(refuse bool, baud uint32, prompt []byte, written chan<- []byte) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		answer := []byte{telnetIAC, telnetDO, optComPort}
+		if refuse {
+			answer[1] = telnetDONT
+		}
+
+		// Ask for an option the client does not know, which it must refuse.
+		answer = append(answer, telnetIAC, telnetDO, 24) //nolint:mnd // TERMINAL-TYPE
+
+		_, _ = conn.Write(answer)
+
+		var stream []byte
+
+		buf := make([]byte, readChunk)
+		subStart := []byte{telnetIAC, telnetSB, optComPort}
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				close(written)
+
+				return
+			}
+
+			stream = append(stream, buf[:n]...)
+
+			for {
+				start := bytes.Index(stream, subStart)
+				end := bytes.Index(stream[max(start, 0):], []byte{telnetIAC, telnetSE})
+
+				if start < 0 || end < 0 {
+					break
+				}
+
+				cmd, value := stream[start+len(subStart)], stream[start+len(subStart)+1:start+end]
+				stream = stream[start+end+2:]
+
+				if cmd == comPortSetBaudRate {
+					if baud == 0 {
+						continue
+					}
+
+					value = binary.BigEndian.AppendUint32(nil, baud)
+				}
+
+				reply := append([]byte{telnetIAC, telnetSB, optComPort, cmd + comPortReply}, value...)
+				_, _ = conn.Write(append(reply, telnetIAC, telnetSE))
+			}
+
+			if prompt != nil && bytes.Contains(buf[:n], []byte("\r")) {
+				_, _ = conn.Write(prompt)
+				prompt = nil
+			}
+
+			written <- bytes.Clone(buf[:n])
+		}
+	}
+}
+
+func TestParsePortName(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantScheme string
+		wantAddr   string
+		wantErr    bool
+	}{
+		{name: "/dev/ttyUSB0", wantAddr: "/dev/ttyUSB0"},
+		{name: "tcp://ts1:7001", wantScheme: schemeTCP, wantAddr: "ts1:7001"},
+		{name: "rfc2217://10.0.0.5:2000", wantScheme: schemeRFC2217, wantAddr: "10.0.0.5:2000"},
+		{name: "rfc2217://[fd00::5]:2000", wantScheme: schemeRFC2217, wantAddr: "[fd00::5]:2000"},
+		{name: "telnet://ts1:7001", wantErr: true},
+		{name: "tcp://ts1", wantErr: true},
+		{name: "tcp://ts1:7001/ttyS0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		scheme, addr, err := parsePortName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePortName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+
+			continue
+		}
+
+		if scheme != tt.wantScheme || addr != tt.wantAddr {
+			t.Errorf("parsePortName(%q) = %q, %q, want %q, %q", tt.name, scheme, addr, tt.wantScheme, tt.wantAddr)
+		}
+	}
+}
+
+func TestTCPPort(t *testing.T) {
+	written := make(chan []byte, 1)
+
+	addr := terminalServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("login: "))
+
+		data, _ := io.ReadAll(io.LimitReader(conn, 5))
+		written <- data
+	})
+
+	p, err := defaultOpenPort("tcp://"+addr, lineSettings{baud: DefaultBaudRate})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer p.Close()
+
+	// A read without data times out like a local port's.
+	start := time.Now()
+
+	if got := readFor(p, 300*time.Millisecond); string(got) != "login: " {
+		t.Errorf("read %q, want the server's output", got)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Error("reads do not time out")
+	}
+
+	_, err = p.Write([]byte("root\r"))
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := <-written; string(got) != "root\r" {
+		t.Errorf("server received %q, want the data as written", got)
+	}
+
+	if err := lineRTS.set(p, true); !errors.Is(err, errNoControlLines) {
+		t.Errorf("setting RTS = %v, want %v", err, errNoControlLines)
+	}
+}
+
+func TestRFC2217Port(t *testing.T) {
+	written := make(chan []byte, 64)
+	prompt := []byte{'=', '>', ' ', telnetIAC, telnetIAC, 0x01}
+
+	addr := terminalServer(t, rfc2217Server(false, 9600, prompt, written))
+
+	p, err := defaultOpenPort("rfc2217://"+addr, lineSettings{baud: 9600, dataBits: 8})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer p.Close()
+
+	_, err = p.Write([]byte{'a', 0xff, 'b', '\r'})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := readFor(p, 300*time.Millisecond); !bytes.Equal(got, []byte{'=', '>', ' ', 0xff, 0x01}) {
+		t.Errorf("read % x, want the data without telnet escaping", got)
+	}
+
+	var stream []byte
+
+	for !bytes.Contains(stream, []byte{'a', telnetIAC, telnetIAC, 'b'}) {
+		select {
+		case data := <-written:
+			stream = append(stream, data...)
+		case <-time.After(time.Second):
+			t.Fatalf("server received % x, want the data with IAC escaped", stream)
+		}
+	}
+
+	for _, want := range [][]byte{
+		{telnetIAC, telnetWILL, optComPort},
+		{telnetIAC, telnetSB, optComPort, comPortSetBaudRate, 0, 0, 0x25, 0x80, telnetIAC, telnetSE},
+		{telnetIAC, telnetWONT, 24},
+	} {
+		if !bytes.Contains(stream, want) {
+			t.Errorf("client did not send % x in % x", want, stream)
+		}
+	}
+}
+
+func TestRFC2217LineSettings(t *testing.T) {
+	written := make(chan []byte, 64)
+
+	addr := terminalServer(t, rfc2217Server(false, 9600, nil, written))
+
+	line := lineSettings{baud: 9600, dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits, flow: flowRTSCTS}
+
+	p, err := defaultOpenPort("rfc2217://"+addr, line)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer p.Close()
+
+	err = lineDTR.set(p, false)
+	if err != nil {
+		t.Fatalf("setting DTR: %v", err)
+	}
+
+	want := [][]byte{
+		{telnetIAC, telnetSB, optComPort, comPortSetDataSize, 7, telnetIAC, telnetSE},
+		{telnetIAC, telnetSB, optComPort, comPortSetParity, 3, telnetIAC, telnetSE},
+		{telnetIAC, telnetSB, optComPort, comPortSetStopSize, 2, telnetIAC, telnetSE},
+		{telnetIAC, telnetSB, optComPort, comPortSetControl, controlHardwareFlow, telnetIAC, telnetSE},
+		{telnetIAC, telnetSB, optComPort, comPortSetControl, controlDTROff, telnetIAC, telnetSE},
+	}
+
+	var stream []byte
+
+	for _, cmd := range want {
+		for !bytes.Contains(stream, cmd) {
+			select {
+			case data := <-written:
+				stream = append(stream, data...)
+			case <-time.After(time.Second):
+				t.Fatalf("client did not send % x in % x", cmd, stream)
+			}
+		}
+	}
+}
+
+func TestRFC2217Negotiation(t *testing.T) {
+	tests := []struct {
+		name    string
+		refuse  bool
+		confirm uint32
+		wantErr string
+	}{
+		{name: "refused", refuse: true, confirm: 9600, wantErr: "does not support RFC 2217"},
+		{name: "not confirmed", wantErr: "does not confirm the baud rate"},
+		{name: "other baud rate", confirm: 115200, wantErr: "set 115200 baud instead of 9600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := terminalServer(t, rfc2217Server(tt.refuse, tt.confirm, nil, make(chan []byte, 64)))
+
+			p, err := defaultOpenPort("rfc2217://"+addr, lineSettings{baud: 9600, dataBits: 8})
+			if err == nil {
+				p.Close()
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("open = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSerialRunOverNetwork(t *testing.T) {
+	written := make(chan []byte, 64)
+
+	addr := terminalServer(t, rfc2217Server(false, DefaultBaudRate, []byte("\r\nlogin: "), written))
+
+	s := &Serial{Port: "rfc2217://" + addr}
+
+	err := s.Init(context.Background())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	sess := &recordingSession{}
+
+	err = s.Run(context.Background(), sess, "-t", "5s", "--", "send", "", "expect", "login:", "send", "root")
+	if err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+
+	var stream []byte
+
+	for !bytes.Contains(stream, []byte("root\r")) {
+		select {
+		case data := <-written:
+			stream = append(stream, data...)
+		case <-time.After(time.Second):
+			t.Fatalf("server received %q, want the sent line", stream)
+		}
+	}
+
+	if !strings.Contains(sess.out.String(), "login:") {
+		t.Errorf("output %q, want the server's output", sess.out.String())
+	}
+}
@@ -0,0 +1,430 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stampLayout is the layout of the timestamp starting every line of a serial
+// log. It sorts lexically and keeps milliseconds, which is enough to tell the
+// lines of a boot apart.
+const stampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// reattachInterval is the pause between attempts to attach to a serial port
+// that cannot be opened, e.g. because the DUT is powered off.
+const reattachInterval = 2 * time.Second
+
+// recorders holds the recorder of every serial port being recorded, keyed by
+// port name, so commands configuring the same port share its log.
+//
+//nolint:gochecknoglobals // the agent-wide registry of recorded ports
+var (
+	recorders   = make(map[string]*recorder)
+	recordersMu sync.Mutex
+)
+
+// recorderConfig configures the recording of a serial port.
+type recorderConfig struct {
+	port    string
+	line    lineSettings
+	dir     string // dir is the directory holding the log files.
+	maxSize int64  // maxSize is the size in bytes at which the log is rotated.
+	keep    int    // keep is the number of rotated log files kept.
+}
+
+// startRecorder starts recording the configured port in the background, or
+// returns the recorder already recording it. Each call must be matched by a
+// call of release. Recording the same port with another configuration fails.
+func startRecorder(cfg recorderConfig, open portOpener, l *slog.Logger) (*recorder, error) {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	if rec, ok := recorders[cfg.port]; ok {
+		if rec.cfg != cfg {
+			return nil, fmt.Errorf("serial port %s is already recorded with another configuration (to %s)", cfg.port, rec.cfg.dir)
+		}
+
+		rec.refs++
+
+		return rec, nil
+	}
+
+	err := os.MkdirAll(cfg.dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+
+	rec := &recorder{
+		cfg:         cfg,
+		open:        open,
+		path:        filepath.Join(cfg.dir, logName(cfg.port)),
+		log:         l,
+		atLineStart: true,
+		refs:        1,
+		done:        make(chan struct{}),
+	}
+
+	err = rec.openLog()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	rec.stop = stop
+
+	go rec.run(ctx)
+
+	recorders[cfg.port] = rec
+
+	return rec, nil
+}
+
+// logName derives the name of the log file from the port name, replacing
+// everything but letters, digits, dots, dashes and underscores.
+func logName(port string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.TrimPrefix(port, "/dev/"))
+
+	return name + ".log"
+}
+
+// recorder records the output of a serial port to a rotating log file. It stays
+// attached to the port as an observer for as long as it runs, so the output is
+// recorded whether or not a command is using the port. Every line of the log
+// starts with the time its first byte was read.
+type recorder struct {
+	cfg  recorderConfig
+	open portOpener
+	path string // path is the current log file; rotated ones get the suffix .1, .2, ...
+	log  *slog.Logger
+
+	// mu guards the log file against rotation while it is written or opened
+	// for reading.
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	atLineStart bool
+	remainder   []byte // a partial escape sequence split across reads.
+
+	refs int // guarded by recordersMu
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// release ends a user of the recorder. The last one stops the recording and
+// closes the log.
+func (r *recorder) release() {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	r.refs--
+	if r.refs > 0 {
+		return
+	}
+
+	delete(recorders, r.cfg.port)
+
+	r.stop()
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.file.Close()
+	if err != nil {
+		r.log.Warn("closing serial log failed", "err", err)
+	}
+}
+
+// run keeps the recorder attached to the port until ctx is done, attaching
+// again whenever the port cannot be opened or fails.
+func (r *recorder) run(ctx context.Context) {
+	defer close(r.done)
+
+	var lastErr string // logged once per failure, not per attempt
+
+	for {
+		obs, err := attach(r.cfg.port, r.cfg.line, r.open, false)
+		if err != nil {
+			if err.Error() != lastErr {
+				r.log.Warn("serial port not recorded, retrying", "err", err)
+				lastErr = err.Error()
+			}
+		} else {
+			lastErr = ""
+
+			r.log.Debug("recording serial port")
+			err = r.record(ctx, obs)
+			_ = obs.Close()
+
+			if err != nil {
+				r.log.Warn("recording serial port interrupted", "err", err)
+			}
+		}
+
+		if sleepCtx(ctx, reattachInterval) != nil {
+			return
+		}
+	}
+}
+
+// record appends the output of obs to the log until ctx is done or reading
+// fails.
+func (r *recorder) record(ctx context.Context, obs *observer) error {
+	buf := make([]byte, readChunk)
+
+	for ctx.Err() == nil {
+		n, err := obs.Read(buf)
+		if n > 0 {
+			werr := r.write(buf[:n], time.Now())
+			if werr != nil {
+				return werr
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write appends output read at now to the log. Terminal escape sequences and
+// carriage returns are dropped, so the log reads like the output shown by the
+// serial command. A line is stamped when its first byte arrives, and the log is
+// rotated only between lines.
+func (r *recorder) write(chunk []byte, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	text := bytes.ReplaceAll(stripEscapes(chunk, &r.remainder), []byte{'\r'}, nil)
+
+	for len(text) > 0 {
+		if r.atLineStart {
+			if r.size >= r.cfg.maxSize {
+				err := r.rotate()
+				if err != nil {
+					return err
+				}
+			}
+
+			err := r.append([]byte(now.Format(stampLayout) + " "))
+			if err != nil {
+				return err
+			}
+		}
+
+		line := text
+
+		if i := bytes.IndexByte(text, '\n'); i >= 0 {
+			line = text[:i+1]
+		}
+
+		err := r.append(line)
+		if err != nil {
+			return err
+		}
+
+		r.atLineStart = line[len(line)-1] == '\n'
+		text = text[len(line):]
+	}
+
+	return nil
+}
+
+func (r *recorder) append(p []byte) error {
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	return err
+}
+
+// openLog opens the current log file for appending.
+func (r *recorder) openLog() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("opening serial log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("opening serial log: %w", err)
+	}
+
+	r.file, r.size = file, info.Size()
+
+	return nil
+}
+
+// rotate moves the current log to the suffix .1, shifting older ones up and
+// dropping the oldest, and starts a new log. It must be called with mu held.
+func (r *recorder) rotate() error {
+	err := r.file.Close()
+	if err != nil {
+		return fmt.Errorf("rotating serial log: %w", err)
+	}
+
+	for i := r.cfg.keep - 1; i > 0; i-- {
+		err = os.Rename(r.rotated(i), r.rotated(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotating serial log: %w", err)
+		}
+	}
+
+	if r.cfg.keep > 0 {
+		err = os.Rename(r.path, r.rotated(1))
+	} else {
+		err = os.Remove(r.path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("rotating serial log: %w", err)
+	}
+
+	return r.openLog()
+}
+
+func (r *recorder) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+// history opens the log files, the oldest first. Opening them all at once keeps
+// a rotation from interfering with reading them. The caller closes the files.
+func (r *recorder) history() ([]*os.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := make([]*os.File, 0, r.cfg.keep+1)
+
+	for i := r.cfg.keep; i >= 0; i-- {
+		name := r.path
+		if i > 0 {
+			name = r.rotated(i)
+		}
+
+		file, err := os.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+
+			return nil, fmt.Errorf("opening serial log: %w", err)
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// logQuery selects lines of a serial log: those stamped in [since, until),
+// where a zero time leaves the range open, and of these the last lines only,
+// unless it is 0.
+type logQuery struct {
+	since time.Time
+	until time.Time
+	last  int
+}
+
+// selectLines reads the log lines from logs, oldest first, and passes those
+// matching q on to emit. A line without a readable stamp is taken to belong to
+// the line before it.
+func selectLines(logs []io.Reader, q logQuery, emit func(line string) error) error {
+	var (
+		tail    []string // the last q.last matching lines, if q.last is set
+		include bool
+	)
+
+	for _, lr := range logs {
+		reader := bufio.NewReader(lr)
+
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				if stamp, ok := lineStamp(line); ok {
+					include = (q.since.IsZero() || !stamp.Before(q.since)) && (q.until.IsZero() || stamp.Before(q.until))
+				}
+
+				switch {
+				case !include:
+				case q.last > 0:
+					tail = append(tail, line)
+					if len(tail) > q.last {
+						tail = tail[1:]
+					}
+				default:
+					eerr := emit(line)
+					if eerr != nil {
+						return eerr
+					}
+				}
+			}
+
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				return fmt.Errorf("reading serial log: %w", err)
+			}
+		}
+	}
+
+	for _, line := range tail {
+		err := emit(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lineStamp returns the time a log line is stamped with.
+func lineStamp(line string) (time.Time, bool) {
+	if len(line) < len(stampLayout) {
+		return time.Time{}, false
+	}
+
+	// The zone of a stamp is "Z" or "+hh:mm", so the stamp ends at the first space.
+	end := strings.IndexByte(line, ' ')
+	if end < 0 {
+		return time.Time{}, false
+	}
+
+	stamp, err := time.Parse(stampLayout, line[:end])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return stamp, true
+}
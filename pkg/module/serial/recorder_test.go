@@ -0,0 +1,281 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+)
+
+// newTestRecorder returns a recorder writing to a log in a temporary directory,
+// without recording a port.
+func newTestRecorder(t *testing.T, maxSize int64, keep int) *recorder {
+	t.Helper()
+
+	rec := &recorder{
+		cfg:         recorderConfig{port: "/dev/ttyTEST", dir: t.TempDir(), maxSize: maxSize, keep: keep},
+		log:         slog.Default(),
+		atLineStart: true,
+	}
+	rec.path = filepath.Join(rec.cfg.dir, logName(rec.cfg.port))
+
+	err := rec.openLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { rec.file.Close() })
+
+	return rec
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestRecorderWrite(t *testing.T) {
+	rec := newTestRecorder(t, 1<<20, 1)
+
+	first := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(1500 * time.Millisecond)
+
+	for _, chunk := range []struct {
+		data string
+		at   time.Time
+	}{
+		{"U-Boot 2024.01\r\n\x1b[32mDRAM", first},
+		{":  1 GiB\x1b[0m\r\n", second},
+		{"login: ", second},
+	} {
+		err := rec.write([]byte(chunk.data), chunk.at)
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	want := "2025-03-01T10:00:00.000Z U-Boot 2024.01\n" +
+		"2025-03-01T10:00:00.000Z DRAM:  1 GiB\n" +
+		"2025-03-01T10:00:01.500Z login: "
+
+	if got := readFile(t, rec.path); got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
+
+func TestRecorderRotate(t *testing.T) {
+	// Rotate before every line but the first of a file.
+	rec := newTestRecorder(t, 1, 2)
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		err := rec.write([]byte(line), now)
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(rec.rotated(3)); !os.IsNotExist(err) {
+		t.Errorf("more than keep rotated logs kept: %v", err)
+	}
+
+	files, err := rec.history()
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+
+	var got []string
+
+	for _, file := range files {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file.Close()
+
+		got = append(got, strings.TrimPrefix(string(data), "2025-03-01T10:00:00.000Z "))
+	}
+
+	want := []string{"two\n", "three\n", "four\n"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("history = %q, want %q, the oldest first", got, want)
+	}
+}
+
+func TestSelectLines(t *testing.T) {
+	logs := []string{
+		"2025-03-01T10:00:00.000Z boot\n" +
+			"2025-03-01T10:01:00.000Z kernel\n",
+		"2025-03-01T10:02:00.000Z panic\n" +
+			"  stack trace\n" +
+			"2025-03-01T10:03:00.000Z reboot\n" +
+			"2025-03-01T10:04:00.000Z login: ",
+	}
+
+	at := func(minute int) time.Time { return time.Date(2025, 3, 1, 10, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		query logQuery
+		want  string
+	}{
+		{name: "all", query: logQuery{}, want: "boot kernel panic stack reboot login:"},
+		{name: "last lines", query: logQuery{last: 2}, want: "reboot login:"},
+		{name: "since", query: logQuery{since: at(2)}, want: "panic stack reboot login:"},
+		{name: "until", query: logQuery{until: at(2)}, want: "boot kernel"},
+		{name: "range and last", query: logQuery{since: at(1), until: at(4), last: 2}, want: "stack reboot"},
+		{name: "empty range", query: logQuery{since: at(5)}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readers := make([]io.Reader, 0, len(logs))
+			for _, l := range logs {
+				readers = append(readers, strings.NewReader(l))
+			}
+
+			var got []string
+
+			err := selectLines(readers, tt.query, func(line string) error {
+				fields := strings.Fields(line)
+				if _, ok := lineStamp(line); ok {
+					fields = fields[1:]
+				}
+
+				got = append(got, fields[0])
+
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("selectLines: %v", err)
+			}
+
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("selected %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogArgs(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     logQuery
+		wantFile string
+		wantErr  bool
+	}{
+		{name: "defaults", args: nil, want: logQuery{last: defaultLast}},
+		{name: "last", args: []string{"-n", "20"}, want: logQuery{last: 20}},
+		{name: "duration", args: []string{"-since", "10m"}, want: logQuery{since: now.Add(-10 * time.Minute)}},
+		{
+			name: "time of day range",
+			args: []string{"-since", "09:15", "-until", "09:45:30"},
+			want: logQuery{since: now.Add(-75 * time.Minute), until: now.Add(-44*time.Minute - 30*time.Second)},
+		},
+		{
+			name: "range and last",
+			args: []string{"-since", "2025-03-01T09:00:00Z", "-n", "5"},
+			want: logQuery{since: now.Add(-90 * time.Minute), last: 5},
+		},
+		{name: "file", args: []string{"-n", "0", "-file", "console.log"}, want: logQuery{}, wantFile: "console.log"},
+		{name: "bad time", args: []string{"-since", "yesterday"}, wantErr: true},
+		{name: "negative lines", args: []string{"-n", "-1"}, wantErr: true},
+		{name: "stray argument", args: []string{"all"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, file, err := parseLogArgs(tt.args, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLogArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !got.since.Equal(tt.want.since) || !got.until.Equal(tt.want.until) || got.last != tt.want.last {
+				t.Errorf("parseLogArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+
+			if file != tt.wantFile {
+				t.Errorf("file = %q, want %q", file, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestLogRecordsAndRetrieves(t *testing.T) {
+	fp := newFeedPort()
+	opens := 0
+
+	mod := &Log{Port: "/dev/log-test", Dir: t.TempDir(), open: countingOpener(fp, &opens)}
+
+	err := mod.Init(context.Background())
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	// The recording attaches in the background; the feed blocks until it reads.
+	fp.feed <- []byte("U-Boot 2024.01\r\n")
+	fp.feed <- []byte("Starting kernel ...\r\n")
+
+	path := filepath.Join(mod.Dir, "log-test.log")
+	deadline := time.Now().Add(time.Second)
+
+	for !strings.Contains(readFile(t, path), "Starting kernel") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sess := &recordingSession{}
+
+	err = mod.Run(context.Background(), sess, "-n", "1")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if got := sess.out.String(); !strings.HasSuffix(got, " Starting kernel ...\n") || strings.Contains(got, "U-Boot") {
+		t.Errorf("Run -n 1 printed %q, want the last line only", got)
+	}
+
+	fileSess := &mock.Session{}
+
+	err = mod.Run(context.Background(), fileSess, "-file", "console.log")
+	if err != nil {
+		t.Fatalf("Run -file: %v", err)
+	}
+
+	if fileSess.SentFileName != "console.log" || strings.Count(string(fileSess.SentFileContent), "\n") != 2 {
+		t.Errorf("sent file %q with %q, want console.log with both lines", fileSess.SentFileName, fileSess.SentFileContent)
+	}
+
+	err = mod.Deinit(context.Background())
+	if err != nil {
+		t.Fatalf("Deinit: %v", err)
+	}
+
+	if !fp.isClosed() {
+		t.Error("port still open after Deinit")
+	}
+}
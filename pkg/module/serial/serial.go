@@ -2,20 +2,27 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package serial provides a dutagent module that runs a scripted send/expect
-// sequence against a DUT's serial port.
+// Package serial provides dutagent modules for a DUT's serial port: serial runs
+// a scripted send/expect sequence against the port or connects it to the
+// client's console, serial-log records the port's output in the background and
+// retrieves it on request, serial-exec runs a shell command on the DUT's console
+// and reports its exit status, and bootloader drives the DUT's bootloader.
 package serial
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/BlindspotSoftware/dutctl/internal/log"
 	"github.com/BlindspotSoftware/dutctl/pkg/module"
 	"go.bug.st/serial"
+	"golang.org/x/sys/unix"
 )
 
 func init() {
@@ -44,49 +51,63 @@ const defaultDelay = 50 * time.Millisecond
 const sendDrain = time.Second
 
 // portOpener opens a serial device. It is the injection point that lets tests
-// substitute a fake port for the real hardware.
-type portOpener func(name string, baud int) (port, error)
+// substitute a fake port for the real hardware. The device is opened by the
+// first command using it and shared with the others (see attach).
+type portOpener func(name string, line lineSettings) (port, error)
 
 // Serial observes the DUT's serial output and runs a scripted sequence of
 // send/expect steps against the serial port.
 //
-// The module is non-interactive: all input is supplied up front as arguments,
-// which makes it suitable for scripts and automated callers.
+// By default the module is non-interactive: all input is supplied up front as
+// arguments, which makes it suitable for scripts and automated callers. In
+// interactive mode it connects the port to the client's console instead, once
+// the steps, if any, completed.
 type Serial struct {
-	Port  string // Port is the path to the serial device on the dutagent.
+	Port  string // Port is the path to the serial device on the dutagent, or a tcp:// or rfc2217:// URL of a network serial port.
 	Baud  int    // Baud is the baud rate of the serial device. If unset, DefaultBaudRate is used.
 	Delay string // Delay is the pause before each send (e.g. "200ms") to pace input. Default 50ms; "0s" disables.
 
+	DataBits int    `yaml:"data-bits"` // DataBits is the number of data bits, 5 to 8. Default 8.
+	Parity   string // Parity is none (default), odd, even, mark or space.
+	StopBits string `yaml:"stop-bits"` // StopBits is the number of stop bits: 1 (default), 1.5 or 2.
+	Flow     string // Flow is the flow control: none (default), xon-xoff or rts-cts.
+
 	delay time.Duration // delay is the parsed Delay, applied before each send.
+	line  lineSettings  // line holds the parsed line settings.
 
 	// drainTimeout overrides the post-send drain window; 0 uses sendDrain. It
 	// exists so tests can shorten the drain; production leaves it 0.
 	drainTimeout time.Duration
 
 	// open opens the serial port. It defaults to defaultOpenPort in Run; tests
-	// set it to a fake. Opening happens per Run, never on the struct.
+	// set it to a fake. The struct never holds the port: a Run attaches to the
+	// port shared by all commands using it, see attach.
 	open portOpener
 }
 
 // Ensure implementing the Module interface.
 var _ module.Module = &Serial{}
 
-const abstract = `Scripted serial connection to the DUT
+const abstract = `Scripted or interactive serial connection to the DUT
 `
 
 const usage = `
 ARGUMENTS:
 	[-t <duration>] [-eol cr|lf|crlf|none] [-keep-escapes]                 (monitor: stream output)
 	[-t <duration>] [-eol cr|lf|crlf|none] [-keep-escapes] [--] <step>...  (run a step sequence)
+	-i [-escape <key>] [-strip-escapes] [-t <duration>] [-eol cr|lf|crlf|none] [[--] <step>...]
+	                                                                       (interactive console)
 
-	step := expect <regex> | send <data> | send-raw <data>
+	step := expect[:<timeout>] <regex> | expect-not <regex> | send <data> | send-raw <data>
+	      | send-xmodem <file> | send-ymodem <file> | receive-xmodem <file> | receive-ymodem <file>
+	      | dtr on|off|pulse <duration> | rts on|off|pulse <duration>
 
 `
 
 const description = `
 The serial module automates interaction with the DUT's serial console. All
 input is provided up front as arguments, so it suits scripts and automated
-callers.
+callers. With -i it serves as an interactive remote console instead.
 
 With no steps it runs in MONITOR mode: it streams the serial output to the
 client until the session is cancelled, or until -t elapses (a success). With
@@ -100,16 +121,52 @@ STEPS (executed in order; the run fails on the first expect that times out):
 	                 matches itself; escape regex meta characters
 	                 (. $ * + ? ( ) [ ] { } ^ | \) to match them literally,
 	                 e.g. expect '192\.168\.0\.1'.
+	expect:<timeout> <regex>
+	                 Like expect, but fail if there is no match within
+	                 <timeout> (e.g. expect:30s), in addition to -t.
+	expect-not <regex>
+	                 Fail the run as soon as the output read by the steps
+	                 after this one matches <regex>, e.g. 'Kernel panic'.
 	send <data>      Write <data> followed by the configured line ending
 	                 (see -eol) to the port.
 	send-raw <data>  Write <data> verbatim, with no line ending appended.
-
-send / send-raw data supports the escapes \r \n \t \\ and \xNN (e.g. \x03 for
-Ctrl-C). Each step value is one argument, so quote values containing spaces.
+	send-xmodem <file>, send-ymodem <file>
+	                 Send <file> from the client to the DUT with the XMODEM
+	                 or YMODEM protocol, e.g. to U-Boot's loadx / loady.
+	receive-xmodem <file>, receive-ymodem <file>
+	                 Receive a file from the DUT with the XMODEM or YMODEM
+	                 protocol and send it to the client as <file>.
+	dtr on|off, rts on|off
+	                 Set the DTR or RTS control line of the port, which is on
+	                 after the port was opened.
+	dtr pulse <duration>, rts pulse <duration>
+	                 Set the line on for <duration> (e.g. 100ms), then off.
+	                 Boards often wire the lines to their reset and boot pins.
+
+send / send-raw data supports the escapes \r \n \t \\ \$ and \xNN (e.g. \x03
+for Ctrl-C). Each step value is one argument, so quote values containing spaces.
+
+The file transfers use 128 byte blocks for XMODEM and 1 KiB blocks for YMODEM,
+checked with CRC-16 (or a checksum, if the DUT asks for it) and repeated on
+errors. A file to send is requested from the client when its step runs and
+streamed to the DUT as it arrives. A received XMODEM file loses trailing
+padding bytes (0x1a), as XMODEM does not tell the file size.
+
+Named groups of an expect pattern capture variables: after
+expect 'address (?P<ip>[0-9.]+)', the data of later sends may use ${ip}, which
+is replaced by the captured text. Any other ${...}, like ${HOME}, is sent as it
+is. Write \${ip} to send ${ip} itself.
 
 If the last step is a send, the module keeps showing output for a moment
 afterwards so the DUT's reply to that final input is visible.
 
+INTERACTIVE mode (-i) connects the serial port to your console once the steps,
+if any, completed: the output is shown as it is, and what you type is written
+to the port, with the Enter key sent as the configured line ending (see -eol).
+In a terminal every key is passed on, control keys included. The session ends
+with the escape key (see -escape), which is not written to the port, or when -t
+elapses or the command is canceled, all of which are a success.
+
 FLAGS (before the steps):
 	-t <duration>          Global timeout for the whole run (e.g. 30s, 3m).
 	                       0 (default) means no timeout.
@@ -117,9 +174,21 @@ FLAGS (before the steps):
 	                       which is what serial consoles expect on Enter.
 	-keep-escapes          Keep terminal escape sequences (cursor moves, colour,
 	                       queries) instead of stripping them from the output.
+	-i                     Interactive mode, see above.
+	-escape <key>          Key ending the interactive session, in caret
+	                       notation (^T, the default) or as \xNN; none disables
+	                       it. Ctrl-] is taken by dutctl to detach.
+	-strip-escapes         Strip terminal escape sequences in interactive mode.
+
+The serial port is shared by all commands using it, so you can watch the
+console while a script runs. Each run sees the output produced after it
+started. Only one run at a time may write to the port, though: a run with send
+steps or in interactive mode fails while another one does.
 
 Terminal escape sequences (cursor moves, colour, queries) are stripped from the
-output before it is shown or matched, unless -keep-escapes is given. Expect
+output before it is shown or matched, unless -keep-escapes is given. In
+interactive mode they are kept, so your terminal renders them, unless
+-strip-escapes is given. Expect
 matching uses a rolling window of the most recent 64 KiB of output, so a single
 pattern cannot span more than that. Match on distinctive markers/prompts
 rather than '^'/'$' anchors. Note the DUT may echo what you send, so an expect
@@ -130,6 +199,12 @@ EXAMPLES:
 	wait for a boot marker:         -- expect 'Welcome to'
 	login then run a command:       -- expect 'login:' send root expect '# ' send reboot
 	send Ctrl-C then expect shell:  -- send-raw '\x03' expect '$ '
+	fail fast on a kernel panic:    -- expect-not 'Kernel panic' expect:3m 'login:'
+	reuse a captured address:       -- expect 'inet (?P<ip>[0-9.]+)' send 'ping -c1 ${ip}'
+	load U-Boot over the console:   -- expect '=> ' send 'loady' send-ymodem u-boot.bin expect '=> '
+	reset via RTS, wait for boot:   -- rts pulse 100ms expect 'U-Boot'
+	interactive console:            -i
+	interrupt boot, then take over: -i -- expect 'autoboot' send-raw ' '
 
 [1] https://golang.org/s/re2syntax
 `
@@ -138,7 +213,7 @@ func (s *Serial) Help() string {
 	help := strings.Builder{}
 	help.WriteString(abstract)
 	help.WriteString(usage)
-	fmt.Fprintf(&help, "Configured COM port is %q with baud rate %d.\n", s.Port, s.Baud)
+	fmt.Fprintf(&help, "Configured COM port is %q with %s.\n", s.Port, s.line)
 	help.WriteString(description)
 
 	return help.String()
@@ -146,17 +221,28 @@ func (s *Serial) Help() string {
 
 // Init validates the configuration and parses the send delay. It deliberately
 // does not open the serial port, so dutagent can start even when the device is
-// unavailable (e.g. powered off); the port is opened per Run instead.
+// unavailable (e.g. powered off); a Run attaches to the port instead, opening it
+// unless another command or a serial-log recording holds it open already.
 func (s *Serial) Init(ctx context.Context) error {
 	if s.Port == "" {
 		return fmt.Errorf("COM port is not set")
 	}
 
+	_, _, err := parsePortName(s.Port)
+	if err != nil {
+		return err
+	}
+
 	if s.Baud == 0 {
 		s.Baud = DefaultBaudRate
 		log.FromContext(ctx).Debug(fmt.Sprintf("no baud rate configured, using default %d", DefaultBaudRate))
 	}
 
+	s.line, err = newLineSettings(s.Baud, s.DataBits, s.Parity, s.StopBits, s.Flow)
+	if err != nil {
+		return err
+	}
+
 	s.delay = defaultDelay
 
 	if s.Delay != "" {
@@ -168,28 +254,55 @@ func (s *Serial) Init(ctx context.Context) error {
 		s.delay = parsed
 	}
 
-	// Note: We don't open the port here to allow dutagent to start
-	// even if the serial device is not yet available (e.g., powered off).
-	// The port will be opened when Run() is called.
-
 	return nil
 }
 
-// Deinit does nothing: the port is opened and closed within each Run, never
-// held on the struct between runs, so there is nothing to release.
+// Deinit does nothing: a Run detaches from the shared port when it returns,
+// which closes the port once no other command and no recording (see Log.Deinit)
+// uses it, so the module holds nothing between runs.
 func (s *Serial) Deinit(_ context.Context) error {
-	// Nothing to clean up: the port is opened and closed within each Run
-	// (see Run's defer), never held on the struct between runs.
 	return nil
 }
 
-// defaultOpenPort is the default portOpener; it opens a real serial device.
-func defaultOpenPort(name string, baud int) (port, error) {
-	serialPort, err := serial.Open(name, &serial.Mode{BaudRate: baud})
+// defaultOpenPort is the default portOpener; it opens a real serial device, or
+// connects to a network serial port if name is a tcp:// or rfc2217:// URL.
+func defaultOpenPort(name string, line lineSettings) (port, error) {
+	scheme, addr, err := parsePortName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheme != "" {
+		return openNetworkPort(scheme, addr, line)
+	}
+
+	// The flow control is set on a descriptor of its own (see setFlowControl),
+	// opened before the port, which takes exclusive access.
+	flowFD := -1
+
+	if line.flow != flowNone {
+		flowFD, err = unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open serial port %s: %w", name, err)
+		}
+
+		defer unix.Close(flowFD)
+	}
+
+	serialPort, err := serial.Open(name, line.mode())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open serial port %s: %w", name, err)
 	}
 
+	if flowFD >= 0 {
+		err = setFlowControl(flowFD, line.flow)
+		if err != nil {
+			_ = serialPort.Close()
+
+			return nil, fmt.Errorf("failed to set flow control on %s: %w", name, err)
+		}
+	}
+
 	// Short read timeout so the read loop stays responsive to context
 	// cancellation; a timed-out read returns (0, nil), not an error.
 	err = serialPort.SetReadTimeout(readTimeout)
@@ -202,7 +315,7 @@ func defaultOpenPort(name string, baud int) (port, error) {
 	return serialPort, nil
 }
 
-// Run opens the configured serial port and either streams its output or
+// Run attaches to the configured serial port and either streams its output or
 // executes a step sequence. With no steps it runs in monitor mode, streaming
 // until the session is cancelled or -t elapses (both a success). With steps it
 // sends and expects in order, failing on the first expect that times out.
@@ -226,23 +339,29 @@ func (s *Serial) Run(ctx context.Context, session module.Session, args ...string
 		opener = defaultOpenPort
 	}
 
-	serialPort, err := opener(s.Port, s.Baud)
+	// The port is shared with the other commands using it: the run observes the
+	// output produced from now on, and holds write access only if it writes.
+	serialPort, err := attach(s.Port, s.line, opener, cfg.writes())
 	if err != nil {
 		return err
 	}
 	defer serialPort.Close()
 
-	// Discard any stale bytes left in the kernel/driver RX buffer from a
-	// previous session, otherwise a step could match data from the last boot.
-	err = serialPort.ResetInputBuffer()
-	if err != nil {
-		l.Warn("reset input buffer failed", "err", err)
-	}
-
-	l.Info(fmt.Sprintf("connected to %s at %d baud", s.Port, s.Baud))
+	l.Info(fmt.Sprintf("connected to %s at %s", s.Port, s.line), "write", cfg.writes())
 
 	clientOut := newClientWriter(session)
-	clientOut.markerf("--- Connected to %s at %d baud ---\n", s.Port, s.Baud)
+
+	// In interactive mode all output goes to the console, which the client
+	// renders in its terminal, so it is not mixed with Print messages.
+	var con console
+
+	if cfg.interactive {
+		stdin, stdout, _ := session.Console()
+		clientOut = newConsoleWriter(stdout)
+		con = console{input: stdin, signals: session.Signals(), eol: cfg.eol, escape: cfg.escape}
+	}
+
+	clientOut.markerf("--- Connected to %s at %s ---\n", s.Port, s.line)
 
 	// loopCtx carries the per-sequence deadline (-t). The original ctx is kept
 	// for the post-send drain so the drain gets its own full window.
@@ -257,10 +376,10 @@ func (s *Serial) Run(ctx context.Context, session module.Session, args ...string
 		defer cancel()
 	}
 
-	eng := newEngine(serialPort, clientOut, !cfg.keepEscapes)
+	eng := newEngine(serialPort, clientOut, cfg.filterEscapes())
 
 	// Monitor mode: no steps — stream the console until cancelled or -t elapses.
-	if len(cfg.steps) == 0 {
+	if len(cfg.steps) == 0 && !cfg.interactive {
 		l.Debug("monitor mode; streaming until cancelled")
 
 		err = eng.monitor(loopCtx)
@@ -273,50 +392,257 @@ func (s *Serial) Run(ctx context.Context, session module.Session, args ...string
 		return nil
 	}
 
+	err = s.runSteps(loopCtx, session, eng, clientOut, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Interactive mode: hand the port over to the user once the steps, if any,
+	// completed. The session shows the reply to a final send, so no drain.
+	if cfg.interactive {
+		l.Debug("interactive mode; connecting the console")
+
+		// The user is in control now: expect-not steps watch the steps only.
+		eng.guards = nil
+		clientOut.markerf("--- Interactive session, %s ---\n", endHint(cfg.escape))
+
+		err = eng.interact(loopCtx, con)
+		if err != nil {
+			return fmt.Errorf("interactive session: %w", err)
+		}
+
+		clientOut.markerf("--- Connection closed ---\n")
+
+		return nil
+	}
+
+	// If the sequence ended on a send, drain briefly so the DUT's reply to the
+	// final input is visible. Uses the original ctx (its own window).
+	if last := cfg.steps[len(cfg.steps)-1]; last.writes() {
+		drainFor := sendDrain
+		if s.drainTimeout > 0 {
+			drainFor = s.drainTimeout
+		}
+
+		err = eng.drain(ctx, drainFor)
+		if err != nil {
+			return fmt.Errorf("draining after final send: %w", err)
+		}
+	}
+
+	clientOut.markerf("--- Script completed ---\n")
+
+	return nil
+}
+
+// runSteps runs the step sequence of cfg, reporting the progress to out. ctx
+// carries the global deadline (-t); an expect step with a timeout of its own
+// fails once that elapsed, too. The text captured by an expect is kept in
+// variables, which later send steps fill in. Files are exchanged with the client
+// through session.
+//
+//nolint:cyclop,funlen // a flat step dispatcher; splitting it would not aid clarity
+func (s *Serial) runSteps(ctx context.Context, session module.Session, eng *engine, out *clientWriter, cfg scriptConfig) error {
 	total := len(cfg.steps)
+	vars := make(map[string]string)
 
 	for idx, curStep := range cfg.steps {
 		switch curStep.kind {
 		case stepExpect:
-			err = eng.readUntil(loopCtx, curStep.expect)
+			captures, err := expectStep(ctx, eng, curStep)
 			if err != nil {
-				return stepError(idx, curStep, cfg.timeout, err)
+				timeout := cfg.timeout
+				if curStep.timeout > 0 && ctx.Err() == nil {
+					timeout = curStep.timeout
+				}
+
+				return stepError(idx, curStep, timeout, err)
 			}
 
-			clientOut.markerf("--- [%d/%d] matched %q ---\n", idx+1, total, curStep.label())
+			maps.Copy(vars, captures)
+			out.markerf("--- [%d/%d] matched %q%s ---\n", idx+1, total, curStep.label(), formatCaptures(curStep, captures))
+		case stepExpectNot:
+			eng.watch(idx, curStep)
+			out.markerf("--- [%d/%d] watching for %q ---\n", idx+1, total, curStep.label())
 		case stepSend, stepSendRaw:
 			// Pace input: pause before each send (interruptible by the deadline).
-			err = sleepCtx(loopCtx, s.delay)
+			err := sleepCtx(ctx, s.delay)
 			if err != nil {
 				return fmt.Errorf("step %d (send %q): %w", idx+1, curStep.label(), err)
 			}
 
-			err = eng.write(curStep.payload)
+			err = eng.write(curStep.data(vars))
 			if err != nil {
 				return fmt.Errorf("step %d (send %q): %w", idx+1, curStep.label(), err)
 			}
 
-			clientOut.markerf("--- [%d/%d] sent %q ---\n", idx+1, total, curStep.label())
+			out.markerf("--- [%d/%d] sent %q ---\n", idx+1, total, curStep.label())
+		case stepSendFile:
+			// The file is streamed from the client to the DUT as it arrives.
+			r, err := session.RequestFile(curStep.src)
+			if err != nil {
+				return fmt.Errorf("step %d (%s %q): requesting the file from the client: %w", idx+1, curStep.verb(), curStep.label(), err)
+			}
+
+			size := fileSize(r)
+			if size >= 0 {
+				out.markerf("--- [%d/%d] sending %q via %s (%d bytes) ---\n", idx+1, total, curStep.label(), curStep.proto, size)
+			} else {
+				out.markerf("--- [%d/%d] sending %q via %s ---\n", idx+1, total, curStep.label(), curStep.proto)
+			}
+
+			err = eng.sendFile(ctx, curStep.proto, path.Base(curStep.src), r, size, transferProgress(out, int(max(size, 0))))
+			if err != nil {
+				return stepError(idx, curStep, cfg.timeout, err)
+			}
+
+			out.markerf("--- [%d/%d] sent %q ---\n", idx+1, total, curStep.label())
+		case stepReceiveFile:
+			out.markerf("--- [%d/%d] receiving %q via %s ---\n", idx+1, total, curStep.label(), curStep.proto)
+
+			n, err := receiveFile(ctx, session, eng, curStep, transferProgress(out, 0))
+			if errors.Is(err, errFileNotSent) {
+				return fmt.Errorf("step %d (%s %q): %w", idx+1, curStep.verb(), curStep.label(), err)
+			}
+
+			if err != nil {
+				return stepError(idx, curStep, cfg.timeout, err)
+			}
+
+			out.markerf("--- [%d/%d] received %q (%d bytes) ---\n", idx+1, total, curStep.label(), n)
+		case stepControl:
+			err := controlStep(ctx, eng, curStep)
+			if err != nil {
+				return fmt.Errorf("step %d (%s %q): %w", idx+1, curStep.verb(), curStep.label(), err)
+			}
+
+			out.markerf("--- [%d/%d] %s %s ---\n", idx+1, total, strings.ToUpper(curStep.verb()), curStep.label())
 		}
 	}
 
-	// If the sequence ended on a send, drain briefly so the DUT's reply to the
-	// final input is visible. Uses the original ctx (its own window).
-	if cfg.steps[total-1].kind != stepExpect {
-		drainFor := sendDrain
-		if s.drainTimeout > 0 {
-			drainFor = s.drainTimeout
+	return nil
+}
+
+// errFileNotSent is wrapped by receiveFile if the received file could not be
+// sent on to the client.
+var errFileNotSent = errors.New("sending the file to the client failed")
+
+// receiveFile receives the file of a receive step from the DUT and streams it to
+// the client as it arrives, so it is never held in memory as a whole. The client
+// discards the file if the transfer from the DUT fails. It returns the size of
+// the file.
+func receiveFile(ctx context.Context, session module.Session, eng *engine, st step, progress func(received int)) (int64, error) {
+	pr, pw := io.Pipe()
+	sent := make(chan error, 1)
+
+	// SendFile only reads the pipe, and returns before receiveFile does.
+	go func() {
+		err := session.SendFile(st.src, pr)
+		pr.CloseWithError(err) // a failed send ends the transfer from the DUT
+		sent <- err
+	}()
+
+	n, err := eng.receiveFile(ctx, st.proto, pw, progress)
+	pw.CloseWithError(err) // a failed transfer fails the file sent to the client
+
+	// A send failing first fails the transfer with its error, and vice versa.
+	sendErr := <-sent
+	if sendErr != nil && (err == nil || errors.Is(err, sendErr)) {
+		return n, fmt.Errorf("%w: %w", errFileNotSent, sendErr)
+	}
+
+	return n, err
+}
+
+// fileSize returns the size of the file read by r if r tells it, else -1.
+func fileSize(r io.Reader) int64 {
+	sized, ok := r.(interface{ Size() int64 })
+	if !ok || sized.Size() <= 0 {
+		return -1
+	}
+
+	return sized.Size()
+}
+
+// transferProgress returns a progress callback for a file transfer, reporting
+// every tenth of size to out, or every 64 KiB if the size is unknown.
+func transferProgress(out *clientWriter, size int) func(done int) {
+	const (
+		steps       = 10
+		unknownStep = 64 * 1024
+	)
+
+	step := size / steps
+	if step == 0 {
+		step = unknownStep
+	}
+
+	next := step
+
+	return func(done int) {
+		if done < next {
+			return
 		}
 
-		err = eng.drain(ctx, drainFor)
-		if err != nil {
-			return fmt.Errorf("draining after final send: %w", err)
+		next = (done/step + 1) * step
+
+		if size > 0 {
+			out.markerf("--- %d%% (%d/%d bytes) ---\n", done*100/size, done, size) //nolint:mnd // percent
+		} else {
+			out.markerf("--- %d bytes ---\n", done)
 		}
 	}
+}
 
-	clientOut.markerf("--- Script completed ---\n")
+// expectStep waits for the pattern of an expect step, at most for the step's
+// timeout, if it has one. It returns the text captured by the pattern.
+func expectStep(ctx context.Context, eng *engine, st step) (map[string]string, error) {
+	if st.timeout > 0 {
+		var cancel context.CancelFunc
 
-	return nil
+		ctx, cancel = context.WithTimeout(ctx, st.timeout)
+		defer cancel()
+	}
+
+	return eng.readUntil(ctx, st.expect)
+}
+
+// controlStep sets the control line of a step, or pulses it: it sets the line
+// on and, after the pulse, off again, even if ctx ended meanwhile.
+func controlStep(ctx context.Context, eng *engine, st step) error {
+	if st.pulse == 0 {
+		return st.line.set(eng.p, st.on)
+	}
+
+	err := st.line.set(eng.p, true)
+	if err != nil {
+		return err
+	}
+
+	waitErr := sleepCtx(ctx, st.pulse)
+
+	err = st.line.set(eng.p, false)
+	if err != nil {
+		return err
+	}
+
+	return waitErr
+}
+
+// formatCaptures renders the variables captured by an expect step for its
+// progress marker, in the order of the pattern's groups.
+func formatCaptures(st step, captures map[string]string) string {
+	names := st.captures()
+	if len(names) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, captures[name]))
+	}
+
+	return ", captured " + strings.Join(parts, " ")
 }
 
 // sleepCtx pauses for d, returning ctx.Err() if ctx is done first. A
@@ -337,18 +663,43 @@ func sleepCtx(ctx context.Context, d time.Duration) error {
 	}
 }
 
-// stepError annotates an expect failure with the step number and pattern, and
-// gives a clear message for the common timeout/cancellation cases.
+// stepError annotates a step failure with the step number and argument, and
+// gives a clear message for the common timeout/cancellation cases and for
+// output matching an expect-not step.
 func stepError(idx int, failedStep step, timeout time.Duration, err error) error {
+	var unwanted *unwantedOutputError
+
+	outcome := "without match"
+	if failedStep.kind == stepSendFile || failedStep.kind == stepReceiveFile {
+		outcome = "before the transfer completed"
+	}
+
 	switch {
+	case errors.As(err, &unwanted):
+		return fmt.Errorf("step %d (%s %q): aborted, %w", idx+1, failedStep.verb(), failedStep.label(), err)
 	case errors.Is(err, context.DeadlineExceeded) && timeout > 0:
-		return fmt.Errorf("step %d (expect %q): timeout of %s reached without match", idx+1, failedStep.label(), timeout)
+		return fmt.Errorf("step %d (%s %q): timeout of %s reached %s", idx+1, failedStep.verb(), failedStep.label(), timeout, outcome)
 	case errors.Is(err, context.DeadlineExceeded):
-		return fmt.Errorf("step %d (expect %q): deadline reached without match", idx+1, failedStep.label())
+		return fmt.Errorf("step %d (%s %q): deadline reached %s", idx+1, failedStep.verb(), failedStep.label(), outcome)
 	case errors.Is(err, context.Canceled):
-		return fmt.Errorf("step %d (expect %q): canceled", idx+1, failedStep.label())
+		return fmt.Errorf("step %d (%s %q): canceled", idx+1, failedStep.verb(), failedStep.label())
 	default:
-		return fmt.Errorf("step %d (expect %q): %w", idx+1, failedStep.label(), err)
+		return fmt.Errorf("step %d (%s %q): %w", idx+1, failedStep.verb(), failedStep.label(), err)
+	}
+}
+
+// endHint tells the user how to end an interactive session with the given
+// escape key, 0 meaning none.
+func endHint(escape byte) string {
+	const caretOffset = 0x40 // caret notation flips bit 6
+
+	switch {
+	case escape == 0:
+		return "cancel the command to end it"
+	case escape < ' ':
+		return fmt.Sprintf("press ^%c to end it", escape^caretOffset)
+	default:
+		return fmt.Sprintf("press \\x%02x to end it", escape)
 	}
 }
 
@@ -358,17 +709,24 @@ func stepError(idx int, failedStep step, timeout time.Duration, err error) error
 // (e.g. a prompt with no trailing newline) did not end one — so every marker
 // lands on its own line.
 type clientWriter struct {
-	session     module.Session
+	print       func(text string)
 	atLineStart bool
 }
 
+// newClientWriter returns a clientWriter printing to the client.
 func newClientWriter(session module.Session) *clientWriter {
-	return &clientWriter{session: session, atLineStart: true}
+	return &clientWriter{print: func(text string) { session.Print(text) }, atLineStart: true}
+}
+
+// newConsoleWriter returns a clientWriter writing to the client's console. Like
+// a print, a write the client does not receive is dropped.
+func newConsoleWriter(stdout io.Writer) *clientWriter {
+	return &clientWriter{print: func(text string) { _, _ = io.WriteString(stdout, text) }, atLineStart: true}
 }
 
 func (w *clientWriter) Write(p []byte) (int, error) {
 	if len(p) > 0 {
-		w.session.Print(string(p))
+		w.print(string(p))
 		w.atLineStart = p[len(p)-1] == '\n'
 	}
 
@@ -380,10 +738,10 @@ func (w *clientWriter) Write(p []byte) (int, error) {
 // start.
 func (w *clientWriter) markerf(format string, args ...any) {
 	if !w.atLineStart {
-		w.session.Print("\n")
+		w.print("\n")
 	}
 
 	msg := fmt.Sprintf(format, args...)
-	w.session.Print(msg)
+	w.print(msg)
 	w.atLineStart = len(msg) > 0 && msg[len(msg)-1] == '\n'
 }
@@ -0,0 +1,487 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func init() {
+	module.Register(module.Record{
+		ID:  "serial-exec",
+		New: func() module.Module { return &Exec{} },
+	})
+}
+
+// Default prompts of the serial-exec module. They are matched at the end of the
+// output, where a prompt waits for input.
+const (
+	defaultShellPrompt    = `[$#] *$`
+	defaultLoginPrompt    = `login: *$`
+	defaultPasswordPrompt = `[Pp]assword: *$`
+)
+
+const (
+	// promptTimeout bounds the wait for each prompt while getting to the shell.
+	promptTimeout = 10 * time.Second
+	// maxLoginSteps bounds the prompts answered while getting to the shell.
+	maxLoginSteps = 5
+	// markerPrefix starts the markers enclosing the output of a command.
+	markerPrefix = "DUTCTL"
+)
+
+// Exec runs a shell command on the DUT's serial console and reports its exit
+// status, which the serial module cannot tell. It logs in, if the console asks
+// for it, runs the command enclosed in markers and passes only the output
+// between them on to the client.
+//
+// The port is shared with the serial module, see attach.
+type Exec struct {
+	Port  string // Port is the path to the serial device on the dutagent, or a URL of a network serial port.
+	Baud  int    // Baud is the baud rate of the serial device. If unset, DefaultBaudRate is used.
+	EOL   string // EOL is the line ending sent after each line: cr (default), lf or crlf.
+	Delay string // Delay is the pause before each line sent (e.g. "200ms"). Default 50ms; "0s" disables.
+
+	// The line settings, as of the serial module. Commands sharing a port must agree on them.
+	DataBits int `yaml:"data-bits"`
+	Parity   string
+	StopBits string `yaml:"stop-bits"`
+	Flow     string
+
+	User           string // User is the user to log in as, if the console asks for a login.
+	Password       string // Password is the password of User.
+	Prompt         string // Prompt is a regular expression matching the shell prompt. Default `[$#] *$`.
+	LoginPrompt    string `yaml:"login-prompt"`    // LoginPrompt matches the login prompt. Default `login: *$`.
+	PasswordPrompt string `yaml:"password-prompt"` // PasswordPrompt matches the password prompt. Default `[Pp]assword: *$`.
+
+	line  lineSettings
+	eol   []byte
+	delay time.Duration
+	// ready matches any prompt on the way to the shell, in groups named like
+	// the prompts.
+	ready *regexp.Regexp
+
+	// open opens the serial port. It defaults to defaultOpenPort; tests set it
+	// to a fake.
+	open portOpener
+}
+
+// Ensure implementing the Module interface.
+var _ module.Module = &Exec{}
+
+const execAbstract = `Run a shell command on the DUT's serial console and check its exit status
+`
+
+const execUsage = `
+ARGUMENTS:
+	[-t <duration>] [--] <command-string>
+
+`
+
+const execDescription = `
+The command-string is run by the shell on the DUT's serial console, and the
+module fails if its exit status is not 0. Quote the command-string if it
+contains spaces or special characters, e.g. "ip -br addr". It must be a single
+line, and the command must not read input.
+
+If the console asks for a login, the module logs in with the configured user
+and password first. The command is enclosed in markers, so only its own output
+is shown, not the echo of the command line, the prompts or other console output.
+
+FLAGS:
+	-t <duration>   Timeout for the whole run (e.g. 30s, 3m). 0 (default) means
+	                no timeout. On timeout the command is interrupted (Ctrl-C).
+
+EXAMPLES:
+	check the kernel version:     "uname -r"
+	wait for the network:         -t 2m "until ping -c1 -W1 10.0.0.1; do sleep 1; done"
+`
+
+func (e *Exec) Help() string {
+	help := strings.Builder{}
+	help.WriteString(execAbstract)
+	help.WriteString(execUsage)
+	fmt.Fprintf(&help, "Configured COM port is %q with %s.\n", e.Port, e.line)
+
+	if e.User != "" {
+		fmt.Fprintf(&help, "Logging in as %q if asked for.\n", e.User)
+	}
+
+	help.WriteString(execDescription)
+
+	return help.String()
+}
+
+// Init validates the configuration and compiles the prompts. Like the serial
+// module it does not open the port.
+//
+//nolint:cyclop // sequential validation of the options
+func (e *Exec) Init(ctx context.Context) error {
+	if e.Port == "" {
+		return errors.New("COM port is not set")
+	}
+
+	_, _, err := parsePortName(e.Port)
+	if err != nil {
+		return err
+	}
+
+	if e.Baud == 0 {
+		e.Baud = DefaultBaudRate
+		log.FromContext(ctx).Debug(fmt.Sprintf("no baud rate configured, using default %d", DefaultBaudRate))
+	}
+
+	e.line, err = newLineSettings(e.Baud, e.DataBits, e.Parity, e.StopBits, e.Flow)
+	if err != nil {
+		return err
+	}
+
+	if e.EOL == "" {
+		e.EOL = defaultEOL
+	}
+
+	e.eol, err = resolveEOL(e.EOL)
+	if err != nil {
+		return err
+	}
+
+	if len(e.eol) == 0 {
+		return errors.New("eol must end lines for the shell: cr, lf or crlf")
+	}
+
+	e.delay = defaultDelay
+
+	if e.Delay != "" {
+		e.delay, err = time.ParseDuration(e.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay %q: %w", e.Delay, err)
+		}
+	}
+
+	prompts := []struct {
+		name, value, fallback string
+	}{
+		{"prompt", e.Prompt, defaultShellPrompt},
+		{"login", e.LoginPrompt, defaultLoginPrompt},
+		{"password", e.PasswordPrompt, defaultPasswordPrompt},
+	}
+
+	groups := make([]string, 0, len(prompts))
+
+	for _, prompt := range prompts {
+		value := prompt.value
+		if value == "" {
+			value = prompt.fallback
+		}
+
+		_, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s pattern %q: %w", prompt.name, value, err)
+		}
+
+		groups = append(groups, fmt.Sprintf("(?P<%s>%s)", prompt.name, value))
+	}
+
+	e.ready = regexp.MustCompile(strings.Join(groups, "|"))
+
+	return nil
+}
+
+// Deinit does nothing: the port is attached within each Run only.
+func (e *Exec) Deinit(_ context.Context) error {
+	return nil
+}
+
+// Run gets to the shell on the console, logging in if asked for, runs the
+// command and passes its output on to the client. It fails if the command
+// exits with a status other than 0.
+func (e *Exec) Run(ctx context.Context, session module.Session, args ...string) error {
+	if e.ready == nil {
+		return errors.New("module not initialized")
+	}
+
+	timeout, command, err := parseExecArgs(args)
+	if err != nil {
+		return err
+	}
+
+	l := log.FromContext(ctx)
+
+	opener := e.open
+	if opener == nil {
+		opener = defaultOpenPort
+	}
+
+	serialPort, err := attach(e.Port, e.line, opener, true)
+	if err != nil {
+		return err
+	}
+	defer serialPort.Close()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	id, err := markerID()
+	if err != nil {
+		return err
+	}
+
+	out := &commandOutput{
+		out:   newClientWriter(session),
+		start: []byte(markerPrefix + "-START-" + id),
+		end:   []byte(markerPrefix + "-END-" + id + ":"),
+	}
+	eng := newEngine(serialPort, out, true)
+
+	err = e.login(ctx, eng)
+	if err != nil {
+		return err
+	}
+
+	l.Info(fmt.Sprintf("running %q on %s", command, e.Port))
+
+	status, err := e.execute(ctx, eng, command, id)
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return fmt.Errorf("command exited with code %d", status)
+	}
+
+	return nil
+}
+
+// parseExecArgs parses the arguments of the serial-exec module into the timeout
+// and the command-string.
+func parseExecArgs(args []string) (time.Duration, string, error) {
+	fs := flag.NewFlagSet("serial-exec", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output.
+
+	var timeout time.Duration
+
+	fs.DurationVar(&timeout, "t", 0, "timeout for the whole run (e.g. 30s, 3m); 0 = no timeout")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+
+	switch {
+	case fs.NArg() == 0:
+		return 0, "", errors.New("missing command-string")
+	case fs.NArg() > 1:
+		return 0, "", errors.New("too many arguments - if the command-string contains spaces or special characters, quote it")
+	case strings.ContainsAny(fs.Arg(0), "\r\n"):
+		return 0, "", errors.New("the command-string must be a single line")
+	}
+
+	return timeout, fs.Arg(0), nil
+}
+
+// markerID returns a random identifier making the markers of a run unique, so
+// neither the output of an earlier run nor the command's output matches them.
+func markerID() (string, error) {
+	const idLen = 6
+
+	id := make([]byte, idLen)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("generating marker: %w", err)
+	}
+
+	return fmt.Sprintf("%x", id), nil
+}
+
+// login gets to the shell prompt: it wakes the console up with a line ending
+// and answers the login and password prompts until the shell prompt shows.
+func (e *Exec) login(ctx context.Context, eng *engine) error {
+	var (
+		next     = e.eol
+		sentUser bool
+	)
+
+	for range maxLoginSteps {
+		err := e.send(ctx, eng, next)
+		if err != nil {
+			return fmt.Errorf("getting to the shell: %w", err)
+		}
+
+		promptCtx, cancel := context.WithTimeout(ctx, promptTimeout)
+		prompt, err := eng.readUntil(promptCtx, e.ready)
+
+		cancel()
+
+		switch {
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			return fmt.Errorf("getting to the shell: no prompt within %s", promptTimeout)
+		case err != nil:
+			return fmt.Errorf("getting to the shell: %w", err)
+		case prompt["prompt"] != "":
+			return nil
+		case prompt["login"] != "" && e.User == "":
+			return errors.New("the console asks for a login, but no user is configured")
+		case prompt["login"] != "" && sentUser:
+			return fmt.Errorf("login as %q failed", e.User)
+		case prompt["login"] != "":
+			next = append([]byte(e.User), e.eol...)
+			sentUser = true
+		default: // the password prompt
+			next = append([]byte(e.Password), e.eol...)
+		}
+	}
+
+	return fmt.Errorf("getting to the shell: no shell prompt after %d prompts", maxLoginSteps)
+}
+
+// execute runs command enclosed in the markers of id and returns its exit
+// status. The start marker is echoed before the command runs, the end marker
+// with the status after it. Both are split by quotes on the command line, so
+// its echo does not match them. If ctx ends first, the command is interrupted.
+func (e *Exec) execute(ctx context.Context, eng *engine, command, id string) (int, error) {
+	line := fmt.Sprintf(`echo '%s''-START-%s'; %s; echo '%s''-END-%s:'$?`, markerPrefix, id, command, markerPrefix, id)
+
+	err := e.send(ctx, eng, append([]byte(line), e.eol...))
+	if err != nil {
+		return 0, fmt.Errorf("sending the command: %w", err)
+	}
+
+	end := regexp.MustCompile(regexp.QuoteMeta(markerPrefix+"-END-"+id+":") + `(?P<status>\d+)\r?\n`)
+
+	result, err := eng.readUntil(ctx, end)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Leave the console usable for the next run.
+			_ = writeAll(eng.p, []byte{ctrlC})
+		}
+
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return 0, errors.New("timeout reached before the command completed, interrupted it")
+		case errors.Is(err, context.Canceled):
+			return 0, errors.New("canceled, interrupted the command")
+		default:
+			return 0, fmt.Errorf("reading the command output: %w", err)
+		}
+	}
+
+	status, err := strconv.Atoi(result["status"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid exit status %q", result["status"])
+	}
+
+	return status, nil
+}
+
+// send writes data to the port after the configured delay.
+func (e *Exec) send(ctx context.Context, eng *engine, data []byte) error {
+	err := sleepCtx(ctx, e.delay)
+	if err != nil {
+		return err
+	}
+
+	return eng.write(data)
+}
+
+// commandOutput passes the output of a command on to out: the output between
+// the line of the start marker and the end marker. Everything else, like the
+// echo of the command line and the prompts, is dropped.
+type commandOutput struct {
+	out   io.Writer
+	start []byte
+	end   []byte
+
+	started bool // whether the start marker was seen
+	inLine  bool // whether the rest of the start marker's line is to be dropped
+	ended   bool // whether the end marker was seen
+	pending []byte
+}
+
+func (c *commandOutput) Write(p []byte) (int, error) {
+	if c.ended {
+		return len(p), nil
+	}
+
+	c.pending = append(c.pending, p...)
+
+	if !c.started {
+		idx := bytes.Index(c.pending, c.start)
+		if idx < 0 {
+			// Keep what may be the beginning of the marker.
+			c.pending = c.pending[max(0, len(c.pending)-len(c.start)+1):]
+
+			return len(p), nil
+		}
+
+		c.pending = c.pending[idx+len(c.start):]
+		c.started = true
+		c.inLine = true
+	}
+
+	if c.inLine {
+		idx := bytes.IndexByte(c.pending, '\n')
+		if idx < 0 {
+			c.pending = c.pending[:0]
+
+			return len(p), nil
+		}
+
+		c.pending = c.pending[idx+1:]
+		c.inLine = false
+	}
+
+	if idx := bytes.Index(c.pending, c.end); idx >= 0 {
+		c.ended = true
+
+		return len(p), c.flush(c.pending[:idx])
+	}
+
+	// Hold back what may be the beginning of the end marker.
+	keep := partialSuffix(c.pending, c.end)
+	err := c.flush(c.pending[:len(c.pending)-keep])
+	c.pending = c.pending[len(c.pending)-keep:]
+
+	return len(p), err
+}
+
+func (c *commandOutput) flush(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	_, err := c.out.Write(data)
+
+	return err
+}
+
+// partialSuffix returns the length of the longest end of data that is the
+// beginning of marker.
+func partialSuffix(data, marker []byte) int {
+	for n := min(len(data), len(marker)-1); n > 0; n-- {
+		if bytes.HasSuffix(data, marker[:n]) {
+			return n
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,272 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// fakeShell plays the console of a DUT on its end of a serial line: it asks for
+// a login, if user is set, and runs command lines enclosed in the markers of
+// the serial-exec module, taking their output and status from run. A negative
+// status leaves the command running. Any other line just gets a new prompt. It ends when ctx is done and reports whether it
+// got a Ctrl-C on interrupted.
+func fakeShell(ctx context.Context, p *linePort, user, password string, run func(cmd string) (string, int), interrupted chan<- bool) {
+	commandLine := regexp.MustCompile(`^echo 'DUTCTL''-START-([0-9a-f]+)'; (.*); echo 'DUTCTL''-END-[0-9a-f]+:'\$\?$`)
+	stage := "shell"
+
+	if user != "" {
+		stage = "login"
+	}
+
+	var (
+		line []byte
+		buf  = make([]byte, readChunk)
+		gotC bool
+	)
+
+	for ctx.Err() == nil {
+		n, _ := p.Read(buf)
+		for _, b := range buf[:n] {
+			if b == ctrlC {
+				gotC = true
+
+				continue
+			}
+
+			if b != '\r' {
+				line = append(line, b)
+
+				continue
+			}
+
+			text := string(line)
+			line = line[:0]
+
+			switch {
+			case stage == "login" && text == user:
+				_, _ = p.Write([]byte(text + "\r\nPassword: "))
+				stage = "password"
+			case stage == "login":
+				_, _ = p.Write([]byte(text + "\r\ndut login: "))
+			case stage == "password" && text == password:
+				_, _ = p.Write([]byte("\r\nroot@dut:~# "))
+				stage = "shell"
+			case stage == "password":
+				_, _ = p.Write([]byte("\r\nLogin incorrect\r\ndut login: "))
+				stage = "login"
+			default:
+				out := text + "\r\n"
+
+				if m := commandLine.FindStringSubmatch(text); m != nil {
+					output, status := run(m[2])
+					out += fmt.Sprintf("DUTCTL-START-%s\r\n%s", m[1], output)
+
+					if status < 0 {
+						_, _ = p.Write([]byte(out))
+
+						continue
+					}
+
+					out += fmt.Sprintf("DUTCTL-END-%s:%d\r\n", m[1], status)
+				}
+
+				_, _ = p.Write([]byte(out + "root@dut:~# "))
+			}
+		}
+	}
+
+	if interrupted != nil {
+		interrupted <- gotC
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	const stream = "echo 'DUTCTL''-START-ab'; ls; echo 'DUTCTL''-END-ab:'$?\r\n" +
+		"DUTCTL-START-ab\r\nfile DUTCTL-E\r\nDUTCTL-END-a\r\nDUTCTL-END-ab:0\r\n# "
+
+	for _, size := range []int{1, 3, 7, len(stream)} {
+		t.Run(fmt.Sprintf("chunks of %d", size), func(t *testing.T) {
+			var got bytes.Buffer
+
+			out := &commandOutput{out: &got, start: []byte("DUTCTL-START-ab"), end: []byte("DUTCTL-END-ab:")}
+
+			for rest := stream; rest != ""; {
+				chunk := rest[:min(size, len(rest))]
+				rest = rest[len(chunk):]
+
+				_, err := out.Write([]byte(chunk))
+				if err != nil {
+					t.Fatalf("Write = %v", err)
+				}
+			}
+
+			if want := "file DUTCTL-E\r\nDUTCTL-END-a\r\n"; got.String() != want {
+				t.Errorf("passed on %q, want %q", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestParseExecArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "command", args: []string{"uname -r"}, want: "uname -r"},
+		{name: "timeout", args: []string{"-t", "5s", "--", "-x"}, want: "-x"},
+		{name: "missing command", args: []string{}, wantErr: true},
+		{name: "unquoted command", args: []string{"uname", "-r"}, wantErr: true},
+		{name: "multiple lines", args: []string{"ls\nreboot"}, wantErr: true},
+		{name: "invalid timeout", args: []string{"-t", "soon", "ls"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := parseExecArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExecArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseExecArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecRun(t *testing.T) {
+	run := func(cmd string) (string, int) {
+		switch cmd {
+		case "uname -r":
+			return "6.6.0\r\n", 0
+		case "false":
+			return "", 1
+		default:
+			return "sh: " + cmd + ": not found\r\n", 127
+		}
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		dutUser  string
+		command  string
+		want     string
+		wantErr  string
+	}{
+		{name: "no login", command: "uname -r", want: "6.6.0\n"},
+		{name: "login", user: "root", password: "secret", dutUser: "root", command: "uname -r", want: "6.6.0\n"},
+		{name: "exit status", command: "false", wantErr: "command exited with code 1"},
+		{name: "not found", command: "foo", want: "sh: foo: not found\n", wantErr: "command exited with code 127"},
+		{name: "wrong password", user: "root", password: "guess", dutUser: "root", command: "ls", wantErr: "login as \"root\" failed"},
+		{name: "no user", dutUser: "root", command: "ls", wantErr: "no user is configured"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dutSide, agentSide := newLine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go fakeShell(ctx, dutSide, tt.dutUser, "secret", run, nil)
+
+			e := &Exec{
+				Port:     fmt.Sprintf("/dev/exec%d", i),
+				User:     tt.user,
+				Password: tt.password,
+				Delay:    "0s",
+				open:     func(_ string, _ lineSettings) (port, error) { return agentSide, nil },
+			}
+
+			err := e.Init(context.Background())
+			if err != nil {
+				t.Fatalf("Init = %v", err)
+			}
+
+			sess := &recordingSession{}
+
+			err = e.Run(context.Background(), sess, "-t", "5s", tt.command)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run = %v, want nil", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run = %v, want error containing %q", err, tt.wantErr)
+			}
+
+			if got := strings.ReplaceAll(sess.out.String(), "\r", ""); got != tt.want {
+				t.Errorf("client got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecRunTimeout(t *testing.T) {
+	dutSide, agentSide := newLine()
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := make(chan bool, 1)
+
+	go fakeShell(ctx, dutSide, "", "", func(string) (string, int) { return "zzz\r\n", -1 }, interrupted)
+
+	e := &Exec{
+		Port:  "/dev/exec-timeout",
+		Delay: "0s",
+		open:  func(_ string, _ lineSettings) (port, error) { return agentSide, nil },
+	}
+
+	err := e.Init(context.Background())
+	if err != nil {
+		t.Fatalf("Init = %v", err)
+	}
+
+	err = e.Run(context.Background(), &recordingSession{}, "-t", "200ms", "sleep 10")
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("Run = %v, want a timeout error", err)
+	}
+
+	cancel()
+
+	if !<-interrupted {
+		t.Error("the command was not interrupted")
+	}
+}
+
+func TestExecInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		exec    Exec
+		wantErr bool
+	}{
+		{name: "defaults", exec: Exec{Port: "/dev/ttyUSB0"}},
+		{name: "network port", exec: Exec{Port: "rfc2217://console:7000", Prompt: `=> $`}},
+		{name: "missing port", exec: Exec{}, wantErr: true},
+		{name: "invalid port", exec: Exec{Port: "ssh://console:22"}, wantErr: true},
+		{name: "invalid prompt", exec: Exec{Port: "/dev/ttyUSB0", Prompt: `[`}, wantErr: true},
+		{name: "invalid password prompt", exec: Exec{Port: "/dev/ttyUSB0", PasswordPrompt: `(`}, wantErr: true},
+		{name: "invalid parity", exec: Exec{Port: "/dev/ttyUSB0", Parity: "high"}, wantErr: true},
+		{name: "invalid eol", exec: Exec{Port: "/dev/ttyUSB0", EOL: "nl"}, wantErr: true},
+		{name: "no eol", exec: Exec{Port: "/dev/ttyUSB0", EOL: "none"}, wantErr: true},
+		{name: "invalid delay", exec: Exec{Port: "/dev/ttyUSB0", Delay: "short"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.exec.Init(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Init = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
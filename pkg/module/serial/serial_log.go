@@ -0,0 +1,373 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func init() {
+	module.Register(module.Record{
+		ID:  "serial-log",
+		New: func() module.Module { return &Log{} },
+	})
+}
+
+const (
+	// defaultMaxSize is the size in MiB at which a serial log is rotated if no
+	// MaxSize is configured.
+	defaultMaxSize = 10
+	// defaultKeep is the number of rotated serial logs kept if no Keep is configured.
+	defaultKeep = 5
+	// defaultLast is the number of lines shown if neither a number of lines nor a
+	// time range is requested.
+	defaultLast = 100
+	// printChunk bounds the log text sent to the client in a single message.
+	printChunk = 32 * 1024
+)
+
+// Log records the output of a DUT's serial port in the background, from the
+// start of the dutagent on, and retrieves it on request. So the output of a
+// boot is available even if no one was watching the console at the time.
+//
+// The recording shares the port with the serial module, see attach.
+type Log struct {
+	Port    string // Port is the path to the serial device on the dutagent, or a URL of a network serial port.
+	Baud    int    // Baud is the baud rate of the serial device. If unset, DefaultBaudRate is used.
+	Dir     string // Dir is the directory on the dutagent the log is written to.
+	MaxSize int    `yaml:"max-size"` // MaxSize is the size in MiB at which the log is rotated. Default 10.
+	Keep    int    // Keep is the number of rotated logs kept in addition to the current one. Default 5.
+
+	// The line settings, as of the serial module. Commands sharing a port must agree on them.
+	DataBits int `yaml:"data-bits"`
+	Parity   string
+	StopBits string `yaml:"stop-bits"`
+	Flow     string
+
+	line lineSettings // line holds the parsed line settings.
+	rec  *recorder    // rec is the recording, started in Init.
+
+	// open opens the serial port. It defaults to defaultOpenPort; tests set it
+	// to a fake.
+	open portOpener
+}
+
+// Ensure implementing the Module interface.
+var _ module.Module = &Log{}
+
+const logAbstract = `Retrieve the recorded output of the DUT's serial port
+`
+
+const logUsage = `
+ARGUMENTS:
+	[-n <lines>] [-since <time>] [-until <time>] [-file <name>]
+
+`
+
+const logDescription = `
+The output of the serial port is recorded in the background from the start of
+the dutagent on, whether or not a command is using the port, so the output of
+a boot is available even if no one was watching the console. Every line is
+stamped with the time it was received. The log is rotated when it reaches its
+maximum size, and the oldest part is dropped.
+
+Without arguments the last 100 recorded lines are shown.
+
+FLAGS:
+	-n <lines>      Show the last <lines> lines (of the time range, if given).
+	                0 shows all lines. Default: 100 without a time range,
+	                all lines of a time range.
+	-since <time>   Show lines received at or after <time>.
+	-until <time>   Show lines received before <time>.
+	-file <name>    Send the lines to the client as file <name> instead of
+	                showing them.
+
+<time> is a duration before now (e.g. 10m, 1h30m), a time of today (15:04 or
+15:04:05) or a date and time in RFC 3339 format (2006-01-02T15:04:05Z07:00).
+
+EXAMPLES:
+	last 100 lines:              (no arguments)
+	output of the last 5 min:    -since 5m
+	a time range:                -since 09:30 -until 09:45
+	save the whole log:          -n 0 -file console.log
+`
+
+func (l *Log) Help() string {
+	help := strings.Builder{}
+	help.WriteString(logAbstract)
+	help.WriteString(logUsage)
+	fmt.Fprintf(&help, "Recording COM port %q with %s.\n", l.Port, l.line)
+	help.WriteString(logDescription)
+
+	return help.String()
+}
+
+// Init validates the configuration and starts recording the port in the
+// background. The port does not need to be available yet: the recording
+// attaches to it as soon as it can be opened.
+func (l *Log) Init(ctx context.Context) error {
+	if l.Port == "" {
+		return errors.New("COM port is not set")
+	}
+
+	_, _, err := parsePortName(l.Port)
+	if err != nil {
+		return err
+	}
+
+	if l.Dir == "" {
+		return errors.New("log directory is not set")
+	}
+
+	if l.Baud == 0 {
+		l.Baud = DefaultBaudRate
+	}
+
+	l.line, err = newLineSettings(l.Baud, l.DataBits, l.Parity, l.StopBits, l.Flow)
+	if err != nil {
+		return err
+	}
+
+	if l.MaxSize < 0 || l.Keep < 0 {
+		return errors.New("max-size and keep must not be negative")
+	}
+
+	if l.MaxSize == 0 {
+		l.MaxSize = defaultMaxSize
+	}
+
+	if l.Keep == 0 {
+		l.Keep = defaultKeep
+	}
+
+	opener := l.open
+	if opener == nil {
+		opener = defaultOpenPort
+	}
+
+	const mib = 1 << 20
+
+	cfg := recorderConfig{
+		port:    l.Port,
+		line:    l.line,
+		dir:     l.Dir,
+		maxSize: int64(l.MaxSize) * mib,
+		keep:    l.Keep,
+	}
+
+	rec, err := startRecorder(cfg, opener, log.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	l.rec = rec
+
+	return nil
+}
+
+// Deinit stops the recording, unless another command still records the port.
+func (l *Log) Deinit(_ context.Context) error {
+	if l.rec != nil {
+		l.rec.release()
+		l.rec = nil
+	}
+
+	return nil
+}
+
+// Run shows the requested lines of the log, or sends them to the client as a file.
+func (l *Log) Run(ctx context.Context, session module.Session, args ...string) error {
+	if l.rec == nil {
+		return errors.New("serial port is not recorded, module not initialized")
+	}
+
+	query, fileName, err := parseLogArgs(args, time.Now())
+	if err != nil {
+		return err
+	}
+
+	files, err := l.rec.history()
+	if err != nil {
+		return err
+	}
+
+	logs := make([]io.Reader, 0, len(files))
+
+	for _, file := range files {
+		defer file.Close()
+
+		logs = append(logs, file)
+	}
+
+	log.FromContext(ctx).Debug("retrieving serial log", "since", query.since, "until", query.until, "last", query.last, "file", fileName)
+
+	if fileName != "" {
+		return sendLines(session, fileName, logs, query)
+	}
+
+	return printLines(session, logs, query)
+}
+
+// printLines shows the lines of logs selected by q to the client, in messages
+// of about printChunk bytes.
+func printLines(session module.Session, logs []io.Reader, q logQuery) error {
+	var (
+		text  strings.Builder
+		empty = true
+		ended = true // whether the last line ended with a newline
+	)
+
+	err := selectLines(logs, q, func(line string) error {
+		empty = false
+		ended = strings.HasSuffix(line, "\n")
+
+		text.WriteString(line)
+
+		if text.Len() >= printChunk {
+			session.Print(text.String())
+			text.Reset()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if empty {
+		session.Println("No serial output recorded in the requested range.")
+
+		return nil
+	}
+
+	// The last line may be a prompt still waiting for its newline.
+	if !ended {
+		text.WriteString("\n")
+	}
+
+	if text.Len() > 0 {
+		session.Print(text.String())
+	}
+
+	return nil
+}
+
+// sendLines sends the lines of logs selected by q to the client as file name.
+func sendLines(session module.Session, name string, logs []io.Reader, q logQuery) error {
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(selectLines(logs, q, func(line string) error {
+			_, err := io.WriteString(pw, line)
+
+			return err
+		}))
+	}()
+
+	err := session.SendFile(name, pr)
+
+	// Unblock the writer if the transfer ended early.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+
+	if err != nil {
+		return fmt.Errorf("sending serial log: %w", err)
+	}
+
+	return nil
+}
+
+// parseLogArgs parses the arguments of the serial-log module into a query and
+// the name of the file to send, if any. Times are resolved relative to now.
+func parseLogArgs(args []string, now time.Time) (logQuery, string, error) {
+	fs := flag.NewFlagSet("serial-log", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output.
+
+	var (
+		last      int
+		since     string
+		until     string
+		fileName  string
+		query     logQuery
+		lastIsSet bool
+	)
+
+	fs.IntVar(&last, "n", defaultLast, "number of lines to show; 0 shows all")
+	fs.StringVar(&since, "since", "", "show lines received at or after this time")
+	fs.StringVar(&until, "until", "", "show lines received before this time")
+	fs.StringVar(&fileName, "file", "", "send the lines as file with this name")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return logQuery{}, "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+
+	if fs.NArg() > 0 {
+		return logQuery{}, "", fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	fs.Visit(func(f *flag.Flag) { lastIsSet = lastIsSet || f.Name == "n" })
+
+	if last < 0 {
+		return logQuery{}, "", fmt.Errorf("invalid number of lines %d", last)
+	}
+
+	if since != "" {
+		query.since, err = parseLogTime(since, now)
+		if err != nil {
+			return logQuery{}, "", fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+
+	if until != "" {
+		query.until, err = parseLogTime(until, now)
+		if err != nil {
+			return logQuery{}, "", fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+
+	// A time range shows all of its lines, unless told otherwise.
+	query.last = last
+	if !lastIsSet && (since != "" || until != "") {
+		query.last = 0
+	}
+
+	return query, fileName, nil
+}
+
+// parseLogTime parses a point in time given as a duration before now, a time
+// of the day of now, or a date and time in RFC 3339 format.
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("negative duration %q", value)
+		}
+
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{time.TimeOnly, "15:04"} {
+		t, err := time.ParseInLocation(layout, value, now.Location())
+		if err == nil {
+			year, month, day := now.Date()
+
+			return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither a duration, a time of day nor an RFC 3339 time", value)
+}
@@ -5,9 +5,11 @@
 package serial
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -34,7 +36,7 @@ func newSerialWithPort(fp *fakePort) *Serial {
 		Port:         "/dev/fake",
 		Baud:         115200,
 		drainTimeout: 10 * time.Millisecond,
-		open:         func(_ string, _ int) (port, error) { return fp, nil },
+		open:         func(_ string, _ lineSettings) (port, error) { return fp, nil },
 	}
 }
 
@@ -206,6 +208,35 @@ func TestSerialRunMonitor(t *testing.T) {
 	}
 }
 
+func TestSerialRunInteractive(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("U-Boot\n=> ")}} // then quiet
+	s := newSerialWithPort(fp)
+
+	stdout := &bytes.Buffer{}
+	sess := &mock.Session{Stdin: strings.NewReader("printenv\n\x14"), Stdout: stdout, Stderr: io.Discard}
+
+	err := s.Run(context.Background(), sess, "-i", "-t", "1s", "--", "expect", "=> ")
+	if err != nil {
+		t.Fatalf("interactive Run = %v, want nil", err)
+	}
+
+	if got, want := string(fp.written), "printenv\r"; got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+
+	// All output goes to the console, none is printed.
+	if sess.PrintCalled {
+		t.Errorf("unexpected print %q in interactive mode", sess.PrintText)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"U-Boot\n=> ", "press ^T to end it", "--- Connection closed ---\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("console output %q does not contain %q", out, want)
+		}
+	}
+}
+
 func TestSerialRunTrailingSendDrain(t *testing.T) {
 	// Sequence ends on a send; the DUT's reply arrives during the drain.
 	fp := &fakePort{reads: [][]byte{[]byte("Rebooting now...\n")}}
@@ -269,3 +300,81 @@ func TestClientWriterMarkersOnOwnLine(t *testing.T) {
 		t.Errorf("client output:\n got: %q\nwant: %q", got, want)
 	}
 }
+
+func TestSerialRunCapturesIntoSend(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("eth0: leased 192.168.1.23\n# ")}}
+	s := newSerialWithPort(fp)
+	sess := &recordingSession{}
+
+	err := s.Run(context.Background(), sess, "-t", "1s", "--",
+		"expect", `leased (?P<ip>\S+)`, "expect", "# ", "send", "echo ${ip}")
+	if err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+
+	if got := string(fp.written); got != "echo 192.168.1.23\r" {
+		t.Errorf("written = %q, want the captured address", got)
+	}
+
+	if !strings.Contains(sess.out.String(), `captured ip="192.168.1.23"`) {
+		t.Errorf("output %q does not report the capture", sess.out.String())
+	}
+}
+
+func TestSerialRunExpectNotAborts(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("Starting kernel ...\n"), []byte("Kernel panic - not syncing\n")}}
+	s := newSerialWithPort(fp)
+
+	start := time.Now()
+
+	err := s.Run(context.Background(), &mock.Session{}, "-t", "5s", "--",
+		"expect-not", "Kernel panic", "expect", "login:")
+	if err == nil {
+		t.Fatal("Run = nil error, want the expect-not failure")
+	}
+
+	if time.Since(start) > time.Second {
+		t.Errorf("Run took %s, want it to fail fast", time.Since(start))
+	}
+
+	for _, want := range []string{"step 2", "login:", "step 1", "Kernel panic"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q misses %q", err, want)
+		}
+	}
+}
+
+func TestSerialRunStepTimeout(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("U-Boot\n")}} // then quiet
+	s := newSerialWithPort(fp)
+
+	err := s.Run(context.Background(), &mock.Session{}, "-t", "5s", "--",
+		"expect", "U-Boot", "expect:30ms", "Hit any key")
+	if err == nil {
+		t.Fatal("Run = nil error, want a step timeout")
+	}
+
+	if !strings.Contains(err.Error(), "step 2") || !strings.Contains(err.Error(), "timeout of 30ms") {
+		t.Errorf("error %q should name step 2 and its own timeout", err)
+	}
+}
+
+func TestSerialRunControlLines(t *testing.T) {
+	fp := &fakePort{reads: [][]byte{[]byte("waiting for download\n")}}
+	s := newSerialWithPort(fp)
+	sess := &recordingSession{}
+
+	err := s.Run(context.Background(), sess, "-t", "5s", "--",
+		"dtr", "off", "rts", "pulse", "10ms", "expect", "waiting for download", "dtr", "on")
+	if err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+
+	if got, want := strings.Join(fp.controls, ", "), "dtr off, rts on, rts off, dtr on"; got != want {
+		t.Errorf("control lines set %q, want %q", got, want)
+	}
+
+	if !strings.Contains(sess.out.String(), "RTS pulse 10ms") {
+		t.Errorf("output %q misses the pulse marker", sess.out.String())
+	}
+}
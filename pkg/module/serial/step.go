@@ -5,6 +5,7 @@
 package serial
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -21,16 +22,112 @@ const (
 	stepExpect stepKind = iota
 	stepSend
 	stepSendRaw
+	stepExpectNot
+	stepSendFile
+	stepReceiveFile
+	stepControl
 )
 
+// String returns the verb of the step kind, as used in the step sequence.
+func (k stepKind) String() string {
+	switch k {
+	case stepExpect:
+		return "expect"
+	case stepSend:
+		return "send"
+	case stepSendRaw:
+		return "send-raw"
+	case stepExpectNot:
+		return "expect-not"
+	case stepSendFile:
+		return "send-file"
+	case stepReceiveFile:
+		return "receive-file"
+	case stepControl:
+		return "control"
+	default:
+		return "unknown"
+	}
+}
+
 // step is one operation in a scripted send/expect sequence.
 type step struct {
 	kind    stepKind
-	expect  *regexp.Regexp // set for stepExpect
+	expect  *regexp.Regexp // set for stepExpect / stepExpectNot
+	timeout time.Duration  // per-step timeout of a stepExpect; 0 leaves only the global -t
 	payload []byte         // set for stepSend / stepSendRaw (escapes decoded; EOL appended for stepSend)
+	refs    []varRef       // variables inserted into payload when sending
+	proto   modemProtocol  // set for stepSendFile / stepReceiveFile
+	line    controlLine    // set for stepControl
+	on      bool           // the state a stepControl sets the line to
+	pulse   time.Duration  // set for a stepControl pulsing the line: on for pulse, then off
 	src     string         // original argument; shown (truncated) via label() in markers and errors
 }
 
+// verb returns the verb of the step, as used in the step sequence.
+func (s step) verb() string {
+	switch s.kind {
+	case stepSendFile:
+		return "send-" + s.proto.String()
+	case stepReceiveFile:
+		return "receive-" + s.proto.String()
+	case stepControl:
+		return s.line.String()
+	default:
+		return s.kind.String()
+	}
+}
+
+// writes reports whether the step writes to the port. The file transfers do,
+// to acknowledge the blocks, and setting a control line counts as writing.
+func (s step) writes() bool {
+	return s.kind != stepExpect && s.kind != stepExpectNot
+}
+
+// varRef is a reference to a variable in send data: the value of the variable
+// is inserted into the payload at offset.
+type varRef struct {
+	offset int
+	name   string
+}
+
+// data returns the payload of a send step with the referenced variables, which
+// are captured by earlier expect steps, filled in.
+func (s step) data(vars map[string]string) []byte {
+	if len(s.refs) == 0 {
+		return s.payload
+	}
+
+	out := make([]byte, 0, len(s.payload))
+	last := 0
+
+	for _, ref := range s.refs {
+		out = append(out, s.payload[last:ref.offset]...)
+		out = append(out, vars[ref.name]...)
+		last = ref.offset
+	}
+
+	return append(out, s.payload[last:]...)
+}
+
+// captures returns the names of the variables an expect step captures: the
+// named groups of its pattern.
+func (s step) captures() []string {
+	if s.kind != stepExpect {
+		return nil
+	}
+
+	var names []string
+
+	for _, name := range s.expect.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
 // maxLabelLen bounds how many runes of a step's source argument appear in
 // progress markers and error messages, so a long pattern or payload does not
 // bloat the log line.
@@ -59,9 +156,49 @@ type scriptConfig struct {
 	timeout     time.Duration
 	keepEscapes bool
 	steps       []step
+
+	// interactive connects the port to the client's console once the steps,
+	// if any, completed. stripEscapes opts into escape stripping there.
+	interactive  bool
+	stripEscapes bool
+	// eol is the line ending appended by 'send' and replacing the newlines
+	// typed into the interactive console.
+	eol []byte
+	// escape is the key ending the interactive session; 0 if disabled.
+	escape byte
 }
 
-const defaultEOL = "cr"
+// writes reports whether the run writes to the port: in interactive mode or to
+// send a step's data. Otherwise it only observes the output.
+func (c scriptConfig) writes() bool {
+	if c.interactive {
+		return true
+	}
+
+	for _, st := range c.steps {
+		if st.writes() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterEscapes reports whether terminal escape sequences are stripped from the
+// output: by default in scripted and monitor mode, only on request in
+// interactive mode, where the client's terminal renders them.
+func (c scriptConfig) filterEscapes() bool {
+	if c.interactive {
+		return c.stripEscapes
+	}
+
+	return !c.keepEscapes
+}
+
+const (
+	defaultEOL    = "cr"
+	defaultEscape = "^T"
+)
 
 // parseArgs parses the module arguments: the leading flags (-t, -eol) followed
 // by the step sequence (optionally after a "--" separator). It returns local
@@ -71,31 +208,54 @@ func parseArgs(args []string) (scriptConfig, error) {
 	fs.SetOutput(io.Discard) // Suppress default error output.
 
 	var (
-		timeout     time.Duration
-		eolName     string
-		keepEscapes bool
+		timeout      time.Duration
+		eolName      string
+		keepEscapes  bool
+		interactive  bool
+		stripEscapes bool
+		escapeName   string
 	)
 
 	fs.DurationVar(&timeout, "t", 0, "global timeout for the whole run (e.g. 30s, 3m); 0 = no timeout")
 	fs.StringVar(&eolName, "eol", defaultEOL, "line ending appended by 'send': cr|lf|crlf|none")
 	fs.BoolVar(&keepEscapes, "keep-escapes", false, "keep terminal escape sequences in the output instead of stripping them")
+	fs.BoolVar(&interactive, "i", false, "connect the serial port to the client's console")
+	fs.BoolVar(&stripEscapes, "strip-escapes", false, "strip terminal escape sequences in interactive mode")
+	fs.StringVar(&escapeName, "escape", defaultEscape, "key ending the interactive session, e.g. ^T or \\x14; none disables it")
 
 	err := fs.Parse(args)
 	if err != nil {
 		return scriptConfig{}, fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
+	if keepEscapes && stripEscapes {
+		return scriptConfig{}, errors.New("-keep-escapes and -strip-escapes are mutually exclusive")
+	}
+
 	eol, err := resolveEOL(eolName)
 	if err != nil {
 		return scriptConfig{}, err
 	}
 
+	escape, err := resolveEscape(escapeName)
+	if err != nil {
+		return scriptConfig{}, err
+	}
+
 	steps, err := parseSteps(fs.Args(), eol)
 	if err != nil {
 		return scriptConfig{}, err
 	}
 
-	return scriptConfig{timeout: timeout, keepEscapes: keepEscapes, steps: steps}, nil
+	return scriptConfig{
+		timeout:      timeout,
+		keepEscapes:  keepEscapes,
+		steps:        steps,
+		interactive:  interactive,
+		stripEscapes: stripEscapes,
+		eol:          eol,
+		escape:       escape,
+	}, nil
 }
 
 // resolveEOL maps the -eol flag value to the bytes appended by a 'send' step.
@@ -114,17 +274,61 @@ func resolveEOL(name string) ([]byte, error) {
 	}
 }
 
+// resolveEscape maps the -escape flag value to the key ending the interactive
+// session. It accepts caret notation (^T), a \xNN escape or "none", which
+// disables the escape key and is returned as 0.
+func resolveEscape(name string) (byte, error) {
+	const caretLen = 2
+
+	switch {
+	case strings.EqualFold(name, "none"):
+		return 0, nil
+	case len(name) == caretLen && name[0] == '^':
+		key := name[1]
+		if key >= 'a' && key <= 'z' {
+			key -= 'a' - 'A'
+		}
+
+		// Caret notation covers the control characters 0x01-0x1f, i.e. ^A to ^_.
+		if key < 'A' || key > '_' {
+			return 0, fmt.Errorf("invalid -escape %q: want ^A to ^_", name)
+		}
+
+		return key ^ 0x40, nil //nolint:mnd // caret notation flips bit 6
+	}
+
+	// Any other key must not be printable, as it could not be typed otherwise.
+	key, err := decodeEscapes(name)
+	if err != nil || len(key) != 1 || key[0] == 0 || (key[0] >= ' ' && key[0] <= '~') {
+		return 0, fmt.Errorf("invalid -escape %q: want a control key like ^T or \\x14, or none", name)
+	}
+
+	return key[0], nil
+}
+
 // parseSteps scans the verb token stream left-to-right into ordered steps.
-// Each verb consumes exactly the next token as its argument.
+// Each verb consumes the next token as its argument, a pulse of a control line
+// also the one after. An expect verb may carry a timeout for the step, as in
+// expect:30s.
+//
+//nolint:cyclop,funlen // a flat verb dispatcher; splitting it would not aid clarity
 func parseSteps(tokens []string, eol []byte) ([]step, error) {
 	var steps []step
 
+	// captured holds the variables captured by the expect steps so far, which
+	// the send steps after them may use.
+	captured := make(map[string]bool)
+
 	for idx := 0; idx < len(tokens); {
-		verb := tokens[idx]
+		verb, timeout, err := parseVerb(tokens[idx])
+		if err != nil {
+			return nil, err
+		}
+
 		idx++
 
 		switch verb {
-		case "expect":
+		case "expect", "expect-not":
 			if idx >= len(tokens) {
 				return nil, fmt.Errorf("%q requires a pattern argument", verb)
 			}
@@ -137,7 +341,17 @@ func parseSteps(tokens []string, eol []byte) ([]step, error) {
 				return nil, fmt.Errorf("invalid regular expression %q: %w", pat, err)
 			}
 
-			steps = append(steps, step{kind: stepExpect, expect: re, src: pat})
+			kind := stepExpect
+			if verb == "expect-not" {
+				kind = stepExpectNot
+			}
+
+			st := step{kind: kind, expect: re, timeout: timeout, src: pat}
+			for _, name := range st.captures() {
+				captured[name] = true
+			}
+
+			steps = append(steps, st)
 		case "send", "send-raw":
 			if idx >= len(tokens) {
 				return nil, fmt.Errorf("%q requires a data argument", verb)
@@ -146,7 +360,7 @@ func parseSteps(tokens []string, eol []byte) ([]step, error) {
 			raw := tokens[idx]
 			idx++
 
-			data, err := decodeEscapes(raw)
+			data, refs, err := parseData(raw, captured)
 			if err != nil {
 				return nil, fmt.Errorf("invalid data %q: %w", raw, err)
 			}
@@ -158,9 +372,39 @@ func parseSteps(tokens []string, eol []byte) ([]step, error) {
 				data = append(data, eol...)
 			}
 
-			steps = append(steps, step{kind: kind, payload: data, src: raw})
+			steps = append(steps, step{kind: kind, payload: data, refs: refs, src: raw})
+		case "send-xmodem", "send-ymodem", "receive-xmodem", "receive-ymodem":
+			if idx >= len(tokens) {
+				return nil, fmt.Errorf("%q requires a file argument", verb)
+			}
+
+			name := tokens[idx]
+			idx++
+
+			direction, protoName, _ := strings.Cut(verb, "-")
+
+			kind, proto := stepSendFile, xmodem
+			if direction == "receive" {
+				kind = stepReceiveFile
+			}
+
+			if protoName == "ymodem" {
+				proto = ymodem
+			}
+
+			steps = append(steps, step{kind: kind, proto: proto, src: name})
+		case "dtr", "rts":
+			st, consumed, err := parseControl(verb, tokens[idx:])
+			if err != nil {
+				return nil, err
+			}
+
+			idx += consumed
+
+			steps = append(steps, st)
 		default:
-			return nil, fmt.Errorf("unknown step verb %q (want expect, send, or send-raw)", verb)
+			return nil, fmt.Errorf("unknown step verb %q (want expect, expect-not, send, send-raw, "+
+				"send-xmodem, send-ymodem, receive-xmodem, receive-ymodem, dtr, or rts)", verb)
 		}
 	}
 
@@ -169,8 +413,123 @@ func parseSteps(tokens []string, eol []byte) ([]step, error) {
 	return steps, nil
 }
 
+// parseControl parses the arguments of a dtr or rts step, which are on, off or
+// pulse followed by a duration. It returns the step and the number of
+// arguments it consumed.
+func parseControl(verb string, args []string) (step, int, error) {
+	if len(args) == 0 {
+		return step{}, 0, fmt.Errorf("%q requires on, off or pulse <duration>", verb)
+	}
+
+	st := step{kind: stepControl, line: lineDTR, src: args[0]}
+	if verb == "rts" {
+		st.line = lineRTS
+	}
+
+	switch args[0] {
+	case "on", "off":
+		st.on = args[0] == "on"
+
+		return st, 1, nil
+	case "pulse":
+		if len(args) < 2 { //nolint:mnd // pulse and its duration
+			return step{}, 0, fmt.Errorf("%s pulse requires a duration", verb)
+		}
+
+		pulse, err := time.ParseDuration(args[1])
+		if err != nil || pulse <= 0 {
+			return step{}, 0, fmt.Errorf("%s pulse %q: want a positive duration like 100ms", verb, args[1])
+		}
+
+		st.pulse = pulse
+		st.src = "pulse " + args[1]
+
+		return st, 2, nil //nolint:mnd // pulse and its duration
+	default:
+		return step{}, 0, fmt.Errorf("%s %q: want on, off or pulse <duration>", verb, args[0])
+	}
+}
+
+// parseVerb splits a verb token into the verb and its step timeout, if any,
+// as in expect:30s. Only expect steps take a timeout.
+func parseVerb(token string) (string, time.Duration, error) {
+	verb, value, ok := strings.Cut(token, ":")
+	if !ok {
+		return verb, 0, nil
+	}
+
+	if verb != "expect" {
+		return "", 0, fmt.Errorf("step verb %q: only expect takes a timeout", token)
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return "", 0, fmt.Errorf("step verb %q: want a positive timeout like expect:30s", token)
+	}
+
+	return verb, timeout, nil
+}
+
+// parseData decodes send data and finds the references to the captured
+// variables in it, written ${name}. The references are cut from the returned
+// payload and recorded with their offset into it. A ${...} that names no captured
+// variable, like ${HOME} in a shell command, is sent as it is. A literal "$" may
+// also be written \$, which keeps ${name} from being filled in.
+func parseData(raw string, captured map[string]bool) ([]byte, []varRef, error) {
+	var (
+		payload []byte
+		refs    []varRef
+		literal strings.Builder
+	)
+
+	flush := func() error {
+		data, err := decodeEscapes(literal.String())
+		if err != nil {
+			return err
+		}
+
+		payload = append(payload, data...)
+		literal.Reset()
+
+		return nil
+	}
+
+	for idx := 0; idx < len(raw); idx++ {
+		switch {
+		case raw[idx] == '\\' && idx+1 < len(raw):
+			// Keep escapes for decodeEscapes, so an escaped $ starts no reference.
+			literal.WriteString(raw[idx : idx+2])
+			idx++
+		case strings.HasPrefix(raw[idx:], "${"):
+			end := strings.IndexByte(raw[idx:], '}')
+			if end < 0 || !captured[raw[idx+2:idx+end]] {
+				literal.WriteByte(raw[idx])
+
+				continue
+			}
+
+			err := flush()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			refs = append(refs, varRef{offset: len(payload), name: raw[idx+2 : idx+end]})
+			idx += end
+		default:
+			literal.WriteByte(raw[idx])
+		}
+	}
+
+	err := flush()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return payload, refs, nil
+}
+
 // decodeEscapes decodes the supported backslash escapes in a send payload:
-// \r \n \t \\ and \xNN (two hex digits). Other escapes are an error so typos
+// \r \n \t \\ \$ and \xNN (two hex digits). Other escapes are an error so typos
 // surface instead of silently passing through.
 //
 //nolint:cyclop // a flat escape dispatcher; splitting it would not aid clarity
@@ -204,6 +563,8 @@ func decodeEscapes(s string) ([]byte, error) {
 			out = append(out, '\t')
 		case '\\':
 			out = append(out, '\\')
+		case '$':
+			out = append(out, '$')
 		case 'x':
 			if idx+hexDigits >= len(s) {
 				return nil, fmt.Errorf(`\x requires two hex digits`)
@@ -86,6 +86,8 @@ func TestParseArgsErrors(t *testing.T) {
 		{"bad escape", []string{"--", "send", `x\q`}},
 		{"trailing backslash", []string{"--", "send", `x\`}},
 		{"short hex escape", []string{"--", "send", `x\x4`}},
+		{"bad escape key", []string{"-i", "-escape", "^1"}},
+		{"keep and strip escapes", []string{"-i", "-keep-escapes", "-strip-escapes"}},
 	}
 
 	for _, tt := range tests {
@@ -226,3 +228,193 @@ func TestParseArgsKeepEscapes(t *testing.T) {
 		t.Error("keepEscapes = true by default, want false")
 	}
 }
+
+func TestParseArgsInteractive(t *testing.T) {
+	cfg, err := parseArgs([]string{"-i", "--", "expect", "=>"})
+	if err != nil {
+		t.Fatalf("parseArgs(-i): %v", err)
+	}
+
+	if !cfg.interactive || len(cfg.steps) != 1 {
+		t.Errorf("got interactive=%v steps=%d, want interactive with 1 step", cfg.interactive, len(cfg.steps))
+	}
+
+	if cfg.escape != 0x14 || string(cfg.eol) != "\r" {
+		t.Errorf("got escape=%#x eol=%q, want the defaults ^T and CR", cfg.escape, cfg.eol)
+	}
+
+	// Escapes are kept in interactive mode unless stripping is requested, and
+	// stripped otherwise unless keeping them is requested.
+	filterTests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-i"}, false},
+		{[]string{"-i", "-strip-escapes"}, true},
+		{nil, true},
+		{[]string{"-keep-escapes"}, false},
+	}
+
+	for _, tt := range filterTests {
+		cfg, err := parseArgs(tt.args)
+		if err != nil {
+			t.Fatalf("parseArgs(%q): %v", tt.args, err)
+		}
+
+		if got := cfg.filterEscapes(); got != tt.want {
+			t.Errorf("parseArgs(%q).filterEscapes() = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestResolveEscape(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    byte
+		wantErr bool
+	}{
+		{in: "^T", want: 0x14},
+		{in: "^x", want: 0x18},
+		{in: "^]", want: 0x1d},
+		{in: "^_", want: 0x1f},
+		{in: `\x01`, want: 0x01},
+		{in: "none", want: 0},
+		{in: "NONE", want: 0},
+		{in: "^1", wantErr: true},
+		{in: "^", wantErr: true},
+		{in: `\x00`, wantErr: true},
+		{in: "ab", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveEscape(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveEscape(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("resolveEscape(%q) = %#x, want %#x", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseStepsTimeoutsAndCaptures(t *testing.T) {
+	cfg, err := parseArgs([]string{"--",
+		"expect-not", "Kernel panic",
+		"expect:2m", `lease (?P<ip>[0-9.]+) via (?P<gw>[0-9.]+)`,
+		"send", `ping -c1 ${gw} # from ${ip}`,
+		"send-raw", `\${ip}`,
+	})
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+
+	if cfg.steps[0].kind != stepExpectNot || !cfg.steps[0].expect.MatchString("Kernel panic - not syncing") {
+		t.Errorf("step0 = %+v, want expect-not 'Kernel panic'", cfg.steps[0])
+	}
+
+	if cfg.steps[1].kind != stepExpect || cfg.steps[1].timeout != 2*time.Minute {
+		t.Errorf("step1 = %+v, want expect with a 2m timeout", cfg.steps[1])
+	}
+
+	if got := strings.Join(cfg.steps[1].captures(), ","); got != "ip,gw" {
+		t.Errorf("captures = %q, want ip,gw", got)
+	}
+
+	vars := map[string]string{"ip": "10.0.0.5", "gw": "10.0.0.1"}
+
+	if got := string(cfg.steps[2].data(vars)); got != "ping -c1 10.0.0.1 # from 10.0.0.5\r" {
+		t.Errorf("send data = %q, want the captured values filled in", got)
+	}
+
+	if got := string(cfg.steps[3].data(vars)); got != "${ip}" {
+		t.Errorf("send-raw data = %q, want the escaped reference kept literally", got)
+	}
+
+	if !cfg.writes() {
+		t.Error("writes() = false for a sequence with sends")
+	}
+}
+
+func TestParseStepsUncapturedReferences(t *testing.T) {
+	cfg, err := parseArgs([]string{"--",
+		"send", `echo ${HOME} ${ip} ${1} ${ip`,
+		"expect", `lease (?P<ip>[0-9.]+)`,
+		"send", `echo \${ip} ${ip}`,
+	})
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+
+	vars := map[string]string{"ip": "10.0.0.5"}
+
+	if got := string(cfg.steps[0].data(vars)); got != "echo ${HOME} ${ip} ${1} ${ip\r" {
+		t.Errorf("send data = %q, want references to no captured variable kept", got)
+	}
+
+	if got := string(cfg.steps[2].data(vars)); got != "echo ${ip} 10.0.0.5\r" {
+		t.Errorf("send data = %q, want only the unescaped reference filled in", got)
+	}
+}
+
+func TestParseStepsControl(t *testing.T) {
+	cfg, err := parseArgs([]string{"--", "dtr", "off", "rts", "pulse", "100ms", "expect", "ready", "dtr", "on"})
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+
+	want := []step{
+		{kind: stepControl, line: lineDTR, on: false, src: "off"},
+		{kind: stepControl, line: lineRTS, pulse: 100 * time.Millisecond, src: "pulse 100ms"},
+		{kind: stepExpect},
+		{kind: stepControl, line: lineDTR, on: true, src: "on"},
+	}
+
+	if len(cfg.steps) != len(want) {
+		t.Fatalf("len(steps) = %d, want %d", len(cfg.steps), len(want))
+	}
+
+	for idx, st := range cfg.steps {
+		w := want[idx]
+		if st.kind != w.kind || st.line != w.line || st.on != w.on || st.pulse != w.pulse || w.kind == stepControl && st.src != w.src {
+			t.Errorf("step%d = %+v, want %+v", idx, st, w)
+		}
+	}
+
+	if got := cfg.steps[1].verb(); got != "rts" {
+		t.Errorf("verb = %q, want rts", got)
+	}
+
+	if !cfg.writes() {
+		t.Error("writes() = false for a sequence setting control lines")
+	}
+}
+
+func TestParseStepsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"timeout on send", []string{"send:5s", "x"}},
+		{"timeout on expect-not", []string{"expect-not:5s", "x"}},
+		{"bad timeout", []string{"expect:soon", "x"}},
+		{"zero timeout", []string{"expect:0s", "x"}},
+		{"expect-not missing pattern", []string{"expect-not"}},
+		{"control without state", []string{"dtr"}},
+		{"bad control state", []string{"rts", "high"}},
+		{"pulse without duration", []string{"rts", "pulse"}},
+		{"bad pulse duration", []string{"dtr", "pulse", "short"}},
+		{"negative pulse", []string{"dtr", "pulse", "-1s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseArgs(tt.args); err == nil {
+				t.Errorf("parseArgs(%q) = nil error, want error", tt.args)
+			}
+		})
+	}
+}
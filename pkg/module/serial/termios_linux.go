@@ -0,0 +1,13 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import "golang.org/x/sys/unix"
+
+// The requests reading and writing the terminal settings of a serial device.
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
@@ -0,0 +1,707 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// modemProtocol selects the protocol of a file transfer over the serial line.
+type modemProtocol int
+
+const (
+	xmodem modemProtocol = iota + 1
+	ymodem
+)
+
+func (p modemProtocol) String() string {
+	switch p {
+	case xmodem:
+		return "xmodem"
+	case ymodem:
+		return "ymodem"
+	default:
+		return "unknown"
+	}
+}
+
+// Control bytes of the XMODEM and YMODEM protocols.
+const (
+	soh        = 0x01 // start of a 128 byte block
+	stx        = 0x02 // start of a 1024 byte block
+	eot        = 0x04 // end of transmission
+	ack        = 0x06
+	nak        = 0x15
+	can        = 0x18 // cancel; two in a row abort the transfer
+	crcRequest = 'C'  // sent by a receiver instead of nak to ask for CRC-16 blocks
+	sub        = 0x1a // pads the last data block
+)
+
+const (
+	blockSize   = 128
+	blockSize1K = 1024
+	// modemRetries bounds the attempts to transfer a block, and the requests of
+	// a receiver to start a transfer.
+	modemRetries = 10
+	// modemStartTimeout is how long a sender waits for the receiver to ask for
+	// the first block, e.g. for U-Boot's loady to come up.
+	modemStartTimeout = time.Minute
+	// modemBlockTimeout is how long the protocol waits for an answer or a block.
+	modemBlockTimeout = 10 * time.Second
+	// modemRequestInterval is how often a receiver asks the sender to start.
+	modemRequestInterval = 3 * time.Second
+	// modemPurgeTimeout is the silence ending the purge of a damaged block.
+	modemPurgeTimeout = time.Second
+)
+
+// errModemTimeout is returned by a read of the modem that timed out; the
+// protocol retries on it.
+var errModemTimeout = errors.New("timeout")
+
+// errModemCanceled is returned if the other side canceled the transfer.
+var errModemCanceled = errors.New("transfer canceled by the DUT")
+
+// modem runs an XMODEM or YMODEM transfer on a port. XMODEM transfers 128 byte
+// blocks, YMODEM 1024 byte blocks and starts with a block telling the file name
+// and size. Blocks are checked with CRC-16, or with a checksum if a sender's
+// receiver asks for it.
+type modem struct {
+	p     port
+	proto modemProtocol
+	// pending holds the bytes read from the port but not consumed yet. What is
+	// left after the transfer is the DUT's output following it.
+	pending []byte
+	buf     []byte
+}
+
+func newModem(p port, proto modemProtocol) *modem {
+	return &modem{p: p, proto: proto, buf: make([]byte, readChunk)}
+}
+
+// readByte returns the next byte from the port, or errModemTimeout if none
+// arrived before deadline.
+func (m *modem) readByte(ctx context.Context, deadline time.Time) (byte, error) {
+	for len(m.pending) == 0 {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		if time.Now().After(deadline) {
+			return 0, errModemTimeout
+		}
+
+		// A timed-out read returns (0, nil); any error means the port is gone.
+		n, err := m.p.Read(m.buf)
+		m.pending = append(m.pending, m.buf[:n]...)
+
+		if err != nil && n == 0 {
+			return 0, err
+		}
+	}
+
+	b := m.pending[0]
+	m.pending = m.pending[1:]
+
+	return b, nil
+}
+
+// canceled reports whether the cancel byte just read is followed by a second
+// one, which aborts the transfer. A single one is taken as line noise.
+func (m *modem) canceled(ctx context.Context) bool {
+	b, err := m.readByte(ctx, time.Now().Add(modemPurgeTimeout))
+
+	return err == nil && b == can
+}
+
+// abort cancels the transfer on the other side, so it does not wait for a
+// block or an answer anymore. It is best-effort.
+func (m *modem) abort() {
+	_ = writeAll(m.p, []byte{can, can, can})
+}
+
+// send sends the file read from r, announced as file name of size bytes to a
+// YMODEM receiver, or without size if it is negative. The file is read block by
+// block as the transfer goes. It calls progress with the number of bytes sent so
+// far after each block.
+func (m *modem) send(ctx context.Context, name string, r io.Reader, size int64, progress func(sent int)) error {
+	var (
+		header     []byte
+		headerSize int
+		err        error
+	)
+
+	// A header the receiver cannot take fails before the transfer starts.
+	if m.proto == ymodem {
+		header, headerSize, err = ymodemHeader(name, size)
+		if err != nil {
+			return err
+		}
+	}
+
+	useCRC, err := m.awaitStart(ctx)
+	if err != nil {
+		return err
+	}
+
+	n := blockSize
+
+	if m.proto == ymodem {
+		n = blockSize1K
+
+		err = m.sendBlock(ctx, 0, header, headerSize, 0, useCRC)
+		if err != nil {
+			return err
+		}
+
+		// The receiver asks for the data once it took the header.
+		useCRC, err = m.awaitStart(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = m.sendData(ctx, r, n, useCRC, progress)
+	if err != nil {
+		return err
+	}
+
+	err = m.sendEOT(ctx)
+	if err != nil {
+		return err
+	}
+
+	if m.proto == ymodem {
+		// An empty header block ends the batch.
+		useCRC, err = m.awaitStart(ctx)
+		if err != nil {
+			return err
+		}
+
+		return m.sendBlock(ctx, 0, nil, blockSize, 0, useCRC)
+	}
+
+	return nil
+}
+
+// sendData sends the data read from r in blocks of n bytes, numbered from 1.
+// A short tail is sent in a small block.
+func (m *modem) sendData(ctx context.Context, r io.Reader, n int, useCRC bool, progress func(sent int)) error {
+	buf := make([]byte, n)
+	sent := 0
+
+	for seq := byte(1); ; seq++ {
+		read, err := io.ReadFull(r, buf)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		last := errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return fmt.Errorf("reading the file: %w", err)
+		}
+
+		size := n
+		if read <= blockSize {
+			size = blockSize // a short tail fits a small block
+		}
+
+		err = m.sendBlock(ctx, seq, buf[:read], size, sub, useCRC)
+		if err != nil {
+			return err
+		}
+
+		sent += read
+
+		progress(sent)
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// ymodemHeader returns the payload of the YMODEM header block telling the file
+// name and size, if it is not negative, and the size of the block: 128 bytes, or
+// 1024 bytes for a name too long for a small block.
+func ymodemHeader(name string, size int64) ([]byte, int, error) {
+	header := []byte(name + "\x00")
+	if size >= 0 {
+		header = strconv.AppendInt(header, size, 10) //nolint:mnd // decimal
+	}
+
+	switch {
+	case len(header) <= blockSize:
+		return header, blockSize, nil
+	case len(header) <= blockSize1K:
+		return header, blockSize1K, nil
+	default:
+		return nil, 0, fmt.Errorf("file name of %d bytes is too long for a YMODEM header of %d bytes", len(name), blockSize1K)
+	}
+}
+
+// awaitStart waits for the receiver to ask for a block and reports whether it
+// asked for CRC-16 blocks rather than checksum blocks. Other output, e.g. the
+// receiver's banner, is skipped.
+func (m *modem) awaitStart(ctx context.Context) (bool, error) {
+	deadline := time.Now().Add(modemStartTimeout)
+
+	for {
+		b, err := m.readByte(ctx, deadline)
+		if errors.Is(err, errModemTimeout) {
+			return false, fmt.Errorf("receiver did not start within %s", modemStartTimeout)
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case b == crcRequest:
+			return true, nil
+		case b == nak:
+			return false, nil
+		case b == can && m.canceled(ctx):
+			return false, errModemCanceled
+		}
+	}
+}
+
+// sendBlock sends block seq carrying payload, padded to size with pad, and
+// retries until the receiver acknowledged it.
+func (m *modem) sendBlock(ctx context.Context, seq byte, payload []byte, size int, pad byte, useCRC bool) error {
+	start := byte(soh)
+	if size == blockSize1K {
+		start = stx
+	}
+
+	const overhead = 5 // start, seq, its complement and CRC
+
+	block := make([]byte, 0, size+overhead)
+	block = append(block, start, seq, ^seq)
+	block = append(block, payload...)
+	block = append(block, bytes.Repeat([]byte{pad}, size-len(payload))...)
+	block = appendCheck(block, block[3:], useCRC)
+
+	for range modemRetries {
+		err := writeAll(m.p, block)
+		if err != nil {
+			return err
+		}
+
+		answer, err := m.awaitAnswer(ctx)
+		if errors.Is(err, errModemTimeout) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if answer == ack {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("block %d not acknowledged after %d attempts", seq, modemRetries)
+}
+
+// sendEOT ends the transmission. A YMODEM receiver answers the first EOT with
+// a nak, so it is repeated until acknowledged.
+func (m *modem) sendEOT(ctx context.Context) error {
+	for range modemRetries {
+		err := writeAll(m.p, []byte{eot})
+		if err != nil {
+			return err
+		}
+
+		answer, err := m.awaitAnswer(ctx)
+		if errors.Is(err, errModemTimeout) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if answer == ack {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("end of transmission not acknowledged after %d attempts", modemRetries)
+}
+
+// awaitAnswer waits for the receiver to acknowledge or reject a block. Other
+// bytes, like repeated requests to start, are skipped.
+func (m *modem) awaitAnswer(ctx context.Context) (byte, error) {
+	deadline := time.Now().Add(modemBlockTimeout)
+
+	for {
+		b, err := m.readByte(ctx, deadline)
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case b == ack, b == nak:
+			return b, nil
+		case b == can && m.canceled(ctx):
+			return 0, errModemCanceled
+		}
+	}
+}
+
+// receive receives a file and writes its data to w as the blocks arrive. The
+// data of an XMODEM transfer keeps its padding, except for trailing SUB bytes, as
+// XMODEM does not tell the size. It returns the number of bytes written to w and
+// calls progress with the number of bytes received so far after each block.
+//
+//nolint:cyclop,funlen // the receiver's state machine; splitting it would not aid clarity
+func (m *modem) receive(ctx context.Context, w io.Writer, progress func(received int)) (int64, error) {
+	var (
+		data     = &blockWriter{w: w, size: -1}
+		received int
+		expected = byte(1) // the sequence number of the next data block
+		header   = m.proto == ymodem
+		request  = []byte{crcRequest}
+		errCount int
+		eotCount int
+	)
+
+	err := writeAll(m.p, request)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		if errCount >= modemRetries {
+			return data.written, fmt.Errorf("giving up after %d errors", errCount)
+		}
+
+		b, err := m.readByte(ctx, time.Now().Add(modemRequestInterval))
+		if errors.Is(err, errModemTimeout) {
+			// Ask again: to start, or to repeat the block that did not come.
+			errCount++
+
+			err = writeAll(m.p, request)
+			if err != nil {
+				return data.written, err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return data.written, err
+		}
+
+		switch b {
+		case soh, stx:
+			n := blockSize
+			if b == stx {
+				n = blockSize1K
+			}
+
+			seq, payload, ok := m.readBlock(ctx, n)
+
+			switch {
+			case !ok:
+				errCount++
+
+				m.purge(ctx)
+
+				err = writeAll(m.p, []byte{nak})
+			case header && seq == 0:
+				data.size, err = parseYmodemHeader(payload)
+				if err != nil {
+					m.abort()
+
+					return 0, err
+				}
+
+				header, errCount = false, 0
+				err = writeAll(m.p, []byte{ack, crcRequest})
+			case seq == expected:
+				err = data.write(payload)
+				if err != nil {
+					m.abort()
+
+					return data.written, err
+				}
+
+				received += len(payload)
+				expected++
+				errCount = 0
+
+				progress(received)
+
+				// From now on a missing block is asked for again with a nak;
+				// a request to start would be taken for a new transfer.
+				request = []byte{nak}
+				err = writeAll(m.p, []byte{ack})
+			case seq == expected-1:
+				// The acknowledgment of the last block got lost.
+				err = writeAll(m.p, []byte{ack})
+			default:
+				m.abort()
+
+				return data.written, fmt.Errorf("block %d out of sequence, want %d", seq, expected)
+			}
+
+			if err != nil {
+				return data.written, err
+			}
+		case eot:
+			eotCount++
+
+			if m.proto == ymodem && eotCount == 1 {
+				// Make sure the end of transmission was meant.
+				err = writeAll(m.p, []byte{nak})
+				if err != nil {
+					return data.written, err
+				}
+
+				continue
+			}
+
+			err = writeAll(m.p, []byte{ack})
+			if err != nil {
+				return data.written, err
+			}
+
+			if m.proto == ymodem {
+				err = m.endBatch(ctx)
+				if err != nil {
+					return data.written, err
+				}
+			}
+
+			return data.written, nil
+		case can:
+			if m.canceled(ctx) {
+				return data.written, errModemCanceled
+			}
+		}
+	}
+}
+
+// readBlock reads the rest of a block of n data bytes after its start byte and
+// reports whether it arrived intact.
+func (m *modem) readBlock(ctx context.Context, n int) (byte, []byte, bool) {
+	const (
+		seqLen = 2 // sequence number and its complement
+		crcLen = 2
+	)
+
+	deadline := time.Now().Add(modemBlockTimeout)
+	block := make([]byte, 0, seqLen+n+crcLen)
+
+	for len(block) < cap(block) {
+		b, err := m.readByte(ctx, deadline)
+		if err != nil {
+			return 0, nil, false
+		}
+
+		block = append(block, b)
+	}
+
+	seq, payload := block[0], block[seqLen:seqLen+n]
+	if block[1] != ^seq {
+		return 0, nil, false
+	}
+
+	if !bytes.Equal(appendCheck(nil, payload, true), block[seqLen+n:]) {
+		return 0, nil, false
+	}
+
+	return seq, payload, true
+}
+
+// purge skips the rest of a damaged block, until the line is silent.
+func (m *modem) purge(ctx context.Context) {
+	for {
+		_, err := m.readByte(ctx, time.Now().Add(modemPurgeTimeout))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// endBatch receives the empty header block ending a YMODEM batch. A sender
+// with another file is canceled, as a step receives a single file.
+func (m *modem) endBatch(ctx context.Context) error {
+	err := writeAll(m.p, []byte{crcRequest})
+	if err != nil {
+		return err
+	}
+
+	b, err := m.readByte(ctx, time.Now().Add(modemBlockTimeout))
+	if err != nil || b != soh {
+		// The file is complete; a sender not ending the batch properly is
+		// no reason to fail.
+		return nil //nolint:nilerr // see above
+	}
+
+	_, payload, ok := m.readBlock(ctx, blockSize)
+	if ok && payload[0] != 0 {
+		m.abort()
+
+		return errors.New("the DUT sends more than one file")
+	}
+
+	return writeAll(m.p, []byte{ack})
+}
+
+// parseYmodemHeader returns the file size told by a YMODEM header block, or -1
+// if it tells none. The block holds the file name, a NUL, and the size in
+// decimal, optionally followed by a space and more attributes.
+func parseYmodemHeader(payload []byte) (int, error) {
+	name, rest, _ := bytes.Cut(payload, []byte{0})
+	if len(name) == 0 {
+		return 0, errors.New("the DUT sends no file")
+	}
+
+	field, _, _ := bytes.Cut(rest, []byte{0})
+	field, _, _ = bytes.Cut(field, []byte{' '})
+
+	if len(field) == 0 {
+		return -1, nil
+	}
+
+	size, err := strconv.Atoi(string(field))
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid file size %q in the YMODEM header", field)
+	}
+
+	return size, nil
+}
+
+// blockWriter writes the payload of received blocks to w, without the padding of
+// the last block: it cuts the data at size if that is known, otherwise it holds
+// back SUB bytes until data other than SUB follows, so trailing ones are dropped.
+type blockWriter struct {
+	w       io.Writer
+	size    int   // the file size told by a YMODEM header, -1 if unknown
+	written int64 // the number of bytes written to w
+	subs    int   // the number of SUB bytes held back
+}
+
+func (b *blockWriter) write(payload []byte) error {
+	if b.size >= 0 {
+		payload = payload[:min(len(payload), max(b.size-int(b.written), 0))]
+
+		return b.writeAll(payload)
+	}
+
+	data := bytes.TrimRight(payload, string([]byte{sub}))
+	if len(data) == 0 {
+		b.subs += len(payload)
+
+		return nil
+	}
+
+	if b.subs > 0 {
+		err := b.writeAll(bytes.Repeat([]byte{sub}, b.subs))
+		if err != nil {
+			return err
+		}
+	}
+
+	b.subs = len(payload) - len(data)
+
+	return b.writeAll(data)
+}
+
+func (b *blockWriter) writeAll(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	n, err := b.w.Write(p)
+	b.written += int64(n)
+
+	return err
+}
+
+// appendCheck appends the check of a block's payload to block: its CRC-16 in
+// big-endian order, or its 8-bit checksum.
+func appendCheck(block, payload []byte, useCRC bool) []byte {
+	if !useCRC {
+		var sum byte
+		for _, b := range payload {
+			sum += b
+		}
+
+		return append(block, sum)
+	}
+
+	crc := crc16(payload)
+
+	return append(block, byte(crc>>8), byte(crc)) //nolint:mnd // high byte first
+}
+
+// crc16 returns the CRC-16/XMODEM of data: polynomial 0x1021, initial value 0.
+func crc16(data []byte) uint16 {
+	const poly = 0x1021
+
+	var crc uint16
+
+	for _, b := range data {
+		crc ^= uint16(b) << 8 //nolint:mnd // the byte enters the high byte
+
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// sendFile sends the file read from r to the DUT with the XMODEM or YMODEM
+// protocol; name and size, if not negative, are told to a YMODEM receiver. The
+// binary protocol is not passed on to the sink, only the output of the DUT
+// following the transfer.
+func (e *engine) sendFile(ctx context.Context, proto modemProtocol, name string, r io.Reader, size int64, progress func(sent int)) error {
+	e.buf = e.buf[:0]
+
+	m := newModem(e.p, proto)
+
+	err := m.send(ctx, name, r, size, progress)
+	if err != nil {
+		m.abort()
+
+		return err
+	}
+
+	return e.feed(m.pending)
+}
+
+// receiveFile receives a file from the DUT with the XMODEM or YMODEM protocol
+// and writes it to w, returning the number of bytes written. Like sendFile it
+// passes on only the output of the DUT following the transfer.
+func (e *engine) receiveFile(ctx context.Context, proto modemProtocol, w io.Writer, progress func(received int)) (int64, error) {
+	e.buf = e.buf[:0]
+
+	m := newModem(e.p, proto)
+
+	n, err := m.receive(ctx, w, progress)
+	if err != nil {
+		m.abort()
+
+		return n, err
+	}
+
+	return n, e.feed(m.pending)
+}
@@ -0,0 +1,398 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+)
+
+// linePort is one end of an in-memory serial line. Reads time out like a real
+// port, and corrupt, if set, may damage what is written.
+type linePort struct {
+	in  chan []byte
+	out chan []byte
+
+	pending []byte
+
+	mu      sync.Mutex
+	corrupt func(p []byte) []byte
+}
+
+// newLine returns the two ends of a serial line.
+func newLine() (*linePort, *linePort) {
+	a, b := make(chan []byte, 64), make(chan []byte, 64)
+
+	return &linePort{in: a, out: b}, &linePort{in: b, out: a}
+}
+
+func (l *linePort) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		select {
+		case chunk := <-l.in:
+			l.pending = chunk
+		case <-time.After(10 * time.Millisecond):
+			return 0, nil
+		}
+	}
+
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+
+	return n, nil
+}
+
+func (l *linePort) Write(p []byte) (int, error) {
+	chunk := bytes.Clone(p)
+
+	l.mu.Lock()
+	if l.corrupt != nil {
+		chunk = l.corrupt(chunk)
+	}
+	l.mu.Unlock()
+
+	l.out <- chunk
+
+	return len(p), nil
+}
+
+func (l *linePort) ResetInputBuffer() error { return nil }
+func (l *linePort) Close() error            { return nil }
+
+// testImage returns n bytes of data not ending in padding.
+func testImage(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i*7 + 1)
+	}
+
+	return data
+}
+
+// receiveBytes receives a file with m and returns its data.
+func receiveBytes(m *modem) ([]byte, error) {
+	var buf bytes.Buffer
+
+	n, err := m.receive(context.Background(), &buf, func(int) {})
+	if err == nil && n != int64(buf.Len()) {
+		return nil, fmt.Errorf("receive reported %d bytes, wrote %d", n, buf.Len())
+	}
+
+	return buf.Bytes(), err
+}
+
+func TestCRC16(t *testing.T) {
+	// The check value of CRC-16/XMODEM.
+	if got := crc16([]byte("123456789")); got != 0x31c3 {
+		t.Errorf("crc16(123456789) = %#04x, want 0x31c3", got)
+	}
+}
+
+func TestModemTransfer(t *testing.T) {
+	for _, proto := range []modemProtocol{xmodem, ymodem} {
+		for _, size := range []int{0, 1, blockSize, 1000, 3 * blockSize1K, 3*blockSize1K + 5} {
+			t.Run(fmt.Sprintf("%s/%d", proto, size), func(t *testing.T) {
+				sender, receiver := newLine()
+				data := testImage(size)
+
+				sent := make(chan error, 1)
+
+				go func() {
+					sent <- newModem(sender, proto).send(context.Background(), "image.bin", bytes.NewReader(data), int64(len(data)), func(int) {})
+				}()
+
+				got, err := receiveBytes(newModem(receiver, proto))
+				if err != nil {
+					t.Fatalf("receive: %v", err)
+				}
+
+				if err := <-sent; err != nil {
+					t.Fatalf("send: %v", err)
+				}
+
+				if !bytes.Equal(got, data) {
+					t.Errorf("received %d bytes, want the %d bytes sent", len(got), len(data))
+				}
+			})
+		}
+	}
+}
+
+func TestModemStreamsReader(t *testing.T) {
+	sender, receiver := newLine()
+	data := testImage(3*blockSize1K + 200)
+
+	// A reader returning a byte at a time, without telling the size.
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- newModem(sender, ymodem).send(context.Background(), "image.bin", iotest.OneByteReader(bytes.NewReader(data)), -1, func(int) {})
+	}()
+
+	got, err := receiveBytes(newModem(receiver, ymodem))
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	if err := <-sent; err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("received %d bytes, want the %d bytes sent", len(got), len(data))
+	}
+}
+
+func TestModemReaderError(t *testing.T) {
+	sender, receiver := newLine()
+	broken := io.MultiReader(bytes.NewReader(testImage(2*blockSize)), iotest.ErrReader(errors.New("connection lost")))
+
+	received := make(chan error, 1)
+
+	go func() {
+		_, err := receiveBytes(newModem(receiver, xmodem))
+		received <- err
+	}()
+
+	err := newModem(sender, xmodem).send(context.Background(), "", broken, -1, func(int) {})
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("send = %v, want the error of the reader", err)
+	}
+
+	newModem(sender, xmodem).abort()
+
+	if err := <-received; err == nil {
+		t.Error("receive of an aborted transfer succeeded")
+	}
+}
+
+func TestModemRetriesDamagedBlock(t *testing.T) {
+	sender, receiver := newLine()
+	data := testImage(2 * blockSize)
+
+	// Damage the first transmission of block 2 only.
+	damaged := false
+	sender.corrupt = func(p []byte) []byte {
+		if !damaged && len(p) > 3 && p[0] == soh && p[1] == 2 {
+			damaged = true
+			p[10] ^= 0xff
+		}
+
+		return p
+	}
+
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- newModem(sender, xmodem).send(context.Background(), "", bytes.NewReader(data), int64(len(data)), func(int) {})
+	}()
+
+	got, err := receiveBytes(newModem(receiver, xmodem))
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	if err := <-sent; err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if !damaged || !bytes.Equal(got, data) {
+		t.Errorf("damaged=%v, received %d bytes intact=%v; want the block repeated", damaged, len(got), bytes.Equal(got, data))
+	}
+}
+
+func TestModemCanceledByReceiver(t *testing.T) {
+	sender, receiver := newLine()
+
+	_, _ = receiver.Write([]byte{can, can})
+
+	err := newModem(sender, xmodem).send(context.Background(), "", bytes.NewReader(testImage(10)), 10, func(int) {})
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("send = %v, want the cancellation", err)
+	}
+}
+
+func TestModemLongYmodemName(t *testing.T) {
+	sender, receiver := newLine()
+	data := testImage(1000)
+	name := strings.Repeat("n", 300) + ".bin"
+
+	var headerStart byte
+
+	sender.corrupt = func(p []byte) []byte {
+		if headerStart == 0 && len(p) > 3 && p[1] == 0 {
+			headerStart = p[0]
+		}
+
+		return p
+	}
+
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- newModem(sender, ymodem).send(context.Background(), name, bytes.NewReader(data), int64(len(data)), func(int) {})
+	}()
+
+	got, err := receiveBytes(newModem(receiver, ymodem))
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	if err := <-sent; err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if headerStart != stx {
+		t.Errorf("header block started with %#02x, want a 1K block", headerStart)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("received %d bytes, want the %d bytes sent", len(got), len(data))
+	}
+
+	// A name too long even for a 1K block fails before waiting for the receiver.
+	sender, _ = newLine()
+
+	tooLong := strings.Repeat("n", blockSize1K)
+
+	err = newModem(sender, ymodem).send(context.Background(), tooLong, bytes.NewReader(data), int64(len(data)), func(int) {})
+	if err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("send with a name of %d bytes = %v, want an error", blockSize1K, err)
+	}
+}
+
+func TestBlockWriterPadding(t *testing.T) {
+	block := func(data string) []byte {
+		return append([]byte(data), bytes.Repeat([]byte{sub}, blockSize-len(data))...)
+	}
+
+	tests := []struct {
+		name   string
+		size   int
+		blocks [][]byte
+		want   string
+	}{
+		{"trailing padding", -1, [][]byte{block("abc")}, "abc"},
+		{"padding within the data", -1, [][]byte{block("abc"), block("\x1a\x1adef")}, string(block("abc")) + "\x1a\x1adef"},
+		{"padding blocks within the data", -1, [][]byte{block("a"), block(""), block("b")}, string(block("a")) + string(block("")) + "b"},
+		{"size within the last block", 5, [][]byte{block("ab\x1a\x1a\x1a")}, "ab\x1a\x1a\x1a"},
+		{"size shorter than the data", 2, [][]byte{block("abc"), block("def")}, "ab"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+
+		w := &blockWriter{w: &buf, size: tt.size}
+
+		for _, b := range tt.blocks {
+			if err := w.write(b); err != nil {
+				t.Fatalf("%s: write: %v", tt.name, err)
+			}
+		}
+
+		if buf.String() != tt.want || w.written != int64(len(tt.want)) {
+			t.Errorf("%s: wrote %q (%d bytes counted), want %q", tt.name, buf.String(), w.written, tt.want)
+		}
+	}
+}
+
+func TestParseYmodemHeader(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{header: "u-boot.bin\x00524288\x00", want: 524288},
+		{header: "u-boot.bin\x00524288 14542431235 100644\x00", want: 524288},
+		{header: "u-boot.bin\x00\x00", want: -1},
+		{header: "\x00", wantErr: true},
+		{header: "u-boot.bin\x00big\x00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseYmodemHeader([]byte(tt.header))
+		if (err != nil) != tt.wantErr || got != tt.want && !tt.wantErr {
+			t.Errorf("parseYmodemHeader(%q) = %d, %v, want %d, error %v", tt.header, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSerialRunSendYmodem(t *testing.T) {
+	dutSide, agentSide := newLine()
+	image := testImage(5000)
+
+	s := &Serial{
+		Port: "/dev/xmodem-run",
+		Baud: 115200,
+		open: func(_ string, _ lineSettings) (port, error) { return agentSide, nil },
+	}
+
+	received := make(chan []byte, 1)
+
+	go func() {
+		data, err := receiveBytes(newModem(dutSide, ymodem))
+		if err != nil {
+			t.Errorf("DUT receive: %v", err)
+		}
+
+		_, _ = dutSide.Write([]byte("## Total Size = 0x00001388 = 5000 Bytes\n=> "))
+		received <- data
+	}()
+
+	sess := &mock.Session{RequestedFileResponse: bytes.NewReader(image)}
+
+	err := s.Run(context.Background(), sess, "-t", "10s", "--", "send-ymodem", "build/u-boot.bin", "expect", "=> ")
+	if err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+
+	if sess.RequestedFileName != "build/u-boot.bin" {
+		t.Errorf("requested %q, want the step's file", sess.RequestedFileName)
+	}
+
+	if got := <-received; !bytes.Equal(got, image) {
+		t.Errorf("DUT received %d bytes, want the %d bytes of the image", len(got), len(image))
+	}
+}
+
+func TestSerialRunReceiveXmodem(t *testing.T) {
+	dutSide, agentSide := newLine()
+	dump := testImage(700)
+
+	s := &Serial{
+		Port:         "/dev/xmodem-receive",
+		Baud:         115200,
+		drainTimeout: 10 * time.Millisecond,
+		open:         func(_ string, _ lineSettings) (port, error) { return agentSide, nil },
+	}
+
+	go func() {
+		err := newModem(dutSide, xmodem).send(context.Background(), "", bytes.NewReader(dump), int64(len(dump)), func(int) {})
+		if err != nil {
+			t.Errorf("DUT send: %v", err)
+		}
+	}()
+
+	sess := &mock.Session{}
+
+	err := s.Run(context.Background(), sess, "-t", "10s", "--", "receive-xmodem", "dump.bin")
+	if err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+
+	if sess.SentFileName != "dump.bin" || !bytes.Equal(sess.SentFileContent, dump) {
+		t.Errorf("sent %q with %d bytes, want dump.bin with the %d bytes of the dump", sess.SentFileName, len(sess.SentFileContent), len(dump))
+	}
+}
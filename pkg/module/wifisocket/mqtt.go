@@ -0,0 +1,461 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wifisocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+)
+
+// MQTT 3.1.1 control packet types, as the first byte of the fixed header
+// including the flags the packet type requires.
+const (
+	mqttConnect    byte = 0x10
+	mqttConnAck    byte = 0x20
+	mqttPublish    byte = 0x30
+	mqttSubscribe  byte = 0x82
+	mqttSubAck     byte = 0x90
+	mqttDisconnect byte = 0xe0
+)
+
+const (
+	defaultMQTTPort = "1883"
+	mqttKeepAlive   = 60        // Keep alive in seconds; connections last for a single command.
+	mqttMaxPacket   = 256 << 10 // Upper bound of accepted packets, far above any Tasmota response.
+)
+
+// tasmotaMQTT is the transport of Tasmota devices reached via an MQTT broker.
+// A command is published to <commandTopic>/<command>, and the device replies
+// on <statusTopic>/RESULT. Each command uses its own short-lived connection.
+type tasmotaMQTT struct {
+	broker       string // broker is the host:port of the MQTT broker.
+	user         string
+	password     string
+	commandTopic string // commandTopic is the prefix of the command topics, e.g. cmnd/tasmota_1A2B3C.
+	statusTopic  string // statusTopic is the prefix of the status topics, e.g. stat/tasmota_1A2B3C.
+}
+
+// send publishes the Tasmota command cmnd and returns the JSON result the
+// device replies with. Results of other commands are skipped: the response to
+// a command carries the command's name, e.g. {"POWER2":"ON"} for Power2.
+func (t tasmotaMQTT) send(ctx context.Context, cmnd string) ([]byte, error) {
+	name, payload, _ := strings.Cut(cmnd, " ")
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	conn, err := dialMQTT(ctx, t.broker, mqttClientID(), t.user, t.password)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	result := t.statusTopic + "/RESULT"
+
+	err = conn.subscribe(result)
+	if err != nil {
+		return nil, err
+	}
+
+	command := t.commandTopic + "/" + name
+
+	log.FromContext(ctx).Debug("MQTT publish "+command, "payload", payload)
+
+	err = conn.publish(command, []byte(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		topic, body, err := conn.receive()
+		if err != nil {
+			// The connection's deadline is the one of ctx, and may expire first.
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil, fmt.Errorf("no response from the device on %s: %w", result, err)
+			}
+
+			return nil, err
+		}
+
+		if topic == result && hasKey(body, strings.ToUpper(name)) {
+			return body, nil
+		}
+	}
+}
+
+// initMQTT prepares the driver for a Tasmota device reached via an MQTT broker.
+func (w *WifiSocket) initMQTT() error {
+	broker, err := brokerAddr(w.Host)
+	if err != nil {
+		return err
+	}
+
+	commandTopic := w.CommandTopic
+	if commandTopic == "" && w.Topic != "" {
+		commandTopic = "cmnd/" + w.Topic
+	}
+
+	statusTopic := w.StatusTopic
+	if statusTopic == "" && w.Topic != "" {
+		statusTopic = "stat/" + w.Topic
+	}
+
+	if commandTopic == "" || statusTopic == "" {
+		return fmt.Errorf("wifisocket: protocol mqtt needs the topic of the device")
+	}
+
+	w.driver = tasmota{
+		transport: tasmotaMQTT{
+			broker:       broker,
+			user:         w.User,
+			password:     w.Password,
+			commandTopic: strings.TrimRight(commandTopic, "/"),
+			statusTopic:  strings.TrimRight(statusTopic, "/"),
+		},
+		channel: w.Channel,
+	}
+
+	return nil
+}
+
+// brokerAddr returns the host:port of the broker configured as host, which is
+// a host name or IP with an optional port.
+func brokerAddr(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if strings.Contains(host, "/") {
+		return "", fmt.Errorf("wifisocket: invalid MQTT broker %q: must be host[:port] without scheme", host)
+	}
+
+	_, _, err := net.SplitHostPort(host)
+	if err != nil {
+		host = net.JoinHostPort(host, defaultMQTTPort)
+	}
+
+	return host, nil
+}
+
+// hasKey reports whether body is a JSON object with the key.
+func hasKey(body []byte, key string) bool {
+	var fields map[string]json.RawMessage
+
+	err := json.Unmarshal(body, &fields)
+	if err != nil {
+		return false
+	}
+
+	_, ok := fields[key]
+
+	return ok
+}
+
+// mqttClientID returns a random client ID, so concurrent commands do not
+// take over each other's session at the broker.
+func mqttClientID() string {
+	var b [4]byte
+
+	_, _ = rand.Read(b[:])
+
+	return "dutagent" + hex.EncodeToString(b[:])
+}
+
+// mqttConn is a minimal MQTT 3.1.1 client connection. It publishes and
+// subscribes with QoS 0 only, which is all sending a command to a device and
+// awaiting its reply needs.
+type mqttConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	stop func() bool // stop releases the watch of the dial context.
+}
+
+// dialMQTT connects to the broker at addr. The connection is bound to ctx:
+// reads and writes fail after its deadline (or defaultTimeout without one) or
+// once it is canceled.
+func dialMQTT(ctx context.Context, addr, clientID, user, password string) (*mqttConn, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+
+	_ = conn.SetDeadline(deadline)
+
+	c := &mqttConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		stop: context.AfterFunc(ctx, func() { conn.Close() }),
+	}
+
+	err = c.connect(clientID, user, password)
+	if err != nil {
+		c.stop()
+		conn.Close()
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *mqttConn) connect(clientID, user, password string) error {
+	const (
+		flagCleanSession = 0x02
+		flagPassword     = 0x40
+		flagUser         = 0x80
+		protocolLevel    = 4 // MQTT 3.1.1
+	)
+
+	flags := byte(flagCleanSession)
+	payload := mqttString(clientID)
+
+	if user != "" {
+		flags |= flagUser
+		payload = append(payload, mqttString(user)...)
+
+		if password != "" {
+			flags |= flagPassword
+			payload = append(payload, mqttString(password)...)
+		}
+	}
+
+	body := mqttString("MQTT")
+	body = append(body, protocolLevel, flags)
+	body = binary.BigEndian.AppendUint16(body, mqttKeepAlive)
+	body = append(body, payload...)
+
+	err := writeMQTTPacket(c.conn, mqttConnect, body)
+	if err != nil {
+		return err
+	}
+
+	header, ack, err := readMQTTPacket(c.r)
+	if err != nil {
+		return fmt.Errorf("MQTT connect: %w", err)
+	}
+
+	if header != mqttConnAck || len(ack) != 2 {
+		return fmt.Errorf("MQTT connect: unexpected packet %#02x", header)
+	}
+
+	if ack[1] != 0 {
+		return fmt.Errorf("MQTT connect refused by the broker: %s", connAckReason(ack[1]))
+	}
+
+	return nil
+}
+
+// subscribe subscribes to topic with QoS 0 and waits for the broker to
+// acknowledge it, so messages published afterwards are received.
+func (c *mqttConn) subscribe(topic string) error {
+	const packetID = 1
+
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	body = append(body, mqttString(topic)...)
+	body = append(body, 0) // QoS 0
+
+	err := writeMQTTPacket(c.conn, mqttSubscribe, body)
+	if err != nil {
+		return err
+	}
+
+	for {
+		header, ack, err := readMQTTPacket(c.r)
+		if err != nil {
+			return fmt.Errorf("MQTT subscribe: %w", err)
+		}
+
+		if header != mqttSubAck {
+			continue
+		}
+
+		if len(ack) != 3 || binary.BigEndian.Uint16(ack) != packetID {
+			return fmt.Errorf("MQTT subscribe: malformed acknowledgement")
+		}
+
+		if ack[2] == 0x80 {
+			return fmt.Errorf("MQTT subscribe to %s refused by the broker", topic)
+		}
+
+		return nil
+	}
+}
+
+// publish publishes payload to topic with QoS 0.
+func (c *mqttConn) publish(topic string, payload []byte) error {
+	return writeMQTTPacket(c.conn, mqttPublish, append(mqttString(topic), payload...))
+}
+
+// receive returns the next message published to a subscribed topic.
+func (c *mqttConn) receive() (string, []byte, error) {
+	const qosMask = 0x06
+
+	for {
+		header, body, err := readMQTTPacket(c.r)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if header&0xf0 != mqttPublish {
+			continue
+		}
+
+		topic, rest, err := readMQTTString(body)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Messages with QoS 1 or 2 carry a packet identifier before the payload.
+		if header&qosMask != 0 {
+			if len(rest) < 2 {
+				return "", nil, fmt.Errorf("malformed MQTT publish packet")
+			}
+
+			rest = rest[2:]
+		}
+
+		return topic, rest, nil
+	}
+}
+
+// close disconnects from the broker.
+func (c *mqttConn) close() {
+	c.stop()
+
+	_ = writeMQTTPacket(c.conn, mqttDisconnect, nil)
+	_ = c.conn.Close()
+}
+
+// writeMQTTPacket writes a control packet with the fixed header byte and body.
+func writeMQTTPacket(w io.Writer, header byte, body []byte) error {
+	if len(body) > mqttMaxPacket {
+		return fmt.Errorf("MQTT packet too large: %d bytes", len(body))
+	}
+
+	packet := []byte{header}
+
+	// The remaining length is encoded in 7-bit groups, least significant first.
+	length := len(body)
+
+	for {
+		b := byte(length & 0x7f)
+
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+
+		packet = append(packet, b)
+
+		if length == 0 {
+			break
+		}
+	}
+
+	_, err := w.Write(append(packet, body...))
+
+	return err
+}
+
+// readMQTTPacket reads a control packet and returns its fixed header byte and body.
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	const maxLengthBytes = 4
+
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := 0
+
+	for i := range maxLengthBytes + 1 {
+		if i == maxLengthBytes {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		length |= int(b&0x7f) << (7 * i)
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if length > mqttMaxPacket {
+		return 0, nil, fmt.Errorf("MQTT packet too large: %d bytes", length)
+	}
+
+	body := make([]byte, length)
+
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return header, body, nil
+}
+
+// mqttString encodes s as an MQTT UTF-8 string: a 16-bit length and the bytes.
+func mqttString(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...) //nolint:gosec // topics and credentials are short
+}
+
+// readMQTTString decodes an MQTT UTF-8 string at the start of b and returns it
+// with the rest of b.
+func readMQTTString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("malformed MQTT string")
+	}
+
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("malformed MQTT string")
+	}
+
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// connAckReason describes the return code of a refused connection.
+func connAckReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("return code %d", code)
+	}
+}
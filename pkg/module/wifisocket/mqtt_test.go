@@ -0,0 +1,383 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wifisocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+// broker is a small in-process MQTT 3.1.1 broker: it accepts connections with
+// the configured credentials and forwards QoS 0 messages to the subscribers of
+// matching topic filters.
+type broker struct {
+	ln       net.Listener
+	user     string
+	password string
+
+	mu   sync.Mutex
+	subs map[net.Conn][]string
+}
+
+func newBroker(t *testing.T, user, password string) *broker {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	b := &broker{ln: ln, user: user, password: password, subs: make(map[net.Conn][]string)}
+	go b.serve()
+	t.Cleanup(func() { ln.Close() })
+
+	return b
+}
+
+func (b *broker) addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *broker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *broker) handle(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+
+	header, body, err := readMQTTPacket(r)
+	if err != nil || header != mqttConnect {
+		return
+	}
+
+	if !b.authorized(body) {
+		_ = writeMQTTPacket(conn, mqttConnAck, []byte{0, 5})
+
+		return
+	}
+
+	b.mu.Lock()
+	b.subs[conn] = nil
+	_ = writeMQTTPacket(conn, mqttConnAck, []byte{0, 0})
+	b.mu.Unlock()
+
+	for {
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+
+		switch header & 0xf0 {
+		case mqttSubscribe & 0xf0:
+			filter, _, err := readMQTTString(body[2:])
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.subs[conn] = append(b.subs[conn], filter)
+			_ = writeMQTTPacket(conn, mqttSubAck, append(body[:2:2], 0))
+			b.mu.Unlock()
+		case mqttPublish:
+			topic, _, err := readMQTTString(body)
+			if err != nil {
+				return
+			}
+			b.forward(topic, body)
+		case mqttDisconnect:
+			return
+		}
+	}
+}
+
+// authorized checks the credentials of a CONNECT packet body.
+func (b *broker) authorized(body []byte) bool {
+	const flagsOffset = 7 // after the protocol name "MQTT" and level
+
+	if b.user == "" {
+		return true
+	}
+
+	flags := body[flagsOffset]
+	rest := body[flagsOffset+3:]
+
+	_, rest, _ = readMQTTString(rest) // client ID
+	if flags&0x80 == 0 || flags&0x40 == 0 {
+		return false
+	}
+
+	user, rest, _ := readMQTTString(rest)
+	password, _, _ := readMQTTString(rest)
+
+	return user == b.user && password == b.password
+}
+
+func (b *broker) forward(topic string, publish []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for conn, filters := range b.subs {
+		for _, filter := range filters {
+			if topicMatch(filter, topic) {
+				_ = writeMQTTPacket(conn, mqttPublish, publish)
+
+				break
+			}
+		}
+	}
+}
+
+// topicMatch matches a topic against a filter with the wildcards + and #.
+func topicMatch(filter, topic string) bool {
+	f, t := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) || (level != "+" && level != t[i]) {
+			return false
+		}
+	}
+
+	return len(f) == len(t)
+}
+
+// runDevice connects a simulated Tasmota device with the topic to the broker.
+// It answers Power commands on cmnd/<topic>/ with a result on
+// stat/<topic>/RESULT, preceded by the result of an unrelated command.
+func runDevice(t *testing.T, addr, user, password, topic string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	conn, err := dialMQTT(ctx, addr, "tasmota_"+topic, user, password)
+	if err != nil {
+		t.Fatalf("device connect: %v", err)
+	}
+
+	if err := conn.subscribe("cmnd/" + topic + "/+"); err != nil {
+		t.Fatalf("device subscribe: %v", err)
+	}
+
+	go func() {
+		defer conn.close()
+
+		power := make(map[string]string)
+		for {
+			cmdTopic, payload, err := conn.receive()
+			if err != nil {
+				return
+			}
+
+			name := strings.ToUpper(cmdTopic[strings.LastIndex(cmdTopic, "/")+1:])
+			if !strings.HasPrefix(name, "POWER") {
+				continue
+			}
+
+			switch strings.ToUpper(string(payload)) {
+			case "ON":
+				power[name] = "ON"
+			case "OFF":
+				power[name] = "OFF"
+			case "TOGGLE":
+				if power[name] == "ON" {
+					power[name] = "OFF"
+				} else {
+					power[name] = "ON"
+				}
+			}
+			if power[name] == "" {
+				power[name] = "OFF"
+			}
+
+			_ = conn.publish("stat/"+topic+"/RESULT", []byte(`{"Dimmer":50}`))
+			_ = conn.publish("stat/"+topic+"/RESULT", []byte(fmt.Sprintf(`{%q:%q}`, name, power[name])))
+		}
+	}()
+}
+
+func TestMQTTPacket(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384} {
+		var buf bytes.Buffer
+		body := bytes.Repeat([]byte{0xa5}, n)
+		if err := writeMQTTPacket(&buf, mqttPublish, body); err != nil {
+			t.Fatalf("writeMQTTPacket(%d bytes) failed: %v", n, err)
+		}
+
+		wantLen := map[int]int{0: 1, 127: 1, 128: 2, 16383: 2, 16384: 3}[n]
+		if got := buf.Len() - 1 - n; got != wantLen {
+			t.Errorf("remaining length of %d bytes encoded in %d bytes, want %d", n, got, wantLen)
+		}
+
+		header, got, err := readMQTTPacket(bufio.NewReader(&buf))
+		if err != nil || header != mqttPublish || !bytes.Equal(got, body) {
+			t.Errorf("readMQTTPacket() = %#02x, %d bytes, %v, want %d bytes", header, len(got), err, n)
+		}
+	}
+
+	malformed := []byte{mqttPublish, 0xff, 0xff, 0xff, 0xff, 0x01}
+	if _, _, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(malformed))); err == nil {
+		t.Fatalf("expected error for a malformed remaining length")
+	}
+
+	s, rest, err := readMQTTString(append(mqttString("stat/plug/RESULT"), 'x'))
+	if err != nil || s != "stat/plug/RESULT" || string(rest) != "x" {
+		t.Fatalf("readMQTTString() = %q %q %v", s, rest, err)
+	}
+	if _, _, err := readMQTTString(binary.BigEndian.AppendUint16(nil, 5)); err == nil {
+		t.Fatalf("expected error for a truncated string")
+	}
+}
+
+func TestBrokerAddr(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{host: "192.168.1.10", want: "192.168.1.10:1883"},
+		{host: "mqtt.lab:8883", want: "mqtt.lab:8883"},
+		{host: "::1", want: "[::1]:1883"},
+		{host: "mqtt://mqtt.lab", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := brokerAddr(tt.host)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("brokerAddr(%q) = %q %v, want %q", tt.host, got, err, tt.want)
+		}
+	}
+}
+
+func TestTasmotaMQTT(t *testing.T) {
+	b := newBroker(t, "dut", "secret")
+	runDevice(t, b.addr(), "dut", "secret", "plug1")
+
+	ctx := context.Background()
+	w := &WifiSocket{Host: b.addr(), Protocol: "mqtt", Topic: "plug1", User: "dut", Password: "secret", Channel: 2}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if err := w.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn failed: %v", err)
+	}
+	if st, err := w.PowerState(ctx); err != nil || st != module.PowerOn {
+		t.Fatalf("PowerState after PowerOn = %v %v, want on", st, err)
+	}
+
+	s := &mock.Session{}
+	if err := w.Run(ctx, s, "toggle"); err != nil {
+		t.Fatalf("Run(toggle) failed: %v", err)
+	}
+	if s.PrintText != "WiFi socket channel 2 set to 'off'\n" {
+		t.Fatalf("unexpected output: %q", s.PrintText)
+	}
+	if err := w.Run(ctx, s, "status"); err != nil {
+		t.Fatalf("Run(status) failed: %v", err)
+	}
+	if s.PrintText != "WiFi socket channel 2 state: off\n" {
+		t.Fatalf("unexpected output: %q", s.PrintText)
+	}
+}
+
+func TestTasmotaMQTTCustomTopics(t *testing.T) {
+	b := newBroker(t, "", "")
+	runDevice(t, b.addr(), "", "", "plug2")
+
+	ctx := context.Background()
+	w := &WifiSocket{Host: b.addr(), Protocol: "mqtt", CommandTopic: "cmnd/plug2/", StatusTopic: "stat/plug2"}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if err := w.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn failed: %v", err)
+	}
+	if st, err := w.PowerState(ctx); err != nil || st != module.PowerOn {
+		t.Fatalf("PowerState after PowerOn = %v %v, want on", st, err)
+	}
+}
+
+func TestTasmotaMQTTErrors(t *testing.T) {
+	b := newBroker(t, "dut", "secret")
+
+	w := &WifiSocket{Host: b.addr(), Protocol: "mqtt", Topic: "plug1", User: "dut", Password: "wrong"}
+	if err := w.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	err := w.PowerOn(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("PowerOn error = %v, want the broker to refuse the connection", err)
+	}
+
+	// No device is subscribed, so the command is never answered.
+	w = &WifiSocket{Host: b.addr(), Protocol: "mqtt", Topic: "plug1", User: "dut", Password: "secret"}
+	if err := w.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = w.PowerState(ctx)
+	if err == nil || !strings.Contains(err.Error(), "no response") {
+		t.Fatalf("PowerState error = %v, want a missing response", err)
+	}
+}
+
+func TestInitFirmwareAndProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		w       WifiSocket
+		wantErr bool
+	}{
+		{name: "default tasmota over http", w: WifiSocket{Host: "192.168.1.50"}},
+		{name: "shelly gen1", w: WifiSocket{Host: "192.168.1.50", Firmware: "shelly-gen1"}},
+		{name: "shelly gen2", w: WifiSocket{Host: "192.168.1.50", Firmware: "Shelly-Gen2"}},
+		{name: "tasmota over mqtt", w: WifiSocket{Host: "192.168.1.5", Protocol: "mqtt", Topic: "plug"}},
+		{name: "unknown firmware", w: WifiSocket{Host: "192.168.1.50", Firmware: "espurna"}, wantErr: true},
+		{name: "unknown protocol", w: WifiSocket{Host: "192.168.1.50", Protocol: "coap"}, wantErr: true},
+		{name: "mqtt without topic", w: WifiSocket{Host: "192.168.1.5", Protocol: "mqtt"}, wantErr: true},
+		{name: "mqtt with shelly", w: WifiSocket{Host: "192.168.1.5", Protocol: "mqtt", Topic: "plug", Firmware: "shelly-gen1"}, wantErr: true},
+		{name: "mqtt broker with scheme", w: WifiSocket{Host: "mqtt://192.168.1.5", Protocol: "mqtt", Topic: "plug"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.w.Init(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.w.driver == nil {
+				t.Fatalf("Init() did not set up a driver")
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wifisocket
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BlindspotSoftware/dutctl/internal/log"
+)
+
+// shellyGen2User is the fixed user name of Shelly Gen2 devices with
+// authentication enabled.
+const shellyGen2User = "admin"
+
+// shellyGen1 drives Shelly Gen1 devices via their HTTP API: a GET of
+// /relay/<id> reports the relay, adding ?turn=on|off|toggle switches it first.
+// The API uses HTTP basic auth.
+type shellyGen1 struct {
+	get   func(ctx context.Context, u string) (*http.Response, error)
+	base  string // base URL of the device
+	relay int    // 0-based relay ID
+}
+
+func (s shellyGen1) setPower(ctx context.Context, op string) (string, error) {
+	switch op {
+	case on, off, toggle:
+	default:
+		return "", fmt.Errorf("invalid operation: %s", op)
+	}
+
+	return s.relayState(ctx, "?turn="+op)
+}
+
+func (s shellyGen1) state(ctx context.Context) (string, error) {
+	return s.relayState(ctx, "")
+}
+
+// relayState requests /relay/<id> with the query and returns the reported state.
+func (s shellyGen1) relayState(ctx context.Context, query string) (string, error) {
+	resp, err := s.get(ctx, fmt.Sprintf("%s/relay/%d%s", s.base, s.relay, query))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var relay struct {
+		IsOn *bool `json:"ison"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&relay)
+	if err != nil {
+		return "", fmt.Errorf("invalid JSON response: %v", err)
+	}
+
+	if relay.IsOn == nil {
+		return "", fmt.Errorf("unexpected device response: no state of relay %d", s.relay)
+	}
+
+	return stateOf(*relay.IsOn), nil
+}
+
+// shellyGen2 drives Shelly Gen2 and later devices via JSON-RPC over HTTP, a
+// POST of the request frame to /rpc. With authentication enabled, the devices
+// answer with an HTTP digest challenge (SHA-256) for the user admin.
+type shellyGen2 struct {
+	client   *http.Client
+	rpcURL   string
+	user     string
+	password string
+	id       int // 0-based switch ID
+}
+
+func (s shellyGen2) setPower(ctx context.Context, op string) (string, error) {
+	switch op {
+	case on, off:
+		err := s.call(ctx, "Switch.Set", map[string]any{"id": s.id, "on": op == on}, nil)
+		if err != nil {
+			return "", err
+		}
+
+		return op, nil
+	case toggle:
+		var result struct {
+			WasOn *bool `json:"was_on"`
+		}
+
+		err := s.call(ctx, "Switch.Toggle", map[string]any{"id": s.id}, &result)
+		if err != nil {
+			return "", err
+		}
+
+		if result.WasOn == nil {
+			return "", nil
+		}
+
+		return stateOf(!*result.WasOn), nil
+	default:
+		return "", fmt.Errorf("invalid operation: %s", op)
+	}
+}
+
+func (s shellyGen2) state(ctx context.Context) (string, error) {
+	var result struct {
+		Output *bool `json:"output"`
+	}
+
+	err := s.call(ctx, "Switch.GetStatus", map[string]any{"id": s.id}, &result)
+	if err != nil {
+		return "", err
+	}
+
+	if result.Output == nil {
+		return "", fmt.Errorf("unexpected device response: no state of switch %d", s.id)
+	}
+
+	return stateOf(*result.Output), nil
+}
+
+// rpcRequest is a JSON-RPC request frame of the Shelly Gen2 API.
+type rpcRequest struct {
+	ID     int    `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC response frame of the Shelly Gen2 API.
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call invokes the RPC method with params and decodes its result into result,
+// unless result is nil.
+func (s shellyGen2) call(ctx context.Context, method string, params, result any) error {
+	frame, err := json.Marshal(rpcRequest{ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	body, status, err := s.post(ctx, frame)
+	if err != nil {
+		return err
+	}
+
+	var response rpcResponse
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		if status != http.StatusOK {
+			return fmt.Errorf("device returned %d %s: %s", status, http.StatusText(status), string(body))
+		}
+
+		return fmt.Errorf("invalid JSON response: %v", err)
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("%s failed: %s (code %d)", method, response.Error.Message, response.Error.Code)
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("device returned %d %s: %s", status, http.StatusText(status), string(body))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(response.Result, result)
+	if err != nil {
+		return fmt.Errorf("invalid %s result: %v", method, err)
+	}
+
+	return nil
+}
+
+// post sends the request frame to the device and returns the response body and
+// status. A digest challenge is answered once if a password is configured.
+func (s shellyGen2) post(ctx context.Context, frame []byte) ([]byte, int, error) {
+	log.FromContext(ctx).Debug("POST " + s.rpcURL)
+
+	resp, err := s.do(ctx, frame, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && s.password != "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		u, err := url.Parse(s.rpcURL)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		authorization, err := digestAuthorization(challenge, http.MethodPost, u.RequestURI(), s.user, s.password, newCnonce())
+		if err != nil {
+			return nil, 0, err
+		}
+
+		resp, err = s.do(ctx, frame, authorization)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+func (s shellyGen2) do(ctx context.Context, frame []byte, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.rpcURL, bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return s.client.Do(req)
+}
+
+// authParam matches a parameter of an HTTP authentication challenge, with a
+// quoted or a token value.
+var authParam = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^\s,]*))`)
+
+// digestAuthorization answers the HTTP digest challenge (RFC 7616) of a Shelly
+// Gen2 device with the value of the Authorization header. Only the SHA-256
+// algorithm and qop auth, which the devices use, are supported.
+func digestAuthorization(challenge, method, uri, user, password, cnonce string) (string, error) {
+	scheme, rest, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return "", fmt.Errorf("authentication failed: unsupported challenge %q", challenge)
+	}
+
+	params := make(map[string]string)
+	for _, m := range authParam.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(m[1])] = m[2] + m[3]
+	}
+
+	if !strings.EqualFold(params["algorithm"], "SHA-256") {
+		return "", fmt.Errorf("authentication failed: unsupported digest algorithm %q", params["algorithm"])
+	}
+
+	qops := strings.Split(params["qop"], ",")
+	for i := range qops {
+		qops[i] = strings.TrimSpace(qops[i])
+	}
+
+	if !slices.Contains(qops, "auth") {
+		return "", fmt.Errorf("authentication failed: unsupported digest qop %q", params["qop"])
+	}
+
+	const nc = "00000001"
+
+	hash := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+
+		return hex.EncodeToString(sum[:])
+	}
+
+	realm, nonce := params["realm"], params["nonce"]
+	ha1 := hash(user + ":" + realm + ":" + password)
+	ha2 := hash(method + ":" + uri)
+	response := hash(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+
+	authorization := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=SHA-256, `+
+		`response="%s", qop=auth, nc=%s, cnonce="%s"`, user, realm, nonce, uri, response, nc, cnonce)
+
+	if opaque, ok := params["opaque"]; ok {
+		authorization += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+
+	return authorization, nil
+}
+
+// newCnonce returns a random client nonce for digest authentication.
+func newCnonce() string {
+	var b [8]byte
+
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+// stateOf returns the state of an output that is on or not.
+func stateOf(isOn bool) string {
+	if isOn {
+		return on
+	}
+
+	return off
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 Blindspot Software
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wifisocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BlindspotSoftware/dutctl/internal/test/mock"
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
+)
+
+func TestShellyGen1(t *testing.T) {
+	isOn := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+		if r.URL.Path != "/relay/1" {
+			http.Error(w, "unknown relay", http.StatusNotFound)
+
+			return
+		}
+		switch r.URL.Query().Get("turn") {
+		case "on":
+			isOn = true
+		case "off":
+			isOn = false
+		case "toggle":
+			isOn = !isOn
+		}
+		fmt.Fprintf(w, `{"ison":%t,"has_timer":false,"timer_duration":0,"source":"http"}`, isOn)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	w := &WifiSocket{Host: srv.URL, Firmware: "shelly-gen1", User: "admin", Password: "secret", Channel: 2}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if err := w.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn failed: %v", err)
+	}
+	if st, err := w.PowerState(ctx); err != nil || st != module.PowerOn {
+		t.Fatalf("PowerState after PowerOn = %v %v, want on", st, err)
+	}
+
+	s := &mock.Session{}
+	if err := w.Run(ctx, s, "toggle"); err != nil {
+		t.Fatalf("Run(toggle) failed: %v", err)
+	}
+	if s.PrintText != "WiFi socket channel 2 set to 'off'\n" {
+		t.Fatalf("unexpected output: %q", s.PrintText)
+	}
+
+	w = &WifiSocket{Host: srv.URL, Firmware: "shelly-gen1", Channel: 2}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := w.PowerOn(ctx); err == nil {
+		t.Fatalf("expected error without credentials")
+	}
+}
+
+// TestDigestAuthorization pins the digest computation against the SHA-256
+// example of RFC 7616, section 3.9.1.
+func TestDigestAuthorization(t *testing.T) {
+	challenge := `Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, ` +
+		`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+
+	got, err := digestAuthorization(challenge, http.MethodGet, "/dir/index.html", "Mufasa", "Circle of Life",
+		"f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ")
+	if err != nil {
+		t.Fatalf("digestAuthorization failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`response="753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"`,
+		`username="Mufasa"`,
+		`qop=auth,`,
+		`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("authorization %q lacks %s", got, want)
+		}
+	}
+
+	for _, challenge := range []string{
+		`Basic realm="shelly"`,
+		`Digest realm="shelly", nonce="1", qop="auth", algorithm=MD5`,
+		`Digest realm="shelly", nonce="1", algorithm=SHA-256`,
+	} {
+		if _, err := digestAuthorization(challenge, http.MethodPost, "/rpc", "admin", "secret", "c"); err == nil {
+			t.Errorf("expected error for challenge %s", challenge)
+		}
+	}
+}
+
+// newShellyGen2Server returns a device serving JSON-RPC on /rpc with switch 0,
+// requiring digest authentication if password is set.
+func newShellyGen2Server(t *testing.T, password string) *httptest.Server {
+	t.Helper()
+
+	const challenge = `Digest qop="auth", realm="shellyplus1-a8032ab12345", nonce="60dc59c6", algorithm=SHA-256`
+
+	output := false
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rpc" {
+			http.Error(w, "not found", http.StatusNotFound)
+
+			return
+		}
+
+		if password != "" {
+			got := r.Header.Get("Authorization")
+			cnonce := ""
+			for _, m := range authParam.FindAllStringSubmatch(got, -1) {
+				if m[1] == "cnonce" {
+					cnonce = m[2]
+				}
+			}
+			want, _ := digestAuthorization(challenge, http.MethodPost, "/rpc", shellyGen2User, password, cnonce)
+			if got == "" || got != want {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, `{"code":401,"message":"unauthorized"}`, http.StatusUnauthorized)
+
+				return
+			}
+		}
+
+		var req struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				ID int   `json:"id"`
+				On *bool `json:"on"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+
+		if req.Params.ID != 0 {
+			fmt.Fprintf(w, `{"id":%d,"src":"shellyplus1","error":{"code":-105,"message":"Argument 'id', value %d not found!"}}`,
+				req.ID, req.Params.ID)
+
+			return
+		}
+
+		var result string
+		switch req.Method {
+		case "Switch.Set":
+			result = fmt.Sprintf(`{"was_on":%t}`, output)
+			output = *req.Params.On
+		case "Switch.Toggle":
+			result = fmt.Sprintf(`{"was_on":%t}`, output)
+			output = !output
+		case "Switch.GetStatus":
+			result = fmt.Sprintf(`{"id":0,"source":"HTTP_in","output":%t,"apower":0.0}`, output)
+		default:
+			fmt.Fprintf(w, `{"id":%d,"src":"shellyplus1","error":{"code":404,"message":"No handler for %s"}}`, req.ID, req.Method)
+
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d,"src":"shellyplus1","result":%s}`, req.ID, result)
+	}))
+}
+
+func TestShellyGen2(t *testing.T) {
+	srv := newShellyGen2Server(t, "secret")
+	defer srv.Close()
+
+	ctx := context.Background()
+	w := &WifiSocket{Host: srv.URL, Firmware: "shelly-gen2", Password: "secret"}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if err := w.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn failed: %v", err)
+	}
+	if st, err := w.PowerState(ctx); err != nil || st != module.PowerOn {
+		t.Fatalf("PowerState after PowerOn = %v %v, want on", st, err)
+	}
+
+	s := &mock.Session{}
+	if err := w.Run(ctx, s, "toggle"); err != nil {
+		t.Fatalf("Run(toggle) failed: %v", err)
+	}
+	if s.PrintText != "WiFi socket channel 1 set to 'off'\n" {
+		t.Fatalf("unexpected output: %q", s.PrintText)
+	}
+	if err := w.Run(ctx, s, "status"); err != nil {
+		t.Fatalf("Run(status) failed: %v", err)
+	}
+	if s.PrintText != "WiFi socket channel 1 state: off\n" {
+		t.Fatalf("unexpected output: %q", s.PrintText)
+	}
+}
+
+func TestShellyGen2Errors(t *testing.T) {
+	srv := newShellyGen2Server(t, "secret")
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	w := &WifiSocket{Host: srv.URL, Firmware: "shelly-gen2", Password: "wrong"}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := w.PowerOn(ctx); err == nil {
+		t.Fatalf("expected error for a wrong password")
+	}
+
+	w = &WifiSocket{Host: srv.URL, Firmware: "shelly-gen2", Password: "secret", Channel: 3}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	_, err := w.PowerState(ctx)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("PowerState error = %v, want the RPC error of the device", err)
+	}
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package wifisocket provides a dutagent module that allows power control of a WiFi socket via HTTP requests.
+// Package wifisocket provides a dutagent module that allows power control of a WiFi socket via HTTP or MQTT.
 package wifisocket
 
 import (
@@ -27,15 +27,23 @@ func init() {
 	})
 }
 
-// WifiSocket controls Tasmota-based WiFi sockets.
+// WifiSocket controls WiFi sockets running Tasmota or Shelly firmware.
 type WifiSocket struct {
-	Host     string // base URL of the device, e.g. http://192.168.1.50
-	User     string // optional HTTP basic auth user
-	Password string // optional HTTP basic auth password
+	Host     string // base URL of the device, e.g. http://192.168.1.50; the broker host[:port] with MQTT
+	User     string // optional HTTP basic auth user, or the MQTT broker user
+	Password string // optional HTTP basic auth password, or the MQTT broker password
 	Channel  int    // channel to control (1 = default single-outlet)
 
+	Firmware string // firmware of the socket: tasmota (default), shelly-gen1 or shelly-gen2
+	Protocol string // protocol to reach the socket: http (default) or mqtt (Tasmota only)
+
+	Topic        string // MQTT topic of the Tasmota device, e.g. tasmota_1A2B3C
+	CommandTopic string // optional MQTT command topic prefix, defaults to cmnd/<topic>
+	StatusTopic  string // optional MQTT status topic prefix, defaults to stat/<topic>
+
 	client     *http.Client
 	controlURL *url.URL
+	driver     driver
 }
 
 const (
@@ -46,10 +54,31 @@ const (
 	status         = "status"
 )
 
+// Supported firmwares and protocols.
+const (
+	firmwareTasmota    = "tasmota"
+	firmwareShellyGen1 = "shelly-gen1"
+	firmwareShellyGen2 = "shelly-gen2"
+	protocolHTTP       = "http"
+	protocolMQTT       = "mqtt"
+)
+
+// driver speaks the API of a socket firmware.
+type driver interface {
+	// setPower applies the operation on, off or toggle to the channel. It returns
+	// the resulting state, or an empty state if the device did not confirm it.
+	setPower(ctx context.Context, op string) (string, error)
+	// state queries the state of the channel: on or off.
+	state(ctx context.Context) (string, error)
+}
+
+// Ensure implementing the PowerController interface.
+var _ module.PowerController = &WifiSocket{}
+
 func (w *WifiSocket) Help() string {
 	help := strings.Builder{}
 
-	help.WriteString("WiFi Socket (Tasmota) Module\n\n")
+	help.WriteString("WiFi Socket (Tasmota, Shelly) Module\n\n")
 	help.WriteString("Usage:\n  wifisocket [on|off|toggle|status]\n\n")
 	help.WriteString("Commands:\n")
 	help.WriteString("  on      - Power on the socket\n")
@@ -57,18 +86,24 @@ func (w *WifiSocket) Help() string {
 	help.WriteString("  toggle  - Toggle the socket\n")
 	help.WriteString("  status  - Query the current state\n\n")
 	help.WriteString("Configuration:\n")
-	help.WriteString("  host     - base URL of the socket (http://IP[:PORT])\n")
-	help.WriteString("  user     - optional HTTP basic auth user\n")
-	help.WriteString("  password - optional HTTP basic auth password\n")
-	help.WriteString("  channel  - channel number (1 for single-outlet devices)\n")
+	help.WriteString("  host         - base URL of the socket (http://IP[:PORT]), or the MQTT broker (IP[:PORT])\n")
+	help.WriteString("  user         - optional HTTP auth or MQTT broker user\n")
+	help.WriteString("  password     - optional HTTP auth or MQTT broker password\n")
+	help.WriteString("  channel      - channel number (1 for single-outlet devices)\n")
+	help.WriteString("  firmware     - tasmota (default), shelly-gen1 or shelly-gen2\n")
+	help.WriteString("  protocol     - http (default) or mqtt (Tasmota only)\n")
+	help.WriteString("  topic        - MQTT topic of the Tasmota device\n")
+	help.WriteString("  commandtopic - optional MQTT command topic prefix (default cmnd/<topic>)\n")
+	help.WriteString("  statustopic  - optional MQTT status topic prefix (default stat/<topic>)\n")
 
 	return help.String()
 }
 
-// Init validates the configuration and prepares the HTTP client. It returns an
-// error when Host is empty. A missing or non-positive Channel defaults to 1.
-// Init normalizes Host in place, prepending "http://" when no scheme is given,
-// and derives the Tasmota control URL from it.
+// Init validates the configuration and prepares the driver of the firmware. It
+// returns an error when Host is empty or the firmware or protocol is unknown. A
+// missing or non-positive Channel defaults to 1. With HTTP, Init normalizes Host
+// in place, prepending "http://" when no scheme is given, and derives the
+// Tasmota control URL from it.
 func (w *WifiSocket) Init(ctx context.Context) error {
 	if w.Host == "" {
 		return fmt.Errorf("wifisocket: host must be configured")
@@ -80,6 +115,30 @@ func (w *WifiSocket) Init(ctx context.Context) error {
 		log.FromContext(ctx).Debug("no channel configured, using default 1")
 	}
 
+	firmware := strings.ToLower(w.Firmware)
+	if firmware == "" {
+		firmware = firmwareTasmota
+	}
+
+	switch firmware {
+	case firmwareTasmota, firmwareShellyGen1, firmwareShellyGen2:
+	default:
+		return fmt.Errorf("wifisocket: unknown firmware %q, supported: %s, %s, %s",
+			w.Firmware, firmwareTasmota, firmwareShellyGen1, firmwareShellyGen2)
+	}
+
+	switch strings.ToLower(w.Protocol) {
+	case "", protocolHTTP:
+	case protocolMQTT:
+		if firmware != firmwareTasmota {
+			return fmt.Errorf("wifisocket: protocol mqtt is supported with firmware %s only", firmwareTasmota)
+		}
+
+		return w.initMQTT()
+	default:
+		return fmt.Errorf("wifisocket: unknown protocol %q, supported: %s, %s", w.Protocol, protocolHTTP, protocolMQTT)
+	}
+
 	w.client = &http.Client{Timeout: defaultTimeout}
 
 	// normalize host: trim spaces and add scheme if missing (allow bare IPs like 192.168.8.71)
@@ -96,6 +155,22 @@ func (w *WifiSocket) Init(ctx context.Context) error {
 	w.controlURL = u
 	w.Host = host
 
+	base := strings.TrimRight(host, "/")
+
+	switch firmware {
+	case firmwareShellyGen1:
+		w.driver = shellyGen1{get: w.doRequest, base: base, relay: w.Channel - 1}
+	case firmwareShellyGen2:
+		user := w.User
+		if user == "" && w.Password != "" {
+			user = shellyGen2User
+		}
+
+		w.driver = shellyGen2{client: w.client, rpcURL: base + "/rpc", user: user, password: w.Password, id: w.Channel - 1}
+	default:
+		w.driver = tasmota{transport: w, channel: w.Channel}
+	}
+
 	return nil
 }
 
@@ -108,7 +183,7 @@ func (w *WifiSocket) Deinit(_ context.Context) error {
 // nil; only failures talking to the device return a non-nil error. Init must
 // have run successfully first, otherwise Run returns an error.
 func (w *WifiSocket) Run(ctx context.Context, s module.Session, args ...string) error {
-	if w.client == nil {
+	if w.driver == nil {
 		return fmt.Errorf("wifisocket client not initialized")
 	}
 
@@ -166,74 +241,142 @@ func (w *WifiSocket) doRequest(ctx context.Context, u string) (*http.Response, e
 }
 
 func (w *WifiSocket) setPower(ctx context.Context, s module.Session, state string) error {
-	opCmd, err := mapStateToTasmotaCmd(state, w.Channel)
+	confirmed, err := w.driver.setPower(ctx, state)
 	if err != nil {
 		return err
 	}
 
-	// Copy controlURL so setting query params does not mutate the shared value.
-	u := *w.controlURL
-	q := u.Query()
-	q.Set("cmnd", opCmd)
-	u.RawQuery = q.Encode()
-
-	resp, err := w.doRequest(ctx, u.String())
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
 	log.FromContext(ctx).Info(fmt.Sprintf("socket channel %d power %s", w.Channel, state))
 
-	body, _ := io.ReadAll(resp.Body)
-
-	confirmed, err := w.parseState(body)
-	if err == nil {
+	if confirmed != "" {
 		s.Printf("WiFi socket channel %d set to '%s'\n", w.Channel, confirmed)
 
 		return nil
 	}
 
-	// fallback to reporting requested state when parsing fails
+	// fallback to reporting requested state when the device did not confirm it
 	s.Printf("WiFi socket channel %d set to '%s'\n", w.Channel, state)
 
 	return nil
 }
 
 func (w *WifiSocket) status(ctx context.Context, s module.Session) error {
-	cmdName := "Power"
-	if w.Channel > 1 {
-		cmdName = fmt.Sprintf("Power%d", w.Channel)
+	state, err := w.driver.state(ctx)
+	if err != nil {
+		return err
 	}
 
-	u := *w.controlURL
-	q := u.Query()
-	q.Set("cmnd", cmdName)
-	u.RawQuery = q.Encode()
+	s.Printf("WiFi socket channel %d state: %s\n", w.Channel, state)
 
-	resp, err := w.doRequest(ctx, u.String())
-	if err != nil {
-		return err
+	return nil
+}
+
+// PowerOn switches the channel on.
+func (w *WifiSocket) PowerOn(ctx context.Context) error {
+	return w.switchPower(ctx, on)
+}
+
+// PowerOff switches the channel off.
+func (w *WifiSocket) PowerOff(ctx context.Context) error {
+	return w.switchPower(ctx, off)
+}
+
+// PowerState reports the state of the channel as read from the socket.
+func (w *WifiSocket) PowerState(ctx context.Context) (module.PowerState, error) {
+	if w.driver == nil {
+		return module.PowerUnknown, fmt.Errorf("wifisocket client not initialized")
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	state, err := w.driver.state(ctx)
 	if err != nil {
-		return err
+		return module.PowerUnknown, err
 	}
 
-	state, err := w.parseState(body)
+	if state == on {
+		return module.PowerOn, nil
+	}
+
+	return module.PowerOff, nil
+}
+
+// switchPower sets the channel to state on behalf of the power controller.
+func (w *WifiSocket) switchPower(ctx context.Context, state string) error {
+	if w.driver == nil {
+		return fmt.Errorf("wifisocket client not initialized")
+	}
+
+	_, err := w.driver.setPower(ctx, state)
 	if err != nil {
 		return err
 	}
 
-	s.Printf("WiFi socket channel %d state: %s\n", w.Channel, state)
+	log.FromContext(ctx).Info(fmt.Sprintf("socket channel %d power %s", w.Channel, state))
 
 	return nil
 }
 
-// parseState returns "on" or "off" when it can be determined.
-func (w *WifiSocket) parseState(body []byte) (string, error) {
+// tasmotaTransport delivers a Tasmota command, e.g. "Power2 ON", and returns
+// the JSON response of the device. The WifiSocket itself is the HTTP transport.
+type tasmotaTransport interface {
+	send(ctx context.Context, cmnd string) ([]byte, error)
+}
+
+// tasmota drives Tasmota firmware. Its commands and responses are the same
+// over HTTP and MQTT; only the transport differs.
+type tasmota struct {
+	transport tasmotaTransport
+	channel   int
+}
+
+func (t tasmota) setPower(ctx context.Context, op string) (string, error) {
+	opCmd, err := mapStateToTasmotaCmd(op, t.channel)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := t.transport.send(ctx, opCmd)
+	if err != nil {
+		return "", err
+	}
+
+	confirmed, err := parseState(body, t.channel)
+	if err != nil {
+		return "", nil //nolint:nilerr // the command was sent, only its confirmation is unreadable
+	}
+
+	return confirmed, nil
+}
+
+// state asks the socket for the state of the channel.
+func (t tasmota) state(ctx context.Context) (string, error) {
+	body, err := t.transport.send(ctx, powerCmdName(t.channel))
+	if err != nil {
+		return "", err
+	}
+
+	return parseState(body, t.channel)
+}
+
+// send issues the Tasmota command cmnd via HTTP and returns the response body.
+func (w *WifiSocket) send(ctx context.Context, cmnd string) ([]byte, error) {
+	// Copy controlURL so setting query params does not mutate the shared value.
+	u := *w.controlURL
+	q := u.Query()
+	q.Set("cmnd", cmnd)
+	u.RawQuery = q.Encode()
+
+	resp, err := w.doRequest(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// parseState returns "on" or "off" when it can be determined from the Tasmota
+// response body for channel.
+func parseState(body []byte, channel int) (string, error) {
 	trim := strings.TrimSpace(string(body))
 	if trim == "" {
 		return "", fmt.Errorf("empty response")
@@ -248,7 +391,7 @@ func (w *WifiSocket) parseState(body []byte) (string, error) {
 		return "", fmt.Errorf("invalid JSON response: %v", err)
 	}
 
-	keys := []string{"POWER", fmt.Sprintf("POWER%d", w.Channel)}
+	keys := []string{"POWER", fmt.Sprintf("POWER%d", channel)}
 
 	for _, k := range keys {
 		if v, ok := dataMap[k]; ok {
@@ -271,12 +414,18 @@ func (w *WifiSocket) parseState(body []byte) (string, error) {
 	return "", fmt.Errorf("unexpected device response: %s", trim)
 }
 
-func mapStateToTasmotaCmd(state string, channel int) (string, error) {
-	cmdName := "Power"
+// powerCmdName returns the Tasmota power command addressing channel.
+func powerCmdName(channel int) string {
 	if channel > 1 {
-		cmdName = fmt.Sprintf("Power%d", channel)
+		return fmt.Sprintf("Power%d", channel)
 	}
 
+	return "Power"
+}
+
+func mapStateToTasmotaCmd(state string, channel int) (string, error) {
+	cmdName := powerCmdName(channel)
+
 	switch strings.ToLower(state) {
 	case on:
 		return fmt.Sprintf("%s ON", cmdName), nil
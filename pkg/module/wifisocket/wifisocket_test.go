@@ -6,8 +6,13 @@ package wifisocket
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/BlindspotSoftware/dutctl/pkg/module"
 )
 
 func TestMapStateToTasmotaCmd(t *testing.T) {
@@ -29,24 +34,22 @@ func TestMapStateToTasmotaCmd(t *testing.T) {
 }
 
 func TestParseStateJSONHTMLPlain(t *testing.T) {
-	w := &WifiSocket{Channel: 1}
-
 	// JSON with trailing junk
 	body := []byte("{\"POWER\":\"OFF\"}%")
-	st, err := w.parseState(body)
+	st, err := parseState(body, 1)
 	if err != nil || st != off {
 		t.Fatalf("json parse failed: %v %v", st, err)
 	}
 
 	// HTML-like fragment should be rejected (parseState expects JSON)
 	body = []byte("<div>...<span>\">ON\"</span>...</div>")
-	if _, err = w.parseState(body); err == nil {
+	if _, err = parseState(body, 1); err == nil {
 		t.Fatalf("expected error for non-JSON html, got nil")
 	}
 
 	// plain text should be rejected
 	body = []byte("off")
-	if _, err = w.parseState(body); err == nil {
+	if _, err = parseState(body, 1); err == nil {
 		t.Fatalf("expected error for non-JSON plain text, got nil")
 	}
 }
@@ -74,3 +77,42 @@ func TestHelpAndDeinit(t *testing.T) {
 		t.Fatalf("Deinit returned error: %v", err)
 	}
 }
+
+func TestPowerController(t *testing.T) {
+	power := "OFF"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cmnd") {
+		case "Power2 ON":
+			power = "ON"
+		case "Power2 OFF":
+			power = "OFF"
+		case "Power2":
+		default:
+			http.Error(w, "unexpected command", http.StatusBadRequest)
+
+			return
+		}
+		fmt.Fprintf(w, `{"POWER2":%q}`, power)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	w := &WifiSocket{Host: srv.URL, Channel: 2}
+	if err := w.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if err := w.PowerOn(ctx); err != nil {
+		t.Fatalf("PowerOn failed: %v", err)
+	}
+	if st, err := w.PowerState(ctx); err != nil || st != module.PowerOn {
+		t.Fatalf("PowerState after PowerOn = %v %v, want on", st, err)
+	}
+
+	if err := w.PowerOff(ctx); err != nil {
+		t.Fatalf("PowerOff failed: %v", err)
+	}
+	if st, err := w.PowerState(ctx); err != nil || st != module.PowerOff {
+		t.Fatalf("PowerState after PowerOff = %v %v, want off", st, err)
+	}
+}
@@ -21,6 +21,277 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// SignalKind identifies a Signal.
+type SignalKind int32
+
+const (
+	SignalKind_SIGNAL_KIND_UNSPECIFIED SignalKind = 0
+	SignalKind_SIGNAL_KIND_INTERRUPT   SignalKind = 1 // Interrupt what is running (Ctrl-C).
+	SignalKind_SIGNAL_KIND_QUIT        SignalKind = 2 // Quit what is running (Ctrl-\).
+	SignalKind_SIGNAL_KIND_EOF         SignalKind = 3 // End of the client's input (Ctrl-D).
+)
+
+// Enum value maps for SignalKind.
+var (
+	SignalKind_name = map[int32]string{
+		0: "SIGNAL_KIND_UNSPECIFIED",
+		1: "SIGNAL_KIND_INTERRUPT",
+		2: "SIGNAL_KIND_QUIT",
+		3: "SIGNAL_KIND_EOF",
+	}
+	SignalKind_value = map[string]int32{
+		"SIGNAL_KIND_UNSPECIFIED": 0,
+		"SIGNAL_KIND_INTERRUPT":   1,
+		"SIGNAL_KIND_QUIT":        2,
+		"SIGNAL_KIND_EOF":         3,
+	}
+)
+
+func (x SignalKind) Enum() *SignalKind {
+	p := new(SignalKind)
+	*p = x
+	return p
+}
+
+func (x SignalKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SignalKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_dutctl_v1_dutctl_proto_enumTypes[0].Descriptor()
+}
+
+func (SignalKind) Type() protoreflect.EnumType {
+	return &file_dutctl_v1_dutctl_proto_enumTypes[0]
+}
+
+func (x SignalKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SignalKind.Descriptor instead.
+func (SignalKind) EnumDescriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{0}
+}
+
+// ModuleOutcome is the outcome of a single module of a command.
+type ModuleOutcome int32
+
+const (
+	ModuleOutcome_MODULE_OUTCOME_UNSPECIFIED ModuleOutcome = 0
+	ModuleOutcome_MODULE_OUTCOME_SUCCEEDED   ModuleOutcome = 1
+	ModuleOutcome_MODULE_OUTCOME_FAILED      ModuleOutcome = 2
+	ModuleOutcome_MODULE_OUTCOME_CANCELED    ModuleOutcome = 3 // The module returned because the execution was aborted.
+	ModuleOutcome_MODULE_OUTCOME_SKIPPED     ModuleOutcome = 4 // The module did not run because the execution ended before.
+)
+
+// Enum value maps for ModuleOutcome.
+var (
+	ModuleOutcome_name = map[int32]string{
+		0: "MODULE_OUTCOME_UNSPECIFIED",
+		1: "MODULE_OUTCOME_SUCCEEDED",
+		2: "MODULE_OUTCOME_FAILED",
+		3: "MODULE_OUTCOME_CANCELED",
+		4: "MODULE_OUTCOME_SKIPPED",
+	}
+	ModuleOutcome_value = map[string]int32{
+		"MODULE_OUTCOME_UNSPECIFIED": 0,
+		"MODULE_OUTCOME_SUCCEEDED":   1,
+		"MODULE_OUTCOME_FAILED":      2,
+		"MODULE_OUTCOME_CANCELED":    3,
+		"MODULE_OUTCOME_SKIPPED":     4,
+	}
+)
+
+func (x ModuleOutcome) Enum() *ModuleOutcome {
+	p := new(ModuleOutcome)
+	*p = x
+	return p
+}
+
+func (x ModuleOutcome) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ModuleOutcome) Descriptor() protoreflect.EnumDescriptor {
+	return file_dutctl_v1_dutctl_proto_enumTypes[1].Descriptor()
+}
+
+func (ModuleOutcome) Type() protoreflect.EnumType {
+	return &file_dutctl_v1_dutctl_proto_enumTypes[1]
+}
+
+func (x ModuleOutcome) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ModuleOutcome.Descriptor instead.
+func (ModuleOutcome) EnumDescriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{1}
+}
+
+// JobState is the state of a job.
+type JobState int32
+
+const (
+	JobState_JOB_STATE_UNSPECIFIED JobState = 0
+	JobState_JOB_STATE_RUNNING     JobState = 1
+	JobState_JOB_STATE_SUCCEEDED   JobState = 2
+	JobState_JOB_STATE_FAILED      JobState = 3
+	JobState_JOB_STATE_CANCELED    JobState = 4
+)
+
+// Enum value maps for JobState.
+var (
+	JobState_name = map[int32]string{
+		0: "JOB_STATE_UNSPECIFIED",
+		1: "JOB_STATE_RUNNING",
+		2: "JOB_STATE_SUCCEEDED",
+		3: "JOB_STATE_FAILED",
+		4: "JOB_STATE_CANCELED",
+	}
+	JobState_value = map[string]int32{
+		"JOB_STATE_UNSPECIFIED": 0,
+		"JOB_STATE_RUNNING":     1,
+		"JOB_STATE_SUCCEEDED":   2,
+		"JOB_STATE_FAILED":      3,
+		"JOB_STATE_CANCELED":    4,
+	}
+)
+
+func (x JobState) Enum() *JobState {
+	p := new(JobState)
+	*p = x
+	return p
+}
+
+func (x JobState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobState) Descriptor() protoreflect.EnumDescriptor {
+	return file_dutctl_v1_dutctl_proto_enumTypes[2].Descriptor()
+}
+
+func (JobState) Type() protoreflect.EnumType {
+	return &file_dutctl_v1_dutctl_proto_enumTypes[2]
+}
+
+func (x JobState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobState.Descriptor instead.
+func (JobState) EnumDescriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{2}
+}
+
+// PowerAction is the action of a PowerRequest.
+type PowerAction int32
+
+const (
+	PowerAction_POWER_ACTION_UNSPECIFIED PowerAction = 0
+	PowerAction_POWER_ACTION_ON          PowerAction = 1
+	PowerAction_POWER_ACTION_OFF         PowerAction = 2
+	PowerAction_POWER_ACTION_CYCLE       PowerAction = 3 // Switch off and, after the off-time, on again.
+	PowerAction_POWER_ACTION_STATUS      PowerAction = 4 // Only report the power state.
+)
+
+// Enum value maps for PowerAction.
+var (
+	PowerAction_name = map[int32]string{
+		0: "POWER_ACTION_UNSPECIFIED",
+		1: "POWER_ACTION_ON",
+		2: "POWER_ACTION_OFF",
+		3: "POWER_ACTION_CYCLE",
+		4: "POWER_ACTION_STATUS",
+	}
+	PowerAction_value = map[string]int32{
+		"POWER_ACTION_UNSPECIFIED": 0,
+		"POWER_ACTION_ON":          1,
+		"POWER_ACTION_OFF":         2,
+		"POWER_ACTION_CYCLE":       3,
+		"POWER_ACTION_STATUS":      4,
+	}
+)
+
+func (x PowerAction) Enum() *PowerAction {
+	p := new(PowerAction)
+	*p = x
+	return p
+}
+
+func (x PowerAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PowerAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_dutctl_v1_dutctl_proto_enumTypes[3].Descriptor()
+}
+
+func (PowerAction) Type() protoreflect.EnumType {
+	return &file_dutctl_v1_dutctl_proto_enumTypes[3]
+}
+
+func (x PowerAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PowerAction.Descriptor instead.
+func (PowerAction) EnumDescriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{3}
+}
+
+// PowerState is the power state of a device.
+type PowerState int32
+
+const (
+	PowerState_POWER_STATE_UNSPECIFIED PowerState = 0 // The power controller cannot tell the state.
+	PowerState_POWER_STATE_ON          PowerState = 1
+	PowerState_POWER_STATE_OFF         PowerState = 2
+)
+
+// Enum value maps for PowerState.
+var (
+	PowerState_name = map[int32]string{
+		0: "POWER_STATE_UNSPECIFIED",
+		1: "POWER_STATE_ON",
+		2: "POWER_STATE_OFF",
+	}
+	PowerState_value = map[string]int32{
+		"POWER_STATE_UNSPECIFIED": 0,
+		"POWER_STATE_ON":          1,
+		"POWER_STATE_OFF":         2,
+	}
+)
+
+func (x PowerState) Enum() *PowerState {
+	p := new(PowerState)
+	*p = x
+	return p
+}
+
+func (x PowerState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PowerState) Descriptor() protoreflect.EnumDescriptor {
+	return file_dutctl_v1_dutctl_proto_enumTypes[4].Descriptor()
+}
+
+func (PowerState) Type() protoreflect.EnumType {
+	return &file_dutctl_v1_dutctl_proto_enumTypes[4]
+}
+
+func (x PowerState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PowerState.Descriptor instead.
+func (PowerState) EnumDescriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{4}
+}
+
 // ListRequest is sent by the client to request a list of devices connected to the agent.
 type ListRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -427,6 +698,8 @@ type RunRequest struct {
 	//	*RunRequest_Command
 	//	*RunRequest_Console
 	//	*RunRequest_File
+	//	*RunRequest_Signal
+	//	*RunRequest_Cancel
 	Msg           isRunRequest_Msg `protobuf_oneof:"msg"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -496,6 +769,24 @@ func (x *RunRequest) GetFile() *File {
 	return nil
 }
 
+func (x *RunRequest) GetSignal() *Signal {
+	if x != nil {
+		if x, ok := x.Msg.(*RunRequest_Signal); ok {
+			return x.Signal
+		}
+	}
+	return nil
+}
+
+func (x *RunRequest) GetCancel() *Cancel {
+	if x != nil {
+		if x, ok := x.Msg.(*RunRequest_Cancel); ok {
+			return x.Cancel
+		}
+	}
+	return nil
+}
+
 type isRunRequest_Msg interface {
 	isRunRequest_Msg()
 }
@@ -512,14 +803,28 @@ type RunRequest_File struct {
 	File *File `protobuf:"bytes,3,opt,name=file,proto3,oneof"`
 }
 
+type RunRequest_Signal struct {
+	Signal *Signal `protobuf:"bytes,4,opt,name=signal,proto3,oneof"`
+}
+
+type RunRequest_Cancel struct {
+	Cancel *Cancel `protobuf:"bytes,5,opt,name=cancel,proto3,oneof"`
+}
+
 func (*RunRequest_Command) isRunRequest_Msg() {}
 
 func (*RunRequest_Console) isRunRequest_Msg() {}
 
 func (*RunRequest_File) isRunRequest_Msg() {}
 
+func (*RunRequest_Signal) isRunRequest_Msg() {}
+
+func (*RunRequest_Cancel) isRunRequest_Msg() {}
+
 // RunResponse is sent by the agent in response to a RunRequest and can either contain
 // just the output of the command (Print), or trigger further interaction with the client.
+// The last RunResponse of a command execution is a RunResult, unless the execution was
+// aborted before its modules completed or failed.
 type RunResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Msg:
@@ -528,6 +833,8 @@ type RunResponse struct {
 	//	*RunResponse_Console
 	//	*RunResponse_FileRequest
 	//	*RunResponse_File
+	//	*RunResponse_Result
+	//	*RunResponse_Job
 	Msg           isRunResponse_Msg `protobuf_oneof:"msg"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -606,6 +913,24 @@ func (x *RunResponse) GetFile() *File {
 	return nil
 }
 
+func (x *RunResponse) GetResult() *RunResult {
+	if x != nil {
+		if x, ok := x.Msg.(*RunResponse_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *RunResponse) GetJob() *Job {
+	if x != nil {
+		if x, ok := x.Msg.(*RunResponse_Job); ok {
+			return x.Job
+		}
+	}
+	return nil
+}
+
 type isRunResponse_Msg interface {
 	isRunResponse_Msg()
 }
@@ -626,6 +951,14 @@ type RunResponse_File struct {
 	File *File `protobuf:"bytes,4,opt,name=file,proto3,oneof"`
 }
 
+type RunResponse_Result struct {
+	Result *RunResult `protobuf:"bytes,5,opt,name=result,proto3,oneof"`
+}
+
+type RunResponse_Job struct {
+	Job *Job `protobuf:"bytes,6,opt,name=job,proto3,oneof"`
+}
+
 func (*RunResponse_Print) isRunResponse_Msg() {}
 
 func (*RunResponse_Console) isRunResponse_Msg() {}
@@ -634,12 +967,20 @@ func (*RunResponse_FileRequest) isRunResponse_Msg() {}
 
 func (*RunResponse_File) isRunResponse_Msg() {}
 
+func (*RunResponse_Result) isRunResponse_Msg() {}
+
+func (*RunResponse_Job) isRunResponse_Msg() {}
+
 // Command is used by the client to start a command execution on a device.
+// A detached command runs as a job on the agent: it keeps running when the client
+// disconnects and can be reattached to with the Attach RPC. The agent answers it
+// with a Job message before any other RunResponse.
 type Command struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
 	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
 	Args          []string               `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+	Detach        bool                   `protobuf:"varint,4,opt,name=detach,proto3" json:"detach,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -695,6 +1036,58 @@ func (x *Command) GetArgs() []string {
 	return nil
 }
 
+func (x *Command) GetDetach() bool {
+	if x != nil {
+		return x.Detach
+	}
+	return false
+}
+
+// Job is used by the agent to announce the job a detached command runs as.
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
 // Print is used by the agent to send the output of a command execution to the client.
 type Print struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -705,7 +1098,7 @@ type Print struct {
 
 func (x *Print) Reset() {
 	*x = Print{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[11]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -717,7 +1110,7 @@ func (x *Print) String() string {
 func (*Print) ProtoMessage() {}
 
 func (x *Print) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[11]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -730,7 +1123,7 @@ func (x *Print) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Print.ProtoReflect.Descriptor instead.
 func (*Print) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{11}
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *Print) GetText() []byte {
@@ -742,6 +1135,8 @@ func (x *Print) GetText() []byte {
 
 // Console is used by the client and agent during an interactive command execution.
 // An interactive session can only be started by the agent by sending the first Console message.
+// The agent opens it with an empty stdout message as soon as a module asks for its console,
+// so the client can prepare its terminal, e.g. switch it to raw mode, before any output.
 type Console struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Data:
@@ -749,6 +1144,7 @@ type Console struct {
 	//	*Console_Stdin
 	//	*Console_Stdout
 	//	*Console_Stderr
+	//	*Console_WindowSize
 	Data          isConsole_Data `protobuf_oneof:"data"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -756,7 +1152,7 @@ type Console struct {
 
 func (x *Console) Reset() {
 	*x = Console{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[12]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -768,7 +1164,7 @@ func (x *Console) String() string {
 func (*Console) ProtoMessage() {}
 
 func (x *Console) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[12]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -781,7 +1177,7 @@ func (x *Console) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Console.ProtoReflect.Descriptor instead.
 func (*Console) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{12}
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *Console) GetData() isConsole_Data {
@@ -818,6 +1214,15 @@ func (x *Console) GetStderr() []byte {
 	return nil
 }
 
+func (x *Console) GetWindowSize() *WindowSize {
+	if x != nil {
+		if x, ok := x.Data.(*Console_WindowSize); ok {
+			return x.WindowSize
+		}
+	}
+	return nil
+}
+
 type isConsole_Data interface {
 	isConsole_Data()
 }
@@ -834,35 +1239,44 @@ type Console_Stderr struct {
 	Stderr []byte `protobuf:"bytes,3,opt,name=stderr,proto3,oneof"`
 }
 
+type Console_WindowSize struct {
+	// window_size is sent by the client once the session is opened and whenever its
+	// terminal is resized. Clients without a terminal do not send it.
+	WindowSize *WindowSize `protobuf:"bytes,4,opt,name=window_size,json=windowSize,proto3,oneof"`
+}
+
 func (*Console_Stdin) isConsole_Data() {}
 
 func (*Console_Stdout) isConsole_Data() {}
 
 func (*Console_Stderr) isConsole_Data() {}
 
-// FileRequest is used by the agent to request a file from the client.
-type FileRequest struct {
+func (*Console_WindowSize) isConsole_Data() {}
+
+// WindowSize is the size of the client's terminal in character cells.
+type WindowSize struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Rows          uint32                 `protobuf:"varint,1,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols          uint32                 `protobuf:"varint,2,opt,name=cols,proto3" json:"cols,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileRequest) Reset() {
-	*x = FileRequest{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[13]
+func (x *WindowSize) Reset() {
+	*x = WindowSize{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileRequest) String() string {
+func (x *WindowSize) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileRequest) ProtoMessage() {}
+func (*WindowSize) ProtoMessage() {}
 
-func (x *FileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[13]
+func (x *WindowSize) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -873,42 +1287,51 @@ func (x *FileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
-func (*FileRequest) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use WindowSize.ProtoReflect.Descriptor instead.
+func (*WindowSize) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *FileRequest) GetPath() string {
+func (x *WindowSize) GetRows() uint32 {
 	if x != nil {
-		return x.Path
+		return x.Rows
 	}
-	return ""
+	return 0
 }
 
-// File is used by the client and the agent to transfer a file.
-type File struct {
+func (x *WindowSize) GetCols() uint32 {
+	if x != nil {
+		return x.Cols
+	}
+	return 0
+}
+
+// Signal is used by the client to pass a signal on to the running command, typically a
+// control key typed at the client's terminal. The modules of the command decide how to
+// handle it, e.g. an interactive console forwards it to the device. A signal no module
+// handles has no effect.
+type Signal struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Kind          SignalKind             `protobuf:"varint,1,opt,name=kind,proto3,enum=dutctl.v1.SignalKind" json:"kind,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *File) Reset() {
-	*x = File{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[14]
+func (x *Signal) Reset() {
+	*x = Signal{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *File) String() string {
+func (x *Signal) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*File) ProtoMessage() {}
+func (*Signal) ProtoMessage() {}
 
-func (x *File) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[14]
+func (x *Signal) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -919,28 +1342,333 @@ func (x *File) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use File.ProtoReflect.Descriptor instead.
-func (*File) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use Signal.ProtoReflect.Descriptor instead.
+func (*Signal) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *File) GetPath() string {
+func (x *Signal) GetKind() SignalKind {
 	if x != nil {
-		return x.Path
+		return x.Kind
 	}
-	return ""
+	return SignalKind_SIGNAL_KIND_UNSPECIFIED
 }
 
-func (x *File) GetContent() []byte {
-	if x != nil {
-		return x.Content
-	}
-	return nil
+// Cancel is used by the client to abort the command execution. Unlike closing the
+// stream, the agent cancels only the context of the modules, so they can tear down,
+// their output still reaches the client and the execution concludes with a RunResult.
+type Cancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-// LockRequest is sent by the client to acquire or extend a lock on a device.
-// The lock owner identity is carried in an HTTP header, not in this message.
-//
+func (x *Cancel) Reset() {
+	*x = Cancel{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cancel) ProtoMessage() {}
+
+func (x *Cancel) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cancel.ProtoReflect.Descriptor instead.
+func (*Cancel) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{16}
+}
+
+// FileRequest is used by the agent to request a file from the client.
+type FileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileRequest) Reset() {
+	*x = FileRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileRequest) ProtoMessage() {}
+
+func (x *FileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
+func (*FileRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+// File is used by the client and the agent to transfer a file.
+// A file is transferred as a sequence of File messages (chunks) carrying the same path,
+// so neither side has to hold the whole file in memory. Chunks are sent in order, each
+// one starting at the offset where the previous one ended. The final chunk leaves more
+// unset, so a file sent as a single message is a valid transfer of one chunk.
+type File struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"` // Chunk data, starting at offset.
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`  // Byte offset of content within the file.
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`      // Total size of the file in bytes, 0 if unknown to the sender.
+	More          bool                   `protobuf:"varint,5,opt,name=more,proto3" json:"more,omitempty"`      // Further chunks follow; unset on the final chunk.
+	Sha256        []byte                 `protobuf:"bytes,6,opt,name=sha256,proto3" json:"sha256,omitempty"`   // SHA-256 digest of the whole file, only set on the final chunk. Optional.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *File) Reset() {
+	*x = File{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *File) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*File) ProtoMessage() {}
+
+func (x *File) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use File.ProtoReflect.Descriptor instead.
+func (*File) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *File) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *File) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *File) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *File) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *File) GetMore() bool {
+	if x != nil {
+		return x.More
+	}
+	return false
+}
+
+func (x *File) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+// RunResult is sent by the agent as the last message of a command execution. It reports
+// the outcome of every module of the command, in execution order.
+type RunResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Modules       []*ModuleResult        `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunResult) Reset() {
+	*x = RunResult{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunResult) ProtoMessage() {}
+
+func (x *RunResult) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunResult.ProtoReflect.Descriptor instead.
+func (*RunResult) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RunResult) GetModules() []*ModuleResult {
+	if x != nil {
+		return x.Modules
+	}
+	return nil
+}
+
+// ModuleResult describes the execution of a single module of a command.
+type ModuleResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Index         int32                  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"` // 1-based position of the module within the command.
+	Outcome       ModuleOutcome          `protobuf:"varint,3,opt,name=outcome,proto3,enum=dutctl.v1.ModuleOutcome" json:"outcome,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                              // Error returned by the module, set for a failed or canceled module.
+	StartedAt     int64                  `protobuf:"varint,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`    // Unix milliseconds, 0 if the module did not run.
+	FinishedAt    int64                  `protobuf:"varint,6,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"` // Unix milliseconds, 0 if the module did not run.
+	DurationMs    int64                  `protobuf:"varint,7,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModuleResult) Reset() {
+	*x = ModuleResult{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModuleResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModuleResult) ProtoMessage() {}
+
+func (x *ModuleResult) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModuleResult.ProtoReflect.Descriptor instead.
+func (*ModuleResult) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ModuleResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ModuleResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ModuleResult) GetOutcome() ModuleOutcome {
+	if x != nil {
+		return x.Outcome
+	}
+	return ModuleOutcome_MODULE_OUTCOME_UNSPECIFIED
+}
+
+func (x *ModuleResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ModuleResult) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *ModuleResult) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+func (x *ModuleResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+// LockRequest is sent by the client to acquire or extend a lock on a device.
+// The lock owner identity is carried in an HTTP header, not in this message.
+//
 // Locks are advisory: any caller may force-release another's lock
 // (UnlockRequest.force), so callers are expected to reserve a device only as
 // long as needed and to release it when done.
@@ -952,21 +1680,306 @@ type LockRequest struct {
 	sizeCache       protoimpl.SizeCache
 }
 
-func (x *LockRequest) Reset() {
-	*x = LockRequest{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[15]
+func (x *LockRequest) Reset() {
+	*x = LockRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockRequest) ProtoMessage() {}
+
+func (x *LockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockRequest.ProtoReflect.Descriptor instead.
+func (*LockRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LockRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *LockRequest) GetDurationSeconds() int64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+// LockResponse is sent by the agent in response to a successful LockRequest.
+type LockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Lock          *LockState             `protobuf:"bytes,2,opt,name=lock,proto3" json:"lock,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockResponse) Reset() {
+	*x = LockResponse{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockResponse) ProtoMessage() {}
+
+func (x *LockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockResponse.ProtoReflect.Descriptor instead.
+func (*LockResponse) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LockResponse) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *LockResponse) GetLock() *LockState {
+	if x != nil {
+		return x.Lock
+	}
+	return nil
+}
+
+// UnlockRequest is sent by the client to release a lock on a device.
+// The lock owner identity is carried in an HTTP header, not in this message.
+type UnlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Force         bool                   `protobuf:"varint,2,opt,name=force,proto3" json:"force,omitempty"` // Release the lock regardless of owner.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockRequest) Reset() {
+	*x = UnlockRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockRequest) ProtoMessage() {}
+
+func (x *UnlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockRequest.ProtoReflect.Descriptor instead.
+func (*UnlockRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UnlockRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *UnlockRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+// UnlockResponse is sent by the agent in response to a successful UnlockRequest.
+type UnlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockResponse) Reset() {
+	*x = UnlockResponse{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockResponse) ProtoMessage() {}
+
+func (x *UnlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockResponse.ProtoReflect.Descriptor instead.
+func (*UnlockResponse) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{24}
+}
+
+// AttachRequest is sent by the client to attach to a job started by a detached
+// command. The first AttachRequest must name the job; the following ones carry the
+// RunRequest messages the client would send to an ordinary command execution.
+// Only the user who started the job may attach to it.
+type AttachRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*AttachRequest_Job
+	//	*AttachRequest_Run
+	Msg           isAttachRequest_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AttachRequest) Reset() {
+	*x = AttachRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttachRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttachRequest) ProtoMessage() {}
+
+func (x *AttachRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttachRequest.ProtoReflect.Descriptor instead.
+func (*AttachRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *AttachRequest) GetMsg() isAttachRequest_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *AttachRequest) GetJob() string {
+	if x != nil {
+		if x, ok := x.Msg.(*AttachRequest_Job); ok {
+			return x.Job
+		}
+	}
+	return ""
+}
+
+func (x *AttachRequest) GetRun() *RunRequest {
+	if x != nil {
+		if x, ok := x.Msg.(*AttachRequest_Run); ok {
+			return x.Run
+		}
+	}
+	return nil
+}
+
+type isAttachRequest_Msg interface {
+	isAttachRequest_Msg()
+}
+
+type AttachRequest_Job struct {
+	Job string `protobuf:"bytes,1,opt,name=job,proto3,oneof"`
+}
+
+type AttachRequest_Run struct {
+	Run *RunRequest `protobuf:"bytes,2,opt,name=run,proto3,oneof"`
+}
+
+func (*AttachRequest_Job) isAttachRequest_Msg() {}
+
+func (*AttachRequest_Run) isAttachRequest_Msg() {}
+
+// AttachResponse is sent by the agent to a client attached to a job. It carries the
+// RunResponse messages of the job, starting with a replay of the output the job
+// produced so far. Only one client is attached to a job at a time: a new attachment
+// takes over and ends the previous one.
+type AttachResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Run           *RunResponse           `protobuf:"bytes,1,opt,name=run,proto3" json:"run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AttachResponse) Reset() {
+	*x = AttachResponse{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LockRequest) String() string {
+func (x *AttachResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LockRequest) ProtoMessage() {}
+func (*AttachResponse) ProtoMessage() {}
 
-func (x *LockRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[15]
+func (x *AttachResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -977,49 +1990,242 @@ func (x *LockRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LockRequest.ProtoReflect.Descriptor instead.
-func (*LockRequest) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use AttachResponse.ProtoReflect.Descriptor instead.
+func (*AttachResponse) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{26}
 }
 
-func (x *LockRequest) GetDevice() string {
+func (x *AttachResponse) GetRun() *RunResponse {
+	if x != nil {
+		return x.Run
+	}
+	return nil
+}
+
+// JobsRequest is sent by the client to request a list of the jobs on the agent.
+type JobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobsRequest) Reset() {
+	*x = JobsRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobsRequest) ProtoMessage() {}
+
+func (x *JobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobsRequest.ProtoReflect.Descriptor instead.
+func (*JobsRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{27}
+}
+
+// JobsResponse is sent by the agent in response to a JobsRequest. It lists the
+// running jobs and the recently finished ones, oldest first.
+type JobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*JobInfo             `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobsResponse) Reset() {
+	*x = JobsResponse{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobsResponse) ProtoMessage() {}
+
+func (x *JobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobsResponse.ProtoReflect.Descriptor instead.
+func (*JobsResponse) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *JobsResponse) GetJobs() []*JobInfo {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+// JobInfo describes a single job.
+type JobInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	Device        string                 `protobuf:"bytes,3,opt,name=device,proto3" json:"device,omitempty"`
+	Command       string                 `protobuf:"bytes,4,opt,name=command,proto3" json:"command,omitempty"`
+	Args          []string               `protobuf:"bytes,5,rep,name=args,proto3" json:"args,omitempty"`
+	State         JobState               `protobuf:"varint,6,opt,name=state,proto3,enum=dutctl.v1.JobState" json:"state,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`                              // Set for a failed or canceled job.
+	CreatedAt     int64                  `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`    // Unix seconds.
+	FinishedAt    int64                  `protobuf:"varint,9,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"` // Unix seconds, 0 while the job is running.
+	Attached      bool                   `protobuf:"varint,10,opt,name=attached,proto3" json:"attached,omitempty"`                      // A client is attached to the job.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobInfo) Reset() {
+	*x = JobInfo{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobInfo) ProtoMessage() {}
+
+func (x *JobInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobInfo.ProtoReflect.Descriptor instead.
+func (*JobInfo) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *JobInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *JobInfo) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *JobInfo) GetDevice() string {
 	if x != nil {
 		return x.Device
 	}
 	return ""
 }
 
-func (x *LockRequest) GetDurationSeconds() int64 {
+func (x *JobInfo) GetCommand() string {
 	if x != nil {
-		return x.DurationSeconds
+		return x.Command
+	}
+	return ""
+}
+
+func (x *JobInfo) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *JobInfo) GetState() JobState {
+	if x != nil {
+		return x.State
+	}
+	return JobState_JOB_STATE_UNSPECIFIED
+}
+
+func (x *JobInfo) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *JobInfo) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
 	}
 	return 0
 }
 
-// LockResponse is sent by the agent in response to a successful LockRequest.
-type LockResponse struct {
+func (x *JobInfo) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+func (x *JobInfo) GetAttached() bool {
+	if x != nil {
+		return x.Attached
+	}
+	return false
+}
+
+// CancelJobRequest is sent by the client to cancel a running job. Only the user who
+// started the job may cancel it.
+type CancelJobRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
-	Lock          *LockState             `protobuf:"bytes,2,opt,name=lock,proto3" json:"lock,omitempty"`
+	Job           string                 `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LockResponse) Reset() {
-	*x = LockResponse{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[16]
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LockResponse) String() string {
+func (x *CancelJobRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LockResponse) ProtoMessage() {}
+func (*CancelJobRequest) ProtoMessage() {}
 
-func (x *LockResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[16]
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1030,50 +2236,82 @@ func (x *LockResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LockResponse.ProtoReflect.Descriptor instead.
-func (*LockResponse) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{30}
 }
 
-func (x *LockResponse) GetDevice() string {
+func (x *CancelJobRequest) GetJob() string {
 	if x != nil {
-		return x.Device
+		return x.Job
 	}
 	return ""
 }
 
-func (x *LockResponse) GetLock() *LockState {
+// CancelJobResponse is sent by the agent in response to a successful CancelJobRequest.
+type CancelJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobResponse) Reset() {
+	*x = CancelJobResponse{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobResponse) ProtoMessage() {}
+
+func (x *CancelJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[31]
 	if x != nil {
-		return x.Lock
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-// UnlockRequest is sent by the client to release a lock on a device.
-// The lock owner identity is carried in an HTTP header, not in this message.
-type UnlockRequest struct {
+// Deprecated: Use CancelJobResponse.ProtoReflect.Descriptor instead.
+func (*CancelJobResponse) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{31}
+}
+
+// PowerRequest is sent by the client to switch or query the power of a device through
+// the power controller configured for it. Switching the power is subject to the locks
+// of the device like running a command; querying it is not.
+type PowerRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
-	Force         bool                   `protobuf:"varint,2,opt,name=force,proto3" json:"force,omitempty"` // Release the lock regardless of owner.
+	Action        PowerAction            `protobuf:"varint,2,opt,name=action,proto3,enum=dutctl.v1.PowerAction" json:"action,omitempty"`
+	OffTimeMs     int64                  `protobuf:"varint,3,opt,name=off_time_ms,json=offTimeMs,proto3" json:"off_time_ms,omitempty"` // Time the device is kept off during a cycle, 0 applies the device's configured off-time.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UnlockRequest) Reset() {
-	*x = UnlockRequest{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[17]
+func (x *PowerRequest) Reset() {
+	*x = PowerRequest{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UnlockRequest) String() string {
+func (x *PowerRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UnlockRequest) ProtoMessage() {}
+func (*PowerRequest) ProtoMessage() {}
 
-func (x *UnlockRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[17]
+func (x *PowerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1084,47 +2322,58 @@ func (x *UnlockRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UnlockRequest.ProtoReflect.Descriptor instead.
-func (*UnlockRequest) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use PowerRequest.ProtoReflect.Descriptor instead.
+func (*PowerRequest) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *UnlockRequest) GetDevice() string {
+func (x *PowerRequest) GetDevice() string {
 	if x != nil {
 		return x.Device
 	}
 	return ""
 }
 
-func (x *UnlockRequest) GetForce() bool {
+func (x *PowerRequest) GetAction() PowerAction {
 	if x != nil {
-		return x.Force
+		return x.Action
 	}
-	return false
+	return PowerAction_POWER_ACTION_UNSPECIFIED
 }
 
-// UnlockResponse is sent by the agent in response to a successful UnlockRequest.
-type UnlockResponse struct {
+func (x *PowerRequest) GetOffTimeMs() int64 {
+	if x != nil {
+		return x.OffTimeMs
+	}
+	return 0
+}
+
+// PowerResponse is sent by the agent in response to a successful PowerRequest. It
+// reports the power state after the action, as read from the power controller.
+type PowerResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	State         PowerState             `protobuf:"varint,2,opt,name=state,proto3,enum=dutctl.v1.PowerState" json:"state,omitempty"`
+	Controller    string                 `protobuf:"bytes,3,opt,name=controller,proto3" json:"controller,omitempty"` // Name of the module acting as the power controller.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UnlockResponse) Reset() {
-	*x = UnlockResponse{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[18]
+func (x *PowerResponse) Reset() {
+	*x = PowerResponse{}
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UnlockResponse) String() string {
+func (x *PowerResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UnlockResponse) ProtoMessage() {}
+func (*PowerResponse) ProtoMessage() {}
 
-func (x *UnlockResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[18]
+func (x *PowerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1135,9 +2384,30 @@ func (x *UnlockResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UnlockResponse.ProtoReflect.Descriptor instead.
-func (*UnlockResponse) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use PowerResponse.ProtoReflect.Descriptor instead.
+func (*PowerResponse) Descriptor() ([]byte, []int) {
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *PowerResponse) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *PowerResponse) GetState() PowerState {
+	if x != nil {
+		return x.State
+	}
+	return PowerState_POWER_STATE_UNSPECIFIED
+}
+
+func (x *PowerResponse) GetController() string {
+	if x != nil {
+		return x.Controller
+	}
+	return ""
 }
 
 // RegisterRequest is sent by a device agent to register with the relay server.
@@ -1152,7 +2422,7 @@ type RegisterRequest struct {
 
 func (x *RegisterRequest) Reset() {
 	*x = RegisterRequest{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[19]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1164,7 +2434,7 @@ func (x *RegisterRequest) String() string {
 func (*RegisterRequest) ProtoMessage() {}
 
 func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[19]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1177,7 +2447,7 @@ func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
 func (*RegisterRequest) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{19}
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *RegisterRequest) GetDevices() []string {
@@ -1204,7 +2474,7 @@ type RegisterResponse struct {
 
 func (x *RegisterResponse) Reset() {
 	*x = RegisterResponse{}
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[20]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1216,7 +2486,7 @@ func (x *RegisterResponse) String() string {
 func (*RegisterResponse) ProtoMessage() {}
 
 func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_dutctl_v1_dutctl_proto_msgTypes[20]
+	mi := &file_dutctl_v1_dutctl_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1229,7 +2499,7 @@ func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
 func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{20}
+	return file_dutctl_v1_dutctl_proto_rawDescGZIP(), []int{35}
 }
 
 var File_dutctl_v1_dutctl_proto protoreflect.FileDescriptor
@@ -1258,35 +2528,68 @@ const file_dutctl_v1_dutctl_proto_rawDesc = "" +
 	"\acommand\x18\x02 \x01(\tR\acommand\x12\x18\n" +
 	"\akeyword\x18\x03 \x01(\tR\akeyword\"+\n" +
 	"\x0fDetailsResponse\x12\x18\n" +
-	"\adetails\x18\x01 \x01(\tR\adetails\"\x9a\x01\n" +
+	"\adetails\x18\x01 \x01(\tR\adetails\"\xf4\x01\n" +
 	"\n" +
 	"RunRequest\x12.\n" +
 	"\acommand\x18\x01 \x01(\v2\x12.dutctl.v1.CommandH\x00R\acommand\x12.\n" +
 	"\aconsole\x18\x02 \x01(\v2\x12.dutctl.v1.ConsoleH\x00R\aconsole\x12%\n" +
-	"\x04file\x18\x03 \x01(\v2\x0f.dutctl.v1.FileH\x00R\x04fileB\x05\n" +
-	"\x03msg\"\xd2\x01\n" +
+	"\x04file\x18\x03 \x01(\v2\x0f.dutctl.v1.FileH\x00R\x04file\x12+\n" +
+	"\x06signal\x18\x04 \x01(\v2\x11.dutctl.v1.SignalH\x00R\x06signal\x12+\n" +
+	"\x06cancel\x18\x05 \x01(\v2\x11.dutctl.v1.CancelH\x00R\x06cancelB\x05\n" +
+	"\x03msg\"\xa6\x02\n" +
 	"\vRunResponse\x12(\n" +
 	"\x05print\x18\x01 \x01(\v2\x10.dutctl.v1.PrintH\x00R\x05print\x12.\n" +
 	"\aconsole\x18\x02 \x01(\v2\x12.dutctl.v1.ConsoleH\x00R\aconsole\x12;\n" +
 	"\ffile_request\x18\x03 \x01(\v2\x16.dutctl.v1.FileRequestH\x00R\vfileRequest\x12%\n" +
-	"\x04file\x18\x04 \x01(\v2\x0f.dutctl.v1.FileH\x00R\x04fileB\x05\n" +
-	"\x03msg\"O\n" +
+	"\x04file\x18\x04 \x01(\v2\x0f.dutctl.v1.FileH\x00R\x04file\x12.\n" +
+	"\x06result\x18\x05 \x01(\v2\x14.dutctl.v1.RunResultH\x00R\x06result\x12\"\n" +
+	"\x03job\x18\x06 \x01(\v2\x0e.dutctl.v1.JobH\x00R\x03jobB\x05\n" +
+	"\x03msg\"g\n" +
 	"\aCommand\x12\x16\n" +
 	"\x06device\x18\x01 \x01(\tR\x06device\x12\x18\n" +
 	"\acommand\x18\x02 \x01(\tR\acommand\x12\x12\n" +
-	"\x04args\x18\x03 \x03(\tR\x04args\"\x1b\n" +
+	"\x04args\x18\x03 \x03(\tR\x04args\x12\x16\n" +
+	"\x06detach\x18\x04 \x01(\bR\x06detach\"\x15\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1b\n" +
 	"\x05Print\x12\x12\n" +
-	"\x04text\x18\x01 \x01(\fR\x04text\"]\n" +
+	"\x04text\x18\x01 \x01(\fR\x04text\"\x97\x01\n" +
 	"\aConsole\x12\x16\n" +
 	"\x05stdin\x18\x01 \x01(\fH\x00R\x05stdin\x12\x18\n" +
 	"\x06stdout\x18\x02 \x01(\fH\x00R\x06stdout\x12\x18\n" +
-	"\x06stderr\x18\x03 \x01(\fH\x00R\x06stderrB\x06\n" +
-	"\x04data\"!\n" +
+	"\x06stderr\x18\x03 \x01(\fH\x00R\x06stderr\x128\n" +
+	"\vwindow_size\x18\x04 \x01(\v2\x15.dutctl.v1.WindowSizeH\x00R\n" +
+	"windowSizeB\x06\n" +
+	"\x04data\"4\n" +
+	"\n" +
+	"WindowSize\x12\x12\n" +
+	"\x04rows\x18\x01 \x01(\rR\x04rows\x12\x12\n" +
+	"\x04cols\x18\x02 \x01(\rR\x04cols\"3\n" +
+	"\x06Signal\x12)\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x15.dutctl.v1.SignalKindR\x04kind\"\b\n" +
+	"\x06Cancel\"!\n" +
 	"\vFileRequest\x12\x12\n" +
-	"\x04path\x18\x01 \x01(\tR\x04path\"4\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x8c\x01\n" +
 	"\x04File\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\fR\acontent\"P\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04more\x18\x05 \x01(\bR\x04more\x12\x16\n" +
+	"\x06sha256\x18\x06 \x01(\fR\x06sha256\">\n" +
+	"\tRunResult\x121\n" +
+	"\amodules\x18\x01 \x03(\v2\x17.dutctl.v1.ModuleResultR\amodules\"\xe3\x01\n" +
+	"\fModuleResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x05R\x05index\x122\n" +
+	"\aoutcome\x18\x03 \x01(\x0e2\x18.dutctl.v1.ModuleOutcomeR\aoutcome\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x05 \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vfinished_at\x18\x06 \x01(\x03R\n" +
+	"finishedAt\x12\x1f\n" +
+	"\vduration_ms\x18\a \x01(\x03R\n" +
+	"durationMs\"P\n" +
 	"\vLockRequest\x12\x16\n" +
 	"\x06device\x18\x01 \x01(\tR\x06device\x12)\n" +
 	"\x10duration_seconds\x18\x02 \x01(\x03R\x0fdurationSeconds\"P\n" +
@@ -1296,18 +2599,87 @@ const file_dutctl_v1_dutctl_proto_rawDesc = "" +
 	"\rUnlockRequest\x12\x16\n" +
 	"\x06device\x18\x01 \x01(\tR\x06device\x12\x14\n" +
 	"\x05force\x18\x02 \x01(\bR\x05force\"\x10\n" +
-	"\x0eUnlockResponse\"E\n" +
+	"\x0eUnlockResponse\"U\n" +
+	"\rAttachRequest\x12\x12\n" +
+	"\x03job\x18\x01 \x01(\tH\x00R\x03job\x12)\n" +
+	"\x03run\x18\x02 \x01(\v2\x15.dutctl.v1.RunRequestH\x00R\x03runB\x05\n" +
+	"\x03msg\":\n" +
+	"\x0eAttachResponse\x12(\n" +
+	"\x03run\x18\x01 \x01(\v2\x16.dutctl.v1.RunResponseR\x03run\"\r\n" +
+	"\vJobsRequest\"6\n" +
+	"\fJobsResponse\x12&\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x12.dutctl.v1.JobInfoR\x04jobs\"\x92\x02\n" +
+	"\aJobInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x16\n" +
+	"\x06device\x18\x03 \x01(\tR\x06device\x12\x18\n" +
+	"\acommand\x18\x04 \x01(\tR\acommand\x12\x12\n" +
+	"\x04args\x18\x05 \x03(\tR\x04args\x12)\n" +
+	"\x05state\x18\x06 \x01(\x0e2\x13.dutctl.v1.JobStateR\x05state\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\b \x01(\x03R\tcreatedAt\x12\x1f\n" +
+	"\vfinished_at\x18\t \x01(\x03R\n" +
+	"finishedAt\x12\x1a\n" +
+	"\battached\x18\n" +
+	" \x01(\bR\battached\"$\n" +
+	"\x10CancelJobRequest\x12\x10\n" +
+	"\x03job\x18\x01 \x01(\tR\x03job\"\x13\n" +
+	"\x11CancelJobResponse\"v\n" +
+	"\fPowerRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12.\n" +
+	"\x06action\x18\x02 \x01(\x0e2\x16.dutctl.v1.PowerActionR\x06action\x12\x1e\n" +
+	"\voff_time_ms\x18\x03 \x01(\x03R\toffTimeMs\"t\n" +
+	"\rPowerResponse\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12+\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x15.dutctl.v1.PowerStateR\x05state\x12\x1e\n" +
+	"\n" +
+	"controller\x18\x03 \x01(\tR\n" +
+	"controller\"E\n" +
 	"\x0fRegisterRequest\x12\x18\n" +
 	"\adevices\x18\x01 \x03(\tR\adevices\x12\x18\n" +
 	"\aaddress\x18\x02 \x01(\tR\aaddress\"\x12\n" +
-	"\x10RegisterResponse2\x8d\x03\n" +
+	"\x10RegisterResponse*o\n" +
+	"\n" +
+	"SignalKind\x12\x1b\n" +
+	"\x17SIGNAL_KIND_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15SIGNAL_KIND_INTERRUPT\x10\x01\x12\x14\n" +
+	"\x10SIGNAL_KIND_QUIT\x10\x02\x12\x13\n" +
+	"\x0fSIGNAL_KIND_EOF\x10\x03*\xa1\x01\n" +
+	"\rModuleOutcome\x12\x1e\n" +
+	"\x1aMODULE_OUTCOME_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18MODULE_OUTCOME_SUCCEEDED\x10\x01\x12\x19\n" +
+	"\x15MODULE_OUTCOME_FAILED\x10\x02\x12\x1b\n" +
+	"\x17MODULE_OUTCOME_CANCELED\x10\x03\x12\x1a\n" +
+	"\x16MODULE_OUTCOME_SKIPPED\x10\x04*\x83\x01\n" +
+	"\bJobState\x12\x19\n" +
+	"\x15JOB_STATE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11JOB_STATE_RUNNING\x10\x01\x12\x17\n" +
+	"\x13JOB_STATE_SUCCEEDED\x10\x02\x12\x14\n" +
+	"\x10JOB_STATE_FAILED\x10\x03\x12\x16\n" +
+	"\x12JOB_STATE_CANCELED\x10\x04*\x87\x01\n" +
+	"\vPowerAction\x12\x1c\n" +
+	"\x18POWER_ACTION_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fPOWER_ACTION_ON\x10\x01\x12\x14\n" +
+	"\x10POWER_ACTION_OFF\x10\x02\x12\x16\n" +
+	"\x12POWER_ACTION_CYCLE\x10\x03\x12\x17\n" +
+	"\x13POWER_ACTION_STATUS\x10\x04*R\n" +
+	"\n" +
+	"PowerState\x12\x1b\n" +
+	"\x17POWER_STATE_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0ePOWER_STATE_ON\x10\x01\x12\x13\n" +
+	"\x0fPOWER_STATE_OFF\x10\x022\x95\x05\n" +
 	"\rDeviceService\x129\n" +
 	"\x04List\x12\x16.dutctl.v1.ListRequest\x1a\x17.dutctl.v1.ListResponse\"\x00\x12E\n" +
 	"\bCommands\x12\x1a.dutctl.v1.CommandsRequest\x1a\x1b.dutctl.v1.CommandsResponse\"\x00\x12B\n" +
 	"\aDetails\x12\x19.dutctl.v1.DetailsRequest\x1a\x1a.dutctl.v1.DetailsResponse\"\x00\x12:\n" +
 	"\x03Run\x12\x15.dutctl.v1.RunRequest\x1a\x16.dutctl.v1.RunResponse\"\x00(\x010\x01\x129\n" +
 	"\x04Lock\x12\x16.dutctl.v1.LockRequest\x1a\x17.dutctl.v1.LockResponse\"\x00\x12?\n" +
-	"\x06Unlock\x12\x18.dutctl.v1.UnlockRequest\x1a\x19.dutctl.v1.UnlockResponse\"\x002U\n" +
+	"\x06Unlock\x12\x18.dutctl.v1.UnlockRequest\x1a\x19.dutctl.v1.UnlockResponse\"\x00\x12C\n" +
+	"\x06Attach\x12\x18.dutctl.v1.AttachRequest\x1a\x19.dutctl.v1.AttachResponse\"\x00(\x010\x01\x129\n" +
+	"\x04Jobs\x12\x16.dutctl.v1.JobsRequest\x1a\x17.dutctl.v1.JobsResponse\"\x00\x12H\n" +
+	"\tCancelJob\x12\x1b.dutctl.v1.CancelJobRequest\x1a\x1c.dutctl.v1.CancelJobResponse\"\x00\x12<\n" +
+	"\x05Power\x12\x17.dutctl.v1.PowerRequest\x1a\x18.dutctl.v1.PowerResponse\"\x002U\n" +
 	"\fRelayService\x12E\n" +
 	"\bRegister\x12\x1a.dutctl.v1.RegisterRequest\x1a\x1b.dutctl.v1.RegisterResponse\"\x00BEZCgithub.com/BlindspotSoftware/dutctl/protobuf/gen/dutctl/v1;dutctlv1b\x06proto3"
 
@@ -1323,60 +2695,103 @@ func file_dutctl_v1_dutctl_proto_rawDescGZIP() []byte {
 	return file_dutctl_v1_dutctl_proto_rawDescData
 }
 
-var file_dutctl_v1_dutctl_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_dutctl_v1_dutctl_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_dutctl_v1_dutctl_proto_msgTypes = make([]protoimpl.MessageInfo, 36)
 var file_dutctl_v1_dutctl_proto_goTypes = []any{
-	(*ListRequest)(nil),      // 0: dutctl.v1.ListRequest
-	(*ListResponse)(nil),     // 1: dutctl.v1.ListResponse
-	(*DeviceInfo)(nil),       // 2: dutctl.v1.DeviceInfo
-	(*LockState)(nil),        // 3: dutctl.v1.LockState
-	(*CommandsRequest)(nil),  // 4: dutctl.v1.CommandsRequest
-	(*CommandsResponse)(nil), // 5: dutctl.v1.CommandsResponse
-	(*DetailsRequest)(nil),   // 6: dutctl.v1.DetailsRequest
-	(*DetailsResponse)(nil),  // 7: dutctl.v1.DetailsResponse
-	(*RunRequest)(nil),       // 8: dutctl.v1.RunRequest
-	(*RunResponse)(nil),      // 9: dutctl.v1.RunResponse
-	(*Command)(nil),          // 10: dutctl.v1.Command
-	(*Print)(nil),            // 11: dutctl.v1.Print
-	(*Console)(nil),          // 12: dutctl.v1.Console
-	(*FileRequest)(nil),      // 13: dutctl.v1.FileRequest
-	(*File)(nil),             // 14: dutctl.v1.File
-	(*LockRequest)(nil),      // 15: dutctl.v1.LockRequest
-	(*LockResponse)(nil),     // 16: dutctl.v1.LockResponse
-	(*UnlockRequest)(nil),    // 17: dutctl.v1.UnlockRequest
-	(*UnlockResponse)(nil),   // 18: dutctl.v1.UnlockResponse
-	(*RegisterRequest)(nil),  // 19: dutctl.v1.RegisterRequest
-	(*RegisterResponse)(nil), // 20: dutctl.v1.RegisterResponse
+	(SignalKind)(0),           // 0: dutctl.v1.SignalKind
+	(ModuleOutcome)(0),        // 1: dutctl.v1.ModuleOutcome
+	(JobState)(0),             // 2: dutctl.v1.JobState
+	(PowerAction)(0),          // 3: dutctl.v1.PowerAction
+	(PowerState)(0),           // 4: dutctl.v1.PowerState
+	(*ListRequest)(nil),       // 5: dutctl.v1.ListRequest
+	(*ListResponse)(nil),      // 6: dutctl.v1.ListResponse
+	(*DeviceInfo)(nil),        // 7: dutctl.v1.DeviceInfo
+	(*LockState)(nil),         // 8: dutctl.v1.LockState
+	(*CommandsRequest)(nil),   // 9: dutctl.v1.CommandsRequest
+	(*CommandsResponse)(nil),  // 10: dutctl.v1.CommandsResponse
+	(*DetailsRequest)(nil),    // 11: dutctl.v1.DetailsRequest
+	(*DetailsResponse)(nil),   // 12: dutctl.v1.DetailsResponse
+	(*RunRequest)(nil),        // 13: dutctl.v1.RunRequest
+	(*RunResponse)(nil),       // 14: dutctl.v1.RunResponse
+	(*Command)(nil),           // 15: dutctl.v1.Command
+	(*Job)(nil),               // 16: dutctl.v1.Job
+	(*Print)(nil),             // 17: dutctl.v1.Print
+	(*Console)(nil),           // 18: dutctl.v1.Console
+	(*WindowSize)(nil),        // 19: dutctl.v1.WindowSize
+	(*Signal)(nil),            // 20: dutctl.v1.Signal
+	(*Cancel)(nil),            // 21: dutctl.v1.Cancel
+	(*FileRequest)(nil),       // 22: dutctl.v1.FileRequest
+	(*File)(nil),              // 23: dutctl.v1.File
+	(*RunResult)(nil),         // 24: dutctl.v1.RunResult
+	(*ModuleResult)(nil),      // 25: dutctl.v1.ModuleResult
+	(*LockRequest)(nil),       // 26: dutctl.v1.LockRequest
+	(*LockResponse)(nil),      // 27: dutctl.v1.LockResponse
+	(*UnlockRequest)(nil),     // 28: dutctl.v1.UnlockRequest
+	(*UnlockResponse)(nil),    // 29: dutctl.v1.UnlockResponse
+	(*AttachRequest)(nil),     // 30: dutctl.v1.AttachRequest
+	(*AttachResponse)(nil),    // 31: dutctl.v1.AttachResponse
+	(*JobsRequest)(nil),       // 32: dutctl.v1.JobsRequest
+	(*JobsResponse)(nil),      // 33: dutctl.v1.JobsResponse
+	(*JobInfo)(nil),           // 34: dutctl.v1.JobInfo
+	(*CancelJobRequest)(nil),  // 35: dutctl.v1.CancelJobRequest
+	(*CancelJobResponse)(nil), // 36: dutctl.v1.CancelJobResponse
+	(*PowerRequest)(nil),      // 37: dutctl.v1.PowerRequest
+	(*PowerResponse)(nil),     // 38: dutctl.v1.PowerResponse
+	(*RegisterRequest)(nil),   // 39: dutctl.v1.RegisterRequest
+	(*RegisterResponse)(nil),  // 40: dutctl.v1.RegisterResponse
 }
 var file_dutctl_v1_dutctl_proto_depIdxs = []int32{
-	2,  // 0: dutctl.v1.ListResponse.devices:type_name -> dutctl.v1.DeviceInfo
-	3,  // 1: dutctl.v1.DeviceInfo.lock:type_name -> dutctl.v1.LockState
-	10, // 2: dutctl.v1.RunRequest.command:type_name -> dutctl.v1.Command
-	12, // 3: dutctl.v1.RunRequest.console:type_name -> dutctl.v1.Console
-	14, // 4: dutctl.v1.RunRequest.file:type_name -> dutctl.v1.File
-	11, // 5: dutctl.v1.RunResponse.print:type_name -> dutctl.v1.Print
-	12, // 6: dutctl.v1.RunResponse.console:type_name -> dutctl.v1.Console
-	13, // 7: dutctl.v1.RunResponse.file_request:type_name -> dutctl.v1.FileRequest
-	14, // 8: dutctl.v1.RunResponse.file:type_name -> dutctl.v1.File
-	3,  // 9: dutctl.v1.LockResponse.lock:type_name -> dutctl.v1.LockState
-	0,  // 10: dutctl.v1.DeviceService.List:input_type -> dutctl.v1.ListRequest
-	4,  // 11: dutctl.v1.DeviceService.Commands:input_type -> dutctl.v1.CommandsRequest
-	6,  // 12: dutctl.v1.DeviceService.Details:input_type -> dutctl.v1.DetailsRequest
-	8,  // 13: dutctl.v1.DeviceService.Run:input_type -> dutctl.v1.RunRequest
-	15, // 14: dutctl.v1.DeviceService.Lock:input_type -> dutctl.v1.LockRequest
-	17, // 15: dutctl.v1.DeviceService.Unlock:input_type -> dutctl.v1.UnlockRequest
-	19, // 16: dutctl.v1.RelayService.Register:input_type -> dutctl.v1.RegisterRequest
-	1,  // 17: dutctl.v1.DeviceService.List:output_type -> dutctl.v1.ListResponse
-	5,  // 18: dutctl.v1.DeviceService.Commands:output_type -> dutctl.v1.CommandsResponse
-	7,  // 19: dutctl.v1.DeviceService.Details:output_type -> dutctl.v1.DetailsResponse
-	9,  // 20: dutctl.v1.DeviceService.Run:output_type -> dutctl.v1.RunResponse
-	16, // 21: dutctl.v1.DeviceService.Lock:output_type -> dutctl.v1.LockResponse
-	18, // 22: dutctl.v1.DeviceService.Unlock:output_type -> dutctl.v1.UnlockResponse
-	20, // 23: dutctl.v1.RelayService.Register:output_type -> dutctl.v1.RegisterResponse
-	17, // [17:24] is the sub-list for method output_type
-	10, // [10:17] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	7,  // 0: dutctl.v1.ListResponse.devices:type_name -> dutctl.v1.DeviceInfo
+	8,  // 1: dutctl.v1.DeviceInfo.lock:type_name -> dutctl.v1.LockState
+	15, // 2: dutctl.v1.RunRequest.command:type_name -> dutctl.v1.Command
+	18, // 3: dutctl.v1.RunRequest.console:type_name -> dutctl.v1.Console
+	23, // 4: dutctl.v1.RunRequest.file:type_name -> dutctl.v1.File
+	20, // 5: dutctl.v1.RunRequest.signal:type_name -> dutctl.v1.Signal
+	21, // 6: dutctl.v1.RunRequest.cancel:type_name -> dutctl.v1.Cancel
+	17, // 7: dutctl.v1.RunResponse.print:type_name -> dutctl.v1.Print
+	18, // 8: dutctl.v1.RunResponse.console:type_name -> dutctl.v1.Console
+	22, // 9: dutctl.v1.RunResponse.file_request:type_name -> dutctl.v1.FileRequest
+	23, // 10: dutctl.v1.RunResponse.file:type_name -> dutctl.v1.File
+	24, // 11: dutctl.v1.RunResponse.result:type_name -> dutctl.v1.RunResult
+	16, // 12: dutctl.v1.RunResponse.job:type_name -> dutctl.v1.Job
+	19, // 13: dutctl.v1.Console.window_size:type_name -> dutctl.v1.WindowSize
+	0,  // 14: dutctl.v1.Signal.kind:type_name -> dutctl.v1.SignalKind
+	25, // 15: dutctl.v1.RunResult.modules:type_name -> dutctl.v1.ModuleResult
+	1,  // 16: dutctl.v1.ModuleResult.outcome:type_name -> dutctl.v1.ModuleOutcome
+	8,  // 17: dutctl.v1.LockResponse.lock:type_name -> dutctl.v1.LockState
+	13, // 18: dutctl.v1.AttachRequest.run:type_name -> dutctl.v1.RunRequest
+	14, // 19: dutctl.v1.AttachResponse.run:type_name -> dutctl.v1.RunResponse
+	34, // 20: dutctl.v1.JobsResponse.jobs:type_name -> dutctl.v1.JobInfo
+	2,  // 21: dutctl.v1.JobInfo.state:type_name -> dutctl.v1.JobState
+	3,  // 22: dutctl.v1.PowerRequest.action:type_name -> dutctl.v1.PowerAction
+	4,  // 23: dutctl.v1.PowerResponse.state:type_name -> dutctl.v1.PowerState
+	5,  // 24: dutctl.v1.DeviceService.List:input_type -> dutctl.v1.ListRequest
+	9,  // 25: dutctl.v1.DeviceService.Commands:input_type -> dutctl.v1.CommandsRequest
+	11, // 26: dutctl.v1.DeviceService.Details:input_type -> dutctl.v1.DetailsRequest
+	13, // 27: dutctl.v1.DeviceService.Run:input_type -> dutctl.v1.RunRequest
+	26, // 28: dutctl.v1.DeviceService.Lock:input_type -> dutctl.v1.LockRequest
+	28, // 29: dutctl.v1.DeviceService.Unlock:input_type -> dutctl.v1.UnlockRequest
+	30, // 30: dutctl.v1.DeviceService.Attach:input_type -> dutctl.v1.AttachRequest
+	32, // 31: dutctl.v1.DeviceService.Jobs:input_type -> dutctl.v1.JobsRequest
+	35, // 32: dutctl.v1.DeviceService.CancelJob:input_type -> dutctl.v1.CancelJobRequest
+	37, // 33: dutctl.v1.DeviceService.Power:input_type -> dutctl.v1.PowerRequest
+	39, // 34: dutctl.v1.RelayService.Register:input_type -> dutctl.v1.RegisterRequest
+	6,  // 35: dutctl.v1.DeviceService.List:output_type -> dutctl.v1.ListResponse
+	10, // 36: dutctl.v1.DeviceService.Commands:output_type -> dutctl.v1.CommandsResponse
+	12, // 37: dutctl.v1.DeviceService.Details:output_type -> dutctl.v1.DetailsResponse
+	14, // 38: dutctl.v1.DeviceService.Run:output_type -> dutctl.v1.RunResponse
+	27, // 39: dutctl.v1.DeviceService.Lock:output_type -> dutctl.v1.LockResponse
+	29, // 40: dutctl.v1.DeviceService.Unlock:output_type -> dutctl.v1.UnlockResponse
+	31, // 41: dutctl.v1.DeviceService.Attach:output_type -> dutctl.v1.AttachResponse
+	33, // 42: dutctl.v1.DeviceService.Jobs:output_type -> dutctl.v1.JobsResponse
+	36, // 43: dutctl.v1.DeviceService.CancelJob:output_type -> dutctl.v1.CancelJobResponse
+	38, // 44: dutctl.v1.DeviceService.Power:output_type -> dutctl.v1.PowerResponse
+	40, // 45: dutctl.v1.RelayService.Register:output_type -> dutctl.v1.RegisterResponse
+	35, // [35:46] is the sub-list for method output_type
+	24, // [24:35] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
 }
 
 func init() { file_dutctl_v1_dutctl_proto_init() }
@@ -1388,30 +2803,40 @@ func file_dutctl_v1_dutctl_proto_init() {
 		(*RunRequest_Command)(nil),
 		(*RunRequest_Console)(nil),
 		(*RunRequest_File)(nil),
+		(*RunRequest_Signal)(nil),
+		(*RunRequest_Cancel)(nil),
 	}
 	file_dutctl_v1_dutctl_proto_msgTypes[9].OneofWrappers = []any{
 		(*RunResponse_Print)(nil),
 		(*RunResponse_Console)(nil),
 		(*RunResponse_FileRequest)(nil),
 		(*RunResponse_File)(nil),
+		(*RunResponse_Result)(nil),
+		(*RunResponse_Job)(nil),
 	}
-	file_dutctl_v1_dutctl_proto_msgTypes[12].OneofWrappers = []any{
+	file_dutctl_v1_dutctl_proto_msgTypes[13].OneofWrappers = []any{
 		(*Console_Stdin)(nil),
 		(*Console_Stdout)(nil),
 		(*Console_Stderr)(nil),
+		(*Console_WindowSize)(nil),
+	}
+	file_dutctl_v1_dutctl_proto_msgTypes[25].OneofWrappers = []any{
+		(*AttachRequest_Job)(nil),
+		(*AttachRequest_Run)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_dutctl_v1_dutctl_proto_rawDesc), len(file_dutctl_v1_dutctl_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   21,
+			NumEnums:      5,
+			NumMessages:   36,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_dutctl_v1_dutctl_proto_goTypes,
 		DependencyIndexes: file_dutctl_v1_dutctl_proto_depIdxs,
+		EnumInfos:         file_dutctl_v1_dutctl_proto_enumTypes,
 		MessageInfos:      file_dutctl_v1_dutctl_proto_msgTypes,
 	}.Build()
 	File_dutctl_v1_dutctl_proto = out.File
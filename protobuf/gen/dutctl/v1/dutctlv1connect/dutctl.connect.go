@@ -47,6 +47,14 @@ const (
 	DeviceServiceLockProcedure = "/dutctl.v1.DeviceService/Lock"
 	// DeviceServiceUnlockProcedure is the fully-qualified name of the DeviceService's Unlock RPC.
 	DeviceServiceUnlockProcedure = "/dutctl.v1.DeviceService/Unlock"
+	// DeviceServiceAttachProcedure is the fully-qualified name of the DeviceService's Attach RPC.
+	DeviceServiceAttachProcedure = "/dutctl.v1.DeviceService/Attach"
+	// DeviceServiceJobsProcedure is the fully-qualified name of the DeviceService's Jobs RPC.
+	DeviceServiceJobsProcedure = "/dutctl.v1.DeviceService/Jobs"
+	// DeviceServiceCancelJobProcedure is the fully-qualified name of the DeviceService's CancelJob RPC.
+	DeviceServiceCancelJobProcedure = "/dutctl.v1.DeviceService/CancelJob"
+	// DeviceServicePowerProcedure is the fully-qualified name of the DeviceService's Power RPC.
+	DeviceServicePowerProcedure = "/dutctl.v1.DeviceService/Power"
 	// RelayServiceRegisterProcedure is the fully-qualified name of the RelayService's Register RPC.
 	RelayServiceRegisterProcedure = "/dutctl.v1.RelayService/Register"
 )
@@ -59,6 +67,10 @@ type DeviceServiceClient interface {
 	Run(context.Context) *connect.BidiStreamForClient[v1.RunRequest, v1.RunResponse]
 	Lock(context.Context, *connect.Request[v1.LockRequest]) (*connect.Response[v1.LockResponse], error)
 	Unlock(context.Context, *connect.Request[v1.UnlockRequest]) (*connect.Response[v1.UnlockResponse], error)
+	Attach(context.Context) *connect.BidiStreamForClient[v1.AttachRequest, v1.AttachResponse]
+	Jobs(context.Context, *connect.Request[v1.JobsRequest]) (*connect.Response[v1.JobsResponse], error)
+	CancelJob(context.Context, *connect.Request[v1.CancelJobRequest]) (*connect.Response[v1.CancelJobResponse], error)
+	Power(context.Context, *connect.Request[v1.PowerRequest]) (*connect.Response[v1.PowerResponse], error)
 }
 
 // NewDeviceServiceClient constructs a client for the dutctl.v1.DeviceService service. By default,
@@ -108,17 +120,45 @@ func NewDeviceServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(deviceServiceMethods.ByName("Unlock")),
 			connect.WithClientOptions(opts...),
 		),
+		attach: connect.NewClient[v1.AttachRequest, v1.AttachResponse](
+			httpClient,
+			baseURL+DeviceServiceAttachProcedure,
+			connect.WithSchema(deviceServiceMethods.ByName("Attach")),
+			connect.WithClientOptions(opts...),
+		),
+		jobs: connect.NewClient[v1.JobsRequest, v1.JobsResponse](
+			httpClient,
+			baseURL+DeviceServiceJobsProcedure,
+			connect.WithSchema(deviceServiceMethods.ByName("Jobs")),
+			connect.WithClientOptions(opts...),
+		),
+		cancelJob: connect.NewClient[v1.CancelJobRequest, v1.CancelJobResponse](
+			httpClient,
+			baseURL+DeviceServiceCancelJobProcedure,
+			connect.WithSchema(deviceServiceMethods.ByName("CancelJob")),
+			connect.WithClientOptions(opts...),
+		),
+		power: connect.NewClient[v1.PowerRequest, v1.PowerResponse](
+			httpClient,
+			baseURL+DeviceServicePowerProcedure,
+			connect.WithSchema(deviceServiceMethods.ByName("Power")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // deviceServiceClient implements DeviceServiceClient.
 type deviceServiceClient struct {
-	list     *connect.Client[v1.ListRequest, v1.ListResponse]
-	commands *connect.Client[v1.CommandsRequest, v1.CommandsResponse]
-	details  *connect.Client[v1.DetailsRequest, v1.DetailsResponse]
-	run      *connect.Client[v1.RunRequest, v1.RunResponse]
-	lock     *connect.Client[v1.LockRequest, v1.LockResponse]
-	unlock   *connect.Client[v1.UnlockRequest, v1.UnlockResponse]
+	list      *connect.Client[v1.ListRequest, v1.ListResponse]
+	commands  *connect.Client[v1.CommandsRequest, v1.CommandsResponse]
+	details   *connect.Client[v1.DetailsRequest, v1.DetailsResponse]
+	run       *connect.Client[v1.RunRequest, v1.RunResponse]
+	lock      *connect.Client[v1.LockRequest, v1.LockResponse]
+	unlock    *connect.Client[v1.UnlockRequest, v1.UnlockResponse]
+	attach    *connect.Client[v1.AttachRequest, v1.AttachResponse]
+	jobs      *connect.Client[v1.JobsRequest, v1.JobsResponse]
+	cancelJob *connect.Client[v1.CancelJobRequest, v1.CancelJobResponse]
+	power     *connect.Client[v1.PowerRequest, v1.PowerResponse]
 }
 
 // List calls dutctl.v1.DeviceService.List.
@@ -151,6 +191,26 @@ func (c *deviceServiceClient) Unlock(ctx context.Context, req *connect.Request[v
 	return c.unlock.CallUnary(ctx, req)
 }
 
+// Attach calls dutctl.v1.DeviceService.Attach.
+func (c *deviceServiceClient) Attach(ctx context.Context) *connect.BidiStreamForClient[v1.AttachRequest, v1.AttachResponse] {
+	return c.attach.CallBidiStream(ctx)
+}
+
+// Jobs calls dutctl.v1.DeviceService.Jobs.
+func (c *deviceServiceClient) Jobs(ctx context.Context, req *connect.Request[v1.JobsRequest]) (*connect.Response[v1.JobsResponse], error) {
+	return c.jobs.CallUnary(ctx, req)
+}
+
+// CancelJob calls dutctl.v1.DeviceService.CancelJob.
+func (c *deviceServiceClient) CancelJob(ctx context.Context, req *connect.Request[v1.CancelJobRequest]) (*connect.Response[v1.CancelJobResponse], error) {
+	return c.cancelJob.CallUnary(ctx, req)
+}
+
+// Power calls dutctl.v1.DeviceService.Power.
+func (c *deviceServiceClient) Power(ctx context.Context, req *connect.Request[v1.PowerRequest]) (*connect.Response[v1.PowerResponse], error) {
+	return c.power.CallUnary(ctx, req)
+}
+
 // DeviceServiceHandler is an implementation of the dutctl.v1.DeviceService service.
 type DeviceServiceHandler interface {
 	List(context.Context, *connect.Request[v1.ListRequest]) (*connect.Response[v1.ListResponse], error)
@@ -159,6 +219,10 @@ type DeviceServiceHandler interface {
 	Run(context.Context, *connect.BidiStream[v1.RunRequest, v1.RunResponse]) error
 	Lock(context.Context, *connect.Request[v1.LockRequest]) (*connect.Response[v1.LockResponse], error)
 	Unlock(context.Context, *connect.Request[v1.UnlockRequest]) (*connect.Response[v1.UnlockResponse], error)
+	Attach(context.Context, *connect.BidiStream[v1.AttachRequest, v1.AttachResponse]) error
+	Jobs(context.Context, *connect.Request[v1.JobsRequest]) (*connect.Response[v1.JobsResponse], error)
+	CancelJob(context.Context, *connect.Request[v1.CancelJobRequest]) (*connect.Response[v1.CancelJobResponse], error)
+	Power(context.Context, *connect.Request[v1.PowerRequest]) (*connect.Response[v1.PowerResponse], error)
 }
 
 // NewDeviceServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -204,6 +268,30 @@ func NewDeviceServiceHandler(svc DeviceServiceHandler, opts ...connect.HandlerOp
 		connect.WithSchema(deviceServiceMethods.ByName("Unlock")),
 		connect.WithHandlerOptions(opts...),
 	)
+	deviceServiceAttachHandler := connect.NewBidiStreamHandler(
+		DeviceServiceAttachProcedure,
+		svc.Attach,
+		connect.WithSchema(deviceServiceMethods.ByName("Attach")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceServiceJobsHandler := connect.NewUnaryHandler(
+		DeviceServiceJobsProcedure,
+		svc.Jobs,
+		connect.WithSchema(deviceServiceMethods.ByName("Jobs")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceServiceCancelJobHandler := connect.NewUnaryHandler(
+		DeviceServiceCancelJobProcedure,
+		svc.CancelJob,
+		connect.WithSchema(deviceServiceMethods.ByName("CancelJob")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceServicePowerHandler := connect.NewUnaryHandler(
+		DeviceServicePowerProcedure,
+		svc.Power,
+		connect.WithSchema(deviceServiceMethods.ByName("Power")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/dutctl.v1.DeviceService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case DeviceServiceListProcedure:
@@ -218,6 +306,14 @@ func NewDeviceServiceHandler(svc DeviceServiceHandler, opts ...connect.HandlerOp
 			deviceServiceLockHandler.ServeHTTP(w, r)
 		case DeviceServiceUnlockProcedure:
 			deviceServiceUnlockHandler.ServeHTTP(w, r)
+		case DeviceServiceAttachProcedure:
+			deviceServiceAttachHandler.ServeHTTP(w, r)
+		case DeviceServiceJobsProcedure:
+			deviceServiceJobsHandler.ServeHTTP(w, r)
+		case DeviceServiceCancelJobProcedure:
+			deviceServiceCancelJobHandler.ServeHTTP(w, r)
+		case DeviceServicePowerProcedure:
+			deviceServicePowerHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -251,6 +347,22 @@ func (UnimplementedDeviceServiceHandler) Unlock(context.Context, *connect.Reques
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dutctl.v1.DeviceService.Unlock is not implemented"))
 }
 
+func (UnimplementedDeviceServiceHandler) Attach(context.Context, *connect.BidiStream[v1.AttachRequest, v1.AttachResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("dutctl.v1.DeviceService.Attach is not implemented"))
+}
+
+func (UnimplementedDeviceServiceHandler) Jobs(context.Context, *connect.Request[v1.JobsRequest]) (*connect.Response[v1.JobsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dutctl.v1.DeviceService.Jobs is not implemented"))
+}
+
+func (UnimplementedDeviceServiceHandler) CancelJob(context.Context, *connect.Request[v1.CancelJobRequest]) (*connect.Response[v1.CancelJobResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dutctl.v1.DeviceService.CancelJob is not implemented"))
+}
+
+func (UnimplementedDeviceServiceHandler) Power(context.Context, *connect.Request[v1.PowerRequest]) (*connect.Response[v1.PowerResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dutctl.v1.DeviceService.Power is not implemented"))
+}
+
 // RelayServiceClient is a client for the dutctl.v1.RelayService service.
 type RelayServiceClient interface {
 	Register(context.Context, *connect.Request[v1.RegisterRequest]) (*connect.Response[v1.RegisterResponse], error)